@@ -0,0 +1,308 @@
+// Package backfill rewrites large tables online in small, resumable chunks,
+// for data migrations that are easier to write in Go than in one UPDATE.
+//
+// A backfill walks the integer primary key of one table in ranges of
+// ChunkSize keys, from the lowest key to the highest one present when it
+// first started, and runs its Step on each range in a transaction of its
+// own. That transaction also advances the backfill's row in the backfills
+// table (see migrations/000007_create_backfills.up.sql), so a crashed or
+// cancelled backfill resumes after its last committed chunk and never
+// applies a chunk twice, and a second instance running the same backfill
+// waits its turn instead of doing the work again. Rows inserted after the
+// start are left to the application, which should be writing the new shape
+// by then.
+//
+//	r := backfill.New(database)
+//	p, err := r.Run(ctx, backfill.Backfill{
+//	    Name:          "users-email-lower",
+//	    Table:         "users",
+//	    Step:          backfill.SQL(`UPDATE users SET email_lower = lower(email) WHERE id > $1 AND id <= $2`),
+//	    RowsPerSecond: 5000,
+//	})
+//
+// Pause stops a running backfill after its current chunk, from any
+// instance; Run returns ErrPaused until Resume is called.
+package backfill
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ErrPaused is returned by Run for a backfill paused with Pause.
+var ErrPaused = errors.New("backfill: paused")
+
+// errMoved means the progress row changed under a chunk: another runner
+// advanced it, or the backfill was paused.
+var errMoved = errors.New("backfill: progress moved")
+
+// Step processes the rows whose key k satisfies from < k <= to, through tx,
+// and returns how many it changed.
+type Step func(ctx context.Context, tx *db.Tx, from, to int64) (int64, error)
+
+// SQL returns a Step executing query with the range bounds as its two
+// arguments, written $1 and $2 whatever the dialect:
+//
+//	UPDATE orders SET total_cents = total * 100 WHERE id > $1 AND id <= $2
+//	INSERT INTO order_totals (order_id, cents) SELECT id, total * 100 FROM orders WHERE id > $1 AND id <= $2
+func SQL(query string) Step {
+	return func(ctx context.Context, tx *db.Tx, from, to int64) (int64, error) {
+		q, args := tx.Dialect().Rebind(query, []any{from, to})
+		return tx.ExecAffected(ctx, q, args...)
+	}
+}
+
+// Backfill describes one data migration.
+type Backfill struct {
+	// Name keys the progress row; it must be stable across restarts.
+	Name string
+	// Table and Key name the table walked and its integer primary key. Key
+	// defaults to "id".
+	Table, Key string
+	Step       Step
+	// ChunkSize is the width of each key range. Defaults to 1000.
+	ChunkSize int64
+	// RowsPerSecond caps the rate at which rows are changed, pausing
+	// between chunks; zero means no cap.
+	RowsPerSecond float64
+	// OnChunk, if set, is called after each committed chunk.
+	OnChunk func(Progress)
+}
+
+// State is where a backfill is in its life.
+type State string
+
+const (
+	StateRunning State = "running"
+	StatePaused  State = "paused"
+	StateDone    State = "done"
+)
+
+// Progress is the persisted state of one backfill.
+type Progress struct {
+	Name  string
+	State State
+	// Keys in (StartKey, LastKey] are done; the backfill ends at EndKey.
+	StartKey, LastKey, EndKey int64
+	Rows                      int64
+	StartedAt, UpdatedAt      time.Time
+}
+
+// Fraction is the share of the key range done, between 0 and 1.
+func (p Progress) Fraction() float64 {
+	if p.State == StateDone || p.EndKey <= p.StartKey {
+		return 1
+	}
+	return float64(p.LastKey-p.StartKey) / float64(p.EndKey-p.StartKey)
+}
+
+// Runner runs backfills and manages their progress rows.
+type Runner struct {
+	d *db.DB
+
+	sqlCreate, sqlStatus, sqlList, sqlAdvance, sqlRows, sqlState, sqlForget string
+}
+
+// New returns a Runner keeping progress in d's backfills table.
+func New(d *db.DB) *Runner {
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	create, err := db.InsertSQL(dialect, "backfills",
+		[]string{"name", "state", "start_key", "last_key", "end_key", "started_at", "updated_at"},
+		db.ConflictSkip, "name")
+	if err != nil {
+		panic(err) // static arguments; cannot fail
+	}
+	const cols = `name, state, start_key, last_key, end_key, rows_done, started_at, updated_at`
+	return &Runner{
+		d:         d,
+		sqlCreate: create,
+		sqlStatus: fmt.Sprintf(`SELECT %s FROM backfills WHERE name = %s`, cols, ph(1)),
+		sqlList:   fmt.Sprintf(`SELECT %s FROM backfills ORDER BY started_at, name`, cols),
+		sqlAdvance: fmt.Sprintf(`
+			UPDATE backfills SET last_key = %s, updated_at = %s
+			WHERE  name = %s AND last_key = %s AND state = 'running'`,
+			ph(1), ph(2), ph(3), ph(4)),
+		sqlRows: fmt.Sprintf(`
+			UPDATE backfills SET rows_done = rows_done + %s WHERE name = %s`,
+			ph(1), ph(2)),
+		sqlState: fmt.Sprintf(`
+			UPDATE backfills SET state = %s, updated_at = %s
+			WHERE  name = %s AND state = %s`,
+			ph(1), ph(2), ph(3), ph(4)),
+		sqlForget: fmt.Sprintf(`DELETE FROM backfills WHERE name = %s`, ph(1)),
+	}
+}
+
+func scanProgress(r db.RowScanner) (Progress, error) {
+	var (
+		p                Progress
+		state            string
+		started, updated int64
+	)
+	err := r.Scan(&p.Name, &state, &p.StartKey, &p.LastKey, &p.EndKey, &p.Rows, &started, &updated)
+	p.State = State(state)
+	p.StartedAt, p.UpdatedAt = time.UnixMicro(started), time.UnixMicro(updated)
+	return p, err
+}
+
+// Status returns the progress of the named backfill; the error satisfies
+// db.IsNotFound when it never ran.
+func (r *Runner) Status(ctx context.Context, name string) (Progress, error) {
+	return db.Get(ctx, r.d, scanProgress, r.sqlStatus, name)
+}
+
+// List returns every backfill, oldest first.
+func (r *Runner) List(ctx context.Context) ([]Progress, error) {
+	return db.Select(ctx, r.d, scanProgress, r.sqlList)
+}
+
+// ── Running ──────────────────────────────────────────────────────────────────
+
+// Run processes the remaining chunks of b until it is done, paused (it then
+// returns ErrPaused), ctx is cancelled or Step fails. Progress up to the
+// last committed chunk is kept either way; calling Run again continues from
+// there. A backfill that is already done returns immediately.
+//
+// Statements run labelled db.WorkloadBatch unless ctx carries another
+// workload, so maintenance windows hold the backfill back.
+func (r *Runner) Run(ctx context.Context, b Backfill) (Progress, error) {
+	if db.WorkloadFrom(ctx) == db.WorkloadInteractive {
+		ctx = db.WithWorkload(ctx, db.WorkloadBatch)
+	}
+	if b.Name == "" || b.Table == "" || b.Step == nil {
+		return Progress{}, errors.New("backfill: Name, Table and Step are required")
+	}
+	if b.Key == "" {
+		b.Key = "id"
+	}
+	if b.ChunkSize <= 0 {
+		b.ChunkSize = 1000
+	}
+	p, err := r.start(ctx, b)
+	if err != nil {
+		return p, err
+	}
+	for {
+		switch {
+		case p.State == StateDone:
+			return p, nil
+		case p.State == StatePaused:
+			return p, ErrPaused
+		case p.LastKey >= p.EndKey:
+			if err := r.setState(ctx, b.Name, StateRunning, StateDone); err != nil {
+				return p, err
+			}
+			return r.Status(ctx, b.Name)
+		}
+		if err := ctx.Err(); err != nil {
+			return p, err
+		}
+
+		from, to := p.LastKey, min(p.LastKey+b.ChunkSize, p.EndKey)
+		began := time.Now()
+		var rows int64
+		err := r.d.ExecTx(ctx, func(tx *db.Tx) error {
+			// Advancing first locks the progress row, so a concurrent runner
+			// blocks here and then finds last_key moved.
+			n, err := tx.ExecAffected(ctx, r.sqlAdvance, to, tx.Now().UnixMicro(), b.Name, from)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return errMoved
+			}
+			if rows, err = b.Step(ctx, tx, from, to); err != nil {
+				return err
+			}
+			_, err = tx.Exec(ctx, r.sqlRows, rows, b.Name)
+			return err
+		})
+		if errors.Is(err, errMoved) {
+			if p, err = r.Status(ctx, b.Name); err != nil {
+				return p, err
+			}
+			continue
+		}
+		if err != nil {
+			return p, fmt.Errorf("backfill %s: keys (%d, %d]: %w", b.Name, from, to, err)
+		}
+		p.LastKey, p.Rows = to, p.Rows+rows
+		if b.OnChunk != nil {
+			b.OnChunk(p)
+		}
+
+		if b.RowsPerSecond > 0 {
+			wait := time.Duration(float64(rows)/b.RowsPerSecond*float64(time.Second)) - time.Since(began)
+			if wait > 0 {
+				select {
+				case <-ctx.Done():
+					return p, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+	}
+}
+
+// start returns b's progress, creating its row with the table's current key
+// range on the first run.
+func (r *Runner) start(ctx context.Context, b Backfill) (Progress, error) {
+	p, err := r.Status(ctx, b.Name)
+	if !db.IsNotFound(err) {
+		return p, err
+	}
+	dialect := r.d.Dialect()
+	var lo, hi int64
+	key := dialect.QuoteIdent(b.Key)
+	err = r.d.QueryRow(ctx, fmt.Sprintf(`SELECT COALESCE(MIN(%s), 0), COALESCE(MAX(%s), 0) FROM %s`,
+		key, key, dialect.QuoteIdent(b.Table))).Scan(&lo, &hi)
+	if err != nil {
+		return p, fmt.Errorf("backfill %s: key range: %w", b.Name, err)
+	}
+	now := r.d.Now().UnixMicro()
+	if _, err := r.d.Exec(ctx, r.sqlCreate, b.Name, string(StateRunning), lo-1, lo-1, hi, now, now); err != nil {
+		return p, fmt.Errorf("backfill %s: %w", b.Name, err)
+	}
+	return r.Status(ctx, b.Name)
+}
+
+// ── Control ──────────────────────────────────────────────────────────────────
+
+// Pause stops the named backfill after the chunk in progress, on whichever
+// instance runs it. Pausing a paused backfill is a no-op.
+func (r *Runner) Pause(ctx context.Context, name string) error {
+	return r.setState(ctx, name, StateRunning, StatePaused)
+}
+
+// Resume lets a paused backfill run again; the next Run continues it.
+// Resuming a running backfill is a no-op.
+func (r *Runner) Resume(ctx context.Context, name string) error {
+	return r.setState(ctx, name, StatePaused, StateRunning)
+}
+
+// Forget deletes the named backfill's progress, so the next Run starts over
+// with a fresh key range.
+func (r *Runner) Forget(ctx context.Context, name string) error {
+	_, err := r.d.Exec(ctx, r.sqlForget, name)
+	return err
+}
+
+func (r *Runner) setState(ctx context.Context, name string, from, to State) error {
+	n, err := r.d.ExecAffected(ctx, r.sqlState, string(to), r.d.Now().UnixMicro(), name, string(from))
+	if err != nil || n > 0 {
+		return err
+	}
+	p, err := r.Status(ctx, name)
+	if err != nil {
+		return err
+	}
+	if p.State != to {
+		return fmt.Errorf("backfill: %s is %s", name, p.State)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package backfill_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/backfill"
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	d := dbtest.OpenSQLite(t, "000007_create_backfills")
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, email_lower TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 25; i++ {
+		if _, err := d.Exec(ctx, `INSERT INTO users (id, email) VALUES (?, ?)`, i*2, fmt.Sprintf("User%d@Example.com", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+func TestRun(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	r := backfill.New(d)
+
+	failAt := int64(30)
+	b := backfill.Backfill{
+		Name:      "users-email-lower",
+		Table:     "users",
+		ChunkSize: 10,
+		Step: func(ctx context.Context, tx *db.Tx, from, to int64) (int64, error) {
+			if from < failAt && failAt <= to {
+				return 0, errors.New("boom")
+			}
+			return backfill.SQL(`UPDATE users SET email_lower = lower(email) WHERE id > $1 AND id <= $2`)(ctx, tx, from, to)
+		},
+	}
+	if _, err := r.Run(ctx, b); err == nil {
+		t.Fatal("expected the failing chunk to stop the run")
+	}
+	p, err := r.Status(ctx, b.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keys 2..50: chunks (1,11], (11,21] committed; (21,31] failed and rolled back.
+	if p.State != backfill.StateRunning || p.StartKey != 1 || p.LastKey != 21 || p.EndKey != 50 || p.Rows != 10 {
+		t.Fatalf("unexpected progress: %+v", p)
+	}
+
+	failAt = -1
+	var chunks int
+	b.OnChunk = func(backfill.Progress) { chunks++ }
+	p, err = r.Run(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State != backfill.StateDone || p.Rows != 25 || p.Fraction() != 1 || chunks != 3 {
+		t.Fatalf("unexpected final progress: %+v after %d chunks", p, chunks)
+	}
+	var missing int
+	if err := d.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE email_lower IS NULL OR email_lower <> lower(email)`).Scan(&missing); err != nil || missing != 0 {
+		t.Fatalf("%d rows not backfilled (%v)", missing, err)
+	}
+	if p, err = r.Run(ctx, b); err != nil || p.State != backfill.StateDone {
+		t.Fatalf("rerunning a done backfill: %+v, %v", p, err)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	r := backfill.New(d)
+
+	b := backfill.Backfill{
+		Name:      "users-email-lower",
+		Table:     "users",
+		ChunkSize: 20,
+		Step:      backfill.SQL(`UPDATE users SET email_lower = lower(email) WHERE id > $1 AND id <= $2`),
+	}
+	b.OnChunk = func(p backfill.Progress) {
+		if p.LastKey == 21 {
+			if err := r.Pause(ctx, b.Name); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+	p, err := r.Run(ctx, b)
+	if !errors.Is(err, backfill.ErrPaused) || p.LastKey != 21 || p.State != backfill.StatePaused {
+		t.Fatalf("expected pause after the first chunk, got %+v, %v", p, err)
+	}
+	if f := p.Fraction(); f <= 0.4 || f >= 0.5 {
+		t.Fatalf("fraction = %v", f)
+	}
+	if _, err := r.Run(ctx, b); !errors.Is(err, backfill.ErrPaused) {
+		t.Fatalf("a paused backfill stays paused, got %v", err)
+	}
+
+	if err := r.Resume(ctx, b.Name); err != nil {
+		t.Fatal(err)
+	}
+	b.OnChunk = nil
+	if p, err = r.Run(ctx, b); err != nil || p.State != backfill.StateDone || p.Rows != 25 {
+		t.Fatalf("after resume: %+v, %v", p, err)
+	}
+	if err := r.Pause(ctx, b.Name); err == nil {
+		t.Fatal("pausing a done backfill should fail")
+	}
+
+	if err := r.Forget(ctx, b.Name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Status(ctx, b.Name); !db.IsNotFound(err) {
+		t.Fatalf("expected not found after Forget, got %v", err)
+	}
+	list, err := r.List(ctx)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("list: %+v, %v", list, err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package checkpoint records how far each consumer of a stream or batch
+// source has got — an offset, a cursor, the last key seen — for
+// exactly-once processing on top of ExecTx.
+//
+// A consumer's position lives in the checkpoints table (see
+// migrations/000008_create_checkpoints.up.sql) and is advanced with
+// compare-and-set in the same transaction as the work it covers. A crash
+// either commits both or neither, so a restarted consumer resumes exactly
+// after the last batch it applied; and of two instances processing the
+// same batch, the second fails with ErrConflict and rolls back instead of
+// applying it again.
+//
+//	cp := checkpoint.New(database)
+//	moved, err := cp.Process(ctx, "order-totals", func(ctx context.Context, tx *db.Tx, from string) (string, error) {
+//	    after, _ := strconv.ParseInt(from, 10, 64) // "" on the first run
+//	    events, err := loadEvents(ctx, tx, after, 500)
+//	    if err != nil || len(events) == 0 {
+//	        return from, err
+//	    }
+//	    for _, e := range events {
+//	        if err := applyEvent(ctx, tx, e); err != nil {
+//	            return from, err
+//	        }
+//	    }
+//	    return strconv.FormatInt(events[len(events)-1].ID, 10), nil
+//	})
+//
+// For a source outside the database, such as a Kafka partition, store its
+// offset the same way and seek to Load's position on startup.
+package checkpoint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ErrConflict is returned when a consumer's position was advanced by
+// someone else since it was loaded. The transaction should roll back; the
+// work is then redone, or skipped, from the new position.
+var ErrConflict = errors.New("checkpoint: position advanced concurrently")
+
+// Checkpoint is a consumer's position.
+type Checkpoint struct {
+	Consumer string
+	// Position is the consumer's opaque offset or cursor; "" before the
+	// first advance.
+	Position string
+	// Version counts advances; 0 before the first. It is the value
+	// compared by Advance.
+	Version   int64
+	UpdatedAt time.Time
+}
+
+// Store reads and advances the checkpoints table.
+type Store struct {
+	d *db.DB
+
+	sqlLoad, sqlCreate, sqlAdvance, sqlDelete, sqlList string
+}
+
+// New returns a Store keeping positions in d's checkpoints table.
+func New(d *db.DB) *Store {
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	create, err := db.InsertSQL(dialect, "checkpoints",
+		[]string{"consumer", "position", "version", "updated_at"}, db.ConflictFail)
+	if err != nil {
+		panic(err) // static arguments; cannot fail
+	}
+	const cols = `consumer, position, version, updated_at`
+	return &Store{
+		d:         d,
+		sqlLoad:   fmt.Sprintf(`SELECT %s FROM checkpoints WHERE consumer = %s`, cols, ph(1)),
+		sqlCreate: create,
+		sqlAdvance: fmt.Sprintf(`
+			UPDATE checkpoints SET position = %s, version = version + 1, updated_at = %s
+			WHERE  consumer = %s AND version = %s`,
+			ph(1), ph(2), ph(3), ph(4)),
+		sqlDelete: fmt.Sprintf(`DELETE FROM checkpoints WHERE consumer = %s`, ph(1)),
+		sqlList:   fmt.Sprintf(`SELECT %s FROM checkpoints ORDER BY consumer`, cols),
+	}
+}
+
+func scanCheckpoint(r db.RowScanner) (Checkpoint, error) {
+	var (
+		c       Checkpoint
+		updated int64
+	)
+	err := r.Scan(&c.Consumer, &c.Position, &c.Version, &updated)
+	c.UpdatedAt = time.UnixMicro(updated)
+	return c, err
+}
+
+// Load returns consumer's checkpoint through q, which may be the processing
+// transaction. A consumer that never advanced has Version 0 and an empty
+// Position.
+func (s *Store) Load(ctx context.Context, q db.Querier, consumer string) (Checkpoint, error) {
+	c, err := db.Get(ctx, q, scanCheckpoint, s.sqlLoad, consumer)
+	if db.IsNotFound(err) {
+		return Checkpoint{Consumer: consumer}, nil
+	}
+	return c, err
+}
+
+// Advance moves c's consumer to position through q, which should be the
+// transaction applying the work up to position, and returns the new
+// checkpoint. It fails with ErrConflict when the stored checkpoint is no
+// longer at c.Version.
+func (s *Store) Advance(ctx context.Context, q db.Querier, c Checkpoint, position string) (Checkpoint, error) {
+	now := db.NowFrom(q)
+	next := Checkpoint{Consumer: c.Consumer, Position: position, Version: c.Version + 1, UpdatedAt: now}
+	if c.Version == 0 {
+		_, err := q.Exec(ctx, s.sqlCreate, c.Consumer, position, next.Version, now.UnixMicro())
+		if db.IsDuplicateKey(err) {
+			return c, fmt.Errorf("%w: %s", ErrConflict, c.Consumer)
+		}
+		return next, err
+	}
+	res, err := q.Exec(ctx, s.sqlAdvance, position, now.UnixMicro(), c.Consumer, c.Version)
+	if err != nil {
+		return c, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return c, err
+	}
+	if n == 0 {
+		return c, fmt.Errorf("%w: %s", ErrConflict, c.Consumer)
+	}
+	return next, nil
+}
+
+// Process runs fn in a transaction with consumer's position and advances
+// it to the position fn returns, in that same transaction: fn's writes and
+// the new position commit together or not at all. It reports whether the
+// position moved; when fn returns from unchanged nothing is written for
+// the checkpoint. An error from fn rolls everything back and is returned,
+// as is ErrConflict when another instance advanced the consumer meanwhile.
+func (s *Store) Process(ctx context.Context, consumer string,
+	fn func(ctx context.Context, tx *db.Tx, from string) (string, error)) (bool, error) {
+	var moved bool
+	err := s.d.ExecTx(ctx, func(tx *db.Tx) error {
+		c, err := s.Load(ctx, tx, consumer)
+		if err != nil {
+			return err
+		}
+		to, err := fn(ctx, tx, c.Position)
+		if err != nil || to == c.Position {
+			return err
+		}
+		_, err = s.Advance(ctx, tx, c, to)
+		moved = err == nil
+		return err
+	})
+	return moved && err == nil, err
+}
+
+// Set moves consumer to position unconditionally, e.g. to rewind it for
+// reprocessing or to seed a new consumer.
+func (s *Store) Set(ctx context.Context, consumer, position string) error {
+	return s.d.ExecTx(ctx, func(tx *db.Tx) error {
+		c, err := s.Load(ctx, tx, consumer)
+		if err != nil {
+			return err
+		}
+		_, err = s.Advance(ctx, tx, c, position)
+		return err
+	})
+}
+
+// Reset forgets consumer's checkpoint; it starts from "" again.
+func (s *Store) Reset(ctx context.Context, consumer string) error {
+	_, err := s.d.Exec(ctx, s.sqlDelete, consumer)
+	return err
+}
+
+// List returns every consumer's checkpoint, by consumer name.
+func (s *Store) List(ctx context.Context) ([]Checkpoint, error) {
+	return db.Select(ctx, s.d, scanCheckpoint, s.sqlList)
+}
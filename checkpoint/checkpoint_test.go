@@ -0,0 +1,128 @@
+package checkpoint_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/checkpoint"
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	d := dbtest.OpenSQLite(t, "000008_create_checkpoints")
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`CREATE TABLE events (id INTEGER PRIMARY KEY, amount INTEGER NOT NULL)`,
+		`CREATE TABLE totals (name TEXT PRIMARY KEY, amount INTEGER NOT NULL)`,
+		`INSERT INTO totals VALUES ('all', 0)`,
+	} {
+		if _, err := d.Exec(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 7; i++ {
+		if _, err := d.Exec(ctx, `INSERT INTO events (id, amount) VALUES (?, ?)`, i, i*10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+// sumEvents adds up to three events after from into totals.
+func sumEvents(fail error) func(ctx context.Context, tx *db.Tx, from string) (string, error) {
+	return func(ctx context.Context, tx *db.Tx, from string) (string, error) {
+		after, _ := strconv.ParseInt(from, 10, 64)
+		var last, sum int64
+		err := tx.QueryRow(ctx, `SELECT COALESCE(MAX(id), 0), COALESCE(SUM(amount), 0)
+			FROM (SELECT id, amount FROM events WHERE id > ? ORDER BY id LIMIT 3)`, after).Scan(&last, &sum)
+		if err != nil || last == 0 {
+			return from, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE totals SET amount = amount + ? WHERE name = 'all'`, sum); err != nil {
+			return from, err
+		}
+		return strconv.FormatInt(last, 10), fail
+	}
+}
+
+func total(t *testing.T, d *db.DB) (n int64) {
+	t.Helper()
+	if err := d.QueryRow(context.Background(), `SELECT amount FROM totals`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestProcess(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	cp := checkpoint.New(d)
+
+	failed := errors.New("boom")
+	if moved, err := cp.Process(ctx, "totals", sumEvents(failed)); !errors.Is(err, failed) || moved {
+		t.Fatalf("failing batch: moved %v, %v", moved, err)
+	}
+	if c, _ := cp.Load(ctx, d, "totals"); c.Version != 0 || total(t, d) != 0 {
+		t.Fatalf("failing batch must roll back work and position: %+v, total %d", c, total(t, d))
+	}
+
+	batches := 0
+	for {
+		moved, err := cp.Process(ctx, "totals", sumEvents(nil))
+		if err != nil {
+			t.Fatalf("process: %v", err)
+		}
+		if !moved {
+			break
+		}
+		batches++
+	}
+	c, err := cp.Load(ctx, d, "totals")
+	if err != nil || batches != 3 || c.Position != "7" || c.Version != 3 || total(t, d) != 280 {
+		t.Fatalf("after %d batches: %+v, %v, total %d", batches, c, err, total(t, d))
+	}
+
+	if err := cp.Set(ctx, "totals", "4"); err != nil {
+		t.Fatal(err)
+	}
+	if list, err := cp.List(ctx); err != nil || len(list) != 1 || list[0].Position != "4" || list[0].Version != 4 {
+		t.Fatalf("List after Set = %+v, %v", list, err)
+	}
+	if err := cp.Reset(ctx, "totals"); err != nil {
+		t.Fatal(err)
+	}
+	if c, _ := cp.Load(ctx, d, "totals"); c.Version != 0 || c.Position != "" {
+		t.Fatalf("after Reset: %+v", c)
+	}
+}
+
+func TestAdvance_Conflict(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	cp := checkpoint.New(d)
+
+	stale, _ := cp.Load(ctx, d, "c")
+	if _, err := cp.Advance(ctx, d, stale, "1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cp.Advance(ctx, d, stale, "1"); !errors.Is(err, checkpoint.ErrConflict) {
+		t.Fatalf("second first advance: expected ErrConflict, got %v", err)
+	}
+
+	cur, _ := cp.Load(ctx, d, "c")
+	next, err := cp.Advance(ctx, d, cur, "2")
+	if err != nil || next.Version != 2 || next.Position != "2" {
+		t.Fatalf("advance = %+v, %v", next, err)
+	}
+	if _, err := cp.Advance(ctx, d, cur, "3"); !errors.Is(err, checkpoint.ErrConflict) {
+		t.Fatalf("advance from a stale version: expected ErrConflict, got %v", err)
+	}
+	if c, _ := cp.Load(ctx, d, "c"); c.Position != "2" {
+		t.Fatalf("conflicting advance changed the position: %+v", c)
+	}
+}
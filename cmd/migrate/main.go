@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/golang-migrate/migrate/v4"
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/migrate"
+	gomigrate "github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/database/mysql"
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
@@ -33,7 +38,7 @@ func main() {
 		migrationsPath = "./migrations"
 	}
 
-	m, err := migrate.New("file://"+migrationsPath, dbURL)
+	m, err := gomigrate.New("file://"+migrationsPath, dbURL)
 	if err != nil {
 		fatalf("migration init failed: %v", err)
 	}
@@ -41,35 +46,123 @@ func main() {
 
 	m.Log = &migrateLogger{}
 
+	history, err := migrate.OpenURL(dbURL)
+	if err != nil {
+		fatalf("history: %v", err)
+	}
+	defer history.Close()
+	ctx := context.Background()
+	if err := migrate.EnsureHistory(ctx, history); err != nil {
+		fatalf("history: %v", err)
+	}
+
 	command := args[0]
 	switch command {
 	case "up":
-		if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		files, err := migrate.Load(migrationsPath)
+		if err != nil {
 			fatalf("up failed: %v", err)
 		}
-		slog.Info("migrations: up completed")
+		n, err := up(ctx, m, history, files)
+		if err != nil {
+			fatalf("up failed: %v", err)
+		}
+		slog.Info("migrations: up completed", "applied", n)
 
 	case "down":
-		steps := 1
-		if len(args) > 1 {
-			n, err := strconv.Atoi(args[1])
+		steps, allowDestructive := 1, false
+		for _, a := range args[1:] {
+			if a == "--allow-destructive" || a == "-allow-destructive" {
+				allowDestructive = true
+				continue
+			}
+			n, err := strconv.Atoi(a)
 			if err != nil || n < 1 {
-				fatalf("down: invalid steps argument %q", args[1])
+				fatalf("down: invalid steps argument %q", a)
 			}
 			steps = n
 		}
-		if err := m.Steps(-steps); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		files, err := migrate.Load(migrationsPath)
+		if err != nil {
+			fatalf("down failed: %v", err)
+		}
+		if err := checkDown(ctx, m, history, files, steps, allowDestructive); err != nil {
+			fatalf("down refused: %v (pass --allow-destructive to run it anyway)", err)
+		}
+		if err := down(ctx, m, history, steps); err != nil {
 			fatalf("down failed: %v", err)
 		}
 		slog.Info("migrations: down completed", "steps", steps)
 
 	case "version":
 		v, dirty, err := m.Version()
-		if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		if err != nil && !errors.Is(err, gomigrate.ErrNilVersion) {
 			fatalf("version failed: %v", err)
 		}
 		fmt.Printf("version: %d  dirty: %v\n", v, dirty)
 
+	case "history":
+		applied, err := migrate.History(ctx, history)
+		if err != nil {
+			fatalf("history failed: %v", err)
+		}
+		for _, a := range applied {
+			fmt.Printf("%06d  %-40s  %s  %10s  %.12s\n", a.Version, a.Name,
+				a.AppliedAt.UTC().Format(time.RFC3339), a.Duration.Round(time.Millisecond), a.Checksum)
+		}
+
+	case "lint":
+		fs := flag.NewFlagSet("lint", flag.ExitOnError)
+		all := fs.Bool("all", false, "lint every migration, not only pending ones")
+		strict := fs.Bool("strict", false, "fail on warnings too")
+		fs.Parse(args[1:])
+		files, err := migrate.Load(migrationsPath)
+		if err != nil {
+			fatalf("lint failed: %v", err)
+		}
+		if !*all {
+			files, err = pending(m, files)
+			if err != nil {
+				fatalf("lint failed: %v", err)
+			}
+		}
+		findings, err := migrate.Lint(history.Dialect(), files)
+		if err != nil {
+			fatalf("lint failed: %v", err)
+		}
+		for _, f := range findings {
+			fmt.Fprintf(os.Stderr, "%06d  %-7s  %-20s  %s\n", f.Version, f.Severity, f.Rule, f.Message)
+			fmt.Fprintf(os.Stderr, "        %s\n", strings.ReplaceAll(f.Rewrite, "\n", "\n        "))
+		}
+		if err := migrate.CheckLint(findings, *strict); err != nil {
+			fatalf("lint: %d finding(s), %v", len(findings), err)
+		}
+		slog.Info("migrations: lint passed", "checked", len(files), "warnings", len(findings))
+
+	case "squash":
+		fs := flag.NewFlagSet("squash", flag.ExitOnError)
+		through := fs.Uint("through", 0, "last migration to fold into the baseline")
+		archive := fs.String("archive", "", "directory for the replaced files (default: MIGRATIONS_PATH/archive)")
+		fs.Parse(args[1:])
+		if *through == 0 {
+			fatalf("squash: --through version required")
+		}
+		current, dirty, err := m.Version()
+		if err != nil && !errors.Is(err, gomigrate.ErrNilVersion) {
+			fatalf("squash failed: %v", err)
+		}
+		if dirty || current != *through {
+			fatalf("squash: database is at version %d (dirty: %v); migrate it to exactly %d first", current, dirty, *through)
+		}
+		baseline, err := migrate.Squash(ctx, history, migrationsPath, *through, *archive)
+		if err != nil {
+			fatalf("squash failed: %v", err)
+		}
+		if err := m.Force(int(*through)); err != nil {
+			fatalf("squash: reset baseline version: %v", err)
+		}
+		slog.Info("migrations: squashed", "through", *through, "baseline", baseline.Up)
+
 	case "force":
 		if len(args) < 2 {
 			fatalf("force: version argument required")
@@ -104,6 +197,123 @@ func main() {
 
 // ─────────────────────────────────────────────────────────────────────────────
 
+// up applies pending migrations one step at a time, recording each in the
+// history table, and returns how many ran.
+func up(ctx context.Context, m *gomigrate.Migrate, history *db.DB, files []migrate.Migration) (int, error) {
+	applied := 0
+	for {
+		current, _, err := m.Version()
+		if err != nil && !errors.Is(err, gomigrate.ErrNilVersion) {
+			return applied, err
+		}
+		var next *migrate.Migration
+		for i := range files {
+			if files[i].Version > current || errors.Is(err, gomigrate.ErrNilVersion) {
+				next = &files[i]
+				break
+			}
+		}
+		if next == nil {
+			return applied, nil
+		}
+		sum, err := next.Checksum()
+		if err != nil {
+			return applied, err
+		}
+		start := time.Now()
+		if err := m.Steps(1); err != nil {
+			if errors.Is(err, gomigrate.ErrNoChange) {
+				return applied, nil
+			}
+			return applied, err
+		}
+		err = migrate.Record(ctx, history, migrate.AppliedMigration{
+			Version: next.Version, Name: next.Name, AppliedAt: start,
+			Duration: time.Since(start), Checksum: sum,
+		})
+		if err != nil {
+			return applied, fmt.Errorf("record %d: %w", next.Version, err)
+		}
+		applied++
+	}
+}
+
+// pending returns the migrations in files newer than the database version.
+func pending(m *gomigrate.Migrate, files []migrate.Migration) ([]migrate.Migration, error) {
+	current, _, err := m.Version()
+	if errors.Is(err, gomigrate.ErrNilVersion) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i, f := range files {
+		if f.Version > current {
+			return files[i:], nil
+		}
+	}
+	return nil, nil
+}
+
+// checkDown prints the destructive statements of the next steps down
+// migrations with the rows they affect, and refuses them unless allowed.
+func checkDown(ctx context.Context, m *gomigrate.Migrate, q *db.DB, files []migrate.Migration, steps int, allow bool) error {
+	current, _, err := m.Version()
+	if errors.Is(err, gomigrate.ErrNilVersion) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var pending []migrate.Migration
+	for i := len(files) - 1; i >= 0 && len(pending) < steps; i-- {
+		if files[i].Version <= current {
+			pending = append(pending, files[i])
+		}
+	}
+	impacts, err := migrate.DownImpact(ctx, q, pending)
+	if err != nil {
+		return err
+	}
+	if len(impacts) > 0 {
+		fmt.Fprintln(os.Stderr, "Destructive statements in the down migrations:")
+		for _, im := range impacts {
+			rows := "unknown"
+			switch {
+			case im.Rows >= 0 && im.Estimated:
+				rows = fmt.Sprintf("~%d", im.Rows)
+			case im.Rows >= 0:
+				rows = strconv.FormatInt(im.Rows, 10)
+			}
+			fmt.Fprintf(os.Stderr, "  %06d  %-50s  rows: %s\n", im.Version, im.String(), rows)
+		}
+	}
+	return migrate.CheckDown(impacts, allow)
+}
+
+// down rolls back steps migrations, removing each from the history table.
+func down(ctx context.Context, m *gomigrate.Migrate, history *db.DB, steps int) error {
+	for range steps {
+		current, _, err := m.Version()
+		if errors.Is(err, gomigrate.ErrNilVersion) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := m.Steps(-1); err != nil {
+			if errors.Is(err, gomigrate.ErrNoChange) {
+				return nil
+			}
+			return err
+		}
+		if err := migrate.Forget(ctx, history, current); err != nil {
+			return fmt.Errorf("forget %d: %w", current, err)
+		}
+	}
+	return nil
+}
+
 type migrateLogger struct{}
 
 func (l *migrateLogger) Printf(format string, v ...any) {
@@ -116,8 +326,20 @@ func usage() {
 
 Commands:
   up           Apply all pending migrations
-  down [N]     Rollback N migrations (default: 1)
+  down [N] [--allow-destructive]
+               Rollback N migrations (default: 1). Down migrations that drop
+               or truncate tables or drop columns are listed with their row
+               counts and refused unless --allow-destructive is given
   version      Print current migration version
+  history      List applied migrations with time, duration and checksum
+  lint [--all] [--strict]
+               Check pending migrations (every one with --all) for
+               statements that lock busy tables, printing safer rewrites.
+               Fails on errors, and on warnings too with --strict
+  squash --through <V> [--archive DIR]
+               Replace migrations up to V with one baseline generated from
+               the live schema, which must be at exactly V, and move the old
+               files to DIR (default: MIGRATIONS_PATH/archive)
   force <V>    Force set migration version (bypass dirty state)
   drop         Drop all tables (dev only)
 
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// observedQuery is one statement shape with its accumulated cost.
+type observedQuery struct {
+	query string
+	calls int64
+	total time.Duration
+}
+
+// indexCandidate is a suggested index and the statements that would use it.
+type indexCandidate struct {
+	table   string
+	cols    []string
+	where   []string // partial index predicates
+	calls   int64
+	total   time.Duration
+	queries []string
+}
+
+func (c *indexCandidate) key() string {
+	return c.table + "(" + strings.Join(c.cols, ",") + ")" + strings.Join(c.where, " AND ")
+}
+
+// runAdvise suggests indexes from recorded statements. It never connects to
+// the database: the output is a script for a human to review.
+func runAdvise(args []string) error {
+	fs := flag.NewFlagSet("advise", flag.ExitOnError)
+	slow := fs.String("slow", "", "JSON array of db.QueryRecord: a file, \"-\" for stdin, or a debughttp /slow URL")
+	pgss := fs.String("pgss", "", "CSV export of pg_stat_statements with query, calls and total_exec_time (or total_time) columns")
+	top := fs.Int("top", 20, "maximum number of suggestions")
+	minCalls := fs.Int64("min-calls", 1, "ignore candidates used by fewer calls")
+	_ = fs.Parse(args)
+
+	var queries []observedQuery
+	var err error
+	switch {
+	case *slow != "" && *pgss != "":
+		return fmt.Errorf("use either -slow or -pgss")
+	case *slow != "":
+		queries, err = readSlowLog(*slow)
+	case *pgss != "":
+		queries, err = readPGStatStatements(*pgss)
+	default:
+		return fmt.Errorf("-slow or -pgss is required")
+	}
+	if err != nil {
+		return err
+	}
+
+	cands := adviseIndexes(queries)
+	fmt.Printf("-- %d statement shapes analysed, %d index candidates\n", len(queries), len(cands))
+	fmt.Println("-- Benefit is the total time spent in matching statements: an upper bound")
+	fmt.Println("-- on the savings. Check existing indexes and EXPLAIN before applying.")
+	n := 0
+	for _, c := range cands {
+		if n == *top {
+			break
+		}
+		if c.calls < *minCalls {
+			continue
+		}
+		n++
+		fmt.Printf("\n-- %d. %s (%s)", n, c.table, strings.Join(c.cols, ", "))
+		if len(c.where) > 0 {
+			fmt.Printf(" WHERE %s", strings.Join(c.where, " AND "))
+		}
+		fmt.Printf("\n--    benefit <= %s over %d calls, %d statement shapes\n", c.total.Round(time.Millisecond), c.calls, len(c.queries))
+		fmt.Printf("--    e.g. %s\n", oneLine(c.queries[0], 160))
+		fmt.Println(createIndexSQL(c))
+	}
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Inputs
+// ─────────────────────────────────────────────────────────────────────────────
+
+// readSlowLog reads the ring buffer dump served by debughttp's /slow (or
+// marshalled from db.RecentQueries) and groups it by fingerprint.
+func readSlowLog(src string) ([]observedQuery, error) {
+	var r io.Reader
+	switch {
+	case src == "-":
+		r = os.Stdin
+	case strings.HasPrefix(src, "http://"), strings.HasPrefix(src, "https://"):
+		resp, err := http.Get(src)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", src, resp.Status)
+		}
+		r = resp.Body
+	default:
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var recs []db.QueryRecord
+	if err := json.NewDecoder(r).Decode(&recs); err != nil {
+		return nil, fmt.Errorf("decode slow log: %w", err)
+	}
+
+	byFP := make(map[string]*observedQuery)
+	var out []observedQuery
+	order := []string{}
+	for _, rec := range recs {
+		fp := rec.Fingerprint
+		if fp == "" {
+			fp = db.Fingerprint(rec.Query)
+		}
+		q, ok := byFP[fp]
+		if !ok {
+			// Keep the raw text: literals decide partial index predicates.
+			q = &observedQuery{query: rec.Query}
+			byFP[fp] = q
+			order = append(order, fp)
+		}
+		q.calls++
+		q.total += rec.Duration
+	}
+	for _, fp := range order {
+		out = append(out, *byFP[fp])
+	}
+	return out, nil
+}
+
+// readPGStatStatements reads a CSV such as produced by
+//
+//	\copy (SELECT query, calls, total_exec_time FROM pg_stat_statements) TO 'pgss.csv' CSV HEADER
+func readPGStatStatements(path string) ([]observedQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range header {
+		col[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	qi, ok := col["query"]
+	if !ok {
+		return nil, fmt.Errorf("%s: no query column", path)
+	}
+	ci, hasCalls := col["calls"]
+	ti, hasTotal := col["total_exec_time"]
+	if !hasTotal {
+		ti, hasTotal = col["total_time"] // PostgreSQL 12 and older
+	}
+	if !hasCalls || !hasTotal {
+		return nil, fmt.Errorf("%s: need calls and total_exec_time columns", path)
+	}
+
+	var out []observedQuery
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		calls, _ := strconv.ParseInt(rec[ci], 10, 64)
+		ms, _ := strconv.ParseFloat(rec[ti], 64)
+		out = append(out, observedQuery{
+			query: rec[qi],
+			calls: calls,
+			total: time.Duration(ms * float64(time.Millisecond)),
+		})
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Analysis
+// ─────────────────────────────────────────────────────────────────────────────
+
+// adviseIndexes derives one candidate per statement shape and merges
+// candidates whose columns are a prefix of another's on the same table and
+// with the same partial predicate, since the longer index serves both.
+func adviseIndexes(queries []observedQuery) []*indexCandidate {
+	byKey := map[string]*indexCandidate{}
+	for _, q := range queries {
+		c := analyseQuery(q.query)
+		if c == nil {
+			continue
+		}
+		if prev, ok := byKey[c.key()]; ok {
+			c = prev
+		} else {
+			byKey[c.key()] = c
+		}
+		c.calls += q.calls
+		c.total += q.total
+		c.queries = append(c.queries, q.query)
+	}
+
+	cands := make([]*indexCandidate, 0, len(byKey))
+	for _, c := range byKey {
+		cands = append(cands, c)
+	}
+	// Longest first, so prefixes fold into the widest index.
+	sort.Slice(cands, func(i, j int) bool {
+		if len(cands[i].cols) != len(cands[j].cols) {
+			return len(cands[i].cols) > len(cands[j].cols)
+		}
+		return cands[i].key() < cands[j].key()
+	})
+	var kept []*indexCandidate
+	for _, c := range cands {
+		merged := false
+		for _, k := range kept {
+			if k.table == c.table && strings.Join(k.where, "") == strings.Join(c.where, "") && hasPrefix(k.cols, c.cols) {
+				k.calls += c.calls
+				k.total += c.total
+				k.queries = append(k.queries, c.queries...)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			kept = append(kept, c)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].total > kept[j].total })
+	return kept
+}
+
+func hasPrefix(cols, prefix []string) bool {
+	if len(prefix) > len(cols) {
+		return false
+	}
+	for i := range prefix {
+		if cols[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var clauseEnd = map[string]bool{
+	"GROUP": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "HAVING": true,
+	"RETURNING": true, "FOR": true, "UNION": true, "EXCEPT": true, "INTERSECT": true,
+	"WINDOW": true, "FETCH": true, ";": true,
+}
+
+// analyseQuery suggests an index for a single-table SELECT, UPDATE or DELETE:
+// equality columns first, then one range column or the ORDER BY columns.
+// Predicates against string or boolean literals and IS [NOT] NULL become the
+// partial index WHERE clause. Statements it cannot reason about (OR at the
+// top level, subqueries in FROM, ...) yield nil.
+func analyseQuery(query string) *indexCandidate {
+	toks := sqlTokens(query)
+	if len(toks) == 0 {
+		return nil
+	}
+	var table, alias string
+	i := 0
+	switch upper(toks[0]) {
+	case "SELECT":
+		i = indexAtDepth0(toks, 0, "FROM")
+		if i < 0 {
+			return nil
+		}
+		i++
+	case "DELETE":
+		i = indexAtDepth0(toks, 0, "FROM")
+		if i < 0 {
+			return nil
+		}
+		i++
+	case "UPDATE":
+		i = 1
+	default:
+		return nil
+	}
+	if i >= len(toks) || !isIdent(toks[i]) {
+		return nil
+	}
+	table = toks[i]
+	i++
+	if i < len(toks) && upper(toks[i]) == "AS" {
+		i++
+	}
+	if i < len(toks) && isIdent(toks[i]) && !isKeyword(toks[i]) {
+		alias = toks[i]
+	}
+	fromEnd := i
+	for fromEnd < len(toks) && upper(toks[fromEnd]) != "WHERE" && upper(toks[fromEnd]) != "SET" && !clauseEnd[upper(toks[fromEnd])] {
+		fromEnd++
+	}
+	joined := indexAtDepth0(toks[:fromEnd], i, "JOIN") >= 0 || indexAtDepth0(toks[:fromEnd], i, ",") >= 0
+
+	// column resolves a reference to the main table's column, or "".
+	column := func(ref string) string {
+		if q, c, ok := strings.Cut(ref, "."); ok {
+			if q == alias || q == table || strings.HasSuffix(table, "."+q) {
+				return c
+			}
+			return ""
+		}
+		if joined {
+			return "" // ambiguous without qualification
+		}
+		return ref
+	}
+
+	c := &indexCandidate{table: table}
+	var eq []string
+	var rng string
+
+	if w := indexAtDepth0(toks, i, "WHERE"); w >= 0 {
+		end := w + 1
+		for depth := 0; end < len(toks); end++ {
+			switch toks[end] {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if depth == 0 && clauseEnd[upper(toks[end])] {
+				break
+			}
+		}
+		where := toks[w+1 : end]
+		if indexAtDepth0(where, 0, "OR") >= 0 {
+			return nil
+		}
+		for _, pred := range splitAtDepth0(where, "AND") {
+			// BETWEEN a AND b was split at its AND; the first half carries
+			// the column.
+			if len(pred) < 2 || !isIdent(pred[0]) {
+				continue
+			}
+			col := column(pred[0])
+			if col == "" {
+				continue
+			}
+			op := upper(pred[1])
+			switch {
+			case op == "IS":
+				if rest := upper(strings.Join(pred[1:], " ")); rest == "IS NULL" || rest == "IS NOT NULL" {
+					c.where = append(c.where, col+" "+rest)
+				}
+			case op == "=" && len(pred) == 3 && isConstant(pred[2]):
+				c.where = append(c.where, col+" = "+pred[2])
+			case op == "=" || op == "IN":
+				eq = appendUnique(eq, col)
+			case op == "<" || op == ">" || op == "<=" || op == ">=" || op == "BETWEEN" || op == "LIKE":
+				if op == "LIKE" && len(pred) > 2 && strings.HasPrefix(pred[2], "'%") {
+					continue // leading wildcard: a btree cannot help
+				}
+				if rng == "" {
+					rng = col
+				}
+			}
+		}
+	}
+
+	cols := eq
+	if rng != "" && !contains(eq, rng) {
+		cols = append(cols, rng)
+	} else if o := indexAtDepth0(toks, i, "ORDER"); o >= 0 && o+1 < len(toks) && upper(toks[o+1]) == "BY" {
+		var order []string
+		end := o + 2
+		for end < len(toks) && !clauseEnd[upper(toks[end])] {
+			end++
+		}
+		for _, term := range splitAtDepth0(toks[o+2:end], ",") {
+			if len(term) == 0 || len(term) > 2 || !isIdent(term[0]) {
+				order = nil
+				break
+			}
+			col := column(term[0])
+			if col == "" {
+				order = nil
+				break
+			}
+			if len(term) == 2 && upper(term[1]) == "DESC" {
+				col += " DESC"
+			}
+			order = append(order, col)
+		}
+		for _, col := range order {
+			if !contains(cols, strings.TrimSuffix(col, " DESC")) {
+				cols = append(cols, col)
+			}
+		}
+	}
+	if len(cols) > 4 {
+		cols = cols[:4]
+	}
+	if len(cols) == 0 || len(cols) == 1 && cols[0] == "id" && len(c.where) == 0 {
+		return nil // nothing to index, or the primary key already does
+	}
+	sort.Strings(c.where)
+	c.cols = cols
+	return c
+}
+
+func createIndexSQL(c *indexCandidate) string {
+	name := "idx_" + strings.ReplaceAll(c.table, ".", "_")
+	for _, col := range c.cols {
+		name += "_" + strings.TrimSuffix(col, " DESC")
+	}
+	if len(c.where) > 0 {
+		name += "_partial"
+	}
+	if len(name) > 63 { // PostgreSQL identifier limit
+		name = name[:63]
+	}
+	sql := fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s (%s)", name, c.table, strings.Join(c.cols, ", "))
+	if len(c.where) > 0 {
+		sql += " WHERE " + strings.Join(c.where, " AND ")
+	}
+	return sql + ";"
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Tokens
+// ─────────────────────────────────────────────────────────────────────────────
+
+// sqlTokens splits query into identifiers (dotted names kept whole), quoted
+// strings, numbers, placeholders and operators. Comments are dropped.
+func sqlTokens(query string) []string {
+	var toks []string
+	s := query
+	for len(s) > 0 {
+		r := rune(s[0])
+		switch {
+		case unicode.IsSpace(r):
+			s = s[1:]
+		case strings.HasPrefix(s, "--"):
+			if j := strings.IndexByte(s, '\n'); j >= 0 {
+				s = s[j:]
+			} else {
+				s = ""
+			}
+		case strings.HasPrefix(s, "/*"):
+			if j := strings.Index(s, "*/"); j >= 0 {
+				s = s[j+2:]
+			} else {
+				s = ""
+			}
+		case r == '\'':
+			j := 1
+			for j < len(s) {
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			j = min(j+1, len(s))
+			toks, s = append(toks, s[:j]), s[j:]
+		case r == '_' || r == '"' || r == '`' || unicode.IsLetter(r):
+			j := 0
+			for j < len(s) {
+				ch := s[j]
+				if ch == '"' || ch == '`' {
+					k := strings.IndexByte(s[j+1:], ch)
+					if k < 0 {
+						j = len(s)
+						break
+					}
+					j += k + 2
+					continue
+				}
+				if ch == '.' || ch == '_' || ch == '$' || ch >= 0x80 || unicode.IsLetter(rune(ch)) || unicode.IsDigit(rune(ch)) {
+					j++
+					continue
+				}
+				break
+			}
+			toks, s = append(toks, s[:j]), s[j:]
+		case unicode.IsDigit(r) || r == '$' || r == '?' || r == ':' && len(s) > 1 && unicode.IsLetter(rune(s[1])):
+			j := 1
+			for j < len(s) && (s[j] == '.' || s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			toks, s = append(toks, s[:j]), s[j:]
+		default:
+			j := 1
+			if len(s) > 1 && strings.Contains("<=>!:|", s[:1]) && strings.Contains("=>:|", s[1:2]) {
+				j = 2
+			}
+			toks, s = append(toks, s[:j]), s[j:]
+		}
+	}
+	return toks
+}
+
+func upper(tok string) string { return strings.ToUpper(tok) }
+
+func isIdent(tok string) bool {
+	r := rune(tok[0])
+	return r == '_' || r == '"' || r == '`' || unicode.IsLetter(r)
+}
+
+var sqlKeywords = map[string]bool{
+	"WHERE": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "ON": true, "SET": true, "USING": true, "NATURAL": true,
+}
+
+func isKeyword(tok string) bool { return sqlKeywords[upper(tok)] || clauseEnd[upper(tok)] }
+
+// isConstant reports literals that suit a partial index predicate: strings
+// and booleans. Numbers are usually ids inlined by an ORM, so they are
+// treated like parameters.
+func isConstant(tok string) bool {
+	return tok[0] == '\'' || upper(tok) == "TRUE" || upper(tok) == "FALSE"
+}
+
+func indexAtDepth0(toks []string, from int, word string) int {
+	depth := 0
+	for i := from; i < len(toks); i++ {
+		switch toks[i] {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && upper(toks[i]) == word {
+			return i
+		}
+	}
+	return -1
+}
+
+func splitAtDepth0(toks []string, sep string) [][]string {
+	var parts [][]string
+	depth, start := 0, 0
+	for i, t := range toks {
+		switch t {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && upper(t) == sep {
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, toks[start:])
+}
+
+func appendUnique(list []string, s string) []string {
+	if contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > max {
+		s = s[:max] + "…"
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSQLTokens(t *testing.T) {
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"SELECT * FROM users WHERE id = $1",
+			[]string{"SELECT", "*", "FROM", "users", "WHERE", "id", "=", "$1"}},
+		{"select u.name from public.users u where u.email=?",
+			[]string{"select", "u.name", "from", "public.users", "u", "where", "u.email", "=", "?"}},
+		{"SELECT 1 -- trailing\n/* block */ FROM t",
+			[]string{"SELECT", "1", "FROM", "t"}},
+		{"WHERE name = 'it''s' AND n >= 2.5",
+			[]string{"WHERE", "name", "=", "'it''s'", "AND", "n", ">=", "2.5"}},
+		{`SELECT "Odd Name", ` + "`tick`" + ` FROM "s"."t"`,
+			[]string{"SELECT", `"Odd Name"`, ",", "`tick`", "FROM", `"s"."t"`}},
+		{"WHERE a <> :name AND b::text != 'x'",
+			[]string{"WHERE", "a", "<>", ":name", "AND", "b", "::", "text", "!=", "'x'"}},
+		{"WHERE s = 'unterminated", []string{"WHERE", "s", "=", "'unterminated"}},
+		{"", nil},
+	}
+	for _, c := range cases {
+		if got := sqlTokens(c.query); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("sqlTokens(%q)\n got  %q\n want %q", c.query, got, c.want)
+		}
+	}
+}
+
+func TestAnalyseQuery(t *testing.T) {
+	cases := []struct {
+		name, query, want string // want is "" when no index is suggested
+	}{
+		{"equality", "SELECT * FROM users WHERE email = $1",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_users_email ON users (email);"},
+		{"equality then range", "SELECT * FROM orders WHERE customer_id = $1 AND created_at > $2",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_orders_customer_id_created_at ON orders (customer_id, created_at);"},
+		{"order by follows equality", "SELECT * FROM posts p WHERE p.author_id = ? ORDER BY p.created_at DESC LIMIT 10",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_posts_author_id_created_at ON posts (author_id, created_at DESC);"},
+		{"partial predicates", "SELECT * FROM jobs WHERE queue = $1 AND status = 'pending' AND deleted_at IS NULL",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_jobs_queue_partial ON jobs (queue) WHERE deleted_at IS NULL AND status = 'pending';"},
+		{"update", "UPDATE sessions SET seen_at = $1 WHERE token = $2",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_sessions_token ON sessions (token);"},
+		{"delete with between", "DELETE FROM events WHERE at BETWEEN $1 AND $2",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_events_at ON events (at);"},
+		{"qualified table", "SELECT * FROM app.users WHERE users.name = $1",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_app_users_name ON app.users (name);"},
+		{"join keeps qualified columns", "SELECT * FROM orders o JOIN users u ON u.id = o.user_id WHERE o.state = $1 AND region = $2",
+			"CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_orders_state ON orders (state);"},
+		{"primary key", "SELECT * FROM users WHERE id = $1", ""},
+		{"top-level OR", "SELECT * FROM users WHERE a = $1 OR b = $2", ""},
+		{"leading wildcard", "SELECT * FROM users WHERE name LIKE '%son'", ""},
+		{"subquery in FROM", "SELECT * FROM (SELECT * FROM users) u WHERE u.a = 1", ""},
+		{"insert", "INSERT INTO users (name) VALUES ($1)", ""},
+		{"no predicate", "SELECT * FROM users", ""},
+	}
+	for _, c := range cases {
+		got := ""
+		if cand := analyseQuery(c.query); cand != nil {
+			got = createIndexSQL(cand)
+		}
+		if got != c.want {
+			t.Errorf("%s: %q\n got  %s\n want %s", c.name, c.query, got, c.want)
+		}
+	}
+}
+
+func TestAdviseIndexes(t *testing.T) {
+	queries := []observedQuery{
+		{query: "SELECT * FROM orders WHERE customer_id = $1", calls: 10, total: time.Second},
+		{query: "SELECT * FROM orders WHERE customer_id = $1 AND created_at > $2", calls: 5, total: 2 * time.Second},
+		{query: "SELECT * FROM users WHERE email = $1", calls: 100, total: 500 * time.Millisecond},
+		{query: "SELECT * FROM users WHERE email = $1", calls: 1, total: time.Millisecond},
+		{query: "SELECT * FROM users WHERE id = $1", calls: 1000, total: time.Hour},
+	}
+	var got []string
+	for _, c := range adviseIndexes(queries) {
+		got = append(got, fmt.Sprintf("%s calls=%d total=%s queries=%d", c.key(), c.calls, c.total, len(c.queries)))
+	}
+	want := []string{
+		// The customer_id index is a prefix of the wider one and folds into it.
+		"orders(customer_id,created_at) calls=15 total=3s queries=2",
+		"users(email) calls=101 total=501ms queries=2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("adviseIndexes\n got  %s\n want %s", strings.Join(got, "\n      "), strings.Join(want, "\n      "))
+	}
+}
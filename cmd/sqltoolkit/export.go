@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// exportOptions control value formatting shared by all output formats.
+type exportOptions struct {
+	format     string
+	null       string
+	timeLayout string
+	binary     string
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	query := fs.String("query", "", "SELECT statement to export (required)")
+	out := fs.String("out", "-", `output file, "-" for stdout; a .gz suffix implies -gzip`)
+	gz := fs.Bool("gzip", false, "gzip-compress the output")
+	describe := fs.Bool("describe", false, "print the result columns and their types instead of the rows")
+	var opts exportOptions
+	fs.StringVar(&opts.format, "format", "csv", "output format: csv or jsonl")
+	fs.StringVar(&opts.null, "null", "", "CSV representation of NULL")
+	fs.StringVar(&opts.timeLayout, "time-format", time.RFC3339Nano, "Go layout for timestamp values")
+	fs.StringVar(&opts.binary, "binary", "base64", "encoding for non-UTF-8 bytes: base64 or hex")
+	_ = fs.Parse(args)
+
+	if *query == "" {
+		return fmt.Errorf("-query is required")
+	}
+	if opts.format != "csv" && opts.format != "jsonl" {
+		return fmt.Errorf("unknown format %q", opts.format)
+	}
+	if opts.binary != "base64" && opts.binary != "hex" {
+		return fmt.Errorf("unknown binary encoding %q", opts.binary)
+	}
+
+	d, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if *describe {
+		return describeColumns(context.Background(), d, *query, os.Stdout)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+		*gz = *gz || strings.HasSuffix(*out, ".gz")
+	}
+	bw := bufio.NewWriterSize(w, 64<<10)
+	w = bw
+	var zw *gzip.Writer
+	if *gz {
+		zw = gzip.NewWriter(bw)
+		w = zw
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	n, err := exportRows(ctx, d, *query, w, opts)
+	if err != nil {
+		return err
+	}
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	slog.Info("export: done", "rows", n, "duration", time.Since(start))
+	return nil
+}
+
+// describeColumns prints the result columns of query as an aligned table.
+func describeColumns(ctx context.Context, q db.Querier, query string, w io.Writer) error {
+	cols, err := db.Columns(ctx, q, query)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "COLUMN\tDATABASE TYPE\tTYPE\tNULLABLE\tLENGTH")
+	for _, c := range cols {
+		nullable, length := "?", ""
+		if c.Nullable != nil {
+			nullable = strconv.FormatBool(*c.Nullable)
+		}
+		switch {
+		case c.Precision > 0:
+			length = fmt.Sprintf("%d,%d", c.Precision, c.Scale)
+		case c.Length > 0 && c.Length < math.MaxInt32:
+			length = strconv.FormatInt(c.Length, 10)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.DatabaseType, c.Type, nullable, length)
+	}
+	return tw.Flush()
+}
+
+// exportRows streams the result of query to w and returns the row count.
+func exportRows(ctx context.Context, q db.Querier, query string, w io.Writer, opts exportOptions) (int64, error) {
+	if opts.format == "jsonl" {
+		return db.QueryJSONOpts(ctx, q, w, db.JSONOptions{
+			Lines: true, TimeLayout: opts.timeLayout, Binary: opts.binary,
+		}, query)
+	}
+	return db.QueryCSV(ctx, q, w, db.CSVOptions{
+		Null: opts.null, TimeLayout: opts.timeLayout, Binary: opts.binary,
+	}, query)
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// errDryRun rolls back the dry-run transaction once every row was inserted.
+var errDryRun = errors.New("dry run")
+
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	table := fs.String("table", "", "destination table (required)")
+	file := fs.String("file", "-", `CSV file with a header row, "-" for stdin`)
+	mapping := fs.String("map", "", "column mapping csv_col=db_col[,...]; unmapped columns keep their name")
+	skipCols := fs.String("skip", "", "comma-separated CSV columns to ignore")
+	batchSize := fs.Int("batch", 500, "rows per transaction")
+	onConflict := fs.String("on-conflict", "fail", "conflict strategy: fail, skip, or upsert")
+	keys := fs.String("key", "", "comma-separated unique key columns (required for upsert on postgres/sqlite)")
+	null := fs.String("null", "", "CSV field value imported as NULL")
+	dryRun := fs.Bool("dry-run", false, "insert inside a transaction and roll it back")
+	_ = fs.Parse(args)
+
+	if *table == "" {
+		return fmt.Errorf("-table is required")
+	}
+	if *batchSize < 1 {
+		return fmt.Errorf("-batch must be positive")
+	}
+	action, err := parseConflict(*onConflict)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	r := csv.NewReader(in)
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("read header: %w", err)
+	}
+
+	cols, idx, err := mapColumns(header, *mapping, *skipCols)
+	if err != nil {
+		return err
+	}
+
+	d, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	stmt, err := db.InsertSQL(d.Dialect(), *table, cols, action, splitList(*keys)...)
+	if err != nil {
+		return err
+	}
+	slog.Debug("import: statement", "sql", stmt)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	var total int64
+	readBatch := func() ([][]any, error) {
+		batch := make([][]any, 0, *batchSize)
+		for len(batch) < *batchSize {
+			rec, err := r.Read()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			row := make([]any, len(idx))
+			for i, j := range idx {
+				if rec[j] == *null {
+					row[i] = nil
+				} else {
+					row[i] = rec[j]
+				}
+			}
+			batch = append(batch, row)
+		}
+		return batch, nil
+	}
+	identity := func(row []any) []any { return row }
+
+	if *dryRun {
+		err = d.ExecTx(ctx, func(tx *db.Tx) error {
+			for {
+				batch, err := readBatch()
+				if err != nil || len(batch) == 0 {
+					if err == nil {
+						err = errDryRun
+					}
+					return err
+				}
+				if err := insertRows(ctx, tx, stmt, batch); err != nil {
+					return fmt.Errorf("row %d: %w", total+1, err)
+				}
+				total += int64(len(batch))
+			}
+		})
+		if !errors.Is(err, errDryRun) {
+			return err
+		}
+		slog.Info("import: dry run ok, rolled back", "rows", total, "duration", time.Since(start))
+		return nil
+	}
+
+	for {
+		batch, err := readBatch()
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := db.BatchExec(d, ctx, stmt, batch, identity); err != nil {
+			return fmt.Errorf("batch starting at row %d: %w", total+1, err)
+		}
+		total += int64(len(batch))
+		slog.Debug("import: batch committed", "rows", total)
+	}
+	slog.Info("import: done", "rows", total, "duration", time.Since(start))
+	return nil
+}
+
+// insertRows executes stmt once per row with a single prepared statement.
+func insertRows(ctx context.Context, q db.Querier, stmt string, rows [][]any) error {
+	s, err := q.Prepare(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	for _, row := range rows {
+		if _, err := s.Exec(ctx, row...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseConflict(s string) (db.OnConflict, error) {
+	switch s {
+	case "fail":
+		return db.ConflictFail, nil
+	case "skip":
+		return db.ConflictSkip, nil
+	case "upsert":
+		return db.ConflictUpdate, nil
+	}
+	return 0, fmt.Errorf("unknown conflict strategy %q", s)
+}
+
+// mapColumns returns the destination column names and, for each, the index
+// of the CSV field feeding it.
+func mapColumns(header []string, mapping, skip string) ([]string, []int, error) {
+	rename := map[string]string{}
+	for _, pair := range splitList(mapping) {
+		from, to, ok := strings.Cut(pair, "=")
+		if !ok || from == "" || to == "" {
+			return nil, nil, fmt.Errorf("invalid -map entry %q", pair)
+		}
+		rename[from] = to
+	}
+	skipped := map[string]bool{}
+	for _, c := range splitList(skip) {
+		skipped[c] = true
+	}
+
+	var cols []string
+	var idx []int
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		if skipped[h] {
+			continue
+		}
+		if to, ok := rename[h]; ok {
+			h = to
+		}
+		cols = append(cols, h)
+		idx = append(idx, i)
+	}
+	if len(cols) == 0 {
+		return nil, nil, fmt.Errorf("no columns to import")
+	}
+	return cols, idx, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
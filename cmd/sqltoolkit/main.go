@@ -0,0 +1,112 @@
+// Command sqltoolkit bundles operational tasks that run through the toolkit's
+// DB wrapper — so they share its hooks, error mapping, and timeouts — without
+// requiring psql/mysql clients in the container.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		usage()
+		os.Exit(1)
+	}
+
+	var err error
+	switch args[0] {
+	case "advise":
+		err = runAdvise(args[1:])
+	case "enum":
+		err = runEnum(args[1:])
+	case "export":
+		err = runExport(args[1:])
+	case "import":
+		err = runImport(args[1:])
+	case "repl":
+		err = runREPL(args[1:])
+	default:
+		usage()
+		os.Exit(1)
+	}
+	if err != nil {
+		fatalf("%s: %v", args[0], err)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+
+// openDB opens the database named by DATABASE_URL. The driver is taken from
+// DATABASE_DRIVER or inferred from the URL scheme.
+func openDB(hooks ...db.Hook) (*db.DB, error) {
+	dsn, err := db.DSNFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	driver := os.Getenv("DATABASE_DRIVER")
+	if driver == "" {
+		if driver, dsn, err = inferDriver(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return db.Open(db.Config{DSN: dsn, DriverName: driver, Hooks: hooks})
+}
+
+// inferDriver maps a URL scheme to a registered driver name, rewriting the DSN
+// where the driver does not accept URLs. Any other DSN is an error rather
+// than a guess: opening a MySQL DSN as a SQLite file would quietly create
+// an empty database.
+func inferDriver(dsn string) (driver, out string, err error) {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return "postgres", dsn, nil
+	case strings.HasPrefix(dsn, "mysql://"):
+		// go-sql-driver/mysql expects user:pass@tcp(host)/db, not a URL.
+		return "mysql", strings.TrimPrefix(dsn, "mysql://"), nil
+	case strings.HasPrefix(dsn, "sqlite3://"):
+		return "sqlite3", strings.TrimPrefix(dsn, "sqlite3://"), nil
+	case strings.HasPrefix(dsn, "sqlite://"):
+		return "sqlite3", strings.TrimPrefix(dsn, "sqlite://"), nil
+	case strings.HasPrefix(dsn, "file:"):
+		return "sqlite3", dsn, nil
+	}
+	return "", "", errors.New("cannot infer the driver from DATABASE_URL: use a postgres://, mysql://, sqlite:// or file: URL, or set DATABASE_DRIVER")
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: sqltoolkit <command> [flags]
+
+Commands:
+  advise       Suggest indexes from a slow-query log or pg_stat_statements
+  enum         Generate a Go enum type registered with db.RegisterEnum
+  export       Stream a query result to CSV or JSONL
+  import       Load a CSV file into a table (batched, with upsert modes)
+  repl         Interactive SQL shell with timing and table output
+
+Run 'sqltoolkit <command> -h' for command flags.
+
+Environment:
+  DATABASE_URL      Required. Full database DSN.
+  DATABASE_DRIVER   Driver name (postgres, mysql, sqlite3). Inferred from the
+                    DATABASE_URL scheme (postgres://, mysql://, sqlite://,
+                    file:) when unset.`)
+}
+
+func fatalf(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
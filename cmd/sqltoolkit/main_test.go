@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+func TestInferDriver(t *testing.T) {
+	cases := []struct{ dsn, driver, out string }{
+		{"postgres://u:p@db/app", "postgres", "postgres://u:p@db/app"},
+		{"postgresql://db/app", "postgres", "postgresql://db/app"},
+		{"mysql://u:p@tcp(db:3306)/app", "mysql", "u:p@tcp(db:3306)/app"},
+		{"sqlite://app.db", "sqlite3", "app.db"},
+		{"sqlite3://:memory:", "sqlite3", ":memory:"},
+		{"file:app.db?mode=ro", "sqlite3", "file:app.db?mode=ro"},
+	}
+	for _, c := range cases {
+		driver, out, err := inferDriver(c.dsn)
+		if err != nil || driver != c.driver || out != c.out {
+			t.Errorf("inferDriver(%q) = %q, %q, %v; want %q, %q", c.dsn, driver, out, err, c.driver, c.out)
+		}
+	}
+	for _, dsn := range []string{"u:p@tcp(db:3306)/app", "app.db", "redis://cache"} {
+		if driver, _, err := inferDriver(dsn); err == nil {
+			t.Errorf("inferDriver(%q) = %q; want an error", dsn, driver)
+		}
+	}
+}
+
+func TestREPLQuery(t *testing.T) {
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	var out strings.Builder
+	s := &replSession{d: d, out: &out, maxRows: 2, opts: exportOptions{null: "NULL", binary: "hex"}}
+	err = s.query(context.Background(), `SELECT 1 AS id, 'a,"b"' || char(10) || 'c' AS note, NULL AS gone, x'00ff' AS raw
+		UNION ALL SELECT 2, 'd', 'e', x'01' UNION ALL SELECT 3, 'f', 'g', x'02'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id |note     |gone |raw\n" +
+		"-- |----     |---- |---\n" +
+		"1  |a,\"b\"\\nc |NULL |00ff\n" +
+		"2  |d        |e    |01\n" +
+		"(3 rows, first 2 shown)\n"
+	if out.String() != want {
+		t.Fatalf("output\n%s\nwant\n%s", out.String(), want)
+	}
+}
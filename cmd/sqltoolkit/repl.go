@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// replSession holds the state of one interactive session.
+type replSession struct {
+	d       *db.DB
+	out     io.Writer
+	timing  bool
+	maxRows int
+	opts    exportOptions
+}
+
+func runREPL(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	slow := fs.Duration("slow", 200*time.Millisecond, "log statements slower than this (0 disables)")
+	maxRows := fs.Int("max-rows", 500, "maximum rows printed per result")
+	timing := fs.Bool("timing", true, "print execution time after each statement")
+	verbose := fs.Bool("v", false, "log every statement, not only slow and failed ones")
+	_ = fs.Parse(args)
+
+	if *verbose {
+		slog.SetLogLoggerLevel(slog.LevelDebug)
+	}
+	// Statements go through the same hook chain as application queries, so
+	// slow and failing ones are logged exactly as they would be in a service.
+	d, err := openDB(db.NewLogHook(db.LogHookConfig{SlowQueryThreshold: *slow, LogArgs: true}))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	s := &replSession{
+		d:       d,
+		out:     os.Stdout,
+		timing:  *timing,
+		maxRows: *maxRows,
+		opts:    exportOptions{null: "NULL", timeLayout: time.RFC3339Nano, binary: "hex"},
+	}
+	return s.run(os.Stdin, isTerminal(os.Stdin))
+}
+
+// run reads statements from in until EOF or \q. A statement ends with a line
+// whose last non-blank character is ';', so it may span several lines.
+func (s *replSession) run(in io.Reader, interactive bool) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	var buf strings.Builder
+	prompt := func() {
+		if !interactive {
+			return
+		}
+		if buf.Len() == 0 {
+			fmt.Fprint(os.Stderr, "sql> ")
+		} else {
+			fmt.Fprint(os.Stderr, "  -> ")
+		}
+	}
+
+	prompt()
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if buf.Len() == 0 && strings.HasPrefix(line, `\`) {
+			if s.meta(line) {
+				return nil
+			}
+			prompt()
+			continue
+		}
+		if line != "" {
+			if buf.Len() > 0 {
+				buf.WriteByte('\n')
+			}
+			buf.WriteString(line)
+		}
+		if strings.HasSuffix(line, ";") {
+			s.exec(strings.TrimRight(buf.String(), "; \n"))
+			buf.Reset()
+		}
+		prompt()
+	}
+	if rest := strings.TrimSpace(buf.String()); rest != "" {
+		s.exec(rest)
+	}
+	return sc.Err()
+}
+
+// meta handles backslash commands and reports whether the session should end.
+func (s *replSession) meta(cmd string) (quit bool) {
+	switch cmd {
+	case `\q`, `\quit`:
+		return true
+	case `\timing`:
+		s.timing = !s.timing
+		fmt.Fprintf(s.out, "Timing is %s.\n", map[bool]string{true: "on", false: "off"}[s.timing])
+	case `\?`, `\help`:
+		fmt.Fprintln(s.out, `Statements end with ';' and may span lines.
+  \timing   toggle execution time output
+  \q        quit`)
+	default:
+		fmt.Fprintf(s.out, "unknown command %s (try \\?)\n", cmd)
+	}
+	return false
+}
+
+// exec runs one statement, cancelling it on Ctrl-C without ending the session.
+func (s *replSession) exec(query string) {
+	if query == "" {
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	var err error
+	if returnsRows(query) {
+		err = s.query(ctx, query)
+	} else {
+		var n int64
+		n, err = s.d.ExecAffected(ctx, query)
+		if err == nil {
+			fmt.Fprintf(s.out, "OK, %d row(s) affected\n", n)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(s.out, "ERROR: %v\n", err)
+	}
+	if s.timing {
+		fmt.Fprintf(s.out, "Time: %s\n", time.Since(start).Round(time.Microsecond))
+	}
+}
+
+// query prints the result of query as an aligned table, truncated to maxRows.
+// Values are formatted by db.QueryCSV, as in export, and read back as
+// records.
+func (s *replSession) query(ctx context.Context, query string) error {
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		_, err := db.QueryCSV(ctx, s.d, pw, db.CSVOptions{
+			Null: s.opts.null, TimeLayout: s.opts.timeLayout, Binary: s.opts.binary,
+		}, query)
+		pw.CloseWithError(err)
+	}()
+
+	r := csv.NewReader(pr)
+	r.ReuseRecord = true
+	tw := tabwriter.NewWriter(s.out, 0, 0, 1, ' ', tabwriter.Debug)
+	n := -1 // the header is the first record
+	var err error
+	for {
+		var record []string
+		if record, err = r.Read(); err != nil {
+			break
+		}
+		if n++; n > s.maxRows {
+			continue
+		}
+		for i, v := range record {
+			record[i] = strings.ReplaceAll(v, "\n", `\n`)
+		}
+		fmt.Fprintln(tw, strings.Join(record, "\t"))
+		if n == 0 {
+			for i, c := range record {
+				record[i] = strings.Repeat("-", len(c))
+			}
+			fmt.Fprintln(tw, strings.Join(record, "\t"))
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	if ferr := tw.Flush(); err == nil {
+		err = ferr
+	}
+	if err != nil {
+		return err
+	}
+	if n > s.maxRows {
+		fmt.Fprintf(s.out, "(%d rows, first %d shown)\n", n, s.maxRows)
+	} else {
+		fmt.Fprintf(s.out, "(%d rows)\n", max(n, 0))
+	}
+	return nil
+}
+
+var (
+	reRowKeyword = regexp.MustCompile(`(?i)^\s*(SELECT|WITH|SHOW|EXPLAIN|PRAGMA|VALUES|DESCRIBE|DESC|TABLE)\b`)
+	reReturning  = regexp.MustCompile(`(?i)\bRETURNING\b`)
+)
+
+// returnsRows guesses whether query produces a result set. Statements that do
+// not are run with Exec so the affected-row count can be reported.
+func returnsRows(query string) bool {
+	return reRowKeyword.MatchString(query) || reReturning.MatchString(query)
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,173 @@
+// Package counters maintains denormalized counters — a post's comment
+// count, a user's unread messages — kept next to the rows they summarize so
+// reads need not count.
+//
+// Increment adjusts a counter in the transaction that changes what it
+// counts, creating its row on first use:
+//
+//	err := database.ExecTx(ctx, func(tx *db.Tx) error {
+//	    if _, err := tx.Exec(ctx, `INSERT INTO comments (post_id, body) VALUES ($1, $2)`, postID, body); err != nil {
+//	        return err
+//	    }
+//	    return counters.Increment(ctx, tx, "post_stats", "comments", map[string]any{"post_id": postID}, 1)
+//	})
+//
+// Counters still drift — a write path that forgets to increment, a manual
+// fix in SQL — so a Reconciliation recomputes them from the source tables,
+// typically as a scheduler job:
+//
+//	sched.MustRegister("reconcile-comment-counts", "@hourly", counters.Job(database, counters.Reconciliation{
+//	    Table: "post_stats", Column: "comments", KeyCols: []string{"post_id"},
+//	    Source: `SELECT post_id, COUNT(*) FROM comments GROUP BY post_id`,
+//	}))
+package counters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/scheduler"
+)
+
+// Increment adds delta, which may be negative, to column of the row of
+// table whose key columns hold the values in key, inserting the row with
+// delta when there is none. The key columns must form the table's primary
+// key or a unique index, and the table's other columns need defaults. The
+// addition happens in the database, so concurrent increments never lose
+// each other's updates. Run it through the transaction making the change
+// counted, so both commit or neither.
+func Increment(ctx context.Context, q db.Querier, table, column string, key map[string]any, delta int64) error {
+	query, args, err := incrementSQL(db.DialectFrom(q), table, column, key, delta)
+	if err != nil {
+		return err
+	}
+	_, err = q.Exec(ctx, query, args...)
+	return err
+}
+
+func incrementSQL(d db.Dialect, table, column string, key map[string]any, delta int64) (string, []any, error) {
+	if len(key) == 0 {
+		return "", nil, errors.New("counters: no key columns")
+	}
+	keyCols := make([]string, 0, len(key))
+	for c := range key {
+		keyCols = append(keyCols, c)
+	}
+	sort.Strings(keyCols)
+	args := make([]any, 0, len(key)+1)
+	for _, c := range keyCols {
+		args = append(args, key[c])
+	}
+	args = append(args, delta)
+
+	query, err := db.InsertSQL(d, table, append(keyCols, column), db.ConflictFail)
+	if err != nil {
+		return "", nil, err
+	}
+	col := d.QuoteIdent(column)
+	if d == db.DialectMySQL {
+		return query + fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s + VALUES(%s)", col, col, col), args, nil
+	}
+	return query + fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s = %s.%s + excluded.%s",
+		quoteAll(d, keyCols), col, d.QuoteIdent(table), col, col), args, nil
+}
+
+func quoteAll(d db.Dialect, names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = d.QuoteIdent(n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// ── Reconciliation ───────────────────────────────────────────────────────────
+
+// Reconciliation recomputes a counter column from its source tables.
+type Reconciliation struct {
+	// Table and Column hold the counter; KeyCols are the columns keying
+	// its rows, as in Increment.
+	Table, Column string
+	KeyCols       []string
+	// Source is a SELECT returning the key columns, named as in KeyCols
+	// and in the same order, followed by the correct count.
+	Source string
+	// Logger is where Job reports corrected counters. Defaults to
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// Reconcile sets every counter of r to the value r.Source computes for it,
+// inserting missing rows and zeroing counters whose key Source no longer
+// returns, in one transaction, and returns how many counters it changed
+// (MySQL counts each corrected existing row twice). Increments committed
+// while it runs may be overwritten by the recomputed value and show up at
+// the next reconciliation; run it when writes are quiet if that matters.
+func Reconcile(ctx context.Context, d *db.DB, r Reconciliation) (int64, error) {
+	upsert, zero, err := reconcileSQL(d.Dialect(), r)
+	if err != nil {
+		return 0, err
+	}
+	ctx = db.NoTimeout(ctx)
+	var changed int64
+	err = d.ExecTx(ctx, func(tx *db.Tx) error {
+		n, err := tx.ExecAffected(ctx, upsert)
+		if err != nil {
+			return err
+		}
+		m, err := tx.ExecAffected(ctx, zero)
+		changed = n + m
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("counters: reconcile %s.%s: %w", r.Table, r.Column, err)
+	}
+	return changed, nil
+}
+
+func reconcileSQL(d db.Dialect, r Reconciliation) (upsert, zero string, err error) {
+	source := strings.TrimRight(strings.TrimSpace(r.Source), ";")
+	if r.Table == "" || r.Column == "" || len(r.KeyCols) == 0 || source == "" {
+		return "", "", errors.New("counters: Reconciliation needs Table, Column, KeyCols and Source")
+	}
+	table, col := d.QuoteIdent(r.Table), d.QuoteIdent(r.Column)
+	cols := quoteAll(d, append(r.KeyCols[:len(r.KeyCols):len(r.KeyCols)], r.Column))
+	if d == db.DialectMySQL {
+		upsert = fmt.Sprintf(`INSERT INTO %s (%s) SELECT * FROM (%s) AS src ON DUPLICATE KEY UPDATE %s = VALUES(%s)`,
+			table, cols, source, col, col)
+	} else {
+		// WHERE true lets SQLite tell ON CONFLICT from a join constraint.
+		upsert = fmt.Sprintf(`INSERT INTO %s (%s) SELECT * FROM (%s) AS src WHERE true
+			ON CONFLICT (%s) DO UPDATE SET %s = excluded.%s WHERE %s.%s <> excluded.%s`,
+			table, cols, source, quoteAll(d, r.KeyCols), col, col, table, col, col)
+	}
+	match := make([]string, len(r.KeyCols))
+	for i, k := range r.KeyCols {
+		k = d.QuoteIdent(k)
+		match[i] = fmt.Sprintf("src.%s = %s.%s", k, table, k)
+	}
+	zero = fmt.Sprintf(`UPDATE %s SET %s = 0 WHERE %s <> 0 AND NOT EXISTS (SELECT 1 FROM (%s) AS src WHERE %s)`,
+		table, col, col, source, strings.Join(match, " AND "))
+	return upsert, zero, nil
+}
+
+// Job returns a scheduler job running Reconcile for r and logging how many
+// counters it corrected.
+func Job(d *db.DB, r Reconciliation) scheduler.Job {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return func(ctx context.Context) error {
+		n, err := Reconcile(ctx, d, r)
+		if n > 0 {
+			logger.WarnContext(ctx, "counters: corrected drifted counters",
+				slog.String("table", r.Table), slog.String("column", r.Column), slog.Int64("changed", n))
+		}
+		return err
+	}
+}
@@ -0,0 +1,129 @@
+package counters_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/counters"
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	d := dbtest.OpenSQLite(t)
+	for _, stmt := range []string{
+		`CREATE TABLE comments (id INTEGER PRIMARY KEY, post_id INTEGER NOT NULL, author TEXT NOT NULL)`,
+		`CREATE TABLE post_stats (post_id INTEGER NOT NULL, author TEXT NOT NULL DEFAULT '',
+			comments INTEGER NOT NULL DEFAULT 0, likes INTEGER NOT NULL DEFAULT 0, PRIMARY KEY (post_id, author))`,
+	} {
+		if _, err := d.Exec(context.Background(), stmt); err != nil {
+			t.Fatalf("schema: %v", err)
+		}
+	}
+	return d
+}
+
+func count(t *testing.T, d *db.DB, postID int, author string) (n int64) {
+	t.Helper()
+	err := d.QueryRow(context.Background(),
+		`SELECT comments FROM post_stats WHERE post_id = ? AND author = ?`, postID, author).Scan(&n)
+	if err != nil && !db.IsNotFound(err) {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func comment(ctx context.Context, d *db.DB, postID int, author string) error {
+	return d.ExecTx(ctx, func(tx *db.Tx) error {
+		if _, err := tx.Exec(ctx, `INSERT INTO comments (post_id, author) VALUES (?, ?)`, postID, author); err != nil {
+			return err
+		}
+		return counters.Increment(ctx, tx, "post_stats", "comments", map[string]any{"post_id": postID, "author": author}, 1)
+	})
+}
+
+func TestIncrement(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			if err := comment(ctx, d, 1, "ann"); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+	if err := comment(ctx, d, 2, "bo"); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, d, 1, "ann"); got != 20 {
+		t.Fatalf("post 1 = %d, want 20", got)
+	}
+	if err := counters.Increment(ctx, d, "post_stats", "comments", map[string]any{"post_id": 2, "author": "bo"}, -3); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(t, d, 2, "bo"); got != -2 {
+		t.Fatalf("post 2 after -3 = %d, want -2", got)
+	}
+	if err := counters.Increment(ctx, d, "post_stats", "comments", nil, 1); err == nil {
+		t.Fatal("an increment without key columns must fail")
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	for _, c := range []struct {
+		post   int
+		author string
+	}{{1, "ann"}, {1, "ann"}, {2, "bo"}, {3, "cy"}} {
+		if err := comment(ctx, d, c.post, c.author); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Drift: a comment inserted behind the counter's back, one deleted,
+	// and a counter with no comments at all.
+	for _, stmt := range []string{
+		`INSERT INTO comments (post_id, author) VALUES (1, 'ann'), (4, 'di')`,
+		`DELETE FROM comments WHERE post_id = 3`,
+		`INSERT INTO post_stats (post_id, author, comments, likes) VALUES (5, 'ed', 7, 2)`,
+	} {
+		if _, err := d.Exec(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := counters.Reconciliation{
+		Table: "post_stats", Column: "comments", KeyCols: []string{"post_id", "author"},
+		Source: `SELECT post_id, author, COUNT(*) AS n FROM comments GROUP BY post_id, author;`,
+	}
+	n, err := counters.Reconcile(ctx, d, r)
+	if err != nil || n != 4 {
+		t.Fatalf("reconcile = %d, %v; want 4 changed", n, err)
+	}
+	for _, want := range []struct {
+		post   int
+		author string
+		n      int64
+	}{{1, "ann", 3}, {2, "bo", 1}, {3, "cy", 0}, {4, "di", 1}, {5, "ed", 0}} {
+		if got := count(t, d, want.post, want.author); got != want.n {
+			t.Errorf("post %d after reconcile = %d, want %d", want.post, got, want.n)
+		}
+	}
+	var likes int64
+	if err := d.QueryRow(ctx, `SELECT likes FROM post_stats WHERE post_id = 5`).Scan(&likes); err != nil || likes != 2 {
+		t.Fatalf("reconcile touched another column: likes = %d, %v", likes, err)
+	}
+
+	if err := counters.Job(d, r)(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := counters.Reconcile(ctx, d, r); err != nil || n != 0 {
+		t.Fatalf("second reconcile = %d, %v; want nothing to change", n, err)
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// AppName — identify the service in server-side session views
+// ─────────────────────────────────────────────────────────────────────────────
+
+// withAppName returns cfg.DSN with cfg.AppName and cfg.ConnAttributes added
+// in the form the driver expects. Settings already present in the DSN are
+// kept as they are.
+func withAppName(cfg Config) string {
+	if cfg.AppName == "" && len(cfg.ConnAttributes) == 0 {
+		return cfg.DSN
+	}
+	switch DialectOf(cfg.DriverName) {
+	case DialectPostgres:
+		return pgAppName(cfg.DSN, cfg.AppName)
+	case DialectMySQL:
+		return mysqlConnAttributes(cfg.DSN, cfg.AppName, cfg.ConnAttributes)
+	}
+	return cfg.DSN
+}
+
+// pgAppName sets application_name in a URL or keyword/value DSN.
+func pgAppName(dsn, app string) string {
+	if app == "" {
+		return dsn
+	}
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return dsn // let the driver report it
+		}
+		q := u.Query()
+		if q.Get("application_name") != "" {
+			return dsn
+		}
+		q.Set("application_name", app)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	if strings.Contains(dsn, "application_name=") {
+		return dsn
+	}
+	quoted := "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(app) + "'"
+	return strings.TrimSpace(dsn + " application_name=" + quoted)
+}
+
+// mysqlConnAttributes adds the connectionAttributes parameter, program_name
+// first, to a go-sql-driver/mysql DSN.
+func mysqlConnAttributes(dsn, app string, attrs map[string]string) string {
+	if strings.Contains(dsn, "connectionAttributes=") {
+		return dsn
+	}
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		if k != "program_name" || app == "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var pairs []string
+	if app != "" {
+		pairs = append(pairs, "program_name:"+app)
+	}
+	for _, k := range keys {
+		pairs = append(pairs, k+":"+attrs[k])
+	}
+	sep := "?"
+	if i := strings.LastIndex(dsn, "/"); strings.Contains(dsn[max(i, 0):], "?") {
+		sep = "&"
+	}
+	return dsn + sep + "connectionAttributes=" + url.QueryEscape(strings.Join(pairs, ","))
+}
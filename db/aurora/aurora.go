@@ -0,0 +1,283 @@
+// Package aurora shortens the failover blackout of Amazon Aurora clusters
+// (PostgreSQL and MySQL flavours).
+//
+// After an Aurora failover the cluster endpoint's DNS moves to the promoted
+// instance within seconds, but pooled connections stay attached to the old
+// writer, which comes back as a reader: every write fails with a read-only
+// error until those connections time out or are recycled, often for minutes.
+//
+// Open wraps the driver so each pooled connection remembers the topology it
+// was dialled in. A Cluster watches the instance behind its pool — polling
+// its role and instance id, and reacting at once to read-only errors from
+// any statement — and on a change retires every existing connection, so the
+// pool redials through the cluster endpoint and reaches the new writer.
+//
+//	c, err := aurora.Open(db.Config{
+//	    DriverName: "pgx",
+//	    DSN:        "host=app.cluster-xyz.eu-west-1.rds.amazonaws.com ...",
+//	}, aurora.Options{})
+//	go c.Run(ctx)
+//	users := repo.NewUserRepo(c.DB)
+package aurora
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Role is the part an instance plays in the cluster.
+type Role int
+
+const (
+	Writer Role = iota
+	Reader
+)
+
+func (r Role) String() string {
+	if r == Reader {
+		return "reader"
+	}
+	return "writer"
+}
+
+// Probe reports the role and instance identifier of the server conn is
+// attached to.
+type Probe func(ctx context.Context, conn *sql.Conn) (role Role, instance string, err error)
+
+// Event describes a detected topology change.
+type Event struct {
+	// Instance and Role are what the pool is attached to now; Previous is
+	// the instance before the change, empty on a read-only error.
+	Instance, Previous string
+	Role               Role
+	// Cause is the statement error that triggered the change, if any.
+	Cause error
+	At    time.Time
+}
+
+// Options configures Open. Every field is optional.
+type Options struct {
+	// Role is what the pool expects to talk to: Writer (default) for the
+	// cluster endpoint, Reader for the reader endpoint. A Writer pool that
+	// finds itself on a reader is invalidated.
+	Role Role
+	// Interval is how often the instance is probed. Defaults to 1s.
+	Interval time.Duration
+	// Probe overrides the role query; the default one is chosen by dialect.
+	Probe Probe
+	// OnChange is called after the pool has been invalidated.
+	OnChange func(Event)
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Cluster is a DB whose pool follows Aurora failovers.
+type Cluster struct {
+	// DB is the pool; use it like any *db.DB.
+	DB *db.DB
+
+	conn    *connector
+	opts    Options
+	maxIdle int
+	driver  string
+
+	mu       sync.Mutex
+	instance string
+	role     Role
+	lastFlip time.Time
+}
+
+// Open opens cfg through a topology-aware connector. cfg.DriverName and
+// cfg.DSN are required; cfg.Connector must be unset.
+func Open(cfg db.Config, opts Options) (*Cluster, error) {
+	if cfg.Connector != nil {
+		return nil, fmt.Errorf("aurora: Config.Connector is set; pass DriverName and DSN instead")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.Probe == nil {
+		opts.Probe = probeFor(db.DialectOf(cfg.DriverName))
+		if opts.Probe == nil {
+			return nil, fmt.Errorf("aurora: no role probe for driver %q; set Options.Probe", cfg.DriverName)
+		}
+	}
+
+	conn, err := newConnector(cfg.DriverName, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("aurora: %w", err)
+	}
+	c := &Cluster{conn: conn, opts: opts, role: opts.Role, maxIdle: cfg.MaxIdleConns, driver: cfg.DriverName}
+	if c.maxIdle <= 0 {
+		c.maxIdle = 2 // database/sql's default
+	}
+	cfg.Connector = conn
+	cfg.Hooks = append(cfg.Hooks[:len(cfg.Hooks):len(cfg.Hooks)], failoverHook{c})
+	if c.DB, err = db.Open(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run probes the instance every Interval until ctx is cancelled. Probe errors
+// are logged; during a failover the instance is briefly unreachable.
+func (c *Cluster) Run(ctx context.Context) error {
+	t := time.NewTicker(c.opts.Interval)
+	defer t.Stop()
+	for {
+		if err := c.Check(ctx); err != nil && ctx.Err() == nil {
+			c.opts.Logger.Warn("aurora: topology probe failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Check probes the instance once and invalidates the pool when the instance
+// changed or the role changed away from the expected one. A mismatch that
+// persists across probes is not acted on again: connections redialled while
+// DNS still points at the old writer are retired by the read-only errors
+// their writes get.
+func (c *Cluster) Check(ctx context.Context) error {
+	conn, err := c.DB.Raw().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	role, instance, err := c.opts.Probe(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	prev, prevRole := c.instance, c.role
+	changed := prev != "" && instance != prev
+	flipped := role != prevRole && role != c.opts.Role
+	c.instance, c.role = instance, role
+	c.mu.Unlock()
+
+	if changed || flipped {
+		c.invalidate(Event{Instance: instance, Previous: prev, Role: role, At: time.Now()})
+	}
+	return nil
+}
+
+// Topology returns the instance and role seen by the last probe.
+func (c *Cluster) Topology() (instance string, role Role) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.instance, c.role
+}
+
+// Invalidate retires every pooled connection: idle ones are closed now and
+// busy ones when they are returned.
+func (c *Cluster) Invalidate() {
+	c.invalidate(Event{At: time.Now()})
+}
+
+func (c *Cluster) invalidate(ev Event) {
+	c.mu.Lock()
+	// A burst of read-only errors from connections of the same generation
+	// should cost one flip, not one per statement.
+	if ev.Cause != nil && time.Since(c.lastFlip) < c.opts.Interval {
+		c.mu.Unlock()
+		return
+	}
+	c.lastFlip = ev.At
+	c.mu.Unlock()
+
+	c.conn.invalidate()
+	raw := c.DB.Raw()
+	raw.SetMaxIdleConns(0) // closes idle connections
+	raw.SetMaxIdleConns(c.maxIdle)
+
+	c.opts.Logger.Warn("aurora: topology changed; pool invalidated",
+		"instance", ev.Instance, "previous", ev.Previous, "role", ev.Role, "cause", ev.Cause)
+	db.Events().Publish(db.Event{
+		Kind: db.EventFailover, At: ev.At, Driver: c.driver, Err: ev.Cause,
+		Attrs: map[string]any{"instance": ev.Instance, "previous": ev.Previous, "role": ev.Role.String()},
+	})
+	if c.opts.OnChange != nil {
+		c.opts.OnChange(ev)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Read-only error detection
+// ─────────────────────────────────────────────────────────────────────────────
+
+// failoverHook invalidates the pool as soon as a write lands on an instance
+// that has become a reader, without waiting for the next probe.
+type failoverHook struct{ c *Cluster }
+
+func (failoverHook) BeforeQuery(context.Context, string, []any) {}
+
+func (h failoverHook) AfterQuery(_ context.Context, _ string, _ []any, _ time.Duration, err error) {
+	if err != nil && h.c.opts.Role == Writer && IsReadOnlyError(err) {
+		h.c.invalidate(Event{Role: Reader, Cause: err, At: time.Now()})
+	}
+}
+
+// IsReadOnlyError reports whether err says the server refused a write
+// because it is read-only: PostgreSQL SQLSTATE 25006, MySQL errors 1290 and
+// 1836.
+func IsReadOnlyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	type sqlState interface{ SQLState() string }
+	var ss sqlState
+	if errors.As(err, &ss) && ss.SQLState() == "25006" {
+		return true
+	}
+	s := err.Error()
+	return strings.Contains(s, "read-only transaction") || // PostgreSQL
+		strings.Contains(s, "SQLSTATE 25006") ||
+		strings.Contains(s, "--read-only option") || // MySQL 1290
+		strings.Contains(s, "Error 1290") ||
+		strings.Contains(s, "Error 1836")
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Default probes
+// ─────────────────────────────────────────────────────────────────────────────
+
+func probeFor(d db.Dialect) Probe {
+	switch d {
+	case db.DialectPostgres:
+		return queryProbe(`SELECT pg_is_in_recovery(), aurora_db_instance_identifier()`)
+	case db.DialectMySQL:
+		return queryProbe(`SELECT @@innodb_read_only = 1, @@aurora_server_id`)
+	}
+	return nil
+}
+
+func queryProbe(query string) Probe {
+	return func(ctx context.Context, conn *sql.Conn) (Role, string, error) {
+		var (
+			readOnly bool
+			instance string
+		)
+		if err := conn.QueryRowContext(ctx, query).Scan(&readOnly, &instance); err != nil {
+			return Writer, "", err
+		}
+		if readOnly {
+			return Reader, instance, nil
+		}
+		return Writer, instance, nil
+	}
+}
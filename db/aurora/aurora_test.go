@@ -0,0 +1,96 @@
+package aurora_test
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/aurora"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestCluster_Failover(t *testing.T) {
+	var instance atomic.Value
+	instance.Store("writer-a")
+	var role atomic.Int32 // aurora.Role
+	var events []aurora.Event
+
+	c, err := aurora.Open(db.Config{DriverName: "sqlite3", DSN: t.TempDir() + "/cluster.db"}, aurora.Options{
+		Interval: time.Millisecond,
+		Probe: func(ctx context.Context, conn *sql.Conn) (aurora.Role, string, error) {
+			return aurora.Role(role.Load()), instance.Load().(string), conn.PingContext(ctx)
+		},
+		OnChange: func(ev aurora.Event) { events = append(events, ev) },
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer c.DB.Close()
+	ctx := context.Background()
+
+	if _, err := c.DB.Exec(ctx, `CREATE TABLE t (id INTEGER)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if err := c.Check(ctx); err != nil || len(events) != 0 {
+		t.Fatalf("steady state: events = %v, err = %v", events, err)
+	}
+	if n := c.DB.Stats().OpenConnections; n == 0 {
+		t.Fatal("expected pooled connections")
+	}
+
+	// The writer moved: every pooled connection is retired.
+	instance.Store("writer-b")
+	if err := c.Check(ctx); err != nil {
+		t.Fatalf("check: %v", err)
+	}
+	if len(events) != 1 || events[0].Previous != "writer-a" || events[0].Instance != "writer-b" {
+		t.Fatalf("events = %+v", events)
+	}
+	if n := c.DB.Stats().OpenConnections; n != 0 {
+		t.Fatalf("open connections after failover = %d, want 0", n)
+	}
+	if got, _ := c.Topology(); got != "writer-b" {
+		t.Fatalf("topology = %q", got)
+	}
+	// The pool redials transparently.
+	if _, err := c.DB.Exec(ctx, `INSERT INTO t (id) VALUES (1)`); err != nil {
+		t.Fatalf("insert after failover: %v", err)
+	}
+
+	// A read-only error invalidates immediately, without a probe.
+	time.Sleep(2 * time.Millisecond)
+	_, err = c.DB.Exec(ctx, `CREATE TRIGGER ro BEFORE INSERT ON t BEGIN
+		SELECT RAISE(ABORT, 'cannot execute INSERT in a read-only transaction'); END`)
+	if err != nil {
+		t.Fatalf("trigger: %v", err)
+	}
+	if _, err := c.DB.Exec(ctx, `INSERT INTO t (id) VALUES (2)`); !aurora.IsReadOnlyError(err) {
+		t.Fatalf("expected read-only error, got %v", err)
+	}
+	if len(events) != 2 || events[1].Cause == nil {
+		t.Fatalf("events = %+v", events)
+	}
+
+	// A probe finding a reader invalidates once, not on every probe while
+	// the mismatch lasts.
+	role.Store(int32(aurora.Reader))
+	for range 3 {
+		if err := c.Check(ctx); err != nil {
+			t.Fatalf("check: %v", err)
+		}
+	}
+	if len(events) != 3 || events[2].Role != aurora.Reader {
+		t.Fatalf("events = %+v", events)
+	}
+	role.Store(int32(aurora.Writer))
+	if err := c.Check(ctx); err != nil || len(events) != 3 {
+		t.Fatalf("back to writer: events = %+v, err = %v", events, err)
+	}
+	role.Store(int32(aurora.Reader))
+	if err := c.Check(ctx); err != nil || len(events) != 4 {
+		t.Fatalf("reader again: events = %+v, err = %v", events, err)
+	}
+}
@@ -0,0 +1,145 @@
+package aurora
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Generation-tagged connector
+// ─────────────────────────────────────────────────────────────────────────────
+
+// connector dials through the wrapped driver and tags every connection with
+// the topology generation it was opened in. Bumping the generation makes
+// database/sql discard older connections the next time it checks one in or
+// out, so the pool redials through the cluster endpoint — whose DNS now
+// points at the new writer — instead of waiting for TCP or lifetime timeouts.
+type connector struct {
+	inner driver.Connector
+	gen   atomic.Uint64
+}
+
+// newConnector wraps the connector database/sql would use for name and dsn.
+func newConnector(name, dsn string) (*connector, error) {
+	probe, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	drv := probe.Driver()
+	_ = probe.Close()
+
+	var inner driver.Connector
+	if dc, ok := drv.(driver.DriverContext); ok {
+		if inner, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	} else {
+		inner = dsnConnector{drv: drv, dsn: dsn}
+	}
+	return &connector{inner: inner}, nil
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	gen := c.gen.Load()
+	dc, err := c.inner.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, c: c, gen: gen}, nil
+}
+
+func (c *connector) Driver() driver.Driver { return c.inner.Driver() }
+
+// invalidate retires every connection opened so far.
+func (c *connector) invalidate() { c.gen.Add(1) }
+
+type dsnConnector struct {
+	drv driver.Driver
+	dsn string
+}
+
+func (d dsnConnector) Connect(context.Context) (driver.Conn, error) { return d.drv.Open(d.dsn) }
+func (d dsnConnector) Driver() driver.Driver                        { return d.drv }
+
+// conn forwards to the driver connection. Optional interfaces the driver
+// lacks answer driver.ErrSkip or the plain equivalent, so database/sql falls
+// back exactly as it would without the wrapper.
+type conn struct {
+	driver.Conn
+	c   *connector
+	gen uint64
+}
+
+func (cn *conn) stale() bool { return cn.gen != cn.c.gen.Load() }
+
+// IsValid implements driver.Validator: stale connections are not returned to
+// the pool.
+func (cn *conn) IsValid() bool {
+	if cn.stale() {
+		return false
+	}
+	if v, ok := cn.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// ResetSession implements driver.SessionResetter: a stale idle connection is
+// discarded when it is checked out.
+func (cn *conn) ResetSession(ctx context.Context) error {
+	if cn.stale() {
+		return driver.ErrBadConn
+	}
+	if r, ok := cn.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (cn *conn) Ping(ctx context.Context) error {
+	if p, ok := cn.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (cn *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if p, ok := cn.Conn.(driver.ConnPrepareContext); ok {
+		return p.PrepareContext(ctx, query)
+	}
+	return cn.Conn.Prepare(query)
+}
+
+func (cn *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := cn.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) || opts.ReadOnly {
+		return nil, errors.New("aurora: driver does not support transaction options")
+	}
+	return cn.Conn.Begin() //nolint:staticcheck // fallback for drivers without BeginTx
+}
+
+func (cn *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if e, ok := cn.Conn.(driver.ExecerContext); ok {
+		return e.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (cn *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if q, ok := cn.Conn.(driver.QueryerContext); ok {
+		return q.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (cn *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if c, ok := cn.Conn.(driver.NamedValueChecker); ok {
+		return c.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
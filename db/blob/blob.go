@@ -0,0 +1,274 @@
+// Package blob streams large binary values in and out of the database in
+// fixed-size chunks, so serving or storing a file never holds the whole value
+// in memory.
+//
+// Reader and Writer work on an ordinary binary column (PostgreSQL BYTEA,
+// MySQL BLOB, SQLite BLOB) addressed by table, column and primary key, using
+// substring reads and append-style updates. PostgreSQL large objects (lo_*)
+// are available through LargeObject.
+//
+//	c := blob.Column{Table: "files", Column: "content", KeyColumn: "id", Key: id}
+//	r, err := blob.NewReader(ctx, database, c)
+//	if err != nil { ... }
+//	http.ServeContent(w, req, name, modTime, r)
+//
+//	w, err := blob.NewWriter(ctx, database, c)
+//	if err != nil { ... }
+//	if _, err := io.Copy(w, upload); err != nil { ... }
+//	err = w.Close()
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// DefaultChunkSize is the number of bytes moved per statement unless a
+// different size is passed to the constructors.
+const DefaultChunkSize = 256 << 10
+
+// Column identifies one binary cell. Table, Column and KeyColumn are
+// identifiers written by the developer; they are quoted but never bound.
+type Column struct {
+	Table     string
+	Column    string
+	KeyColumn string
+	Key       any
+}
+
+// statements returns the dialect-specific SQL for c.
+func (c Column) statements(d db.Dialect) (length, read, reset, appendTo string, err error) {
+	t, col, key := d.QuoteIdent(c.Table), d.QuoteIdent(c.Column), d.QuoteIdent(c.KeyColumn)
+	switch d {
+	case db.DialectPostgres:
+		length = fmt.Sprintf(`SELECT octet_length(%s) FROM %s WHERE %s = $1`, col, t, key)
+		read = fmt.Sprintf(`SELECT substring(%s FROM $1 FOR $2) FROM %s WHERE %s = $3`, col, t, key)
+		reset = fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s = $2`, t, col, key)
+		appendTo = fmt.Sprintf(`UPDATE %s SET %s = %s || $1 WHERE %s = $2`, t, col, col, key)
+	case db.DialectMySQL:
+		length = fmt.Sprintf("SELECT LENGTH(%s) FROM %s WHERE %s = ?", col, t, key)
+		read = fmt.Sprintf("SELECT SUBSTRING(%s, ?, ?) FROM %s WHERE %s = ?", col, t, key)
+		reset = fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", t, col, key)
+		appendTo = fmt.Sprintf("UPDATE %s SET %s = CONCAT(%s, ?) WHERE %s = ?", t, col, col, key)
+	case db.DialectSQLite:
+		// || yields TEXT in SQLite; the casts keep the value a BLOB so
+		// length and substr count bytes.
+		length = fmt.Sprintf(`SELECT length(CAST(%s AS BLOB)) FROM %s WHERE %s = $1`, col, t, key)
+		read = fmt.Sprintf(`SELECT substr(CAST(%s AS BLOB), $1, $2) FROM %s WHERE %s = $3`, col, t, key)
+		reset = fmt.Sprintf(`UPDATE %s SET %s = $1 WHERE %s = $2`, t, col, key)
+		appendTo = fmt.Sprintf(`UPDATE %s SET %s = CAST(%s || $1 AS BLOB) WHERE %s = $2`, t, col, col, key)
+	default:
+		err = fmt.Errorf("sqltoolkit/blob: unsupported dialect %q", d)
+	}
+	return
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Reader
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Reader reads a binary column chunk by chunk. It implements io.Reader,
+// io.Seeker and io.ReaderAt; each chunk is one query, so a Reader should not
+// outlive the request that created it. The value is not snapshotted:
+// concurrent updates to the row are visible between chunks unless q is a
+// transaction with suitable isolation.
+type Reader struct {
+	ctx  context.Context
+	q    db.Querier
+	key  any
+	size int64
+	off  int64
+
+	sqlRead string
+	chunk   int
+	mu      sync.Mutex // guards the chunk cache for concurrent ReadAt
+	buf     []byte
+	bufOff  int64
+}
+
+// NewReader returns a Reader for c, reading chunkSize bytes per query
+// (DefaultChunkSize when omitted or <= 0). It returns db.ErrNotFound when the
+// row does not exist; a NULL value reads as empty.
+func NewReader(ctx context.Context, q db.Querier, c Column, chunkSize ...int) (*Reader, error) {
+	sqlLength, sqlRead, _, _, err := c.statements(db.DialectFrom(q))
+	if err != nil {
+		return nil, err
+	}
+	var size *int64
+	if err := q.QueryRow(ctx, sqlLength, c.Key).Scan(&size); err != nil {
+		return nil, err
+	}
+	r := &Reader{ctx: ctx, q: q, key: c.Key, sqlRead: sqlRead, chunk: chunkSizeOf(chunkSize)}
+	if size != nil {
+		r.size = *size
+	}
+	return r, nil
+}
+
+// Size returns the length of the value when the Reader was created.
+func (r *Reader) Size() int64 { return r.size }
+
+// Read implements io.Reader.
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.off)
+	r.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements io.ReaderAt. The most recent chunk is cached, so small
+// sequential reads cost one query per chunk.
+func (r *Reader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("sqltoolkit/blob: negative offset")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+		if pos < r.bufOff || pos >= r.bufOff+int64(len(r.buf)) {
+			if err := r.fill(pos); err != nil {
+				return n, err
+			}
+			if len(r.buf) == 0 { // value shrank since NewReader
+				return n, io.ErrUnexpectedEOF
+			}
+		}
+		n += copy(p[n:], r.buf[pos-r.bufOff:])
+	}
+	return n, nil
+}
+
+func (r *Reader) fill(pos int64) error {
+	// SQL substring offsets are 1-based.
+	var chunk []byte
+	if err := r.q.QueryRow(r.ctx, r.sqlRead, pos+1, r.chunk, r.key).Scan(&chunk); err != nil {
+		return err
+	}
+	r.buf, r.bufOff = chunk, pos
+	return nil
+}
+
+// Seek implements io.Seeker.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("sqltoolkit/blob: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("sqltoolkit/blob: negative position")
+	}
+	r.off = offset
+	return offset, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Writer
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Writer replaces a binary column with the bytes written to it, appending one
+// chunk per statement. The row must already exist. Pass a *db.Tx as q to
+// make the replacement atomic; otherwise readers may observe a partially
+// written value.
+type Writer struct {
+	ctx       context.Context
+	q         db.Querier
+	key       any
+	sqlAppend string
+	buf       []byte
+	err       error
+	closed    bool
+}
+
+// NewWriter empties c and returns a Writer appending chunkSize bytes per
+// statement (DefaultChunkSize when omitted or <= 0). It returns
+// db.ErrNotFound when the row does not exist.
+func NewWriter(ctx context.Context, q db.Querier, c Column, chunkSize ...int) (*Writer, error) {
+	sqlLength, _, sqlReset, sqlAppend, err := c.statements(db.DialectFrom(q))
+	if err != nil {
+		return nil, err
+	}
+	// Check existence with a read: MySQL reports 0 affected rows when the
+	// reset leaves an already empty value unchanged.
+	var size *int64
+	if err := q.QueryRow(ctx, sqlLength, c.Key).Scan(&size); err != nil {
+		return nil, err
+	}
+	if _, err := q.Exec(ctx, sqlReset, []byte{}, c.Key); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		ctx:       ctx,
+		q:         q,
+		key:       c.Key,
+		sqlAppend: sqlAppend,
+		buf:       make([]byte, 0, chunkSizeOf(chunkSize)),
+	}, nil
+}
+
+// Write implements io.Writer. Errors are sticky: once a chunk fails, every
+// later call returns the same error.
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("sqltoolkit/blob: write after Close")
+	}
+	n := 0
+	for w.err == nil && n < len(p) {
+		k := copy(w.buf[len(w.buf):cap(w.buf)], p[n:])
+		w.buf = w.buf[:len(w.buf)+k]
+		n += k
+		if len(w.buf) == cap(w.buf) {
+			w.flush()
+		}
+	}
+	return n, w.err
+}
+
+func (w *Writer) flush() {
+	if len(w.buf) == 0 || w.err != nil {
+		return
+	}
+	if _, err := w.q.Exec(w.ctx, w.sqlAppend, w.buf, w.key); err != nil {
+		w.err = err
+	}
+	w.buf = w.buf[:0]
+}
+
+// Close writes any buffered bytes. The value is complete only if Close
+// returns nil.
+func (w *Writer) Close() error {
+	if !w.closed {
+		w.flush()
+		w.closed = true
+	}
+	return w.err
+}
+
+func chunkSizeOf(opt []int) int {
+	if len(opt) > 0 && opt[0] > 0 {
+		return opt[0]
+	}
+	return DefaultChunkSize
+}
+
+var (
+	_ io.ReadSeeker  = (*Reader)(nil)
+	_ io.ReaderAt    = (*Reader)(nil)
+	_ io.WriteCloser = (*Writer)(nil)
+)
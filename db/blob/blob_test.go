@@ -0,0 +1,74 @@
+package blob_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/blob"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestReaderWriter_RoundTrip(t *testing.T) {
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE files (id INTEGER PRIMARY KEY, content BLOB)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO files (id) VALUES (1)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// Includes NUL and invalid UTF-8 bytes, which must survive SQLite's ||.
+	want := make([]byte, 10_000)
+	for i := range want {
+		want[i] = byte(i * 7)
+	}
+	c := blob.Column{Table: "files", Column: "content", KeyColumn: "id", Key: 1}
+
+	w, err := blob.NewWriter(ctx, d, c, 1024)
+	if err != nil {
+		t.Fatalf("writer: %v", err)
+	}
+	if _, err := io.Copy(w, bytes.NewReader(want)); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	r, err := blob.NewReader(ctx, d, c, 999)
+	if err != nil {
+		t.Fatalf("reader: %v", err)
+	}
+	if r.Size() != int64(len(want)) {
+		t.Fatalf("size = %d, want %d", r.Size(), len(want))
+	}
+	got, err := io.ReadAll(r)
+	if err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("read back %d bytes (err %v), content equal: %v", len(got), err, bytes.Equal(got, want))
+	}
+
+	if _, err := r.Seek(-100, io.SeekEnd); err != nil {
+		t.Fatalf("seek: %v", err)
+	}
+	tail, _ := io.ReadAll(r)
+	if !bytes.Equal(tail, want[len(want)-100:]) {
+		t.Fatal("tail after seek mismatch")
+	}
+
+	missing := c
+	missing.Key = 2
+	if _, err := blob.NewReader(ctx, d, missing); !db.IsNotFound(err) {
+		t.Fatalf("missing row: err = %v, want ErrNotFound", err)
+	}
+	if _, err := blob.NewWriter(ctx, d, missing); !db.IsNotFound(err) {
+		t.Fatalf("missing row: err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,120 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// PostgreSQL large objects
+// ─────────────────────────────────────────────────────────────────────────────
+
+// errNoLO is returned by the large object helpers on non-PostgreSQL databases.
+var errNoLO = errors.New("sqltoolkit/blob: large objects require PostgreSQL")
+
+// Large object open modes, as defined by libpq (INV_READ, INV_WRITE).
+const (
+	ModeRead      = 0x40000
+	ModeWrite     = 0x20000
+	ModeReadWrite = ModeRead | ModeWrite
+)
+
+// LargeObject is an open PostgreSQL large object, accessed through the
+// server-side lo_* functions. Descriptors are only valid inside the
+// transaction that opened them, so every method runs on that *db.Tx; Close
+// before the transaction ends.
+type LargeObject struct {
+	ctx context.Context
+	tx  *db.Tx
+	fd  int32
+}
+
+// CreateLargeObject creates an empty large object and returns its OID, to be
+// stored in an OID column referencing it.
+func CreateLargeObject(ctx context.Context, tx *db.Tx) (uint32, error) {
+	if tx.Dialect() != db.DialectPostgres {
+		return 0, errNoLO
+	}
+	var oid int64
+	err := tx.QueryRow(ctx, `SELECT lo_create(0)`).Scan(&oid)
+	return uint32(oid), err
+}
+
+// OpenLargeObject opens the large object oid with mode (ModeRead, ModeWrite
+// or ModeReadWrite).
+func OpenLargeObject(ctx context.Context, tx *db.Tx, oid uint32, mode int) (*LargeObject, error) {
+	if tx.Dialect() != db.DialectPostgres {
+		return nil, errNoLO
+	}
+	var fd int32
+	if err := tx.QueryRow(ctx, `SELECT lo_open($1, $2)`, int64(oid), mode).Scan(&fd); err != nil {
+		return nil, err
+	}
+	return &LargeObject{ctx: ctx, tx: tx, fd: fd}, nil
+}
+
+// UnlinkLargeObject deletes the large object oid. Deleting the row that
+// references it does not; unlink it in the same transaction.
+func UnlinkLargeObject(ctx context.Context, tx *db.Tx, oid uint32) error {
+	if tx.Dialect() != db.DialectPostgres {
+		return errNoLO
+	}
+	_, err := tx.Exec(ctx, `SELECT lo_unlink($1)`, int64(oid))
+	return err
+}
+
+// Read implements io.Reader.
+func (lo *LargeObject) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if len(p) > DefaultChunkSize {
+		p = p[:DefaultChunkSize]
+	}
+	var chunk []byte
+	if err := lo.tx.QueryRow(lo.ctx, `SELECT loread($1, $2)`, lo.fd, len(p)).Scan(&chunk); err != nil {
+		return 0, err
+	}
+	if len(chunk) == 0 {
+		return 0, io.EOF
+	}
+	return copy(p, chunk), nil
+}
+
+// Write implements io.Writer.
+func (lo *LargeObject) Write(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		end := min(n+DefaultChunkSize, len(p))
+		var written int
+		if err := lo.tx.QueryRow(lo.ctx, `SELECT lowrite($1, $2)`, lo.fd, p[n:end]).Scan(&written); err != nil {
+			return n, err
+		}
+		n += written
+	}
+	return n, nil
+}
+
+// Seek implements io.Seeker.
+func (lo *LargeObject) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	err := lo.tx.QueryRow(lo.ctx, `SELECT lo_lseek64($1, $2, $3)`, lo.fd, offset, whence).Scan(&pos)
+	return pos, err
+}
+
+// Truncate cuts or extends the object to size bytes. It requires ModeWrite.
+func (lo *LargeObject) Truncate(size int64) error {
+	_, err := lo.tx.Exec(lo.ctx, `SELECT lo_truncate64($1, $2)`, lo.fd, size)
+	return err
+}
+
+// Close releases the descriptor.
+func (lo *LargeObject) Close() error {
+	_, err := lo.tx.Exec(lo.ctx, `SELECT lo_close($1)`, lo.fd)
+	return err
+}
+
+var _ io.ReadWriteSeeker = (*LargeObject)(nil)
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Booleans — one representation across drivers
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Bool is a bool column that scans the same on every driver: PostgreSQL
+// boolean, MySQL TINYINT(1) and BIT(1), and SQLite integers or text all
+// become true or false. NULL scans as false; use sql.Null[Bool] to keep it.
+// It is written as a Go bool, which every supported driver accepts.
+//
+//	type User struct {
+//	    Active db.Bool
+//	}
+//	if u.Active { ... }
+type Bool bool
+
+// Scan implements sql.Scanner.
+func (b *Bool) Scan(src any) error {
+	if src == nil {
+		*b = false
+		return nil
+	}
+	v, err := ParseBool(src)
+	if err != nil {
+		return err
+	}
+	*b = Bool(v)
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (b Bool) Value() (driver.Value, error) { return bool(b), nil }
+
+// ParseBool converts a value returned by a driver for a boolean column to a
+// bool: bool itself, integers and floats (non-zero is true), the single
+// bytes MySQL returns for BIT(1), and the strings "1"/"0", "t"/"f",
+// "true"/"false", "y"/"n", "yes"/"no" and "on"/"off" in any case.
+func ParseBool(src any) (bool, error) {
+	switch v := src.(type) {
+	case bool:
+		return v, nil
+	case int64:
+		return v != 0, nil
+	case int:
+		return v != 0, nil
+	case int32:
+		return v != 0, nil
+	case uint64:
+		return v != 0, nil
+	case float64:
+		return v != 0, nil
+	case []byte:
+		if len(v) == 1 && v[0] <= 1 { // BIT(1)
+			return v[0] == 1, nil
+		}
+		return parseBoolString(string(v))
+	case string:
+		return parseBoolString(v)
+	}
+	return false, fmt.Errorf("sqltoolkit/db: cannot convert %T to bool", src)
+}
+
+func parseBoolString(s string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "t", "true", "y", "yes", "on":
+		return true, nil
+	case "0", "f", "false", "n", "no", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("sqltoolkit/db: cannot convert %q to bool", s)
+}
+
+// boolColumns reports which result columns hold booleans by their database
+// type. MySQL reports TINYINT(1) as TINYINT, so those columns are not
+// detected; scan them into Bool. BIT(n) holds an n-bit number, so BIT
+// columns count only when the driver reports a length of 1 or no length at
+// all; Stream leaves values of the latter that are not booleans unchanged.
+func boolColumns(types []*sql.ColumnType) []bool {
+	var flags []bool
+	for i, ct := range types {
+		switch strings.ToUpper(ct.DatabaseTypeName()) {
+		case "BIT":
+			if n, ok := ct.Length(); ok && n != 1 {
+				continue
+			}
+			fallthrough
+		case "BOOL", "BOOLEAN":
+			if flags == nil {
+				flags = make([]bool, len(types))
+			}
+			flags[i] = true
+		}
+	}
+	return flags
+}
+
+// boolNormalizer is implemented by *DB and *Tx.
+type boolNormalizer interface{ normalizeBools() bool }
+
+func (d *DB) normalizeBools() bool { return d.cfg.NormalizeBools }
+func (t *Tx) normalizeBools() bool { return t.cfg.NormalizeBools }
+
+func normalizesBools(q Querier) bool {
+	n, ok := q.(boolNormalizer)
+	return ok && n.normalizeBools()
+}
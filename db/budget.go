@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Query budget — cap the number of statements per request
+// ─────────────────────────────────────────────────────────────────────────────
+
+type budgetKey struct{}
+
+type queryBudget struct {
+	max    int64
+	soft   bool
+	used   atomic.Int64
+	logged atomic.Bool
+}
+
+// WithQueryBudget returns a context that allows at most max statements to be
+// executed through it (DB, Tx and Stmt calls alike). Further statements fail
+// with ErrBudgetExceeded before reaching the database, and the first overrun
+// is logged with its call site.
+//
+// Install it in HTTP middleware or test setup to turn accidental N+1 loops
+// into failures:
+//
+//	ctx = db.WithQueryBudget(r.Context(), 20)
+func WithQueryBudget(ctx context.Context, max int) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &queryBudget{max: int64(max)})
+}
+
+// WithSoftQueryBudget is like WithQueryBudget but only logs the first overrun;
+// statements keep executing. Use it to find offenders in production before
+// enforcing a limit.
+func WithSoftQueryBudget(ctx context.Context, max int) context.Context {
+	return context.WithValue(ctx, budgetKey{}, &queryBudget{max: int64(max), soft: true})
+}
+
+// QueryBudgetUsed reports how many statements ran under ctx's budget and its
+// limit. ok is false when ctx carries no budget.
+func QueryBudgetUsed(ctx context.Context) (used, max int, ok bool) {
+	b, _ := ctx.Value(budgetKey{}).(*queryBudget)
+	if b == nil {
+		return 0, 0, false
+	}
+	return int(b.used.Load()), int(b.max), true
+}
+
+// spend charges one statement against the budget.
+func (b *queryBudget) spend(ctx context.Context, query string) error {
+	n := b.used.Add(1)
+	if n <= b.max {
+		return nil
+	}
+	if b.logged.CompareAndSwap(false, true) {
+		slog.WarnContext(ctx, "sqltoolkit/db: query budget exceeded",
+			slog.Int64("max", b.max),
+			slog.String("query", Fingerprint(query)),
+			slog.String("caller", externalCaller()),
+			slog.Bool("enforced", !b.soft),
+		)
+	}
+	if b.soft {
+		return nil
+	}
+	return &DBError{
+		Sentinel: ErrBudgetExceeded,
+		Cause:    fmt.Errorf("statement %d exceeds limit of %d", n, b.max),
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// preflight — checks that may refuse a statement before it is sent
+// ─────────────────────────────────────────────────────────────────────────────
+
+// preflight runs every context-scoped guard for query. A non-nil error means
+// the statement must not reach the driver; hooks are not invoked for it.
+func preflight(ctx context.Context, query string) error {
+	if err := checkReadOnly(query); err != nil {
+		return err
+	}
+	if b, _ := ctx.Value(budgetKey{}).(*queryBudget); b != nil {
+		if err := b.spend(ctx, query); err != nil {
+			return err
+		}
+	}
+	return waitMaintenance(ctx)
+}
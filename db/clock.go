@@ -0,0 +1,81 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Clock — injectable time source
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Clock is the time source behind DB.Now, Tx.Now and NowFrom.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock reads the wall clock.
+type SystemClock struct{}
+
+// Now implements Clock.
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// FakeClock is a Clock that only moves when told to, for tests asserting on
+// stored timestamps:
+//
+//	clock := db.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	conn := db.MustOpen(db.Config{..., Clock: clock})
+//	u, _ := repo.NewUserRepo(conn).Insert(ctx, params) // u.CreatedAt == 2024-01-01
+//	clock.Advance(time.Hour)
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a FakeClock frozen at t.
+func NewFakeClock(t time.Time) *FakeClock { return &FakeClock{now: t} }
+
+// Now implements Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to t.
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.now = t
+	c.mu.Unlock()
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// Now returns the current time according to Config.Clock.
+func (d *DB) Now() time.Time { return clockOf(d.cfg).Now() }
+
+// Now returns the current time according to the Config.Clock of the DB the
+// transaction was started on.
+func (t *Tx) Now() time.Time { return clockOf(t.cfg).Now() }
+
+// NowFrom returns q's current time when q exposes a clock (*DB and *Tx do),
+// and the system time otherwise. Repositories stamp rows with it instead of
+// calling time.Now, so tests can freeze time through Config.Clock.
+func NowFrom(q Querier) time.Time {
+	if c, ok := q.(Clock); ok {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func clockOf(cfg Config) Clock {
+	if cfg.Clock != nil {
+		return cfg.Clock
+	}
+	return SystemClock{}
+}
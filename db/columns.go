@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Columns — result set metadata
+// ─────────────────────────────────────────────────────────────────────────────
+
+// ColumnInfo describes one column of a result set as the driver reports it.
+// Drivers differ in what they know: lib/pq and pgx report no nullability,
+// SQLite reports the declared type of table columns only, and lengths and
+// decimal sizes exist only for the types that have them.
+type ColumnInfo struct {
+	Name string `json:"name"`
+	// DatabaseType is the driver's upper-case type name, e.g. "VARCHAR",
+	// "INT8", "DECIMAL"; empty when unknown.
+	DatabaseType string `json:"database_type"`
+	// Type is the family of DatabaseType, as used by ValidateSchema.
+	Type ColumnType `json:"type"`
+	// Nullable is nil when the driver does not report it.
+	Nullable *bool `json:"nullable,omitempty"`
+	// Length is the maximum length of variable-length text and binary
+	// columns; zero when not applicable or unreported.
+	Length int64 `json:"length,omitempty"`
+	// Precision and Scale size decimal columns.
+	Precision int64 `json:"precision,omitempty"`
+	Scale     int64 `json:"scale,omitempty"`
+	// ScanType is the Go type the driver scans the column into by default.
+	ScanType reflect.Type `json:"-"`
+}
+
+// Columns runs query and returns the metadata of its result columns, for
+// export tooling, admin UIs and code that scans rows it does not know in
+// advance. No row is read, but the statement does run; give costly queries
+// a LIMIT 0 when only their shape is wanted:
+//
+//	cols, err := db.Columns(ctx, q, "SELECT * FROM orders LIMIT 0")
+func Columns(ctx context.Context, q Querier, query string, args ...any) ([]ColumnInfo, error) {
+	rows, done, err := queryRows(ctx, q, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	return columnInfos(types), nil
+}
+
+func columnInfos(types []*sql.ColumnType) []ColumnInfo {
+	out := make([]ColumnInfo, len(types))
+	for i, ct := range types {
+		c := ColumnInfo{
+			Name:         ct.Name(),
+			DatabaseType: strings.ToUpper(ct.DatabaseTypeName()),
+			ScanType:     ct.ScanType(),
+		}
+		c.Type = columnTypeOf(c.DatabaseType)
+		if nullable, ok := ct.Nullable(); ok {
+			c.Nullable = &nullable
+		}
+		if length, ok := ct.Length(); ok {
+			c.Length = length
+		}
+		if precision, scale, ok := ct.DecimalSize(); ok {
+			c.Precision, c.Scale = precision, scale
+		}
+		out[i] = c
+	}
+	return out
+}
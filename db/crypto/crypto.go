@@ -0,0 +1,241 @@
+// Package crypto provides application-level column encryption.
+//
+// Encrypted[T] encrypts its value with AES-256-GCM when written and decrypts
+// it when scanned, so PII columns are opaque to the database, backups, and
+// anyone with read access to them:
+//
+//	crypto.SetKeyProvider(crypto.NewKeyRing("2024-06", map[string][]byte{
+//	    "2024-01": oldKey,
+//	    "2024-06": newKey,
+//	}))
+//
+//	type Customer struct {
+//	    ID  int64
+//	    SSN crypto.Encrypted[string]
+//	}
+//	_, err := d.Exec(ctx, "INSERT INTO customers (ssn) VALUES ($1)", c.SSN)
+//	err = d.QueryRow(ctx, "SELECT ssn FROM customers WHERE id = $1", id).Scan(&c.SSN)
+//
+// The ciphertext envelope records the id of the key that produced it, so keys
+// can be rotated without a flag day: new writes use the current key, old rows
+// stay readable, and NeedsRotation reports rows worth re-encrypting.
+//
+// Encrypted columns cannot be searched or indexed by value; store a keyed
+// hash alongside when equality lookups are needed.
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// envelopePrefix versions the ciphertext format: enc:v1:<key id>:<base64>.
+const envelopePrefix = "enc:v1:"
+
+// ErrNoKeyProvider is returned when Encrypted is used before SetKeyProvider.
+var ErrNoKeyProvider = errors.New("sqltoolkit/crypto: no key provider configured")
+
+// ─────────────────────────────────────────────────────────────────────────────
+// KeyProvider
+// ─────────────────────────────────────────────────────────────────────────────
+
+// KeyProvider supplies 32-byte AES-256 keys. Implement it on top of a KMS,
+// Vault, or environment variables.
+type KeyProvider interface {
+	// CurrentKey returns the key used for new ciphertexts and its id.
+	// The id must not contain ':'.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given id, for decrypting older values.
+	Key(id string) ([]byte, error)
+}
+
+var (
+	providerMu sync.RWMutex
+	provider   KeyProvider
+)
+
+// SetKeyProvider installs the process-wide KeyProvider used by Encrypted.
+func SetKeyProvider(p KeyProvider) {
+	providerMu.Lock()
+	defer providerMu.Unlock()
+	provider = p
+}
+
+func currentProvider() (KeyProvider, error) {
+	providerMu.RLock()
+	defer providerMu.RUnlock()
+	if provider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	return provider, nil
+}
+
+// KeyRing is a static in-memory KeyProvider.
+type KeyRing struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewKeyRing returns a KeyProvider encrypting with keys[current] and able to
+// decrypt with any key in keys.
+func NewKeyRing(current string, keys map[string][]byte) *KeyRing {
+	return &KeyRing{current: current, keys: keys}
+}
+
+// CurrentKey implements KeyProvider.
+func (k *KeyRing) CurrentKey() (string, []byte, error) {
+	key, err := k.Key(k.current)
+	return k.current, key, err
+}
+
+// Key implements KeyProvider.
+func (k *KeyRing) Key(id string) ([]byte, error) {
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("sqltoolkit/crypto: unknown key id %q", id)
+	}
+	return key, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Encrypted[T]
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Encrypted holds a T that is stored encrypted. T is serialised with
+// encoding/json before encryption. A NULL column scans as the zero value with
+// Valid false; a zero Encrypted with Valid false is written as NULL.
+type Encrypted[T any] struct {
+	V     T
+	Valid bool
+
+	keyID string // key that produced the scanned ciphertext
+}
+
+// Encrypt returns a valid Encrypted holding v.
+func Encrypt[T any](v T) Encrypted[T] { return Encrypted[T]{V: v, Valid: true} }
+
+// Value implements driver.Valuer, producing the ciphertext envelope.
+func (e Encrypted[T]) Value() (driver.Value, error) {
+	if !e.Valid {
+		return nil, nil
+	}
+	p, err := currentProvider()
+	if err != nil {
+		return nil, err
+	}
+	id, key, err := p.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(id, ":") {
+		return nil, fmt.Errorf("sqltoolkit/crypto: key id %q must not contain ':'", id)
+	}
+	plain, err := json.Marshal(e.V)
+	if err != nil {
+		return nil, fmt.Errorf("sqltoolkit/crypto: marshal: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	// The key id is authenticated so an envelope cannot be relabelled.
+	sealed := aead.Seal(nonce, nonce, plain, []byte(id))
+	return envelopePrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Scan implements sql.Scanner, decrypting the ciphertext envelope.
+func (e *Encrypted[T]) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*e = Encrypted[T]{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("sqltoolkit/crypto: unsupported source type %T", src)
+	}
+
+	if !strings.HasPrefix(s, envelopePrefix) {
+		return errors.New("sqltoolkit/crypto: value is not an encryption envelope")
+	}
+	id, payload, ok := strings.Cut(s[len(envelopePrefix):], ":")
+	if !ok {
+		return errors.New("sqltoolkit/crypto: malformed envelope")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return fmt.Errorf("sqltoolkit/crypto: malformed envelope: %w", err)
+	}
+
+	p, err := currentProvider()
+	if err != nil {
+		return err
+	}
+	key, err := p.Key(id)
+	if err != nil {
+		return err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return errors.New("sqltoolkit/crypto: malformed envelope")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, []byte(id))
+	if err != nil {
+		return fmt.Errorf("sqltoolkit/crypto: decrypt: %w", err)
+	}
+
+	var v T
+	if err := json.Unmarshal(plain, &v); err != nil {
+		return fmt.Errorf("sqltoolkit/crypto: unmarshal: %w", err)
+	}
+	*e = Encrypted[T]{V: v, Valid: true, keyID: id}
+	return nil
+}
+
+// KeyID returns the id of the key that encrypted the scanned value, or "" if
+// the value was not scanned from the database.
+func (e Encrypted[T]) KeyID() string { return e.keyID }
+
+// NeedsRotation reports whether the scanned value was encrypted with a key
+// other than the provider's current key. Writing it back re-encrypts it.
+func (e Encrypted[T]) NeedsRotation() bool {
+	if e.keyID == "" {
+		return false
+	}
+	p, err := currentProvider()
+	if err != nil {
+		return false
+	}
+	id, _, err := p.CurrentKey()
+	return err == nil && id != e.keyID
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("sqltoolkit/crypto: key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,76 @@
+package crypto_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/crypto"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var (
+	oldKey = bytes.Repeat([]byte{1}, 32)
+	newKey = bytes.Repeat([]byte{2}, 32)
+)
+
+func TestEncrypted_RoundTripAndRotation(t *testing.T) {
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE secrets (id INTEGER PRIMARY KEY, ssn TEXT)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	crypto.SetKeyProvider(crypto.NewKeyRing("k1", map[string][]byte{"k1": oldKey}))
+	if _, err := d.Exec(ctx, `INSERT INTO secrets (id, ssn) VALUES (1, ?)`, crypto.Encrypt("123-45-6789")); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var raw string
+	_ = d.QueryRow(ctx, `SELECT ssn FROM secrets WHERE id = 1`).Scan(&raw)
+	if strings.Contains(raw, "6789") || !strings.HasPrefix(raw, "enc:v1:k1:") {
+		t.Fatalf("column is not encrypted: %q", raw)
+	}
+
+	// Rotate: k2 becomes current, k1 stays readable.
+	crypto.SetKeyProvider(crypto.NewKeyRing("k2", map[string][]byte{"k1": oldKey, "k2": newKey}))
+
+	var ssn crypto.Encrypted[string]
+	if err := d.QueryRow(ctx, `SELECT ssn FROM secrets WHERE id = 1`).Scan(&ssn); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if !ssn.Valid || ssn.V != "123-45-6789" {
+		t.Fatalf("unexpected value: %+v", ssn)
+	}
+	if !ssn.NeedsRotation() {
+		t.Fatal("expected value encrypted with k1 to need rotation")
+	}
+}
+
+func TestEncrypted_TamperAndNull(t *testing.T) {
+	crypto.SetKeyProvider(crypto.NewKeyRing("k1", map[string][]byte{"k1": oldKey}))
+
+	v, err := crypto.Encrypt(map[string]int{"a": 1}).Value()
+	if err != nil {
+		t.Fatalf("value: %v", err)
+	}
+	s := v.(string)
+	tampered := s[:len(s)-4] + "AAA="
+
+	var e crypto.Encrypted[map[string]int]
+	if err := e.Scan(tampered); err == nil {
+		t.Fatal("expected authentication failure on tampered ciphertext")
+	}
+	if err := e.Scan(nil); err != nil || e.Valid {
+		t.Fatalf("expected NULL to scan as invalid, got %+v err=%v", e, err)
+	}
+	if v, _ := e.Value(); v != nil {
+		t.Fatalf("expected invalid value to be written as NULL, got %v", v)
+	}
+}
@@ -6,7 +6,9 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -22,6 +24,40 @@ type Config struct {
 	// DriverName is "pgx", "postgres", "mysql", or "sqlite3".
 	DriverName string
 
+	// Connector, when set, is opened with sql.OpenDB instead of DSN, for
+	// drivers configured in code rather than by string (e.g. libsql
+	// embedded replicas). DriverName still selects the dialect.
+	Connector driver.Connector
+
+	// AppName identifies the service to the server: it becomes
+	// application_name on PostgreSQL and the program_name connection
+	// attribute on MySQL, so DBAs can attribute load in pg_stat_activity or
+	// performance_schema. ConnAttributes adds further MySQL connection
+	// attributes (team, version, ...); PostgreSQL has no equivalent. Both
+	// are added to DSN at Open, where settings already in DSN win; they are
+	// ignored with Connector. MySQL attributes need go-sql-driver/mysql
+	// 1.8 or later.
+	AppName        string
+	ConnAttributes map[string]string
+
+	// SessionSettings are applied to every new connection before the pool
+	// hands it out, so sessions behave the same whatever the server
+	// defaults: SET via set_config on PostgreSQL (search_path, timezone,
+	// lock_timeout, ...), SET SESSION on MySQL (sql_mode, time_zone, ...)
+	// and PRAGMA on SQLite (foreign_keys, busy_timeout, ...). On MySQL,
+	// numbers and keywords are written into the statement, since a bound
+	// value is always a string; other values are bound. A setting the
+	// server rejects fails the connection.
+	SessionSettings map[string]string
+
+	// DisableSQLiteForeignKeys stops Open from turning foreign key
+	// enforcement on for every SQLite connection. By default Open runs
+	// PRAGMA foreign_keys = ON on each connection, unless SessionSettings
+	// sets foreign_keys itself, and fails if SQLite does not report it on,
+	// so constraint violations behave the same whatever the driver's
+	// defaults.
+	DisableSQLiteForeignKeys bool
+
 	// Pool settings
 	MaxOpenConns    int
 	MaxIdleConns    int
@@ -29,12 +65,49 @@ type Config struct {
 	ConnMaxIdleTime time.Duration
 
 	// Default query timeout applied when no deadline is set on the context.
-	// Zero means no default timeout.
+	// Zero means no default timeout. For rows from Query, the deadline's
+	// timer is released by the garbage collector after Close (see Query).
 	DefaultTimeout time.Duration
 
+	// AdaptiveTimeout, when set, replaces DefaultTimeout for Exec and
+	// QueryRow with a deadline learned per query fingerprint. Query keeps
+	// DefaultTimeout: its deadline also covers reading the rows, which the
+	// learned latency does not include. See AdaptiveTimeout for the defaults.
+	AdaptiveTimeout *AdaptiveTimeout
+
+	// PrepareOnOpen lists statements Open prepares before returning. Open
+	// fails if any of them does not prepare against the live schema, and
+	// Exec, Query and QueryRow reuse the prepared statement whenever they
+	// run the exact same SQL text.
+	PrepareOnOpen []string
+
+	// PrepareRegistered adds every statement passed to RegisterQueries to
+	// PrepareOnOpen.
+	PrepareRegistered bool
+
 	// Hooks executed around every statement (logging, metrics, tracing).
 	// All hooks are optional; nil entries are silently skipped.
 	Hooks []Hook
+
+	// DisableRecentQueries keeps this DB's statements out of the package-level
+	// buffer read by RecentQueries.
+	DisableRecentQueries bool
+
+	// CollectQueryStats feeds this DB's statements into the package-level
+	// latency aggregator read by QueryStats.
+	CollectQueryStats bool
+
+	// Clock supplies the current time to repositories and helpers that
+	// stamp rows (see DB.Now and NowFrom). Nil means the system clock; tests
+	// set a FakeClock to freeze time.
+	Clock Clock
+
+	// NormalizeBools makes Stream return Go bools for columns the driver
+	// types as BOOL, BOOLEAN or BIT(1), instead of the integers, bytes or
+	// strings some drivers produce; values that are not booleans, such as
+	// those of a wider BIT column whose length the driver does not report,
+	// are returned unchanged. Typed scans use Bool instead.
+	NormalizeBools bool
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -52,19 +125,23 @@ type DB struct {
 	cfg     Config
 	hooks   hookChain
 	errMap  ErrorMapper
+	txs     *txRegistry
+	drv     Driver // set by OpenWithDriver; nil after Open
+	timeout *adaptiveTimeouts // nil unless Config.AdaptiveTimeout
+	stmts   map[string]*sql.Stmt // prepared at Open, keyed by SQL
 }
 
 // Open opens the database described by cfg and verifies connectivity with Ping.
 // Callers are responsible for calling Close() when the application shuts down.
 func Open(cfg Config) (*DB, error) {
-	if cfg.DSN == "" {
+	if cfg.DSN == "" && cfg.Connector == nil {
 		return nil, fmt.Errorf("sqltoolkit/db: DSN must not be empty")
 	}
 	if cfg.DriverName == "" {
 		return nil, fmt.Errorf("sqltoolkit/db: DriverName must not be empty")
 	}
 
-	sqldb, err := sql.Open(cfg.DriverName, cfg.DSN)
+	sqldb, err := openPool(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("sqltoolkit/db: open: %w", err)
 	}
@@ -83,11 +160,26 @@ func Open(cfg Config) (*DB, error) {
 		sqldb.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
 	}
 
+	hooks := cfg.Hooks
+	if !cfg.DisableRecentQueries {
+		hooks = append(hooks[:len(hooks):len(hooks)], recentQueries)
+	}
+	if cfg.CollectQueryStats {
+		hooks = append(hooks[:len(hooks):len(hooks)], queryStats)
+	}
+	var timeout *adaptiveTimeouts
+	if cfg.AdaptiveTimeout != nil {
+		timeout = newAdaptiveTimeouts(*cfg.AdaptiveTimeout, cfg.DefaultTimeout)
+		hooks = append(hooks[:len(hooks):len(hooks)], timeout)
+	}
+
 	d := &DB{
 		sqldb:  sqldb,
 		cfg:    cfg,
-		hooks:  newHookChain(cfg.Hooks),
+		hooks:  newHookChain(hooks),
 		errMap: DefaultErrorMapper(),
+		txs:    &txRegistry{},
+		timeout: timeout,
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -96,7 +188,16 @@ func Open(cfg Config) (*DB, error) {
 		_ = sqldb.Close()
 		return nil, fmt.Errorf("sqltoolkit/db: ping: %w", err)
 	}
+	if err := d.verifyForeignKeys(ctx); err != nil {
+		_ = sqldb.Close()
+		return nil, fmt.Errorf("sqltoolkit/db: %w", err)
+	}
+	if err := d.prepareOnOpen(ctx); err != nil {
+		_ = sqldb.Close()
+		return nil, fmt.Errorf("sqltoolkit/db: prepare on open: %w", err)
+	}
 
+	events.Publish(Event{Kind: EventPoolOpened, Driver: cfg.DriverName})
 	return d, nil
 }
 
@@ -113,17 +214,27 @@ func MustOpen(cfg Config) *DB {
 // Prefer the wrapper methods where possible.
 func (d *DB) Raw() *sql.DB { return d.sqldb }
 
+// Config returns the configuration d was opened with, e.g. for tooling that
+// needs a second connection to the same server.
+func (d *DB) Config() Config { return d.cfg }
+
 // SetErrorMapper replaces the default error mapper with a custom one.
 // Use this to add driver-specific error code translations.
 func (d *DB) SetErrorMapper(m ErrorMapper) { d.errMap = m }
 
 // Close closes all pooled connections and frees resources.
 // Safe to call multiple times.
-func (d *DB) Close() error { return d.sqldb.Close() }
+func (d *DB) Close() error {
+	d.closePrepared()
+	err := d.sqldb.Close()
+	events.Publish(Event{Kind: EventPoolClosed, Driver: d.cfg.DriverName, Err: err})
+	return err
+}
 
 // Ping verifies that the database is reachable.
 func (d *DB) Ping(ctx context.Context) error {
-	ctx = d.applyDefaultTimeout(ctx)
+	ctx, cancel := d.applyDefaultTimeout(ctx)
+	defer cancel()
 	return d.sqldb.PingContext(ctx)
 }
 
@@ -138,23 +249,63 @@ func (d *DB) Stats() sql.DBStats { return d.sqldb.Stats() }
 // It returns the number of rows affected and any error translated through the
 // unified error mapper.
 func (d *DB) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
-	ctx = d.applyDefaultTimeout(ctx)
+	ctx, cancel := d.applyQueryTimeout(ctx, query)
+	defer cancel()
+	if err := preflight(ctx, query); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	d.hooks.Before(ctx, query, args)
-	res, err := d.sqldb.ExecContext(ctx, query, args...)
-	err = d.mapErr(err)
-	d.hooks.After(ctx, query, args, time.Since(start), err)
+	var res sql.Result
+	var err error
+	if s := d.preparedFor(query); s != nil {
+		res, err = s.ExecContext(ctx, args...)
+	} else {
+		res, err = d.sqldb.ExecContext(ctx, query, args...)
+	}
+	err = annotate(d.mapErr(err), OpExec, query, start)
+	d.hooks.AfterExec(ctx, query, args, time.Since(start), res, err)
 	return res, err
 }
 
 // Query executes a query that returns rows.
-// The caller MUST close the returned *sql.Rows.
+// The caller MUST close the returned *sql.Rows. A deadline applied by the
+// DB covers iterating the rows too, and is released as soon as the rows are
+// closed, explicitly or by Next returning false.
 func (d *DB) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
-	ctx = d.applyDefaultTimeout(ctx)
+	ctx, cancel := d.applyDefaultTimeout(ctx)
+	rc := newCloseRelease(ctx, cancel)
+	rows, err := d.runQuery(rc, query, args)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	rc.arm()
+	return rows, nil
+}
+
+// query is Query returning the deadline's cancel func for the caller to
+// call after closing the rows, or at once when err is set and rows is nil.
+func (d *DB) query(ctx context.Context, query string, args []any) (*sql.Rows, context.CancelFunc, error) {
+	ctx, cancel := d.applyDefaultTimeout(ctx)
+	rows, err := d.runQuery(ctx, query, args)
+	return rows, cancel, err
+}
+
+func (d *DB) runQuery(ctx context.Context, query string, args []any) (*sql.Rows, error) {
+	if err := preflight(ctx, query); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	d.hooks.Before(ctx, query, args)
-	rows, err := d.sqldb.QueryContext(ctx, query, args...)
-	err = d.mapErr(err)
+	var rows *sql.Rows
+	var err error
+	if s := d.preparedFor(query); s != nil {
+		rows, err = s.QueryContext(ctx, args...)
+	} else {
+		rows, err = d.sqldb.QueryContext(ctx, query, args...)
+	}
+	err = annotate(d.mapErr(err), OpQuery, query, start)
 	d.hooks.After(ctx, query, args, time.Since(start), err)
 	return rows, err
 }
@@ -163,12 +314,90 @@ func (d *DB) Query(ctx context.Context, query string, args ...any) (*sql.Rows, e
 // Use Scan() on the returned *sql.Row; ErrNotFound is returned when no row
 // matches.
 func (d *DB) QueryRow(ctx context.Context, query string, args ...any) *Row {
-	ctx = d.applyDefaultTimeout(ctx)
+	ctx, cancel := d.applyQueryTimeout(ctx, query)
+	if err := preflight(ctx, query); err != nil {
+		cancel()
+		return &Row{err: err, errMap: d.errMap}
+	}
 	start := time.Now()
 	d.hooks.Before(ctx, query, args)
-	raw := d.sqldb.QueryRowContext(ctx, query, args...)
+	var raw *sql.Row
+	if s := d.preparedFor(query); s != nil {
+		raw = s.QueryRowContext(ctx, args...)
+	} else {
+		raw = d.sqldb.QueryRowContext(ctx, query, args...)
+	}
 	d.hooks.After(ctx, query, args, time.Since(start), nil) // err unknown until Scan
-	return &Row{raw: raw, errMap: d.errMap}
+	return &Row{raw: raw, errMap: d.errMap, query: query, start: start, cancel: cancel}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Result helpers
+// ─────────────────────────────────────────────────────────────────────────────
+
+// ExecAffected executes a statement and returns the number of rows affected.
+func (d *DB) ExecAffected(ctx context.Context, query string, args ...any) (int64, error) {
+	return execAffected(ctx, d, query, args)
+}
+
+// ExecReturningID executes an INSERT and returns the id of the new row.
+//
+// If query carries a RETURNING clause (PostgreSQL, SQLite ≥ 3.35) the single
+// returned column is scanned; otherwise the driver's LastInsertId is used
+// (MySQL, SQLite). lib/pq does not support LastInsertId, so PostgreSQL
+// callers must write the RETURNING clause themselves:
+//
+//	id, err := db.ExecReturningID(ctx,
+//	    "INSERT INTO users(name,email) VALUES($1,$2) RETURNING id", name, email)
+func (d *DB) ExecReturningID(ctx context.Context, query string, args ...any) (int64, error) {
+	return execReturningID(ctx, d, query, args)
+}
+
+func execAffected(ctx context.Context, q Querier, query string, args []any) (int64, error) {
+	res, err := q.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func execReturningID(ctx context.Context, q Querier, query string, args []any) (int64, error) {
+	if hasReturning(query) {
+		var id int64
+		if err := q.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+	res, err := q.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// hasReturning reports whether query has a RETURNING clause: the keyword as
+// a word of its own, outside strings, quoted identifiers and comments.
+func hasReturning(query string) bool {
+	for i := 0; i < len(query); {
+		if j := skipNonCode(query, i, false); j > i {
+			i = j
+			continue
+		}
+		if !isIdentByte(query[i]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(query) && (isIdentByte(query[j]) || query[j] == '$') {
+			j++
+		}
+		if strings.EqualFold(query[i:j], "RETURNING") {
+			return true
+		}
+		i = j
+	}
+	return false
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -178,10 +407,12 @@ func (d *DB) QueryRow(ctx context.Context, query string, args ...any) *Row {
 // Prepare creates a prepared statement for repeated use.
 // The caller is responsible for calling stmt.Close().
 func (d *DB) Prepare(ctx context.Context, query string) (*Stmt, error) {
-	ctx = d.applyDefaultTimeout(ctx)
+	ctx, cancel := d.applyDefaultTimeout(ctx)
+	defer cancel()
+	start := time.Now()
 	s, err := d.sqldb.PrepareContext(ctx, query)
 	if err != nil {
-		return nil, d.mapErr(err)
+		return nil, annotate(d.mapErr(err), OpPrepare, query, start)
 	}
 	return &Stmt{stmt: s, query: query, hooks: d.hooks, errMap: d.errMap}, nil
 }
@@ -205,6 +436,13 @@ func BatchExec[T any](
 	items []T,
 	argsFn func(T) []any,
 ) error {
+	// Drivers with a bulk appender (DuckDB) load positional INSERTs faster
+	// through it.
+	if ad, ok := appenderOf(d); ok {
+		if table, ok := appendTarget(query); ok {
+			return appendRows(d, ctx, ad, table, items, argsFn)
+		}
+	}
 	return d.ExecTx(ctx, func(tx *Tx) error {
 		stmt, err := tx.Prepare(ctx, query)
 		if err != nil {
@@ -225,15 +463,11 @@ func BatchExec[T any](
 // Internal helpers
 // ─────────────────────────────────────────────────────────────────────────────
 
-func (d *DB) applyDefaultTimeout(ctx context.Context) context.Context {
-	if d.cfg.DefaultTimeout == 0 {
-		return ctx
-	}
-	if _, ok := ctx.Deadline(); ok {
-		return ctx // caller already set a deadline
-	}
-	ctx, _ = context.WithTimeout(ctx, d.cfg.DefaultTimeout) //nolint:govet
-	return ctx
+// applyDefaultTimeout bounds ctx by Config.DefaultTimeout unless it has a
+// deadline already. The returned cancel must be called once the statement
+// is done with ctx; it is a no-op when no deadline was added.
+func (d *DB) applyDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, d.cfg.DefaultTimeout)
 }
 
 func (d *DB) mapErr(err error) error {
@@ -251,13 +485,30 @@ func (d *DB) mapErr(err error) error {
 type Row struct {
 	raw    *sql.Row
 	errMap ErrorMapper
+	// err is set when the statement was refused before execution.
+	err error
+	// query and start annotate Scan errors.
+	query string
+	start time.Time
+	// cancel releases the deadline the DB applied, once Scan is done.
+	cancel context.CancelFunc
 }
 
+// ErrRow returns a Row whose Scan returns err, for fakes and mocks of
+// Querier (e.g. db.ErrRow(db.ErrNotFound)).
+func ErrRow(err error) *Row { return &Row{err: err} }
+
 // Scan copies columns from the matched row into dest values.
 // ErrNotFound is returned when no row was found.
 func (r *Row) Scan(dest ...any) error {
+	if r.cancel != nil {
+		defer r.cancel()
+	}
+	if r.err != nil {
+		return r.err
+	}
 	err := r.raw.Scan(dest...)
-	return r.errMap.Map(err)
+	return annotate(r.errMap.Map(err), OpQueryRow, r.query, r.start)
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -274,21 +525,27 @@ type Stmt struct {
 
 // Exec executes the prepared statement.
 func (s *Stmt) Exec(ctx context.Context, args ...any) (sql.Result, error) {
+	if err := preflight(ctx, s.query); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	s.hooks.Before(ctx, s.query, args)
 	res, err := s.stmt.ExecContext(ctx, args...)
-	err = s.errMap.Map(err)
-	s.hooks.After(ctx, s.query, args, time.Since(start), err)
+	err = annotate(s.errMap.Map(err), OpExec, s.query, start)
+	s.hooks.AfterExec(ctx, s.query, args, time.Since(start), res, err)
 	return res, err
 }
 
 // QueryRow executes the prepared statement expecting one row.
 func (s *Stmt) QueryRow(ctx context.Context, args ...any) *Row {
+	if err := preflight(ctx, s.query); err != nil {
+		return &Row{err: err, errMap: s.errMap}
+	}
 	start := time.Now()
 	s.hooks.Before(ctx, s.query, args)
 	raw := s.stmt.QueryRowContext(ctx, args...)
 	s.hooks.After(ctx, s.query, args, time.Since(start), nil)
-	return &Row{raw: raw, errMap: s.errMap}
+	return &Row{raw: raw, errMap: s.errMap, query: s.query, start: start}
 }
 
 // Close releases the prepared statement resources.
@@ -303,36 +560,69 @@ type RetryConfig struct {
 	MaxAttempts int
 	Delay       time.Duration
 	// RetryOn decides whether a given error should trigger a retry.
-	// Defaults to retrying on ErrDeadlock and ErrTimeout if nil.
+	// Defaults to retrying on ErrDeadlock, ErrTimeout and errors that carry
+	// a RetryAfter hint if nil.
 	RetryOn func(error) bool
 }
 
+// DefaultRetry returns the configuration most callers want: three attempts
+// 100ms apart, retrying on the default transient errors.
+func DefaultRetry() RetryConfig {
+	return RetryConfig{MaxAttempts: 3, Delay: 100 * time.Millisecond}
+}
+
 // WithRetry executes fn, retrying on transient errors per cfg.
 // It is safe to pass a transaction operation inside fn; just make sure fn
-// is idempotent or handles partial state correctly.
+// is idempotent or handles partial state correctly. InsertOnce makes a
+// single-row INSERT idempotent.
+// When the failed attempt's error suggests a backoff (see RetryAfter), the
+// next attempt waits for the longer of that and cfg.Delay.
 func WithRetry(ctx context.Context, cfg RetryConfig, fn func() error) error {
+	_, err := Retry(ctx, cfg, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+// Retry is WithRetry for operations that return a value, which is returned
+// from the successful attempt:
+//
+//	u, err := db.Retry(ctx, db.DefaultRetry(), func() (*models.User, error) {
+//	    return users.GetByID(ctx, id)
+//	})
+func Retry[T any](ctx context.Context, cfg RetryConfig, fn func() (T, error)) (T, error) {
+	var zero T
 	retryOn := cfg.RetryOn
 	if retryOn == nil {
-		retryOn = func(err error) bool {
-			return IsDeadlock(err) || IsTimeout(err)
-		}
+		retryOn = retryableByDefault
 	}
 	var lastErr error
 	for attempt := 0; attempt < cfg.MaxAttempts; attempt++ {
 		if attempt > 0 {
+			delay := cfg.Delay
+			if hint, ok := RetryAfter(lastErr); ok && hint > delay {
+				delay = hint
+			}
 			select {
 			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(cfg.Delay):
+				return zero, ctx.Err()
+			case <-time.After(delay):
 			}
 		}
-		lastErr = fn()
-		if lastErr == nil {
-			return nil
+		v, err := fn()
+		if err == nil {
+			return v, nil
 		}
+		lastErr = err
 		if !retryOn(lastErr) {
-			return lastErr
+			return zero, lastErr
 		}
 	}
-	return fmt.Errorf("sqltoolkit/db: all %d attempts failed, last error: %w", cfg.MaxAttempts, lastErr)
+	return zero, fmt.Errorf("sqltoolkit/db: all %d attempts failed, last error: %w", cfg.MaxAttempts, lastErr)
+}
+
+// retryableByDefault is the RetryOn of a RetryConfig that sets none.
+func retryableByDefault(err error) bool {
+	_, hinted := RetryAfter(err)
+	return IsDeadlock(err) || IsTimeout(err) || hinted
 }
\ No newline at end of file
@@ -5,13 +5,23 @@
 package db_test
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/Skryldev/sql-toolkit/db"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/mattn/go-sqlite3"
 )
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -65,6 +75,244 @@ func TestOpen_InvalidDSN(t *testing.T) {
 	}
 }
 
+func TestSQLiteDriver_DSN(t *testing.T) {
+	dsn, err := db.SQLiteDriver{}.DSN(db.DriverOptions{Database: "app.db"})
+	if err != nil || dsn != "app.db?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=1" {
+		t.Fatalf("default DSN = %q, %v", dsn, err)
+	}
+	dsn, _ = db.SQLiteDriver{JournalMode: "-", BusyTimeout: -1, DisableForeignKeys: true}.DSN(
+		db.DriverOptions{Database: "file:app.db?cache=shared", Extra: map[string]string{"_txlock": "immediate"}})
+	if dsn != "file:app.db?cache=shared&_txlock=immediate" {
+		t.Fatalf("custom DSN = %q", dsn)
+	}
+}
+
+func TestOpenSQLite(t *testing.T) {
+	ctx := context.Background()
+	w, r, err := db.OpenSQLite(db.SQLiteDriver{}, t.TempDir()+"/app.db", db.Config{})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer w.Close()
+	defer r.Close()
+	if got := w.Stats().MaxOpenConnections; got != 1 {
+		t.Fatalf("writer MaxOpenConnections = %d", got)
+	}
+
+	var mode string
+	if err := r.QueryRow(ctx, `PRAGMA journal_mode`).Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal_mode = %q, %v", mode, err)
+	}
+	if _, err := w.Exec(ctx, `CREATE TABLE t (id INTEGER PRIMARY KEY)`); err != nil {
+		t.Fatalf("writer: %v", err)
+	}
+	// A read transaction open on the reader does not block the writer.
+	err = r.ExecTx(ctx, func(tx *db.Tx) error {
+		var n int
+		if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM t`).Scan(&n); err != nil {
+			return err
+		}
+		_, err := w.Exec(ctx, `INSERT INTO t (id) VALUES (1)`)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("write during read: %v", err)
+	}
+	if _, err := r.Exec(ctx, `INSERT INTO t (id) VALUES (2)`); err == nil {
+		t.Fatal("expected the reader pool to reject writes")
+	}
+}
+
+// sqliteConnector stands in for go-libsql's embedded replica connector.
+type sqliteConnector struct{ dsn string }
+
+func (c sqliteConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.Driver().Open(c.dsn)
+}
+func (sqliteConnector) Driver() driver.Driver { return &sqlite3.SQLiteDriver{} }
+
+func TestLibSQLDriver(t *testing.T) {
+	drv := db.LibSQLDriver{}
+	dsn, _ := drv.DSN(db.DriverOptions{Host: "app-org.turso.io", Password: "tok"})
+	if dsn != "libsql://app-org.turso.io?authToken=tok" {
+		t.Fatalf("remote DSN = %q", dsn)
+	}
+	dsn, _ = drv.DSN(db.DriverOptions{Host: "localhost", Port: 8080, SSLMode: "disable"})
+	if dsn != "http://localhost:8080" {
+		t.Fatalf("local sqld DSN = %q", dsn)
+	}
+
+	m := drv.ErrorMapper()
+	for msg, want := range map[string]error{
+		"failed to execute SQL: SQLITE_CONSTRAINT_UNIQUE: UNIQUE constraint failed: users.email": db.ErrDuplicateKey,
+		"SQLITE_CONSTRAINT_FOREIGNKEY: FOREIGN KEY constraint failed":                            db.ErrForeignKeyViolation,
+		"SQLITE_BUSY: database is locked":                                                        db.ErrDeadlock,
+		"unexpected status code 401: Unauthorized":                                               db.ErrConnectionFailed,
+		"hrana: status code: 504":                                                                db.ErrTimeout,
+	} {
+		if err := m.Map(errors.New(msg)); !errors.Is(err, want) {
+			t.Errorf("%q: got %v, want %v", msg, err, want)
+		}
+	}
+
+	// An embedded replica is opened through its connector.
+	var primary, token string
+	drv = db.LibSQLDriver{
+		ReplicaPath: t.TempDir() + "/replica.db",
+		NewReplica: func(path, primaryURL, authToken string, _ time.Duration) (driver.Connector, error) {
+			primary, token = primaryURL, authToken
+			return sqliteConnector{dsn: path}, nil
+		},
+	}
+	db.ReplaceDriver(drv)
+	t.Cleanup(func() { db.ReplaceDriver(db.LibSQLDriver{}) })
+	d, err := db.OpenWithDriver("libsql", db.DriverOptions{Host: "app-org.turso.io", Password: "tok"}, db.Config{})
+	if err != nil {
+		t.Fatalf("open replica: %v", err)
+	}
+	defer d.Close()
+	if primary != "libsql://app-org.turso.io" || token != "tok" || d.Dialect() != db.DialectSQLite {
+		t.Fatalf("replica primary = %q, token = %q, dialect = %s", primary, token, d.Dialect())
+	}
+}
+
+// execAppender stands in for a go-duckdb appender by inserting each row on
+// the raw SQLite connection.
+type execAppender struct {
+	conn  driver.Conn
+	table string
+	rows  *int
+}
+
+func (a execAppender) AppendRow(vals ...driver.Value) error {
+	marks := strings.TrimSuffix(strings.Repeat("?, ", len(vals)), ", ")
+	_, err := a.conn.(driver.Execer).Exec("INSERT INTO "+a.table+" VALUES ("+marks+")", vals)
+	if err == nil {
+		*a.rows++
+	}
+	return err
+}
+func (execAppender) Close() error { return nil }
+
+func TestDuckDBDriver_Appender(t *testing.T) {
+	if !slices.Contains(sql.Drivers(), "duckdb") {
+		sql.Register("duckdb", &sqlite3.SQLiteDriver{})
+	}
+	appended := 0
+	db.ReplaceDriver(db.DuckDBDriver{
+		NewAppender: func(c driver.Conn, _, table string) (db.RowAppender, error) {
+			return execAppender{conn: c, table: table, rows: &appended}, nil
+		},
+	})
+	t.Cleanup(func() { db.ReplaceDriver(db.DuckDBDriver{}) })
+
+	d, err := db.OpenWithDriver("duckdb", db.DriverOptions{Database: t.TempDir() + "/olap.db"}, db.Config{})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	argsFn := func(id int) []any { return []any{id, "click"} }
+	if err := db.BatchExec(d, ctx, `INSERT INTO events VALUES ($1, $2)`, []int{1, 2, 3}, argsFn); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if appended != 3 {
+		t.Fatalf("appended %d rows, want 3", appended)
+	}
+	// A failing row rolls the whole load back.
+	err = db.BatchExec(d, ctx, `INSERT INTO events VALUES ($1, $2)`, []int{4, 1}, argsFn)
+	if !db.IsDuplicateKey(err) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+	// A column list needs INSERT statements.
+	if err := db.BatchExec(d, ctx, `INSERT INTO events (id, kind) VALUES ($1, $2)`, []int{5}, argsFn); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	var n int
+	_ = d.QueryRow(ctx, `SELECT COUNT(*) FROM events`).Scan(&n)
+	if n != 4 || appended != 4 {
+		t.Fatalf("rows = %d, appended = %d; want 4, 4", n, appended)
+	}
+
+	m := db.DuckDBDriver{}.ErrorMapper()
+	for msg, want := range map[string]error{
+		`Constraint Error: Duplicate key "id: 1" violates primary key constraint.`: db.ErrDuplicateKey,
+		`TransactionContext Error: Catalog write-write conflict on alter with "t"`: db.ErrDeadlock,
+	} {
+		if err := m.Map(errors.New(msg)); !errors.Is(err, want) {
+			t.Errorf("%q: got %v, want %v", msg, err, want)
+		}
+	}
+}
+
+func TestMySQLDriver_StrictMode(t *testing.T) {
+	dsn, err := (db.MySQLDriver{StrictMode: true}).DSN(db.DriverOptions{Host: "db", User: "u", Password: "p", Database: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "u:p@tcp(db:3306)/app?parseTime=true&sql_mode=%27STRICT_TRANS_TABLES%2CANSI_QUOTES%2CNO_ZERO_IN_DATE" +
+		"%2CNO_ZERO_DATE%2CERROR_FOR_DIVISION_BY_ZERO%2CNO_ENGINE_SUBSTITUTION%27"
+	if dsn != want {
+		t.Fatalf("DSN = %q", dsn)
+	}
+}
+
+func TestMySQLDriver_Vitess(t *testing.T) {
+	drv := db.MySQLDriver{Vitess: true, Boost: true}
+	dsn, _ := drv.DSN(db.DriverOptions{Host: "aws.connect.psdb.cloud", User: "u", Password: "p", Database: "app"})
+	if dsn != "u:p@tcp(aws.connect.psdb.cloud:3306)/app?parseTime=true&tls=true&interpolateParams=true&boost_cached_queries=true" {
+		t.Fatalf("DSN = %q", dsn)
+	}
+	if _, err := (db.MySQLDriver{Boost: true}).DSN(db.DriverOptions{Host: "h", Database: "d"}); err == nil {
+		t.Fatal("expected Boost without Vitess to be rejected")
+	}
+	if n := drv.SafeBatchSize(4); n != 1000 {
+		t.Fatalf("Vitess batch size = %d", n)
+	}
+	if n := (db.MySQLDriver{}).SafeBatchSize(100); n != 655 {
+		t.Fatalf("MySQL batch size = %d", n)
+	}
+
+	m := drv.ErrorMapper()
+	for msg, want := range map[string]error{
+		"Error 1105: target: app.-.primary: vttablet: rpc error: code = ResourceExhausted desc = transaction pool connection limit exceeded": db.ErrResourceExhausted,
+		"Error 1105: Row count exceeded 100000 (errno 10001)":                                                                                db.ErrResourceExhausted,
+		"Error 1105: vttablet: rpc error: code = Aborted desc = transaction 1638: ended at 2024-01-01 (exceeded timeout: 20s)":               db.ErrDeadlock,
+		"Error 1105: vttablet: rpc error: code = Aborted desc = reserved connection 42 not found":                                            db.ErrConnectionFailed,
+	} {
+		if err := m.Map(errors.New(msg)); !errors.Is(err, want) {
+			t.Errorf("%q: got %v, want %v", msg, err, want)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	ch, cancel := db.Events().Subscribe(1)
+	defer cancel()
+	var kinds []db.EventKind
+	off := db.Events().On(func(ev db.Event) { kinds = append(kinds, ev.Kind) })
+	defer off()
+
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_ = d.Close() // the channel is full: dropped
+	if ev := <-ch; ev.Kind != db.EventPoolOpened || ev.Driver != "sqlite3" || ev.At.IsZero() {
+		t.Fatalf("event = %+v", ev)
+	}
+	if len(kinds) != 2 || kinds[1] != db.EventPoolClosed {
+		t.Fatalf("callback kinds = %v", kinds)
+	}
+	if db.Events().Dropped() == 0 {
+		t.Fatal("expected a dropped event")
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Exec / QueryRow
 // ─────────────────────────────────────────────────────────────────────────────
@@ -122,6 +370,59 @@ func TestQueryRow_NotFound(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────────────────────────────────────
+// ExecAffected / ExecReturningID
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestExecReturningID(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	// LastInsertId path
+	id1, err := d.ExecReturningID(ctx,
+		`INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+		"Ida", "ida@test.com", now, now,
+	)
+	if err != nil {
+		t.Fatalf("exec (LastInsertId): %v", err)
+	}
+
+	// RETURNING path
+	id2, err := d.ExecReturningID(ctx,
+		`INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?) RETURNING id`,
+		"Jon", "jon@test.com", now, now,
+	)
+	if err != nil {
+		t.Fatalf("exec (RETURNING): %v", err)
+	}
+	if id1 == 0 || id2 != id1+1 {
+		t.Fatalf("unexpected ids: %d, %d", id1, id2)
+	}
+
+	// RETURNING in a literal or a comment is not a clause: LastInsertId path.
+	for i, query := range []string{
+		`INSERT INTO users (name, email, created_at, updated_at) VALUES ('RETURNING id', ?, ?, ?)`,
+		`INSERT INTO users (name, email, created_at, updated_at) VALUES ('x', ?, ?, ?) -- RETURNING id`,
+		`INSERT INTO users /* RETURNING id */ (name, email, created_at, updated_at) VALUES ('y', ?, ?, ?)`,
+		`INSERT INTO users (name, email, created_at, updated_at) VALUES ("returning", ?, ?, ?)`,
+	} {
+		id, err := d.ExecReturningID(ctx, query, fmt.Sprintf("r%d@test.com", i), now, now)
+		if err != nil || id != id2+int64(i)+1 {
+			t.Fatalf("%s: id %d, %v", query, id, err)
+		}
+	}
+	id2 += 4
+
+	n, err := d.ExecAffected(ctx, `UPDATE users SET name = ? WHERE id IN (?, ?)`, "Renamed", id1, id2)
+	if err != nil {
+		t.Fatalf("exec affected: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 rows affected, got %d", n)
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Query — multiple rows
 // ─────────────────────────────────────────────────────────────────────────────
@@ -167,6 +468,66 @@ func TestQuery_MultipleRows(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────────────────────────────────────
+// Get / Select
+// ─────────────────────────────────────────────────────────────────────────────
+
+func scanName(s db.RowScanner) (string, error) {
+	var n string
+	err := s.Scan(&n)
+	return n, err
+}
+
+func TestGetSelect(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, n := range []string{"Ann", "Ben"} {
+		_, err := d.Exec(ctx,
+			`INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+			n, n+"@gs.com", now, now,
+		)
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	name, err := db.Get(ctx, d, scanName, `SELECT name FROM users WHERE email = ?`, "Ben@gs.com")
+	if err != nil || name != "Ben" {
+		t.Fatalf("get: name=%q err=%v", name, err)
+	}
+
+	_, err = db.Get(ctx, d, scanName, `SELECT name FROM users WHERE email = ?`, "nobody@gs.com")
+	if !db.IsNotFound(err) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	names, err := db.Select(ctx, d, scanName, `SELECT name FROM users ORDER BY name`)
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if len(names) != 2 || names[0] != "Ann" || names[1] != "Ben" {
+		t.Fatalf("unexpected names: %v", names)
+	}
+
+	// An error on the second row surfaces through rows.Err, and is mapped
+	// like the errors Query returns.
+	d.SetErrorMapper(db.ErrorMapperFunc(func(err error) error {
+		if strings.Contains(err.Error(), "integer overflow") {
+			return &db.DBError{Sentinel: db.ErrInvalidData, Cause: err}
+		}
+		return db.DefaultErrorMapper().Map(err)
+	}))
+	failing := `SELECT CASE WHEN name = 'Ben' THEN abs(-9223372036854775808) ELSE name END FROM users ORDER BY name`
+	if _, err := db.Select(ctx, d, scanName, failing); !errors.Is(err, db.ErrInvalidData) {
+		t.Fatalf("select: expected the mapped iteration error, got %v", err)
+	}
+	if err := db.Stream(ctx, d, failing, nil, func([]string, []any) error { return nil }); !errors.Is(err, db.ErrInvalidData) {
+		t.Fatalf("stream: expected the mapped iteration error, got %v", err)
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // ExecTx — commit
 // ─────────────────────────────────────────────────────────────────────────────
@@ -245,6 +606,61 @@ func TestExecTx_RollbackOnPanic(t *testing.T) {
 	})
 }
 
+func TestInTx_PropagatesThroughContext(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	insert := func(ctx context.Context, email string) error {
+		q := db.QuerierFromContext(ctx, d)
+		_, err := q.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+			"Ctx", email, now, now)
+		return err
+	}
+
+	err := d.InTx(ctx, func(ctx context.Context) error {
+		if _, ok := db.TxFromContext(ctx); !ok {
+			t.Fatal("expected tx in context")
+		}
+		if err := insert(ctx, "ctx1@test.com"); err != nil {
+			return err
+		}
+		// Nested InTx joins the outer transaction.
+		return d.InTx(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "ctx2@test.com"); err != nil {
+				return err
+			}
+			return errors.New("abort")
+		})
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	var n int
+	if err := d.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	if n != 0 {
+		t.Fatalf("expected both inserts rolled back, found %d rows", n)
+	}
+	if q := db.QuerierFromContext(ctx, d); q != db.Querier(d) {
+		t.Fatal("expected fallback outside a transaction")
+	}
+}
+
+func TestPrepareTx_RequiresPostgres(t *testing.T) {
+	d := newTestDB(t)
+	called := false
+	err := d.PrepareTx(context.Background(), "gid-1", func(db.Querier) error { called = true; return nil })
+	if err == nil || called {
+		t.Fatalf("expected PrepareTx to refuse SQLite without running fn (err=%v called=%v)", err, called)
+	}
+	if _, err := d.PreparedTxs(context.Background()); err == nil {
+		t.Fatal("expected PreparedTxs to refuse SQLite")
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Prepared statements
 // ─────────────────────────────────────────────────────────────────────────────
@@ -275,75 +691,506 @@ func TestPrepare(t *testing.T) {
 	}
 }
 
-// ─────────────────────────────────────────────────────────────────────────────
-// Error mapping — DuplicateKey (SQLite)
-// ─────────────────────────────────────────────────────────────────────────────
-
-func TestErrorMapper_DuplicateKey(t *testing.T) {
+func TestValidateQueries(t *testing.T) {
 	d := newTestDB(t)
-	ctx := context.Background()
-	now := time.Now()
+	err := db.ValidateQueries(context.Background(), d, map[string]string{
+		"ok":        `SELECT id, name FROM users WHERE email = $1`,
+		"badColumn": `SELECT id, nmae FROM users`,
+		"badTable":  `DELETE FROM userz WHERE id = $1`,
+	})
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, "badColumn:") || !strings.Contains(msg, "badTable:") || strings.Contains(msg, "ok:") {
+		t.Fatalf("unexpected error: %v", msg)
+	}
+	var n int
+	_ = d.QueryRow(context.Background(), `SELECT COUNT(*) FROM users`).Scan(&n)
+	if n != 0 {
+		t.Fatal("validation must not execute statements")
+	}
+}
 
-	insert := func() error {
-		_, err := d.Exec(ctx,
-			`INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)`,
-			"Alice", "dup@test.com", now, now,
-		)
-		return err
+func TestColumns(t *testing.T) {
+	d := newTestDB(t)
+	cols, err := db.Columns(context.Background(), d, `SELECT id, name, created_at, 1 + 1 AS two FROM users LIMIT 0`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range cols {
+		got = append(got, fmt.Sprintf("%s:%s:%s", c.Name, c.DatabaseType, c.Type))
+	}
+	if want := "id:INTEGER:integer name:TEXT:text created_at:DATETIME:time two::"; strings.Join(got, " ") != want {
+		t.Fatalf("got  %s\nwant %s", strings.Join(got, " "), want)
+	}
+	if cols[0].ScanType == nil {
+		t.Fatal("ScanType should be reported")
+	}
+	if _, err := db.Columns(context.Background(), d, `SELECT nope FROM users`); err == nil {
+		t.Fatal("expected an error for an invalid query")
 	}
 
-	if err := insert(); err != nil {
-		t.Fatalf("first insert: %v", err)
+	// The tiny integer, text and blob types classify apart.
+	if _, err := d.Exec(context.Background(), `CREATE TABLE tiny (n TINYINT, s TINYTEXT, b TINYBLOB, p POINT)`); err != nil {
+		t.Fatal(err)
 	}
-	err := insert() // should trigger UNIQUE constraint
-	if !db.IsDuplicateKey(err) {
-		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	cols, err = db.Columns(context.Background(), d, `SELECT n, s, b, p FROM tiny`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = got[:0]
+	for _, c := range cols {
+		got = append(got, fmt.Sprintf("%s:%s", c.Name, c.Type))
+	}
+	if want := "n:integer s:text b:bytes p:"; strings.Join(got, " ") != want {
+		t.Fatalf("got  %s\nwant %s", strings.Join(got, " "), want)
 	}
 }
 
-// ─────────────────────────────────────────────────────────────────────────────
-// WithRetry
-// ─────────────────────────────────────────────────────────────────────────────
-
-func TestWithRetry_SucceedsOnSecondAttempt(t *testing.T) {
+func TestQueryMaps(t *testing.T) {
+	d := newTestDB(t)
 	ctx := context.Background()
-	attempts := 0
-	transient := errors.New("transient")
-
-	err := db.WithRetry(ctx, db.RetryConfig{
-		MaxAttempts: 3,
-		Delay:       1 * time.Millisecond,
-		RetryOn:     func(err error) bool { return errors.Is(err, transient) },
-	}, func() error {
-		attempts++
-		if attempts < 2 {
-			return transient
-		}
-		return nil
-	})
+	_, err := d.Exec(ctx, `
+		CREATE TABLE things (id INTEGER PRIMARY KEY, label TEXT, score REAL, active BOOLEAN, data BLOB, qty INTEGER);
+		INSERT INTO things VALUES (1, 'a', 1.5, 1, x'00ff', '7'), (2, NULL, 2, 0, NULL, NULL)`)
 	if err != nil {
-		t.Fatalf("expected success: %v", err)
+		t.Fatal(err)
 	}
-	if attempts != 2 {
-		t.Fatalf("expected 2 attempts, got %d", attempts)
+	rows, err := db.QueryMaps(ctx, d, `SELECT id, label, score, active, data, qty FROM things ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]any{
+		{"id": int64(1), "label": "a", "score": 1.5, "active": true, "data": []byte{0, 0xff}, "qty": int64(7)},
+		{"id": int64(2), "label": nil, "score": 2.0, "active": false, "data": nil, "qty": nil},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Fatalf("got  %#v\nwant %#v", rows, want)
+	}
+
+	rows, err = db.QueryMaps(db.WithMaxRows(ctx, 1), d, `SELECT id FROM things`)
+	if !errors.Is(err, db.ErrTruncated) || len(rows) != 1 {
+		t.Fatalf("WithMaxRows: %d rows, %v", len(rows), err)
 	}
 }
 
-func TestWithRetry_ExhaustsAttempts(t *testing.T) {
+func TestQueryJSON(t *testing.T) {
+	d := newTestDB(t)
 	ctx := context.Background()
-	permanent := errors.New("permanent")
+	at := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	if _, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`,
+		"Zed", "zed@example.com", at); err != nil {
+		t.Fatal(err)
+	}
 
-	err := db.WithRetry(ctx, db.RetryConfig{
-		MaxAttempts: 3,
-		Delay:       1 * time.Millisecond,
-		RetryOn:     func(err error) bool { return errors.Is(err, permanent) },
-	}, func() error {
-		return permanent
-	})
-	if err == nil {
-		t.Fatal("expected error after exhausting attempts")
+	var buf bytes.Buffer
+	n, err := db.QueryJSON(ctx, d, &buf, `SELECT name, id, created_at, x'00ff' AS raw, NULL AS gone FROM users`)
+	if err != nil || n != 1 {
+		t.Fatalf("QueryJSON: %d rows, %v", n, err)
+	}
+	want := `[{"name":"Zed","id":1,"created_at":"2026-10-15T09:30:00Z","raw":"AP8=","gone":null}]` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got  %swant %s", buf.String(), want)
 	}
-}
+
+	buf.Reset()
+	_, err = db.QueryJSONOpts(ctx, d, &buf, db.JSONOptions{Lines: true, TimeLayout: time.DateOnly, Binary: "hex"},
+		`SELECT created_at, x'00ff' AS raw FROM users UNION ALL SELECT created_at, x'01' FROM users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\"created_at\":\"2026-10-15\",\"raw\":\"00ff\"}\n{\"created_at\":\"2026-10-15\",\"raw\":\"01\"}\n"; buf.String() != want {
+		t.Fatalf("lines: got %q", buf.String())
+	}
+
+	buf.Reset()
+	if n, err := db.QueryJSON(ctx, d, &buf, `SELECT id FROM users WHERE id < 0`); err != nil || n != 0 || buf.String() != "[]\n" {
+		t.Fatalf("empty result: %q, %d, %v", buf.String(), n, err)
+	}
+}
+
+func TestQueryCSV(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	at := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	if _, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`,
+		`Zed "Z", Jr`, "zed@example.com", at); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := db.QueryCSV(ctx, d, &buf, db.CSVOptions{},
+		`SELECT id, name, created_at, '' AS blank, NULL AS gone, 2.5 AS f, x'00ff' AS raw FROM users`)
+	if err != nil || n != 1 {
+		t.Fatalf("QueryCSV: %d rows, %v", n, err)
+	}
+	want := "id,name,created_at,blank,gone,f,raw\n" +
+		`1,"Zed ""Z"", Jr",2026-10-15T09:30:00Z,"",,2.5,AP8=` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got  %qwant %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	_, err = db.QueryCSV(ctx, d, &buf, db.CSVOptions{
+		NoHeader: true, Null: `\N`, Comma: ';', Quote: db.QuoteNonNumeric, UseCRLF: true, Binary: "hex",
+	}, `SELECT id, email, '' AS blank, NULL AS gone, x'00ff' AS raw FROM users`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1;\"zed@example.com\";\"\";\\N;\"00ff\"\r\n"; buf.String() != want {
+		t.Fatalf("options: got %q want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if _, err := db.QueryCSV(ctx, d, &buf, db.CSVOptions{Quote: db.QuoteAll}, `SELECT id, email FROM users`); err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"id\",\"email\"\n\"1\",\"zed@example.com\"\n"; buf.String() != want {
+		t.Fatalf("QuoteAll: got %q", buf.String())
+	}
+
+	buf.Reset()
+	if n, err := db.QueryCSV(ctx, d, &buf, db.CSVOptions{}, `SELECT id, name FROM users WHERE id < 0`); err != nil || n != 0 || buf.String() != "id,name\n" {
+		t.Fatalf("empty result: %q, %d, %v", buf.String(), n, err)
+	}
+	if _, err := db.QueryCSV(ctx, d, &buf, db.CSVOptions{Comma: '"'}, `SELECT 1`); err == nil {
+		t.Fatal("a quote as separator must be rejected")
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	type user struct {
+		ID        int64
+		Name      string
+		Email     *string
+		CreatedAt time.Time
+		UpdatedAt sql.NullTime
+	}
+	if err := db.ValidateSchema(ctx, d, db.ExpectStruct[*user]("users")); err != nil {
+		t.Fatalf("matching schema: %v", err)
+	}
+
+	if _, err := d.Exec(ctx, `CREATE TABLE profiles (user_id INTEGER NOT NULL, bio TEXT, age TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	type profile struct {
+		UserID   int64
+		Bio      string
+		Age      int
+		Nickname string `db:"nick"`
+		Ignored  string `db:"-"`
+	}
+	err := db.ValidateSchema(ctx, d,
+		db.ExpectStruct[profile]("profiles"),
+		db.TableExpectation{Table: "missing", Columns: []db.ColumnExpectation{{Name: "id"}}})
+	if err == nil {
+		t.Fatal("expected mismatches")
+	}
+	for _, want := range []string{
+		"profiles.bio: column is nullable",
+		"profiles.age: integer expected, column is TEXT",
+		"profiles.nick: missing column",
+		"missing: missing table",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("report lacks %q:\n%v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "user_id") || strings.Contains(err.Error(), "ignored") {
+		t.Errorf("unexpected mismatch:\n%v", err)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Portable RETURNING
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestRebind(t *testing.T) {
+	q, args := db.DialectMySQL.Rebind(
+		"UPDATE t SET a = $2, b = '$1', c = $2 -- $3\nWHERE id = $1", []any{7, "x"})
+	if q != "UPDATE t SET a = ?, b = '$1', c = ? -- $3\nWHERE id = ?" {
+		t.Fatalf("query = %q", q)
+	}
+	if len(args) != 3 || args[0] != "x" || args[1] != "x" || args[2] != 7 {
+		t.Fatalf("args = %v", args)
+	}
+	if q, _ := db.DialectPostgres.Rebind("SELECT $1", []any{1}); q != "SELECT $1" {
+		t.Fatalf("postgres query = %q", q)
+	}
+}
+
+func TestLikeEscape(t *testing.T) {
+	if got := db.DialectMySQL.LikeEscape(); got != "" {
+		t.Fatalf("MySQL LikeEscape = %q; backslash is its default escape", got)
+	}
+	d := newTestDB(t)
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES
+		('a_b', 'x@x', $1, $1), ('axb', 'y@x', $1, $1)`, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	err := d.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE name LIKE $1"+d.Dialect().LikeEscape(), db.LikePrefix("a_")).Scan(&n)
+	if err != nil || n != 1 {
+		t.Fatalf("escaped prefix matched %d rows, %v; want 1", n, err)
+	}
+}
+
+// mysqlDB reports the MySQL dialect so the RETURNING emulation runs on SQLite,
+// which also accepts ? placeholders and LastInsertId.
+type mysqlDB struct{ *db.DB }
+
+func (mysqlDB) Dialect() db.Dialect { return db.DialectMySQL }
+
+func TestInsertReturning(t *testing.T) {
+	ctx := context.Background()
+	ret := db.Returning{Table: "users", Columns: []string{"id", "name", "email"}}
+	for name, q := range map[string]db.Querier{"native": newTestDB(t), "emulated": mysqlDB{newTestDB(t)}} {
+		now := time.Now().UTC()
+		var (
+			id          int64
+			uname, mail string
+		)
+		err := db.InsertReturning(ctx, q,
+			`INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`,
+			[]any{"ann", "ann@x", now}, ret, &id, &uname, &mail)
+		if err != nil || id == 0 || uname != "ann" || mail != "ann@x" {
+			t.Fatalf("%s: insert = %d %q %q, %v", name, id, uname, mail, err)
+		}
+
+		err = db.UpdateReturning(ctx, q, `UPDATE users SET name = $1 WHERE id = $2`,
+			[]any{"bea", id}, ret, id, &id, &uname, &mail)
+		if err != nil || uname != "bea" {
+			t.Fatalf("%s: update = %q, %v", name, uname, err)
+		}
+		err = db.UpdateReturning(ctx, q, `UPDATE users SET name = $1 WHERE id = $2`,
+			[]any{"x", id + 1}, ret, id+1, &id, &uname, &mail)
+		if !errors.Is(err, db.ErrNotFound) {
+			t.Fatalf("%s: update missing row: %v", name, err)
+		}
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Error mapping — DuplicateKey (SQLite)
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestErrorMapper_DuplicateKey(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	insert := func() error {
+		_, err := d.Exec(ctx,
+			`INSERT INTO users (name, email, created_at, updated_at) VALUES (?, ?, ?, ?)`,
+			"Alice", "dup@test.com", now, now,
+		)
+		return err
+	}
+
+	if err := insert(); err != nil {
+		t.Fatalf("first insert: %v", err)
+	}
+	err := insert() // should trigger UNIQUE constraint
+	if !db.IsDuplicateKey(err) {
+		t.Fatalf("expected ErrDuplicateKey, got %v", err)
+	}
+}
+
+// moderncError mimics *sqlite.Error from modernc.org/sqlite.
+type moderncError struct{ code int }
+
+func (e moderncError) Error() string { return fmt.Sprintf("sqlite error (%d)", e.code) }
+func (e moderncError) Code() int     { return e.code }
+
+func TestErrorMapper_ModerncCodes(t *testing.T) {
+	m := db.DefaultErrorMapper()
+	for code, want := range map[int]error{
+		2067: db.ErrDuplicateKey,        // SQLITE_CONSTRAINT_UNIQUE
+		787:  db.ErrForeignKeyViolation, // SQLITE_CONSTRAINT_FOREIGNKEY
+		275:  db.ErrCheckViolation,      // SQLITE_CONSTRAINT_CHECK
+		1299: db.ErrNotNullViolation,    // SQLITE_CONSTRAINT_NOTNULL
+		20:   db.ErrInvalidData,         // SQLITE_MISMATCH
+		517:  db.ErrDeadlock,            // SQLITE_BUSY_SNAPSHOT
+	} {
+		if err := m.Map(fmt.Errorf("exec: %w", moderncError{code})); !errors.Is(err, want) {
+			t.Errorf("code %d: got %v, want %v", code, err, want)
+		}
+	}
+
+	drv := db.SQLiteDriver{DriverName: "sqlite", TxLock: "immediate"}
+	dsn, _ := drv.DSN(db.DriverOptions{Database: "app.db"})
+	if dsn != "app.db?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=foreign_keys(1)&_txlock=immediate" {
+		t.Fatalf("modernc DSN = %q", dsn)
+	}
+}
+
+func TestInsertSQL_Conflicts(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	now := time.Now()
+	cols := []string{"name", "email", "created_at", "updated_at"}
+
+	run := func(action db.OnConflict, name string) error {
+		q, err := db.InsertSQL(d.Dialect(), "users", cols, action, "email")
+		if err != nil {
+			t.Fatalf("InsertSQL: %v", err)
+		}
+		_, err = d.Exec(ctx, q, name, "up@test.com", now, now)
+		return err
+	}
+
+	if err := run(db.ConflictFail, "Alice"); err != nil {
+		t.Fatalf("first insert: %v", err)
+	}
+	if err := run(db.ConflictFail, "Bob"); !db.IsDuplicateKey(err) {
+		t.Fatalf("ConflictFail: expected ErrDuplicateKey, got %v", err)
+	}
+	if err := run(db.ConflictSkip, "Bob"); err != nil {
+		t.Fatalf("ConflictSkip: %v", err)
+	}
+	if err := run(db.ConflictUpdate, "Carol"); err != nil {
+		t.Fatalf("ConflictUpdate: %v", err)
+	}
+
+	var name string
+	var n int
+	if err := d.QueryRow(ctx, `SELECT name, (SELECT COUNT(*) FROM users) FROM users WHERE email = $1`, "up@test.com").Scan(&name, &n); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if name != "Carol" || n != 1 {
+		t.Fatalf("got name=%q count=%d, want Carol/1", name, n)
+	}
+
+	if _, err := db.InsertSQL(db.DialectPostgres, "users", cols, db.ConflictUpdate); err == nil {
+		t.Fatal("expected error for ConflictUpdate without key columns")
+	}
+	got, _ := db.InsertSQL(db.DialectMySQL, "users", []string{"email", "name"}, db.ConflictUpdate, "email")
+	want := "INSERT INTO `users` (`email`, `name`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`)"
+	if got != want {
+		t.Fatalf("mysql upsert:\n got %s\nwant %s", got, want)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// WithRetry
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestWithRetry_SucceedsOnSecondAttempt(t *testing.T) {
+	ctx := context.Background()
+	attempts := 0
+	transient := errors.New("transient")
+
+	err := db.WithRetry(ctx, db.RetryConfig{
+		MaxAttempts: 3,
+		Delay:       1 * time.Millisecond,
+		RetryOn:     func(err error) bool { return errors.Is(err, transient) },
+	}, func() error {
+		attempts++
+		if attempts < 2 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestWithRetry_ExhaustsAttempts(t *testing.T) {
+	ctx := context.Background()
+	permanent := errors.New("permanent")
+
+	err := db.WithRetry(ctx, db.RetryConfig{
+		MaxAttempts: 3,
+		Delay:       1 * time.Millisecond,
+		RetryOn:     func(err error) bool { return errors.Is(err, permanent) },
+	}, func() error {
+		return permanent
+	})
+	if err == nil {
+		t.Fatal("expected error after exhausting attempts")
+	}
+}
+
+// lostAckDB commits the first Exec but reports a dropped connection, as when
+// the network fails between COMMIT and its acknowledgement.
+type lostAckDB struct {
+	*db.DB
+	lost bool
+}
+
+func (l *lostAckDB) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := l.DB.Exec(ctx, query, args...)
+	if err == nil && !l.lost {
+		l.lost = true
+		return nil, &db.DBError{Sentinel: db.ErrConnectionFailed, Message: "connection reset"}
+	}
+	return res, err
+}
+
+func TestInsertOnce(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	if _, err := d.Exec(ctx, `CREATE TABLE payments (id INTEGER PRIMARY KEY, amount INTEGER, token TEXT NOT NULL UNIQUE)`); err != nil {
+		t.Fatal(err)
+	}
+	count := func() (n int) {
+		if err := d.QueryRow(ctx, `SELECT COUNT(*) FROM payments`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	cfg := db.DefaultRetry()
+	cfg.Delay = time.Millisecond
+
+	token := db.NewIdempotencyToken()
+	if len(token) != 32 || token == db.NewIdempotencyToken() {
+		t.Fatalf("token %q", token)
+	}
+	created, err := db.InsertOnce(ctx, &lostAckDB{DB: d}, cfg, "payments", "token", token, []string{"amount"}, 100)
+	if err != nil || created || count() != 1 {
+		t.Fatalf("retry after a committed attempt: created %v, %v, %d rows; want one row", created, err, count())
+	}
+	if created, err := db.InsertOnce(ctx, d, cfg, "payments", "token", token, []string{"amount"}, 100); err != nil || created || count() != 1 {
+		t.Fatalf("same token again: created %v, %v, %d rows", created, err, count())
+	}
+	if created, err := db.InsertOnce(ctx, d, cfg, "payments", "token", db.NewIdempotencyToken(), []string{"amount"}, 50); err != nil || !created || count() != 2 {
+		t.Fatalf("new token: created %v, %v, %d rows", created, err, count())
+	}
+}
+
+func TestRetry_ReturnsValue(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	attempts := 0
+
+	cfg := db.DefaultRetry()
+	cfg.Delay = time.Millisecond
+	n, err := db.Retry(ctx, cfg, func() (int, error) {
+		if attempts++; attempts < 2 {
+			return 0, &db.DBError{Sentinel: db.ErrDeadlock}
+		}
+		var n int
+		err := d.QueryRow(ctx, `SELECT 42`).Scan(&n)
+		return n, err
+	})
+	if err != nil || n != 42 || attempts != 2 {
+		t.Fatalf("got %d, %v after %d attempts", n, err, attempts)
+	}
+
+	_, err = db.Retry(ctx, cfg, func() (int, error) { return 0, db.ErrNotFound })
+	if !db.IsNotFound(err) {
+		t.Fatalf("non-transient error should be returned as is, got %v", err)
+	}
+}
 
 // ─────────────────────────────────────────────────────────────────────────────
 // Hooks — verify they are called
@@ -379,6 +1226,134 @@ func TestHooks_CalledOnExec(t *testing.T) {
 	}
 }
 
+// ─────────────────────────────────────────────────────────────────────────────
+// RecentQueries / Fingerprint
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestRecentQueries_RecordsFailures(t *testing.T) {
+	db.ConfigureRecentQueries(db.QueryRecorderConfig{Size: 2, SlowThreshold: time.Hour})
+	t.Cleanup(func() { db.ConfigureRecentQueries(db.QueryRecorderConfig{}) })
+	d := newTestDB(t)
+	ctx := context.Background()
+
+	_, _ = d.Exec(ctx, `SELECT 1`)
+	for i := 0; i < 3; i++ {
+		_, _ = d.Exec(ctx, `SELECT missing_col FROM users WHERE id = 42`)
+	}
+
+	recs := db.RecentQueries()
+	if len(recs) != 2 {
+		t.Fatalf("expected ring buffer of 2, got %d records", len(recs))
+	}
+	r := recs[0]
+	if r.Fingerprint != "SELECT missing_col FROM users WHERE id = ?" || r.Error == "" {
+		t.Fatalf("unexpected record: %+v", r)
+	}
+	if !strings.Contains(r.Caller, "db_test.go:") {
+		t.Fatalf("caller = %q, want this test file", r.Caller)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	cases := map[string]string{
+		"SELECT * FROM t WHERE id IN (1, 2, 3) AND name = 'O''Brien'":   "SELECT * FROM t WHERE id IN (?) AND name = ?",
+		"select a::text from \"T\"\n  where x >= $1 -- note\n limit 10": `select a::text from "T" where x >= ? limit ?`,
+		"INSERT INTO users(name,email) VALUES (?, ?) /* c */":           "INSERT INTO users (name, email) VALUES (?)",
+		"UPDATE t2 SET v = :v WHERE id = :id":                           "UPDATE t2 SET v = ? WHERE id = ?",
+	}
+	for in, want := range cases {
+		if got := db.Fingerprint(in); got != want {
+			t.Errorf("Fingerprint(%q)\n got %q\nwant %q", in, got, want)
+		}
+	}
+}
+
+func TestQueryStats_Percentiles(t *testing.T) {
+	c := db.NewQueryStatsCollector()
+	ctx := context.Background()
+	for i := 1; i <= 100; i++ {
+		c.AfterQuery(ctx, "SELECT * FROM users WHERE id = 1", nil, time.Duration(i)*time.Millisecond, nil)
+	}
+	c.AfterQuery(ctx, "SELECT 1", nil, time.Millisecond, errors.New("boom"))
+
+	stats := c.Snapshot()
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 fingerprints, got %+v", stats)
+	}
+	s := stats[0]
+	if s.Fingerprint != "SELECT * FROM users WHERE id = ?" || s.Count != 100 || s.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected stat: %+v", s)
+	}
+	near := func(got, want time.Duration) bool { return got > want*90/100 && got < want*110/100 }
+	if !near(s.P50, 50*time.Millisecond) || !near(s.P95, 95*time.Millisecond) || !near(s.P99, 99*time.Millisecond) {
+		t.Fatalf("percentiles off: p50=%v p95=%v p99=%v", s.P50, s.P95, s.P99)
+	}
+	if stats[1].Errors != 1 {
+		t.Fatalf("expected error counted, got %+v", stats[1])
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Query budget
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestQueryBudget(t *testing.T) {
+	d := newTestDB(t)
+	ctx := db.WithQueryBudget(context.Background(), 2)
+
+	if _, err := d.Exec(ctx, `SELECT 1`); err != nil {
+		t.Fatalf("first: %v", err)
+	}
+	err := d.ExecTx(ctx, func(tx *db.Tx) error {
+		var n int
+		if err := tx.QueryRow(ctx, `SELECT 1`).Scan(&n); err != nil {
+			t.Fatalf("second: %v", err)
+		}
+		return tx.QueryRow(ctx, `SELECT 1`).Scan(&n)
+	})
+	if !db.IsBudgetExceeded(err) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	if used, max, ok := db.QueryBudgetUsed(ctx); !ok || used != 3 || max != 2 {
+		t.Fatalf("QueryBudgetUsed = %d, %d, %v", used, max, ok)
+	}
+	soft := db.WithSoftQueryBudget(context.Background(), 0)
+	if _, err := d.Exec(soft, `SELECT 1`); err != nil {
+		t.Fatalf("soft budget must not fail: %v", err)
+	}
+}
+
+func TestNPlusOneDetector(t *testing.T) {
+	det := db.NewNPlusOneDetector(db.NPlusOneConfig{Threshold: 3})
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", Hooks: []db.Hook{det}})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+
+	ctx, done := det.Scope(context.Background())
+	for i := 0; i < 5; i++ {
+		var n int
+		_ = d.QueryRow(ctx, `SELECT $1`, i).Scan(&n)
+		_ = d.QueryRow(ctx, `SELECT count(*) FROM sqlite_master`).Scan(&n)
+	}
+	_, _ = d.Exec(context.Background(), `SELECT $1`, 99) // outside the scope
+
+	findings := done()
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", findings)
+	}
+	f := findings[0]
+	if f.Fingerprint != "SELECT ?" || f.Count != 5 || f.DistinctArgs != 5 {
+		t.Fatalf("unexpected finding: %+v", f)
+	}
+	for caller := range f.Callers {
+		if !strings.Contains(caller, "db_test.go:") {
+			t.Fatalf("caller = %q, want this test file", caller)
+		}
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // BatchExec
 // ─────────────────────────────────────────────────────────────────────────────
@@ -427,4 +1402,1293 @@ func TestContextCancellation(t *testing.T) {
 		// the error sentinel tests above.
 		t.Log("SQLite executed before context was observed (acceptable)")
 	}
-}
\ No newline at end of file
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Clock
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestClock(t *testing.T) {
+	frozen := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := db.NewFakeClock(frozen)
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", Clock: clock})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+
+	if got := db.NowFrom(d); !got.Equal(frozen) {
+		t.Fatalf("DB now = %v", got)
+	}
+	clock.Advance(time.Minute)
+	_ = d.ExecTx(context.Background(), func(tx *db.Tx) error {
+		if got := db.NowFrom(tx); !got.Equal(frozen.Add(time.Minute)) {
+			t.Fatalf("Tx now = %v", got)
+		}
+		return nil
+	})
+	if time.Since(newTestDB(t).Now()) > time.Minute {
+		t.Fatal("default clock is not the system clock")
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Read-only mode
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestIsWriteStatement(t *testing.T) {
+	cases := map[string]bool{
+		`SELECT * FROM users`: false,
+		"  -- comment\n  insert into users (name) values ('x')":            true,
+		`SELECT id FROM users WHERE id = $1 FOR UPDATE`:                    false,
+		`WITH x AS (SELECT 1 FOR NO KEY UPDATE) SELECT * FROM x`:           false,
+		`WITH gone AS (DELETE FROM users RETURNING id) SELECT * FROM gone`: true,
+		`SELECT 'DELETE FROM users'`:                                       false,
+		`(SELECT 1) UNION (SELECT 2)`:                                      false,
+		`CREATE INDEX idx ON users (email)`:                                true,
+		`EXPLAIN SELECT 1`:                                                 false,
+		`SELECT * INTO users_copy FROM users`:                              true,
+		`SELECT id FROM users INTO OUTFILE '/tmp/ids'`:                     true,
+		`WITH x AS (SELECT 1) SELECT * INTO y FROM x`:                      true,
+		`SELECT 'INTO' AS "into"`:                                          false,
+		`EXPLAIN ANALYZE DELETE FROM users`:                                true,
+		`EXPLAIN (ANALYZE, BUFFERS) UPDATE users SET name = 'x'`:           true,
+		`explain analyse insert into users (name) values ('x')`:            true,
+		`EXPLAIN ANALYZE WITH g AS (DELETE FROM t RETURNING id) SELECT 1`:  true,
+		`EXPLAIN ANALYZE SELECT * FROM users`:                              false,
+		`EXPLAIN (ANALYZE false) DELETE FROM users`:                        false,
+		`EXPLAIN (ANALYZE off, COSTS off) DELETE FROM users`:               false,
+		`EXPLAIN DELETE FROM users`:                                        false,
+		``:                                                                 false,
+	}
+	for q, want := range cases {
+		if got := db.IsWriteStatement(q); got != want {
+			t.Errorf("IsWriteStatement(%q) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestSetReadOnly(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	stmt, err := d.Prepare(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`)
+	if err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	defer stmt.Close()
+
+	evs, unsubscribe := db.Events().Subscribe(4)
+	defer unsubscribe()
+	db.SetReadOnly(true)
+	t.Cleanup(func() { db.SetReadOnly(false) })
+	if !db.ReadOnly() {
+		t.Fatal("ReadOnly() = false")
+	}
+
+	if _, err := d.Exec(ctx, `INSERT INTO users (name, email) VALUES ('ro', 'ro@x')`); !db.IsReadOnly(err) {
+		t.Fatalf("DB.Exec = %v", err)
+	}
+	if _, err := stmt.Exec(ctx, "ro", "ro@x", time.Now()); !db.IsReadOnly(err) {
+		t.Fatalf("Stmt.Exec = %v", err)
+	}
+	err = d.ExecTx(ctx, func(tx *db.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM users`)
+		return err
+	})
+	if !db.IsReadOnly(err) {
+		t.Fatalf("Tx.Exec = %v", err)
+	}
+	var n int
+	if err := d.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatalf("reads must still work: %v", err)
+	}
+
+	db.SetReadOnly(false)
+	if _, err := stmt.Exec(ctx, "rw", "rw@x", time.Now()); err != nil {
+		t.Fatalf("after leaving read-only mode: %v", err)
+	}
+	var changes []any
+	for len(changes) < 2 {
+		select {
+		case ev := <-evs:
+			if ev.Kind == db.EventReadOnlyChanged {
+				changes = append(changes, ev.Attrs["read_only"])
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("read-only events = %v", changes)
+		}
+	}
+	if changes[0] != true || changes[1] != false {
+		t.Fatalf("read-only events = %v", changes)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Maintenance windows
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestMaintenanceWindow_Pause(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	batch := db.WithWorkload(ctx, db.WorkloadBatch)
+
+	now := time.Now()
+	done := db.ScheduleMaintenance(db.MaintenanceWindow{Name: "vacuum", Start: now.Add(-time.Second), End: now.Add(time.Hour)})
+	t.Cleanup(done)
+	if w, ok := db.ActiveMaintenance(); !ok || w.Name != "vacuum" {
+		t.Fatalf("ActiveMaintenance() = %+v, %v", w, ok)
+	}
+
+	// Interactive statements are never held back.
+	if _, err := d.Exec(ctx, `SELECT 1`); err != nil {
+		t.Fatalf("interactive: %v", err)
+	}
+	// A deadline inside the window fails at once.
+	short, cancel := context.WithTimeout(batch, time.Minute)
+	defer cancel()
+	start := time.Now()
+	if _, err := d.Exec(short, `SELECT 1`); !db.IsTimeout(err) || time.Since(start) > time.Second {
+		t.Fatalf("batch with deadline: %v after %v", err, time.Since(start))
+	}
+
+	// Withdrawing the window releases a waiting statement.
+	errc := make(chan error, 1)
+	go func() {
+		_, err := d.Exec(batch, `SELECT 1`)
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		t.Fatalf("batch statement ran during the window: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	done()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("batch after window: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("batch statement still waiting after the window was withdrawn")
+	}
+}
+
+func TestMaintenanceWindow_Throttle(t *testing.T) {
+	d := newTestDB(t)
+	ctx := db.WithWorkload(context.Background(), db.WorkloadBackground)
+
+	now := time.Now()
+	t.Cleanup(db.ScheduleMaintenance(db.MaintenanceWindow{Start: now, End: now.Add(time.Hour), MaxRate: 50}))
+	start := time.Now()
+	for range 6 {
+		if _, err := d.Exec(ctx, `SELECT 1`); err != nil {
+			t.Fatalf("exec: %v", err)
+		}
+	}
+	// 50/s spaces six statements at least 5 × 20ms apart.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("six throttled statements took %v", elapsed)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Diff
+// ─────────────────────────────────────────────────────────────────────────────
+
+type diffAudit struct {
+	ActorID int64
+}
+
+type diffRow struct {
+	diffAudit
+	ID        int64
+	Name      string
+	Nickname  *string
+	HTTPCode  int    `db:"status"`
+	Secret    string `db:"-"`
+	Tags      []string
+	UpdatedAt time.Time
+	internal  int
+}
+
+func TestDiff(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	nick := "al"
+	old := diffRow{ID: 1, Name: "Alice", HTTPCode: 200, Secret: "a", Tags: []string{"x"}, UpdatedAt: ts}
+
+	same := old
+	same.UpdatedAt = ts.In(time.FixedZone("CET", 3600))
+	same.Tags = []string{"x"}
+	same.Secret, same.internal = "b", 7
+	if got := db.Diff(old, same); got != nil {
+		t.Fatalf("Diff(equal rows) = %v", got)
+	}
+
+	changed := old
+	changed.ActorID = 9
+	changed.Name = "Alicia"
+	changed.Nickname = &nick
+	changed.HTTPCode = 404
+	got := db.Diff(&old, &changed)
+	want := map[string]any{"actor_id": int64(9), "name": "Alicia", "nickname": "al", "status": 404}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Diff = %v, want %v", got, want)
+	}
+
+	if got := db.Diff(changed, old); got["nickname"] != nil || len(got) != 4 {
+		t.Fatalf("Diff back to NULL = %v", got)
+	}
+}
+
+func TestPatchSet(t *testing.T) {
+	allowed := map[string]string{"name": "name", "displayName": "display_name"}
+	set, args, err := db.PatchSet(db.DialectPostgres, allowed, map[string]any{"name": "x", "displayName": nil})
+	if err != nil {
+		t.Fatalf("PatchSet: %v", err)
+	}
+	if set != `"display_name" = $1, "name" = $2` || !reflect.DeepEqual(args, []any{nil, "x"}) {
+		t.Fatalf("PatchSet = %q %v", set, args)
+	}
+	if _, _, err := db.PatchSet(db.DialectPostgres, allowed, map[string]any{"id": 1}); !db.IsInvalidFilter(err) {
+		t.Fatalf("unknown field: %v", err)
+	}
+}
+
+func TestOrderBy(t *testing.T) {
+	allowed := map[string]string{"name": "name", "created": "created_at", "id": "id"}
+	fields, err := db.ParseSort(" -created, +name ,id")
+	if err != nil {
+		t.Fatalf("ParseSort: %v", err)
+	}
+	got, err := db.OrderBy(allowed, fields)
+	if err != nil || got != "ORDER BY created_at DESC, name ASC, id ASC" {
+		t.Fatalf("OrderBy = %q, %v", got, err)
+	}
+	if got, err := db.OrderBy(allowed, nil); got != "" || err != nil {
+		t.Fatalf("OrderBy(nil) = %q, %v", got, err)
+	}
+
+	for _, bad := range [][]db.SortField{
+		{{Field: "password"}},
+		{{Field: "name"}, {Field: "name", Dir: db.Desc}},
+		{{Field: "name", Dir: "SIDEWAYS"}},
+	} {
+		if _, err := db.OrderBy(allowed, bad); !db.IsInvalidFilter(err) {
+			t.Errorf("OrderBy(%v) = %v, want ErrInvalidFilter", bad, err)
+		}
+	}
+	if _, err := db.ParseSort("name,,id"); !db.IsInvalidFilter(err) {
+		t.Errorf("ParseSort with empty field = %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	allowed := map[string]string{"name": "name", "email": "email", "id": "id"}
+	f, err := db.ParseFilter(`{"or": [
+		{"field": "name", "op": "prefix", "value": "A_"},
+		{"and": [{"field": "id", "op": "in", "value": [1, 2.5]}, {"not": {"field": "email", "op": "null", "value": true}}]}]}`)
+	if err != nil {
+		t.Fatalf("ParseFilter: %v", err)
+	}
+	expr, args, err := db.CompileFilter(db.DialectPostgres, f, allowed)
+	want := `(name LIKE ? ESCAPE '\' OR (id IN (?, ?) AND NOT (email IS NULL)))`
+	if err != nil || expr != want || !reflect.DeepEqual(args, []any{`A\_%`, int64(1), 2.5}) {
+		t.Fatalf("CompileFilter = %q %v, %v", expr, args, err)
+	}
+
+	// Built in code, it round-trips through JSON.
+	built := db.AnyOf(db.Match("name", db.OpPrefix, "A_"),
+		db.AllOf(db.Match("id", db.OpIn, []any{int64(1), 2.5}), db.Negate(db.Match("email", db.OpNull, true))))
+	data, err := json.Marshal(built)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, err := db.ParseFilter(string(data)); err != nil || !reflect.DeepEqual(*again, *f) {
+		t.Fatalf("round trip of %s = %+v, %v", data, again, err)
+	}
+
+	c := db.NewConditions(db.DollarPlaceholder).Add("id > ?", 0)
+	if err := c.AddFilter(&built, allowed); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Where(); got != `WHERE id > $1 AND (name LIKE $2 ESCAPE '\' OR (id IN ($3, $4) AND NOT (email IS NULL)))` {
+		t.Fatalf("Where = %q", got)
+	}
+	// Typed slices are lists as well as []any.
+	for _, list := range []any{[]int64{1, 2}, []string{"a@x", "b@x"}, [2]float64{1, 2.5}} {
+		expr, args, err := db.CompileFilter(db.DialectPostgres, &db.Filter{Field: "id", Op: db.OpNotIn, Value: list}, allowed)
+		want := reflect.ValueOf(list)
+		if err != nil || expr != "id NOT IN (?, ?)" || len(args) != 2 || args[1] != want.Index(1).Interface() {
+			t.Fatalf("NOT IN %v = %q %v, %v", list, expr, args, err)
+		}
+	}
+	if expr, _, _ := db.CompileFilter(db.DialectMySQL, f, allowed); strings.Contains(expr, "ESCAPE") {
+		t.Fatalf("MySQL filter = %q; want no ESCAPE clause", expr)
+	}
+	if f, _ := db.ParseFilter(" "); f != nil {
+		t.Fatalf("empty filter = %+v", f)
+	}
+	if expr, _, err := db.CompileFilter(db.DialectPostgres, &db.Filter{}, allowed); expr != "" || err != nil {
+		t.Fatalf("zero filter = %q, %v", expr, err)
+	}
+
+	deep := db.Match("id", db.OpEq, 1)
+	for range 10 {
+		deep = db.Negate(deep)
+	}
+	many := make([]db.Filter, 40)
+	for i := range many {
+		many[i] = db.Match("id", db.OpEq, i)
+	}
+	for name, bad := range map[string]db.Filter{
+		"unknown field":    db.Match("password", db.OpEq, "x"),
+		"unknown operator": db.Match("name", "regex", ".*"),
+		"object value":     db.Match("name", db.OpEq, map[string]any{"a": 1}),
+		"empty in":         db.Match("id", db.OpIn, []any{}),
+		"empty typed in":   db.Match("id", db.OpIn, []int64{}),
+		"bytes in":         db.Match("id", db.OpIn, []byte("ab")),
+		"nested in":        db.Match("id", db.OpIn, [][]int{{1}}),
+		"two forms":        {Field: "id", Op: db.OpEq, Value: 1, Or: []db.Filter{db.Match("id", db.OpEq, 2)}},
+		"too deep":         deep,
+		"too many terms":   db.AllOf(many...),
+	} {
+		if _, _, err := db.CompileFilter(db.DialectPostgres, &bad, allowed); !db.IsInvalidFilter(err) {
+			t.Errorf("%s: expected ErrInvalidFilter, got %v", name, err)
+		}
+	}
+	for _, bad := range []string{`{"field": "id", "op": "eq", "value": 1, "limit": 5}`, `{"and": [{"field": 3}]}`, `[`} {
+		if _, err := db.ParseFilter(bad); !db.IsInvalidFilter(err) {
+			t.Errorf("ParseFilter(%s) = %v, want ErrInvalidFilter", bad, err)
+		}
+	}
+
+	d := newTestDB(t)
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES
+		('A_1', 'a@x', $1, $1), ('Ab', 'b@x', $1, $1), ('Bo', 'c@x', $1, $1)`, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	c = db.NewConditions(db.DollarPlaceholder)
+	if err := c.AddFilter(f, allowed); err != nil {
+		t.Fatal(err)
+	}
+	names, err := db.Select(ctx, d, func(r db.RowScanner) (string, error) {
+		var s string
+		return s, r.Scan(&s)
+	}, "SELECT name FROM users "+c.Where()+" ORDER BY id", c.Args()...)
+	if err != nil || !reflect.DeepEqual(names, []string{"A_1"}) {
+		t.Fatalf("filtered select = %v, %v", names, err)
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Relation loading
+// ─────────────────────────────────────────────────────────────────────────────
+
+type relUser struct {
+	ID    int64
+	Posts []relPost
+}
+
+type relPost struct {
+	ID, UserID int64
+	Title      string
+}
+
+func TestLoadChildren(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER, title TEXT)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	for _, p := range []relPost{{1, 1, "a"}, {2, 2, "b"}, {3, 1, "c"}, {4, 3, "draft"}, {5, 4, "x"}} {
+		if _, err := d.Exec(ctx, `INSERT INTO posts VALUES ($1, $2, $3)`, p.ID, p.UserID, p.Title); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	users := []*relUser{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 1}, {ID: 9}}
+	err := db.LoadChildren(ctx, d, users, db.ChildQuery[relPost, int64]{
+		SQL:  `SELECT id, user_id, title FROM posts WHERE title NOT LIKE $1 AND user_id IN (%s) ORDER BY id`,
+		Args: []any{"draft%"},
+		Scan: func(rs db.RowScanner) (relPost, error) {
+			var p relPost
+			return p, rs.Scan(&p.ID, &p.UserID, &p.Title)
+		},
+		Key:       func(p relPost) int64 { return p.UserID },
+		BatchSize: 2,
+	}, func(u *relUser) int64 { return u.ID }, func(u *relUser, posts []relPost) { u.Posts = posts })
+	if err != nil {
+		t.Fatalf("LoadChildren: %v", err)
+	}
+
+	titles := func(u *relUser) string {
+		var ts []string
+		for _, p := range u.Posts {
+			ts = append(ts, p.Title)
+		}
+		return strings.Join(ts, ",")
+	}
+	for i, want := range []string{"a,c", "b", "", "a,c", ""} {
+		if got := titles(users[i]); got != want {
+			t.Errorf("users[%d] posts = %q, want %q", i, got, want)
+		}
+	}
+	if users[2].Posts != nil || users[4].Posts != nil {
+		t.Errorf("parents without children should get nil")
+	}
+
+	bad := db.ChildQuery[relPost, int64]{SQL: `SELECT id FROM posts`}
+	if err := db.LoadChildren(ctx, d, users, bad, func(u *relUser) int64 { return u.ID }, func(*relUser, []relPost) {}); err == nil {
+		t.Fatal("expected an error for a query without a key marker")
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Table
+// ─────────────────────────────────────────────────────────────────────────────
+
+type membership struct {
+	TenantID, UserID int64
+	Role             string
+}
+
+type membershipKey struct {
+	Tenant int64 `db:"tenant_id"`
+	UserID int64
+}
+
+func TestTable_CompositeKey(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE memberships (tenant_id INTEGER, user_id INTEGER, role TEXT, PRIMARY KEY (tenant_id, user_id))`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	for _, m := range []membership{{1, 10, "admin"}, {2, 10, "viewer"}} {
+		if _, err := d.Exec(ctx, `INSERT INTO memberships VALUES ($1, $2, $3)`, m.TenantID, m.UserID, m.Role); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	tbl := db.MustTable[membership, membershipKey](db.TableSpec[membership]{
+		Name:    "memberships",
+		Columns: []string{"tenant_id", "user_id", "role"},
+		Key:     []string{"tenant_id", "user_id"},
+		Scan: func(rs db.RowScanner) (membership, error) {
+			var m membership
+			return m, rs.Scan(&m.TenantID, &m.UserID, &m.Role)
+		},
+	})
+
+	m, err := tbl.Get(ctx, d, membershipKey{Tenant: 2, UserID: 10})
+	if err != nil || m.Role != "viewer" {
+		t.Fatalf("Get = %+v, %v", m, err)
+	}
+	if err := tbl.Update(ctx, d, membershipKey{Tenant: 2, UserID: 10}, map[string]any{"role": "editor"}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := tbl.Update(ctx, d, membershipKey{Tenant: 2, UserID: 10}, map[string]any{"tenant_id": 3}); !db.IsInvalidFilter(err) {
+		t.Fatalf("Update of a key column = %v", err)
+	}
+	if err := tbl.Update(ctx, d, membershipKey{Tenant: 3, UserID: 10}, map[string]any{"role": "x"}); !db.IsNotFound(err) {
+		t.Fatalf("Update of a missing row = %v", err)
+	}
+	if err := tbl.Delete(ctx, d, membershipKey{Tenant: 1, UserID: 10}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := tbl.Get(ctx, d, membershipKey{Tenant: 1, UserID: 10}); !db.IsNotFound(err) {
+		t.Fatalf("Get after Delete = %v", err)
+	}
+	if m, _ := tbl.Get(ctx, d, membershipKey{Tenant: 2, UserID: 10}); m.Role != "editor" {
+		t.Fatalf("other row changed: %+v", m)
+	}
+
+	// Scalar keys address single-column tables.
+	users := db.MustTable[string, int64](db.TableSpec[string]{
+		Name:    "users",
+		Columns: []string{"name"},
+		Scan:    func(rs db.RowScanner) (s string, err error) { return s, rs.Scan(&s) },
+	})
+	if _, err := users.Get(ctx, d, 1); !db.IsNotFound(err) {
+		t.Fatalf("scalar Get = %v", err)
+	}
+	if _, err := db.NewTable[string, int64](db.TableSpec[string]{
+		Name: "memberships", Columns: []string{"role"}, Key: []string{"tenant_id", "user_id"},
+		Scan: func(rs db.RowScanner) (s string, err error) { return s, rs.Scan(&s) },
+	}); err == nil {
+		t.Fatal("NewTable accepted a scalar key for a composite primary key")
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Enum
+// ─────────────────────────────────────────────────────────────────────────────
+
+type orderStatus string
+
+const (
+	orderPending orderStatus = "pending"
+	orderPaid    orderStatus = "paid"
+)
+
+var orderStatusValues = db.RegisterEnum(orderPending, orderPaid)
+
+func TestEnum(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE orders (id INTEGER PRIMARY KEY, status TEXT)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+
+	if _, err := d.Exec(ctx, `INSERT INTO orders VALUES (1, $1), (2, NULL), (3, 'refunded')`, db.NewEnum(orderPaid)); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	var s db.Enum[orderStatus]
+	if err := d.QueryRow(ctx, `SELECT status FROM orders WHERE id = 1`).Scan(&s); err != nil || s != db.NewEnum(orderPaid) {
+		t.Fatalf("scan = %+v, %v", s, err)
+	}
+	if err := d.QueryRow(ctx, `SELECT status FROM orders WHERE id = 2`).Scan(&s); err != nil || s.Valid {
+		t.Fatalf("scan NULL = %+v, %v", s, err)
+	}
+	var enumErr *db.EnumError
+	err := d.QueryRow(ctx, `SELECT status FROM orders WHERE id = 3`).Scan(&s)
+	if !errors.As(err, &enumErr) || enumErr.Value != "refunded" {
+		t.Fatalf("scan of unknown value = %v", err)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO orders VALUES (4, $1)`, db.NewEnum(orderStatus("shipped"))); !errors.As(err, &enumErr) {
+		t.Fatalf("write of unknown value = %v", err)
+	}
+
+	if v, err := orderStatusValues.Parse("pending"); err != nil || v != orderPending {
+		t.Fatalf("Parse = %q, %v", v, err)
+	}
+	if !slices.Equal(orderStatusValues.Values(), []orderStatus{orderPending, orderPaid}) {
+		t.Fatalf("Values = %v", orderStatusValues.Values())
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Booleans
+// ─────────────────────────────────────────────────────────────────────────────
+
+func TestParseBool(t *testing.T) {
+	for _, src := range []any{true, int64(1), 2.5, []byte{1}, []byte("t"), "TRUE", " yes ", "on"} {
+		if v, err := db.ParseBool(src); err != nil || !v {
+			t.Errorf("ParseBool(%#v) = %v, %v; want true", src, v, err)
+		}
+	}
+	for _, src := range []any{false, int64(0), []byte{0}, []byte("0"), "f", "No", "off"} {
+		if v, err := db.ParseBool(src); err != nil || v {
+			t.Errorf("ParseBool(%#v) = %v, %v; want false", src, v, err)
+		}
+	}
+	if _, err := db.ParseBool("maybe"); err == nil {
+		t.Error(`ParseBool("maybe") succeeded`)
+	}
+}
+
+func TestBool_NormalizeAcrossStorage(t *testing.T) {
+	ctx := context.Background()
+	for _, normalize := range []bool{false, true} {
+		d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", NormalizeBools: normalize})
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		defer d.Close()
+		// SQLite keeps whatever it is given; BIT has no affinity of its own.
+		if _, err := d.Exec(ctx, `CREATE TABLE flags (id INTEGER PRIMARY KEY, on_int BIT, on_text BIT)`); err != nil {
+			t.Fatalf("schema: %v", err)
+		}
+		if _, err := d.Exec(ctx, `INSERT INTO flags VALUES (1, 1, 'true'), (2, 0, 'f'), (3, NULL, NULL), (4, 0, x'0105')`); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+
+		var a, b db.Bool
+		if err := d.QueryRow(ctx, `SELECT on_int, on_text FROM flags WHERE id = 1`).Scan(&a, &b); err != nil || !a || !b {
+			t.Fatalf("scan: %v %v %v", a, b, err)
+		}
+		if err := d.QueryRow(ctx, `SELECT on_int, on_text FROM flags WHERE id = 3`).Scan(&a, &b); err != nil || a || b {
+			t.Fatalf("scan NULL: %v %v %v", a, b, err)
+		}
+
+		var got []any
+		err = db.Stream(ctx, d, `SELECT on_int, on_text FROM flags ORDER BY id`, nil, func(_ []string, vals []any) error {
+			got = append(got, vals...)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("stream: %v", err)
+		}
+		// x'0105' is what a wider BIT column holds: not a boolean, so it
+		// passes through.
+		want := []any{true, true, false, false, nil, nil, false, []byte{1, 5}}
+		if !normalize {
+			want = []any{int64(1), "true", int64(0), "f", nil, nil, int64(0), []byte{1, 5}}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("NormalizeBools=%v: stream = %#v, want %#v", normalize, got, want)
+		}
+	}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Struct scanning
+// ─────────────────────────────────────────────────────────────────────────────
+
+type userID string
+
+type scannedSession struct {
+	ID      userID `db:"user_id"`
+	IP      netip.Addr
+	LastIP  *netip.Addr
+	TTL     time.Duration `db:"ttl_ms"`
+	Active  db.Bool
+	Comment string
+}
+
+func TestSelectStructs_RegisterScanner(t *testing.T) {
+	db.RegisterScanner(reflect.TypeFor[netip.Addr](), func(src any) (any, error) {
+		s, err := db.AsString(src)
+		if err != nil {
+			return nil, err
+		}
+		return netip.ParseAddr(s)
+	})
+	db.RegisterScanner(reflect.TypeFor[time.Duration](), func(src any) (any, error) {
+		ms, ok := src.(int64)
+		if !ok {
+			return nil, fmt.Errorf("want milliseconds, got %T", src)
+		}
+		return time.Duration(ms) * time.Millisecond, nil
+	})
+	// Custom IDs convert from the driver's string without a converter.
+
+	d := newTestDB(t)
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE sessions (user_id TEXT, ip TEXT, last_ip TEXT, ttl_ms INTEGER, active INTEGER)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO sessions VALUES ('u1', '10.0.0.1', NULL, 1500, 1), ('u2', '::1', '10.0.0.2', 0, 0)`); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	got, err := db.SelectStructs[*scannedSession](ctx, d, `SELECT user_id, ip, last_ip, ttl_ms, active FROM sessions ORDER BY user_id`)
+	if err != nil {
+		t.Fatalf("SelectStructs: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("got %d rows", len(got))
+	}
+	if s := got[0]; s.ID != "u1" || s.IP != netip.MustParseAddr("10.0.0.1") || s.LastIP != nil || s.TTL != 1500*time.Millisecond || !s.Active {
+		t.Errorf("row 1 = %+v", s)
+	}
+	if s := got[1]; s.LastIP == nil || *s.LastIP != netip.MustParseAddr("10.0.0.2") || s.Active {
+		t.Errorf("row 2 = %+v", s)
+	}
+
+	one, err := db.GetStruct[scannedSession](ctx, d, `SELECT ip FROM sessions WHERE user_id = $1`, "u2")
+	if err != nil || !one.IP.IsLoopback() {
+		t.Fatalf("GetStruct = %+v, %v", one, err)
+	}
+	if _, err := db.GetStruct[scannedSession](ctx, d, `SELECT ip FROM sessions WHERE user_id = 'nobody'`); !db.IsNotFound(err) {
+		t.Fatalf("GetStruct of no rows = %v", err)
+	}
+	if _, err := db.GetStruct[scannedSession](ctx, d, `SELECT ip, 1 AS mystery FROM sessions`); err == nil {
+		t.Fatal("expected an error for a column without a field")
+	}
+	if _, err := db.GetStruct[scannedSession](ctx, d, `SELECT 'not an ip' AS ip`); err == nil {
+		t.Fatal("expected the converter error")
+	}
+}
+
+func TestDBError_CallContext(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	insert := `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`
+	if _, err := d.Exec(ctx, insert, "a", "dup@x", time.Now()); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	_, err := d.Exec(ctx, insert, "b", "dup@x", time.Now())
+	var dbe *db.DBError
+	if !errors.As(err, &dbe) || dbe.Op != db.OpExec || dbe.Query != insert || dbe.Duration <= 0 {
+		t.Fatalf("Exec error = %#v", err)
+	}
+	if strings.Contains(err.Error(), "INSERT") {
+		t.Errorf("query leaked into Error(): %v", err)
+	}
+
+	var id int64
+	err = d.QueryRow(ctx, `SELECT id FROM users WHERE email = $1`, "none").Scan(&id)
+	if !errors.As(err, &dbe) || dbe.Op != db.OpQueryRow || !strings.HasPrefix(dbe.Query, "SELECT id") || !db.IsNotFound(err) {
+		t.Fatalf("QueryRow error = %#v", err)
+	}
+
+	var buf strings.Builder
+	slog.New(slog.NewTextHandler(&buf, nil)).Error("lookup failed", slog.Any("error", err))
+	if !strings.Contains(buf.String(), "error.op=query_row") || !strings.Contains(buf.String(), `error.query="SELECT id`) {
+		t.Errorf("log output = %s", buf.String())
+	}
+}
+
+type pgCodeError struct{ code string }
+
+func (e pgCodeError) Error() string   { return "pq: " + e.code }
+func (e pgCodeError) GetCode() string { return e.code }
+
+type mysqlNumberError struct{ n uint16 }
+
+func (e mysqlNumberError) Error() string  { return fmt.Sprintf("Error %d", e.n) }
+func (e mysqlNumberError) Number() uint16 { return e.n }
+
+func TestErrorMapper_InputViolations(t *testing.T) {
+	m := db.DefaultErrorMapper()
+	for _, tc := range []struct {
+		err  error
+		want error
+	}{
+		{pgCodeError{"23502"}, db.ErrNotNullViolation},
+		{pgCodeError{"22001"}, db.ErrInvalidData}, // string_data_right_truncation
+		{pgCodeError{"22P02"}, db.ErrInvalidData}, // invalid_text_representation
+		{mysqlNumberError{1048}, db.ErrNotNullViolation},
+		{mysqlNumberError{1406}, db.ErrInvalidData},
+	} {
+		if err := m.Map(tc.err); !errors.Is(err, tc.want) {
+			t.Errorf("%v: got %v, want %v", tc.err, err, tc.want)
+		}
+	}
+
+	d := newTestDB(t)
+	_, err := d.Exec(context.Background(), `INSERT INTO users (name, email) VALUES ('x', 'x@x')`)
+	if !db.IsNotNullViolation(err) {
+		t.Fatalf("sqlite: expected ErrNotNullViolation, got %v", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	m := db.DefaultErrorMapper()
+	for _, tc := range []struct {
+		err  error
+		want error
+	}{
+		{pgCodeError{"53300"}, db.ErrResourceExhausted},
+		{pgCodeError{"57P03"}, db.ErrConnectionFailed},
+		{mysqlNumberError{1040}, db.ErrResourceExhausted},
+	} {
+		err := m.Map(tc.err)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%v: got %v, want %v", tc.err, err, tc.want)
+		}
+		if d, ok := db.RetryAfter(err); !ok || d <= 0 {
+			t.Errorf("%v: expected a RetryAfter hint, got %v %v", tc.err, d, ok)
+		}
+	}
+	if _, ok := db.RetryAfter(m.Map(pgCodeError{"40P01"})); ok {
+		t.Error("deadlock should carry no RetryAfter hint")
+	}
+
+	// The hint overrides a shorter Delay and makes the error retryable.
+	hinted := &db.DBError{Sentinel: db.ErrResourceExhausted, RetryAfter: 50 * time.Millisecond}
+	calls := 0
+	start := time.Now()
+	err := db.WithRetry(context.Background(), db.RetryConfig{MaxAttempts: 3, Delay: time.Millisecond}, func() error {
+		if calls++; calls == 1 {
+			return hinted
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("expected success on the second attempt, got %v after %d calls", err, calls)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("retried after %v, want at least the 50ms hint", elapsed)
+	}
+}
+
+func TestAdaptiveTimeout(t *testing.T) {
+	ctx := context.Background()
+	h := &ctxHook{}
+	d, err := db.Open(db.Config{
+		DSN:            ":memory:",
+		DriverName:     "sqlite3",
+		Hooks:          []db.Hook{h},
+		DefaultTimeout: 5 * time.Second,
+		AdaptiveTimeout: &db.AdaptiveTimeout{
+			MinSamples: 10,
+			Min:        20 * time.Millisecond,
+			Max:        time.Second,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	const q = `SELECT 1`
+	if got := d.QueryTimeout(q); got != 5*time.Second {
+		t.Fatalf("without history: got %v, want DefaultTimeout", got)
+	}
+	for range 10 {
+		var n int
+		if err := d.QueryRow(ctx, q).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// SQLite answers in microseconds, so p99×3 is clamped up to Min.
+	if got := d.QueryTimeout(q); got != 20*time.Millisecond {
+		t.Fatalf("after 10 samples: got %v, want the 20ms floor", got)
+	}
+	if got := d.QueryTimeout(`SELECT name FROM sqlite_master`); got != 5*time.Second {
+		t.Fatalf("other fingerprint: got %v, want DefaultTimeout", got)
+	}
+
+	// Query's deadline covers iterating the rows, which the learned
+	// latency does not measure.
+	rows, err := d.Query(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	if _, limit, _ := db.TimeoutRemaining(h.ctxs[len(h.ctxs)-1]); limit != 5*time.Second {
+		t.Fatalf("Query deadline = %v, want DefaultTimeout", limit)
+	}
+}
+
+// ctxHook keeps the context each statement ran under.
+type ctxHook struct {
+	ctxs []context.Context
+}
+
+func (h *ctxHook) BeforeQuery(ctx context.Context, _ string, _ []any)              { h.ctxs = append(h.ctxs, ctx) }
+func (h *ctxHook) AfterQuery(context.Context, string, []any, time.Duration, error) {}
+
+func TestDefaultTimeout_Released(t *testing.T) {
+	ctx := context.Background()
+	h := &ctxHook{}
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1,
+		DefaultTimeout: time.Minute, Hooks: []db.Hook{h}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if _, err := d.Exec(ctx, `CREATE TABLE t (n INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	left, limit, ok := db.TimeoutRemaining(h.ctxs[0])
+	if !ok || limit != time.Minute || left <= 0 || left > time.Minute {
+		t.Fatalf("TimeoutRemaining = %v, %v, %v; want the applied minute", left, limit, ok)
+	}
+	if h.ctxs[0].Err() == nil {
+		t.Fatal("Exec must release its deadline when it returns")
+	}
+
+	row := d.QueryRow(ctx, `SELECT COUNT(*) FROM t`)
+	if h.ctxs[1].Err() != nil {
+		t.Fatal("QueryRow must keep its deadline until Scan")
+	}
+	var n int
+	if err := row.Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if h.ctxs[1].Err() == nil {
+		t.Fatal("Scan must release the deadline")
+	}
+
+	if _, err := db.Select(ctx, d, func(r db.RowScanner) (n int, err error) { return n, r.Scan(&n) }, `SELECT n FROM t`); err != nil {
+		t.Fatal(err)
+	}
+	if h.ctxs[2].Err() == nil {
+		t.Fatal("Select must release the deadline as it closes the rows")
+	}
+
+	// Query releases on Close and when Next runs out, without waiting for a
+	// garbage collection.
+	rows, err := d.Query(ctx, `SELECT n FROM t`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.ctxs[3].Err() != nil {
+		t.Fatal("Query must keep its deadline while the rows are open")
+	}
+	rows.Close()
+	if h.ctxs[3].Err() == nil {
+		t.Fatal("rows.Close must release the Query deadline")
+	}
+	if rows, err = d.Query(ctx, `SELECT 1`); err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		if h.ctxs[4].Err() != nil {
+			t.Fatal("Query deadline released while reading")
+		}
+	}
+	if h.ctxs[4].Err() == nil {
+		t.Fatal("Next returning false must release the Query deadline")
+	}
+	rows.Close()
+	h.ctxs = h.ctxs[:2]
+
+	own, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+	if _, err := d.Exec(own, `DELETE FROM t`); err != nil {
+		t.Fatal(err)
+	}
+	if _, limit, ok := db.TimeoutRemaining(h.ctxs[2]); !ok || limit != 0 {
+		t.Fatalf("caller deadline: limit %v ok %v; want 0, true", limit, ok)
+	}
+	if own.Err() != nil {
+		t.Fatal("the caller's own context must not be cancelled")
+	}
+
+	if _, err := d.Exec(db.NoTimeout(ctx), `INSERT INTO t (n) VALUES (1)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := db.TimeoutRemaining(h.ctxs[3]); ok {
+		t.Fatal("NoTimeout must bypass DefaultTimeout")
+	}
+}
+
+func TestPrepareOnOpen(t *testing.T) {
+	ctx := context.Background()
+	const q = `SELECT count(*) FROM sqlite_master WHERE type = $1`
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", PrepareOnOpen: []string{q}})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	var n int
+	if err := d.QueryRow(ctx, q, "table").Scan(&n); err != nil || n != 0 {
+		t.Fatalf("prepared query: n=%d err=%v", n, err)
+	}
+	_ = d.Close()
+
+	_, err = db.Open(db.Config{
+		DSN:           ":memory:",
+		DriverName:    "sqlite3",
+		PrepareOnOpen: []string{q, `SELECT id FROM missing_table`},
+	})
+	if err == nil || !strings.Contains(err.Error(), "missing_table") {
+		t.Fatalf("expected Open to fail naming the bad statement, got %v", err)
+	}
+}
+
+func TestWithMaxRows(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	for i := range 5 {
+		_, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`,
+			fmt.Sprintf("u%d", i), fmt.Sprintf("u%d@x", i), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	scanID := func(r db.RowScanner) (int64, error) {
+		var id int64
+		return id, r.Scan(&id)
+	}
+
+	ids, err := db.Select(db.WithMaxRows(ctx, 3), d, scanID, `SELECT id FROM users ORDER BY id`)
+	if !db.IsTruncated(err) || len(ids) != 3 {
+		t.Fatalf("expected 3 rows and ErrTruncated, got %d rows, %v", len(ids), err)
+	}
+	ids, err = db.Select(db.WithMaxRows(ctx, 5), d, scanID, `SELECT id FROM users ORDER BY id`)
+	if err != nil || len(ids) != 5 {
+		t.Fatalf("limit equal to the result: got %d rows, %v", len(ids), err)
+	}
+
+	streamed := 0
+	err = db.Stream(db.WithMaxRows(ctx, 2), d, `SELECT id FROM users`, nil, func([]string, []any) error {
+		streamed++
+		return nil
+	})
+	if !db.IsTruncated(err) || streamed != 2 {
+		t.Fatalf("Stream: expected 2 rows and ErrTruncated, got %d, %v", streamed, err)
+	}
+}
+
+func TestWithMaxRowBytes(t *testing.T) {
+	ctx := context.Background()
+	d := newTestDB(t)
+	big := strings.Repeat("x", 1000)
+	_, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`,
+		"short", big, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []any
+	err = db.Stream(db.WithMaxRowBytes(ctx, 10), d, `SELECT id, name, email FROM users`, nil,
+		func(_ []string, vals []any) error {
+			got = append([]any(nil), vals...)
+			return nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := got[0].(int64); !ok || id != 1 {
+		t.Errorf("id should pass through, got %#v", got[0])
+	}
+	if name, ok := got[1].(string); !ok || name != "short" {
+		t.Errorf("name fits the budget and should be kept, got %#v", got[1])
+	}
+	tv, ok := got[2].(db.TruncatedValue)
+	if !ok || tv.Size != 1000 || string(tv.Prefix) != "xxxxx" {
+		t.Fatalf("email should be truncated to the remaining 5 bytes, got %#v", got[2])
+	}
+}
+
+func TestTemporalTable_AsOf(t *testing.T) {
+	users := db.TemporalTable{Name: "users"}
+
+	got, err := users.AsOf(db.DialectMySQL, 2)
+	if want := "`users` FOR SYSTEM_TIME AS OF TIMESTAMP $2"; err != nil || got != want {
+		t.Errorf("mysql: got %q, %v; want %q", got, err, want)
+	}
+	got, err = users.AsOf(db.DialectPostgres, 2)
+	want := `(SELECT * FROM "users" WHERE "sys_period" @> $2::timestamptz` +
+		` UNION ALL SELECT * FROM "users_history" WHERE "sys_period" @> $2::timestamptz) AS "users"`
+	if err != nil || got != want {
+		t.Errorf("postgres: got %q, %v; want %q", got, err, want)
+	}
+	if _, err := users.AsOf(db.DialectSQLite, 2); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("sqlite: expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	ctx := context.Background()
+	var qs []db.Querier
+	for i := range 3 {
+		d := newTestDB(t)
+		_, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`,
+			fmt.Sprintf("region%d", i), fmt.Sprintf("r%d@x", i), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		qs = append(qs, d)
+	}
+	scanName := func(r db.RowScanner) (string, error) {
+		var s string
+		return s, r.Scan(&s)
+	}
+
+	names, err := db.FanOut(ctx, qs, `SELECT name FROM users WHERE id = $1`, []any{1}, scanName, 2)
+	if err != nil || strings.Join(names, ",") != "region0,region1,region2" {
+		t.Fatalf("got %v, %v", names, err)
+	}
+
+	if _, err := qs[1].Exec(ctx, `DROP TABLE users`); err != nil {
+		t.Fatal(err)
+	}
+	names, err = db.FanOut(ctx, qs, `SELECT name FROM users`, nil, scanName)
+	var fe *db.FanOutError
+	if !errors.As(err, &fe) || fe.Errs[0] != nil || fe.Errs[1] == nil || fe.Errs[2] != nil {
+		t.Fatalf("expected only #1 to fail, got %v", err)
+	}
+	if strings.Join(names, ",") != "region0,region2" {
+		t.Fatalf("expected the healthy databases' rows, got %v", names)
+	}
+}
+
+func TestTableStats(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	for i := range 5 {
+		_, err := d.Exec(ctx, `INSERT INTO users (name, email, created_at, updated_at) VALUES ($1, $2, $3, $3)`,
+			fmt.Sprintf("u%d", i), fmt.Sprintf("u%d@x", i), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := d.TableStats(ctx, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Rows != 5 || s.Estimated || s.Analyzed || s.ModifiedSinceAnalyze != -1 {
+		t.Fatalf("unexpected stats: %+v", s)
+	}
+	if stale, reason := s.NeedsAnalyze(db.AnalyzePolicy{}, time.Now()); !stale || reason != "never analyzed" {
+		t.Fatalf("NeedsAnalyze = %v, %q", stale, reason)
+	}
+
+	if analyzed, err := d.AnalyzeIfStale(ctx, "users", db.AnalyzePolicy{}); err != nil || !analyzed {
+		t.Fatalf("first AnalyzeIfStale = %v, %v", analyzed, err)
+	}
+	if analyzed, err := d.AnalyzeIfStale(ctx, "users", db.AnalyzePolicy{}); err != nil || analyzed {
+		t.Fatalf("statistics are fresh; AnalyzeIfStale = %v, %v", analyzed, err)
+	}
+
+	pg := db.TableStat{Rows: 1000, Analyzed: true, ModifiedSinceAnalyze: 150, LastAnalyze: time.Now().Add(-time.Hour)}
+	if stale, reason := pg.NeedsAnalyze(db.AnalyzePolicy{}, time.Now()); !stale || !strings.Contains(reason, "15%") {
+		t.Fatalf("NeedsAnalyze = %v, %q", stale, reason)
+	}
+	pg.ModifiedSinceAnalyze = 50
+	if stale, _ := pg.NeedsAnalyze(db.AnalyzePolicy{}, time.Now()); stale {
+		t.Fatal("5% modified is below the default threshold")
+	}
+	if stale, _ := pg.NeedsAnalyze(db.AnalyzePolicy{MaxAge: time.Minute}, time.Now()); !stale {
+		t.Fatal("statistics older than MaxAge are stale")
+	}
+}
+
+// dsnRecorder is a driver that only records the DSN it is asked to open.
+type dsnRecorder struct{ dsn *string }
+
+func (r dsnRecorder) Open(dsn string) (driver.Conn, error) {
+	*r.dsn = dsn
+	return nil, errors.New("dsnRecorder: not a real database")
+}
+
+func TestConfig_AppName(t *testing.T) {
+	var got string
+	for _, name := range []string{"pgx", "mysql"} {
+		if !slices.Contains(sql.Drivers(), name) {
+			sql.Register(name, dsnRecorder{&got})
+		}
+	}
+	cases := []struct {
+		driver, dsn string
+		attrs       map[string]string
+		want        string
+	}{
+		{"pgx", "postgres://app@db:5432/shop?sslmode=disable", nil,
+			"postgres://app@db:5432/shop?application_name=billing-api&sslmode=disable"},
+		{"pgx", "host=db dbname=shop", nil, "host=db dbname=shop application_name='billing-api'"},
+		{"pgx", "host=db application_name=psql", nil, "host=db application_name=psql"},
+		{"mysql", "app:pw@tcp(db:3306)/shop", map[string]string{"team": "payments"},
+			"app:pw@tcp(db:3306)/shop?connectionAttributes=program_name%3Abilling-api%2Cteam%3Apayments"},
+		{"mysql", "app:pw@tcp(db:3306)/shop?parseTime=true", nil,
+			"app:pw@tcp(db:3306)/shop?parseTime=true&connectionAttributes=program_name%3Abilling-api"},
+	}
+	for _, c := range cases {
+		got = ""
+		_, err := db.Open(db.Config{DriverName: c.driver, DSN: c.dsn, AppName: "billing-api", ConnAttributes: c.attrs})
+		if err == nil {
+			t.Fatal("dsnRecorder cannot connect; Open should fail")
+		}
+		if got != c.want {
+			t.Errorf("%s %q:\ngot  %s\nwant %s", c.driver, c.dsn, got, c.want)
+		}
+	}
+}
+
+func TestConfig_SessionSettings(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(db.Config{
+		DSN: "file:session?mode=memory&cache=shared", DriverName: "sqlite3", MaxOpenConns: 3,
+		SessionSettings: map[string]string{"foreign_keys": "ON", "busy_timeout": "1234"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	// Hold connections open so every check runs on a fresh one.
+	var conns []*sql.Conn
+	defer func() {
+		for _, c := range conns {
+			_ = c.Close()
+		}
+	}()
+	for range 3 {
+		c, err := d.Raw().Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+		var fk, busy int
+		if err := c.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&fk); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.QueryRowContext(ctx, `PRAGMA busy_timeout`).Scan(&busy); err != nil {
+			t.Fatal(err)
+		}
+		if fk != 1 || busy != 1234 {
+			t.Fatalf("connection %d: foreign_keys=%d busy_timeout=%d", len(conns), fk, busy)
+		}
+	}
+
+	_, err = db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3",
+		SessionSettings: map[string]string{"foreign_keys; DROP TABLE users": "ON"}})
+	if err == nil {
+		t.Fatal("setting names must be identifiers")
+	}
+}
+
+// execRecorder is a connection that records the statements executed on it.
+type execRecorder struct{ got *[]string }
+
+func (c execRecorder) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	for _, a := range args {
+		query += fmt.Sprintf(" [%v]", a.Value)
+	}
+	*c.got = append(*c.got, query)
+	return driver.RowsAffected(0), nil
+}
+func (execRecorder) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("execRecorder: no statements")
+}
+func (execRecorder) Close() error { return nil }
+func (execRecorder) Begin() (driver.Tx, error) {
+	return nil, errors.New("execRecorder: no transactions")
+}
+
+type execRecorderConnector struct{ got *[]string }
+
+func (c execRecorderConnector) Connect(context.Context) (driver.Conn, error) {
+	return execRecorder(c), nil
+}
+func (execRecorderConnector) Driver() driver.Driver { return nil }
+
+func TestConfig_SessionSettings_MySQL(t *testing.T) {
+	var got []string
+	d, err := db.Open(db.Config{DriverName: "mysql", Connector: execRecorderConnector{&got},
+		SessionSettings: map[string]string{
+			"lock_wait_timeout": "5", "long_query_time": "0.5", "autocommit": "ON",
+			"time_zone": "+00:00", "sql_mode": "STRICT_ALL_TABLES,NO_ZERO_DATE",
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	want := []string{
+		"SET SESSION autocommit = ON",
+		"SET SESSION lock_wait_timeout = 5",
+		"SET SESSION long_query_time = 0.5",
+		"SET SESSION sql_mode = ? [STRICT_ALL_TABLES,NO_ZERO_DATE]",
+		"SET SESSION time_zone = ? [+00:00]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("statements:\ngot  %q\nwant %q", got, want)
+	}
+}
+
+func TestConfig_SQLiteForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	const schema = `
+		CREATE TABLE parents (id INTEGER PRIMARY KEY);
+		CREATE TABLE children (id INTEGER PRIMARY KEY, parent_id INTEGER NOT NULL REFERENCES parents (id))`
+	insertOrphan := func(cfg db.Config) error {
+		d, err := db.Open(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer d.Close()
+		if _, err := d.Exec(ctx, schema); err != nil {
+			t.Fatal(err)
+		}
+		_, err = d.Exec(ctx, `INSERT INTO children (id, parent_id) VALUES (1, 42)`)
+		return err
+	}
+
+	if err := insertOrphan(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1}); err == nil {
+		t.Fatal("foreign keys must be enforced by default")
+	}
+	if err := insertOrphan(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1,
+		DisableSQLiteForeignKeys: true}); err != nil {
+		t.Fatalf("opt-out should leave foreign keys off: %v", err)
+	}
+	if err := insertOrphan(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1,
+		SessionSettings: map[string]string{"foreign_keys": "OFF"}}); err != nil {
+		t.Fatalf("an explicit session setting wins: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Deadlock simulation
+// ─────────────────────────────────────────────────────────────────────────────
+
+// ProvokeDeadlock runs two concurrent transactions on d that lock the rows of
+// table whose keyColumn equals a and b in opposite order, and returns the
+// error the losing transaction got — already mapped, so on a correctly
+// configured driver db.IsDeadlock(err) holds. It returns an error that is not
+// a lock conflict when both transactions commit.
+//
+// Both rows must exist; they are updated to themselves, so their contents do
+// not change. How the conflict surfaces depends on the database:
+//
+//   - PostgreSQL detects the cycle after deadlock_timeout (1s by default) and
+//     aborts one transaction with SQLSTATE 40P01.
+//   - MySQL/InnoDB detects it immediately (error 1213).
+//   - SQLite locks the whole database: the second writer waits for the busy
+//     timeout and fails with SQLITE_BUSY. d must be a file database with at
+//     least two connections; keep the busy timeout short.
+func ProvokeDeadlock(ctx context.Context, d *db.DB, table, keyColumn string, a, b any) error {
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	col := dialect.QuoteIdent(keyColumn)
+	update := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s = %s", dialect.QuoteIdent(table), col, col, col, ph(1))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	locked := [2]chan struct{}{make(chan struct{}), make(chan struct{})}
+	errs := make(chan error, 2)
+	run := func(self int, first, second any) {
+		errs <- d.ExecTx(ctx, func(tx *db.Tx) error {
+			_, err := tx.Exec(ctx, update, first)
+			close(locked[self]) // release the peer even when the first lock failed
+			if err != nil {
+				return err
+			}
+			select {
+			case <-locked[1-self]:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			_, err = tx.Exec(ctx, update, second)
+			return err
+		})
+	}
+	go run(0, a, b)
+	go run(1, b, a)
+
+	var conflict error
+	for range 2 {
+		if err := <-errs; err != nil && conflict == nil {
+			conflict = err
+		}
+	}
+	if conflict == nil {
+		return errors.New("dbtest: both transactions committed; no lock conflict occurred")
+	}
+	return conflict
+}
+
+// AssertRetryable provokes a deadlock with ProvokeDeadlock and fails t unless
+// retryOn — the predicate given to db.RetryConfig, nil for its default —
+// accepts the resulting error:
+//
+//	dbtest.AssertRetryable(t, conn, "accounts", "id", 1, 2, myRetryOn)
+func AssertRetryable(t testing.TB, d *db.DB, table, keyColumn string, a, b any, retryOn func(error) bool) {
+	t.Helper()
+	if retryOn == nil {
+		retryOn = func(err error) bool { return db.IsDeadlock(err) || db.IsTimeout(err) }
+	}
+	err := ProvokeDeadlock(context.Background(), d, table, keyColumn, a, b)
+	if !retryOn(err) {
+		t.Errorf("dbtest: lock conflict on %s is not retried: %v", d.Dialect(), err)
+	}
+}
@@ -0,0 +1,34 @@
+package dbtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+)
+
+func TestProvokeDeadlock(t *testing.T) {
+	dsn := "file:" + t.TempDir() + "/locks.db?_busy_timeout=50&_journal_mode=WAL"
+	d, err := db.Open(db.Config{DSN: dsn, DriverName: "sqlite3", MaxOpenConns: 2})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE accounts (id INTEGER PRIMARY KEY, balance INTEGER)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	_, _ = d.Exec(ctx, `INSERT INTO accounts (id, balance) VALUES (1, 10), (2, 20)`)
+
+	if err := dbtest.ProvokeDeadlock(ctx, d, "accounts", "id", 1, 2); !db.IsDeadlock(err) {
+		t.Fatalf("expected deadlock, got %v", err)
+	}
+	dbtest.AssertRetryable(t, d, "accounts", "id", 1, 2, nil)
+
+	rec := &recordingTB{TB: t}
+	dbtest.AssertRetryable(rec, d, "accounts", "id", 1, 2, db.IsTimeout)
+	if len(rec.errs) != 1 {
+		t.Fatalf("narrow RetryOn not reported: %v", rec.errs)
+	}
+}
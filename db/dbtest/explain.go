@@ -0,0 +1,245 @@
+// Package dbtest contains helpers for tests that run against a real schema.
+package dbtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// EXPLAIN verification
+// ─────────────────────────────────────────────────────────────────────────────
+
+// ExplainOptions configures an ExplainChecker.
+type ExplainOptions struct {
+	// MaxScanRows is the largest table a full scan is tolerated on.
+	// Defaults to 1000. Seed at least this many rows into tables whose
+	// scans should be caught.
+	MaxScanRows int64
+	// Lookups declares, per table, the columns that must always be served by
+	// an index: a full scan of the table by a statement filtering on one of
+	// them fails regardless of the table size.
+	Lookups map[string][]string
+}
+
+// ExplainChecker is a db.Hook that records every successful SELECT, UPDATE
+// and DELETE issued through a DB, so that Verify can EXPLAIN each of them
+// once the test has exercised the repositories:
+//
+//	ec := dbtest.NewExplainChecker(dbtest.ExplainOptions{
+//	    Lookups: map[string][]string{"users": {"email"}},
+//	})
+//	d := db.MustOpen(db.Config{DSN: dsn, DriverName: "postgres", Hooks: []db.Hook{ec}})
+//	// ... migrate, seed, run repository methods ...
+//	ec.Verify(t, d)
+//
+// On PostgreSQL the plans are produced with enable_seqscan off, so a
+// sequential scan in the plan means no index could serve the statement, not
+// merely that the planner preferred a scan on a small test table.
+type ExplainChecker struct {
+	opts ExplainOptions
+
+	mu        sync.Mutex
+	seen      map[string][]any
+	verifying bool // ignore Verify's own statements
+}
+
+// NewExplainChecker returns an empty checker.
+func NewExplainChecker(opts ExplainOptions) *ExplainChecker {
+	if opts.MaxScanRows <= 0 {
+		opts.MaxScanRows = 1000
+	}
+	return &ExplainChecker{opts: opts, seen: make(map[string][]any)}
+}
+
+var explainable = regexp.MustCompile(`(?is)^\s*(WITH|SELECT|UPDATE|DELETE)\b`)
+
+// BeforeQuery implements db.Hook.
+func (e *ExplainChecker) BeforeQuery(context.Context, string, []any) {}
+
+// AfterQuery implements db.Hook. Only the first arguments seen for each
+// statement are kept.
+func (e *ExplainChecker) AfterQuery(_ context.Context, query string, args []any, _ time.Duration, err error) {
+	if err != nil && !db.IsNotFound(err) || !explainable.MatchString(query) {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.seen[query]; !ok && !e.verifying {
+		e.seen[query] = append([]any(nil), args...)
+	}
+}
+
+// Verify EXPLAINs every recorded statement on d and reports each offending
+// full scan with t.Errorf.
+func (e *ExplainChecker) Verify(t testing.TB, d *db.DB) {
+	t.Helper()
+	e.mu.Lock()
+	e.verifying = true
+	queries := make([]string, 0, len(e.seen))
+	for q := range e.seen {
+		queries = append(queries, q)
+	}
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.verifying = false
+		e.mu.Unlock()
+	}()
+	sort.Strings(queries)
+
+	ctx := context.Background()
+	sizes := map[string]int64{}
+	for _, q := range queries {
+		e.mu.Lock()
+		args := e.seen[q]
+		e.mu.Unlock()
+
+		scans, err := fullScans(ctx, d, q, args)
+		if err != nil {
+			t.Errorf("dbtest: EXPLAIN %s: %v", oneLine(q), err)
+			continue
+		}
+		for _, s := range scans {
+			if col := e.lookupIn(s.table, q); col != "" {
+				t.Errorf("dbtest: full scan of %s although the statement filters on lookup column %s\n  query: %s\n  plan:  %s",
+					s.table, col, oneLine(q), s.detail)
+				continue
+			}
+			n, ok := sizes[s.table]
+			if !ok {
+				if err := d.QueryRow(ctx, "SELECT COUNT(*) FROM "+d.Dialect().QuoteIdent(s.table)).Scan(&n); err != nil {
+					t.Errorf("dbtest: count %s: %v", s.table, err)
+					continue
+				}
+				sizes[s.table] = n
+			}
+			if n > e.opts.MaxScanRows {
+				t.Errorf("dbtest: full scan of %s (%d rows > %d)\n  query: %s\n  plan:  %s",
+					s.table, n, e.opts.MaxScanRows, oneLine(q), s.detail)
+			}
+		}
+	}
+}
+
+// lookupIn returns the first declared lookup column of table that query
+// filters on, or "".
+func (e *ExplainChecker) lookupIn(table, query string) string {
+	i := strings.Index(strings.ToUpper(query), "WHERE")
+	if i < 0 {
+		return ""
+	}
+	where := query[i:]
+	for _, col := range e.opts.Lookups[table] {
+		if regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(col) + `\b`).MatchString(where) {
+			return col
+		}
+	}
+	return ""
+}
+
+// scan is one full table scan found in a plan.
+type scan struct {
+	table  string
+	detail string
+}
+
+// fullScans returns the full table scans in query's plan.
+func fullScans(ctx context.Context, d *db.DB, query string, args []any) ([]scan, error) {
+	switch d.Dialect() {
+	case db.DialectPostgres:
+		var raw []byte
+		err := d.ExecTx(ctx, func(tx *db.Tx) error {
+			if _, err := tx.Exec(ctx, "SET LOCAL enable_seqscan = off"); err != nil {
+				return err
+			}
+			return tx.QueryRow(ctx, "EXPLAIN (FORMAT JSON) "+query, args...).Scan(&raw)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var plans []struct{ Plan pgPlan }
+		if err := json.Unmarshal(raw, &plans); err != nil {
+			return nil, err
+		}
+		var out []scan
+		for _, p := range plans {
+			p.Plan.walk(func(n *pgPlan) {
+				if n.NodeType == "Seq Scan" {
+					out = append(out, scan{table: n.Relation, detail: fmt.Sprintf("Seq Scan on %s (filter: %s)", n.Relation, n.Filter)})
+				}
+			})
+		}
+		return out, nil
+
+	case db.DialectSQLite:
+		var out []scan
+		err := db.Stream(ctx, d, "EXPLAIN QUERY PLAN "+query, args, func(cols []string, vals []any) error {
+			detail := fmt.Sprint(vals[len(vals)-1])
+			// "SCAN users" is a full scan; "SCAN users USING INDEX ..."
+			// walks an index and "SEARCH ..." is a lookup.
+			f := strings.Fields(detail)
+			if len(f) > 2 && f[1] == "TABLE" { // SQLite < 3.36
+				f = f[1:]
+			}
+			if len(f) >= 2 && f[0] == "SCAN" && !strings.Contains(detail, " USING ") &&
+				f[1] != "CONSTANT" && !strings.HasPrefix(f[1], "(") {
+				out = append(out, scan{table: f[1], detail: detail})
+			}
+			return nil
+		})
+		return out, err
+
+	case db.DialectMySQL:
+		var out []scan
+		err := db.Stream(ctx, d, "EXPLAIN "+query, args, func(cols []string, vals []any) error {
+			row := map[string]string{}
+			for i, c := range cols {
+				row[c] = asString(vals[i])
+			}
+			if row["type"] == "ALL" {
+				out = append(out, scan{table: row["table"], detail: fmt.Sprintf("type=ALL table=%s rows=%s extra=%s", row["table"], row["rows"], row["Extra"])})
+			}
+			return nil
+		})
+		return out, err
+	}
+	return nil, fmt.Errorf("EXPLAIN not supported for dialect %q", d.Dialect())
+}
+
+type pgPlan struct {
+	NodeType string   `json:"Node Type"`
+	Relation string   `json:"Relation Name"`
+	Filter   string   `json:"Filter"`
+	Plans    []pgPlan `json:"Plans"`
+}
+
+func (p *pgPlan) walk(fn func(*pgPlan)) {
+	fn(p)
+	for i := range p.Plans {
+		p.Plans[i].walk(fn)
+	}
+}
+
+func asString(v any) string {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+func oneLine(q string) string { return strings.Join(strings.Fields(q), " ") }
+
+var _ db.Hook = (*ExplainChecker)(nil)
@@ -0,0 +1,65 @@
+package dbtest_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// recordingTB captures Errorf calls so failures can be asserted on.
+type recordingTB struct {
+	testing.TB
+	errs []string
+}
+
+func (r *recordingTB) Helper() {}
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errs = append(r.errs, fmt.Sprintf(format, args...))
+}
+
+func TestExplainChecker(t *testing.T) {
+	ec := dbtest.NewExplainChecker(dbtest.ExplainOptions{
+		MaxScanRows: 10,
+		Lookups:     map[string][]string{"users": {"email", "name"}},
+	})
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1, Hooks: []db.Hook{ec}})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	for _, q := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT, country TEXT)`,
+		`CREATE INDEX idx_users_email ON users (email)`,
+		`CREATE TABLE tags (id INTEGER PRIMARY KEY, label TEXT)`,
+	} {
+		if _, err := d.Exec(ctx, q); err != nil {
+			t.Fatalf("schema: %v", err)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		_, _ = d.Exec(ctx, `INSERT INTO users (name, email, country) VALUES ($1, $2, 'nl')`, fmt.Sprint("u", i), fmt.Sprint("u", i, "@x"))
+	}
+	_, _ = d.Exec(ctx, `INSERT INTO tags (label) VALUES ('a')`)
+
+	var n int
+	_ = d.QueryRow(ctx, `SELECT id FROM users WHERE email = $1`, "u1@x").Scan(&n) // index lookup
+	_ = d.QueryRow(ctx, `SELECT id FROM users WHERE id = $1`, 1).Scan(&n)         // primary key
+	_ = d.QueryRow(ctx, `SELECT count(*) FROM tags`).Scan(&n)                     // small table
+	_ = d.QueryRow(ctx, `SELECT id FROM users WHERE name = $1`, "u1").Scan(&n)    // lookup without index
+	_ = d.QueryRow(ctx, `SELECT id FROM users WHERE country = $1`, "nl").Scan(&n) // large table scan
+
+	rec := &recordingTB{TB: t}
+	ec.Verify(rec, d)
+	if len(rec.errs) != 2 {
+		t.Fatalf("got %d failures, want 2:\n%s", len(rec.errs), strings.Join(rec.errs, "\n"))
+	}
+	if !strings.Contains(rec.errs[0], "20 rows > 10") || !strings.Contains(rec.errs[1], "lookup column name") {
+		t.Fatalf("unexpected failures:\n%s", strings.Join(rec.errs, "\n"))
+	}
+}
@@ -0,0 +1,137 @@
+package dbtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Golden-query recording
+// ─────────────────────────────────────────────────────────────────────────────
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden rewrite
+// golden files instead of comparing against them:
+//
+//	DBTEST_UPDATE_GOLDEN=1 go test ./repo/...
+const UpdateGoldenEnv = "DBTEST_UPDATE_GOLDEN"
+
+// Statement is one statement captured by a Recorder.
+type Statement struct {
+	Query string
+	Args  []any
+}
+
+// Recorder is a db.Hook that captures every statement issued through a DB,
+// with its arguments, in order. Compared against a golden file it pins the
+// exact SQL a repository emits, so a refactor of a query builder cannot
+// change it unnoticed:
+//
+//	rec := dbtest.NewRecorder()
+//	d := db.MustOpen(db.Config{..., Hooks: []db.Hook{rec},
+//	    Clock: db.NewFakeClock(fixed)}) // stable timestamp arguments
+//	// ... schema and fixtures ...
+//	rec.Reset()
+//	_, _ = users.Update(ctx, params)
+//	rec.AssertGolden(t, "testdata/user_update.golden")
+type Recorder struct {
+	mu    sync.Mutex
+	stmts []Statement
+}
+
+// NewRecorder returns an empty Recorder; add it to Config.Hooks.
+func NewRecorder() *Recorder { return &Recorder{} }
+
+// BeforeQuery implements db.Hook. Statements are recorded before they run,
+// so failed ones are included.
+func (r *Recorder) BeforeQuery(_ context.Context, query string, args []any) {
+	r.mu.Lock()
+	r.stmts = append(r.stmts, Statement{Query: query, Args: append([]any(nil), args...)})
+	r.mu.Unlock()
+}
+
+// AfterQuery implements db.Hook.
+func (r *Recorder) AfterQuery(context.Context, string, []any, time.Duration, error) {}
+
+// Statements returns the statements recorded so far.
+func (r *Recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.stmts...)
+}
+
+// Reset discards the recorded statements, e.g. after schema setup.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.stmts = nil
+	r.mu.Unlock()
+}
+
+// String renders the statements in the golden file format: one block per
+// statement, whitespace collapsed, arguments on the following line.
+func (r *Recorder) String() string {
+	var b strings.Builder
+	for i, s := range r.Statements() {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		fmt.Fprintf(&b, "-- %d\n%s;\n", i+1, oneLine(s.Query))
+		if len(s.Args) > 0 {
+			args := make([]string, len(s.Args))
+			for j, a := range s.Args {
+				args[j] = formatArg(a)
+			}
+			fmt.Fprintf(&b, "-- args: %s\n", strings.Join(args, ", "))
+		}
+	}
+	return b.String()
+}
+
+// AssertGolden compares the recorded statements with the file at path and
+// reports a difference with t.Errorf. With DBTEST_UPDATE_GOLDEN set, the file
+// is written instead.
+func (r *Recorder) AssertGolden(t testing.TB, path string) {
+	t.Helper()
+	got := []byte(r.String())
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("dbtest: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("dbtest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("dbtest: read golden file (run with %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("dbtest: statements differ from %s (run with %s=1 to accept)\n--- want\n%s--- got\n%s",
+			path, UpdateGoldenEnv, want, got)
+	}
+}
+
+func formatArg(a any) string {
+	switch v := a.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return fmt.Sprintf("x%q", v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(a)
+}
+
+var _ db.Hook = (*Recorder)(nil)
@@ -0,0 +1,287 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Snapshot / restore
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Image is a saved database state taken by Snapshot.
+type Image struct {
+	dialect db.Dialect
+
+	// SQLite: a copy of the database file.
+	path string
+
+	// PostgreSQL: a template database on the same server.
+	driver, adminDSN string
+	source, name     string
+}
+
+// Snapshot saves d's current schema and data, typically right after running
+// migrations, so Restore can bring the database back to that state in
+// milliseconds instead of migrating again:
+//
+//	func TestMain(m *testing.M) {
+//	    conn = openAndMigrate()
+//	    img, err := dbtest.Snapshot(ctx, conn)
+//	    ...
+//	    code := m.Run()
+//	    _ = img.Drop(ctx)
+//	    os.Exit(code)
+//	}
+//
+//	func resetDB(t *testing.T) {
+//	    if err := dbtest.Restore(ctx, conn, img); err != nil { t.Fatal(err) }
+//	}
+//
+// SQLite databases (files and :memory:) are copied with VACUUM INTO.
+// PostgreSQL databases are copied into a template database with CREATE
+// DATABASE ... TEMPLATE, which needs the CREATEDB privilege and a
+// maintenance database named "postgres". Other dialects are not supported.
+//
+// Snapshot and Restore disconnect every other session of the database; run
+// them while the suite is idle.
+func Snapshot(ctx context.Context, d *db.DB) (*Image, error) {
+	switch d.Dialect() {
+	case db.DialectSQLite:
+		return snapshotSQLite(ctx, d)
+	case db.DialectPostgres:
+		return snapshotPostgres(ctx, d)
+	}
+	return nil, fmt.Errorf("dbtest: snapshots not supported for dialect %q", d.Dialect())
+}
+
+// Restore replaces d's schema and data with img. The pool stays usable; on
+// PostgreSQL its connections are re-established.
+func Restore(ctx context.Context, d *db.DB, img *Image) error {
+	if d.Dialect() != img.dialect {
+		return fmt.Errorf("dbtest: cannot restore a %s image into a %s database", img.dialect, d.Dialect())
+	}
+	if img.dialect == db.DialectSQLite {
+		return restoreSQLite(ctx, d, img)
+	}
+	return restorePostgres(ctx, d, img)
+}
+
+// Drop deletes the image.
+func (img *Image) Drop(ctx context.Context) error {
+	if img.dialect == db.DialectSQLite {
+		return os.RemoveAll(filepath.Dir(img.path))
+	}
+	return img.admin(ctx, func(admin *sql.DB) error {
+		_, err := admin.ExecContext(ctx, "DROP DATABASE IF EXISTS "+db.DialectPostgres.QuoteIdent(img.name))
+		return err
+	})
+}
+
+// ── SQLite ───────────────────────────────────────────────────────────────────
+
+func snapshotSQLite(ctx context.Context, d *db.DB) (*Image, error) {
+	dir, err := os.MkdirTemp("", "dbtest-snapshot-")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, "snapshot.db")
+	if _, err := d.Raw().ExecContext(ctx, "VACUUM INTO "+sqlString(path)); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, fmt.Errorf("dbtest: snapshot: %w", err)
+	}
+	return &Image{dialect: db.DialectSQLite, path: path}, nil
+}
+
+// restoreSQLite rebuilds the main schema from the attached image and copies
+// its rows, on one connection with foreign keys off.
+func restoreSQLite(ctx context.Context, d *db.DB, img *Image) (err error) {
+	conn, err := d.Raw().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var fk int
+	if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&fk); err != nil {
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), fmt.Sprintf("PRAGMA foreign_keys = %d", fk))
+	}()
+	if _, err := conn.ExecContext(ctx, "ATTACH DATABASE "+sqlString(img.path)+" AS dbtest_snapshot"); err != nil {
+		return fmt.Errorf("dbtest: restore: %w", err)
+	}
+	defer func() { _, _ = conn.ExecContext(context.WithoutCancel(ctx), "DETACH DATABASE dbtest_snapshot") }()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	objects := func(schema string) ([][3]string, error) {
+		rows, err := tx.QueryContext(ctx, `SELECT type, name, sql FROM `+schema+`.sqlite_master
+			WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+			ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 ELSE 2 END, rowid`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var out [][3]string
+		for rows.Next() {
+			var o [3]string
+			if err := rows.Scan(&o[0], &o[1], &o[2]); err != nil {
+				return nil, err
+			}
+			out = append(out, o)
+		}
+		return out, rows.Err()
+	}
+
+	current, err := objects("main")
+	if err != nil {
+		return err
+	}
+	for i := len(current) - 1; i >= 0; i-- { // views and triggers first
+		o := current[i]
+		stmt := fmt.Sprintf("DROP %s IF EXISTS main.%s", strings.ToUpper(o[0]), db.DialectSQLite.QuoteIdent(o[1]))
+		if _, err = tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("dbtest: restore: %w", err)
+		}
+	}
+
+	saved, err := objects("dbtest_snapshot")
+	if err != nil {
+		return err
+	}
+	for _, o := range saved {
+		if _, err = tx.ExecContext(ctx, o[2]); err != nil {
+			return fmt.Errorf("dbtest: restore %s: %w", o[1], err)
+		}
+		if o[0] == "table" {
+			t := db.DialectSQLite.QuoteIdent(o[1])
+			if _, err = tx.ExecContext(ctx, "INSERT INTO main."+t+" SELECT * FROM dbtest_snapshot."+t); err != nil {
+				return fmt.Errorf("dbtest: restore %s: %w", o[1], err)
+			}
+		}
+	}
+	// AUTOINCREMENT counters.
+	var seq int
+	_ = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM dbtest_snapshot.sqlite_master WHERE name = 'sqlite_sequence'`).Scan(&seq)
+	if seq > 0 {
+		if _, err = tx.ExecContext(ctx, `DELETE FROM main.sqlite_sequence`); err != nil {
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, `INSERT INTO main.sqlite_sequence SELECT * FROM dbtest_snapshot.sqlite_sequence`); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ── PostgreSQL ───────────────────────────────────────────────────────────────
+
+func snapshotPostgres(ctx context.Context, d *db.DB) (*Image, error) {
+	cfg := d.Config()
+	if cfg.DSN == "" {
+		return nil, fmt.Errorf("dbtest: snapshot: DB opened from a Connector has no DSN")
+	}
+	var source string
+	if err := d.QueryRow(ctx, "SELECT current_database()").Scan(&source); err != nil {
+		return nil, err
+	}
+	admin, err := withDatabase(cfg.DSN, "postgres")
+	if err != nil {
+		return nil, err
+	}
+	name := source
+	if len(name) > 54 { // identifiers are limited to 63 bytes
+		name = name[:54]
+	}
+	img := &Image{dialect: db.DialectPostgres, driver: cfg.DriverName, adminDSN: admin, source: source, name: name + "_snapshot"}
+	err = img.admin(ctx, func(admin *sql.DB) error {
+		return img.copy(ctx, admin, d, source, img.name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+func restorePostgres(ctx context.Context, d *db.DB, img *Image) error {
+	return img.admin(ctx, func(admin *sql.DB) error {
+		return img.copy(ctx, admin, d, img.name, img.source)
+	})
+}
+
+// copy recreates database to from template from, after disconnecting d and
+// every other session of both.
+func (img *Image) copy(ctx context.Context, admin *sql.DB, d *db.DB, from, to string) error {
+	pool := d.Raw()
+	pool.SetMaxIdleConns(0) // close our idle connections
+	defer pool.SetMaxIdleConns(max(d.Config().MaxIdleConns, 2))
+
+	q := db.DialectPostgres.QuoteIdent
+	stmts := []string{
+		`SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		 WHERE datname IN (` + sqlString(from) + `, ` + sqlString(to) + `) AND pid <> pg_backend_pid()`,
+		"DROP DATABASE IF EXISTS " + q(to),
+		"CREATE DATABASE " + q(to) + " TEMPLATE " + q(from),
+	}
+	for _, s := range stmts {
+		if _, err := admin.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("dbtest: copy %s to %s: %w", from, to, err)
+		}
+	}
+	return nil
+}
+
+func (img *Image) admin(ctx context.Context, fn func(*sql.DB) error) error {
+	admin, err := sql.Open(img.driver, img.adminDSN)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	admin.SetMaxOpenConns(1)
+	if err := admin.PingContext(ctx); err != nil {
+		return fmt.Errorf("dbtest: connect to maintenance database: %w", err)
+	}
+	return fn(admin)
+}
+
+var dbnameRe = regexp.MustCompile(`(^|\s)dbname\s*=\s*('(?:[^'\\]|\\.)*'|\S+)`)
+
+// withDatabase points a PostgreSQL DSN, URL or key=value form, at database.
+func withDatabase(dsn, database string) (string, error) {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", fmt.Errorf("dbtest: parse DSN: %w", err)
+		}
+		u.Path = "/" + database
+		return u.String(), nil
+	}
+	kv := "dbname=" + database
+	if dbnameRe.MatchString(dsn) {
+		return dbnameRe.ReplaceAllString(dsn, "${1}"+kv), nil
+	}
+	return strings.TrimSpace(dsn + " " + kv), nil
+}
+
+func sqlString(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
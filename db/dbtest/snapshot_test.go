@@ -0,0 +1,73 @@
+package dbtest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	for _, q := range []string{
+		`PRAGMA foreign_keys = ON`,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL)`,
+		`CREATE UNIQUE INDEX idx_users_email ON users (email)`,
+		`CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id))`,
+		`CREATE VIEW user_emails AS SELECT email FROM users`,
+		`INSERT INTO users (email) VALUES ('seed@x')`,
+		`INSERT INTO posts (user_id) VALUES (1)`,
+	} {
+		if _, err := d.Exec(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	img, err := dbtest.Snapshot(ctx, d)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	defer img.Drop(ctx)
+
+	// Dirty the database: rows, schema, and the autoincrement counter.
+	for _, q := range []string{
+		`INSERT INTO users (email) VALUES ('a@x'), ('b@x')`,
+		`DELETE FROM posts`,
+		`DROP VIEW user_emails`,
+		`CREATE TABLE scratch (v TEXT)`,
+	} {
+		if _, err := d.Exec(ctx, q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	for range 2 { // restoring is repeatable
+		if err := dbtest.Restore(ctx, d, img); err != nil {
+			t.Fatalf("restore: %v", err)
+		}
+	}
+	var users, posts, views, scratch int
+	_ = d.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&users)
+	_ = d.QueryRow(ctx, `SELECT COUNT(*) FROM posts`).Scan(&posts)
+	_ = d.QueryRow(ctx, `SELECT COUNT(*) FROM user_emails`).Scan(&views)
+	_ = d.QueryRow(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'scratch'`).Scan(&scratch)
+	if users != 1 || posts != 1 || views != 1 || scratch != 0 {
+		t.Fatalf("after restore: users=%d posts=%d view rows=%d scratch=%d", users, posts, views, scratch)
+	}
+	id, err := d.ExecReturningID(ctx, `INSERT INTO users (email) VALUES ('c@x')`)
+	if err != nil || id != 2 {
+		t.Fatalf("next id = %d (err %v), want 2", id, err)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO users (email) VALUES ('seed@x')`); !db.IsDuplicateKey(err) {
+		t.Fatalf("unique index not restored: %v", err)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO posts (user_id) VALUES (99)`); !db.IsForeignKeyViolation(err) {
+		t.Fatalf("foreign keys not re-enabled: %v", err)
+	}
+}
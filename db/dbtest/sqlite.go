@@ -0,0 +1,64 @@
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// OpenSQLite opens an in-memory SQLite database closed when t ends and runs
+// the named up migrations of the module's migrations/ directory on it, in
+// order:
+//
+//	d := dbtest.OpenSQLite(t, "000005_create_scheduled_jobs", "000009_create_matview_refreshes")
+//
+// The pool holds one connection, so every statement sees the same database.
+// migrations/ is found by walking up from the test's directory to the
+// module root. The caller imports the sqlite3 driver.
+func OpenSQLite(t testing.TB, migrations ...string) *db.DB {
+	t.Helper()
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("dbtest: open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	if len(migrations) == 0 {
+		return d
+	}
+	dir, err := migrationsDir()
+	if err != nil {
+		t.Fatalf("dbtest: %v", err)
+	}
+	for _, name := range migrations {
+		schema, err := os.ReadFile(filepath.Join(dir, name+".up.sql"))
+		if err != nil {
+			t.Fatalf("dbtest: read migration: %v", err)
+		}
+		if _, err := d.Exec(context.Background(), string(schema)); err != nil {
+			t.Fatalf("dbtest: migration %s: %v", name, err)
+		}
+	}
+	return d
+}
+
+// migrationsDir returns the migrations directory next to the go.mod above
+// the working directory.
+func migrationsDir() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return filepath.Join(dir, "migrations"), nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", os.ErrNotExist
+		}
+		dir = parent
+	}
+}
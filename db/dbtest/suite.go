@@ -0,0 +1,243 @@
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Driver certification suite
+// ─────────────────────────────────────────────────────────────────────────────
+
+const (
+	suiteParents = "dbtest_suite_parents"
+	suiteItems   = "dbtest_suite_items"
+)
+
+// RunDriverSuite opens cfg and runs, as subtests, the behaviours the rest of
+// the toolkit assumes of every driver: errors mapped to the db sentinels,
+// transactions that commit and roll back as a unit, atomic BatchExec, and
+// deadlines that stop a running statement with ErrTimeout. Driver adapter
+// authors run it against a real server to certify an adapter:
+//
+//	func TestOracleDriver(t *testing.T) {
+//	    dbtest.RunDriverSuite(t, db.Config{DriverName: "oracle", DSN: os.Getenv("ORACLE_DSN")})
+//	}
+//
+// When a Driver is registered under cfg.DriverName its ErrorMapper is
+// installed as OpenWithDriver would. The suite creates and finally drops two
+// tables prefixed dbtest_suite_; every connection of the pool must see the
+// same database, so SQLite needs a file or shared-cache DSN.
+func RunDriverSuite(t *testing.T, cfg db.Config) {
+	t.Helper()
+	d, err := db.Open(cfg)
+	if err != nil {
+		t.Fatalf("dbtest: open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	if drv, err := db.LookupDriver(cfg.DriverName); err == nil {
+		d.SetErrorMapper(db.ChainMapper(drv.ErrorMapper(), db.DefaultErrorMapper()))
+	}
+
+	s := &suite{d: d, dialect: d.Dialect()}
+	ph := s.dialect.Placeholder()
+	s.insert = fmt.Sprintf(`INSERT INTO %s (id, email, qty, parent_id) VALUES (%s, %s, %s, %s)`,
+		suiteItems, ph(1), ph(2), ph(3), ph(4))
+	s.create(t)
+	t.Cleanup(func() { s.drop() })
+
+	t.Run("ErrorMapping", s.errorMapping)
+	t.Run("Tx", s.tx)
+	t.Run("Batch", s.batch)
+	t.Run("Timeout", s.timeout)
+}
+
+type suite struct {
+	d       *db.DB
+	dialect db.Dialect
+	insert  string
+}
+
+type suiteItem struct {
+	id, qty int
+	email   string
+	parent  any
+}
+
+func (s *suite) args(it suiteItem) []any { return []any{it.id, it.email, it.qty, it.parent} }
+
+func (s *suite) create(t *testing.T) {
+	t.Helper()
+	s.drop()
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`CREATE TABLE ` + suiteParents + ` (id INTEGER NOT NULL PRIMARY KEY)`,
+		`CREATE TABLE ` + suiteItems + ` (
+			id        INTEGER      NOT NULL PRIMARY KEY,
+			email     VARCHAR(100) NOT NULL UNIQUE,
+			qty       INTEGER      NOT NULL CHECK (qty >= 0),
+			parent_id INTEGER,
+			FOREIGN KEY (parent_id) REFERENCES ` + suiteParents + ` (id))`,
+		`INSERT INTO ` + suiteParents + ` (id) VALUES (1)`,
+	} {
+		if _, err := s.d.Exec(ctx, stmt); err != nil {
+			t.Fatalf("dbtest: create suite tables: %v", err)
+		}
+	}
+}
+
+func (s *suite) drop() {
+	ctx := context.Background()
+	_, _ = s.d.Exec(ctx, `DROP TABLE IF EXISTS `+suiteItems)
+	_, _ = s.d.Exec(ctx, `DROP TABLE IF EXISTS `+suiteParents)
+}
+
+// reset empties the items table and returns a fresh context.
+func (s *suite) reset(t *testing.T) context.Context {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := s.d.Exec(ctx, `DELETE FROM `+suiteItems); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	return ctx
+}
+
+func (s *suite) count(t *testing.T) int {
+	t.Helper()
+	var n int
+	if err := s.d.QueryRow(context.Background(), `SELECT COUNT(*) FROM `+suiteItems).Scan(&n); err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	return n
+}
+
+func (s *suite) errorMapping(t *testing.T) {
+	ctx := s.reset(t)
+	if _, err := s.d.Exec(ctx, s.insert, s.args(suiteItem{id: 1, email: "a@example.com", qty: 1, parent: 1})...); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	var id int
+	err := s.d.QueryRow(ctx, fmt.Sprintf(`SELECT id FROM %s WHERE id = %s`, suiteItems, s.dialect.Placeholder()(1)), 42).Scan(&id)
+	if !db.IsNotFound(err) {
+		t.Errorf("no rows: expected ErrNotFound, got %v", err)
+	}
+
+	cases := []struct {
+		name string
+		item suiteItem
+		is   func(error) bool
+	}{
+		{"duplicate primary key", suiteItem{id: 1, email: "b@example.com", qty: 1}, db.IsDuplicateKey},
+		{"duplicate unique column", suiteItem{id: 2, email: "a@example.com", qty: 1}, db.IsDuplicateKey},
+		{"not null", suiteItem{id: 3, qty: 1}, db.IsNotNullViolation},
+		{"check", suiteItem{id: 4, email: "c@example.com", qty: -1}, db.IsCheckViolation},
+		{"foreign key", suiteItem{id: 5, email: "d@example.com", qty: 1, parent: 99}, db.IsForeignKeyViolation},
+	}
+	for _, c := range cases {
+		args := s.args(c.item)
+		if c.item.email == "" {
+			args[1] = nil
+		}
+		if _, err := s.d.Exec(ctx, s.insert, args...); !c.is(err) {
+			t.Errorf("%s: not mapped to its sentinel: %v", c.name, err)
+		}
+	}
+}
+
+func (s *suite) tx(t *testing.T) {
+	ctx := s.reset(t)
+	err := s.d.ExecTx(ctx, func(tx *db.Tx) error {
+		_, err := tx.Exec(ctx, s.insert, s.args(suiteItem{id: 1, email: "a@example.com", qty: 1})...)
+		return err
+	})
+	if err != nil || s.count(t) != 1 {
+		t.Fatalf("commit: err %v, %d rows", err, s.count(t))
+	}
+
+	failed := errors.New("dbtest: fail")
+	err = s.d.ExecTx(ctx, func(tx *db.Tx) error {
+		if _, err := tx.Exec(ctx, s.insert, s.args(suiteItem{id: 2, email: "b@example.com", qty: 1})...); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) || s.count(t) != 1 {
+		t.Errorf("rollback on error: err %v, %d rows", err, s.count(t))
+	}
+
+	err = s.d.ExecTx(ctx, func(tx *db.Tx) error {
+		_, err := tx.Exec(ctx, s.insert, s.args(suiteItem{id: 3, email: "a@example.com", qty: 1})...)
+		return err
+	})
+	if !db.IsDuplicateKey(err) {
+		t.Errorf("errors inside a transaction must be mapped, got %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("panic inside ExecTx must propagate")
+			}
+		}()
+		_ = s.d.ExecTx(ctx, func(tx *db.Tx) error {
+			_, _ = tx.Exec(ctx, s.insert, s.args(suiteItem{id: 4, email: "d@example.com", qty: 1})...)
+			panic("dbtest: panic")
+		})
+	}()
+	if n := s.count(t); n != 1 {
+		t.Errorf("rollback on panic: %d rows, want 1", n)
+	}
+}
+
+func (s *suite) batch(t *testing.T) {
+	ctx := s.reset(t)
+	items := make([]suiteItem, 100)
+	for i := range items {
+		items[i] = suiteItem{id: i + 1, email: fmt.Sprintf("u%d@example.com", i+1), qty: i}
+	}
+	if err := db.BatchExec(s.d, ctx, s.insert, items, s.args); err != nil {
+		t.Fatalf("batch: %v", err)
+	}
+	if n := s.count(t); n != len(items) {
+		t.Fatalf("batch inserted %d rows, want %d", n, len(items))
+	}
+
+	ctx = s.reset(t)
+	items[50].email = items[10].email
+	if err := db.BatchExec(s.d, ctx, s.insert, items, s.args); !db.IsDuplicateKey(err) {
+		t.Errorf("failing batch: expected ErrDuplicateKey, got %v", err)
+	}
+	if n := s.count(t); n != 0 {
+		t.Errorf("failing batch left %d rows; it must be atomic", n)
+	}
+}
+
+func (s *suite) timeout(t *testing.T) {
+	slow := `WITH RECURSIVE r(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM r WHERE n < 1000000000) SELECT COUNT(*) FROM r`
+	switch s.dialect {
+	case db.DialectPostgres:
+		slow = `SELECT pg_sleep(30)`
+	case db.DialectMySQL:
+		slow = `SELECT SLEEP(30)`
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	var n int64
+	err := s.d.QueryRow(ctx, slow).Scan(&n)
+	if !db.IsTimeout(err) {
+		t.Errorf("statement past its deadline: expected ErrTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("statement ran %s past a 100ms deadline; the driver ignores cancellation", elapsed)
+	}
+	if err := s.d.QueryRow(context.Background(), `SELECT 1`).Scan(&n); err != nil {
+		t.Errorf("pool unusable after a timeout: %v", err)
+	}
+}
@@ -0,0 +1,15 @@
+package dbtest_test
+
+import (
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+)
+
+func TestRunDriverSuite_SQLite(t *testing.T) {
+	dbtest.RunDriverSuite(t, db.Config{
+		DSN:        "file:" + t.TempDir() + "/suite.db?_busy_timeout=1000",
+		DriverName: "sqlite3",
+	})
+}
@@ -0,0 +1,36 @@
+package dbtest
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ValidateRegistered prepares every statement registered with
+// db.RegisterQueries on q, which must hold the migrated schema, and reports
+// each one that fails to prepare with t.Errorf. Nothing is executed.
+//
+//	func TestQueries(t *testing.T) {
+//	    d := openMigratedDB(t)
+//	    dbtest.ValidateRegistered(t, d)
+//	}
+func ValidateRegistered(t testing.TB, q db.Querier) {
+	t.Helper()
+	queries := db.RegisteredQueries()
+	if len(queries) == 0 {
+		t.Errorf("dbtest: no queries registered; is the repository package imported?")
+		return
+	}
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := db.ValidateQueries(context.Background(), q, map[string]string{name: queries[name]}); err != nil {
+			t.Errorf("dbtest: %v", err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package debughttp exposes read-only HTTP handlers for inspecting a *db.DB in
+// a running service: pool statistics, recent slow queries, open transactions,
+// and a health probe.
+//
+// The handlers reveal query text and call sites, so mount them on an internal
+// listener or behind authentication only:
+//
+//	conn := db.MustOpen(cfg)
+//	mux.Handle("/debug/db/", http.StripPrefix("/debug/db",
+//	    debughttp.Handler(conn, debughttp.Options{})))
+package debughttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Options configures Handler.
+type Options struct {
+	// Recorder supplies /slow. When nil, the package-level buffer behind
+	// db.RecentQueries is used.
+	Recorder *db.QueryRecorder
+	// QueryStats supplies /queries. When nil, the package-level collector
+	// behind db.QueryStats is used.
+	QueryStats *db.QueryStatsCollector
+	// HealthTimeout bounds the ping issued by /health. Defaults to 2s.
+	HealthTimeout time.Duration
+}
+
+// Handler returns an http.Handler serving:
+//
+//	GET /        index of the endpoints below
+//	GET /stats   sql.DBStats of the pool
+//	GET /slow    recent slow and failed queries, newest first
+//	GET /queries latency percentiles per fingerprint (see db.QueryStats)
+//	GET /tx      transactions currently open through ExecTx, oldest first
+//	GET /health  200 when the database answers a ping, 503 otherwise
+//
+// Paths are relative; use http.StripPrefix to mount it under a prefix.
+func Handler(d *db.DB, opts Options) http.Handler {
+	if opts.HealthTimeout <= 0 {
+		opts.HealthTimeout = 2 * time.Second
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []string{"stats", "slow", "queries", "tx", "health"})
+	})
+	mux.HandleFunc("GET /stats", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, d.Stats())
+	})
+	mux.HandleFunc("GET /slow", func(w http.ResponseWriter, r *http.Request) {
+		var recs []db.QueryRecord
+		if opts.Recorder != nil {
+			recs = opts.Recorder.Recent()
+		} else {
+			recs = db.RecentQueries()
+		}
+		writeJSON(w, http.StatusOK, recs)
+	})
+	mux.HandleFunc("GET /queries", func(w http.ResponseWriter, r *http.Request) {
+		var stats []db.QueryStat
+		if opts.QueryStats != nil {
+			stats = opts.QueryStats.Snapshot()
+		} else {
+			stats = db.QueryStats()
+		}
+		writeJSON(w, http.StatusOK, stats)
+	})
+	mux.HandleFunc("GET /tx", func(w http.ResponseWriter, r *http.Request) {
+		type txView struct {
+			db.TxInfo
+			Age time.Duration `json:"age"`
+		}
+		now := time.Now()
+		out := []txView{}
+		for _, info := range d.ActiveTxs() {
+			out = append(out, txView{TxInfo: info, Age: now.Sub(info.Started)})
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), opts.HealthTimeout)
+		defer cancel()
+		start := time.Now()
+		if err := d.Ping(ctx); err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{
+				"status": "unavailable",
+				"error":  err.Error(),
+			})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"status":  "ok",
+			"latency": time.Since(start).String(),
+		})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
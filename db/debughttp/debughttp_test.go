@@ -0,0 +1,76 @@
+package debughttp_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/debughttp"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandler(t *testing.T) {
+	rec := db.NewQueryRecorder(db.QueryRecorderConfig{SlowThreshold: 1})
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", Hooks: []db.Hook{rec}})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	ctx := context.Background()
+
+	srv := httptest.NewServer(http.StripPrefix("/debug/db", debughttp.Handler(d, debughttp.Options{Recorder: rec})))
+	t.Cleanup(srv.Close)
+
+	get := func(path string, v any) int {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/debug/db" + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return resp.StatusCode
+	}
+
+	if _, err := d.Exec(ctx, "SELECT 1"); err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	var slow []db.QueryRecord
+	if code := get("/slow", &slow); code != http.StatusOK || len(slow) != 1 || slow[0].Query != "SELECT 1" {
+		t.Fatalf("/slow: code=%d records=%+v", code, slow)
+	}
+
+	err = d.ExecTx(ctx, func(*db.Tx) error {
+		var txs []db.TxInfo
+		if code := get("/tx", &txs); code != http.StatusOK || len(txs) != 1 || txs[0].Caller == "" {
+			t.Errorf("/tx inside tx: code=%d txs=%+v", code, txs)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("tx: %v", err)
+	}
+	var txs []db.TxInfo
+	if get("/tx", &txs); len(txs) != 0 {
+		t.Fatalf("/tx after commit: %+v", txs)
+	}
+
+	var health map[string]any
+	if code := get("/health", &health); code != http.StatusOK || health["status"] != "ok" {
+		t.Fatalf("/health: code=%d body=%v", code, health)
+	}
+	var stats map[string]any
+	if code := get("/stats", &stats); code != http.StatusOK || stats["OpenConnections"] == nil {
+		t.Fatalf("/stats: code=%d body=%v", code, stats)
+	}
+
+	_ = d.Close()
+	if code := get("/health", &health); code != http.StatusServiceUnavailable {
+		t.Fatalf("/health after close: code=%d", code)
+	}
+}
@@ -0,0 +1,84 @@
+package db
+
+import "strings"
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Dialect — SQL flavour derived from the driver name
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Dialect identifies the SQL flavour spoken by a connection. Helpers that must
+// emit database-specific syntax (placeholders, upserts, full-text search)
+// switch on it; everything else stays dialect-agnostic.
+type Dialect string
+
+const (
+	DialectUnknown  Dialect = ""
+	DialectPostgres Dialect = "postgres"
+	DialectMySQL    Dialect = "mysql"
+	DialectSQLite   Dialect = "sqlite"
+)
+
+// DialectOf maps a database/sql driver name to its Dialect.
+func DialectOf(driverName string) Dialect {
+	switch driverName {
+	case "postgres", "pgx", "pgx/v5", "cloudsqlpostgres":
+		return DialectPostgres
+	case "mysql":
+		return DialectMySQL
+	case "sqlite3", "sqlite", "libsql":
+		return DialectSQLite
+	}
+	return DialectUnknown
+}
+
+// Placeholder returns the bind parameter style for the dialect.
+// SQLite accepts both styles; $N is used so SQL can be shared with PostgreSQL.
+func (d Dialect) Placeholder() Placeholder {
+	if d == DialectMySQL {
+		return QuestionPlaceholder
+	}
+	return DollarPlaceholder
+}
+
+// Dialect returns the SQL flavour of the underlying driver.
+func (d *DB) Dialect() Dialect { return DialectOf(d.cfg.DriverName) }
+
+// Dialect returns the SQL flavour of the underlying driver.
+func (t *Tx) Dialect() Dialect { return DialectOf(t.cfg.DriverName) }
+
+// DialectFrom returns q's Dialect when q exposes one (*DB and *Tx do), and
+// DialectUnknown otherwise.
+func DialectFrom(q Querier) Dialect {
+	if dq, ok := q.(interface{ Dialect() Dialect }); ok {
+		return dq.Dialect()
+	}
+	return DialectUnknown
+}
+
+// QuoteIdent quotes a (optionally schema-qualified) identifier for the
+// dialect: "schema"."table" for PostgreSQL/SQLite, `schema`.`table` for MySQL.
+// Use it whenever a table or column name comes from configuration or input.
+func (d Dialect) QuoteIdent(name string) string {
+	q := `"`
+	if d == DialectMySQL {
+		q = "`"
+	}
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		parts[i] = q + strings.ReplaceAll(p, q, q+q) + q
+	}
+	return strings.Join(parts, ".")
+}
+
+// LikeEscape returns the clause to append to a LIKE pattern built with
+// EscapeLike: " ESCAPE '\'" on PostgreSQL and SQLite, and "" on MySQL, where
+// backslash is already the escape character and the clause would read as an
+// unterminated string literal.
+//
+//	c.Add("name LIKE ?"+d.LikeEscape(), db.LikePrefix(prefix))
+func (d Dialect) LikeEscape() string {
+	if d == DialectMySQL {
+		return ""
+	}
+	return ` ESCAPE '\'`
+}
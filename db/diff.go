@@ -0,0 +1,145 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Diff — changed columns between two versions of a row
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Diff compares two values of the same struct type (or pointers to it) field
+// by field and returns the columns whose values differ, mapped to their value
+// in newV. Repositories use it to build partial updates, and audit trails to
+// capture what an update changed:
+//
+//	changes := db.Diff(before, after) // {"email": "new@example.com"}
+//
+// Column names come from the `db:"name"` tag, or the field name in snake_case
+// (CreatedAt → created_at, UserID → user_id); `db:"-"` and unexported fields
+// are skipped, and embedded structs are flattened. Pointer fields are
+// compared by the value they point to and reported dereferenced, so nil
+// means NULL — which makes pointer "params" structs work too:
+//
+//	db.Diff(models.UpdateUserParams{ID: p.ID}, p) // only the fields p sets
+//
+// time.Time values are compared with Equal. The result is nil when nothing
+// changed.
+func Diff[T any](oldV, newV T) map[string]any {
+	ov, nv := reflect.ValueOf(&oldV).Elem(), reflect.ValueOf(&newV).Elem()
+	for ov.Kind() == reflect.Pointer {
+		if ov.IsNil() || nv.IsNil() {
+			panic("db.Diff: nil pointer")
+		}
+		ov, nv = ov.Elem(), nv.Elem()
+	}
+	if ov.Kind() != reflect.Struct {
+		panic("db.Diff: " + ov.Type().String() + " is not a struct")
+	}
+	var changed map[string]any
+	for _, f := range diffFields(ov.Type()) {
+		a, b := ov.FieldByIndex(f.index), nv.FieldByIndex(f.index)
+		if fieldEqual(a, b) {
+			continue
+		}
+		if changed == nil {
+			changed = map[string]any{}
+		}
+		changed[f.column] = fieldValue(b)
+	}
+	return changed
+}
+
+type diffField struct {
+	column string
+	index  []int
+}
+
+var diffFieldCache sync.Map // reflect.Type → []diffField
+
+// diffFields lists the column fields of struct type t in declaration order.
+func diffFields(t reflect.Type) []diffField {
+	if v, ok := diffFieldCache.Load(t); ok {
+		return v.([]diffField)
+	}
+	var fields []diffField
+	for _, sf := range reflect.VisibleFields(t) {
+		if !sf.IsExported() || len(sf.Index) > 1 && !parentsEmbedded(t, sf.Index) {
+			continue
+		}
+		tag := sf.Tag.Get("db")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && name == "" {
+			continue // flattened: its fields are visited on their own
+		}
+		if name == "" {
+			name = snakeCase(sf.Name)
+		}
+		fields = append(fields, diffField{column: name, index: sf.Index})
+	}
+	diffFieldCache.Store(t, fields)
+	return fields
+}
+
+// parentsEmbedded reports whether every struct on the path to a promoted
+// field is an untagged embedded struct, i.e. flattened into the row.
+func parentsEmbedded(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		sf := t.Field(i)
+		if !sf.Anonymous || sf.Tag.Get("db") != "" || sf.Type.Kind() != reflect.Struct {
+			return false
+		}
+		t = sf.Type
+	}
+	return true
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func fieldEqual(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Pointer {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+func fieldValue(v reflect.Value) any {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	return v.Interface()
+}
+
+// snakeCase converts a Go field name to a column name, keeping initialisms
+// together: ID → id, UserID → user_id, HTTPStatus → http_status.
+func snakeCase(name string) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || unicode.IsDigit(rs[i-1]) ||
+				i+1 < len(rs) && unicode.IsLower(rs[i+1]) && unicode.IsUpper(rs[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
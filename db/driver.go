@@ -5,9 +5,16 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -36,6 +43,14 @@ type Driver interface {
 	Register()
 }
 
+// ConnectorDriver is implemented by drivers that sometimes need a
+// driver.Connector rather than a DSN. OpenWithDriver uses the connector when
+// Connector returns a non-nil one, and DSN otherwise.
+type ConnectorDriver interface {
+	Driver
+	Connector(opts DriverOptions) (driver.Connector, error)
+}
+
 // DriverOptions carries the most common connection parameters in a structured,
 // driver-agnostic form. DSN() converts them to the driver's native format.
 type DriverOptions struct {
@@ -100,15 +115,31 @@ func OpenWithDriver(driverName string, driverOpts DriverOptions, cfg Config) (*D
 	if err != nil {
 		return nil, err
 	}
-	drv.Register()
+	return openDriver(drv, driverOpts, cfg)
+}
 
-	dsn, err := drv.DSN(driverOpts)
-	if err != nil {
-		return nil, fmt.Errorf("sqltoolkit/db: DSN construction failed: %w", err)
-	}
+// openDriver opens a DB through drv; see OpenWithDriver.
+func openDriver(drv Driver, driverOpts DriverOptions, cfg Config) (*DB, error) {
+	drv.Register()
 
 	cfg.DriverName = drv.Name()
-	cfg.DSN = dsn
+	if s, ok := drv.(SQLiteDriver); ok && s.DisableForeignKeys {
+		cfg.DisableSQLiteForeignKeys = true
+	}
+	if cd, ok := drv.(ConnectorDriver); ok {
+		conn, err := cd.Connector(driverOpts)
+		if err != nil {
+			return nil, fmt.Errorf("sqltoolkit/db: connector construction failed: %w", err)
+		}
+		cfg.Connector = conn
+	}
+	if cfg.Connector == nil {
+		dsn, err := drv.DSN(driverOpts)
+		if err != nil {
+			return nil, fmt.Errorf("sqltoolkit/db: DSN construction failed: %w", err)
+		}
+		cfg.DSN = dsn
+	}
 
 	db, err := Open(cfg)
 	if err != nil {
@@ -117,6 +148,19 @@ func OpenWithDriver(driverName string, driverOpts DriverOptions, cfg Config) (*D
 
 	// Install the driver-specific error mapper.
 	db.SetErrorMapper(ChainMapper(drv.ErrorMapper(), DefaultErrorMapper()))
+	db.drv = drv
+
+	// Drivers that configure sessions through the DSN check the result.
+	if v, ok := drv.(interface {
+		verify(ctx context.Context, d *DB) error
+	}); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := v.verify(ctx, db); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("sqltoolkit/db: %w", err)
+		}
+	}
 	return db, nil
 }
 
@@ -160,58 +204,247 @@ func (PostgresDriver) Register()                { /* lib/pq self-registers via i
 // ─────────────────────────────────────────────────────────────────────────────
 
 // MySQLDriver is the built-in go-sql-driver/mysql adapter.
-type MySQLDriver struct{}
+type MySQLDriver struct {
+	// Vitess enables compatibility with Vitess and PlanetScale: TLS and
+	// client-side parameter interpolation in the DSN, and mapping of vttablet
+	// errors. Vitess does not enforce foreign keys, so ErrForeignKeyViolation
+	// is never returned; check references in application code. Keep batches
+	// within SafeBatchSize and result sets below VitessMaxRows.
+	Vitess bool
+	// Boost routes eligible reads to PlanetScale Boost's query cache by
+	// setting boost_cached_queries on every connection. Requires Vitess.
+	Boost bool
+	// StrictMode sets sql_mode to StrictSQLMode on every connection, whatever
+	// the server's default, so values too long or out of range for their
+	// column fail instead of being silently truncated or zeroed, and double
+	// quotes delimit identifiers as on PostgreSQL. OpenWithDriver reads the
+	// mode back and fails if a server or proxy did not apply it.
+	StrictMode bool
+}
+
+// StrictSQLMode is the sql_mode MySQLDriver.StrictMode enforces.
+const StrictSQLMode = "STRICT_TRANS_TABLES,ANSI_QUOTES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION"
 
 func (MySQLDriver) Name() string { return "mysql" }
 
-func (MySQLDriver) DSN(o DriverOptions) (string, error) {
+func (m MySQLDriver) DSN(o DriverOptions) (string, error) {
 	if o.Host == "" || o.Database == "" {
 		return "", fmt.Errorf("mysql driver: Host and Database are required")
 	}
+	if m.Boost && !m.Vitess {
+		return "", fmt.Errorf("mysql driver: Boost requires Vitess")
+	}
 	port := o.Port
 	if port == 0 {
 		port = 3306
 	}
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true",
 		o.User, o.Password, o.Host, port, o.Database)
+	if m.Vitess {
+		if o.SSLMode != "disable" {
+			dsn += "&tls=true"
+		}
+		// Server-side prepared statements cost a round trip each through
+		// vtgate; interpolate parameters in the client instead.
+		dsn += "&interpolateParams=true"
+		if m.Boost {
+			dsn += "&boost_cached_queries=true"
+		}
+	}
+	if m.StrictMode {
+		// Unknown DSN parameters are SET on each new connection.
+		dsn += "&sql_mode=" + url.QueryEscape("'"+StrictSQLMode+"'")
+	}
 	for k, v := range o.Extra {
 		dsn += fmt.Sprintf("&%s=%s", k, v)
 	}
 	return dsn, nil
 }
 
-func (MySQLDriver) ErrorMapper() ErrorMapper { return DefaultErrorMapper() }
+func (m MySQLDriver) ErrorMapper() ErrorMapper {
+	if m.Vitess {
+		return ErrorMapperFunc(mapVitess)
+	}
+	return DefaultErrorMapper()
+}
 func (MySQLDriver) Register()                { /* go-sql-driver/mysql self-registers */ }
 
+// verify checks that StrictMode took effect on the session.
+func (m MySQLDriver) verify(ctx context.Context, d *DB) error {
+	if !m.StrictMode {
+		return nil
+	}
+	var mode string
+	if err := d.QueryRow(ctx, `SELECT @@SESSION.sql_mode`).Scan(&mode); err != nil {
+		return fmt.Errorf("mysql driver: read sql_mode: %w", err)
+	}
+	have := map[string]bool{}
+	for _, flag := range strings.Split(mode, ",") {
+		have[strings.TrimSpace(flag)] = true
+	}
+	var missing []string
+	for _, flag := range strings.Split(StrictSQLMode, ",") {
+		if !have[flag] {
+			missing = append(missing, flag)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("mysql driver: StrictMode: session sql_mode %q lacks %s; "+
+			"the server or a proxy in between overrides sql_mode", mode, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // SQLite driver adapter
 // ─────────────────────────────────────────────────────────────────────────────
 
-// SQLiteDriver is the built-in mattn/go-sqlite3 adapter.
-type SQLiteDriver struct{}
+// SQLiteDriver is the built-in SQLite adapter. It drives mattn/go-sqlite3
+// (cgo, "sqlite3") by default, or modernc.org/sqlite (pure Go, "sqlite")
+// when DriverName is "sqlite" — import _ "modernc.org/sqlite" and open with
+// OpenWithDriver("sqlite", ...) to build without cgo. Both are registered.
+//
+// The zero value is tuned for concurrent use: WAL journaling (readers no
+// longer block the writer), a 5s busy timeout (a locked database is waited
+// on instead of failing with "database is locked") and foreign key
+// enforcement. Set fields to change them, and install the result with
+// ReplaceDriver or pass it to OpenSQLite. Keys in DriverOptions.Extra are
+// appended to the DSN verbatim.
+type SQLiteDriver struct {
+	// DriverName is "sqlite3" (mattn/go-sqlite3, the default) or "sqlite"
+	// (modernc.org/sqlite).
+	DriverName string
+	// JournalMode is the journal_mode pragma. Default "WAL"; "-" keeps the
+	// SQLite default (DELETE).
+	JournalMode string
+	// BusyTimeout is how long a statement waits for a lock. Default 5s;
+	// negative disables waiting.
+	BusyTimeout time.Duration
+	// DisableForeignKeys leaves foreign_keys=OFF, SQLite's own default, and
+	// sets Config.DisableSQLiteForeignKeys.
+	DisableForeignKeys bool
+	// Synchronous is the synchronous pragma, e.g. "NORMAL" — safe with WAL
+	// and much faster than the default FULL. Empty keeps the default.
+	Synchronous string
+	// TxLock is how transactions begin: "deferred" (default), "immediate"
+	// or "exclusive". Writers should use "immediate" so a transaction that
+	// reads before it writes cannot fail to upgrade its lock.
+	TxLock string
+	// QueryOnly rejects every write on the connection.
+	QueryOnly bool
+}
 
-func (SQLiteDriver) Name() string { return "sqlite3" }
+// DefaultSQLiteBusyTimeout is the BusyTimeout of a zero SQLiteDriver.
+const DefaultSQLiteBusyTimeout = 5 * time.Second
+
+func (s SQLiteDriver) Name() string {
+	if s.DriverName == "" {
+		return "sqlite3"
+	}
+	return s.DriverName
+}
 
-func (SQLiteDriver) DSN(o DriverOptions) (string, error) {
+func (s SQLiteDriver) DSN(o DriverOptions) (string, error) {
 	if o.Database == "" {
-		return "", fmt.Errorf("sqlite3 driver: Database (file path) is required")
+		return "", fmt.Errorf("%s driver: Database (file path) is required", s.Name())
 	}
-	dsn := o.Database
-	first := true
-	for k, v := range o.Extra {
-		if first {
-			dsn += "?"
-			first = false
+	var pragmas [][2]string
+	switch s.JournalMode {
+	case "":
+		pragmas = append(pragmas, [2]string{"journal_mode", "WAL"})
+	case "-":
+	default:
+		pragmas = append(pragmas, [2]string{"journal_mode", s.JournalMode})
+	}
+	busy := s.BusyTimeout
+	if busy == 0 {
+		busy = DefaultSQLiteBusyTimeout
+	}
+	if busy > 0 {
+		pragmas = append(pragmas, [2]string{"busy_timeout", strconv.FormatInt(busy.Milliseconds(), 10)})
+	}
+	if !s.DisableForeignKeys {
+		pragmas = append(pragmas, [2]string{"foreign_keys", "1"})
+	}
+	if s.Synchronous != "" {
+		pragmas = append(pragmas, [2]string{"synchronous", s.Synchronous})
+	}
+	if s.QueryOnly {
+		pragmas = append(pragmas, [2]string{"query_only", "1"})
+	}
+
+	// go-sqlite3 takes one "_name=value" parameter per pragma and
+	// modernc.org/sqlite repeated "_pragma=name(value)" parameters. Values
+	// are not escaped: both read them verbatim.
+	var params []string
+	for _, p := range pragmas {
+		if s.Name() == "sqlite" {
+			params = append(params, "_pragma="+p[0]+"("+p[1]+")")
 		} else {
-			dsn += "&"
+			params = append(params, "_"+p[0]+"="+p[1])
 		}
-		dsn += k + "=" + v
+	}
+	if s.TxLock != "" {
+		params = append(params, "_txlock="+s.TxLock)
+	}
+	extra := make([]string, 0, len(o.Extra))
+	for k, v := range o.Extra {
+		extra = append(extra, k+"="+v)
+	}
+	sort.Strings(extra)
+	params = append(params, extra...)
+
+	dsn := o.Database
+	if len(params) > 0 {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + strings.Join(params, "&")
 	}
 	return dsn, nil
 }
 
+// WriterConfig returns cfg as a single-writer pool: SQLite serialises writes
+// anyway, and one connection turns lock contention between pool connections
+// into queueing in database/sql.
+func (SQLiteDriver) WriterConfig(cfg Config) Config {
+	cfg.MaxOpenConns = 1
+	cfg.MaxIdleConns = 1
+	cfg.ConnMaxLifetime = 0
+	cfg.ConnMaxIdleTime = 0
+	return cfg
+}
+
+// OpenSQLite opens the file at path as two pools: writer, limited to one
+// connection with BEGIN IMMEDIATE transactions, and reader, a QueryOnly pool
+// for concurrent reads under WAL. cfg applies to both; the writer's pool
+// limits come from WriterConfig.
+//
+//	w, r, err := db.OpenSQLite(db.SQLiteDriver{}, "app.db", db.Config{})
+//	users := repo.NewUserRepo(w)
+//	reports := repo.NewUserRepo(r)
+func OpenSQLite(drv SQLiteDriver, path string, cfg Config) (writer, reader *DB, err error) {
+	w := drv
+	if w.TxLock == "" {
+		w.TxLock = "immediate"
+	}
+	writer, err = openDriver(w, DriverOptions{Database: path}, drv.WriterConfig(cfg))
+	if err != nil {
+		return nil, nil, err
+	}
+	r := drv
+	r.QueryOnly = true
+	reader, err = openDriver(r, DriverOptions{Database: path}, cfg)
+	if err != nil {
+		_ = writer.Close()
+		return nil, nil, err
+	}
+	return writer, reader, nil
+}
+
 func (SQLiteDriver) ErrorMapper() ErrorMapper { return DefaultErrorMapper() }
-func (SQLiteDriver) Register()                { /* mattn/go-sqlite3 self-registers */ }
+func (SQLiteDriver) Register()                { /* both SQLite drivers self-register */ }
 
 // ─────────────────────────────────────────────────────────────────────────────
 // Auto-register built-in drivers at init time
@@ -225,6 +458,7 @@ func init() {
 	safeRegister(PostgresDriver{})
 	safeRegister(MySQLDriver{})
 	safeRegister(SQLiteDriver{})
+	safeRegister(SQLiteDriver{DriverName: "sqlite"})
 }
 
 func safeRegister(d Driver) {
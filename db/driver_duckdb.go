@@ -0,0 +1,245 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// DuckDB driver adapter
+// ─────────────────────────────────────────────────────────────────────────────
+
+// DuckDBDriver is the adapter for marcboeker/go-duckdb, registered as
+// "duckdb", for local analytics next to the OLTP database. DriverOptions.
+// Database is the database file ("" for in-memory) and Extra its
+// configuration (access_mode, threads, memory_limit, ...).
+//
+// DuckDB is much faster loading rows through its appender than through
+// INSERT statements. Set NewAppender to enable it for AppendRows and for
+// BatchExec of plain "INSERT INTO t VALUES (...)" statements:
+//
+//	db.ReplaceDriver(db.DuckDBDriver{
+//	    NewAppender: func(c driver.Conn, schema, table string) (db.RowAppender, error) {
+//	        return duckdb.NewAppenderFromConn(c, schema, table)
+//	    },
+//	})
+type DuckDBDriver struct {
+	// NewAppender opens a go-duckdb appender on a raw connection. Nil
+	// disables appender-based inserts.
+	NewAppender func(conn driver.Conn, schema, table string) (RowAppender, error)
+}
+
+func (DuckDBDriver) Name() string { return "duckdb" }
+
+func (DuckDBDriver) DSN(o DriverOptions) (string, error) {
+	dsn := o.Database
+	if len(o.Extra) > 0 {
+		keys := make([]string, 0, len(o.Extra))
+		for k := range o.Extra {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i == 0 {
+				dsn += "?"
+			} else {
+				dsn += "&"
+			}
+			dsn += k + "=" + o.Extra[k]
+		}
+	}
+	return dsn, nil
+}
+
+// Appender implements AppenderDriver.
+func (d DuckDBDriver) Appender(conn driver.Conn, schema, table string) (RowAppender, error) {
+	if d.NewAppender == nil {
+		return nil, errNoAppender
+	}
+	return d.NewAppender(conn, schema, table)
+}
+
+func (d DuckDBDriver) appenderEnabled() bool { return d.NewAppender != nil }
+
+func (DuckDBDriver) ErrorMapper() ErrorMapper { return ErrorMapperFunc(mapDuckDB) }
+func (DuckDBDriver) Register()                { /* go-duckdb self-registers */ }
+
+func init() { safeRegister(DuckDBDriver{}) }
+
+// mapDuckDB maps go-duckdb errors, which carry their class in the message
+// ("Constraint Error: ...") rather than a code.
+func mapDuckDB(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbe *DBError
+	if errors.As(err, &dbe) {
+		return err
+	}
+	s := err.Error()
+	switch {
+	case strings.Contains(s, "Constraint Error"):
+		switch {
+		case strings.Contains(s, "Duplicate key"):
+			return &DBError{Sentinel: ErrDuplicateKey, Cause: err}
+		case strings.Contains(s, "foreign key constraint"):
+			return &DBError{Sentinel: ErrForeignKeyViolation, Cause: err}
+		case strings.Contains(s, "CHECK constraint failed"):
+			return &DBError{Sentinel: ErrCheckViolation, Cause: err}
+		case strings.Contains(s, "NOT NULL constraint failed"):
+			return &DBError{Sentinel: ErrNotNullViolation, Cause: err}
+		}
+	case strings.Contains(s, "Conversion Error"), strings.Contains(s, "Out of Range Error"):
+		return &DBError{Sentinel: ErrInvalidData, Cause: err}
+	case strings.Contains(s, "write-write conflict"),
+		strings.Contains(s, "Transaction conflict"):
+		// Optimistic concurrency control; retrying the transaction helps.
+		return &DBError{Sentinel: ErrDeadlock, Cause: err}
+	case strings.Contains(s, "Interrupt Error"):
+		return &DBError{Sentinel: ErrTimeout, Cause: err}
+	case strings.Contains(s, "Could not set lock on file"):
+		return &DBError{Sentinel: ErrConnectionFailed, Cause: err, Message: "duckdb: database file is in use by another process"}
+	}
+	return defaultMap(err)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Appender-based bulk insert
+// ─────────────────────────────────────────────────────────────────────────────
+
+// RowAppender appends whole rows to a table, in the table's column order.
+// *duckdb.Appender satisfies it.
+type RowAppender interface {
+	AppendRow(values ...driver.Value) error
+	Close() error
+}
+
+// AppenderDriver is implemented by drivers with a bulk appender.
+type AppenderDriver interface {
+	Driver
+	Appender(conn driver.Conn, schema, table string) (RowAppender, error)
+}
+
+var errNoAppender = errors.New("sqltoolkit/db: driver has no appender")
+
+// appenderOf returns the DB's driver when it can append rows.
+func appenderOf(d *DB) (AppenderDriver, bool) {
+	ad, ok := d.drv.(AppenderDriver)
+	if !ok {
+		return nil, false
+	}
+	if e, ok := ad.(interface{ appenderEnabled() bool }); ok && !e.appenderEnabled() {
+		return nil, false
+	}
+	return ad, true
+}
+
+// AppendRows inserts items into table through the driver's appender, in one
+// transaction. argsFn must return a value for every column of the table, in
+// column order. The DB must have been opened with OpenWithDriver for a
+// driver that implements AppenderDriver.
+//
+//	err := db.AppendRows(d, ctx, "events", events,
+//	    func(e Event) []any { return []any{e.ID, e.Kind, e.At} })
+func AppendRows[T any](d *DB, ctx context.Context, table string, items []T, argsFn func(T) []any) error {
+	ad, ok := appenderOf(d)
+	if !ok {
+		return errNoAppender
+	}
+	return appendRows(d, ctx, ad, table, items, argsFn)
+}
+
+func appendRows[T any](d *DB, ctx context.Context, ad AppenderDriver, table string, items []T, argsFn func(T) []any) (err error) {
+	ctx, cancel := d.applyDefaultTimeout(ctx)
+	defer cancel()
+	label := "APPEND " + table
+	if err := preflight(ctx, label); err != nil {
+		return err
+	}
+	start := time.Now()
+	d.hooks.Before(ctx, label, nil)
+	defer func() {
+		err = d.mapErr(err)
+		d.hooks.After(ctx, label, nil, time.Since(start), err)
+	}()
+
+	schema, name := splitTable(table)
+	conn, err := d.sqldb.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The appender works on the connection, not a *sql.Tx; an explicit
+	// transaction on that connection keeps the load all-or-nothing.
+	if _, err := conn.ExecContext(ctx, "BEGIN TRANSACTION"); err != nil {
+		return err
+	}
+	err = conn.Raw(func(dc any) error {
+		c, ok := dc.(driver.Conn)
+		if !ok {
+			return fmt.Errorf("sqltoolkit/db: unexpected driver connection %T", dc)
+		}
+		app, err := ad.Appender(c, schema, name)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			args := argsFn(item)
+			vals := make([]driver.Value, len(args))
+			for i, a := range args {
+				if vals[i], err = driver.DefaultParameterConverter.ConvertValue(a); err != nil {
+					_ = app.Close()
+					return err
+				}
+			}
+			if err := app.AppendRow(vals...); err != nil {
+				_ = app.Close()
+				return err
+			}
+		}
+		return app.Close() // flushes
+	})
+	if err != nil {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "COMMIT")
+	return err
+}
+
+// appendInsertRe matches an INSERT that supplies every column positionally
+// from placeholders — the only shape an appender can take over.
+var appendInsertRe = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\s+([\w."]+)\s+VALUES\s*\(([^()]*)\)\s*;?\s*$`)
+
+// appendTarget returns the table of query when BatchExec may run it through
+// an appender: no column list, and bind parameters only, in order.
+func appendTarget(query string) (string, bool) {
+	m := appendInsertRe.FindStringSubmatch(query)
+	if m == nil {
+		return "", false
+	}
+	for i, p := range strings.Split(m[2], ",") {
+		p = strings.TrimSpace(p)
+		if p != "?" && p != "$"+strconv.Itoa(i+1) {
+			return "", false
+		}
+	}
+	return m[1], true
+}
+
+// splitTable splits "schema.table" and removes identifier quotes.
+func splitTable(table string) (schema, name string) {
+	name = table
+	if i := strings.LastIndexByte(table, '.'); i >= 0 {
+		schema, name = table[:i], table[i+1:]
+	}
+	return strings.Trim(schema, `"`), strings.Trim(name, `"`)
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// libsql / Turso driver adapter
+// ─────────────────────────────────────────────────────────────────────────────
+
+// LibSQLDriver is the adapter for libsql (Turso), registered as "libsql".
+// Import _ "github.com/tursodatabase/libsql-client-go/libsql" for remote
+// databases, or github.com/tursodatabase/go-libsql for embedded replicas.
+//
+// DriverOptions map onto a libsql URL: Host is the database host
+// ("app-org.turso.io"), Password the auth token, and Extra further URL
+// parameters. SSLMode "disable" selects plain HTTP, for a local sqld. With no
+// Host, Database names a local file.
+//
+//	d, err := db.OpenWithDriver("libsql", db.DriverOptions{
+//	    Host: "app-org.turso.io", Password: os.Getenv("TURSO_AUTH_TOKEN"),
+//	}, db.Config{})
+//
+// Setting ReplicaPath opens an embedded replica instead: a local SQLite file
+// that serves reads and is synced from the remote primary, while writes are
+// forwarded to it. The replica connector comes from go-libsql, passed in as
+// NewReplica so this package does not depend on it:
+//
+//	db.ReplaceDriver(db.LibSQLDriver{
+//	    ReplicaPath:  "/var/lib/app/replica.db",
+//	    SyncInterval: time.Minute,
+//	    NewReplica: func(path, primaryURL, authToken string, every time.Duration) (driver.Connector, error) {
+//	        return libsql.NewEmbeddedReplicaConnector(path, primaryURL,
+//	            libsql.WithAuthToken(authToken), libsql.WithSyncInterval(every))
+//	    },
+//	})
+type LibSQLDriver struct {
+	// ReplicaPath is the local file of an embedded replica; empty connects
+	// to the remote database directly.
+	ReplicaPath string
+	// SyncInterval is how often the replica pulls from the primary. Zero
+	// leaves syncing to the application.
+	SyncInterval time.Duration
+	// NewReplica builds the embedded replica connector. Required with
+	// ReplicaPath.
+	NewReplica func(path, primaryURL, authToken string, syncInterval time.Duration) (driver.Connector, error)
+}
+
+func (LibSQLDriver) Name() string { return "libsql" }
+
+func (LibSQLDriver) DSN(o DriverOptions) (string, error) {
+	if o.Host == "" {
+		if o.Database == "" {
+			return "", fmt.Errorf("libsql driver: Host or Database (file path) is required")
+		}
+		return "file:" + o.Database, nil
+	}
+	scheme := "libsql"
+	if o.SSLMode == "disable" {
+		scheme = "http"
+	}
+	u := url.URL{Scheme: scheme, Host: o.Host}
+	if o.Port != 0 {
+		u.Host += ":" + strconv.Itoa(o.Port)
+	}
+	q := url.Values{}
+	if o.Password != "" {
+		q.Set("authToken", o.Password)
+	}
+	for k, v := range o.Extra {
+		q.Set(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Connector returns the embedded replica connector when ReplicaPath is set,
+// and nil — use DSN — otherwise.
+func (l LibSQLDriver) Connector(o DriverOptions) (driver.Connector, error) {
+	if l.ReplicaPath == "" {
+		return nil, nil
+	}
+	if l.NewReplica == nil {
+		return nil, fmt.Errorf("libsql driver: NewReplica is required with ReplicaPath")
+	}
+	if o.Host == "" {
+		return nil, fmt.Errorf("libsql driver: Host of the primary is required with ReplicaPath")
+	}
+	primary := o
+	primary.Password = "" // passed separately, not in the URL
+	dsn, err := l.DSN(primary)
+	if err != nil {
+		return nil, err
+	}
+	return l.NewReplica(l.ReplicaPath, dsn, o.Password, l.SyncInterval)
+}
+
+func (LibSQLDriver) ErrorMapper() ErrorMapper { return ErrorMapperFunc(mapLibSQL) }
+func (LibSQLDriver) Register()                { /* the libsql packages self-register */ }
+
+var _ ConnectorDriver = LibSQLDriver{}
+
+func init() { safeRegister(LibSQLDriver{}) }
+
+// mapLibSQL maps errors returned over Hrana, libsql's HTTP/WebSocket
+// protocol, where a failed statement carries the SQLite result code by name
+// ("SQLITE_CONSTRAINT_UNIQUE") and transport failures an HTTP status.
+// Anything else falls through to DefaultErrorMapper.
+func mapLibSQL(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbe *DBError
+	if errors.As(err, &dbe) {
+		return err
+	}
+	s := err.Error()
+	for _, c := range libsqlCodes {
+		if strings.Contains(s, c.name) {
+			return &DBError{Sentinel: c.sentinel, Cause: err}
+		}
+	}
+	if status, ok := httpStatus(s); ok {
+		switch {
+		case status == 401 || status == 403:
+			return &DBError{Sentinel: ErrConnectionFailed, Cause: err, Message: "libsql: authentication failed"}
+		case status == 408 || status == 504:
+			return &DBError{Sentinel: ErrTimeout, Cause: err}
+		case status >= 500:
+			return &DBError{Sentinel: ErrConnectionFailed, Cause: err}
+		}
+	}
+	return defaultMap(err)
+}
+
+// libsqlCodes is checked in order, so extended codes precede their primary
+// code.
+var libsqlCodes = []struct {
+	name     string
+	sentinel error
+}{
+	{"SQLITE_CONSTRAINT_UNIQUE", ErrDuplicateKey},
+	{"SQLITE_CONSTRAINT_PRIMARYKEY", ErrDuplicateKey},
+	{"SQLITE_CONSTRAINT_FOREIGNKEY", ErrForeignKeyViolation},
+	{"SQLITE_CONSTRAINT_CHECK", ErrCheckViolation},
+	{"SQLITE_CONSTRAINT_NOTNULL", ErrNotNullViolation},
+	{"SQLITE_MISMATCH", ErrInvalidData},
+	{"SQLITE_BUSY", ErrDeadlock},
+	{"SQLITE_LOCKED", ErrDeadlock},
+	{"SQLITE_CANTOPEN", ErrConnectionFailed},
+}
+
+// httpStatus extracts the status code from the libsql clients' "unexpected
+// status code 503" / "status code: 503" messages.
+func httpStatus(s string) (int, bool) {
+	const marker = "status code"
+	idx := strings.Index(s, marker)
+	if idx < 0 {
+		return 0, false
+	}
+	rest := strings.TrimLeft(s[idx+len(marker):], ": ")
+	end := 0
+	for end < len(rest) && rest[end] >= '0' && rest[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(rest[:end])
+	return n, err == nil
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Vitess / PlanetScale compatibility
+// ─────────────────────────────────────────────────────────────────────────────
+
+const (
+	// VitessMaxRows is the default row limit of a vttablet result set
+	// (PlanetScale's as well); larger results fail with ErrResourceExhausted.
+	// Page reads with keyset pagination well below it.
+	VitessMaxRows = 100_000
+
+	// VitessTxTimeout is PlanetScale's transaction timeout. Transactions
+	// still open after it are killed and fail with ErrDeadlock.
+	VitessTxTimeout = 20 * time.Second
+
+	// vitessMaxBatchRows keeps one multi-row statement well inside the
+	// vttablet query size and transaction time limits.
+	vitessMaxBatchRows = 1000
+
+	// mysqlMaxPlaceholders is the protocol limit of bind parameters per
+	// statement.
+	mysqlMaxPlaceholders = 65535
+)
+
+// SafeBatchSize returns how many rows of columns values each to write per
+// statement or BatchExec call. It respects MySQL's placeholder limit and, in
+// Vitess mode, stays at 1000 rows so a batch finishes well inside the
+// transaction timeout.
+//
+//	size := db.MySQLDriver{Vitess: true}.SafeBatchSize(4) // 1000
+func (m MySQLDriver) SafeBatchSize(columns int) int {
+	if columns < 1 {
+		columns = 1
+	}
+	n := mysqlMaxPlaceholders / columns
+	if m.Vitess {
+		n = min(n, vitessMaxBatchRows)
+	}
+	return n
+}
+
+// vitessErrors maps vttablet/vtgate messages, which arrive as MySQL error
+// 1105 wrapping a gRPC status ("vttablet: rpc error: code = ResourceExhausted
+// desc = ..."). Checked in order: reserved connection failures also carry
+// code Aborted but mean the session's connection is gone.
+var vitessErrors = []struct {
+	match    string
+	sentinel error
+}{
+	{"reserved connection", ErrConnectionFailed},
+	{"Row count exceeded", ErrResourceExhausted},
+	{"code = ResourceExhausted", ErrResourceExhausted},
+	{"code = DeadlineExceeded", ErrTimeout},
+	{"code = Aborted", ErrDeadlock},
+	{"code = Unavailable", ErrConnectionFailed},
+	{"code = ClusterEvent", ErrConnectionFailed},
+}
+
+// mapVitess is the ErrorMapper of MySQLDriver in Vitess mode. Errors it does
+// not recognise go to DefaultErrorMapper.
+func mapVitess(err error) error {
+	if err == nil {
+		return nil
+	}
+	var dbe *DBError
+	if errors.As(err, &dbe) {
+		return err
+	}
+	s := err.Error()
+	for _, v := range vitessErrors {
+		if strings.Contains(s, v.match) {
+			return &DBError{Sentinel: v.sentinel, Cause: err}
+		}
+	}
+	return defaultMap(err)
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Enums — string columns restricted to a known set of values
+// ─────────────────────────────────────────────────────────────────────────────
+
+// EnumError reports a value outside the set registered for an enum type,
+// read from or about to be written to the database.
+type EnumError struct {
+	Type  string // Go type name, e.g. "models.OrderStatus"
+	Value string
+}
+
+func (e *EnumError) Error() string {
+	return fmt.Sprintf("sqltoolkit/db: invalid %s value %q", e.Type, e.Value)
+}
+
+// EnumSet is the set of valid values of T, created by RegisterEnum.
+type EnumSet[T ~string] struct {
+	typ    string
+	values []T
+	valid  map[T]bool
+}
+
+var enumRegistry sync.Map // reflect.Type → *EnumSet[T]
+
+// RegisterEnum declares the valid values of T, for Enum[T] and for direct
+// validation. Call it once per type, from a package-level variable, next to
+// the constants (`sqltoolkit enum` generates both):
+//
+//	type OrderStatus string
+//
+//	const (
+//	    OrderStatusPending OrderStatus = "pending"
+//	    OrderStatusPaid    OrderStatus = "paid"
+//	)
+//
+//	var OrderStatusValues = db.RegisterEnum(OrderStatusPending, OrderStatusPaid)
+//
+// Registering T again replaces its values.
+func RegisterEnum[T ~string](values ...T) *EnumSet[T] {
+	s := &EnumSet[T]{
+		typ:    reflect.TypeFor[T]().String(),
+		values: append([]T(nil), values...),
+		valid:  make(map[T]bool, len(values)),
+	}
+	for _, v := range values {
+		s.valid[v] = true
+	}
+	enumRegistry.Store(reflect.TypeFor[T](), s)
+	return s
+}
+
+// Values returns the valid values in registration order.
+func (s *EnumSet[T]) Values() []T { return append([]T(nil), s.values...) }
+
+// Valid reports whether v is one of the values.
+func (s *EnumSet[T]) Valid(v T) bool { return s.valid[v] }
+
+// Parse converts str to T, or returns an *EnumError — e.g. for request input.
+func (s *EnumSet[T]) Parse(str string) (T, error) {
+	if !s.valid[T(str)] {
+		return "", &EnumError{Type: s.typ, Value: str}
+	}
+	return T(str), nil
+}
+
+func enumSetOf[T ~string]() (*EnumSet[T], error) {
+	s, ok := enumRegistry.Load(reflect.TypeFor[T]())
+	if !ok {
+		return nil, fmt.Errorf("sqltoolkit/db: no values registered for enum %s", reflect.TypeFor[T]())
+	}
+	return s.(*EnumSet[T]), nil
+}
+
+// Enum is a nullable column holding a T registered with RegisterEnum. Scan
+// and Value both check the value against the registered set, so a typo in
+// code or an unexpected value in the database fails with *EnumError instead
+// of spreading as a raw string. A NULL column scans with Valid false.
+//
+//	type Order struct {
+//	    ID     int64
+//	    Status db.Enum[OrderStatus]
+//	}
+//	err := row.Scan(&o.ID, &o.Status)
+//	if o.Status.V == OrderStatusPaid { ... }
+type Enum[T ~string] struct {
+	V     T
+	Valid bool
+}
+
+// NewEnum returns a valid Enum holding v.
+func NewEnum[T ~string](v T) Enum[T] { return Enum[T]{V: v, Valid: true} }
+
+// Scan implements sql.Scanner.
+func (e *Enum[T]) Scan(src any) error {
+	var str string
+	switch v := src.(type) {
+	case nil:
+		*e = Enum[T]{}
+		return nil
+	case string:
+		str = v
+	case []byte:
+		str = string(v)
+	default:
+		return fmt.Errorf("sqltoolkit/db: cannot scan %T into Enum[%s]", src, reflect.TypeFor[T]())
+	}
+	s, err := enumSetOf[T]()
+	if err != nil {
+		return err
+	}
+	v, err := s.Parse(str)
+	if err != nil {
+		return err
+	}
+	*e = Enum[T]{V: v, Valid: true}
+	return nil
+}
+
+// Value implements driver.Valuer.
+func (e Enum[T]) Value() (driver.Value, error) {
+	if !e.Valid {
+		return nil, nil
+	}
+	s, err := enumSetOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	if !s.Valid(e.V) {
+		return nil, &EnumError{Type: s.typ, Value: string(e.V)}
+	}
+	return string(e.V), nil
+}
@@ -4,7 +4,9 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 )
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -30,8 +32,38 @@ var (
 	// ErrCheckViolation is returned when a CHECK constraint is violated.
 	ErrCheckViolation = errors.New("sqltoolkit/db: check constraint violation")
 
+	// ErrNotNullViolation is returned when a statement leaves a NOT NULL
+	// column without a value — usually a missing field in the input.
+	ErrNotNullViolation = errors.New("sqltoolkit/db: not null violation")
+
+	// ErrInvalidData is returned when the database rejects a value itself:
+	// out of range, too long, malformed (PostgreSQL SQLSTATE class 22).
+	// Like the constraint violations it points at bad input, not at a fault.
+	ErrInvalidData = errors.New("sqltoolkit/db: invalid data")
+
 	// ErrConnectionFailed is returned when the driver cannot reach the server.
 	ErrConnectionFailed = errors.New("sqltoolkit/db: connection failed")
+
+	// ErrResourceExhausted is returned when the server refuses work to
+	// protect itself — a full connection or transaction pool, or a result
+	// over the row limit. Back off, or split the statement.
+	ErrResourceExhausted = errors.New("sqltoolkit/db: resource exhausted")
+
+	// ErrInvalidFilter is returned when caller-supplied filter or sort input
+	// does not pass the whitelist. It never reaches the database.
+	ErrInvalidFilter = errors.New("sqltoolkit/db: invalid filter")
+
+	// ErrBudgetExceeded is returned when a context created by WithQueryBudget
+	// has used up its statement allowance. It never reaches the database.
+	ErrBudgetExceeded = errors.New("sqltoolkit/db: query budget exceeded")
+
+	// ErrReadOnly is returned for write statements while read-only mode is
+	// on (see SetReadOnly). It never reaches the database.
+	ErrReadOnly = errors.New("sqltoolkit/db: read-only mode")
+
+	// ErrTruncated is returned with the first n rows when a result under
+	// WithMaxRows has more than n.
+	ErrTruncated = errors.New("sqltoolkit/db: result truncated")
 )
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -44,6 +76,13 @@ func IsForeignKeyViolation(err error) bool { return errors.Is(err, ErrForeignKey
 func IsDeadlock(err error) bool           { return errors.Is(err, ErrDeadlock) }
 func IsTimeout(err error) bool            { return errors.Is(err, ErrTimeout) }
 func IsCheckViolation(err error) bool     { return errors.Is(err, ErrCheckViolation) }
+func IsNotNullViolation(err error) bool   { return errors.Is(err, ErrNotNullViolation) }
+func IsInvalidData(err error) bool        { return errors.Is(err, ErrInvalidData) }
+func IsResourceExhausted(err error) bool   { return errors.Is(err, ErrResourceExhausted) }
+func IsInvalidFilter(err error) bool      { return errors.Is(err, ErrInvalidFilter) }
+func IsBudgetExceeded(err error) bool     { return errors.Is(err, ErrBudgetExceeded) }
+func IsReadOnly(err error) bool           { return errors.Is(err, ErrReadOnly) }
+func IsTruncated(err error) bool          { return errors.Is(err, ErrTruncated) }
 
 // ─────────────────────────────────────────────────────────────────────────────
 // DBError — rich error type preserving original driver error
@@ -59,6 +98,76 @@ type DBError struct {
 	Cause error
 	// Message is an optional human-readable hint.
 	Message string
+
+	// Op, Query and Duration describe the call that failed: the kind of
+	// call, the statement (trimmed to 500 bytes) and the time spent until
+	// the error. They are set for errors from DB, Tx, Stmt and Row and are
+	// not part of Error(), so statements never leak into messages shown to
+	// users; log the error with slog to include them (see LogValue).
+	Op       Op
+	Query    string
+	Duration time.Duration
+
+	// RetryAfter is the backoff the server's state suggests before trying
+	// again — set when it refused a connection for being at capacity. Zero
+	// when the error carries no hint. WithRetry waits at least this long.
+	RetryAfter time.Duration
+}
+
+// Op names the kind of call that produced a DBError.
+type Op string
+
+const (
+	OpExec     Op = "exec"
+	OpQuery    Op = "query"
+	OpQueryRow Op = "query_row"
+	OpPrepare  Op = "prepare"
+)
+
+// LogValue implements slog.LogValuer, so logging a DBError as an attribute
+// records its context as a group:
+//
+//	slog.Error("update failed", slog.Any("error", err))
+//	// error.msg=... error.op=exec error.query="UPDATE ..." error.duration=3ms
+func (e *DBError) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("msg", e.Error())}
+	if e.Op != "" {
+		attrs = append(attrs, slog.String("op", string(e.Op)))
+	}
+	if e.Query != "" {
+		attrs = append(attrs, slog.String("query", e.Query))
+	}
+	if e.Duration > 0 {
+		attrs = append(attrs, slog.Duration("duration", e.Duration))
+	}
+	if e.RetryAfter > 0 {
+		attrs = append(attrs, slog.Duration("retry_after", e.RetryAfter))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// pressureBackoff is the RetryAfter attached to "too many connections"
+// errors: long enough for the server to release a few sessions.
+const pressureBackoff = time.Second
+
+// RetryAfter returns the backoff suggested by a mapped error, if any.
+func RetryAfter(err error) (time.Duration, bool) {
+	var dbe *DBError
+	if !errors.As(err, &dbe) || dbe.RetryAfter <= 0 {
+		return 0, false
+	}
+	return dbe.RetryAfter, true
+}
+
+// annotate records the failing call on a mapped *DBError. Errors that are
+// not a DBError, or already carry a call, are returned unchanged.
+func annotate(err error, op Op, query string, start time.Time) error {
+	var dbe *DBError
+	if err == nil || !errors.As(err, &dbe) || dbe.Op != "" {
+		return err
+	}
+	dbe.Op, dbe.Query, dbe.Duration = op, trimQuery(query), time.Since(start)
+	return err
 }
 
 func (e *DBError) Error() string {
@@ -201,12 +310,21 @@ func mapByPGCode(code string, cause error) error {
 		return &DBError{Sentinel: ErrForeignKeyViolation, Cause: cause}
 	case "23514": // check_violation
 		return &DBError{Sentinel: ErrCheckViolation, Cause: cause}
+	case "23502": // not_null_violation
+		return &DBError{Sentinel: ErrNotNullViolation, Cause: cause}
 	case "40P01": // deadlock_detected
 		return &DBError{Sentinel: ErrDeadlock, Cause: cause}
 	case "57014": // query_canceled (statement_timeout)
 		return &DBError{Sentinel: ErrTimeout, Cause: cause}
 	case "08000", "08003", "08006", "08001", "08004", "08007", "08P01":
 		return &DBError{Sentinel: ErrConnectionFailed, Cause: cause}
+	case "53300": // too_many_connections
+		return &DBError{Sentinel: ErrResourceExhausted, Cause: cause, RetryAfter: pressureBackoff}
+	case "57P03": // cannot_connect_now (starting up or shutting down)
+		return &DBError{Sentinel: ErrConnectionFailed, Cause: cause, RetryAfter: pressureBackoff}
+	}
+	if strings.HasPrefix(code, "22") { // data_exception class
+		return &DBError{Sentinel: ErrInvalidData, Cause: cause}
 	}
 	return nil
 }
@@ -230,35 +348,85 @@ func mapMySQLError(err error) error {
 		return &DBError{Sentinel: ErrDuplicateKey, Cause: err}
 	case 1452, 1216, 1217: // ER_NO_REFERENCED_ROW, ER_ROW_IS_REFERENCED
 		return &DBError{Sentinel: ErrForeignKeyViolation, Cause: err}
+	case 1048, 1364: // ER_BAD_NULL_ERROR, ER_NO_DEFAULT_FOR_FIELD
+		return &DBError{Sentinel: ErrNotNullViolation, Cause: err}
+	case 1264, 1265, 1292, 1366, 1406: // out of range, truncated, incorrect value, too long
+		return &DBError{Sentinel: ErrInvalidData, Cause: err}
 	case 1213: // ER_LOCK_DEADLOCK
 		return &DBError{Sentinel: ErrDeadlock, Cause: err}
 	case 3024: // ER_QUERY_TIMEOUT
 		return &DBError{Sentinel: ErrTimeout, Cause: err}
 	case 1045, 2002, 2003, 2006, 2013:
 		return &DBError{Sentinel: ErrConnectionFailed, Cause: err}
+	case 1040, 1203: // ER_CON_COUNT_ERROR, ER_TOO_MANY_USER_CONNECTIONS
+		return &DBError{Sentinel: ErrResourceExhausted, Cause: err, RetryAfter: pressureBackoff}
 	}
 	return nil
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
-// SQLite mapping (string-based, driver doesn't export typed errors)
+// SQLite mapping
 // ─────────────────────────────────────────────────────────────────────────────
 
+// mapSQLiteError maps modernc.org/sqlite errors by their extended result
+// code and mattn/go-sqlite3 errors, whose Error type has no methods to
+// duck-type against, by message. Both drivers use SQLite's own messages, so
+// the message match covers either.
 func mapSQLiteError(err error) error {
+	type sqliteErr interface {
+		error
+		Code() int
+	}
+	var se sqliteErr
+	if errors.As(err, &se) {
+		if mapped := mapBySQLiteCode(se.Code(), err); mapped != nil {
+			return mapped
+		}
+	}
+
 	s := err.Error()
 	switch {
-	case strings.Contains(s, "UNIQUE constraint failed"):
+	case strings.Contains(s, "UNIQUE constraint failed"),
+		strings.Contains(s, "PRIMARY KEY constraint failed"):
 		return &DBError{Sentinel: ErrDuplicateKey, Cause: err}
 	case strings.Contains(s, "FOREIGN KEY constraint failed"):
 		return &DBError{Sentinel: ErrForeignKeyViolation, Cause: err}
 	case strings.Contains(s, "CHECK constraint failed"):
 		return &DBError{Sentinel: ErrCheckViolation, Cause: err}
-	case strings.Contains(s, "database is locked"):
+	case strings.Contains(s, "NOT NULL constraint failed"):
+		return &DBError{Sentinel: ErrNotNullViolation, Cause: err}
+	case strings.Contains(s, "datatype mismatch"):
+		return &DBError{Sentinel: ErrInvalidData, Cause: err}
+	case strings.Contains(s, "database is locked"),
+		strings.Contains(s, "database table is locked"):
 		return &DBError{Sentinel: ErrDeadlock, Cause: err}
 	}
 	return nil
 }
 
+// SQLite result codes: https://www.sqlite.org/rescode.html
+func mapBySQLiteCode(code int, cause error) error {
+	switch code {
+	case 2067, 1555: // SQLITE_CONSTRAINT_UNIQUE, SQLITE_CONSTRAINT_PRIMARYKEY
+		return &DBError{Sentinel: ErrDuplicateKey, Cause: cause}
+	case 787: // SQLITE_CONSTRAINT_FOREIGNKEY
+		return &DBError{Sentinel: ErrForeignKeyViolation, Cause: cause}
+	case 275: // SQLITE_CONSTRAINT_CHECK
+		return &DBError{Sentinel: ErrCheckViolation, Cause: cause}
+	case 1299: // SQLITE_CONSTRAINT_NOTNULL
+		return &DBError{Sentinel: ErrNotNullViolation, Cause: cause}
+	case 20: // SQLITE_MISMATCH
+		return &DBError{Sentinel: ErrInvalidData, Cause: cause}
+	}
+	switch code & 0xff { // primary code of an extended one
+	case 5, 6: // SQLITE_BUSY, SQLITE_LOCKED
+		return &DBError{Sentinel: ErrDeadlock, Cause: cause}
+	case 14: // SQLITE_CANTOPEN
+		return &DBError{Sentinel: ErrConnectionFailed, Cause: cause}
+	}
+	return nil
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // ChainedMapper — compose multiple mappers (first match wins)
 // ─────────────────────────────────────────────────────────────────────────────
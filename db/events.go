@@ -0,0 +1,140 @@
+package db
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Lifecycle events
+// ─────────────────────────────────────────────────────────────────────────────
+
+// EventKind names a lifecycle event. The kinds below are published by this
+// module; applications and other packages may publish their own.
+type EventKind string
+
+const (
+	// EventPoolOpened is published by Open once the pool answers a ping.
+	EventPoolOpened EventKind = "pool_opened"
+	// EventPoolClosed is published by Close.
+	EventPoolClosed EventKind = "pool_closed"
+	// EventFailover is published when a topology change retires a pool's
+	// connections (see db/aurora).
+	EventFailover EventKind = "failover"
+	// EventMigrationApplied is published by migrate.Record for each
+	// migration it records; Attrs holds "version", "name" and "duration".
+	EventMigrationApplied EventKind = "migration_applied"
+	// EventReadOnlyChanged is published by SetReadOnly; Attrs["read_only"]
+	// holds the new state.
+	EventReadOnlyChanged EventKind = "read_only_changed"
+)
+
+// Event is one lifecycle occurrence.
+type Event struct {
+	Kind EventKind
+	At   time.Time
+	// Driver is the database/sql driver name of the pool concerned, if any.
+	Driver string
+	// Attrs carries kind-specific details, e.g. "instance" for a failover or
+	// "version" for a migration.
+	Attrs map[string]any
+	// Err is the error behind the event, if any.
+	Err error
+}
+
+// EventBus fans lifecycle events out to subscribers. Publishing never
+// blocks: a channel subscriber that is not keeping up loses events, counted
+// by Dropped.
+type EventBus struct {
+	mu      sync.RWMutex
+	nextID  int
+	chans   map[int]chan Event
+	funcs   map[int]func(Event)
+	dropped atomic.Uint64
+}
+
+var events = &EventBus{}
+
+// Events returns the process-wide bus every DB publishes to.
+//
+//	ch, cancel := db.Events().Subscribe(16)
+//	defer cancel()
+//	for ev := range ch {
+//	    if ev.Kind == db.EventFailover { cache.Flush() }
+//	}
+func Events() *EventBus { return events }
+
+// Subscribe returns a channel receiving every event published from now on,
+// buffered to size, and a function that unsubscribes and closes it.
+func (b *EventBus) Subscribe(size int) (<-chan Event, func()) {
+	ch := make(chan Event, max(size, 1))
+	b.mu.Lock()
+	if b.chans == nil {
+		b.chans = map[int]chan Event{}
+	}
+	id := b.nextID
+	b.nextID++
+	b.chans[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.chans, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// On calls fn for every event published from now on, on the publishing
+// goroutine, so fn must be quick. The returned function unregisters it.
+func (b *EventBus) On(fn func(Event)) func() {
+	b.mu.Lock()
+	if b.funcs == nil {
+		b.funcs = map[int]func(Event){}
+	}
+	id := b.nextID
+	b.nextID++
+	b.funcs[id] = fn
+	b.mu.Unlock()
+	return func() {
+		b.mu.Lock()
+		delete(b.funcs, id)
+		b.mu.Unlock()
+	}
+}
+
+// Publish delivers ev to every subscriber. A zero At is set to now.
+func (b *EventBus) Publish(ev Event) {
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.chans {
+		select {
+		case ch <- ev:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+	for _, fn := range b.funcs {
+		safeEventFunc(fn, ev)
+	}
+}
+
+// Dropped returns how many events channel subscribers have missed.
+func (b *EventBus) Dropped() uint64 { return b.dropped.Load() }
+
+func safeEventFunc(fn func(Event), ev Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("sqltoolkit/db: event handler panic", "kind", ev.Kind, "panic", r)
+		}
+	}()
+	fn(ev)
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// FanOut — one query across several databases
+// ─────────────────────────────────────────────────────────────────────────────
+
+// defaultFanOutWorkers bounds FanOut when the caller gives no limit.
+const defaultFanOutWorkers = 8
+
+// FanOutError reports the databases a FanOut could not read from. Errs is
+// indexed like the Queriers passed to FanOut, with nil for those that
+// answered.
+type FanOutError struct {
+	Errs []error
+}
+
+func (e *FanOutError) Error() string {
+	var parts []string
+	for i, err := range e.Errs {
+		if err != nil {
+			parts = append(parts, fmt.Sprintf("#%d: %v", i, err))
+		}
+	}
+	return fmt.Sprintf("sqltoolkit/db: fan-out failed on %d of %d databases: %s",
+		len(parts), len(e.Errs), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the individual errors, so errors.Is and IsTimeout & co.
+// see through a FanOutError.
+func (e *FanOutError) Unwrap() []error {
+	var out []error
+	for _, err := range e.Errs {
+		if err != nil {
+			out = append(out, err)
+		}
+	}
+	return out
+}
+
+// FanOut runs query on every Querier concurrently — at most workers at a
+// time, 8 by default — and concatenates the rows in the order of qs. When
+// some databases fail, the rows of the others are returned together with a
+// *FanOutError, so callers decide whether a partial answer will do:
+//
+//	orders, err := db.FanOut(ctx, []db.Querier{eu, us, apac},
+//	    `SELECT id, total FROM orders WHERE customer_id = $1`, []any{id}, scanOrder)
+//	var fe *db.FanOutError
+//	if errors.As(err, &fe) { ... }
+//
+// Each database gets the same ctx; cancel it to abandon the slow ones.
+func FanOut[T any](ctx context.Context, qs []Querier, query string, args []any, scan ScanFunc[T], workers ...int) ([]T, error) {
+	limit := defaultFanOutWorkers
+	if len(workers) > 0 && workers[0] > 0 {
+		limit = workers[0]
+	}
+	results := make([][]T, len(qs))
+	errs := make([]error, len(qs))
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, q := range qs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			results[i], errs[i] = Select(ctx, q, scan, query, args...)
+		}()
+	}
+	wg.Wait()
+
+	var out []T
+	failed := false
+	for i, rows := range results {
+		if errs[i] != nil {
+			failed = true
+			continue
+		}
+		out = append(out, rows...)
+	}
+	if failed {
+		return out, &FanOutError{Errs: errs}
+	}
+	return out, nil
+}
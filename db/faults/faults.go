@@ -0,0 +1,193 @@
+// Package faults injects latency and errors into database calls so retry,
+// timeout and fallback paths can be exercised in integration tests without
+// breaking a real database.
+//
+// An Injector holds rules; Wrap puts it in front of any db.Querier (a *db.DB,
+// a *db.Tx or a decorator), so repositories built on the wrapper see the
+// faults while the rest of the test talks to the database directly:
+//
+//	inj := faults.New(1,
+//	    faults.Rule{Match: regexp.MustCompile(`^\s*UPDATE`), Err: db.ErrDeadlock, Times: 2},
+//	    faults.Rule{Latency: 50 * time.Millisecond, Probability: 0.1},
+//	)
+//	users := repo.NewUserRepo(faults.Wrap(conn, inj))
+//	err := db.WithRetry(ctx, db.RetryConfig{MaxAttempts: 3}, func() error {
+//	    _, err := users.Update(ctx, params)
+//	    return err
+//	})
+//	// err == nil after two injected deadlocks; inj.Fired() == 2
+//
+// Injected errors wrap the rule's error, so errors.Is(err, db.ErrDeadlock)
+// and the Is* helpers behave as they would for a real driver error.
+package faults
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Rule describes one fault.
+type Rule struct {
+	// Match selects the statements the rule applies to; nil matches all.
+	Match *regexp.Regexp
+	// Probability is the chance, between 0 and 1, that a matching statement
+	// triggers the rule. Zero means always.
+	Probability float64
+	// Latency is added before the statement runs (or fails). A context that
+	// expires during the delay fails the statement with db.ErrTimeout.
+	Latency time.Duration
+	// Err, when set, fails the statement instead of running it, e.g.
+	// db.ErrDeadlock, db.ErrTimeout or db.ErrConnectionFailed.
+	Err error
+	// Times limits how often the rule fires; zero means no limit.
+	Times int
+}
+
+// Injector decides, statement by statement, which rules fire.
+type Injector struct {
+	mu    sync.Mutex
+	rng   *rand.Rand
+	rules []*rule
+	fired int
+}
+
+type rule struct {
+	Rule
+	fired int
+}
+
+// New returns an Injector with rules. seed makes probabilistic rules
+// reproducible across runs.
+func New(seed int64, rules ...Rule) *Injector {
+	inj := &Injector{rng: rand.New(rand.NewSource(seed))}
+	for _, r := range rules {
+		inj.Add(r)
+	}
+	return inj
+}
+
+// Add appends a rule. Rules are evaluated in order; every rule that fires
+// contributes its latency, and the first firing rule with an Err fails the
+// statement.
+func (inj *Injector) Add(r Rule) {
+	inj.mu.Lock()
+	inj.rules = append(inj.rules, &rule{Rule: r})
+	inj.mu.Unlock()
+}
+
+// Reset removes every rule and clears the counters.
+func (inj *Injector) Reset() {
+	inj.mu.Lock()
+	inj.rules, inj.fired = nil, 0
+	inj.mu.Unlock()
+}
+
+// Fired returns how many statements have had at least one rule fire.
+func (inj *Injector) Fired() int {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	return inj.fired
+}
+
+// decide returns the latency and error to inject for query.
+func (inj *Injector) decide(query string) (time.Duration, error) {
+	inj.mu.Lock()
+	defer inj.mu.Unlock()
+	var (
+		delay time.Duration
+		err   error
+		hit   bool
+	)
+	for _, r := range inj.rules {
+		if r.Times > 0 && r.fired >= r.Times {
+			continue
+		}
+		if r.Match != nil && !r.Match.MatchString(query) {
+			continue
+		}
+		if r.Probability > 0 && inj.rng.Float64() >= r.Probability {
+			continue
+		}
+		r.fired++
+		hit = true
+		delay += r.Latency
+		if err == nil && r.Err != nil {
+			err = r.Err
+		}
+	}
+	if hit {
+		inj.fired++
+	}
+	return delay, err
+}
+
+// inject applies the faults chosen for query.
+func (inj *Injector) inject(ctx context.Context, query string) error {
+	delay, err := inj.decide(query)
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("faults: injected latency: %w: %w", db.ErrTimeout, ctx.Err())
+		case <-t.C:
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("faults: injected: %w", err)
+	}
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Querier wrapper
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Wrap returns a Querier that consults inj before every call to q. Dialect
+// and clock are those of q.
+func Wrap(q db.Querier, inj *Injector) db.Querier { return &querier{q: q, inj: inj} }
+
+type querier struct {
+	q   db.Querier
+	inj *Injector
+}
+
+func (w *querier) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := w.inj.inject(ctx, query); err != nil {
+		return nil, err
+	}
+	return w.q.Exec(ctx, query, args...)
+}
+
+func (w *querier) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := w.inj.inject(ctx, query); err != nil {
+		return nil, err
+	}
+	return w.q.Query(ctx, query, args...)
+}
+
+func (w *querier) QueryRow(ctx context.Context, query string, args ...any) *db.Row {
+	if err := w.inj.inject(ctx, query); err != nil {
+		return db.ErrRow(err)
+	}
+	return w.q.QueryRow(ctx, query, args...)
+}
+
+// Prepare injects faults when the statement is prepared, not on each
+// execution of the prepared statement.
+func (w *querier) Prepare(ctx context.Context, query string) (*db.Stmt, error) {
+	if err := w.inj.inject(ctx, query); err != nil {
+		return nil, err
+	}
+	return w.q.Prepare(ctx, query)
+}
+
+func (w *querier) Dialect() db.Dialect { return db.DialectFrom(w.q) }
+func (w *querier) Now() time.Time      { return db.NowFrom(w.q) }
@@ -0,0 +1,65 @@
+package faults_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/faults"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestInjector(t *testing.T) {
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	_, _ = d.Exec(ctx, `CREATE TABLE t (id INTEGER PRIMARY KEY, n INTEGER)`)
+	_, _ = d.Exec(ctx, `INSERT INTO t (id, n) VALUES (1, 0)`)
+
+	inj := faults.New(1, faults.Rule{Match: regexp.MustCompile(`^UPDATE`), Err: db.ErrDeadlock, Times: 2})
+	q := faults.Wrap(d, inj)
+	if db.DialectFrom(q) != db.DialectSQLite {
+		t.Fatalf("dialect = %q", db.DialectFrom(q))
+	}
+
+	attempts := 0
+	err = db.WithRetry(ctx, db.RetryConfig{MaxAttempts: 3}, func() error {
+		attempts++
+		_, err := q.Exec(ctx, `UPDATE t SET n = n + 1 WHERE id = 1`)
+		return err
+	})
+	if err != nil || attempts != 3 || inj.Fired() != 2 {
+		t.Fatalf("retry: err=%v attempts=%d fired=%d", err, attempts, inj.Fired())
+	}
+	var n int
+	if err := q.QueryRow(ctx, `SELECT n FROM t WHERE id = 1`).Scan(&n); err != nil || n != 1 {
+		t.Fatalf("n = %d (err %v): unmatched statements must pass through", n, err)
+	}
+
+	// Latency beyond the deadline surfaces as a timeout.
+	inj.Reset()
+	inj.Add(faults.Rule{Latency: time.Second})
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := q.QueryRow(short, `SELECT n FROM t`).Scan(&n); !db.IsTimeout(err) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	// Probabilistic rules are reproducible for a seed.
+	count := func() int {
+		inj := faults.New(42, faults.Rule{Err: db.ErrConnectionFailed, Probability: 0.3})
+		q := faults.Wrap(d, inj)
+		for range 100 {
+			_, _ = q.Exec(ctx, `SELECT 1`)
+		}
+		return inj.Fired()
+	}
+	if a, b := count(), count(); a != b || a < 10 || a > 50 {
+		t.Fatalf("fired %d and %d times", a, b)
+	}
+}
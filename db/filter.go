@@ -0,0 +1,226 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Placeholder styles
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Placeholder renders the n-th (1-based) bind parameter marker.
+type Placeholder func(n int) string
+
+var (
+	// DollarPlaceholder renders $1, $2, … (PostgreSQL, SQLite).
+	DollarPlaceholder Placeholder = func(n int) string { return "$" + strconv.Itoa(n) }
+
+	// QuestionPlaceholder renders ? for every parameter (MySQL, SQLite).
+	QuestionPlaceholder Placeholder = func(int) string { return "?" }
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Conditions — parameterised WHERE clause builder
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Conditions accumulates AND-ed predicates together with their bind
+// arguments. Values never reach the SQL text — only placeholders do — so it
+// is safe to feed caller-supplied filter values straight in.
+//
+// Column names and operators, on the other hand, are written by the
+// repository author. Never build expr from user input.
+//
+//	c := db.NewDialectConditions(db.DialectPostgres)
+//	if f.NamePrefix != "" {
+//	    c.Add("name LIKE ?"+db.DialectPostgres.LikeEscape(), db.LikePrefix(f.NamePrefix))
+//	}
+//	query := "SELECT ... FROM users " + c.Where() + " LIMIT " + c.Bind(limit)
+//	rows, err := q.Query(ctx, query, c.Args()...)
+type Conditions struct {
+	ph      Placeholder
+	dialect Dialect
+	preds   []string
+	args    []any
+}
+
+// NewConditions returns an empty builder rendering placeholders with ph.
+// A nil ph defaults to DollarPlaceholder.
+func NewConditions(ph Placeholder) *Conditions {
+	if ph == nil {
+		ph = DollarPlaceholder
+	}
+	return &Conditions{ph: ph}
+}
+
+// NewDialectConditions returns an empty builder for d: it renders d's
+// placeholders, and AddFilter emits d's LIKE syntax.
+func NewDialectConditions(d Dialect) *Conditions {
+	return &Conditions{ph: d.Placeholder(), dialect: d}
+}
+
+// Add appends a predicate. Every '?' in expr is replaced by the next
+// placeholder and consumes one of args, in order. It panics if the number of
+// markers and args differ — that is always a programming error.
+func (c *Conditions) Add(expr string, args ...any) *Conditions {
+	if n := strings.Count(expr, "?"); n != len(args) {
+		panic(fmt.Sprintf("sqltoolkit/db: Conditions.Add: %d placeholders but %d args in %q", n, len(args), expr))
+	}
+	var b strings.Builder
+	n := len(c.args)
+	for _, r := range expr {
+		if r == '?' {
+			n++
+			b.WriteString(c.ph(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	c.preds = append(c.preds, b.String())
+	c.args = append(c.args, args...)
+	return c
+}
+
+// Bind appends v as an argument without a predicate and returns its
+// placeholder. Use it for LIMIT/OFFSET and other trailing parameters.
+func (c *Conditions) Bind(v any) string {
+	c.args = append(c.args, v)
+	return c.ph(len(c.args))
+}
+
+// Where returns "WHERE p1 AND p2 …", or "" when no predicates were added.
+func (c *Conditions) Where() string {
+	if len(c.preds) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(c.preds, " AND ")
+}
+
+// Args returns the bind arguments in placeholder order.
+func (c *Conditions) Args() []any { return c.args }
+
+// ─────────────────────────────────────────────────────────────────────────────
+// LIKE helpers
+// ─────────────────────────────────────────────────────────────────────────────
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes LIKE wildcards in s using backslash. Pair it with
+// Dialect.LikeEscape in the predicate.
+func EscapeLike(s string) string { return likeEscaper.Replace(s) }
+
+// LikePrefix returns a pattern matching values starting with s.
+func LikePrefix(s string) string { return EscapeLike(s) + "%" }
+
+// LikeSuffix returns a pattern matching values ending with s.
+func LikeSuffix(s string) string { return "%" + EscapeLike(s) }
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Sorting
+// ─────────────────────────────────────────────────────────────────────────────
+
+// SortDirection is ASC or DESC.
+type SortDirection string
+
+const (
+	Asc  SortDirection = "ASC"
+	Desc SortDirection = "DESC"
+)
+
+// ParseSortDirection accepts "asc"/"desc" in any case; "" means Asc.
+func ParseSortDirection(s string) (SortDirection, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "", "ASC":
+		return Asc, nil
+	case "DESC":
+		return Desc, nil
+	}
+	return "", fmt.Errorf("%w: sort direction %q", ErrInvalidFilter, s)
+}
+
+// SortClause validates field against allowed (API name → SQL column) and
+// returns "ORDER BY <column> <dir>". An empty field selects def, which must
+// itself be a key of allowed.
+func SortClause(allowed map[string]string, field, def string, dir SortDirection) (string, error) {
+	if field == "" {
+		field = def
+	}
+	return OrderBy(allowed, []SortField{{Field: field, Dir: dir}})
+}
+
+// SortField is one requested sort key: an API field name, not a column.
+type SortField struct {
+	Field string
+	Dir   SortDirection
+}
+
+// maxSortFields bounds OrderBy so a request cannot sort on every column.
+const maxSortFields = 8
+
+// OrderBy validates requested against allowed (API name → SQL column) and
+// returns "ORDER BY <col> <dir>, ...". Unknown or repeated fields, bad
+// directions and more than eight keys fail with ErrInvalidFilter; only
+// columns from allowed ever reach the SQL text, so requested may come
+// straight from a query string (see ParseSort). An empty Dir means Asc, and
+// an empty requested returns "".
+//
+//	orderBy, err := db.OrderBy(map[string]string{"name": "name", "created": "created_at"},
+//	    []db.SortField{{Field: "created", Dir: db.Desc}, {Field: "name"}})
+//	// ORDER BY created_at DESC, name ASC
+func OrderBy(allowed map[string]string, requested []SortField) (string, error) {
+	if len(requested) == 0 {
+		return "", nil
+	}
+	if len(requested) > maxSortFields {
+		return "", fmt.Errorf("%w: %d sort fields, at most %d allowed", ErrInvalidFilter, len(requested), maxSortFields)
+	}
+	terms := make([]string, len(requested))
+	seen := make(map[string]bool, len(requested))
+	for i, sf := range requested {
+		col, ok := allowed[sf.Field]
+		if !ok {
+			return "", fmt.Errorf("%w: sort field %q not allowed", ErrInvalidFilter, sf.Field)
+		}
+		if seen[col] {
+			return "", fmt.Errorf("%w: sort field %q repeated", ErrInvalidFilter, sf.Field)
+		}
+		seen[col] = true
+		dir := sf.Dir
+		if dir == "" {
+			dir = Asc
+		}
+		if dir != Asc && dir != Desc {
+			return "", fmt.Errorf("%w: sort direction %q", ErrInvalidFilter, dir)
+		}
+		terms[i] = col + " " + string(dir)
+	}
+	return "ORDER BY " + strings.Join(terms, ", "), nil
+}
+
+// ParseSort parses the common "sort" query parameter format: comma-separated
+// field names, each optionally prefixed with "-" for descending or "+" for
+// ascending, e.g. "-created_at,name". Fields are not validated here; pass
+// the result to OrderBy.
+func ParseSort(s string) ([]SortField, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	fields := make([]SortField, len(parts))
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		dir := Asc
+		switch {
+		case strings.HasPrefix(p, "-"):
+			p, dir = p[1:], Desc
+		case strings.HasPrefix(p, "+"):
+			p = p[1:]
+		}
+		if p == "" {
+			return nil, fmt.Errorf("%w: empty sort field in %q", ErrInvalidFilter, s)
+		}
+		fields[i] = SortField{Field: p, Dir: dir}
+	}
+	return fields, nil
+}
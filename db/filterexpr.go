@@ -0,0 +1,345 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Filter expressions — client-supplied predicates over whitelisted columns
+// ─────────────────────────────────────────────────────────────────────────────
+
+// FilterOp is the comparison of a Filter leaf.
+type FilterOp string
+
+const (
+	OpEq  FilterOp = "eq"
+	OpNe  FilterOp = "ne"
+	OpLt  FilterOp = "lt"
+	OpLte FilterOp = "lte"
+	OpGt  FilterOp = "gt"
+	OpGte FilterOp = "gte"
+	// OpIn and OpNotIn take a non-empty array of values.
+	OpIn    FilterOp = "in"
+	OpNotIn FilterOp = "not_in"
+	// OpPrefix, OpSuffix and OpContains match text with LIKE; wildcards in
+	// the value are escaped.
+	OpPrefix   FilterOp = "prefix"
+	OpSuffix   FilterOp = "suffix"
+	OpContains FilterOp = "contains"
+	// OpNull takes a boolean: true for IS NULL, false for IS NOT NULL.
+	OpNull FilterOp = "null"
+)
+
+var filterComparisons = map[FilterOp]string{
+	OpEq: "=", OpNe: "<>", OpLt: "<", OpLte: "<=", OpGt: ">", OpGte: ">=",
+}
+
+// Limits on a Filter, so a request cannot make the database evaluate an
+// arbitrarily large predicate.
+const (
+	maxFilterDepth  = 8
+	maxFilterTerms  = 32
+	maxFilterValues = 100
+)
+
+// Filter is a filter expression: a comparison of one field with a value, or
+// an AND, OR or NOT group of filters. It is shared between an HTTP API and
+// the repository behind it: clients send it as JSON,
+//
+//	{"or": [{"field": "name", "op": "prefix", "value": "Al"},
+//	        {"and": [{"field": "age", "op": "gte", "value": 18}, {"field": "email", "op": "null", "value": false}]}]}
+//
+// and the repository compiles it with CompileFilter or Conditions.AddFilter
+// against its own whitelist of API fields → SQL columns. Field names only
+// ever select columns from the whitelist and values are bound as
+// parameters, so a Filter can come straight from a request.
+//
+// Exactly one of (Field, Op, Value), And, Or and Not is set; the zero Filter
+// matches every row.
+type Filter struct {
+	Field string
+	Op    FilterOp
+	// Value is a string, number, bool or time.Time; a slice of them for
+	// OpIn and OpNotIn, []any or typed such as []int64 or []string.
+	Value any
+
+	And []Filter
+	Or  []Filter
+	Not *Filter
+}
+
+// Match returns the filter comparing field with value.
+func Match(field string, op FilterOp, value any) Filter {
+	return Filter{Field: field, Op: op, Value: value}
+}
+
+// AllOf returns the filter matching rows that match every f.
+func AllOf(f ...Filter) Filter { return Filter{And: f} }
+
+// AnyOf returns the filter matching rows that match at least one f.
+func AnyOf(f ...Filter) Filter { return Filter{Or: f} }
+
+// Negate returns the filter matching rows f does not match.
+func Negate(f Filter) Filter { return Filter{Not: &f} }
+
+// ParseFilter parses a JSON filter expression, e.g. from a "filter" query
+// parameter. Malformed input fails with ErrInvalidFilter; "" returns nil.
+// Fields are not validated here; pass the result to CompileFilter.
+func ParseFilter(s string) (*Filter, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var f Filter
+	if err := json.Unmarshal([]byte(s), &f); err != nil {
+		if IsInvalidFilter(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return &f, nil
+}
+
+type filterJSON struct {
+	Field string          `json:"field,omitempty"`
+	Op    FilterOp        `json:"op,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	And   []Filter        `json:"and,omitempty"`
+	Or    []Filter        `json:"or,omitempty"`
+	Not   *Filter         `json:"not,omitempty"`
+}
+
+// MarshalJSON writes the object form shown on Filter.
+func (f Filter) MarshalJSON() ([]byte, error) {
+	j := filterJSON{Field: f.Field, Op: f.Op, And: f.And, Or: f.Or, Not: f.Not}
+	if f.Field != "" || f.Op != "" {
+		v, err := json.Marshal(f.Value)
+		if err != nil {
+			return nil, err
+		}
+		j.Value = v
+	}
+	return json.Marshal(j)
+}
+
+// UnmarshalJSON reads the object form shown on Filter. Numbers become int64
+// when integral and float64 otherwise; unknown keys are rejected.
+func (f *Filter) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var j filterJSON
+	if err := dec.Decode(&j); err != nil {
+		if IsInvalidFilter(err) { // from a nested filter
+			return err
+		}
+		return fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	*f = Filter{Field: j.Field, Op: j.Op, And: j.And, Or: j.Or, Not: j.Not}
+	if len(j.Value) == 0 {
+		return nil
+	}
+	dec = json.NewDecoder(bytes.NewReader(j.Value))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return fmt.Errorf("%w: value: %v", ErrInvalidFilter, err)
+	}
+	f.Value = filterJSONValue(v)
+	return nil
+}
+
+func filterJSONValue(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		n, _ := x.Float64()
+		return n
+	case []any:
+		for i := range x {
+			x[i] = filterJSONValue(x[i])
+		}
+	}
+	return v
+}
+
+// CompileFilter validates f against allowed (API field → SQL column) and
+// returns it as a predicate for dialect d with '?' markers and its
+// arguments, ready for Conditions.Add. Unknown fields and operators, values
+// of the wrong shape and filters nested deeper than 8 levels or with more
+// than 32 comparisons fail with ErrInvalidFilter. A nil or zero f returns "".
+//
+//	expr, args, err := db.CompileFilter(db.DialectPostgres, f, map[string]string{"name": "name", "created": "created_at"})
+//	// (name LIKE ? ESCAPE '\' OR created_at >= ?), ["Al%", "2026-01-01"]
+func CompileFilter(d Dialect, f *Filter, allowed map[string]string) (string, []any, error) {
+	if f == nil {
+		return "", nil, nil
+	}
+	c := filterCompiler{dialect: d, allowed: allowed}
+	expr, err := c.compile(f, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return expr, c.args, nil
+}
+
+// AddFilter compiles f with CompileFilter, for the dialect given to
+// NewDialectConditions, and adds it as one predicate.
+func (c *Conditions) AddFilter(f *Filter, allowed map[string]string) error {
+	expr, args, err := CompileFilter(c.dialect, f, allowed)
+	if err != nil || expr == "" {
+		return err
+	}
+	c.Add(expr, args...)
+	return nil
+}
+
+type filterCompiler struct {
+	dialect Dialect
+	allowed map[string]string
+	args    []any
+	terms   int
+}
+
+func (c *filterCompiler) compile(f *Filter, depth int) (string, error) {
+	if depth > maxFilterDepth {
+		return "", fmt.Errorf("%w: filter nested deeper than %d levels", ErrInvalidFilter, maxFilterDepth)
+	}
+	forms := 0
+	for _, set := range []bool{f.Field != "" || f.Op != "", f.And != nil, f.Or != nil, f.Not != nil} {
+		if set {
+			forms++
+		}
+	}
+	if forms > 1 {
+		return "", fmt.Errorf("%w: a filter is one of a comparison, and, or, not", ErrInvalidFilter)
+	}
+	switch {
+	case f.And != nil:
+		return c.group(f.And, " AND ", depth)
+	case f.Or != nil:
+		return c.group(f.Or, " OR ", depth)
+	case f.Not != nil:
+		expr, err := c.compile(f.Not, depth+1)
+		if err != nil {
+			return "", err
+		}
+		if expr == "" {
+			return "", fmt.Errorf("%w: empty not", ErrInvalidFilter)
+		}
+		return "NOT (" + expr + ")", nil
+	case forms == 0:
+		return "", nil
+	}
+	return c.leaf(f)
+}
+
+func (c *filterCompiler) group(fs []Filter, sep string, depth int) (string, error) {
+	if len(fs) == 0 {
+		return "", fmt.Errorf("%w: empty%sgroup", ErrInvalidFilter, strings.ToLower(sep))
+	}
+	terms := make([]string, 0, len(fs))
+	for i := range fs {
+		expr, err := c.compile(&fs[i], depth+1)
+		if err != nil {
+			return "", err
+		}
+		if expr != "" {
+			terms = append(terms, expr)
+		}
+	}
+	switch len(terms) {
+	case 0:
+		return "", nil
+	case 1:
+		return terms[0], nil
+	}
+	return "(" + strings.Join(terms, sep) + ")", nil
+}
+
+func (c *filterCompiler) leaf(f *Filter) (string, error) {
+	if c.terms++; c.terms > maxFilterTerms {
+		return "", fmt.Errorf("%w: more than %d filter comparisons", ErrInvalidFilter, maxFilterTerms)
+	}
+	col, ok := c.allowed[f.Field]
+	if !ok {
+		return "", fmt.Errorf("%w: filter field %q not allowed", ErrInvalidFilter, f.Field)
+	}
+	bad := func(want string) error {
+		return fmt.Errorf("%w: filter %s %s: value must be %s", ErrInvalidFilter, f.Field, f.Op, want)
+	}
+	if sqlOp, ok := filterComparisons[f.Op]; ok {
+		if !filterScalar(f.Value) {
+			return "", bad("a string, number, bool or time")
+		}
+		c.args = append(c.args, f.Value)
+		return col + " " + sqlOp + " ?", nil
+	}
+	switch f.Op {
+	case OpIn, OpNotIn:
+		vals, ok := filterList(f.Value)
+		if !ok || len(vals) == 0 || len(vals) > maxFilterValues {
+			return "", bad(fmt.Sprintf("an array of 1 to %d values", maxFilterValues))
+		}
+		for _, v := range vals {
+			if !filterScalar(v) {
+				return "", bad("an array of strings, numbers, bools or times")
+			}
+		}
+		c.args = append(c.args, vals...)
+		marks := strings.TrimSuffix(strings.Repeat("?, ", len(vals)), ", ")
+		if f.Op == OpNotIn {
+			return col + " NOT IN (" + marks + ")", nil
+		}
+		return col + " IN (" + marks + ")", nil
+	case OpPrefix, OpSuffix, OpContains:
+		s, ok := f.Value.(string)
+		if !ok {
+			return "", bad("a string")
+		}
+		pattern := map[FilterOp]string{OpPrefix: LikePrefix(s), OpSuffix: LikeSuffix(s), OpContains: "%" + EscapeLike(s) + "%"}[f.Op]
+		c.args = append(c.args, pattern)
+		return col + " LIKE ?" + c.dialect.LikeEscape(), nil
+	case OpNull:
+		isNull, ok := f.Value.(bool)
+		if !ok {
+			return "", bad("true or false")
+		}
+		if isNull {
+			return col + " IS NULL", nil
+		}
+		return col + " IS NOT NULL", nil
+	}
+	return "", fmt.Errorf("%w: filter operator %q", ErrInvalidFilter, f.Op)
+}
+
+// filterList returns the elements of v when it is a slice or array of any
+// element type but byte: a []byte is not a list.
+func filterList(v any) ([]any, bool) {
+	if vals, ok := v.([]any); ok {
+		return vals, true
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	vals := make([]any, rv.Len())
+	for i := range vals {
+		vals[i] = rv.Index(i).Interface()
+	}
+	return vals, true
+}
+
+func filterScalar(v any) bool {
+	switch v.(type) {
+	case string, bool, time.Time,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
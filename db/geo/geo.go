@@ -0,0 +1,409 @@
+// Package geo provides database/sql Scanner/Valuer types for common
+// geospatial values.
+//
+// With PostGIS, Point and BBox are written as EWKT and read from (E)WKB — the
+// format PostGIS returns for geometry/geography columns — or from WKT when the
+// query uses ST_AsText. On databases without spatial types, store coordinates
+// in two plain columns and use ScanTargets / Args / BBox.Where.
+package geo
+
+import (
+	"database/sql/driver"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// DefaultSRID is WGS 84, the reference system used by GPS.
+const DefaultSRID = 4326
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Point
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Point is a longitude/latitude pair. SRID 0 is written as DefaultSRID.
+type Point struct {
+	Lng  float64
+	Lat  float64
+	SRID int
+}
+
+// NewPoint returns a WGS 84 point. Note the argument order: latitude first,
+// as humans write it; WKT itself is longitude first.
+func NewPoint(lat, lng float64) Point { return Point{Lng: lng, Lat: lat, SRID: DefaultSRID} }
+
+// WKT returns the point as "POINT(lng lat)".
+func (p Point) WKT() string {
+	return "POINT(" + fmtCoord(p.Lng) + " " + fmtCoord(p.Lat) + ")"
+}
+
+// Value implements driver.Valuer as EWKT ("SRID=4326;POINT(lng lat)").
+func (p Point) Value() (driver.Value, error) {
+	return "SRID=" + strconv.Itoa(srid(p.SRID)) + ";" + p.WKT(), nil
+}
+
+// Scan implements sql.Scanner. It accepts raw WKB, hex-encoded (E)WKB, and
+// (E)WKT.
+func (p *Point) Scan(src any) error {
+	g, err := decode(src)
+	if err != nil {
+		return err
+	}
+	if g.kind != wkbPoint {
+		return fmt.Errorf("geo: expected POINT, got geometry type %d", g.kind)
+	}
+	*p = Point{Lng: g.coords[0][0], Lat: g.coords[0][1], SRID: g.srid}
+	return nil
+}
+
+// Args returns (lat, lng) for writing to two plain columns.
+func (p Point) Args() []any { return []any{p.Lat, p.Lng} }
+
+// ScanTargets returns (&lat, &lng) for reading from two plain columns.
+//
+//	row.Scan(append([]any{&s.ID}, s.Location.ScanTargets()...)...)
+func (p *Point) ScanTargets() []any { return []any{&p.Lat, &p.Lng} }
+
+// NullPoint is a Point that may be NULL.
+type NullPoint struct {
+	Point Point
+	Valid bool
+}
+
+// Value implements driver.Valuer.
+func (n NullPoint) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Point.Value()
+}
+
+// Scan implements sql.Scanner.
+func (n *NullPoint) Scan(src any) error {
+	if src == nil {
+		*n = NullPoint{}
+		return nil
+	}
+	n.Valid = true
+	return n.Point.Scan(src)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// BBox
+// ─────────────────────────────────────────────────────────────────────────────
+
+// BBox is an axis-aligned bounding box in longitude/latitude.
+type BBox struct {
+	MinLng, MinLat float64
+	MaxLng, MaxLat float64
+	SRID           int
+}
+
+// Contains reports whether p lies inside or on the edge of b.
+func (b BBox) Contains(p Point) bool {
+	return p.Lng >= b.MinLng && p.Lng <= b.MaxLng && p.Lat >= b.MinLat && p.Lat <= b.MaxLat
+}
+
+// WKT returns the box as a closed five-vertex POLYGON.
+func (b BBox) WKT() string {
+	pt := func(x, y float64) string { return fmtCoord(x) + " " + fmtCoord(y) }
+	return "POLYGON((" + strings.Join([]string{
+		pt(b.MinLng, b.MinLat), pt(b.MaxLng, b.MinLat), pt(b.MaxLng, b.MaxLat),
+		pt(b.MinLng, b.MaxLat), pt(b.MinLng, b.MinLat),
+	}, ", ") + "))"
+}
+
+// Value implements driver.Valuer as an EWKT polygon.
+func (b BBox) Value() (driver.Value, error) {
+	return "SRID=" + strconv.Itoa(srid(b.SRID)) + ";" + b.WKT(), nil
+}
+
+// Scan implements sql.Scanner. Any POINT or POLYGON is accepted; the box is
+// its envelope (the result of ST_Envelope / box2d).
+func (b *BBox) Scan(src any) error {
+	g, err := decode(src)
+	if err != nil {
+		return err
+	}
+	if len(g.coords) == 0 {
+		return errors.New("geo: empty geometry")
+	}
+	out := BBox{
+		MinLng: math.Inf(1), MinLat: math.Inf(1),
+		MaxLng: math.Inf(-1), MaxLat: math.Inf(-1),
+		SRID: g.srid,
+	}
+	for _, c := range g.coords {
+		out.MinLng, out.MaxLng = math.Min(out.MinLng, c[0]), math.Max(out.MaxLng, c[0])
+		out.MinLat, out.MaxLat = math.Min(out.MinLat, c[1]), math.Max(out.MaxLat, c[1])
+	}
+	*b = out
+	return nil
+}
+
+// Where adds "lat BETWEEN … AND lng BETWEEN …" for tables storing
+// coordinates in plain columns. latCol and lngCol are developer-supplied.
+func (b BBox) Where(c *db.Conditions, latCol, lngCol string) {
+	c.Add(latCol+" BETWEEN ? AND ?", b.MinLat, b.MaxLat)
+	c.Add(lngCol+" BETWEEN ? AND ?", b.MinLng, b.MaxLng)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Decoding
+// ─────────────────────────────────────────────────────────────────────────────
+
+const (
+	wkbPoint   = 1
+	wkbPolygon = 3
+
+	ewkbSRIDFlag = 0x20000000
+	ewkbZFlag    = 0x80000000
+	ewkbMFlag    = 0x40000000
+)
+
+type geometry struct {
+	kind   uint32
+	srid   int
+	coords [][2]float64
+}
+
+func decode(src any) (geometry, error) {
+	switch v := src.(type) {
+	case string:
+		return decodeText(v)
+	case []byte:
+		if isHex(v) {
+			return decodeText(string(v))
+		}
+		if len(v) > 0 && (v[0] == 'P' || v[0] == 'S' || v[0] == 'p' || v[0] == 's') {
+			return decodeText(string(v))
+		}
+		return decodeWKB(v)
+	case nil:
+		return geometry{}, errors.New("geo: cannot scan NULL; use NullPoint")
+	}
+	return geometry{}, fmt.Errorf("geo: unsupported source type %T", src)
+}
+
+func decodeText(s string) (geometry, error) {
+	s = strings.TrimSpace(s)
+	if isHex([]byte(s)) {
+		raw, err := hex.DecodeString(s)
+		if err != nil {
+			return geometry{}, fmt.Errorf("geo: hex: %w", err)
+		}
+		return decodeWKB(raw)
+	}
+	return parseWKT(s)
+}
+
+func isHex(b []byte) bool {
+	if len(b) < 10 || len(b)%2 != 0 {
+		return false
+	}
+	for _, c := range b {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+func decodeWKB(b []byte) (geometry, error) {
+	r := wkbReader{buf: b}
+	order, err := r.byte()
+	if err != nil {
+		return geometry{}, err
+	}
+	switch order {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return geometry{}, fmt.Errorf("geo: invalid WKB byte order %d", order)
+	}
+	typ, err := r.uint32()
+	if err != nil {
+		return geometry{}, err
+	}
+	g := geometry{kind: typ &^ (ewkbSRIDFlag | ewkbZFlag | ewkbMFlag)}
+	dims := 2
+	if typ&ewkbZFlag != 0 {
+		dims++
+	}
+	if typ&ewkbMFlag != 0 {
+		dims++
+	}
+	// ISO WKB encodes Z/M as +1000/+2000/+3000.
+	switch {
+	case g.kind > 3000:
+		g.kind -= 3000
+		dims = 4
+	case g.kind > 2000:
+		g.kind -= 2000
+		dims = 3
+	case g.kind > 1000:
+		g.kind -= 1000
+		dims = 3
+	}
+	if typ&ewkbSRIDFlag != 0 {
+		s, err := r.uint32()
+		if err != nil {
+			return geometry{}, err
+		}
+		g.srid = int(s)
+	}
+
+	switch g.kind {
+	case wkbPoint:
+		c, err := r.coord(dims)
+		if err != nil {
+			return geometry{}, err
+		}
+		g.coords = [][2]float64{c}
+	case wkbPolygon:
+		rings, err := r.uint32()
+		if err != nil {
+			return geometry{}, err
+		}
+		for range rings {
+			n, err := r.uint32()
+			if err != nil {
+				return geometry{}, err
+			}
+			for range n {
+				c, err := r.coord(dims)
+				if err != nil {
+					return geometry{}, err
+				}
+				g.coords = append(g.coords, c)
+			}
+		}
+	default:
+		return geometry{}, fmt.Errorf("geo: unsupported WKB geometry type %d", g.kind)
+	}
+	return g, nil
+}
+
+type wkbReader struct {
+	buf   []byte
+	order binary.ByteOrder
+}
+
+func (r *wkbReader) take(n int) ([]byte, error) {
+	if len(r.buf) < n {
+		return nil, errors.New("geo: truncated WKB")
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b, nil
+}
+
+func (r *wkbReader) byte() (byte, error) {
+	b, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *wkbReader) uint32() (uint32, error) {
+	b, err := r.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return r.order.Uint32(b), nil
+}
+
+func (r *wkbReader) coord(dims int) ([2]float64, error) {
+	var c [2]float64
+	for i := range dims {
+		b, err := r.take(8)
+		if err != nil {
+			return c, err
+		}
+		if i < 2 {
+			c[i] = math.Float64frombits(r.order.Uint64(b))
+		}
+	}
+	return c, nil
+}
+
+// parseWKT parses POINT and POLYGON well-known text, with an optional
+// "SRID=n;" prefix. Z/M ordinates are ignored.
+func parseWKT(s string) (geometry, error) {
+	var g geometry
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(strings.ToUpper(s), "SRID=") {
+		semi := strings.IndexByte(s, ';')
+		if semi < 0 {
+			return g, fmt.Errorf("geo: malformed EWKT %q", s)
+		}
+		n, err := strconv.Atoi(s[5:semi])
+		if err != nil {
+			return g, fmt.Errorf("geo: malformed SRID in %q", s)
+		}
+		g.srid = n
+		s = s[semi+1:]
+	}
+	open := strings.IndexByte(s, '(')
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return g, fmt.Errorf("geo: malformed WKT %q", s)
+	}
+	head := strings.Fields(strings.ToUpper(s[:open]))
+	if len(head) == 0 {
+		return g, fmt.Errorf("geo: malformed WKT %q", s)
+	}
+	switch head[0] {
+	case "POINT":
+		g.kind = wkbPoint
+	case "POLYGON":
+		g.kind = wkbPolygon
+	default:
+		return g, fmt.Errorf("geo: unsupported WKT type %q", head[0])
+	}
+	body := strings.NewReplacer("(", " ", ")", " ").Replace(s[open:])
+	for _, pair := range strings.Split(body, ",") {
+		f := strings.Fields(pair)
+		if len(f) < 2 {
+			return g, fmt.Errorf("geo: malformed coordinate %q", pair)
+		}
+		x, err1 := strconv.ParseFloat(f[0], 64)
+		y, err2 := strconv.ParseFloat(f[1], 64)
+		if err1 != nil || err2 != nil {
+			return g, fmt.Errorf("geo: malformed coordinate %q", pair)
+		}
+		g.coords = append(g.coords, [2]float64{x, y})
+	}
+	if g.kind == wkbPoint && len(g.coords) != 1 {
+		return g, fmt.Errorf("geo: POINT must have one coordinate")
+	}
+	return g, nil
+}
+
+// WKB encodes p as little-endian EWKB with SRID, the form PostGIS emits.
+func (p Point) WKB() []byte {
+	b := make([]byte, 0, 25)
+	b = append(b, 1)
+	b = binary.LittleEndian.AppendUint32(b, wkbPoint|ewkbSRIDFlag)
+	b = binary.LittleEndian.AppendUint32(b, uint32(srid(p.SRID)))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p.Lng))
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(p.Lat))
+	return b
+}
+
+func srid(s int) int {
+	if s == 0 {
+		return DefaultSRID
+	}
+	return s
+}
+
+func fmtCoord(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
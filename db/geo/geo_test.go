@@ -0,0 +1,76 @@
+package geo_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/geo"
+)
+
+func TestPoint_ValueScanRoundTrip(t *testing.T) {
+	p := geo.NewPoint(35.6892, 51.389)
+
+	v, err := p.Value()
+	if err != nil || v != "SRID=4326;POINT(51.389 35.6892)" {
+		t.Fatalf("value: %v err=%v", v, err)
+	}
+
+	for name, src := range map[string]any{
+		"ewkt":       v,
+		"wkb":        p.WKB(),
+		"hex ewkb":   hex.EncodeToString(p.WKB()),
+		"hex []byte": []byte(hex.EncodeToString(p.WKB())),
+	} {
+		var got geo.Point
+		if err := got.Scan(src); err != nil {
+			t.Fatalf("%s: scan: %v", name, err)
+		}
+		if got != p {
+			t.Fatalf("%s: got %+v, want %+v", name, got, p)
+		}
+	}
+}
+
+func TestPoint_ScanPostGISHex(t *testing.T) {
+	// SELECT 'SRID=4326;POINT(1 2)'::geometry
+	var p geo.Point
+	if err := p.Scan("0101000020E6100000000000000000F03F0000000000000040"); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if p.Lng != 1 || p.Lat != 2 || p.SRID != 4326 {
+		t.Fatalf("unexpected point: %+v", p)
+	}
+}
+
+func TestNullPoint(t *testing.T) {
+	var n geo.NullPoint
+	if err := n.Scan(nil); err != nil || n.Valid {
+		t.Fatalf("expected invalid NullPoint, got %+v err=%v", n, err)
+	}
+	if v, _ := n.Value(); v != nil {
+		t.Fatalf("expected nil value, got %v", v)
+	}
+}
+
+func TestBBox(t *testing.T) {
+	b := geo.BBox{MinLng: 1, MinLat: 2, MaxLng: 3, MaxLat: 4}
+	v, _ := b.Value()
+
+	var got geo.BBox
+	if err := got.Scan(v); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if got.MinLng != 1 || got.MinLat != 2 || got.MaxLng != 3 || got.MaxLat != 4 {
+		t.Fatalf("unexpected bbox: %+v", got)
+	}
+	if !b.Contains(geo.NewPoint(3, 2)) || b.Contains(geo.NewPoint(5, 2)) {
+		t.Fatal("Contains mismatch")
+	}
+
+	c := db.NewConditions(nil)
+	b.Where(c, "lat", "lng")
+	if c.Where() != "WHERE lat BETWEEN $1 AND $2 AND lng BETWEEN $3 AND $4" {
+		t.Fatalf("unexpected where: %s", c.Where())
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"log/slog"
 	"time"
 )
@@ -57,6 +58,26 @@ func (c hookChain) After(ctx context.Context, query string, args []any, d time.D
 	}
 }
 
+// AfterExec is After for statements that return a sql.Result; the rows they
+// affected are available to hooks through RowsAffected.
+func (c hookChain) AfterExec(ctx context.Context, query string, args []any, d time.Duration, res sql.Result, err error) {
+	if len(c.hooks) > 0 && err == nil && res != nil {
+		if n, rerr := res.RowsAffected(); rerr == nil {
+			ctx = context.WithValue(ctx, rowsAffectedKey{}, n)
+		}
+	}
+	c.After(ctx, query, args, d, err)
+}
+
+type rowsAffectedKey struct{}
+
+// RowsAffected reports, from within Hook.AfterQuery, how many rows a
+// successful Exec affected. ok is false for queries and failed statements.
+func RowsAffected(ctx context.Context) (n int64, ok bool) {
+	n, ok = ctx.Value(rowsAffectedKey{}).(int64)
+	return n, ok
+}
+
 func safeBeforeQuery(h Hook, ctx context.Context, query string, args []any) {
 	defer func() {
 		if r := recover(); r != nil {
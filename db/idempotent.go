@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Idempotent inserts — retries that cannot insert twice
+// ─────────────────────────────────────────────────────────────────────────────
+
+// NewIdempotencyToken returns a random 128-bit token as 32 hex digits. Make
+// one per logical insert — before the first attempt, or on the client that
+// sends the request — never one per attempt.
+func NewIdempotencyToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // never fails; see crypto/rand.Read
+	return hex.EncodeToString(b)
+}
+
+// InsertOnce inserts one row into table, retrying per cfg, and never inserts
+// it twice. WithRetry around a plain INSERT can: when the connection drops
+// after the database committed but before the client heard back, the retry
+// inserts a second row. InsertOnce stores token in tokenCol, which must have
+// a unique index, and inserts with ON CONFLICT (tokenCol) DO NOTHING (INSERT
+// IGNORE on MySQL), so an attempt after a committed one changes nothing:
+//
+//	token := db.NewIdempotencyToken() // or the client's Idempotency-Key header
+//	created, err := db.InsertOnce(ctx, database, db.DefaultRetry(), "payments",
+//	    "idempotency_key", token, []string{"account_id", "amount"}, accountID, amount)
+//
+// cols and args are the other columns and their values. It reports whether
+// this attempt inserted the row; false means a row with token was already
+// there, from an earlier attempt that committed or an earlier call with the
+// same token. Select by token when the row's id is needed.
+//
+// Without cfg.RetryOn, connection failures are retried too, along with the
+// errors WithRetry retries, since a repeat can no longer duplicate the row.
+// Run it on a *DB: inside a transaction it is the transaction to retry.
+// On MySQL, INSERT IGNORE also turns some invalid values into warnings.
+func InsertOnce(ctx context.Context, q Querier, cfg RetryConfig, table, tokenCol, token string, cols []string, args ...any) (bool, error) {
+	query, err := InsertSQL(DialectFrom(q), table, append(cols[:len(cols):len(cols)], tokenCol), ConflictSkip, tokenCol)
+	if err != nil {
+		return false, err
+	}
+	args = append(args[:len(args):len(args)], token)
+	if cfg.RetryOn == nil {
+		cfg.RetryOn = func(err error) bool {
+			return errors.Is(err, ErrConnectionFailed) || retryableByDefault(err)
+		}
+	}
+	return Retry(ctx, cfg, func() (bool, error) {
+		res, err := q.Exec(ctx, query, args...)
+		if err != nil {
+			return false, err
+		}
+		n, err := res.RowsAffected()
+		return n > 0, err
+	})
+}
@@ -0,0 +1,222 @@
+// Package kv is a small key-value store on top of the application database,
+// for feature flags, cursors and other bits of configuration that do not
+// warrant their own table. Values are opaque bytes with an optional expiry.
+//
+// The table is created by migrations/000004_create_kv_store.up.sql on
+// PostgreSQL and SQLite; CreateTable creates it on MySQL, which rejects that
+// file's BYTEA column.
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+const table = "kv_store"
+
+// Store reads and writes the kv_store table.
+type Store struct {
+	d *db.DB
+
+	sqlGet, sqlSet, sqlInsert, sqlDelete, sqlCAS, sqlPurge, sqlSweep, sqlList string
+}
+
+// New returns a Store using d. Statements are generated for d's dialect.
+func New(d *db.DB) *Store {
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	cols := []string{"key_name", "value", "expires_at"}
+	set, err := db.InsertSQL(dialect, table, cols, db.ConflictUpdate, "key_name")
+	if err != nil {
+		panic(err) // static arguments; cannot fail
+	}
+	insert, err := db.InsertSQL(dialect, table, cols, db.ConflictSkip, "key_name")
+	if err != nil {
+		panic(err)
+	}
+	return &Store{
+		d:         d,
+		sqlGet:    fmt.Sprintf(`SELECT value FROM kv_store WHERE key_name = %s AND (expires_at = 0 OR expires_at > %s)`, ph(1), ph(2)),
+		sqlSet:    set,
+		sqlInsert: insert,
+		sqlDelete: fmt.Sprintf(`DELETE FROM kv_store WHERE key_name = %s`, ph(1)),
+		sqlCAS: fmt.Sprintf(`UPDATE kv_store SET value = %s, expires_at = %s
+			WHERE key_name = %s AND value = %s AND (expires_at = 0 OR expires_at > %s)`,
+			ph(1), ph(2), ph(3), ph(4), ph(5)),
+		sqlPurge: fmt.Sprintf(`DELETE FROM kv_store WHERE key_name = %s AND expires_at <> 0 AND expires_at <= %s`, ph(1), ph(2)),
+		sqlSweep: fmt.Sprintf(`DELETE FROM kv_store WHERE expires_at <> 0 AND expires_at <= %s`, ph(1)),
+		sqlList: fmt.Sprintf(`SELECT key_name, value FROM kv_store
+			WHERE key_name LIKE %s%s AND (expires_at = 0 OR expires_at > %s)
+			ORDER BY key_name`, ph(1), dialect.LikeEscape(), ph(2)),
+	}
+}
+
+// Schema returns the statements creating the kv_store table on dialect.
+// The column types differ: PostgreSQL stores values as BYTEA, MySQL as
+// LONGBLOB, and MySQL's CREATE INDEX has no IF NOT EXISTS, so its index is
+// declared with the table.
+func Schema(dialect db.Dialect) ([]string, error) {
+	switch dialect {
+	case db.DialectPostgres, db.DialectSQLite:
+		value := "BYTEA"
+		if dialect == db.DialectSQLite {
+			value = "BLOB"
+		}
+		return []string{
+			`CREATE TABLE IF NOT EXISTS kv_store (
+			    key_name   VARCHAR(255) PRIMARY KEY,
+			    value      ` + value + ` NOT NULL,
+			    expires_at BIGINT       NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_kv_store_expires_at ON kv_store(expires_at)`,
+		}, nil
+	case db.DialectMySQL:
+		return []string{
+			`CREATE TABLE IF NOT EXISTS kv_store (
+			    key_name   VARCHAR(255) PRIMARY KEY,
+			    value      LONGBLOB     NOT NULL,
+			    expires_at BIGINT       NOT NULL DEFAULT 0,
+			    INDEX idx_kv_store_expires_at (expires_at)
+			)`,
+		}, nil
+	}
+	return nil, fmt.Errorf("kv: no schema for dialect %q", dialect)
+}
+
+// CreateTable creates the kv_store table on d if it does not exist, for
+// databases not migrated with migrations/.
+func CreateTable(ctx context.Context, d *db.DB) error {
+	stmts, err := Schema(d.Dialect())
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := d.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("kv: create table: %w", err)
+		}
+	}
+	return nil
+}
+
+// Get returns the value stored under key, or db.ErrNotFound when it is
+// missing or expired.
+func (s *Store) Get(ctx context.Context, key string) ([]byte, error) {
+	var v []byte
+	if err := s.d.QueryRow(ctx, s.sqlGet, key, s.nowMicro()).Scan(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Set stores value under key, replacing any previous value. ttl <= 0 means
+// the entry never expires.
+func (s *Store) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := s.d.Exec(ctx, s.sqlSet, key, value, s.expiresAt(ttl))
+	return err
+}
+
+// Delete removes key. Deleting a missing key is not an error.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	_, err := s.d.Exec(ctx, s.sqlDelete, key)
+	return err
+}
+
+// CompareAndSwap replaces key's value with value only if it currently equals
+// old, and reports whether it did. A nil old means "only if absent" (expired
+// entries count as absent). ttl applies to the new value as in Set.
+func (s *Store) CompareAndSwap(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+	now := s.nowMicro()
+	if old == nil {
+		var n int64
+		err := s.d.ExecTx(ctx, func(tx *db.Tx) error {
+			if _, err := tx.Exec(ctx, s.sqlPurge, key, now); err != nil {
+				return err
+			}
+			var err error
+			n, err = tx.ExecAffected(ctx, s.sqlInsert, key, value, s.expiresAt(ttl))
+			return err
+		})
+		return n == 1, err
+	}
+	if !bytes.Equal(old, value) || s.d.Dialect() != db.DialectMySQL {
+		n, err := s.d.ExecAffected(ctx, s.sqlCAS, value, s.expiresAt(ttl), key, old, now)
+		return n == 1, err
+	}
+	// MySQL counts changed rows, not matched ones, so swapping a value for
+	// itself can report none; confirm the match under the UPDATE's row lock.
+	var swapped bool
+	err := s.d.ExecTx(ctx, func(tx *db.Tx) error {
+		n, err := tx.ExecAffected(ctx, s.sqlCAS, value, s.expiresAt(ttl), key, old, now)
+		if err != nil || n == 1 {
+			swapped = n == 1
+			return err
+		}
+		var v []byte
+		err = tx.QueryRow(ctx, s.sqlGet, key, now).Scan(&v)
+		if db.IsNotFound(err) {
+			return nil
+		}
+		swapped = err == nil && bytes.Equal(v, old)
+		return err
+	})
+	return swapped, err
+}
+
+// Entry is one key-value pair returned by List.
+type Entry struct {
+	Key   string
+	Value []byte
+}
+
+// List returns the live entries whose key starts with prefix, ordered by key.
+func (s *Store) List(ctx context.Context, prefix string) ([]Entry, error) {
+	return db.Select(ctx, s.d, func(r db.RowScanner) (Entry, error) {
+		var e Entry
+		err := r.Scan(&e.Key, &e.Value)
+		return e, err
+	}, s.sqlList, db.LikePrefix(prefix), s.nowMicro())
+}
+
+// Sweep deletes expired entries and returns how many were removed. Expired
+// entries are already invisible to reads; sweeping only reclaims space.
+func (s *Store) Sweep(ctx context.Context) (int64, error) {
+	return s.d.ExecAffected(ctx, s.sqlSweep, s.nowMicro())
+}
+
+// ── JSON helpers ─────────────────────────────────────────────────────────────
+
+// GetJSON decodes the value under key into a T.
+func GetJSON[T any](ctx context.Context, s *Store, key string) (T, error) {
+	var v T
+	raw, err := s.Get(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return v, fmt.Errorf("kv: decode %q: %w", key, err)
+	}
+	return v, nil
+}
+
+// SetJSON encodes v as JSON and stores it under key.
+func SetJSON[T any](ctx context.Context, s *Store, key string, v T, ttl time.Duration) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("kv: encode %q: %w", key, err)
+	}
+	return s.Set(ctx, key, raw, ttl)
+}
+
+func (s *Store) nowMicro() int64 { return s.d.Now().UnixMicro() }
+
+func (s *Store) expiresAt(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return s.d.Now().Add(ttl).UnixMicro()
+}
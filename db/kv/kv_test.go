@@ -0,0 +1,175 @@
+package kv_test
+
+import (
+	"context"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/kv"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestStore(t *testing.T) *kv.Store {
+	t.Helper()
+	return newClockedStore(t, nil)
+}
+
+func newClockedStore(t *testing.T, clock db.Clock) *kv.Store {
+	t.Helper()
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1, Clock: clock})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	schema, err := os.ReadFile("../../migrations/000004_create_kv_store.up.sql")
+	if err != nil {
+		t.Fatalf("read migration: %v", err)
+	}
+	if _, err := d.Exec(context.Background(), string(schema)); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return kv.New(d)
+}
+
+func TestStore_SetGetDelete(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if _, err := s.Get(ctx, "missing"); !db.IsNotFound(err) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Set(ctx, "a", []byte("1"), 0); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if err := s.Set(ctx, "a", []byte("2"), 0); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+	if v, err := s.Get(ctx, "a"); err != nil || string(v) != "2" {
+		t.Fatalf("get = %q, %v", v, err)
+	}
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := s.Get(ctx, "a"); !db.IsNotFound(err) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+
+	type cfg struct{ Limit int }
+	if err := kv.SetJSON(ctx, s, "cfg:x", cfg{Limit: 7}, 0); err != nil {
+		t.Fatalf("set json: %v", err)
+	}
+	if got, err := kv.GetJSON[cfg](ctx, s, "cfg:x"); err != nil || got.Limit != 7 {
+		t.Fatalf("get json = %+v, %v", got, err)
+	}
+	_ = s.Set(ctx, "cfg_y", []byte("{}"), 0) // '_' must not match as a wildcard
+	if entries, err := s.List(ctx, "cfg:"); err != nil || len(entries) != 1 || entries[0].Key != "cfg:x" {
+		t.Fatalf("list = %+v, %v", entries, err)
+	}
+}
+
+func TestStore_CompareAndSwap(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+
+	if ok, err := s.CompareAndSwap(ctx, "lock", nil, []byte("me"), 0); err != nil || !ok {
+		t.Fatalf("create-if-absent: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "lock", nil, []byte("you"), 0); ok {
+		t.Fatal("create-if-absent must fail when present")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "lock", []byte("you"), []byte("x"), 0); ok {
+		t.Fatal("swap must fail on mismatch")
+	}
+	if ok, err := s.CompareAndSwap(ctx, "lock", []byte("me"), []byte("me2"), 0); err != nil || !ok {
+		t.Fatalf("swap: ok=%v err=%v", ok, err)
+	}
+	if ok, err := s.CompareAndSwap(ctx, "lock", []byte("me2"), []byte("me2"), 0); err != nil || !ok {
+		t.Fatalf("swap for the same value: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestStore_TTL(t *testing.T) {
+	clock := db.NewFakeClock(time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC))
+	s := newClockedStore(t, clock)
+	ctx := context.Background()
+
+	if err := s.Set(ctx, "tmp", []byte("v"), time.Minute); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if _, err := s.Get(ctx, "tmp"); err != nil {
+		t.Fatalf("get before expiry: %v", err)
+	}
+	clock.Advance(2 * time.Minute)
+	if _, err := s.Get(ctx, "tmp"); !db.IsNotFound(err) {
+		t.Fatalf("expected expired entry to be invisible, got %v", err)
+	}
+	if ok, err := s.CompareAndSwap(ctx, "tmp", nil, []byte("new"), 0); err != nil || !ok {
+		t.Fatalf("expired entry should count as absent: ok=%v err=%v", ok, err)
+	}
+	_ = s.Set(ctx, "gone", []byte("v"), time.Second)
+	clock.Advance(time.Minute)
+	if n, err := s.Sweep(ctx); err != nil || n != 1 {
+		t.Fatalf("sweep = %d, %v", n, err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	// The PostgreSQL statements are the migration's, so the two stay in step.
+	raw, err := os.ReadFile("../../migrations/000004_create_kv_store.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments := regexp.MustCompile(`(?m)--.*$`)
+	var migration []string
+	for _, stmt := range strings.Split(comments.ReplaceAllString(string(raw), ""), ";") {
+		if stmt = strings.Join(strings.Fields(stmt), " "); stmt != "" {
+			migration = append(migration, stmt)
+		}
+	}
+	pg, err := kv.Schema(db.DialectPostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range pg {
+		pg[i] = strings.Join(strings.Fields(pg[i]), " ")
+	}
+	if strings.Join(pg, ";\n") != strings.Join(migration, ";\n") {
+		t.Fatalf("PostgreSQL schema differs from the migration:\n%s\nvs\n%s", strings.Join(pg, ";\n"), strings.Join(migration, ";\n"))
+	}
+
+	mysql, err := kv.Schema(db.DialectMySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range mysql {
+		if strings.Contains(stmt, "BYTEA") || strings.Contains(stmt, "CREATE INDEX") {
+			t.Fatalf("MySQL schema has PostgreSQL-only DDL: %s", stmt)
+		}
+	}
+	if _, err := kv.Schema(db.Dialect("oracle")); err == nil {
+		t.Fatal("expected an error for an unknown dialect")
+	}
+
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	for range 2 { // idempotent
+		if err := kv.CreateTable(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := kv.New(d)
+	if err := s.Set(ctx, "k", []byte{0, 1, 2}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get(ctx, "k"); err != nil || string(v) != "\x00\x01\x02" {
+		t.Fatalf("get = %q, %v", v, err)
+	}
+}
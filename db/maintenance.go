@@ -0,0 +1,203 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Workload labels — tell interactive statements from batch load
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Workload classifies the statements issued under a context.
+type Workload string
+
+const (
+	// WorkloadInteractive is the default: request-serving statements, never
+	// delayed by maintenance windows.
+	WorkloadInteractive Workload = ""
+	// WorkloadBatch marks bulk jobs (imports, backfills, reports).
+	WorkloadBatch Workload = "batch"
+	// WorkloadBackground marks housekeeping (sweepers, relays, refreshers).
+	WorkloadBackground Workload = "background"
+)
+
+type workloadKey struct{}
+
+// WithWorkload labels every statement executed through ctx with w:
+//
+//	ctx = db.WithWorkload(ctx, db.WorkloadBatch)
+//	err := db.BatchExec(conn, ctx, insertSQL, rows, argsFn)
+func WithWorkload(ctx context.Context, w Workload) context.Context {
+	return context.WithValue(ctx, workloadKey{}, w)
+}
+
+// WorkloadFrom returns the label installed by WithWorkload, or
+// WorkloadInteractive.
+func WorkloadFrom(ctx context.Context) Workload {
+	w, _ := ctx.Value(workloadKey{}).(Workload)
+	return w
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Maintenance windows — hold back batch load during VACUUM, ALTER, ...
+// ─────────────────────────────────────────────────────────────────────────────
+
+// MaintenanceWindow is a period during which batch and background statements
+// are paused or throttled so they do not compete with maintenance work.
+type MaintenanceWindow struct {
+	Name       string
+	Start, End time.Time
+	// MaxRate caps batch and background statements, process-wide, to this
+	// many per second during the window. Zero pauses them until End.
+	MaxRate float64
+}
+
+type maintenanceSchedule struct {
+	mu      sync.Mutex
+	windows map[int]MaintenanceWindow
+	nextID  int
+	slot    time.Time     // earliest start of the next throttled statement
+	changed chan struct{} // closed and replaced whenever windows change
+}
+
+var maintenance = maintenanceSchedule{windows: map[int]MaintenanceWindow{}, changed: make(chan struct{})}
+
+// ScheduleMaintenance declares w for every DB in the process and returns a
+// function that withdraws it, e.g. once the maintenance finished early:
+//
+//	done := db.ScheduleMaintenance(db.MaintenanceWindow{
+//	    Name: "vacuum orders", Start: now, End: now.Add(30 * time.Minute),
+//	})
+//	defer done()
+//	_, err := conn.Exec(ctx, `VACUUM (ANALYZE) orders`)
+//
+// Statements labelled WorkloadBatch or WorkloadBackground (see WithWorkload)
+// wait in preflight while a window is open; interactive statements are never
+// delayed. Overlapping windows apply the strictest limit. A statement whose
+// context (or Config.DefaultTimeout) expires before it may run fails with
+// ErrTimeout — at once when its deadline falls before that point. Waiting
+// statements inside a transaction keep their locks, so batch jobs should
+// label short transactions only.
+func ScheduleMaintenance(w MaintenanceWindow) (cancel func()) {
+	m := &maintenance
+	m.mu.Lock()
+	id := m.nextID
+	m.nextID++
+	m.windows[id] = w
+	m.notifyLocked()
+	m.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			delete(m.windows, id)
+			m.notifyLocked()
+			m.mu.Unlock()
+		})
+	}
+}
+
+// ActiveMaintenance returns the strictest window open now, if any.
+func ActiveMaintenance() (MaintenanceWindow, bool) {
+	maintenance.mu.Lock()
+	defer maintenance.mu.Unlock()
+	return maintenance.activeLocked(time.Now())
+}
+
+// notifyLocked wakes every statement waiting on the current windows.
+func (m *maintenanceSchedule) notifyLocked() {
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// activeLocked picks the window that restricts statements most at now:
+// a pause over any rate, then the lowest rate. Expired windows are dropped.
+func (m *maintenanceSchedule) activeLocked(now time.Time) (MaintenanceWindow, bool) {
+	var (
+		best  MaintenanceWindow
+		found bool
+	)
+	for id, w := range m.windows {
+		if !now.Before(w.End) {
+			delete(m.windows, id)
+			continue
+		}
+		if now.Before(w.Start) {
+			continue
+		}
+		if !found || stricter(w, best) {
+			best, found = w, true
+		}
+	}
+	return best, found
+}
+
+func stricter(a, b MaintenanceWindow) bool {
+	switch {
+	case a.MaxRate <= 0:
+		return b.MaxRate > 0 || a.End.After(b.End)
+	case b.MaxRate <= 0:
+		return false
+	}
+	return a.MaxRate < b.MaxRate
+}
+
+// waitMaintenance is the preflight guard for maintenance windows.
+func waitMaintenance(ctx context.Context) error {
+	if WorkloadFrom(ctx) == WorkloadInteractive {
+		return nil
+	}
+	m := &maintenance
+	for {
+		now := time.Now()
+		m.mu.Lock()
+		w, ok := m.activeLocked(now)
+		if !ok {
+			m.mu.Unlock()
+			return nil
+		}
+		until, reserved := w.End, false
+		if w.MaxRate > 0 {
+			slot := m.slot
+			if slot.Before(now) {
+				slot = now
+			}
+			m.slot = slot.Add(time.Duration(float64(time.Second) / w.MaxRate))
+			if !slot.After(now) {
+				m.mu.Unlock()
+				return nil
+			}
+			if slot.Before(w.End) {
+				until, reserved = slot, true
+			}
+		}
+		changed := m.changed
+		m.mu.Unlock()
+
+		if dl, ok := ctx.Deadline(); ok && dl.Before(until) {
+			return &DBError{
+				Sentinel: ErrTimeout,
+				Cause:    fmt.Errorf("deadline falls inside maintenance window %q", w.Name),
+			}
+		}
+		t := time.NewTimer(until.Sub(now))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return &DBError{
+				Sentinel: ErrTimeout,
+				Cause:    fmt.Errorf("waiting for maintenance window %q: %w", w.Name, ctx.Err()),
+			}
+		case <-changed:
+			t.Stop()
+		case <-t.C:
+			if reserved {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Package mask rewrites sensitive column values on read so production
+// snapshots can be used safely in staging.
+//
+// database/sql hands rows straight to the caller, so masking is applied where
+// the column → field mapping is known: in repository decorators (see
+// repo.NewMaskedUserRepo) or in hand-written scan functions via Rules.
+//
+//	rules := mask.Rules{"email": mask.Email, "ssn": mask.Null}
+//	users := repo.NewUserRepo(d)
+//	if mask.EnabledFromEnv() {
+//	    users = repo.NewMaskedUserRepo(users, rules)
+//	}
+package mask
+
+import (
+	"database/sql"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EnvVar switches masked mode on when set to a true value ("1", "true", …).
+const EnvVar = "SQLTOOLKIT_MASKED"
+
+// EnabledFromEnv reports whether masked mode is enabled via EnvVar.
+func EnabledFromEnv() bool {
+	on, _ := strconv.ParseBool(os.Getenv(EnvVar))
+	return on
+}
+
+// Func masks a single column value.
+type Func func(string) string
+
+// Rules maps column names to their masking function. Columns without a rule
+// are returned unchanged.
+type Rules map[string]Func
+
+// String masks v as column.
+func (r Rules) String(column, v string) string {
+	if f, ok := r[column]; ok && f != nil {
+		return f(v)
+	}
+	return v
+}
+
+// NullString masks v as column. A rule yielding "" (such as Null) turns the
+// value into SQL NULL.
+func (r Rules) NullString(column string, v sql.NullString) sql.NullString {
+	f, ok := r[column]
+	if !ok || f == nil || !v.Valid {
+		return v
+	}
+	out := f(v.String)
+	if out == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: out, Valid: true}
+}
+
+// Has reports whether column has a masking rule.
+func (r Rules) Has(column string) bool {
+	_, ok := r[column]
+	return ok
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Built-in masks
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Email keeps the first character of the local part and the domain:
+// "alice@example.com" → "a****@example.com".
+func Email(v string) string {
+	local, domain, ok := strings.Cut(v, "@")
+	if !ok || local == "" {
+		return Redact(v)
+	}
+	r := []rune(local)
+	return string(r[0]) + strings.Repeat("*", max(len(r)-1, 3)) + "@" + domain
+}
+
+// Redact replaces every character with '*', preserving length.
+func Redact(v string) string { return strings.Repeat("*", len([]rune(v))) }
+
+// Null blanks the value; with Rules.NullString it becomes SQL NULL.
+func Null(string) string { return "" }
+
+// Fixed returns a Func replacing every value with s.
+func Fixed(s string) Func { return func(string) string { return s } }
+
+// KeepLast returns a Func that redacts all but the last n characters,
+// e.g. KeepLast(4) on a card number.
+func KeepLast(n int) Func {
+	return func(v string) string {
+		r := []rune(v)
+		if len(r) <= n {
+			return Redact(v)
+		}
+		return strings.Repeat("*", len(r)-n) + string(r[len(r)-n:])
+	}
+}
@@ -0,0 +1,40 @@
+package mask_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db/mask"
+)
+
+func TestBuiltins(t *testing.T) {
+	cases := []struct {
+		name string
+		f    mask.Func
+		in   string
+		want string
+	}{
+		{"email", mask.Email, "alice@example.com", "a****@example.com"},
+		{"short email", mask.Email, "al@x.io", "a***@x.io"},
+		{"not an email", mask.Email, "nope", "****"},
+		{"keep last", mask.KeepLast(4), "4111111111111111", "************1111"},
+		{"fixed", mask.Fixed("[hidden]"), "secret", "[hidden]"},
+	}
+	for _, c := range cases {
+		if got := c.f(c.in); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	rules := mask.Rules{"email": mask.Email, "ssn": mask.Null}
+
+	if got := rules.String("name", "Alice"); got != "Alice" {
+		t.Fatalf("unmasked column changed: %q", got)
+	}
+	ssn := rules.NullString("ssn", sql.NullString{String: "123-45-6789", Valid: true})
+	if ssn.Valid {
+		t.Fatalf("expected ssn to be nulled, got %+v", ssn)
+	}
+}
@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Table-change triggers (PostgreSQL)
+// ─────────────────────────────────────────────────────────────────────────────
+
+// DefaultChangeChannel is the channel InstallChangeTrigger notifies on when
+// none is given.
+const DefaultChangeChannel = "table_changes"
+
+// changeFunctionSQL creates the trigger function shared by every table. Its
+// arguments are the channel and the key column; the payload is a Change
+// encoded as JSON. An UPDATE that changes the key also notifies the old key.
+const changeFunctionSQL = `CREATE OR REPLACE FUNCTION sqltoolkit_notify_change() RETURNS trigger AS $$
+DECLARE
+	old_key text;
+	new_key text;
+BEGIN
+	IF TG_OP <> 'INSERT' THEN
+		old_key := to_jsonb(OLD) ->> TG_ARGV[1];
+		PERFORM pg_notify(TG_ARGV[0], json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'key', old_key)::text);
+	END IF;
+	IF TG_OP <> 'DELETE' THEN
+		new_key := to_jsonb(NEW) ->> TG_ARGV[1];
+		IF old_key IS DISTINCT FROM new_key THEN
+			PERFORM pg_notify(TG_ARGV[0], json_build_object('table', TG_TABLE_NAME, 'op', TG_OP, 'key', new_key)::text);
+		END IF;
+	END IF;
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql`
+
+// InstallChangeTrigger creates (or replaces) a row-level trigger on table
+// that publishes a Change on channel after every INSERT, UPDATE and DELETE,
+// identifying the row by keyColumn. It is idempotent, so it can run at
+// start-up; pass a *db.Tx to install atomically. PostgreSQL 11 or later only.
+//
+//	err := notify.InstallChangeTrigger(ctx, conn, "users", "id", "")
+func InstallChangeTrigger(ctx context.Context, q db.Querier, table, keyColumn, channel string) error {
+	if d := db.DialectFrom(q); d != db.DialectPostgres {
+		return fmt.Errorf("notify: change triggers require PostgreSQL, not %q", d)
+	}
+	if channel == "" {
+		channel = DefaultChangeChannel
+	}
+	name := table[strings.LastIndexByte(table, '.')+1:]
+	trigger := db.DialectPostgres.QuoteIdent(name + "_notify_change")
+	tbl := db.DialectPostgres.QuoteIdent(table)
+	stmts := []string{
+		changeFunctionSQL,
+		`DROP TRIGGER IF EXISTS ` + trigger + ` ON ` + tbl,
+		`CREATE TRIGGER ` + trigger + ` AFTER INSERT OR UPDATE OR DELETE ON ` + tbl +
+			` FOR EACH ROW EXECUTE FUNCTION sqltoolkit_notify_change(` + quoteLiteral(channel) + `, ` + quoteLiteral(keyColumn) + `)`,
+	}
+	for _, s := range stmts {
+		if _, err := q.Exec(ctx, s); err != nil {
+			return fmt.Errorf("notify: install trigger on %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func quoteLiteral(s string) string {
+	out := make([]byte, 0, len(s)+2)
+	out = append(out, '\'')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\'' {
+			out = append(out, '\'')
+		}
+		out = append(out, s[i])
+	}
+	return string(append(out, '\''))
+}
+
+// Change is the payload published by the change trigger.
+type Change struct {
+	Table string `json:"table"`
+	// Op is INSERT, UPDATE or DELETE.
+	Op string `json:"op"`
+	// Key is the row's key column as text.
+	Key string `json:"key"`
+}
+
+// ParseChange decodes a change trigger payload.
+func ParseChange(payload string) (Change, error) {
+	var c Change
+	if err := json.Unmarshal([]byte(payload), &c); err != nil {
+		return Change{}, fmt.Errorf("notify: malformed change payload %q: %w", payload, err)
+	}
+	return c, nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Invalidator — cache invalidation from change notifications
+// ─────────────────────────────────────────────────────────────────────────────
+
+// KeyDeleter is the part of a cache the Invalidator needs; repo.Cache
+// satisfies it.
+type KeyDeleter interface {
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// InvalidatorOptions configures NewInvalidator. Every field is optional.
+type InvalidatorOptions struct {
+	// Channel defaults to DefaultChangeChannel.
+	Channel string
+	// OnReset is called when notifications may have been missed (the
+	// subscriber reconnected). Flush the cache there if entries outliving
+	// their TTL matters; without it, stale entries expire on their own.
+	OnReset func(ctx context.Context)
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Invalidator deletes cache entries when the rows behind them change,
+// completing the read-through cache: the caching decorators invalidate on
+// their own writes, the Invalidator on everyone else's.
+//
+//	inv := notify.NewInvalidator(sub, cache, notify.InvalidatorOptions{})
+//	inv.Table("users", repo.UserCacheKeys)
+//	go inv.Run(ctx)
+type Invalidator struct {
+	sub   Subscriber
+	cache KeyDeleter
+	opts  InvalidatorOptions
+
+	mu     sync.RWMutex
+	tables map[string]func(key string) []string
+}
+
+// NewInvalidator returns an Invalidator deleting from cache. Register tables
+// with Table, then start Run.
+func NewInvalidator(sub Subscriber, cache KeyDeleter, opts InvalidatorOptions) *Invalidator {
+	if opts.Channel == "" {
+		opts.Channel = DefaultChangeChannel
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Invalidator{sub: sub, cache: cache, opts: opts, tables: map[string]func(string) []string{}}
+}
+
+// Table maps changes to rows of table to the cache keys holding them.
+// Changes to unregistered tables are ignored.
+func (inv *Invalidator) Table(table string, keys func(key string) []string) {
+	inv.mu.Lock()
+	inv.tables[table] = keys
+	inv.mu.Unlock()
+}
+
+// Run invalidates until ctx is cancelled. Malformed payloads and cache
+// errors are logged, not returned.
+func (inv *Invalidator) Run(ctx context.Context) error {
+	ch, err := inv.sub.Listen(ctx, inv.opts.Channel)
+	if err != nil {
+		return err
+	}
+	for payload := range ch {
+		inv.handle(ctx, payload)
+	}
+	return ctx.Err()
+}
+
+func (inv *Invalidator) handle(ctx context.Context, payload string) {
+	if payload == "" {
+		if inv.opts.OnReset != nil {
+			inv.opts.OnReset(ctx)
+		}
+		return
+	}
+	c, err := ParseChange(payload)
+	if err != nil {
+		inv.opts.Logger.Warn("notify: ignoring change", "error", err)
+		return
+	}
+	inv.mu.RLock()
+	keysFn := inv.tables[c.Table]
+	inv.mu.RUnlock()
+	if keysFn == nil || c.Key == "" {
+		return
+	}
+	if keys := keysFn(c.Key); len(keys) > 0 {
+		if err := inv.cache.Delete(ctx, keys...); err != nil {
+			inv.opts.Logger.Warn("notify: cache invalidation failed", "table", c.Table, "key", c.Key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Package notify carries lightweight change notifications between processes
+// sharing a database, so in-memory caches can be refreshed as soon as the
+// underlying rows change instead of on the next poll.
+//
+// PostgreSQL LISTEN/NOTIFY is supported through lib/pq (NewPQSubscriber,
+// NewPGPublisher). Hub is an in-process implementation for tests, single
+// instance deployments and databases without a notification mechanism.
+//
+// Notifications are hints, not a queue: they can be lost across reconnects,
+// so subscribers should reload their full state when they receive the empty
+// payload that signals a reconnect, and keep a slow poll as a safety net.
+package notify
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Publisher sends payload to every current subscriber of channel.
+type Publisher interface {
+	Notify(ctx context.Context, channel, payload string) error
+}
+
+// Subscriber delivers notifications for channel until ctx is cancelled, at
+// which point the returned channel is closed. An empty payload means
+// notifications may have been missed (e.g. after a reconnect).
+type Subscriber interface {
+	Listen(ctx context.Context, channel string) (<-chan string, error)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// PostgreSQL publisher
+// ─────────────────────────────────────────────────────────────────────────────
+
+// PGPublisher publishes with pg_notify through a Querier. Pass a *db.Tx to
+// have the notification delivered only if the transaction commits.
+type PGPublisher struct{ q db.Querier }
+
+// NewPGPublisher returns a Publisher issuing pg_notify on q.
+func NewPGPublisher(q db.Querier) *PGPublisher { return &PGPublisher{q: q} }
+
+// Notify implements Publisher. PostgreSQL limits payloads to 8000 bytes.
+func (p *PGPublisher) Notify(ctx context.Context, channel, payload string) error {
+	_, err := p.q.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, payload)
+	return err
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Hub — in-process implementation
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Hub is an in-memory Publisher and Subscriber. Slow subscribers lose
+// notifications rather than blocking publishers.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan string]struct{}
+}
+
+// NewHub returns an empty Hub.
+func NewHub() *Hub { return &Hub{subs: make(map[string]map[chan string]struct{})} }
+
+// Notify implements Publisher.
+func (h *Hub) Notify(_ context.Context, channel, payload string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[channel] {
+		select {
+		case ch <- payload:
+		default:
+		}
+	}
+	return nil
+}
+
+// Listen implements Subscriber.
+func (h *Hub) Listen(ctx context.Context, channel string) (<-chan string, error) {
+	ch := make(chan string, 16)
+	h.mu.Lock()
+	if h.subs[channel] == nil {
+		h.subs[channel] = make(map[chan string]struct{})
+	}
+	h.subs[channel][ch] = struct{}{}
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs[channel], ch)
+		close(ch)
+		h.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+var (
+	_ Publisher  = (*Hub)(nil)
+	_ Subscriber = (*Hub)(nil)
+	_ Publisher  = (*PGPublisher)(nil)
+)
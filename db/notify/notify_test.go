@@ -0,0 +1,99 @@
+package notify_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db/notify"
+	"github.com/Skryldev/sql-toolkit/repo"
+)
+
+func TestHub(t *testing.T) {
+	h := notify.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	a, _ := h.Listen(ctx, "flags")
+	b, _ := h.Listen(context.Background(), "other")
+	if err := h.Notify(ctx, "flags", "beta"); err != nil {
+		t.Fatalf("notify: %v", err)
+	}
+
+	select {
+	case p := <-a:
+		if p != "beta" {
+			t.Fatalf("payload = %q", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("notification not delivered")
+	}
+	select {
+	case p := <-b:
+		t.Fatalf("unrelated channel received %q", p)
+	default:
+	}
+
+	cancel()
+	select {
+	case _, ok := <-a:
+		if ok {
+			t.Fatal("expected channel closed after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+}
+
+func TestInvalidator(t *testing.T) {
+	h := notify.NewHub()
+	cache := repo.NewMemoryCache()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, k := range []string{"user:id:1", "user:id:2"} {
+		_ = cache.Set(ctx, k, []byte("{}"), 0)
+	}
+
+	resets := make(chan struct{}, 1)
+	inv := notify.NewInvalidator(h, cache, notify.InvalidatorOptions{
+		OnReset: func(context.Context) { resets <- struct{}{} },
+	})
+	inv.Table("users", repo.UserCacheKeys)
+	done := make(chan error)
+	go func() { done <- inv.Run(ctx) }()
+
+	// Wait for the listener before publishing; the Hub has no backlog.
+	deadline := time.Now().Add(time.Second)
+	for cache.Len() == 2 && time.Now().Before(deadline) {
+		_ = h.Notify(ctx, notify.DefaultChangeChannel, `{"table":"users","op":"UPDATE","key":"1"}`)
+		time.Sleep(5 * time.Millisecond)
+	}
+	_ = h.Notify(ctx, notify.DefaultChangeChannel, `{"table":"orders","op":"DELETE","key":"2"}`)
+	_ = h.Notify(ctx, notify.DefaultChangeChannel, "")
+
+	select {
+	case <-resets:
+	case <-time.After(time.Second):
+		t.Fatal("reset not signalled")
+	}
+	if _, ok, _ := cache.Get(ctx, "user:id:1"); ok {
+		t.Fatal("user 1 still cached")
+	}
+	if _, ok, _ := cache.Get(ctx, "user:id:2"); !ok {
+		t.Fatal("change to another table invalidated user 2")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestParseChange(t *testing.T) {
+	c, err := notify.ParseChange(`{"table":"users","op":"DELETE","key":"7"}`)
+	if err != nil || c != (notify.Change{Table: "users", Op: "DELETE", Key: "7"}) {
+		t.Fatalf("ParseChange = %+v, %v", c, err)
+	}
+	if _, err := notify.ParseChange("users:7"); err == nil {
+		t.Fatal("expected error for malformed payload")
+	}
+}
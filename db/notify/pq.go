@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PQSubscriber implements Subscriber with a single lib/pq LISTEN connection
+// shared by all channels. It reconnects automatically; after a reconnect every
+// listener receives an empty payload.
+type PQSubscriber struct {
+	l *pq.Listener
+
+	mu   sync.Mutex
+	subs map[string]map[chan string]struct{}
+	once sync.Once
+}
+
+// NewPQSubscriber opens a dedicated LISTEN connection to dsn (a lib/pq DSN,
+// not a pooled *db.DB: LISTEN state is per connection). Close releases it.
+func NewPQSubscriber(dsn string) *PQSubscriber {
+	s := &PQSubscriber{subs: make(map[string]map[chan string]struct{})}
+	s.l = pq.NewListener(dsn, 100*time.Millisecond, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("sqltoolkit/notify: listener event", slog.Int("event", int(ev)), slog.Any("error", err))
+		}
+	})
+	return s
+}
+
+// Listen implements Subscriber.
+func (s *PQSubscriber) Listen(ctx context.Context, channel string) (<-chan string, error) {
+	s.once.Do(func() { go s.dispatch() })
+
+	ch := make(chan string, 16)
+	s.mu.Lock()
+	first := len(s.subs[channel]) == 0
+	if first {
+		s.subs[channel] = make(map[chan string]struct{})
+	}
+	s.subs[channel][ch] = struct{}{}
+	s.mu.Unlock()
+
+	if first {
+		if err := s.l.Listen(channel); err != nil && err != pq.ErrChannelAlreadyOpen {
+			s.remove(channel, ch)
+			return nil, err
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		s.remove(channel, ch)
+	}()
+	return ch, nil
+}
+
+func (s *PQSubscriber) remove(channel string, ch chan string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subs[channel][ch]; !ok {
+		return
+	}
+	delete(s.subs[channel], ch)
+	close(ch)
+	if len(s.subs[channel]) == 0 {
+		delete(s.subs, channel)
+		_ = s.l.Unlisten(channel)
+	}
+}
+
+func (s *PQSubscriber) dispatch() {
+	for n := range s.l.NotificationChannel() {
+		s.mu.Lock()
+		for channel, subs := range s.subs {
+			// A nil notification follows a reconnect: every channel may have
+			// missed messages.
+			if n != nil && n.Channel != channel {
+				continue
+			}
+			payload := ""
+			if n != nil {
+				payload = n.Extra
+			}
+			for ch := range subs {
+				select {
+				case ch <- payload:
+				default:
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// Close stops listening on every channel and closes the connection.
+func (s *PQSubscriber) Close() error {
+	s.mu.Lock()
+	for channel, subs := range s.subs {
+		for ch := range subs {
+			close(ch)
+		}
+		delete(s.subs, channel)
+	}
+	s.mu.Unlock()
+	return s.l.Close()
+}
+
+var _ Subscriber = (*PQSubscriber)(nil)
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// N+1 detector — development hook
+// ─────────────────────────────────────────────────────────────────────────────
+
+// NPlusOneConfig configures NewNPlusOneDetector.
+type NPlusOneConfig struct {
+	// Threshold is the number of executions of one fingerprint with distinct
+	// args that triggers a finding. Defaults to 10.
+	Threshold int
+	// Logger receives one consolidated warning per finding. Defaults to
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// NPlusOneFinding describes a query shape repeated within one scope.
+type NPlusOneFinding struct {
+	Fingerprint string
+	Count       int
+	// DistinctArgs is capped at 2×Threshold.
+	DistinctArgs int
+	Total        time.Duration
+	// Callers maps call sites to their execution counts.
+	Callers map[string]int
+}
+
+// NPlusOneDetector is a Hook that spots loops issuing the same statement with
+// different arguments — the N+1 pattern repositories make easy to hide. It
+// only observes contexts returned by Scope, so it costs nothing elsewhere;
+// enable it in development and CI rather than production.
+//
+//	det := db.NewNPlusOneDetector(db.NPlusOneConfig{})
+//	conn := db.MustOpen(db.Config{..., Hooks: []db.Hook{det}})
+//
+//	func middleware(next http.Handler) http.Handler {
+//	    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+//	        ctx, done := det.Scope(r.Context())
+//	        defer done()
+//	        next.ServeHTTP(w, r.WithContext(ctx))
+//	    })
+//	}
+type NPlusOneDetector struct {
+	threshold int
+	logger    *slog.Logger
+}
+
+// NewNPlusOneDetector returns a detector; add it to Config.Hooks.
+func NewNPlusOneDetector(cfg NPlusOneConfig) *NPlusOneDetector {
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = 10
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &NPlusOneDetector{threshold: cfg.Threshold, logger: cfg.Logger}
+}
+
+type nplusoneKey struct{}
+
+type nplusoneScope struct {
+	det *NPlusOneDetector
+	mu  sync.Mutex
+	fps map[string]*nplusoneEntry
+}
+
+type nplusoneEntry struct {
+	count   int
+	args    map[uint64]struct{}
+	total   time.Duration
+	callers map[string]int
+}
+
+// Scope starts a detection scope (typically one request or job). done logs a
+// warning for every fingerprint that crossed the threshold and returns the
+// findings; it must be called once the scope's work has finished.
+func (d *NPlusOneDetector) Scope(ctx context.Context) (context.Context, func() []NPlusOneFinding) {
+	s := &nplusoneScope{det: d, fps: make(map[string]*nplusoneEntry)}
+	return context.WithValue(ctx, nplusoneKey{}, s), func() []NPlusOneFinding { return s.report(ctx) }
+}
+
+func (d *NPlusOneDetector) BeforeQuery(_ context.Context, _ string, _ []any) {}
+
+func (d *NPlusOneDetector) AfterQuery(ctx context.Context, query string, args []any, dur time.Duration, _ error) {
+	s, _ := ctx.Value(nplusoneKey{}).(*nplusoneScope)
+	if s == nil || s.det != d {
+		return
+	}
+	fp := Fingerprint(query)
+	caller := externalCaller()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.fps[fp]
+	if e == nil {
+		e = &nplusoneEntry{args: make(map[uint64]struct{}), callers: make(map[string]int)}
+		s.fps[fp] = e
+	}
+	e.count++
+	e.total += dur
+	e.callers[caller]++
+	if len(e.args) < 2*d.threshold {
+		e.args[hashArgs(args)] = struct{}{}
+	}
+}
+
+func (s *nplusoneScope) report(ctx context.Context) []NPlusOneFinding {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []NPlusOneFinding
+	for fp, e := range s.fps {
+		if len(e.args) < s.det.threshold {
+			continue
+		}
+		out = append(out, NPlusOneFinding{
+			Fingerprint:  fp,
+			Count:        e.count,
+			DistinctArgs: len(e.args),
+			Total:        e.total,
+			Callers:      e.callers,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	for _, f := range out {
+		s.det.logger.WarnContext(ctx, "sqltoolkit/db: possible N+1 query",
+			slog.String("query", f.Fingerprint),
+			slog.Int("count", f.Count),
+			slog.Int("distinct_args", f.DistinctArgs),
+			slog.Duration("total", f.Total),
+			slog.Any("callers", f.Callers),
+		)
+	}
+	return out
+}
+
+func hashArgs(args []any) uint64 {
+	h := fnv.New64a()
+	for _, a := range args {
+		fmt.Fprintf(h, "%T:%v\x00", a, a)
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// PATCH — partial UPDATE from caller-supplied fields
+// ─────────────────────────────────────────────────────────────────────────────
+
+// PatchSet turns fields — typically a decoded JSON body of an HTTP PATCH
+// request — into the assignments of an UPDATE's SET clause. allowed maps
+// each accepted field name to its column, exactly like SortClause; a field
+// not in allowed fails the whole patch with ErrInvalidFilter, so clients
+// cannot write id, created_at or any column the repository did not opt in.
+//
+// Assignments are ordered by column and numbered from $1, with the values
+// bound as args; a JSON null sets the column to NULL. Further placeholders
+// (updated_at, the key) continue at len(args)+1, and the statement can be run
+// with UpdateReturning, which rebinds $N for MySQL:
+//
+//	set, args, err := db.PatchSet(db.DialectPostgres, userPatchColumns, body)
+//	// set:  `"email" = $1, "name" = $2`
+//	query := fmt.Sprintf(`UPDATE users SET %s WHERE id = $%d`, set, len(args)+1)
+//
+// set is empty when fields is.
+func PatchSet(d Dialect, allowed map[string]string, fields map[string]any) (set string, args []any, err error) {
+	cols := make([]string, 0, len(fields))
+	values := make(map[string]any, len(fields))
+	for field, v := range fields {
+		col, ok := allowed[field]
+		if !ok {
+			return "", nil, fmt.Errorf("%w: patch field %q not allowed", ErrInvalidFilter, field)
+		}
+		if _, dup := values[col]; dup {
+			return "", nil, fmt.Errorf("%w: patch field %q sets column %q twice", ErrInvalidFilter, field, col)
+		}
+		cols = append(cols, col)
+		values[col] = v
+	}
+	sort.Strings(cols)
+
+	ph := DollarPlaceholder
+	args = make([]any, len(cols))
+	for i, col := range cols {
+		if i > 0 {
+			set += ", "
+		}
+		set += d.QuoteIdent(col) + " = " + ph(i+1)
+		args[i] = values[col]
+	}
+	return set, args, nil
+}
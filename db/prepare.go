@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Statements prepared at Open
+// ─────────────────────────────────────────────────────────────────────────────
+
+// prepareOnOpen prepares Config.PrepareOnOpen and, with
+// Config.PrepareRegistered, every statement passed to RegisterQueries. All
+// statements are tried; the error joins one entry per failure, named by its
+// registry key or trimmed SQL. On success the statements are cached for
+// Exec, Query and QueryRow.
+func (d *DB) prepareOnOpen(ctx context.Context) error {
+	queries := map[string]string{}
+	for _, q := range d.cfg.PrepareOnOpen {
+		queries[trimQuery(q)] = q
+	}
+	if d.cfg.PrepareRegistered {
+		for name, q := range RegisteredQueries() {
+			queries[name] = q
+		}
+	}
+	if len(queries) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	stmts := make(map[string]*sql.Stmt, len(queries))
+	var errs []error
+	for _, name := range names {
+		query := queries[name]
+		if _, done := stmts[query]; done {
+			continue
+		}
+		s, err := d.sqldb.PrepareContext(ctx, query)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, d.mapErr(err)))
+			continue
+		}
+		stmts[query] = s
+	}
+	if len(errs) > 0 {
+		for _, s := range stmts {
+			_ = s.Close()
+		}
+		return errors.Join(errs...)
+	}
+	d.stmts = stmts
+	return nil
+}
+
+// preparedFor returns the statement prepared at Open for query, or nil. The
+// map is written once before Open returns, so reads need no lock.
+func (d *DB) preparedFor(query string) *sql.Stmt { return d.stmts[query] }
+
+// closePrepared releases the statements prepared at Open.
+func (d *DB) closePrepared() {
+	for _, s := range d.stmts {
+		_ = s.Close()
+	}
+}
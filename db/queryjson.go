@@ -0,0 +1,140 @@
+package db
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// QueryJSON — results streamed as JSON
+// ─────────────────────────────────────────────────────────────────────────────
+
+// JSONOptions controls QueryJSONOpts.
+type JSONOptions struct {
+	// Lines writes one object per line (JSON Lines) instead of one array.
+	Lines bool
+	// TimeLayout formats time values. Defaults to time.RFC3339Nano.
+	TimeLayout string
+	// Binary encodes binary values: "base64" (default) or "hex". Text that
+	// a driver returns as bytes is written as a string either way.
+	Binary string
+}
+
+// QueryJSON runs query and writes its rows to w as a JSON array of objects,
+// one key per column in column order, and returns how many rows it wrote.
+// Rows are written as they are read, so a result of any size costs one row
+// of memory:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//	    w.Header().Set("Content-Type", "application/json")
+//	    if _, err := db.QueryJSON(r.Context(), database, w, "SELECT * FROM jobs WHERE state = $1", "failed"); err != nil {
+//	        slog.ErrorContext(r.Context(), "jobs", "error", err)
+//	    }
+//	}
+//
+// Values are shaped as by QueryMaps: numbers, booleans, strings and null,
+// DECIMAL as a string; times are formatted with time.RFC3339Nano, binary
+// values base64-encoded, and NaN and infinities written as strings. An
+// error after the first row leaves w with an incomplete document.
+func QueryJSON(ctx context.Context, q Querier, w io.Writer, query string, args ...any) (int64, error) {
+	return QueryJSONOpts(ctx, q, w, JSONOptions{}, query, args...)
+}
+
+// QueryJSONOpts is QueryJSON with explicit options.
+func QueryJSONOpts(ctx context.Context, q Querier, w io.Writer, opts JSONOptions, query string, args ...any) (int64, error) {
+	if opts.TimeLayout == "" {
+		opts.TimeLayout = time.RFC3339Nano
+	}
+	bw := bufio.NewWriterSize(w, 32<<10)
+	var (
+		n        int64
+		keys     [][]byte
+		families []ColumnType
+		buf      []byte
+	)
+	err := stream(ctx, q, query, args, func(cols []string, types []*sql.ColumnType) error {
+		families = coercionTypes(types)
+		keys = make([][]byte, len(cols))
+		for i, c := range cols {
+			keys[i], _ = json.Marshal(c)
+		}
+		return nil
+	}, func(cols []string, vals []any) error {
+		switch {
+		case opts.Lines:
+		case n == 0:
+			buf = append(buf[:0], '[')
+		default:
+			buf = append(buf[:0], ',')
+		}
+		buf = append(buf, '{')
+		for i, v := range vals {
+			if i > 0 {
+				buf = append(buf, ',')
+			}
+			buf = append(buf, keys[i]...)
+			buf = append(buf, ':')
+			raw, err := json.Marshal(opts.value(coerceValue(v, families[i])))
+			if err != nil {
+				return err
+			}
+			buf = append(buf, raw...)
+		}
+		buf = append(buf, '}')
+		if opts.Lines {
+			buf = append(buf, '\n')
+		}
+		n++
+		_, err := bw.Write(buf)
+		buf = buf[:0]
+		return err
+	})
+	if err != nil {
+		_ = bw.Flush()
+		return n, err
+	}
+	if !opts.Lines {
+		if n == 0 {
+			_, _ = bw.WriteString("[")
+		}
+		_, _ = bw.WriteString("]\n")
+	}
+	return n, bw.Flush()
+}
+
+// value converts a coerced value to what encoding/json should write.
+func (o JSONOptions) value(v any) any {
+	switch x := v.(type) {
+	case time.Time:
+		return x.Format(o.TimeLayout)
+	case []byte:
+		if o.Binary == "hex" {
+			return hex.EncodeToString(x)
+		}
+		return base64.StdEncoding.EncodeToString(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fmtFloat(x)
+		}
+	case TruncatedValue:
+		return x.String()
+	}
+	return v
+}
+
+func fmtFloat(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f > 0:
+		return "Infinity"
+	}
+	return "-Infinity"
+}
@@ -0,0 +1,309 @@
+// Package querylog ships one event per statement — fingerprint, duration,
+// rows affected and error class — to an HTTP endpoint, for teams that
+// analyse queries in a log or analytics store rather than in metrics.
+//
+// The Shipper is a db.Hook that never blocks the statement: events go into a
+// bounded buffer and are posted in batches by Run. When the buffer is full,
+// or a batch cannot be delivered, events are dropped and counted in Stats.
+//
+//	ship := querylog.New(querylog.Options{
+//	    Endpoint: "https://otel-collector:4318/v1/logs",
+//	    Format:   querylog.FormatOTLP,
+//	    Service:  "billing",
+//	})
+//	go ship.Run(ctx)
+//	conn := db.MustOpen(db.Config{..., Hooks: []db.Hook{ship}})
+package querylog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Event describes one executed statement.
+type Event struct {
+	Fingerprint string  `json:"fingerprint"`
+	Duration    float64 `json:"duration_ms"`
+	// Rows is the number of rows affected by an Exec; nil for queries and
+	// failed statements.
+	Rows *int64 `json:"rows,omitempty"`
+	// ErrorClass names the sentinel the error mapped to ("duplicate_key",
+	// "timeout", ...), "other" for unmapped errors, and is empty on success.
+	ErrorClass string    `json:"error_class,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// Format is the request body layout.
+type Format int
+
+const (
+	// FormatJSON posts {"events": [...]}.
+	FormatJSON Format = iota
+	// FormatOTLP posts an OTLP/HTTP JSON logs request, one log record per
+	// event, for an OpenTelemetry collector's /v1/logs.
+	FormatOTLP
+)
+
+// Options configures New. Endpoint is required.
+type Options struct {
+	Endpoint string
+	Format   Format
+	// Headers are added to every request, e.g. an API key.
+	Headers map[string]string
+	// Service is reported as the OTLP service.name resource attribute.
+	Service string
+	// BatchSize is the most events per request. Defaults to 100.
+	BatchSize int
+	// FlushInterval bounds how long an event waits for its batch to fill.
+	// Defaults to 5s.
+	FlushInterval time.Duration
+	// BufferSize is the most events held in memory. Defaults to 10000.
+	BufferSize int
+	// Client defaults to an http.Client with a 10s timeout.
+	Client *http.Client
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Stats counts events by outcome.
+type Stats struct {
+	Sent uint64 `json:"sent"`
+	// Dropped were discarded because the buffer was full.
+	Dropped uint64 `json:"dropped"`
+	// Failed were in batches the endpoint did not accept.
+	Failed uint64 `json:"failed"`
+}
+
+// Shipper is a db.Hook that batches events to an HTTP endpoint.
+type Shipper struct {
+	opts   Options
+	events chan Event
+
+	sent, dropped, failed atomic.Uint64
+}
+
+// New returns a Shipper; add it to Config.Hooks and start Run.
+func New(opts Options) *Shipper {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 10000
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Shipper{opts: opts, events: make(chan Event, opts.BufferSize)}
+}
+
+// Stats returns the event counters.
+func (s *Shipper) Stats() Stats {
+	return Stats{Sent: s.sent.Load(), Dropped: s.dropped.Load(), Failed: s.failed.Load()}
+}
+
+func (s *Shipper) BeforeQuery(context.Context, string, []any) {}
+
+func (s *Shipper) AfterQuery(ctx context.Context, query string, _ []any, d time.Duration, err error) {
+	ev := Event{
+		Fingerprint: db.Fingerprint(query),
+		Duration:    float64(d) / float64(time.Millisecond),
+		ErrorClass:  ErrorClass(err),
+		At:          time.Now().UTC(),
+	}
+	if n, ok := db.RowsAffected(ctx); ok {
+		ev.Rows = &n
+	}
+	select {
+	case s.events <- ev:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// Run posts batches until ctx is cancelled, then flushes what is buffered
+// and returns.
+func (s *Shipper) Run(ctx context.Context) error {
+	t := time.NewTicker(s.opts.FlushInterval)
+	defer t.Stop()
+	batch := make([]Event, 0, s.opts.BatchSize)
+	// Requests are bounded by the client timeout, not ctx, so a batch in
+	// flight at shutdown is still delivered.
+	postCtx := context.WithoutCancel(ctx)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.post(postCtx, batch); err != nil {
+			s.failed.Add(uint64(len(batch)))
+			s.opts.Logger.Warn("querylog: batch not delivered", "events", len(batch), "error", err)
+		} else {
+			s.sent.Add(uint64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case ev := <-s.events:
+			batch = append(batch, ev)
+			if len(batch) >= s.opts.BatchSize {
+				flush()
+			}
+		case <-t.C:
+			flush()
+		case <-ctx.Done():
+			for {
+				select {
+				case ev := <-s.events:
+					batch = append(batch, ev)
+					if len(batch) >= s.opts.BatchSize {
+						flush()
+					}
+					continue
+				default:
+				}
+				flush()
+				return ctx.Err()
+			}
+		}
+	}
+}
+
+func (s *Shipper) post(ctx context.Context, batch []Event) error {
+	var body any = map[string]any{"events": batch}
+	if s.opts.Format == FormatOTLP {
+		body = otlpLogs(s.opts.Service, batch)
+	}
+	buf, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.Endpoint, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("querylog: %s returned %s", s.opts.Endpoint, resp.Status)
+	}
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Error classes
+// ─────────────────────────────────────────────────────────────────────────────
+
+var errorClasses = []struct {
+	sentinel error
+	class    string
+}{
+	{db.ErrNotFound, "not_found"},
+	{db.ErrDuplicateKey, "duplicate_key"},
+	{db.ErrForeignKeyViolation, "foreign_key_violation"},
+	{db.ErrCheckViolation, "check_violation"},
+	{db.ErrNotNullViolation, "not_null_violation"},
+	{db.ErrInvalidData, "invalid_data"},
+	{db.ErrDeadlock, "deadlock"},
+	{db.ErrTimeout, "timeout"},
+	{db.ErrConnectionFailed, "connection_failed"},
+	{db.ErrResourceExhausted, "resource_exhausted"},
+	{db.ErrBudgetExceeded, "budget_exceeded"},
+	{db.ErrInvalidFilter, "invalid_filter"},
+	{db.ErrReadOnly, "read_only"},
+	{db.ErrTruncated, "truncated"},
+}
+
+// ErrorClass returns a low-cardinality name for err: the sentinel it maps
+// to, "other" for anything else, and "" for nil.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	for _, c := range errorClasses {
+		if errors.Is(err, c.sentinel) {
+			return c.class
+		}
+	}
+	return "other"
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// OTLP/HTTP JSON encoding
+// ─────────────────────────────────────────────────────────────────────────────
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"` // int64 as a string, per the OTLP JSON mapping
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpAttr struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+func strAttr(k, v string) otlpAttr { return otlpAttr{Key: k, Value: otlpValue{StringValue: &v}} }
+
+func otlpLogs(service string, batch []Event) any {
+	records := make([]any, len(batch))
+	for i, ev := range batch {
+		attrs := []otlpAttr{
+			strAttr("db.query.fingerprint", ev.Fingerprint),
+			{Key: "db.duration_ms", Value: otlpValue{DoubleValue: &ev.Duration}},
+		}
+		if ev.Rows != nil {
+			n := strconv.FormatInt(*ev.Rows, 10)
+			attrs = append(attrs, otlpAttr{Key: "db.rows_affected", Value: otlpValue{IntValue: &n}})
+		}
+		severity := "INFO"
+		if ev.ErrorClass != "" {
+			attrs = append(attrs, strAttr("db.error_class", ev.ErrorClass))
+			severity = "ERROR"
+		}
+		fp := ev.Fingerprint
+		records[i] = map[string]any{
+			"timeUnixNano": strconv.FormatInt(ev.At.UnixNano(), 10),
+			"severityText": severity,
+			"body":         otlpValue{StringValue: &fp},
+			"attributes":   attrs,
+		}
+	}
+	var resource []otlpAttr
+	if service != "" {
+		resource = append(resource, strAttr("service.name", service))
+	}
+	return map[string]any{
+		"resourceLogs": []any{map[string]any{
+			"resource": map[string]any{"attributes": resource},
+			"scopeLogs": []any{map[string]any{
+				"scope":      map[string]any{"name": "github.com/Skryldev/sql-toolkit/db/querylog"},
+				"logRecords": records,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,81 @@
+package querylog_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/querylog"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestShipper(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		events []querylog.Event
+		otlp   map[string]any
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/v1/logs" {
+			_ = json.NewDecoder(r.Body).Decode(&otlp)
+			return
+		}
+		var body struct{ Events []querylog.Event }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		events = append(events, body.Events...)
+	}))
+	defer srv.Close()
+
+	ship := querylog.New(querylog.Options{Endpoint: srv.URL, BatchSize: 2, BufferSize: 3})
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1, Hooks: []db.Hook{ship}})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	_, _ = d.Exec(ctx, `CREATE TABLE t (id INTEGER PRIMARY KEY)`)
+	_, _ = d.Exec(ctx, `INSERT INTO t (id) VALUES (1), (2)`)
+	_, _ = d.Exec(ctx, `INSERT INTO t (id) VALUES (1)`)
+	_, _ = d.Exec(ctx, `DELETE FROM t`) // buffer full: dropped
+
+	runCtx, stop := context.WithCancel(ctx)
+	stop()
+	_ = ship.Run(runCtx) // drains and flushes
+
+	if st := ship.Stats(); st.Sent != 3 || st.Dropped != 1 || st.Failed != 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+	mu.Lock()
+	got := events
+	mu.Unlock()
+	if len(got) != 3 {
+		t.Fatalf("received %d events", len(got))
+	}
+	if ev := got[1]; ev.Rows == nil || *ev.Rows != 2 || ev.Fingerprint != "INSERT INTO t (id) VALUES (?), (?)" {
+		t.Fatalf("insert event = %+v", ev)
+	}
+	if ev := got[2]; ev.ErrorClass != "duplicate_key" || ev.Rows != nil {
+		t.Fatalf("failed event = %+v", ev)
+	}
+
+	// OTLP batches go to a collector's /v1/logs.
+	ship = querylog.New(querylog.Options{Endpoint: srv.URL + "/v1/logs", Format: querylog.FormatOTLP, Service: "billing", FlushInterval: time.Millisecond})
+	ship.AfterQuery(ctx, "SELECT 1", nil, time.Millisecond, nil)
+	runCtx, stop = context.WithCancel(ctx)
+	stop()
+	_ = ship.Run(runCtx)
+	mu.Lock()
+	body, _ := json.Marshal(otlp)
+	mu.Unlock()
+	if ship.Stats().Sent != 1 || !strings.Contains(string(body), `"stringValue":"billing"`) {
+		t.Fatalf("otlp body = %s", body)
+	}
+}
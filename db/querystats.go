@@ -0,0 +1,180 @@
+package db
+
+import (
+	"context"
+	"math/bits"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// QueryStats — in-process latency percentiles per fingerprint
+// ─────────────────────────────────────────────────────────────────────────────
+
+// QueryStat summarises the executions of one query fingerprint. Percentiles
+// come from a log-linear histogram and are accurate to within ~7%.
+type QueryStat struct {
+	Fingerprint string        `json:"fingerprint"`
+	Count       uint64        `json:"count"`
+	Errors      uint64        `json:"errors"`
+	Total       time.Duration `json:"total"`
+	Max         time.Duration `json:"max"`
+	P50         time.Duration `json:"p50"`
+	P95         time.Duration `json:"p95"`
+	P99         time.Duration `json:"p99"`
+}
+
+// maxStatFingerprints bounds memory when queries are built dynamically;
+// executions beyond it are folded into otherFingerprint.
+const (
+	maxStatFingerprints = 1000
+	otherFingerprint    = "(other)"
+)
+
+// QueryStatsCollector is a Hook aggregating per-fingerprint latency in
+// memory, for admin endpoints or periodic logging where running Prometheus is
+// not an option. Each fingerprint costs about 2 KiB.
+//
+// Setting Config.CollectQueryStats feeds the package-level collector read by
+// QueryStats; NewQueryStatsCollector is for callers that want a separate one.
+type QueryStatsCollector struct {
+	mu    sync.Mutex
+	stats map[string]*queryHist
+}
+
+// NewQueryStatsCollector returns an empty collector; add it to Config.Hooks.
+func NewQueryStatsCollector() *QueryStatsCollector {
+	return &QueryStatsCollector{stats: make(map[string]*queryHist)}
+}
+
+func (c *QueryStatsCollector) BeforeQuery(_ context.Context, _ string, _ []any) {}
+
+func (c *QueryStatsCollector) AfterQuery(_ context.Context, query string, _ []any, d time.Duration, err error) {
+	fp := Fingerprint(query)
+	failed := err != nil && !IsNotFound(err)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	h := c.stats[fp]
+	if h == nil {
+		if len(c.stats) >= maxStatFingerprints {
+			fp = otherFingerprint
+			h = c.stats[fp]
+		}
+		if h == nil {
+			h = &queryHist{}
+			c.stats[fp] = h
+		}
+	}
+	h.observe(d, failed)
+}
+
+// Snapshot returns the current statistics ordered by total time, highest
+// first, which puts the queries worth optimising at the top.
+func (c *QueryStatsCollector) Snapshot() []QueryStat {
+	c.mu.Lock()
+	out := make([]QueryStat, 0, len(c.stats))
+	for fp, h := range c.stats {
+		out = append(out, h.stat(fp))
+	}
+	c.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Total != out[j].Total {
+			return out[i].Total > out[j].Total
+		}
+		return out[i].Fingerprint < out[j].Fingerprint
+	})
+	return out
+}
+
+// Reset discards all statistics.
+func (c *QueryStatsCollector) Reset() {
+	c.mu.Lock()
+	c.stats = make(map[string]*queryHist)
+	c.mu.Unlock()
+}
+
+// ── Package-level collector ──────────────────────────────────────────────────
+
+var queryStats = NewQueryStatsCollector()
+
+// QueryStats returns per-fingerprint latency statistics for every DB opened
+// with Config.CollectQueryStats, ordered by total time.
+func QueryStats() []QueryStat { return queryStats.Snapshot() }
+
+// ResetQueryStats clears the statistics returned by QueryStats, e.g. after
+// each periodic log line.
+func ResetQueryStats() { queryStats.Reset() }
+
+// ── Histogram ────────────────────────────────────────────────────────────────
+
+// histSub is the number of linear sub-buckets per power of two.
+const histSub = 8
+
+type queryHist struct {
+	count, errors uint64
+	total, max    time.Duration
+	buckets       [64 * histSub]uint32
+}
+
+func (h *queryHist) observe(d time.Duration, failed bool) {
+	h.count++
+	if failed {
+		h.errors++
+	}
+	h.total += d
+	if d > h.max {
+		h.max = d
+	}
+	h.buckets[bucketOf(d)]++
+}
+
+func (h *queryHist) stat(fp string) QueryStat {
+	return QueryStat{
+		Fingerprint: fp,
+		Count:       h.count,
+		Errors:      h.errors,
+		Total:       h.total,
+		Max:         h.max,
+		P50:         h.quantile(0.50),
+		P95:         h.quantile(0.95),
+		P99:         h.quantile(0.99),
+	}
+}
+
+func (h *queryHist) quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.count-1)) + 1
+	var seen uint64
+	for i, n := range h.buckets {
+		seen += uint64(n)
+		if seen >= rank {
+			return min(bucketMid(i), h.max)
+		}
+	}
+	return h.max
+}
+
+// bucketOf maps d to its log-linear bucket: the power of two selects the
+// group, the next three bits select one of histSub linear slots.
+func bucketOf(d time.Duration) int {
+	ns := uint64(max(d, 1))
+	e := bits.Len64(ns) - 1
+	if e < 3 {
+		return int(ns)
+	}
+	return e*histSub + int(ns>>(e-3)&(histSub-1))
+}
+
+// bucketMid returns the midpoint of bucket i.
+func bucketMid(i int) time.Duration {
+	e, sub := i/histSub, i%histSub
+	if e < 3 {
+		return time.Duration(i)
+	}
+	lo := uint64(histSub+sub) << (e - 3)
+	return time.Duration(lo + (uint64(1)<<(e-3))/2)
+}
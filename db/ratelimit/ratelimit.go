@@ -0,0 +1,183 @@
+// Package ratelimit implements per-key rate limits stored in the application
+// database, for services that need simple limits (login attempts, API keys)
+// without running Redis.
+//
+// Both limiters keep their state in the tables created by
+// migrations/000003_create_rate_limits.up.sql and update it with a single
+// short transaction per call, so every instance sharing the database shares
+// the limit.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Result reports the outcome of one Allow call.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of further requests allowed right now.
+	Remaining int
+	// RetryAfter is how long to wait before the next request can succeed;
+	// zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// Limiter is satisfied by FixedWindow and TokenBucket.
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+var (
+	_ Limiter = (*FixedWindow)(nil)
+	_ Limiter = (*TokenBucket)(nil)
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// FixedWindow
+// ─────────────────────────────────────────────────────────────────────────────
+
+// FixedWindow allows Limit requests per key in each aligned Window (e.g. per
+// calendar minute). It is the cheapest limiter — one upsert and one read —
+// but permits up to 2×Limit requests around a window boundary.
+type FixedWindow struct {
+	d      *db.DB
+	limit  int
+	window time.Duration
+
+	sqlHit, sqlCount, sqlSweep string
+}
+
+// NewFixedWindow returns a limiter allowing limit requests per window.
+func NewFixedWindow(d *db.DB, limit int, window time.Duration) *FixedWindow {
+	ph := d.Dialect().Placeholder()
+	hit := fmt.Sprintf(`INSERT INTO rate_limit_windows (limit_key, window_start, hits) VALUES (%s, %s, 1)`, ph(1), ph(2))
+	if d.Dialect() == db.DialectMySQL {
+		hit += ` ON DUPLICATE KEY UPDATE hits = hits + 1`
+	} else {
+		hit += ` ON CONFLICT (limit_key, window_start) DO UPDATE SET hits = rate_limit_windows.hits + 1`
+	}
+	return &FixedWindow{
+		d:        d,
+		limit:    limit,
+		window:   window,
+		sqlHit:   hit,
+		sqlCount: fmt.Sprintf(`SELECT hits FROM rate_limit_windows WHERE limit_key = %s AND window_start = %s`, ph(1), ph(2)),
+		sqlSweep: fmt.Sprintf(`DELETE FROM rate_limit_windows WHERE window_start < %s`, ph(1)),
+	}
+}
+
+// Allow counts a request for key and reports whether it is within the limit.
+// Rejected requests are counted too, so hammering a key does not reset it.
+func (l *FixedWindow) Allow(ctx context.Context, key string) (Result, error) {
+	now := l.d.Now()
+	start := now.Truncate(l.window)
+	var hits int
+	err := l.d.ExecTx(ctx, func(tx *db.Tx) error {
+		if _, err := tx.Exec(ctx, l.sqlHit, key, start.UnixMicro()); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, l.sqlCount, key, start.UnixMicro()).Scan(&hits)
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	if hits > l.limit {
+		return Result{RetryAfter: start.Add(l.window).Sub(now)}, nil
+	}
+	return Result{Allowed: true, Remaining: l.limit - hits}, nil
+}
+
+// Sweep deletes windows that have ended. Run it periodically; stale rows are
+// harmless but accumulate one per active key and window.
+func (l *FixedWindow) Sweep(ctx context.Context) (int64, error) {
+	cutoff := l.d.Now().Truncate(l.window)
+	return l.d.ExecAffected(ctx, l.sqlSweep, cutoff.UnixMicro())
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// TokenBucket
+// ─────────────────────────────────────────────────────────────────────────────
+
+// TokenBucket allows bursts of up to Capacity requests per key, refilled at
+// Rate tokens per second. Refill is computed from the caller's clock, so
+// instances should run NTP; skew only shifts refill timing slightly.
+type TokenBucket struct {
+	d        *db.DB
+	capacity float64
+	rate     float64
+
+	sqlInit, sqlLock, sqlUpdate string
+}
+
+// NewTokenBucket returns a limiter with the given burst capacity and refill
+// rate in tokens per second.
+func NewTokenBucket(d *db.DB, capacity int, perSecond float64) *TokenBucket {
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	init := fmt.Sprintf(`INSERT INTO rate_limit_buckets (limit_key, tokens, updated_at) VALUES (%s, %s, %s)`, ph(1), ph(2), ph(3))
+	lock := fmt.Sprintf(`SELECT tokens, updated_at FROM rate_limit_buckets WHERE limit_key = %s`, ph(1))
+	switch dialect {
+	case db.DialectMySQL:
+		init = "INSERT IGNORE" + init[len("INSERT"):]
+		lock += " FOR UPDATE"
+	case db.DialectPostgres:
+		init += " ON CONFLICT (limit_key) DO NOTHING"
+		lock += " FOR UPDATE"
+	default:
+		// SQLite: the INSERT takes the database write lock for the rest of
+		// the transaction, which serialises concurrent callers.
+		init += " ON CONFLICT (limit_key) DO NOTHING"
+	}
+	return &TokenBucket{
+		d:         d,
+		capacity:  float64(capacity),
+		rate:      perSecond,
+		sqlInit:   init,
+		sqlLock:   lock,
+		sqlUpdate: fmt.Sprintf(`UPDATE rate_limit_buckets SET tokens = %s, updated_at = %s WHERE limit_key = %s`, ph(1), ph(2), ph(3)),
+	}
+}
+
+// Allow takes one token for key.
+func (b *TokenBucket) Allow(ctx context.Context, key string) (Result, error) {
+	return b.AllowN(ctx, key, 1)
+}
+
+// AllowN takes n tokens for key if available. A rejected call takes nothing.
+func (b *TokenBucket) AllowN(ctx context.Context, key string, n int) (Result, error) {
+	var res Result
+	err := b.d.ExecTx(ctx, func(tx *db.Tx) error {
+		now := tx.Now().UnixMicro()
+		if _, err := tx.Exec(ctx, b.sqlInit, key, b.capacity, now); err != nil {
+			return err
+		}
+		var tokens float64
+		var updated int64
+		if err := tx.QueryRow(ctx, b.sqlLock, key).Scan(&tokens, &updated); err != nil {
+			return err
+		}
+		elapsed := float64(max(now-updated, 0)) / 1e6
+		tokens = math.Min(b.capacity, tokens+elapsed*b.rate)
+
+		need := float64(n)
+		if tokens >= need {
+			tokens -= need
+			res = Result{Allowed: true, Remaining: int(tokens)}
+		} else if b.rate > 0 {
+			res.RetryAfter = time.Duration((need - tokens) / b.rate * float64(time.Second))
+		} else {
+			res.RetryAfter = time.Duration(math.MaxInt64)
+		}
+		_, err := tx.Exec(ctx, b.sqlUpdate, tokens, now, key)
+		return err
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
@@ -0,0 +1,60 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	"github.com/Skryldev/sql-toolkit/db/ratelimit"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	return dbtest.OpenSQLite(t, "000003_create_rate_limits")
+}
+
+func TestFixedWindow(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	l := ratelimit.NewFixedWindow(d, 3, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		res, err := l.Allow(ctx, "login:alice")
+		if err != nil || !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("attempt %d: res=%+v err=%v", i+1, res, err)
+		}
+	}
+	res, err := l.Allow(ctx, "login:alice")
+	if err != nil || res.Allowed || res.RetryAfter <= 0 {
+		t.Fatalf("4th attempt should be rejected: res=%+v err=%v", res, err)
+	}
+	if res, _ := l.Allow(ctx, "login:bob"); !res.Allowed {
+		t.Fatal("keys must be limited independently")
+	}
+	if n, err := l.Sweep(ctx); err != nil || n != 0 {
+		t.Fatalf("sweep removed current windows: n=%d err=%v", n, err)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	b := ratelimit.NewTokenBucket(d, 2, 50) // refills one token every 20ms
+
+	for i := 0; i < 2; i++ {
+		if res, err := b.Allow(ctx, "api:k"); err != nil || !res.Allowed {
+			t.Fatalf("burst %d: res=%+v err=%v", i+1, res, err)
+		}
+	}
+	res, err := b.Allow(ctx, "api:k")
+	if err != nil || res.Allowed || res.RetryAfter <= 0 || res.RetryAfter > 20*time.Millisecond {
+		t.Fatalf("expected rejection with short retry: res=%+v err=%v", res, err)
+	}
+	time.Sleep(res.RetryAfter + 5*time.Millisecond)
+	if res, err := b.Allow(ctx, "api:k"); err != nil || !res.Allowed {
+		t.Fatalf("expected refill: res=%+v err=%v", res, err)
+	}
+}
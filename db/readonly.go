@@ -0,0 +1,111 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Read-only mode — process-wide switch rejecting writes
+// ─────────────────────────────────────────────────────────────────────────────
+
+var readOnly atomic.Bool
+
+// SetReadOnly turns read-only mode on or off for every DB in the process.
+// While it is on, write statements (see IsWriteStatement) fail with
+// ErrReadOnly before reaching the database, through DB, Tx and Stmt alike;
+// reads are unaffected. Use it for maintenance windows, failover drills and
+// "degrade to read-only" playbooks, e.g. from an admin endpoint or a flag:
+//
+//	db.SetReadOnly(true)
+//	defer db.SetReadOnly(false)
+//
+// Transactions already writing are not interrupted, but their next write
+// statement fails. Changes are logged and published as EventReadOnlyChanged.
+func SetReadOnly(on bool) {
+	if readOnly.Swap(on) == on {
+		return
+	}
+	slog.Warn("sqltoolkit/db: read-only mode changed", slog.Bool("read_only", on))
+	events.Publish(Event{Kind: EventReadOnlyChanged, Attrs: map[string]any{"read_only": on}})
+}
+
+// ReadOnly reports whether read-only mode is on.
+func ReadOnly() bool { return readOnly.Load() }
+
+// checkReadOnly is the preflight guard for read-only mode.
+func checkReadOnly(query string) error {
+	if !readOnly.Load() || !IsWriteStatement(query) {
+		return nil
+	}
+	return &DBError{
+		Sentinel: ErrReadOnly,
+		Cause:    fmt.Errorf("write rejected: %s", Fingerprint(query)),
+	}
+}
+
+// writeVerbs are the statements that change data or schema. CALL and DO run
+// arbitrary server code and are treated as writes; APPEND is the label
+// AppendRows runs its preflight under.
+var writeVerbs = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true, "REPLACE": true, "UPSERT": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true, "COMMENT": true,
+	"GRANT": true, "REVOKE": true, "VACUUM": true, "REINDEX": true, "CLUSTER": true, "REFRESH": true,
+	"COPY": true, "LOAD": true, "CALL": true, "DO": true, "APPEND": true,
+}
+
+// IsWriteStatement reports whether query changes data or schema: its leading
+// verb is a write (INSERT, UPDATE, DELETE, DDL, ...), it is a WITH query
+// containing a data-modifying statement, a SELECT ... INTO, which creates a
+// table (PostgreSQL) or writes a file or variables (MySQL), or an EXPLAIN
+// ANALYZE, which runs the statement it explains, of a write. SELECT ... FOR
+// UPDATE is a read.
+func IsWriteStatement(query string) bool {
+	return isWrite(strings.Fields(strings.NewReplacer("(", " ", ")", " ", ",", " ", ";", " ").
+		Replace(strings.ToUpper(Fingerprint(query)))))
+}
+
+func isWrite(toks []string) bool {
+	if len(toks) == 0 {
+		return false
+	}
+	switch toks[0] {
+	case "EXPLAIN":
+		return explainsWrite(toks[1:])
+	case "WITH", "SELECT":
+	default:
+		return writeVerbs[toks[0]]
+	}
+	for i, tok := range toks {
+		switch tok {
+		case "INSERT", "DELETE", "MERGE", "INTO":
+			return true
+		case "UPDATE":
+			if prev := toks[i-1]; prev != "FOR" && prev != "KEY" { // FOR [NO KEY] UPDATE
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// explainsWrite reports whether the EXPLAIN whose options and statement are
+// toks runs a write: only ANALYZE executes the statement.
+func explainsWrite(toks []string) bool {
+	analyze := false
+	for i, tok := range toks {
+		switch {
+		case tok == "ANALYZE" || tok == "ANALYSE":
+			next := ""
+			if i+1 < len(toks) {
+				next = toks[i+1]
+			}
+			analyze = next != "FALSE" && next != "OFF"
+		case tok == "SELECT" || tok == "WITH" || tok == "VALUES" || tok == "TABLE" || tok == "EXECUTE" || writeVerbs[tok]:
+			return analyze && isWrite(toks[i:])
+		}
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// QueryRecorder — in-memory ring buffer of slow and failed queries
+// ─────────────────────────────────────────────────────────────────────────────
+
+// QueryRecord describes one captured statement.
+type QueryRecord struct {
+	// Fingerprint is the statement with literals and placeholders replaced by
+	// '?', suitable for grouping (see Fingerprint).
+	Fingerprint string        `json:"fingerprint"`
+	Query       string        `json:"query"`
+	Duration    time.Duration `json:"duration"`
+	// Error is the mapped error text; empty for slow but successful queries.
+	Error string    `json:"error,omitempty"`
+	At    time.Time `json:"at"`
+	// Caller is the file:line outside this package that issued the query.
+	Caller string `json:"caller,omitempty"`
+}
+
+// QueryRecorderConfig configures NewQueryRecorder.
+type QueryRecorderConfig struct {
+	// Size is the number of records kept. Defaults to 100.
+	Size int
+	// SlowThreshold is the minimum duration recorded. Defaults to 100ms.
+	SlowThreshold time.Duration
+	// SkipErrors disables recording of failed statements that were fast.
+	SkipErrors bool
+}
+
+// QueryRecorder is a Hook that keeps the last Size slow or failed statements
+// in memory so they can be inspected (e.g. via db/debughttp) without a log
+// pipeline. ErrNotFound is not considered a failure.
+//
+// Every DB opened with Open feeds the package-level recorder read by
+// RecentQueries; NewQueryRecorder is for callers that want a separate buffer.
+type QueryRecorder struct {
+	threshold  atomic.Int64 // time.Duration
+	skipErrors atomic.Bool
+
+	mu   sync.Mutex
+	buf  []QueryRecord
+	next int
+	full bool
+}
+
+// NewQueryRecorder returns a QueryRecorder; add it to Config.Hooks.
+func NewQueryRecorder(cfg QueryRecorderConfig) *QueryRecorder {
+	r := &QueryRecorder{}
+	r.configure(cfg)
+	return r
+}
+
+func (r *QueryRecorder) configure(cfg QueryRecorderConfig) {
+	if cfg.Size <= 0 {
+		cfg.Size = 100
+	}
+	if cfg.SlowThreshold <= 0 {
+		cfg.SlowThreshold = 100 * time.Millisecond
+	}
+	r.threshold.Store(int64(cfg.SlowThreshold))
+	r.skipErrors.Store(cfg.SkipErrors)
+	r.mu.Lock()
+	r.buf = make([]QueryRecord, cfg.Size)
+	r.next, r.full = 0, false
+	r.mu.Unlock()
+}
+
+func (r *QueryRecorder) BeforeQuery(_ context.Context, _ string, _ []any) {}
+
+func (r *QueryRecorder) AfterQuery(_ context.Context, query string, _ []any, d time.Duration, err error) {
+	failed := err != nil && !errors.Is(err, ErrNotFound) && !r.skipErrors.Load()
+	if !failed && d < time.Duration(r.threshold.Load()) {
+		return
+	}
+	rec := QueryRecord{
+		Fingerprint: Fingerprint(query),
+		Query:       trimQuery(query),
+		Duration:    d,
+		At:          time.Now(),
+		Caller:      externalCaller(),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	r.add(rec)
+}
+
+func (r *QueryRecorder) add(rec QueryRecord) {
+	r.mu.Lock()
+	r.buf[r.next] = rec
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+	r.mu.Unlock()
+}
+
+// Recent returns the recorded statements, newest first.
+func (r *QueryRecorder) Recent() []QueryRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.buf)
+	}
+	out := make([]QueryRecord, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, r.buf[(r.next-i+len(r.buf))%len(r.buf)])
+	}
+	return out
+}
+
+// Reset discards all recorded statements.
+func (r *QueryRecorder) Reset() {
+	r.mu.Lock()
+	clear(r.buf)
+	r.next, r.full = 0, false
+	r.mu.Unlock()
+}
+
+// ── Package-level recorder ───────────────────────────────────────────────────
+
+var recentQueries = NewQueryRecorder(QueryRecorderConfig{})
+
+// RecentQueries returns the last slow or failed statements issued through any
+// DB opened with Open, newest first.
+func RecentQueries() []QueryRecord { return recentQueries.Recent() }
+
+// ConfigureRecentQueries changes the size and thresholds of the buffer behind
+// RecentQueries. Existing records are discarded.
+func ConfigureRecentQueries(cfg QueryRecorderConfig) { recentQueries.configure(cfg) }
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Fingerprint — literal-free query shape
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Fingerprint normalises query so that statements differing only in literal
+// values share one key: string and numeric literals and placeholders ($1, ?,
+// :name) become '?', lists of them collapse to a single '?', comments are
+// dropped and whitespace is collapsed. Identifiers and keywords are kept.
+//
+//	Fingerprint("SELECT * FROM t WHERE id IN (1, 2, 3) AND name = 'x'")
+//	// SELECT * FROM t WHERE id IN (?) AND name = ?
+func Fingerprint(query string) string {
+	var toks []string
+	push := func(tok string) {
+		n := len(toks)
+		if tok == "?" && n >= 2 && toks[n-1] == "," && toks[n-2] == "?" {
+			toks = toks[:n-1]
+			return
+		}
+		toks = append(toks, tok)
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(query[i:], "--"):
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				i += end + 4
+			} else {
+				i = len(query)
+			}
+		case c == '\'':
+			i = skipQuoted(query, i, '\'')
+			push("?")
+		case c == '"' || c == '`':
+			j := skipQuoted(query, i, c)
+			push(query[i:j])
+			i = j
+		case strings.HasPrefix(query[i:], "::"):
+			push("::")
+			i += 2
+		case c == '?':
+			push("?")
+			i++
+		case (c == '$' || c == ':') && i+1 < len(query) && isIdentByte(query[i+1]):
+			i++
+			for i < len(query) && isIdentByte(query[i]) {
+				i++
+			}
+			push("?")
+		case c >= '0' && c <= '9':
+			for i < len(query) && (isIdentByte(query[i]) || query[i] == '.') {
+				i++
+			}
+			push("?")
+		case isIdentByte(c):
+			j := i + 1
+			for j < len(query) && isIdentByte(query[j]) {
+				j++
+			}
+			push(query[i:j])
+			i = j
+		case strings.IndexByte(operatorBytes, c) >= 0:
+			j := i + 1
+			for j < len(query) && strings.IndexByte(operatorBytes, query[j]) >= 0 {
+				j++
+			}
+			push(query[i:j])
+			i = j
+		default:
+			push(string(c))
+			i++
+		}
+	}
+
+	var b strings.Builder
+	b.Grow(len(query))
+	for i, tok := range toks {
+		if i > 0 && !tightAfter(toks[i-1]) && !tightBefore(tok) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(tok)
+	}
+	return b.String()
+}
+
+const operatorBytes = "<>=!|&+-*/%^~"
+
+func tightAfter(tok string) bool  { return tok == "(" || tok == "." || tok == "::" }
+func tightBefore(tok string) bool { return tok == ")" || tok == "," || tok == "." || tok == "::" || tok == ";" }
+
+// skipQuoted returns the index just past the quoted section starting at
+// s[i]; a doubled quote character is an escaped quote.
+func skipQuoted(s string, i int, q byte) int {
+	for i++; i < len(s); i++ {
+		if s[i] == q {
+			if i+1 < len(s) && s[i+1] == q {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= 0x80
+}
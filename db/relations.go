@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Relation loading — explicit batch loading of 1:N children
+// ─────────────────────────────────────────────────────────────────────────────
+
+// defaultChildBatch keeps IN lists under SQLite's bind parameter limit.
+const defaultChildBatch = 500
+
+// ChildQuery describes how LoadChildren fetches the children of a batch of
+// parents.
+type ChildQuery[C any, K comparable] struct {
+	// SQL selects the children and contains exactly one %s, replaced by the
+	// bind markers of the parent keys (it is not a format string), e.g.
+	//   SELECT id, user_id, title FROM posts WHERE user_id IN (%s) ORDER BY id
+	SQL string
+	// Args are bound before the keys, as $1..$len(Args).
+	Args []any
+	// Scan maps one row to a child.
+	Scan ScanFunc[C]
+	// Key returns the parent key a child belongs to (its foreign key).
+	Key func(C) K
+	// BatchSize caps the keys per query. Defaults to 500.
+	BatchSize int
+}
+
+// LoadChildren loads the children of parents with one IN query per batch of
+// distinct keys and hands each parent its children through attach — in the
+// order the query returns them, and nil for parents without any. keyFn
+// returns a parent's key. Nothing is loaded lazily: the call is the only
+// place the queries run, which makes 1:N loading explicit without an N+1
+// loop:
+//
+//	err := db.LoadChildren(ctx, q, users, db.ChildQuery[*Post, int64]{
+//	    SQL:  `SELECT id, user_id, title FROM posts WHERE user_id IN (%s) ORDER BY id`,
+//	    Scan: scanPost,
+//	    Key:  func(p *Post) int64 { return p.UserID },
+//	}, func(u *User) int64 { return u.ID }, func(u *User, posts []*Post) { u.Posts = posts })
+func LoadChildren[P, C any, K comparable](ctx context.Context, q Querier, parents []P, cq ChildQuery[C, K], keyFn func(P) K, attach func(P, []C)) error {
+	if strings.Count(cq.SQL, "%s") != 1 {
+		return fmt.Errorf("sqltoolkit/db: LoadChildren: query must contain exactly one %%s: %q", cq.SQL)
+	}
+	batch := cq.BatchSize
+	if batch <= 0 {
+		batch = defaultChildBatch
+	}
+
+	keys := make([]K, 0, len(parents))
+	seen := make(map[K]bool, len(parents))
+	for _, p := range parents {
+		if k := keyFn(p); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+
+	ph := DialectFrom(q).Placeholder()
+	children := make(map[K][]C, len(keys))
+	for start := 0; start < len(keys); start += batch {
+		chunk := keys[start:min(start+batch, len(keys))]
+		marks := make([]string, len(chunk))
+		args := append(make([]any, 0, len(cq.Args)+len(chunk)), cq.Args...)
+		for i, k := range chunk {
+			marks[i] = ph(len(cq.Args) + i + 1)
+			args = append(args, k)
+		}
+		rows, err := Select(ctx, q, cq.Scan, strings.Replace(cq.SQL, "%s", strings.Join(marks, ", "), 1), args...)
+		if err != nil {
+			return err
+		}
+		for _, c := range rows {
+			k := cq.Key(c)
+			children[k] = append(children[k], c)
+		}
+	}
+
+	for _, p := range parents {
+		attach(p, children[keyFn(p)])
+	}
+	return nil
+}
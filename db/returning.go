@@ -0,0 +1,190 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Portable RETURNING
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Returning names the row read back by InsertReturning and UpdateReturning.
+type Returning struct {
+	// Table is the table the statement writes to.
+	Table string
+	// Columns are returned in order, matching the dest arguments.
+	Columns []string
+	// Key is the primary key column; on MySQL it must be the AUTO_INCREMENT
+	// column LastInsertId reports. Defaults to "id".
+	Key string
+}
+
+func (r Returning) key() string {
+	if r.Key == "" {
+		return "id"
+	}
+	return r.Key
+}
+
+func (r Returning) clause(d Dialect) string {
+	cols := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		cols[i] = d.QuoteIdent(c)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// selectByKey reads the Returning columns of one row on MySQL.
+func (r Returning) selectByKey() string {
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s = ?",
+		r.clause(DialectMySQL), DialectMySQL.QuoteIdent(r.Table), DialectMySQL.QuoteIdent(r.key()))
+}
+
+// InsertReturning runs query, a single-row INSERT written with $N
+// placeholders and without a RETURNING clause, and scans ret.Columns of the
+// inserted row into dest.
+//
+// PostgreSQL and SQLite append RETURNING to the statement. MySQL, which has
+// no RETURNING, runs the INSERT, reads LastInsertId and selects the row by
+// ret.Key — inside one transaction when q is a *DB, so the follow-up read
+// sees exactly the inserted row.
+//
+//	err := db.InsertReturning(ctx, q,
+//	    `INSERT INTO users (name, email) VALUES ($1, $2)`, []any{name, email},
+//	    db.Returning{Table: "users", Columns: []string{"id", "created_at"}},
+//	    &u.ID, &u.CreatedAt)
+func InsertReturning(ctx context.Context, q Querier, query string, args []any, ret Returning, dest ...any) error {
+	d := DialectFrom(q)
+	if d != DialectMySQL {
+		return q.QueryRow(ctx, query+"\nRETURNING "+ret.clause(d), args...).Scan(dest...)
+	}
+	return withTx(ctx, q, func(q Querier) error {
+		query, args := d.Rebind(query, args)
+		res, err := q.Exec(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		return q.QueryRow(ctx, ret.selectByKey(), id).Scan(dest...)
+	})
+}
+
+// UpdateReturning runs query, an UPDATE of the single row whose ret.Key is
+// key, written with $N placeholders and without RETURNING, and scans
+// ret.Columns of the updated row into dest. ErrNotFound is returned when the
+// row does not exist. MySQL is emulated as in InsertReturning.
+func UpdateReturning(ctx context.Context, q Querier, query string, args []any, ret Returning, key any, dest ...any) error {
+	d := DialectFrom(q)
+	if d != DialectMySQL {
+		return q.QueryRow(ctx, query+"\nRETURNING "+ret.clause(d), args...).Scan(dest...)
+	}
+	return withTx(ctx, q, func(q Querier) error {
+		query, args := d.Rebind(query, args)
+		if _, err := q.Exec(ctx, query, args...); err != nil {
+			return err
+		}
+		// RowsAffected is 0 on MySQL when the values did not change, so
+		// existence is decided by the read.
+		return q.QueryRow(ctx, ret.selectByKey(), key).Scan(dest...)
+	})
+}
+
+// withTx runs fn in a transaction when q is a *DB, and on q itself when it
+// is already a transaction or connection.
+func withTx(ctx context.Context, q Querier, fn func(Querier) error) error {
+	if d, ok := q.(*DB); ok {
+		return d.ExecTx(ctx, func(tx *Tx) error { return fn(tx) })
+	}
+	return fn(q)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Placeholder rebinding
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Rebind adapts a statement written with $N placeholders to the dialect.
+// PostgreSQL and SQLite accept $N, so query and args are returned unchanged.
+// For MySQL every $N becomes ? and args are reordered — and repeated where a
+// parameter is used more than once — to match. Quoted strings, quoted
+// identifiers and comments are left alone.
+//
+//	q, args := db.DialectMySQL.Rebind(`UPDATE t SET a = $2, b = $2 WHERE id = $1`, []any{7, "x"})
+//	// UPDATE t SET a = ?, b = ? WHERE id = ?   ["x", "x", 7]
+func (d Dialect) Rebind(query string, args []any) (string, []any) {
+	if d != DialectMySQL || !strings.Contains(query, "$") {
+		return query, args
+	}
+	var (
+		b   strings.Builder
+		out = make([]any, 0, len(args))
+	)
+	b.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		if j := skipNonCode(query, i, true); j > i {
+			b.WriteString(query[i:j])
+			i = j
+			continue
+		}
+		switch {
+		case c == '$':
+			j := i + 1
+			for j < len(query) && query[j] >= '0' && query[j] <= '9' {
+				j++
+			}
+			n, err := strconv.Atoi(query[i+1 : j])
+			if err != nil || n < 1 || n > len(args) {
+				b.WriteByte(c) // not a placeholder we can bind; keep as is
+				i++
+				continue
+			}
+			b.WriteByte('?')
+			out = append(out, args[n-1])
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), out
+}
+
+// skipNonCode returns the index just past the quoted string, quoted
+// identifier or comment starting at query[i], or i when none starts there.
+// A backslash escapes the next byte in strings, and '#' starts a comment
+// only when hash is set, as on MySQL.
+func skipNonCode(query string, i int, hash bool) int {
+	c := query[i]
+	switch {
+	case c == '\'' || c == '"' || c == '`':
+		j := i + 1
+		for j < len(query) {
+			if query[j] == '\\' && c != '`' {
+				j += 2
+				continue
+			}
+			if query[j] == c {
+				break
+			}
+			j++
+		}
+		return min(j+1, len(query))
+	case strings.HasPrefix(query[i:], "--") || hash && c == '#':
+		if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+			return i + j
+		}
+		return len(query)
+	case strings.HasPrefix(query[i:], "/*"):
+		if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+			return i + 2 + j + 2
+		}
+		return len(query)
+	}
+	return i
+}
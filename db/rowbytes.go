@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Row byte caps — bound the memory a wide row may take
+// ─────────────────────────────────────────────────────────────────────────────
+
+type maxRowBytesKey struct{}
+
+// WithMaxRowBytes returns a context under which Stream keeps at most n bytes
+// of text and binary values per row. Values are kept in column order while
+// they fit; one that does not becomes a TruncatedValue holding the bytes
+// that still fit and the original size, and the columns after it keep only
+// what remains. Numbers, booleans and times are never capped.
+//
+//	err := db.Stream(db.WithMaxRowBytes(ctx, 64<<10), q, `SELECT * FROM events`, nil,
+//	    func(cols []string, vals []any) error { ... })
+//
+// Values are copied straight from the driver's buffer up to the cap, so an
+// accidental SELECT * over large JSON or bytea columns costs the driver's
+// read buffer, not a copy per row held by the caller. n <= 0 removes the cap.
+func WithMaxRowBytes(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxRowBytesKey{}, n)
+}
+
+func maxRowBytesFrom(ctx context.Context) int {
+	n, _ := ctx.Value(maxRowBytesKey{}).(int)
+	return max(n, 0)
+}
+
+// TruncatedValue marks a text or binary value cut short by WithMaxRowBytes.
+type TruncatedValue struct {
+	// Prefix holds the leading bytes that fit in the row's budget.
+	Prefix []byte
+	// Size is the length of the full value in bytes.
+	Size int
+	// Text reports whether the driver returned the value as a string.
+	Text bool
+}
+
+func (v TruncatedValue) String() string {
+	return fmt.Sprintf("%q… (%d of %d bytes)", v.Prefix, len(v.Prefix), v.Size)
+}
+
+// cappedValue scans one column, charging text and binary values against
+// the shared row budget.
+type cappedValue struct {
+	dst    *any
+	budget *int
+}
+
+func (c cappedValue) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		if len(v) > *c.budget {
+			*c.dst = TruncatedValue{Prefix: append([]byte(nil), v[:*c.budget]...), Size: len(v)}
+			*c.budget = 0
+			return nil
+		}
+		*c.budget -= len(v)
+		*c.dst = append([]byte(nil), v...)
+	case string:
+		if len(v) > *c.budget {
+			*c.dst = TruncatedValue{Prefix: []byte(v[:*c.budget]), Size: len(v), Text: true}
+			*c.budget = 0
+			return nil
+		}
+		*c.budget -= len(v)
+		*c.dst = v
+	default:
+		*c.dst = src
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Row limits — cap the rows a result may yield
+// ─────────────────────────────────────────────────────────────────────────────
+
+type maxRowsKey struct{}
+
+// WithMaxRows returns a context under which Select, SelectStructs and Stream
+// stop after n rows. When the result has more, they return the first n
+// together with an error wrapping ErrTruncated, so an API built on a query
+// that lost its LIMIT degrades to a partial answer instead of loading the
+// whole table:
+//
+//	users, err := db.Select(db.WithMaxRows(ctx, 1000), q, scanUser, query)
+//	if db.IsTruncated(err) {
+//	    // respond with users and a "more results" marker
+//	}
+//
+// The statement itself is not rewritten; the database may still produce
+// rows the driver has buffered. n <= 0 removes the limit.
+func WithMaxRows(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, maxRowsKey{}, n)
+}
+
+// maxRowsFrom returns the row limit of ctx, or 0 for none.
+func maxRowsFrom(ctx context.Context) int {
+	n, _ := ctx.Value(maxRowsKey{}).(int)
+	return max(n, 0)
+}
+
+func truncatedError(n int) error {
+	return &DBError{Sentinel: ErrTruncated, Message: fmt.Sprintf("result has more than %d rows", n)}
+}
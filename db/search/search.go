@@ -0,0 +1,179 @@
+// Package search builds full-text search predicates for PostgreSQL
+// (tsvector/tsquery) and SQLite (FTS5 MATCH) from raw user input.
+//
+// User input is sanitised down to word characters before it is turned into a
+// query expression, and the expression itself is always bound as a parameter,
+// so it is safe to pass a search box value straight through.
+//
+//	m := search.Match("name,email", r.URL.Query().Get("q"))
+//	if !m.Empty() {
+//	    c := db.NewConditions(d.Dialect().Placeholder())
+//	    where, _ := m.Where(d.Dialect(), c)
+//	    rank, _ := m.Rank(d.Dialect(), c)
+//	    query := "SELECT id, name FROM users WHERE " + where + " ORDER BY " + rank + " DESC"
+//	    rows, err := d.Query(ctx, query, c.Args()...)
+//	}
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Query is a sanitised full-text search over a fixed set of columns.
+type Query struct {
+	columns  []string
+	terms    []string
+	language string
+	table    string
+}
+
+// Match returns a prefix-matching search for input over columns, a
+// comma-separated list written by the developer (never by the user).
+// Every term must match (AND semantics).
+func Match(columns, input string) Query {
+	var cols []string
+	for _, c := range strings.Split(columns, ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			cols = append(cols, c)
+		}
+	}
+	return Query{columns: cols, terms: Terms(input), language: "simple"}
+}
+
+// Language sets the PostgreSQL text search configuration (default "simple").
+// It is validated to contain only word characters.
+func (q Query) Language(cfg string) Query {
+	q.language = cfg
+	return q
+}
+
+// Table sets the FTS5 virtual table the MATCH runs against (SQLite only).
+// The FTS5 table must declare the searched columns in the same order as
+// they were passed to Match.
+func (q Query) Table(fts5Table string) Query {
+	q.table = fts5Table
+	return q
+}
+
+// Empty reports whether sanitisation left no terms to search for. Callers
+// should skip the predicate entirely in that case.
+func (q Query) Empty() bool { return len(q.terms) == 0 }
+
+// Terms splits input into sanitised search terms: runs of letters, digits and
+// underscores. Everything else — operators, quotes, punctuation — is dropped.
+func Terms(input string) []string {
+	return strings.FieldsFunc(input, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// SQL generation
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Where returns the boolean search predicate, binding the query expression
+// through c.
+func (q Query) Where(d db.Dialect, c *db.Conditions) (string, error) {
+	if err := q.validate(d); err != nil {
+		return "", err
+	}
+	switch d {
+	case db.DialectPostgres:
+		return q.vector() + " @@ " + q.tsquery(c), nil
+	default: // SQLite
+		return q.table + " MATCH " + c.Bind(q.fts5Expr()), nil
+	}
+}
+
+// Rank returns an expression suitable for ORDER BY … DESC that puts the best
+// matches first.
+func (q Query) Rank(d db.Dialect, c *db.Conditions) (string, error) {
+	if err := q.validate(d); err != nil {
+		return "", err
+	}
+	switch d {
+	case db.DialectPostgres:
+		return "ts_rank(" + q.vector() + ", " + q.tsquery(c) + ")", nil
+	default:
+		// bm25 is "lower is better"; negate so all dialects sort DESC.
+		return "-bm25(" + q.table + ")", nil
+	}
+}
+
+// Highlight returns an expression yielding column with matched terms wrapped
+// in start/stop markers (e.g. "<b>", "</b>").
+func (q Query) Highlight(d db.Dialect, c *db.Conditions, column, start, stop string) (string, error) {
+	if err := q.validate(d); err != nil {
+		return "", err
+	}
+	idx := -1
+	for i, col := range q.columns {
+		if col == column {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return "", fmt.Errorf("search: column %q is not part of the match", column)
+	}
+	switch d {
+	case db.DialectPostgres:
+		opts := c.Bind("StartSel=" + start + ", StopSel=" + stop)
+		return fmt.Sprintf("ts_headline('%s', %s, %s, %s)", q.language, column, q.tsquery(c), opts), nil
+	default:
+		return fmt.Sprintf("highlight(%s, %d, %s, %s)", q.table, idx, c.Bind(start), c.Bind(stop)), nil
+	}
+}
+
+func (q Query) validate(d db.Dialect) error {
+	if q.Empty() {
+		return fmt.Errorf("search: empty query")
+	}
+	if len(q.columns) == 0 {
+		return fmt.Errorf("search: no columns")
+	}
+	switch d {
+	case db.DialectPostgres:
+		if len(Terms(q.language)) != 1 || Terms(q.language)[0] != q.language {
+			return fmt.Errorf("search: invalid text search configuration %q", q.language)
+		}
+	case db.DialectSQLite:
+		if q.table == "" {
+			return fmt.Errorf("search: SQLite requires an FTS5 table; call Table()")
+		}
+	default:
+		return fmt.Errorf("search: dialect %q not supported", d)
+	}
+	return nil
+}
+
+// vector concatenates the searched columns into a single tsvector.
+func (q Query) vector() string {
+	parts := make([]string, len(q.columns))
+	for i, c := range q.columns {
+		parts[i] = "coalesce(" + c + "::text, '')"
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", q.language, strings.Join(parts, " || ' ' || "))
+}
+
+// tsquery binds "t1:* & t2:*" and wraps it in to_tsquery.
+func (q Query) tsquery(c *db.Conditions) string {
+	parts := make([]string, len(q.terms))
+	for i, t := range q.terms {
+		parts[i] = t + ":*"
+	}
+	return fmt.Sprintf("to_tsquery('%s', %s)", q.language, c.Bind(strings.Join(parts, " & ")))
+}
+
+// fts5Expr renders `{c1 c2} : "t1"* AND "t2"*`. Terms are sanitised, so
+// quoting them cannot break out of the string.
+func (q Query) fts5Expr() string {
+	parts := make([]string, len(q.terms))
+	for i, t := range q.terms {
+		parts[i] = `"` + t + `"*`
+	}
+	return "{" + strings.Join(q.columns, " ") + "} : " + strings.Join(parts, " AND ")
+}
@@ -0,0 +1,68 @@
+package search_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/search"
+)
+
+func TestTerms_Sanitises(t *testing.T) {
+	got := search.Terms(`al'ice & bob:* "OR" 1=1; DROP`)
+	want := []string{"al", "ice", "bob", "OR", "1", "1", "DROP"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMatch_Postgres(t *testing.T) {
+	c := db.NewConditions(db.DollarPlaceholder)
+	m := search.Match("name, email", "ali gmail")
+
+	where, err := m.Where(db.DialectPostgres, c)
+	if err != nil {
+		t.Fatalf("where: %v", err)
+	}
+	want := `to_tsvector('simple', coalesce(name::text, '') || ' ' || coalesce(email::text, '')) @@ to_tsquery('simple', $1)`
+	if where != want {
+		t.Fatalf("where:\n got %s\nwant %s", where, want)
+	}
+	if args := c.Args(); len(args) != 1 || args[0] != "ali:* & gmail:*" {
+		t.Fatalf("unexpected args: %v", args)
+	}
+}
+
+func TestMatch_SQLite(t *testing.T) {
+	c := db.NewConditions(db.DollarPlaceholder)
+	m := search.Match("name,email", `ali "x`).Table("users_fts")
+
+	where, err := m.Where(db.DialectSQLite, c)
+	if err != nil {
+		t.Fatalf("where: %v", err)
+	}
+	if where != "users_fts MATCH $1" {
+		t.Fatalf("unexpected where: %s", where)
+	}
+	if got := c.Args()[0]; got != `{name email} : "ali"* AND "x"*` {
+		t.Fatalf("unexpected expression: %v", got)
+	}
+
+	hl, err := m.Highlight(db.DialectSQLite, c, "email", "<b>", "</b>")
+	if err != nil || hl != "highlight(users_fts, 1, $2, $3)" {
+		t.Fatalf("highlight: %s err=%v", hl, err)
+	}
+}
+
+func TestMatch_Errors(t *testing.T) {
+	c := db.NewConditions(nil)
+	if _, err := search.Match("name", "!!!").Where(db.DialectPostgres, c); err == nil {
+		t.Fatal("expected error for empty query")
+	}
+	if _, err := search.Match("name", "x").Where(db.DialectSQLite, c); err == nil {
+		t.Fatal("expected error for missing FTS5 table")
+	}
+	if _, err := search.Match("name", "x").Language("en'; --").Where(db.DialectPostgres, c); err == nil {
+		t.Fatal("expected error for invalid language")
+	}
+}
@@ -0,0 +1,302 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Get / Select — typed query helpers
+// ─────────────────────────────────────────────────────────────────────────────
+
+// RowScanner is satisfied by *sql.Rows and *Row, so a single scan function can
+// serve Get, Select, and hand-written loops alike.
+type RowScanner interface {
+	Scan(dest ...any) error
+}
+
+// ScanFunc maps the current row onto a T. It must not call Next or Close.
+type ScanFunc[T any] func(RowScanner) (T, error)
+
+// Get runs query and scans the first row with scan. ErrNotFound is returned
+// when the query yields no rows; additional rows are ignored.
+//
+//	u, err := db.Get(ctx, q, scanUser, "SELECT id, name FROM users WHERE id = $1", id)
+func Get[T any](ctx context.Context, q Querier, scan ScanFunc[T], query string, args ...any) (T, error) {
+	var zero T
+	start := time.Now()
+	rows, done, err := queryRows(ctx, q, query, args)
+	if err != nil {
+		return zero, err
+	}
+	defer done()
+
+	if !rows.Next() {
+		if err := rowsErr(q, rows, query, start); err != nil {
+			return zero, err
+		}
+		return zero, &DBError{Sentinel: ErrNotFound, Cause: sql.ErrNoRows}
+	}
+	v, err := scan(rows)
+	if err != nil {
+		return zero, fmt.Errorf("sqltoolkit/db: scan: %w", err)
+	}
+	return v, rowsErr(q, rows, query, start)
+}
+
+// Select runs query and scans every row with scan. An empty result yields a
+// nil slice and no error. Under WithMaxRows it returns at most that many rows.
+//
+//	users, err := db.Select(ctx, q, scanUser, "SELECT id, name FROM users ORDER BY id")
+func Select[T any](ctx context.Context, q Querier, scan ScanFunc[T], query string, args ...any) ([]T, error) {
+	start := time.Now()
+	rows, done, err := queryRows(ctx, q, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	limit := maxRowsFrom(ctx)
+	var out []T
+	for rows.Next() {
+		if limit > 0 && len(out) == limit {
+			return out, truncatedError(limit)
+		}
+		v, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqltoolkit/db: scan: %w", err)
+		}
+		out = append(out, v)
+	}
+	if err := rowsErr(q, rows, query, start); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// rowsErr returns the error that ended the iteration of rows, mapped through
+// q's ErrorMapper and annotated as q's Query does with its own errors.
+func rowsErr(q Querier, rows *sql.Rows, query string, start time.Time) error {
+	err := rows.Err()
+	if m, ok := q.(interface{ mapErr(error) error }); ok {
+		err = m.mapErr(err)
+	}
+	return annotate(err, OpQuery, query, start)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Stream — untyped row streaming
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Stream runs query and calls fn once per row with the column names and the
+// values returned by the driver. Nothing is materialised beyond the current
+// row, so it is suitable for exporting arbitrarily large result sets.
+//
+// vals is reused between calls; copy anything that must outlive fn. Returning
+// an error from fn stops iteration and is returned as-is. With
+// Config.NormalizeBools, boolean columns arrive as Go bools on every driver.
+// Under WithMaxRows, fn sees at most that many rows; under WithMaxRowBytes,
+// oversized values arrive as TruncatedValue.
+func Stream(ctx context.Context, q Querier, query string, args []any, fn func(cols []string, vals []any) error) error {
+	return stream(ctx, q, query, args, nil, fn)
+}
+
+// stream is Stream that first calls head, when set, with the columns and
+// their types, before any row is read.
+func stream(ctx context.Context, q Querier, query string, args []any,
+	head func(cols []string, types []*sql.ColumnType) error, fn func(cols []string, vals []any) error) error {
+	start := time.Now()
+	rows, done, err := queryRows(ctx, q, query, args)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	var types []*sql.ColumnType
+	if head != nil || normalizesBools(q) {
+		if types, err = rows.ColumnTypes(); err != nil {
+			return err
+		}
+	}
+	if head != nil {
+		if err := head(cols, types); err != nil {
+			return err
+		}
+	}
+	var bools []bool
+	if normalizesBools(q) {
+		bools = boolColumns(types)
+	}
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	rowBytes, budget := maxRowBytesFrom(ctx), 0
+	for i := range vals {
+		if rowBytes > 0 {
+			ptrs[i] = cappedValue{dst: &vals[i], budget: &budget}
+		} else {
+			ptrs[i] = &vals[i]
+		}
+	}
+	limit, seen := maxRowsFrom(ctx), 0
+	for rows.Next() {
+		if seen++; limit > 0 && seen > limit {
+			return truncatedError(limit)
+		}
+		budget = rowBytes
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("sqltoolkit/db: scan: %w", err)
+		}
+		for i, isBool := range bools {
+			if isBool && vals[i] != nil {
+				if b, err := ParseBool(vals[i]); err == nil {
+					vals[i] = b
+				}
+			}
+		}
+		if err := fn(cols, vals); err != nil {
+			return err
+		}
+	}
+	return rowsErr(q, rows, query, start)
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// QueryMaps — untyped rows for tooling
+// ─────────────────────────────────────────────────────────────────────────────
+
+// QueryMaps runs query and returns every row as a column → value map, for
+// admin tooling, REPLs and debug endpoints that have no model to scan into.
+// Values are coerced by column type so every driver yields the same shapes:
+// integers as int64, floating point as float64, booleans as bool, text as
+// string and binary as []byte. DECIMAL and NUMERIC stay strings, keeping
+// their exact digits; times are time.Time where the driver parses them and
+// strings otherwise. NULL is nil. Of two columns with the same name the
+// last one wins.
+//
+// Every row is held in memory; use Stream for large results. Under
+// WithMaxRows it returns the first rows and ErrTruncated.
+func QueryMaps(ctx context.Context, q Querier, query string, args ...any) ([]map[string]any, error) {
+	var out []map[string]any
+	var families []ColumnType
+	err := stream(ctx, q, query, args, func(_ []string, types []*sql.ColumnType) error {
+		families = coercionTypes(types)
+		return nil
+	}, func(cols []string, vals []any) error {
+		m := make(map[string]any, len(cols))
+		for i, v := range vals {
+			m[cols[i]] = coerceValue(v, families[i])
+		}
+		out = append(out, m)
+		return nil
+	})
+	return out, err
+}
+
+// StreamValues is Stream with the value shapes of QueryMaps, for exporters
+// that must know a column's type before its first value. head is called
+// once with the result's columns before any row, also when there is none;
+// each column's Type is the family its values are coerced to, so DECIMAL
+// and NUMERIC report TypeText. vals is reused between calls to fn.
+func StreamValues(ctx context.Context, q Querier, query string, args []any,
+	head func(cols []ColumnInfo) error, fn func(vals []any) error) error {
+	var families []ColumnType
+	return stream(ctx, q, query, args, func(_ []string, types []*sql.ColumnType) error {
+		families = coercionTypes(types)
+		cols := columnInfos(types)
+		for i := range cols {
+			cols[i].Type = families[i]
+		}
+		return head(cols)
+	}, func(_ []string, vals []any) error {
+		for i, v := range vals {
+			vals[i] = coerceValue(v, families[i])
+		}
+		return fn(vals)
+	})
+}
+
+// coercionTypes returns the family coerceValue converts each column to;
+// DECIMAL and NUMERIC are treated as text to keep their exact digits.
+func coercionTypes(types []*sql.ColumnType) []ColumnType {
+	families := make([]ColumnType, len(types))
+	for i, ct := range types {
+		name := strings.ToUpper(ct.DatabaseTypeName())
+		families[i] = columnTypeOf(name)
+		if strings.Contains(name, "DECIMAL") || strings.Contains(name, "NUMERIC") {
+			families[i] = TypeText
+		}
+	}
+	return families
+}
+
+// looksLikeText reports whether bytes of a column of unknown type are text:
+// valid UTF-8 without control characters other than whitespace.
+func looksLikeText(b []byte) bool {
+	for _, c := range b {
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' || c == 0x7f {
+			return false
+		}
+	}
+	return utf8.Valid(b)
+}
+
+// coerceValue converts a driver value of a column in family t to the shape
+// QueryMaps promises. Values that do not parse are returned as text.
+func coerceValue(v any, t ColumnType) any {
+	switch x := v.(type) {
+	case []byte:
+		switch t {
+		case TypeBytes:
+			return x
+		case TypeInteger:
+			if n, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+				return n
+			}
+		case TypeFloat:
+			if f, err := strconv.ParseFloat(string(x), 64); err == nil {
+				return f
+			}
+		case TypeBool:
+			if b, err := ParseBool(x); err == nil {
+				return b
+			}
+		case TypeAny:
+			if !looksLikeText(x) {
+				return x
+			}
+		}
+		return string(x)
+	case int64:
+		if t == TypeBool {
+			return x != 0
+		}
+		if t == TypeFloat {
+			return float64(x)
+		}
+	case string:
+		switch t {
+		case TypeInteger:
+			if n, err := strconv.ParseInt(x, 10, 64); err == nil {
+				return n
+			}
+		case TypeFloat:
+			if f, err := strconv.ParseFloat(x, 64); err == nil {
+				return f
+			}
+		}
+	case float32:
+		return float64(x)
+	case int32:
+		return int64(x)
+	}
+	return v
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Session settings — applied to every new connection
+// ─────────────────────────────────────────────────────────────────────────────
+
+// openPool opens the *sql.DB behind cfg, through a connector applying
+// cfg.SessionSettings when there are any.
+func openPool(cfg Config) (*sql.DB, error) {
+	if enforceForeignKeys(cfg) {
+		settings := make(map[string]string, len(cfg.SessionSettings)+1)
+		for k, v := range cfg.SessionSettings {
+			settings[k] = v
+		}
+		settings["foreign_keys"] = "ON"
+		cfg.SessionSettings = settings
+	}
+	if len(cfg.SessionSettings) == 0 {
+		if cfg.Connector != nil {
+			return sql.OpenDB(cfg.Connector), nil
+		}
+		return sql.Open(cfg.DriverName, withAppName(cfg))
+	}
+	stmts, err := sessionStatements(DialectOf(cfg.DriverName), cfg.SessionSettings)
+	if err != nil {
+		return nil, err
+	}
+	inner := cfg.Connector
+	if inner == nil {
+		// sql.Open only looks the driver up; it does not connect.
+		probe, err := sql.Open(cfg.DriverName, withAppName(cfg))
+		if err != nil {
+			return nil, err
+		}
+		drv := probe.Driver()
+		_ = probe.Close()
+		if dc, ok := drv.(driver.DriverContext); ok {
+			if inner, err = dc.OpenConnector(withAppName(cfg)); err != nil {
+				return nil, err
+			}
+		} else {
+			inner = dsnConnector{drv: drv, dsn: withAppName(cfg)}
+		}
+	}
+	return sql.OpenDB(sessionConnector{Connector: inner, stmts: stmts}), nil
+}
+
+// sessionStmt is one setting as a statement and its arguments.
+type sessionStmt struct {
+	query string
+	args  []driver.NamedValue
+}
+
+var (
+	settingNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+	// MySQL settings whose values are inlined rather than bound: a bound
+	// value always arrives as a string, which MySQL rejects for integer
+	// variables such as lock_wait_timeout (error 1232).
+	mysqlNumberRe  = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+	mysqlKeywordRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// sessionStatements turns settings into statements for d, in name order.
+func sessionStatements(d Dialect, settings map[string]string) ([]sessionStmt, error) {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		if !settingNameRe.MatchString(name) {
+			return nil, fmt.Errorf("session setting %q: invalid name", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]sessionStmt, len(names))
+	for i, name := range names {
+		value := settings[name]
+		switch d {
+		case DialectPostgres:
+			out[i] = sessionStmt{`SELECT set_config($1, $2, false)`,
+				[]driver.NamedValue{{Ordinal: 1, Value: name}, {Ordinal: 2, Value: value}}}
+		case DialectMySQL:
+			// Numbers and keywords (ON, DEFAULT, utf8mb4) are inlined; they
+			// match patterns that cannot carry anything else. Other values
+			// are bound as strings.
+			if mysqlNumberRe.MatchString(value) || mysqlKeywordRe.MatchString(value) {
+				out[i] = sessionStmt{query: "SET SESSION " + name + " = " + value}
+			} else {
+				out[i] = sessionStmt{"SET SESSION " + name + " = ?", []driver.NamedValue{{Ordinal: 1, Value: value}}}
+			}
+		case DialectSQLite:
+			// PRAGMA takes no bind parameters.
+			out[i] = sessionStmt{query: "PRAGMA " + name + " = '" + strings.ReplaceAll(value, "'", "''") + "'"}
+		default:
+			return nil, fmt.Errorf("session settings: not supported for driver dialect %q", d)
+		}
+	}
+	return out, nil
+}
+
+// sessionConnector runs stmts on every connection it makes.
+type sessionConnector struct {
+	driver.Connector
+	stmts []sessionStmt
+}
+
+func (c sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range c.stmts {
+		if err := execConn(ctx, conn, s.query, s.args); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("session setting: %s: %w", s.query, err)
+		}
+	}
+	return conn, nil
+}
+
+// execConn executes query on a raw driver connection.
+func execConn(ctx context.Context, conn driver.Conn, query string, args []driver.NamedValue) error {
+	if ex, ok := conn.(driver.ExecerContext); ok {
+		if _, err := ex.ExecContext(ctx, query, args); err != driver.ErrSkip {
+			return err
+		}
+	}
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	values := make([]driver.Value, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	_, err = stmt.Exec(values)
+	return err
+}
+
+// dsnConnector adapts a driver without DriverContext to driver.Connector.
+type dsnConnector struct {
+	drv driver.Driver
+	dsn string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+// enforceForeignKeys reports whether Open turns SQLite's foreign_keys on.
+func enforceForeignKeys(cfg Config) bool {
+	if DialectOf(cfg.DriverName) != DialectSQLite || cfg.DisableSQLiteForeignKeys {
+		return false
+	}
+	_, explicit := cfg.SessionSettings["foreign_keys"]
+	return !explicit
+}
+
+// verifyForeignKeys checks that the foreign_keys setting enforceForeignKeys
+// asked for took effect; SQLite silently ignores it when built without
+// foreign key support.
+func (d *DB) verifyForeignKeys(ctx context.Context) error {
+	if !enforceForeignKeys(d.cfg) {
+		return nil
+	}
+	var on int
+	if err := d.sqldb.QueryRowContext(ctx, `PRAGMA foreign_keys`).Scan(&on); err != nil {
+		return fmt.Errorf("foreign keys: %w", err)
+	}
+	if on != 1 {
+		return fmt.Errorf("foreign keys: PRAGMA foreign_keys = ON did not take effect; " +
+			"the SQLite build may omit foreign key support (set DisableSQLiteForeignKeys to open anyway)")
+	}
+	return nil
+}
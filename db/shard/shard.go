@@ -0,0 +1,173 @@
+// Package shard routes statements to one of several databases by a shard
+// key, and reads across all of them.
+//
+// A Router holds one pool per shard. The key extractor turns an application
+// key — a tenant, a user id — into a 64-bit hash, which jump consistent
+// hashing maps to a shard: adding a shard at the end moves only 1/n of the
+// keys, and the rest stay where they are.
+//
+//	users, err := shard.Open(shard.Int64Key, []shard.Config{
+//	    {Name: "users-0", DB: db.Config{DriverName: "postgres", DSN: dsn0}},
+//	    {Name: "users-1", DB: db.Config{DriverName: "postgres", DSN: dsn1}},
+//	})
+//	q, err := users.Querier(ctx, userID)
+//	u, err := repo.NewUserRepo(q).GetByID(ctx, userID)
+//
+//	// Cross-shard read: rows from every shard, plus the shards that failed.
+//	recent, err := shard.Query(ctx, users, scanUser, `SELECT ... WHERE created_at > $1`, since)
+//
+// The shard order is part of the routing: never reorder or remove shards
+// without migrating their rows.
+package shard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Config describes one shard.
+type Config struct {
+	// Name identifies the shard in errors and logs.
+	Name string
+	// DB opens the shard's pool.
+	DB db.Config
+}
+
+// StringKey hashes a string shard key with FNV-1a.
+func StringKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// Int64Key hashes an integer shard key, spreading sequential ids evenly.
+func Int64Key(key int64) uint64 {
+	// splitmix64 finaliser
+	x := uint64(key) + 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+// Router maps keys of type K to shards. It is safe for concurrent use.
+type Router[K any] struct {
+	key    func(K) uint64
+	names  []string
+	shards []*db.DB
+}
+
+// Open opens every shard and returns a Router hashing keys with key. If any
+// shard fails to open, the ones already opened are closed again.
+func Open[K any](key func(K) uint64, shards []Config) (*Router[K], error) {
+	if len(shards) == 0 {
+		return nil, errors.New("shard: no shards configured")
+	}
+	r := &Router[K]{key: key}
+	for _, s := range shards {
+		d, err := db.Open(s.DB)
+		if err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("shard: open %s: %w", s.Name, err)
+		}
+		r.names = append(r.names, s.Name)
+		r.shards = append(r.shards, d)
+	}
+	return r, nil
+}
+
+// Index returns the position of key's shard.
+func (r *Router[K]) Index(key K) int { return jumpHash(r.key(key), len(r.shards)) }
+
+// For returns the pool of key's shard.
+func (r *Router[K]) For(key K) *db.DB { return r.shards[r.Index(key)] }
+
+// Querier returns key's shard as a Querier, or ctx's error once it is done.
+func (r *Router[K]) Querier(ctx context.Context, key K) (db.Querier, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.For(key), nil
+}
+
+// Shards returns the shard names and pools in configuration order.
+func (r *Router[K]) Shards() ([]string, []*db.DB) {
+	return append([]string(nil), r.names...), append([]*db.DB(nil), r.shards...)
+}
+
+// Close closes every shard.
+func (r *Router[K]) Close() error {
+	var errs []error
+	for _, d := range r.shards {
+		errs = append(errs, d.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// jumpHash is Lamping and Veach's jump consistent hash.
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64(key>>33+1)))
+	}
+	return int(b)
+}
+
+// ── Fan-out ──────────────────────────────────────────────────────────────────
+
+// Error reports the shards a fan-out read could not get rows from, by name.
+type Error struct {
+	Failed map[string]error
+}
+
+func (e *Error) Error() string {
+	names := make([]string, 0, len(e.Failed))
+	for name := range e.Failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s: %v", name, e.Failed[name])
+	}
+	return fmt.Sprintf("shard: %d of the shards failed: %s", len(names), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the per-shard errors, so errors.Is sees through them.
+func (e *Error) Unwrap() []error {
+	out := make([]error, 0, len(e.Failed))
+	for _, err := range e.Failed {
+		out = append(out, err)
+	}
+	return out
+}
+
+// Query runs query on every shard concurrently (see db.FanOut) and
+// concatenates the rows in shard order. When some shards fail, the rows of
+// the others are returned together with an *Error naming the failed ones, so
+// callers decide whether a partial answer is acceptable.
+func Query[K, T any](ctx context.Context, r *Router[K], scan db.ScanFunc[T], query string, args ...any) ([]T, error) {
+	qs := make([]db.Querier, len(r.shards))
+	for i, d := range r.shards {
+		qs[i] = d
+	}
+	out, err := db.FanOut(ctx, qs, query, args, scan, len(qs))
+	var fe *db.FanOutError
+	if !errors.As(err, &fe) {
+		return out, err
+	}
+	failed := map[string]error{}
+	for i, err := range fe.Errs {
+		if err != nil {
+			failed[r.names[i]] = err
+		}
+	}
+	return out, &Error{Failed: failed}
+}
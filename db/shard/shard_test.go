@@ -0,0 +1,71 @@
+package shard_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/shard"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRouter_RouteAndQuery(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	var shards []shard.Config
+	for _, name := range []string{"s0", "s1", "s2"} {
+		shards = append(shards, shard.Config{Name: name, DB: db.Config{
+			DriverName: "sqlite3", DSN: filepath.Join(dir, name+".db"),
+		}})
+	}
+	r, err := shard.Open(shard.Int64Key, shards)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer r.Close()
+
+	_, pools := r.Shards()
+	for _, d := range pools {
+		if _, err := d.Exec(ctx, `CREATE TABLE items (id INTEGER PRIMARY KEY)`); err != nil {
+			t.Fatal(err)
+		}
+	}
+	used := map[int]bool{}
+	for id := int64(1); id <= 30; id++ {
+		q, err := r.Querier(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := q.Exec(ctx, `INSERT INTO items (id) VALUES ($1)`, id); err != nil {
+			t.Fatal(err)
+		}
+		used[r.Index(id)] = true
+	}
+	if len(used) != 3 {
+		t.Fatalf("30 keys should reach all 3 shards, reached %v", used)
+	}
+
+	scanID := func(s db.RowScanner) (int64, error) {
+		var id int64
+		return id, s.Scan(&id)
+	}
+	ids, err := shard.Query(ctx, r, scanID, `SELECT id FROM items`)
+	if err != nil || len(ids) != 30 {
+		t.Fatalf("fan-out: got %d rows, %v", len(ids), err)
+	}
+
+	// One shard missing the table: the others still answer.
+	if _, err := pools[1].Exec(ctx, `DROP TABLE items`); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = shard.Query(ctx, r, scanID, `SELECT id FROM items`)
+	var se *shard.Error
+	if !errors.As(err, &se) || len(se.Failed) != 1 || se.Failed["s1"] == nil {
+		t.Fatalf("expected s1 to be reported, got %v", err)
+	}
+	if len(ids) == 0 || len(ids) >= 30 {
+		t.Fatalf("expected the rows of the healthy shards, got %d", len(ids))
+	}
+}
@@ -0,0 +1,230 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Struct scanning — columns to fields, with pluggable type converters
+// ─────────────────────────────────────────────────────────────────────────────
+
+var scanConverters sync.Map // reflect.Type → func(any) (any, error)
+
+// RegisterScanner teaches the struct-scanning helpers (SelectStructs,
+// GetStruct) to fill fields of type t — and *t, which stays nil for NULL —
+// from the raw driver value, for types that do not implement sql.Scanner
+// themselves:
+//
+//	db.RegisterScanner(reflect.TypeFor[netip.Addr](), func(src any) (any, error) {
+//	    s, err := db.AsString(src)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return netip.ParseAddr(s)
+//	})
+//
+// conv receives a non-nil value as returned by the driver (int64, float64,
+// bool, []byte, string or time.Time) and must return a value assignable or
+// convertible to t. Registering t again replaces its converter. Types that
+// implement sql.Scanner use their own Scan and ignore the registry.
+func RegisterScanner(t reflect.Type, conv func(src any) (any, error)) {
+	scanConverters.Store(t, conv)
+}
+
+// AsString returns src as a string when the driver produced text.
+func AsString(src any) (string, error) {
+	switch v := src.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	}
+	return "", fmt.Errorf("sqltoolkit/db: expected text, got %T", src)
+}
+
+var scannerType = reflect.TypeFor[sql.Scanner]()
+
+// converterFor returns the registered converter for a field type, if the
+// type is not a sql.Scanner itself.
+func converterFor(t reflect.Type) (func(any) (any, error), bool) {
+	if reflect.PointerTo(t).Implements(scannerType) {
+		return nil, false
+	}
+	conv, ok := scanConverters.Load(t)
+	if !ok {
+		return nil, false
+	}
+	return conv.(func(any) (any, error)), true
+}
+
+// convertedField scans a column through a registered converter.
+type convertedField struct {
+	field reflect.Value // addressable field
+	conv  func(any) (any, error)
+}
+
+func (c *convertedField) Scan(src any) error {
+	ft := c.field.Type()
+	if src == nil {
+		c.field.Set(reflect.Zero(ft))
+		return nil
+	}
+	elem := ft
+	if ft.Kind() == reflect.Pointer {
+		elem = ft.Elem()
+	}
+	v, err := c.conv(src)
+	if err != nil {
+		return fmt.Errorf("convert %T to %s: %w", src, elem, err)
+	}
+	rv := reflect.ValueOf(v)
+	switch {
+	case !rv.IsValid():
+		return fmt.Errorf("convert %T to %s: converter returned nil", src, elem)
+	case rv.Type().AssignableTo(elem):
+	case rv.Type().ConvertibleTo(elem):
+		rv = rv.Convert(elem)
+	default:
+		return fmt.Errorf("convert %T to %s: converter returned %s", src, elem, rv.Type())
+	}
+	if ft.Kind() == reflect.Pointer {
+		p := reflect.New(elem)
+		p.Elem().Set(rv)
+		rv = p
+	}
+	c.field.Set(rv)
+	return nil
+}
+
+// structDest maps result columns onto the fields of T (a struct or a
+// pointer to one) by the column rules of Diff.
+type structDest[T any] struct {
+	index [][]int
+}
+
+func newStructDest[T any](cols []string) (*structDest[T], error) {
+	st := reflect.TypeFor[T]()
+	if st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("sqltoolkit/db: %s is not a struct", reflect.TypeFor[T]())
+	}
+	byColumn := map[string][]int{}
+	for _, f := range diffFields(st) {
+		byColumn[f.column] = f.index
+	}
+	d := &structDest[T]{index: make([][]int, len(cols))}
+	for i, c := range cols {
+		idx, ok := byColumn[c]
+		if !ok {
+			return nil, fmt.Errorf("sqltoolkit/db: column %q has no field in %s", c, st)
+		}
+		d.index[i] = idx
+	}
+	return d, nil
+}
+
+func (d *structDest[T]) scan(rows *sql.Rows) (T, error) {
+	var out T
+	sv := reflect.ValueOf(&out).Elem()
+	if sv.Kind() == reflect.Pointer {
+		sv.Set(reflect.New(sv.Type().Elem()))
+		sv = sv.Elem()
+	}
+	dest := make([]any, len(d.index))
+	for i, idx := range d.index {
+		f := sv.FieldByIndex(idx)
+		ft := f.Type()
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if conv, ok := converterFor(ft); ok {
+			dest[i] = &convertedField{field: f, conv: conv}
+			continue
+		}
+		dest[i] = f.Addr().Interface()
+	}
+	err := rows.Scan(dest...)
+	return out, err
+}
+
+// SelectStructs runs query and scans every row into a T — a struct or a
+// pointer to one — matching columns to fields by name as Diff does (`db`
+// tag, else snake_case). Every column needs a field; fields without a column
+// keep their zero value. Field types without native database/sql support are
+// filled by converters added with RegisterScanner. WithMaxRows applies as
+// in Select.
+//
+//	users, err := db.SelectStructs[*models.User](ctx, q,
+//	    `SELECT id, name, email, created_at, updated_at FROM users`)
+func SelectStructs[T any](ctx context.Context, q Querier, query string, args ...any) ([]T, error) {
+	start := time.Now()
+	rows, done, err := queryRows(ctx, q, query, args)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	dest, err := newStructDest[T](cols)
+	if err != nil {
+		return nil, err
+	}
+	limit := maxRowsFrom(ctx)
+	var out []T
+	for rows.Next() {
+		if limit > 0 && len(out) == limit {
+			return out, truncatedError(limit)
+		}
+		v, err := dest.scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("sqltoolkit/db: scan: %w", err)
+		}
+		out = append(out, v)
+	}
+	if err := rowsErr(q, rows, query, start); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetStruct is SelectStructs for a single row: it returns the first row, or
+// ErrNotFound when there is none.
+func GetStruct[T any](ctx context.Context, q Querier, query string, args ...any) (T, error) {
+	var zero T
+	start := time.Now()
+	rows, done, err := queryRows(ctx, q, query, args)
+	if err != nil {
+		return zero, err
+	}
+	defer done()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return zero, err
+	}
+	dest, err := newStructDest[T](cols)
+	if err != nil {
+		return zero, err
+	}
+	if !rows.Next() {
+		if err := rowsErr(q, rows, query, start); err != nil {
+			return zero, err
+		}
+		return zero, &DBError{Sentinel: ErrNotFound, Cause: sql.ErrNoRows}
+	}
+	v, err := dest.scan(rows)
+	if err != nil {
+		return zero, fmt.Errorf("sqltoolkit/db: scan: %w", err)
+	}
+	return v, rowsErr(q, rows, query, start)
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Table — keyed CRUD helpers with single or composite primary keys
+// ─────────────────────────────────────────────────────────────────────────────
+
+// TableSpec describes a table for NewTable.
+type TableSpec[T any] struct {
+	// Name is the table name.
+	Name string
+	// Columns are selected in this order and passed to Scan.
+	Columns []string
+	// Key lists the primary key columns. Defaults to {"id"}.
+	Key []string
+	// Scan maps a row of Columns to a T.
+	Scan ScanFunc[T]
+}
+
+// Table runs the keyed statements every repository writes by hand — get,
+// update and delete by primary key — for keys of any shape. K is either a
+// scalar, for a single key column, or a struct whose fields map to the key
+// columns by the same rules as Diff (`db` tag or snake_case name), which
+// covers join tables and tenant-scoped keys:
+//
+//	type MembershipKey struct{ TenantID, UserID int64 }
+//	memberships := db.MustTable[*Membership, MembershipKey](db.TableSpec[*Membership]{
+//	    Name:    "memberships",
+//	    Columns: []string{"tenant_id", "user_id", "role"},
+//	    Key:     []string{"tenant_id", "user_id"},
+//	    Scan:    scanMembership,
+//	})
+//	m, err := memberships.Get(ctx, q, MembershipKey{TenantID: 7, UserID: 42})
+//
+// Identifiers are quoted for the dialect of the Querier; values are always
+// bound. A Table holds no connection and is safe for concurrent use.
+type Table[T any, K any] struct {
+	spec     TableSpec[T]
+	keyArgs  func(K) []any
+	writable map[string]string // column → column, for PatchSet
+}
+
+// NewTable validates spec against the key type K.
+func NewTable[T any, K any](spec TableSpec[T]) (*Table[T, K], error) {
+	if spec.Name == "" || len(spec.Columns) == 0 || spec.Scan == nil {
+		return nil, fmt.Errorf("sqltoolkit/db: NewTable: Name, Columns and Scan are required")
+	}
+	if len(spec.Key) == 0 {
+		spec.Key = []string{"id"}
+	}
+	keyArgs, err := keyExtractor[K](spec.Key)
+	if err != nil {
+		return nil, fmt.Errorf("sqltoolkit/db: NewTable %s: %w", spec.Name, err)
+	}
+	t := &Table[T, K]{spec: spec, keyArgs: keyArgs, writable: map[string]string{}}
+	isKey := map[string]bool{}
+	for _, k := range spec.Key {
+		isKey[k] = true
+	}
+	for _, c := range spec.Columns {
+		if !isKey[c] {
+			t.writable[c] = c
+		}
+	}
+	return t, nil
+}
+
+// MustTable is NewTable that panics on error, for package-level variables.
+func MustTable[T any, K any](spec TableSpec[T]) *Table[T, K] {
+	t, err := NewTable[T, K](spec)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// keyExtractor returns a function listing the values of K in key order.
+func keyExtractor[K any](key []string) (func(K) []any, error) {
+	kt := reflect.TypeFor[K]()
+	if kt.Kind() != reflect.Struct || kt == timeType {
+		if len(key) != 1 {
+			return nil, fmt.Errorf("key type %s cannot hold the %d key columns %v", kt, len(key), key)
+		}
+		return func(k K) []any { return []any{k} }, nil
+	}
+	byColumn := map[string][]int{}
+	for _, f := range diffFields(kt) {
+		byColumn[f.column] = f.index
+	}
+	index := make([][]int, len(key))
+	for i, col := range key {
+		idx, ok := byColumn[col]
+		if !ok {
+			return nil, fmt.Errorf("key type %s has no field for column %q", kt, col)
+		}
+		index[i] = idx
+	}
+	return func(k K) []any {
+		v := reflect.ValueOf(k)
+		args := make([]any, len(index))
+		for i, idx := range index {
+			args[i] = v.FieldByIndex(idx).Interface()
+		}
+		return args
+	}, nil
+}
+
+// where renders the key predicate with placeholders from $start.
+func (t *Table[T, K]) where(d Dialect, start int) string {
+	preds := make([]string, len(t.spec.Key))
+	for i, col := range t.spec.Key {
+		preds[i] = fmt.Sprintf("%s = $%d", d.QuoteIdent(col), start+i)
+	}
+	return strings.Join(preds, " AND ")
+}
+
+func (t *Table[T, K]) columns(d Dialect) string {
+	cols := make([]string, len(t.spec.Columns))
+	for i, c := range t.spec.Columns {
+		cols[i] = d.QuoteIdent(c)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// Get returns the row with key, or ErrNotFound.
+func (t *Table[T, K]) Get(ctx context.Context, q Querier, key K) (T, error) {
+	d := DialectFrom(q)
+	query, args := d.Rebind(fmt.Sprintf("SELECT %s FROM %s WHERE %s",
+		t.columns(d), d.QuoteIdent(t.spec.Name), t.where(d, 1)), t.keyArgs(key))
+	return Get(ctx, q, t.spec.Scan, query, args...)
+}
+
+// Update sets the given columns of the row with key and returns ErrNotFound
+// when no row has it. Columns are checked as in PatchSet against the
+// non-key columns of the table, so a key can never be rewritten here.
+func (t *Table[T, K]) Update(ctx context.Context, q Querier, key K, changes map[string]any) error {
+	d := DialectFrom(q)
+	set, args, err := PatchSet(d, t.writable, changes)
+	if err != nil || set == "" {
+		return err
+	}
+	query, args := d.Rebind(fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		d.QuoteIdent(t.spec.Name), set, t.where(d, len(args)+1)), append(args, t.keyArgs(key)...))
+	err = t.expectOne(ctx, q, query, args)
+	if d == DialectMySQL && IsNotFound(err) {
+		// MySQL counts changed rows, not matched ones; look the row up.
+		query, args := d.Rebind(fmt.Sprintf("SELECT 1 FROM %s WHERE %s",
+			d.QuoteIdent(t.spec.Name), t.where(d, 1)), t.keyArgs(key))
+		var one int
+		return q.QueryRow(ctx, query, args...).Scan(&one)
+	}
+	return err
+}
+
+// Delete removes the row with key and returns ErrNotFound when no row has it.
+func (t *Table[T, K]) Delete(ctx context.Context, q Querier, key K) error {
+	d := DialectFrom(q)
+	query, args := d.Rebind(fmt.Sprintf("DELETE FROM %s WHERE %s",
+		d.QuoteIdent(t.spec.Name), t.where(d, 1)), t.keyArgs(key))
+	return t.expectOne(ctx, q, query, args)
+}
+
+func (t *Table[T, K]) expectOne(ctx context.Context, q Querier, query string, args []any) error {
+	res, err := q.Exec(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// TableStats — size, churn and planner statistics of one table
+// ─────────────────────────────────────────────────────────────────────────────
+
+// TableStat describes one table as the database sees it. Fields a dialect
+// does not report are zero: dead rows and vacuum times are PostgreSQL's,
+// free space is MySQL's, and SQLite reports little beyond an exact count.
+type TableStat struct {
+	Table string `json:"table"`
+	// Rows is the planner's estimate on PostgreSQL and MySQL, an exact
+	// count on SQLite.
+	Rows      int64 `json:"rows"`
+	Estimated bool  `json:"estimated"`
+	// DeadRows are deleted or updated row versions not yet vacuumed;
+	// DeadRatio is their share of live plus dead rows.
+	DeadRows  int64   `json:"dead_rows"`
+	DeadRatio float64 `json:"dead_ratio"`
+	// ModifiedSinceAnalyze counts rows written since statistics were last
+	// gathered; -1 when the database does not track it.
+	ModifiedSinceAnalyze int64 `json:"modified_since_analyze"`
+	// Analyzed is false when the table has no planner statistics at all.
+	Analyzed    bool      `json:"analyzed"`
+	LastVacuum  time.Time `json:"last_vacuum"`
+	LastAnalyze time.Time `json:"last_analyze"`
+	DataBytes   int64     `json:"data_bytes"`
+	IndexBytes  int64     `json:"index_bytes"`
+	// FreeBytes is allocated but unused space; Fragmentation is its share
+	// of the table's total size.
+	FreeBytes     int64   `json:"free_bytes"`
+	Fragmentation float64 `json:"fragmentation"`
+}
+
+// TableStats reads the statistics of table. A table that does not exist
+// fails with ErrNotFound on PostgreSQL and MySQL.
+func (d *DB) TableStats(ctx context.Context, table string) (TableStat, error) {
+	s := TableStat{Table: table, ModifiedSinceAnalyze: -1}
+	var err error
+	switch dialect := d.Dialect(); dialect {
+	case DialectPostgres:
+		err = d.pgTableStats(ctx, &s)
+	case DialectMySQL:
+		err = d.mysqlTableStats(ctx, &s)
+	default:
+		err = d.QueryRow(ctx, "SELECT COUNT(*) FROM "+dialect.QuoteIdent(table)).Scan(&s.Rows)
+		if err == nil && dialect == DialectSQLite {
+			// sqlite_stat1 only exists once ANALYZE ran somewhere.
+			var n int
+			s.Analyzed = d.QueryRow(ctx, `SELECT COUNT(*) FROM sqlite_stat1 WHERE tbl = ?`, table).Scan(&n) == nil && n > 0
+		}
+	}
+	if err != nil {
+		return s, err
+	}
+	if total := s.Rows + s.DeadRows; total > 0 {
+		s.DeadRatio = float64(s.DeadRows) / float64(total)
+	}
+	if total := s.DataBytes + s.IndexBytes + s.FreeBytes; total > 0 {
+		s.Fragmentation = float64(s.FreeBytes) / float64(total)
+	}
+	return s, nil
+}
+
+func (d *DB) pgTableStats(ctx context.Context, s *TableStat) error {
+	var estimate, live int64
+	var vacuumed, analyzed sql.NullTime
+	err := d.QueryRow(ctx, `
+		SELECT c.reltuples::bigint, COALESCE(t.n_live_tup, 0), COALESCE(t.n_dead_tup, 0),
+		       COALESCE(t.n_mod_since_analyze, 0),
+		       GREATEST(t.last_vacuum, t.last_autovacuum), GREATEST(t.last_analyze, t.last_autoanalyze),
+		       pg_table_size(c.oid), pg_indexes_size(c.oid)
+		FROM   pg_class c
+		LEFT   JOIN pg_stat_user_tables t ON t.relid = c.oid
+		WHERE  c.oid = to_regclass($1)`, DialectPostgres.QuoteIdent(s.Table)).
+		Scan(&estimate, &live, &s.DeadRows, &s.ModifiedSinceAnalyze, &vacuumed, &analyzed, &s.DataBytes, &s.IndexBytes)
+	if err != nil {
+		return err
+	}
+	// reltuples is -1 until the first ANALYZE or VACUUM (PostgreSQL 14+).
+	s.Rows, s.Estimated, s.Analyzed = estimate, true, estimate >= 0 || analyzed.Valid
+	if estimate < 0 {
+		s.Rows = live
+	}
+	s.LastVacuum, s.LastAnalyze = vacuumed.Time, analyzed.Time
+	return nil
+}
+
+func (d *DB) mysqlTableStats(ctx context.Context, s *TableStat) error {
+	err := d.QueryRow(ctx, `
+		SELECT COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0), COALESCE(INDEX_LENGTH, 0), COALESCE(DATA_FREE, 0)
+		FROM   information_schema.TABLES
+		WHERE  TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`, s.Table).
+		Scan(&s.Rows, &s.DataBytes, &s.IndexBytes, &s.FreeBytes)
+	if err != nil {
+		return err
+	}
+	s.Estimated = true
+	// InnoDB's persistent statistics; the table needs privileges on the
+	// mysql schema, so without them the statistics are assumed present.
+	var updated int64
+	err = d.QueryRow(ctx, `
+		SELECT UNIX_TIMESTAMP(last_update) FROM mysql.innodb_table_stats
+		WHERE  database_name = DATABASE() AND table_name = ?`, s.Table).Scan(&updated)
+	switch {
+	case err == nil:
+		s.Analyzed, s.LastAnalyze = true, time.Unix(updated, 0)
+	case IsNotFound(err):
+		s.Analyzed = false
+	default:
+		s.Analyzed = true
+	}
+	return nil
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ANALYZE advisor — refresh planner statistics before batch jobs
+// ─────────────────────────────────────────────────────────────────────────────
+
+// AnalyzePolicy decides when a table's planner statistics are stale.
+type AnalyzePolicy struct {
+	// MaxModifiedRatio is the share of rows modified since the last ANALYZE
+	// above which statistics are stale. Defaults to 0.1, autovacuum's own
+	// threshold; it applies where ModifiedSinceAnalyze is tracked.
+	MaxModifiedRatio float64
+	// MaxAge makes statistics older than it stale. Zero ignores their age.
+	MaxAge time.Duration
+}
+
+// NeedsAnalyze reports whether s's statistics are stale under p at now, and
+// why.
+func (s TableStat) NeedsAnalyze(p AnalyzePolicy, now time.Time) (bool, string) {
+	if p.MaxModifiedRatio <= 0 {
+		p.MaxModifiedRatio = 0.1
+	}
+	if !s.Analyzed {
+		if s.Rows == 0 && s.ModifiedSinceAnalyze <= 0 {
+			return false, ""
+		}
+		return true, "never analyzed"
+	}
+	if s.ModifiedSinceAnalyze > 0 {
+		if ratio := float64(s.ModifiedSinceAnalyze) / float64(max(s.Rows, 1)); ratio > p.MaxModifiedRatio {
+			return true, fmt.Sprintf("%d rows (%.0f%%) modified since last analyze", s.ModifiedSinceAnalyze, 100*ratio)
+		}
+	}
+	if p.MaxAge > 0 && !s.LastAnalyze.IsZero() {
+		if age := now.Sub(s.LastAnalyze); age > p.MaxAge {
+			return true, fmt.Sprintf("last analyzed %s ago", age.Round(time.Second))
+		}
+	}
+	return false, ""
+}
+
+// AnalyzeIfStale runs ANALYZE on table when its statistics are stale under
+// p and reports whether it did. Call it before a batch job whose queries
+// would otherwise be planned for a table that has since grown or changed:
+//
+//	if _, err := conn.AnalyzeIfStale(ctx, "orders", db.AnalyzePolicy{MaxAge: 24 * time.Hour}); err != nil {
+//	    return err
+//	}
+//	err := db.BatchExec(conn, ctx, insertSQL, rows, argsFn)
+func (d *DB) AnalyzeIfStale(ctx context.Context, table string, p AnalyzePolicy) (bool, error) {
+	s, err := d.TableStats(ctx, table)
+	if err != nil {
+		return false, err
+	}
+	stale, reason := s.NeedsAnalyze(p, d.Now())
+	if !stale {
+		return false, nil
+	}
+	dialect := d.Dialect()
+	stmt := "ANALYZE " + dialect.QuoteIdent(table)
+	if dialect == DialectMySQL {
+		stmt = "ANALYZE TABLE " + dialect.QuoteIdent(table)
+	}
+	if _, err := d.Exec(ctx, stmt); err != nil {
+		return false, fmt.Errorf("analyze %s (%s): %w", table, reason, err)
+	}
+	return true, nil
+}
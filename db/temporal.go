@@ -0,0 +1,52 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Temporal tables — reading system-versioned rows as of a point in time
+// ─────────────────────────────────────────────────────────────────────────────
+
+// TemporalTable describes a system-versioned table: native on MariaDB
+// (WITH SYSTEM VERSIONING), emulated on PostgreSQL with the layout of the
+// temporal_tables extension — a tstzrange period column on the table and a
+// history table of the same shape filled by its versioning trigger.
+type TemporalTable struct {
+	// Name is the table name.
+	Name string
+	// History is the PostgreSQL history table. Defaults to Name + "_history".
+	History string
+	// Period is the PostgreSQL tstzrange column. Defaults to "sys_period".
+	Period string
+}
+
+// AsOf returns a FROM-clause source yielding the rows of the table as they
+// were at the timestamp bound to $n, aliased to the table name so column
+// references need no change:
+//
+//	src, err := users.AsOf(db.DialectFrom(q), 2)
+//	query := "SELECT id, name FROM " + src + " WHERE id = $1"
+//	row := q.QueryRow(ctx, query, id, at) // Rebind for MySQL
+//
+// Dialects without system versioning return an error wrapping
+// errors.ErrUnsupported.
+func (t TemporalTable) AsOf(d Dialect, n int) (string, error) {
+	switch d {
+	case DialectMySQL:
+		return fmt.Sprintf("%s FOR SYSTEM_TIME AS OF TIMESTAMP $%d", d.QuoteIdent(t.Name), n), nil
+	case DialectPostgres:
+		history, period := t.History, t.Period
+		if history == "" {
+			history = t.Name + "_history"
+		}
+		if period == "" {
+			period = "sys_period"
+		}
+		return fmt.Sprintf("(SELECT * FROM %[1]s WHERE %[3]s @> $%[4]d::timestamptz"+
+			" UNION ALL SELECT * FROM %[2]s WHERE %[3]s @> $%[4]d::timestamptz) AS %[1]s",
+			d.QuoteIdent(t.Name), d.QuoteIdent(history), d.QuoteIdent(period), n), nil
+	}
+	return "", fmt.Errorf("sqltoolkit/db: system-versioned table %s on %q: %w", t.Name, d, errors.ErrUnsupported)
+}
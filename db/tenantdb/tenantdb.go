@@ -0,0 +1,278 @@
+// Package tenantdb routes database-per-tenant applications to the right
+// pool.
+//
+// A Manager resolves a tenant ID to a db.Config — from a catalog table or
+// any callback — opens the pool on first use and keeps at most MaxOpen pools
+// alive, evicting the least recently used one when another tenant needs a
+// slot. Opening is shared: concurrent first requests for a tenant wait for a
+// single db.Open.
+//
+//	tenants := tenantdb.New(tenantdb.Catalog(control,
+//	    `SELECT dsn FROM tenants WHERE id = $1`,
+//	    db.Config{DriverName: "postgres", MaxOpenConns: 5},
+//	), tenantdb.Options{MaxOpen: 50})
+//	defer tenants.Close()
+//
+//	q, err := tenants.For(r.Context(), tenantID) // leased until the request ends
+//	users := repo.NewUserRepo(q)
+//
+// Every lookup takes a lease on the pool, and an evicted pool is closed only
+// once its last lease is released, so requests in flight never see it
+// closed. Fetch the Querier per request rather than holding on to it.
+package tenantdb
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ErrUnknownTenant is returned when the resolver has no database for a
+// tenant.
+var ErrUnknownTenant = errors.New("tenantdb: unknown tenant")
+
+var errManagerClosed = errors.New("tenantdb: manager closed")
+
+// Resolver returns the connection settings of a tenant's database, or an
+// error wrapping ErrUnknownTenant.
+type Resolver func(ctx context.Context, tenantID string) (db.Config, error)
+
+// Catalog returns a Resolver reading DSNs from a catalog database. query
+// takes the tenant ID as $1 and returns one DSN column; the other settings
+// come from base. A tenant without a row is ErrUnknownTenant.
+func Catalog(catalog db.Querier, query string, base db.Config) Resolver {
+	return func(ctx context.Context, tenantID string) (db.Config, error) {
+		q, args := db.DialectFrom(catalog).Rebind(query, []any{tenantID})
+		var dsn string
+		if err := catalog.QueryRow(ctx, q, args...).Scan(&dsn); err != nil {
+			if db.IsNotFound(err) {
+				return db.Config{}, fmt.Errorf("%w: %s", ErrUnknownTenant, tenantID)
+			}
+			return db.Config{}, err
+		}
+		cfg := base
+		cfg.DSN = dsn
+		return cfg, nil
+	}
+}
+
+// Options configures a Manager. Every field is optional.
+type Options struct {
+	// MaxOpen bounds the pools kept open at once. Defaults to 32.
+	MaxOpen int
+	// OnEvict is called after a tenant's pool was closed to make room.
+	OnEvict func(tenantID string, err error)
+}
+
+// Manager opens and caches one *db.DB per tenant. It is safe for
+// concurrent use.
+type Manager struct {
+	resolve Resolver
+	opts    Options
+
+	mu     sync.Mutex
+	pools  map[string]*list.Element // of *pool
+	lru    *list.List               // front = most recently used
+	closed bool
+}
+
+type pool struct {
+	tenant string
+	ready  chan struct{} // closed once db/err are set
+	db     *db.DB
+	err    error
+
+	// Guarded by Manager.mu. An evicted pool is closed by the release that
+	// brings leases to zero.
+	leases  int
+	evicted bool
+}
+
+// New returns a Manager resolving tenants with resolve.
+func New(resolve Resolver, opts Options) *Manager {
+	if opts.MaxOpen <= 0 {
+		opts.MaxOpen = 32
+	}
+	return &Manager{resolve: resolve, opts: opts, pools: map[string]*list.Element{}, lru: list.New()}
+}
+
+// For returns the Querier of tenantID's database, opening its pool if
+// needed. The pool is leased until ctx is done, typically the request's
+// context; a ctx that is never done, such as context.Background(), takes no
+// lease. Use Acquire to release explicitly.
+func (m *Manager) For(ctx context.Context, tenantID string) (db.Querier, error) {
+	return m.DB(ctx, tenantID)
+}
+
+// DB is For returning the *db.DB, for transactions and pool statistics.
+func (m *Manager) DB(ctx context.Context, tenantID string) (*db.DB, error) {
+	d, release, err := m.Acquire(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		release()
+	} else {
+		context.AfterFunc(ctx, release)
+	}
+	return d, nil
+}
+
+// Acquire returns tenantID's database, opening its pool if needed, with a
+// lease on it that the returned func gives back. If the pool is evicted
+// meanwhile it stays open until its last lease is released. The func is
+// idempotent.
+func (m *Manager) Acquire(ctx context.Context, tenantID string) (*db.DB, func(), error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, nil, errManagerClosed
+	}
+	if el, ok := m.pools[tenantID]; ok {
+		m.lru.MoveToFront(el)
+		p := el.Value.(*pool)
+		p.leases++
+		m.mu.Unlock()
+		d, err := p.wait(ctx)
+		if err != nil {
+			m.release(p)
+			return nil, nil, err
+		}
+		return d, m.releaser(p), nil
+	}
+	p := &pool{tenant: tenantID, ready: make(chan struct{}), leases: 1}
+	m.pools[tenantID] = m.lru.PushFront(p)
+	m.mu.Unlock()
+
+	d, err := m.open(ctx, tenantID)
+
+	// Make room only once the pool exists, so unknown tenants never push
+	// out working ones.
+	m.mu.Lock()
+	if m.closed && d != nil {
+		_ = d.Close()
+		d, err = nil, errManagerClosed
+	}
+	p.db, p.err = d, err
+	close(p.ready)
+	var evicted []*pool
+	if p.err != nil {
+		if el, ok := m.pools[tenantID]; ok && el.Value == p {
+			m.lru.Remove(el)
+			delete(m.pools, tenantID)
+		}
+	} else {
+		evicted = m.evictLocked()
+	}
+	m.mu.Unlock()
+	for _, e := range evicted {
+		m.close(e)
+	}
+	if p.err != nil {
+		m.release(p)
+		return nil, nil, p.err
+	}
+	return p.db, m.releaser(p), nil
+}
+
+func (m *Manager) releaser(p *pool) func() {
+	var once sync.Once
+	return func() { once.Do(func() { m.release(p) }) }
+}
+
+// release gives back a lease on p, closing p if it was the last lease on
+// an evicted pool.
+func (m *Manager) release(p *pool) {
+	m.mu.Lock()
+	p.leases--
+	last := p.leases == 0 && p.evicted
+	m.mu.Unlock()
+	if last {
+		m.close(p)
+	}
+}
+
+func (m *Manager) open(ctx context.Context, tenantID string) (*db.DB, error) {
+	cfg, err := m.resolve(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	d, err := db.Open(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("tenantdb: open %s: %w", tenantID, err)
+	}
+	return d, nil
+}
+
+// evictLocked removes the least recently used pools beyond MaxOpen and
+// returns those without leases for closing outside the lock; the others are
+// closed by their last release.
+func (m *Manager) evictLocked() []*pool {
+	var out []*pool
+	for m.lru.Len() > m.opts.MaxOpen {
+		el := m.lru.Back()
+		p := el.Value.(*pool)
+		m.lru.Remove(el)
+		delete(m.pools, p.tenant)
+		p.evicted = true
+		if p.leases == 0 {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// close waits for p to finish opening and closes it.
+func (m *Manager) close(p *pool) {
+	<-p.ready
+	if p.db == nil {
+		return
+	}
+	err := p.db.Close()
+	if m.opts.OnEvict != nil {
+		m.opts.OnEvict(p.tenant, err)
+	}
+}
+
+func (p *pool) wait(ctx context.Context) (*db.DB, error) {
+	select {
+	case <-p.ready:
+		return p.db, p.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Len reports how many tenant pools are open or opening, not counting
+// evicted pools that stay open for their leases. While pools are opening it
+// may briefly exceed MaxOpen.
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// Close closes every pool, leased or not. For fails afterwards.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	var pools []*pool
+	for el := m.lru.Front(); el != nil; el = el.Next() {
+		pools = append(pools, el.Value.(*pool))
+	}
+	m.pools, m.lru = map[string]*list.Element{}, list.New()
+	m.mu.Unlock()
+
+	var errs []error
+	for _, p := range pools {
+		<-p.ready
+		if p.db != nil {
+			errs = append(errs, p.db.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,116 @@
+package tenantdb_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/tenantdb"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestManager_CatalogAndEviction(t *testing.T) {
+	ctx := context.Background()
+	control, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer control.Close()
+	dir := t.TempDir()
+	if _, err := control.Exec(ctx, `CREATE TABLE tenants (id TEXT PRIMARY KEY, dsn TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"acme", "globex"} {
+		if _, err := control.Exec(ctx, `INSERT INTO tenants VALUES ($1, $2)`, id, filepath.Join(dir, id+".db")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var evicted []string
+	m := tenantdb.New(tenantdb.Catalog(control, `SELECT dsn FROM tenants WHERE id = $1`,
+		db.Config{DriverName: "sqlite3"}), tenantdb.Options{
+		MaxOpen: 1,
+		OnEvict: func(id string, _ error) { evicted = append(evicted, id) },
+	})
+	defer m.Close()
+
+	acme, err := m.For(ctx, "acme")
+	if err != nil {
+		t.Fatalf("acme: %v", err)
+	}
+	if _, err := acme.Exec(ctx, `CREATE TABLE marker (tenant TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := m.For(ctx, "acme"); again != acme {
+		t.Fatal("second lookup should reuse the open pool")
+	}
+
+	globex, err := m.For(ctx, "globex")
+	if err != nil {
+		t.Fatalf("globex: %v", err)
+	}
+	if len(evicted) != 1 || evicted[0] != "acme" || m.Len() != 1 {
+		t.Fatalf("expected acme to be evicted, got %v (open %d)", evicted, m.Len())
+	}
+	// Each tenant has its own database.
+	var n int
+	if err := globex.QueryRow(ctx, `SELECT count(*) FROM sqlite_master WHERE name = 'marker'`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("globex sees acme's table: n=%d err=%v", n, err)
+	}
+
+	if _, err := m.For(ctx, "initech"); !errors.Is(err, tenantdb.ErrUnknownTenant) {
+		t.Fatalf("expected ErrUnknownTenant, got %v", err)
+	}
+	if m.Len() != 1 {
+		t.Fatalf("failed lookups must not hold a slot, open %d", m.Len())
+	}
+}
+
+func TestManager_LeasedPoolOutlivesEviction(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	evicted := make(chan string, 2)
+	m := tenantdb.New(func(_ context.Context, id string) (db.Config, error) {
+		return db.Config{DriverName: "sqlite3", DSN: filepath.Join(dir, id+".db")}, nil
+	}, tenantdb.Options{MaxOpen: 1, OnEvict: func(id string, _ error) { evicted <- id }})
+	defer m.Close()
+
+	acme, release, err := m.Acquire(ctx, "acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqCtx, endRequest := context.WithCancel(ctx)
+	if _, err := m.For(reqCtx, "acme"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.For(ctx, "globex"); err != nil {
+		t.Fatal(err)
+	}
+	if len(evicted) != 0 || m.Len() != 1 {
+		t.Fatalf("a leased pool must not be closed on eviction: %d evicted, open %d", len(evicted), m.Len())
+	}
+	if _, err := acme.Exec(ctx, `CREATE TABLE t (n INTEGER)`); err != nil {
+		t.Fatalf("in-flight request after eviction: %v", err)
+	}
+
+	release()
+	release() // idempotent
+	if len(evicted) != 0 {
+		t.Fatal("the request context still holds a lease")
+	}
+	endRequest()
+	select {
+	case id := <-evicted:
+		if id != "acme" {
+			t.Fatalf("evicted %s, want acme", id)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the last release must close the evicted pool")
+	}
+	if _, err := acme.Exec(ctx, `SELECT 1`); err == nil {
+		t.Fatal("the evicted pool should be closed")
+	}
+}
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Adaptive timeouts — per-fingerprint deadlines from observed latency
+// ─────────────────────────────────────────────────────────────────────────────
+
+// AdaptiveTimeout derives a deadline for each query fingerprint from its own
+// latency history instead of one DefaultTimeout for everything: a lookup that
+// normally takes 2ms is cut off long before a report that takes 20s.
+//
+//	cfg.DefaultTimeout = 10 * time.Second // until a fingerprint has history
+//	cfg.AdaptiveTimeout = &db.AdaptiveTimeout{Factor: 4, Min: 50 * time.Millisecond, Max: 30 * time.Second}
+//
+// The deadline is Percentile latency × Factor, clamped to [Min, Max]. Like
+// DefaultTimeout it only applies when the context has no deadline of its own.
+// Latency is measured until the driver answers, so it bounds Exec and
+// QueryRow; Query, whose deadline must also cover iterating an arbitrarily
+// large result (Stream, exports), keeps DefaultTimeout.
+type AdaptiveTimeout struct {
+	// Percentile of observed latency the deadline is based on. Defaults to 0.99.
+	Percentile float64
+	// Factor multiplies the percentile. Defaults to 3.
+	Factor float64
+	// Min and Max clamp the deadline. Zero Min means no lower bound; zero
+	// Max falls back to DefaultTimeout, or no upper bound without one.
+	Min, Max time.Duration
+	// MinSamples is how many executions a fingerprint needs before its own
+	// deadline is used; until then DefaultTimeout applies. Defaults to 100.
+	MinSamples uint64
+}
+
+// adaptiveRefresh is how many observations pass between recomputations of a
+// fingerprint's deadline, keeping the per-query cost to a map lookup.
+const adaptiveRefresh = 32
+
+// adaptiveTimeouts is a Hook recording latency per fingerprint and serving
+// the resulting deadlines.
+type adaptiveTimeouts struct {
+	cfg AdaptiveTimeout
+
+	mu    sync.Mutex
+	stats map[string]*adaptiveStat
+}
+
+type adaptiveStat struct {
+	hist  queryHist
+	limit time.Duration // zero until MinSamples
+}
+
+func newAdaptiveTimeouts(cfg AdaptiveTimeout, fallbackMax time.Duration) *adaptiveTimeouts {
+	if cfg.Percentile <= 0 || cfg.Percentile > 1 {
+		cfg.Percentile = 0.99
+	}
+	if cfg.Factor <= 0 {
+		cfg.Factor = 3
+	}
+	if cfg.Max == 0 {
+		cfg.Max = fallbackMax
+	}
+	if cfg.MinSamples == 0 {
+		cfg.MinSamples = 100
+	}
+	return &adaptiveTimeouts{cfg: cfg, stats: make(map[string]*adaptiveStat)}
+}
+
+func (a *adaptiveTimeouts) BeforeQuery(_ context.Context, _ string, _ []any) {}
+
+func (a *adaptiveTimeouts) AfterQuery(_ context.Context, query string, _ []any, d time.Duration, err error) {
+	fp := Fingerprint(query)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := a.stats[fp]
+	if s == nil {
+		if len(a.stats) >= maxStatFingerprints {
+			return // unbounded dynamic SQL keeps DefaultTimeout
+		}
+		s = &adaptiveStat{}
+		a.stats[fp] = s
+	}
+	s.hist.observe(d, err != nil && !IsNotFound(err))
+	if s.hist.count >= a.cfg.MinSamples && (s.limit == 0 || s.hist.count%adaptiveRefresh == 0) {
+		s.limit = a.compute(&s.hist)
+	}
+}
+
+func (a *adaptiveTimeouts) compute(h *queryHist) time.Duration {
+	limit := time.Duration(float64(h.quantile(a.cfg.Percentile)) * a.cfg.Factor)
+	limit = max(limit, a.cfg.Min, 1)
+	if a.cfg.Max > 0 {
+		limit = min(limit, a.cfg.Max)
+	}
+	return limit
+}
+
+// limit returns the deadline for query, or zero when it has too little
+// history.
+func (a *adaptiveTimeouts) limit(query string) time.Duration {
+	fp := Fingerprint(query)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s := a.stats[fp]; s != nil {
+		return s.limit
+	}
+	return 0
+}
+
+// QueryTimeout returns the deadline DB applies to query in Exec and QueryRow
+// when the context has none: the adaptive one once the fingerprint has
+// enough history, else Config.DefaultTimeout. Zero means no deadline.
+func (d *DB) QueryTimeout(query string) time.Duration {
+	if d.timeout != nil {
+		if limit := d.timeout.limit(query); limit > 0 {
+			return limit
+		}
+	}
+	return d.cfg.DefaultTimeout
+}
+
+// applyQueryTimeout is applyDefaultTimeout with the deadline chosen per
+// query by QueryTimeout.
+func (d *DB) applyQueryTimeout(ctx context.Context, query string) (context.Context, context.CancelFunc) {
+	if d.timeout == nil {
+		return d.applyDefaultTimeout(ctx)
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return withTimeout(ctx, d.QueryTimeout(query))
+}
+
+type (
+	timeoutKey   struct{}
+	noTimeoutKey struct{}
+)
+
+// NoTimeout returns a context under which statements run without
+// Config.DefaultTimeout or the adaptive timeout, for operations known to run
+// long — backfills, exports, index builds — instead of faking a far-future
+// deadline:
+//
+//	err := database.ExecTx(db.NoTimeout(ctx), func(tx *db.Tx) error { ... })
+//
+// Cancellation of ctx, and a deadline it already carries, still apply.
+func NoTimeout(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noTimeoutKey{}, true)
+}
+
+// withTimeout bounds ctx by limit unless limit is zero, ctx has a deadline
+// of its own or was marked with NoTimeout, recording limit for
+// TimeoutRemaining.
+func withTimeout(ctx context.Context, limit time.Duration) (context.Context, context.CancelFunc) {
+	if limit == 0 || ctx.Value(noTimeoutKey{}) != nil {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {} // caller already set a deadline
+	}
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	return context.WithValue(ctx, timeoutKey{}, limit), cancel
+}
+
+// TimeoutRemaining reports, from a hook, how much of the statement's
+// deadline is left and which limit the DB applied — DefaultTimeout or the
+// adaptive one; limit is zero when the deadline came from the caller's
+// context. ok is false when the statement runs without a deadline.
+//
+//	func (h *slowHook) BeforeQuery(ctx context.Context, query string, _ []any) {
+//	    if left, limit, ok := db.TimeoutRemaining(ctx); ok && limit > 0 && left < limit/10 {
+//	        h.log.Warn("statement starts with little time left", "query", query, "left", left)
+//	    }
+//	}
+func TimeoutRemaining(ctx context.Context) (remaining, limit time.Duration, ok bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, 0, false
+	}
+	limit, _ = ctx.Value(timeoutKey{}).(time.Duration)
+	return time.Until(deadline), limit, true
+}
+
+// closeRelease is the context DB.Query runs under: the deadline's context,
+// told by database/sql when the rows read under it are closed so the
+// deadline's cancel runs then rather than when its timer fires.
+//
+// *sql.Rows has no close callback, but it watches its query's context
+// through a child it derives with context.WithCancel and cancels on Close,
+// explicit or once Next returns false. A parent implementing AfterFunc gets
+// that child's registration, and its stop func called on the child's
+// cancel; closeRelease turns that stop into cancel. Done is a channel of its
+// own, so context does not bypass AfterFunc by attaching the child to the
+// *cancelCtx underneath, and it stays open on release: Err reports the
+// release, but database/sql, which only watches Done, must not take it for
+// a cancelled query and fail Rows.Err.
+type closeRelease struct {
+	context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu       sync.Mutex
+	n, armed int // registrations so far; the one whose stop releases
+	released bool
+}
+
+func newCloseRelease(ctx context.Context, cancel context.CancelFunc) *closeRelease {
+	c := &closeRelease{Context: ctx, cancel: cancel, done: make(chan struct{})}
+	context.AfterFunc(ctx, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if !c.released {
+			close(c.done)
+		}
+	})
+	return c
+}
+
+func (c *closeRelease) Done() <-chan struct{} { return c.done }
+
+// AfterFunc is the hook context.WithCancel uses on a parent of a type it
+// does not know.
+func (c *closeRelease) AfterFunc(f func()) func() bool {
+	stop := context.AfterFunc(c.Context, f)
+	c.mu.Lock()
+	c.n++
+	id := c.n
+	c.mu.Unlock()
+	return func() bool {
+		stopped := stop()
+		c.mu.Lock()
+		release := id == c.armed && !c.released
+		if release {
+			c.released = true
+		}
+		c.mu.Unlock()
+		if release {
+			c.cancel()
+		}
+		return stopped
+	}
+}
+
+// arm marks the latest registration, made by database/sql for the rows Query
+// just returned, as the one whose stop releases the deadline. Children the
+// driver derived while running the query come before it and are ignored.
+func (c *closeRelease) arm() {
+	c.mu.Lock()
+	c.armed = c.n
+	c.mu.Unlock()
+}
+
+// queryRows runs query through q and returns the rows with a func closing
+// them. On a *DB, the func also releases the deadline Query applied, at
+// as the rows are closed, like Query.
+func queryRows(ctx context.Context, q Querier, query string, args []any) (*sql.Rows, func(), error) {
+	if dq, ok := q.(interface {
+		query(context.Context, string, []any) (*sql.Rows, context.CancelFunc, error)
+	}); ok {
+		rows, cancel, err := dq.query(ctx, query, args)
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		return rows, func() { _ = rows.Close(); cancel() }, nil
+	}
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, func() { _ = rows.Close() }, nil
+}
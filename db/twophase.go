@@ -0,0 +1,215 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Two-phase commit (PostgreSQL prepared transactions)
+// ─────────────────────────────────────────────────────────────────────────────
+
+// errNo2PC is returned by the two-phase helpers on non-PostgreSQL databases.
+var errNo2PC = errors.New("sqltoolkit/db: prepared transactions require PostgreSQL")
+
+// PreparedTx is a transaction left in the prepared state, as listed by
+// pg_prepared_xacts.
+type PreparedTx struct {
+	GID      string
+	Prepared time.Time
+	Owner    string
+	Database string
+}
+
+// PrepareTx runs fn in a new transaction and, if fn succeeds, ends it with
+// PREPARE TRANSACTION gid instead of COMMIT. The transaction's changes are
+// then durable but invisible until CommitPrepared or RollbackPrepared is
+// called with the same gid — possibly from another process after a crash.
+//
+// The server must run with max_prepared_transactions > 0. A prepared
+// transaction keeps its locks until resolved, so callers must always follow up
+// and should run RecoverPrepared at startup.
+//
+//	err := pg.PrepareTx(ctx, "transfer-42-debit", func(q db.Querier) error {
+//	    _, err := q.Exec(ctx, "UPDATE accounts SET balance = balance - $1 WHERE id = $2", amt, from)
+//	    return err
+//	})
+func (d *DB) PrepareTx(ctx context.Context, gid string, fn func(q Querier) error) (err error) {
+	lit, err := d.gidLiteral(gid)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := d.applyDefaultTimeout(ctx)
+	defer cancel()
+
+	// A dedicated connection with explicit BEGIN is used rather than sql.Tx:
+	// after PREPARE TRANSACTION the session is no longer in a transaction,
+	// which drivers' Tx.Commit/Rollback treat as an error.
+	conn, err := d.sqldb.Conn(ctx)
+	if err != nil {
+		return d.mapErr(err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "BEGIN"); err != nil {
+		return d.mapErr(err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = conn.ExecContext(context.Background(), "ROLLBACK")
+			panic(p)
+		}
+		if err != nil {
+			_, _ = conn.ExecContext(context.Background(), "ROLLBACK")
+		}
+	}()
+
+	if err = fn(&connQuerier{conn: conn, hooks: d.hooks, errMap: d.errMap}); err != nil {
+		return d.mapErr(err)
+	}
+	if _, err = conn.ExecContext(ctx, "PREPARE TRANSACTION "+lit); err != nil {
+		return d.mapErr(err)
+	}
+	return nil
+}
+
+// CommitPrepared commits the transaction prepared under gid.
+func (d *DB) CommitPrepared(ctx context.Context, gid string) error {
+	return d.finishPrepared(ctx, "COMMIT PREPARED ", gid)
+}
+
+// RollbackPrepared discards the transaction prepared under gid.
+func (d *DB) RollbackPrepared(ctx context.Context, gid string) error {
+	return d.finishPrepared(ctx, "ROLLBACK PREPARED ", gid)
+}
+
+func (d *DB) finishPrepared(ctx context.Context, verb, gid string) error {
+	lit, err := d.gidLiteral(gid)
+	if err != nil {
+		return err
+	}
+	// COMMIT/ROLLBACK PREPARED cannot take bind parameters.
+	_, err = d.Exec(ctx, verb+lit)
+	return err
+}
+
+// PreparedTxs lists the prepared transactions of the current database, oldest
+// first.
+func (d *DB) PreparedTxs(ctx context.Context) ([]PreparedTx, error) {
+	if d.Dialect() != DialectPostgres {
+		return nil, errNo2PC
+	}
+	return Select(ctx, d, func(s RowScanner) (PreparedTx, error) {
+		var p PreparedTx
+		err := s.Scan(&p.GID, &p.Prepared, &p.Owner, &p.Database)
+		return p, err
+	}, `SELECT gid, prepared, owner, database
+	    FROM   pg_prepared_xacts
+	    WHERE  database = current_database()
+	    ORDER  BY prepared`)
+}
+
+// RecoverPrepared resolves prepared transactions older than minAge — those
+// orphaned by a coordinator that crashed between the two phases. resolve
+// decides each one by consulting the coordinator's own record (commit=true
+// commits, false rolls back); returning an error leaves it untouched and is
+// reported after the remaining transactions have been processed.
+//
+// Call it at startup, before accepting work, with minAge longer than the
+// slowest expected gap between PrepareTx and CommitPrepared.
+func (d *DB) RecoverPrepared(ctx context.Context, minAge time.Duration, resolve func(PreparedTx) (commit bool, err error)) (resolved int, err error) {
+	txs, err := d.PreparedTxs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var errs []error
+	cutoff := time.Now().Add(-minAge)
+	for _, p := range txs {
+		if p.Prepared.After(cutoff) {
+			continue
+		}
+		commit, rerr := resolve(p)
+		if rerr == nil {
+			if commit {
+				rerr = d.CommitPrepared(ctx, p.GID)
+			} else {
+				rerr = d.RollbackPrepared(ctx, p.GID)
+			}
+		}
+		if rerr != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", p.GID, rerr))
+			continue
+		}
+		resolved++
+	}
+	return resolved, errors.Join(errs...)
+}
+
+// gidLiteral validates gid and returns it as a quoted SQL string literal.
+func (d *DB) gidLiteral(gid string) (string, error) {
+	if d.Dialect() != DialectPostgres {
+		return "", errNo2PC
+	}
+	if gid == "" || len(gid) >= 200 {
+		return "", fmt.Errorf("sqltoolkit/db: prepared transaction id must be 1-199 bytes")
+	}
+	return "'" + strings.ReplaceAll(gid, "'", "''") + "'", nil
+}
+
+// ── connQuerier ──────────────────────────────────────────────────────────────
+
+// connQuerier runs statements on a single pinned connection with the DB's
+// hooks and error mapping.
+type connQuerier struct {
+	conn   *sql.Conn
+	hooks  hookChain
+	errMap ErrorMapper
+}
+
+func (c *connQuerier) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := preflight(ctx, query); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	c.hooks.Before(ctx, query, args)
+	res, err := c.conn.ExecContext(ctx, query, args...)
+	err = annotate(c.errMap.Map(err), OpExec, query, start)
+	c.hooks.AfterExec(ctx, query, args, time.Since(start), res, err)
+	return res, err
+}
+
+func (c *connQuerier) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := preflight(ctx, query); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	c.hooks.Before(ctx, query, args)
+	rows, err := c.conn.QueryContext(ctx, query, args...)
+	err = annotate(c.errMap.Map(err), OpQuery, query, start)
+	c.hooks.After(ctx, query, args, time.Since(start), err)
+	return rows, err
+}
+
+func (c *connQuerier) QueryRow(ctx context.Context, query string, args ...any) *Row {
+	if err := preflight(ctx, query); err != nil {
+		return &Row{err: err, errMap: c.errMap}
+	}
+	start := time.Now()
+	c.hooks.Before(ctx, query, args)
+	raw := c.conn.QueryRowContext(ctx, query, args...)
+	c.hooks.After(ctx, query, args, time.Since(start), nil)
+	return &Row{raw: raw, errMap: c.errMap, query: query, start: start}
+}
+
+func (c *connQuerier) Prepare(ctx context.Context, query string) (*Stmt, error) {
+	start := time.Now()
+	s, err := c.conn.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, annotate(c.errMap.Map(err), OpPrepare, query, start)
+	}
+	return &Stmt{stmt: s, query: query, hooks: c.hooks, errMap: c.errMap}, nil
+}
@@ -4,6 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,38 +30,59 @@ func (t *Tx) Raw() *sql.Tx { return t.sqltx }
 
 // Exec executes a statement that does not return rows.
 func (t *Tx) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if err := preflight(ctx, query); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	t.hooks.Before(ctx, query, args)
 	res, err := t.sqltx.ExecContext(ctx, query, args...)
-	err = t.mapErr(err)
-	t.hooks.After(ctx, query, args, time.Since(start), err)
+	err = annotate(t.mapErr(err), OpExec, query, start)
+	t.hooks.AfterExec(ctx, query, args, time.Since(start), res, err)
 	return res, err
 }
 
 // Query executes a query returning rows. The caller MUST close *sql.Rows.
 func (t *Tx) Query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if err := preflight(ctx, query); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	t.hooks.Before(ctx, query, args)
 	rows, err := t.sqltx.QueryContext(ctx, query, args...)
-	err = t.mapErr(err)
+	err = annotate(t.mapErr(err), OpQuery, query, start)
 	t.hooks.After(ctx, query, args, time.Since(start), err)
 	return rows, err
 }
 
 // QueryRow executes a query expected to return at most one row.
 func (t *Tx) QueryRow(ctx context.Context, query string, args ...any) *Row {
+	if err := preflight(ctx, query); err != nil {
+		return &Row{err: err, errMap: t.errMap}
+	}
 	start := time.Now()
 	t.hooks.Before(ctx, query, args)
 	raw := t.sqltx.QueryRowContext(ctx, query, args...)
 	t.hooks.After(ctx, query, args, time.Since(start), nil)
-	return &Row{raw: raw, errMap: t.errMap}
+	return &Row{raw: raw, errMap: t.errMap, query: query, start: start}
+}
+
+// ExecAffected executes a statement and returns the number of rows affected.
+func (t *Tx) ExecAffected(ctx context.Context, query string, args ...any) (int64, error) {
+	return execAffected(ctx, t, query, args)
+}
+
+// ExecReturningID executes an INSERT and returns the id of the new row.
+// See DB.ExecReturningID for the RETURNING vs LastInsertId rules.
+func (t *Tx) ExecReturningID(ctx context.Context, query string, args ...any) (int64, error) {
+	return execReturningID(ctx, t, query, args)
 }
 
 // Prepare creates a prepared statement within the transaction.
 func (t *Tx) Prepare(ctx context.Context, query string) (*Stmt, error) {
+	start := time.Now()
 	s, err := t.sqltx.PrepareContext(ctx, query)
 	if err != nil {
-		return nil, t.mapErr(err)
+		return nil, annotate(t.mapErr(err), OpPrepare, query, start)
 	}
 	return &Stmt{stmt: s, query: query, hooks: t.hooks, errMap: t.errMap}, nil
 }
@@ -96,7 +122,8 @@ func (d *DB) ExecTx(ctx context.Context, fn func(*Tx) error, opts ...TxOptions)
 
 // ExecTxOpts is ExecTx with explicit options forwarding.
 func (d *DB) ExecTxOpts(ctx context.Context, fn func(*Tx) error, opts ...TxOptions) (err error) {
-	ctx = d.applyDefaultTimeout(ctx)
+	ctx, cancel := d.applyDefaultTimeout(ctx)
+	defer cancel()
 
 	var sqlOpts *sql.TxOptions
 	if len(opts) > 0 {
@@ -110,6 +137,8 @@ func (d *DB) ExecTxOpts(ctx context.Context, fn func(*Tx) error, opts ...TxOptio
 	if err != nil {
 		return d.mapErr(err)
 	}
+	done := d.txs.track(sqlOpts != nil && sqlOpts.ReadOnly)
+	defer done()
 
 	tx := &Tx{
 		sqltx:  sqltx,
@@ -143,6 +172,82 @@ func (d *DB) ExecTxOpts(ctx context.Context, fn func(*Tx) error, opts ...TxOptio
 	return nil
 }
 
+// ─────────────────────────────────────────────────────────────────────────────
+// Active transaction tracking
+// ─────────────────────────────────────────────────────────────────────────────
+
+// TxInfo describes a transaction currently open through ExecTx.
+type TxInfo struct {
+	ID       uint64    `json:"id"`
+	Started  time.Time `json:"started"`
+	ReadOnly bool      `json:"read_only"`
+	// Caller is the file:line that called ExecTx.
+	Caller string `json:"caller"`
+}
+
+// ActiveTxs returns the transactions currently open through ExecTx, oldest
+// first. Long-lived entries usually point at a transaction held across a
+// network call.
+func (d *DB) ActiveTxs() []TxInfo { return d.txs.list() }
+
+type txRegistry struct {
+	seq  atomic.Uint64
+	mu   sync.Mutex
+	open map[uint64]TxInfo
+}
+
+// track registers a transaction and returns the function that removes it.
+// A nil registry (DB built without Open) tracks nothing.
+func (r *txRegistry) track(readOnly bool) func() {
+	if r == nil {
+		return func() {}
+	}
+	info := TxInfo{ID: r.seq.Add(1), Started: time.Now(), ReadOnly: readOnly, Caller: externalCaller()}
+	r.mu.Lock()
+	if r.open == nil {
+		r.open = make(map[uint64]TxInfo)
+	}
+	r.open[info.ID] = info
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		delete(r.open, info.ID)
+		r.mu.Unlock()
+	}
+}
+
+func (r *txRegistry) list() []TxInfo {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	out := make([]TxInfo, 0, len(r.open))
+	for _, info := range r.open {
+		out = append(out, info)
+	}
+	r.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// externalCaller returns file:line of the first stack frame outside this
+// package (tests excepted), or "" when none is found.
+func externalCaller() string {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "github.com/Skryldev/sql-toolkit/db.") ||
+			strings.HasSuffix(f.File, "_test.go") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Querier — the shared interface accepted by repositories
 // ─────────────────────────────────────────────────────────────────────────────
@@ -0,0 +1,54 @@
+package db
+
+import "context"
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Transaction propagation through context
+// ─────────────────────────────────────────────────────────────────────────────
+
+type txCtxKey struct{}
+
+// ContextWithTx returns a copy of ctx carrying tx. Repositories built with
+// QuerierFromContext then run inside tx without it appearing in every
+// function signature.
+func ContextWithTx(ctx context.Context, tx *Tx) context.Context {
+	return context.WithValue(ctx, txCtxKey{}, tx)
+}
+
+// TxFromContext returns the transaction stored by ContextWithTx, if any.
+func TxFromContext(ctx context.Context) (*Tx, bool) {
+	tx, ok := ctx.Value(txCtxKey{}).(*Tx)
+	return tx, ok && tx != nil
+}
+
+// QuerierFromContext returns the transaction carried by ctx, or fallback when
+// there is none:
+//
+//	func (s *Service) users(ctx context.Context) repo.UserRepository {
+//	    return repo.NewUserRepo(db.QuerierFromContext(ctx, s.db))
+//	}
+func QuerierFromContext(ctx context.Context, fallback Querier) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return fallback
+}
+
+// InTx runs fn with a context carrying a transaction. If ctx already carries
+// one, fn joins it and commit/rollback is left to the outermost InTx;
+// otherwise a new transaction is started via ExecTx and opts apply.
+//
+//	err := d.InTx(ctx, func(ctx context.Context) error {
+//	    if err := s.users(ctx).Delete(ctx, id); err != nil {
+//	        return err
+//	    }
+//	    return s.audit(ctx).Record(ctx, "user.deleted", id)
+//	})
+func (d *DB) InTx(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOptions) error {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+	return d.ExecTx(ctx, func(tx *Tx) error {
+		return fn(ContextWithTx(ctx, tx))
+	}, opts...)
+}
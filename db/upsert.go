@@ -0,0 +1,105 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// INSERT with conflict handling
+// ─────────────────────────────────────────────────────────────────────────────
+
+// OnConflict selects what InsertSQL does when a row violates a unique key.
+type OnConflict int
+
+const (
+	// ConflictFail lets the database raise ErrDuplicateKey.
+	ConflictFail OnConflict = iota
+	// ConflictSkip silently keeps the existing row.
+	ConflictSkip
+	// ConflictUpdate overwrites the non-key columns of the existing row.
+	ConflictUpdate
+)
+
+// InsertSQL builds a single-row INSERT for table and cols using the dialect's
+// upsert syntax. keyCols names the unique key checked for conflicts; it is
+// required for ConflictUpdate on PostgreSQL and SQLite, and ignored by MySQL,
+// which resolves conflicts against every unique index.
+//
+// All identifiers are quoted, so table and column names may come from
+// configuration. Values are always bound as parameters.
+//
+//	q, _ := db.InsertSQL(db.DialectPostgres, "users", []string{"email", "name"},
+//	    db.ConflictUpdate, "email")
+//	// INSERT INTO "users" ("email", "name") VALUES ($1, $2)
+//	//   ON CONFLICT ("email") DO UPDATE SET "name" = excluded."name"
+func InsertSQL(d Dialect, table string, cols []string, action OnConflict, keyCols ...string) (string, error) {
+	if len(cols) == 0 {
+		return "", fmt.Errorf("sqltoolkit/db: InsertSQL: no columns")
+	}
+	ph := d.Placeholder()
+	quoted := make([]string, len(cols))
+	marks := make([]string, len(cols))
+	for i, c := range cols {
+		quoted[i] = d.QuoteIdent(c)
+		marks[i] = ph(i + 1)
+	}
+	keys := make(map[string]bool, len(keyCols))
+	quotedKeys := make([]string, len(keyCols))
+	for i, k := range keyCols {
+		keys[k] = true
+		quotedKeys[i] = d.QuoteIdent(k)
+	}
+
+	verb := "INSERT"
+	if action == ConflictSkip && d == DialectMySQL {
+		verb = "INSERT IGNORE"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s INTO %s (%s) VALUES (%s)",
+		verb, d.QuoteIdent(table), strings.Join(quoted, ", "), strings.Join(marks, ", "))
+
+	switch action {
+	case ConflictFail:
+	case ConflictSkip:
+		if d != DialectMySQL {
+			b.WriteString(" ON CONFLICT")
+			if len(keyCols) > 0 {
+				b.WriteString(" (" + strings.Join(quotedKeys, ", ") + ")")
+			}
+			b.WriteString(" DO NOTHING")
+		}
+	case ConflictUpdate:
+		var sets []string
+		for i, c := range cols {
+			if keys[c] {
+				continue
+			}
+			if d == DialectMySQL {
+				sets = append(sets, quoted[i]+" = VALUES("+quoted[i]+")")
+			} else {
+				sets = append(sets, quoted[i]+" = excluded."+quoted[i])
+			}
+		}
+		if d == DialectMySQL {
+			if len(sets) == 0 {
+				// Nothing to update: a self-assignment keeps the statement valid.
+				sets = []string{quoted[0] + " = " + quoted[0]}
+			}
+			b.WriteString(" ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "))
+			break
+		}
+		if len(keyCols) == 0 {
+			return "", fmt.Errorf("sqltoolkit/db: InsertSQL: ConflictUpdate requires key columns")
+		}
+		b.WriteString(" ON CONFLICT (" + strings.Join(quotedKeys, ", ") + ")")
+		if len(sets) == 0 {
+			b.WriteString(" DO NOTHING")
+		} else {
+			b.WriteString(" DO UPDATE SET " + strings.Join(sets, ", "))
+		}
+	default:
+		return "", fmt.Errorf("sqltoolkit/db: InsertSQL: unknown conflict action %d", action)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,334 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Query validation
+// ─────────────────────────────────────────────────────────────────────────────
+
+// ValidateQueries prepares every statement in queries (name → SQL) on q and
+// closes it again without executing it. Preparing makes the database parse
+// the statement and resolve its tables and columns, so typos and schema drift
+// surface at startup or in CI rather than on the first request.
+//
+// All statements are checked; the returned error joins one error per failing
+// statement, each prefixed with its name. Run it against a migrated schema.
+func ValidateQueries(ctx context.Context, q Querier, queries map[string]string) error {
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		stmt, err := q.Prepare(ctx, queries[name])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		_ = stmt.Close()
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]string{}
+)
+
+// RegisterQueries records statements for validation under "pkg.name" keys.
+// Repository packages call it from init with their SQL constants, so a single
+// ValidateQueries(ctx, d, RegisteredQueries()) covers every repository linked
+// into the binary. Registering the same key twice panics.
+func RegisterQueries(pkg string, queries map[string]string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	for name, sql := range queries {
+		key := pkg + "." + name
+		if _, dup := registry[key]; dup {
+			panic("sqltoolkit/db: query " + key + " registered twice")
+		}
+		registry[key] = sql
+	}
+}
+
+// RegisteredQueries returns a copy of every statement passed to
+// RegisterQueries.
+func RegisteredQueries() map[string]string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	out := make(map[string]string, len(registry))
+	for k, v := range registry {
+		out[k] = v
+	}
+	return out
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Schema validation — the tables and columns repositories scan from
+// ─────────────────────────────────────────────────────────────────────────────
+
+// ColumnType is a family of SQL types that scan into the same Go types.
+type ColumnType string
+
+const (
+	TypeAny     ColumnType = ""
+	TypeInteger ColumnType = "integer"
+	TypeFloat   ColumnType = "float" // real, double and numeric columns
+	TypeText    ColumnType = "text"  // also uuid, json and enum columns
+	TypeBool    ColumnType = "bool"
+	TypeTime    ColumnType = "time"
+	TypeBytes   ColumnType = "bytes"
+)
+
+// accepts reports whether a column of family col scans into a field
+// expecting t. A column whose type is not recognised is accepted.
+func (t ColumnType) accepts(col ColumnType) bool {
+	switch {
+	case t == TypeAny || col == TypeAny || t == col:
+		return true
+	case t == TypeFloat, t == TypeBool:
+		return col == TypeInteger // numeric widening; tinyint and 0/1 booleans
+	case t == TypeBytes:
+		return col == TypeText
+	}
+	return false
+}
+
+// columnTypeOf classifies a column type as reported by the database.
+func columnTypeOf(sqlType string) ColumnType {
+	s := strings.ToLower(sqlType)
+	switch {
+	case s == "" || s == "interval" || strings.Contains(s, "point"):
+		return TypeAny // SQLite column without a declared type; not an "int"
+	case strings.Contains(s, "bool") || s == "bit":
+		return TypeBool
+	// Text and blob families come before "int", so none of their names is
+	// taken for an integer.
+	case strings.Contains(s, "char") || strings.Contains(s, "text") || strings.Contains(s, "clob") ||
+		strings.Contains(s, "uuid") || strings.Contains(s, "json") || s == "enum" || s == "set":
+		return TypeText
+	case strings.Contains(s, "blob") || strings.Contains(s, "binary") || s == "bytea":
+		return TypeBytes
+	case strings.Contains(s, "int") || strings.Contains(s, "serial"):
+		return TypeInteger
+	case strings.Contains(s, "time") || strings.Contains(s, "date"):
+		return TypeTime
+	case strings.Contains(s, "real") || strings.Contains(s, "floa") || strings.Contains(s, "doub") ||
+		strings.Contains(s, "numeric") || strings.Contains(s, "decimal"):
+		return TypeFloat
+	}
+	return TypeAny
+}
+
+// ColumnExpectation is what a repository needs of one column.
+type ColumnExpectation struct {
+	Name string
+	Type ColumnType
+	// NotNull requires the column to be NOT NULL, for fields that cannot
+	// hold NULL and would fail to scan one.
+	NotNull bool
+}
+
+// TableExpectation is what a repository needs of one table.
+type TableExpectation struct {
+	Table   string
+	Columns []ColumnExpectation
+}
+
+var nullTypes = map[reflect.Type]ColumnType{
+	reflect.TypeFor[sql.NullString]():  TypeText,
+	reflect.TypeFor[sql.NullInt64]():   TypeInteger,
+	reflect.TypeFor[sql.NullInt32]():   TypeInteger,
+	reflect.TypeFor[sql.NullInt16]():   TypeInteger,
+	reflect.TypeFor[sql.NullByte]():    TypeInteger,
+	reflect.TypeFor[sql.NullFloat64](): TypeFloat,
+	reflect.TypeFor[sql.NullBool]():    TypeBool,
+	reflect.TypeFor[sql.NullTime]():    TypeTime,
+}
+
+// ExpectStruct derives the expectation for table from the fields of T (a
+// struct or a pointer to one), mapped to columns by the same rules as Diff.
+// Pointers and sql.Null types may be NULL; types implementing sql.Scanner
+// or registered with RegisterScanner accept any column.
+func ExpectStruct[T any](table string) TableExpectation {
+	st := reflect.TypeFor[T]()
+	if st.Kind() == reflect.Pointer {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("sqltoolkit/db: ExpectStruct: %s is not a struct", reflect.TypeFor[T]()))
+	}
+	e := TableExpectation{Table: table}
+	for _, f := range diffFields(st) {
+		ft := st.FieldByIndex(f.index).Type
+		c := ColumnExpectation{Name: f.column, NotNull: true}
+		if ft.Kind() == reflect.Pointer {
+			ft, c.NotNull = ft.Elem(), false
+		}
+		if _, ok := converterFor(ft); ok {
+			c.NotNull = false
+		} else if typ, ok := nullTypes[ft]; ok {
+			c.Type, c.NotNull = typ, false
+		} else if reflect.PointerTo(ft).Implements(scannerType) {
+			c.NotNull = false
+		} else {
+			c.Type = goColumnType(ft)
+		}
+		e.Columns = append(e.Columns, c)
+	}
+	return e
+}
+
+func goColumnType(t reflect.Type) ColumnType {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return TypeInteger
+	case reflect.Float32, reflect.Float64:
+		return TypeFloat
+	case reflect.String:
+		return TypeText
+	case reflect.Bool:
+		return TypeBool
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return TypeBytes
+		}
+	case reflect.Struct:
+		if t == timeType {
+			return TypeTime
+		}
+	}
+	return TypeAny
+}
+
+// ValidateSchema checks that every expected table and column exists on q
+// with a compatible type and, where required, NOT NULL, so a schema that
+// drifted from the code fails at startup with one report instead of as scan
+// errors on the requests that happen to touch it:
+//
+//	err := db.ValidateSchema(ctx, database, db.RegisteredSchema()...)
+//
+// The returned error joins one error per mismatch, prefixed with the table
+// and column. Columns the database has but nothing expects are fine.
+func ValidateSchema(ctx context.Context, q Querier, tables ...TableExpectation) error {
+	var errs []error
+	for _, t := range tables {
+		cols, err := tableColumns(ctx, q, t.Table)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Table, err))
+			continue
+		}
+		if len(cols) == 0 {
+			errs = append(errs, fmt.Errorf("%s: missing table", t.Table))
+			continue
+		}
+		for _, want := range t.Columns {
+			got, ok := cols[want.Name]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("%s.%s: missing column", t.Table, want.Name))
+			case !want.Type.accepts(columnTypeOf(got.typ)):
+				errs = append(errs, fmt.Errorf("%s.%s: %s expected, column is %s", t.Table, want.Name, want.Type, got.typ))
+			case want.NotNull && got.nullable:
+				errs = append(errs, fmt.Errorf("%s.%s: column is nullable but the field cannot hold NULL", t.Table, want.Name))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type schemaColumn struct {
+	typ      string
+	nullable bool
+}
+
+// tableColumns returns the columns of table by name; empty when the table
+// does not exist.
+func tableColumns(ctx context.Context, q Querier, table string) (map[string]schemaColumn, error) {
+	type col struct {
+		name string
+		schemaColumn
+	}
+	var (
+		cols []col
+		err  error
+	)
+	switch DialectFrom(q) {
+	case DialectPostgres:
+		schema, name, ok := strings.Cut(table, ".")
+		if !ok {
+			schema, name = "", table
+		}
+		cols, err = Select(ctx, q, func(r RowScanner) (col, error) {
+			var c col
+			var nullable string
+			err := r.Scan(&c.name, &c.typ, &nullable)
+			c.nullable = nullable == "YES"
+			return c, err
+		}, `
+			SELECT column_name, data_type, is_nullable FROM information_schema.columns
+			WHERE  table_schema = COALESCE(NULLIF($1, ''), current_schema()) AND table_name = $2`, schema, name)
+	case DialectMySQL:
+		cols, err = Select(ctx, q, func(r RowScanner) (col, error) {
+			var c col
+			var nullable string
+			err := r.Scan(&c.name, &c.typ, &nullable)
+			c.nullable = nullable == "YES"
+			return c, err
+		}, `
+			SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE FROM information_schema.COLUMNS
+			WHERE  TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`, table)
+	default:
+		cols, err = Select(ctx, q, func(r RowScanner) (col, error) {
+			var c col
+			var notNull, pk int
+			err := r.Scan(&c.name, &c.typ, &notNull, &pk)
+			c.nullable = notNull == 0 && pk == 0
+			return c, err
+		}, `SELECT name, type, "notnull", pk FROM pragma_table_info(?)`, table)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]schemaColumn, len(cols))
+	for _, c := range cols {
+		out[c.name] = c.schemaColumn
+	}
+	return out, nil
+}
+
+var (
+	schemaMu       sync.RWMutex
+	schemaRegistry []TableExpectation
+)
+
+// RegisterSchema records table expectations for ValidateSchema. Repository
+// packages call it from init next to RegisterQueries, so a single
+// ValidateSchema(ctx, d, RegisteredSchema()...) covers every repository
+// linked into the binary.
+func RegisterSchema(tables ...TableExpectation) {
+	schemaMu.Lock()
+	defer schemaMu.Unlock()
+	schemaRegistry = append(schemaRegistry, tables...)
+}
+
+// RegisteredSchema returns a copy of every expectation passed to
+// RegisterSchema.
+func RegisteredSchema() []TableExpectation {
+	schemaMu.RLock()
+	defer schemaMu.RUnlock()
+	return slices.Clone(schemaRegistry)
+}
@@ -0,0 +1,230 @@
+// Package watchdog cancels this application's queries that run longer than
+// a policy allows: a safety net for runaway reports and forgotten filters,
+// which otherwise hold locks, connections and I/O until someone notices.
+//
+// A Watchdog polls pg_stat_activity (PostgreSQL) or the processlist (MySQL)
+// every Interval, cancels the statements past MaxDuration and logs each one
+// it killed. A statement is only cancelled if the session is still running
+// the same one: the cancel is conditional on query_start (PostgreSQL) or on
+// a fresh look at the processlist entry (MySQL), so a session that finished
+// the slow query and started another in between is left alone. On PostgreSQL it only looks at sessions with its
+// ApplicationName, so several services sharing a server, psql sessions and
+// maintenance jobs are never touched; on MySQL, which has no application
+// name, it looks at sessions of the same user in the same schema.
+//
+//	database, err := db.Open(db.Config{..., AppName: "billing-api"})
+//	w, err := watchdog.New(database, watchdog.Options{MaxDuration: 2 * time.Minute})
+//	go w.Run(ctx)
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Options configures New.
+type Options struct {
+	// ApplicationName scopes the watchdog to sessions with this
+	// application_name. Defaults to the pool's Config.AppName; required on
+	// PostgreSQL, ignored on MySQL.
+	ApplicationName string
+	// MaxDuration is how long a statement may run. Defaults to 5m.
+	MaxDuration time.Duration
+	// Interval is how often sessions are inspected. Defaults to 10s.
+	Interval time.Duration
+	// Terminate ends the whole session instead of cancelling the statement,
+	// for clients that would otherwise retry the query right away.
+	Terminate bool
+	// Exempt, if set, spares the statements it returns true for, e.g.
+	// known long exports.
+	Exempt func(query string) bool
+	// OnKill is called for every statement cancelled.
+	OnKill func(Killed)
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Killed describes a cancelled statement.
+type Killed struct {
+	// Session is the backend pid (PostgreSQL) or connection id (MySQL).
+	Session  int64
+	User     string
+	Query    string
+	Duration time.Duration
+	At       time.Time
+}
+
+// Watchdog inspects and cancels long-running statements of one database.
+type Watchdog struct {
+	d    *db.DB
+	opts Options
+
+	sqlList, sqlKill, sqlRecheck string
+}
+
+// session is a running statement as listed, plus what identifies it when it
+// is cancelled.
+type session struct {
+	Killed
+	start time.Time // query_start, PostgreSQL only
+	secs  int64     // TIME, MySQL only
+}
+
+// New returns a Watchdog for d. Dialects other than PostgreSQL and MySQL get
+// an error wrapping errors.ErrUnsupported.
+func New(d *db.DB, opts Options) (*Watchdog, error) {
+	if opts.MaxDuration <= 0 {
+		opts.MaxDuration = 5 * time.Minute
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	if opts.ApplicationName == "" {
+		opts.ApplicationName = d.Config().AppName
+	}
+	w := &Watchdog{d: d, opts: opts}
+	switch dialect := d.Dialect(); dialect {
+	case db.DialectPostgres:
+		if opts.ApplicationName == "" {
+			return nil, errors.New("watchdog: ApplicationName is required on PostgreSQL")
+		}
+		w.sqlList = `
+			SELECT pid, usename, EXTRACT(EPOCH FROM now() - query_start)::float8, query, query_start
+			FROM   pg_stat_activity
+			WHERE  state = 'active' AND backend_type = 'client backend'
+			  AND  application_name = $1 AND pid <> pg_backend_pid()
+			  AND  now() - query_start > make_interval(secs => $2)`
+		w.sqlKill = `SELECT pg_cancel_backend(pid) FROM pg_stat_activity WHERE pid = $1 AND query_start = $2`
+		if opts.Terminate {
+			w.sqlKill = `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE pid = $1 AND query_start = $2`
+		}
+	case db.DialectMySQL:
+		w.sqlList = `
+			SELECT ID, USER, TIME, COALESCE(INFO, '')
+			FROM   information_schema.PROCESSLIST
+			WHERE  COMMAND = 'Query' AND ID <> CONNECTION_ID()
+			  AND  USER = SUBSTRING_INDEX(CURRENT_USER(), '@', 1) AND DB = DATABASE()
+			  AND  TIME > ?`
+		// KILL takes no condition, so the entry is looked up again right
+		// before it; TIME only grows while the same statement runs.
+		w.sqlRecheck = `
+			SELECT COUNT(*)
+			FROM   information_schema.PROCESSLIST
+			WHERE  ID = ? AND COMMAND = 'Query' AND TIME >= ? AND COALESCE(INFO, '') = ?`
+		w.sqlKill = `KILL QUERY %d`
+		if opts.Terminate {
+			w.sqlKill = `KILL %d`
+		}
+	default:
+		return nil, fmt.Errorf("watchdog: %w on dialect %q", errors.ErrUnsupported, dialect)
+	}
+	return w, nil
+}
+
+// Run checks every Interval until ctx is cancelled. Errors are logged and
+// do not stop the watchdog.
+func (w *Watchdog) Run(ctx context.Context) error {
+	t := time.NewTicker(w.opts.Interval)
+	defer t.Stop()
+	for {
+		if _, err := w.Check(ctx); err != nil && ctx.Err() == nil {
+			w.opts.Logger.Warn("watchdog: check failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Check cancels the statements currently past MaxDuration and returns them.
+func (w *Watchdog) Check(ctx context.Context) ([]Killed, error) {
+	args := []any{w.opts.ApplicationName, w.opts.MaxDuration.Seconds()}
+	if w.d.Dialect() == db.DialectMySQL {
+		args = []any{int64(w.opts.MaxDuration / time.Second)} // TIME is whole seconds
+	}
+	mysql := w.d.Dialect() == db.DialectMySQL
+	running, err := db.Select(ctx, w.d, func(r db.RowScanner) (session, error) {
+		var s session
+		var secs float64
+		var err error
+		if mysql {
+			err = r.Scan(&s.Session, &s.User, &s.secs, &s.Query)
+			secs = float64(s.secs)
+		} else {
+			err = r.Scan(&s.Session, &s.User, &secs, &s.Query, &s.start)
+		}
+		s.Duration = time.Duration(secs * float64(time.Second))
+		return s, err
+	}, w.sqlList, args...)
+	if err != nil {
+		return nil, fmt.Errorf("watchdog: list sessions: %w", err)
+	}
+
+	var killed []Killed
+	var errs []error
+	for _, s := range running {
+		if w.opts.Exempt != nil && w.opts.Exempt(s.Query) {
+			continue
+		}
+		ok, err := w.kill(ctx, s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("watchdog: cancel session %d: %w", s.Session, err))
+			continue
+		}
+		if !ok {
+			continue // finished, or moved on to another statement
+		}
+		k := s.Killed
+		k.At = time.Now()
+		killed = append(killed, k)
+		w.opts.Logger.Warn("watchdog: cancelled long-running query",
+			"session", k.Session, "user", k.User, "duration", k.Duration.Round(time.Second),
+			"max_duration", w.opts.MaxDuration, "query", db.Fingerprint(k.Query))
+		if w.opts.OnKill != nil {
+			w.opts.OnKill(k)
+		}
+	}
+	return killed, errors.Join(errs...)
+}
+
+// kill cancels s if the session is still running the statement that was
+// listed, and reports whether it did.
+func (w *Watchdog) kill(ctx context.Context, s session) (bool, error) {
+	if w.d.Dialect() != db.DialectMySQL {
+		ok, err := db.Get(ctx, w.d, func(r db.RowScanner) (bool, error) {
+			var ok bool
+			err := r.Scan(&ok)
+			return ok, err
+		}, w.sqlKill, s.Session, s.start)
+		if db.IsNotFound(err) {
+			return false, nil
+		}
+		return ok, err
+	}
+	n, err := db.Get(ctx, w.d, func(r db.RowScanner) (int64, error) {
+		var n int64
+		err := r.Scan(&n)
+		return n, err
+	}, w.sqlRecheck, s.Session, s.secs, s.Query)
+	if err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := w.d.Exec(ctx, fmt.Sprintf(w.sqlKill, s.Session)); err != nil {
+		var me interface{ Number() uint16 }
+		if errors.As(err, &me) && me.Number() == 1094 { // ER_NO_SUCH_THREAD: gone since the recheck
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,22 @@
+package watchdog_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/watchdog"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestNew(t *testing.T) {
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, err := watchdog.New(d, watchdog.Options{ApplicationName: "app"}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("SQLite has no session list; expected ErrUnsupported, got %v", err)
+	}
+
+}
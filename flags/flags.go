@@ -0,0 +1,299 @@
+// Package flags serves feature flags stored in the database from an
+// in-memory snapshot, so checking a flag never touches the database.
+//
+// Flags live in the db/kv table under the "flags/" prefix. The snapshot is
+// refreshed by polling and, when a notify.Subscriber is configured, as soon as
+// another instance changes a flag. An optional shared repo.Cache lets a fleet
+// of instances poll the cache instead of the database.
+//
+//	f := flags.New(kv.New(database), flags.Options{
+//	    Subscriber: notify.NewPQSubscriber(dsn),
+//	    Publisher:  notify.NewPGPublisher(database),
+//	})
+//	go f.Run(ctx)
+//
+//	if f.Rollout("new-checkout", userID) { ... }
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db/kv"
+	"github.com/Skryldev/sql-toolkit/db/notify"
+	"github.com/Skryldev/sql-toolkit/repo"
+)
+
+const (
+	keyPrefix   = "flags/"
+	snapshotKey = "flags:snapshot"
+
+	// DefaultChannel is the notification channel used when Options.Channel
+	// is empty.
+	DefaultChannel = "sqltoolkit_flags"
+)
+
+// Flag is the stored state of one feature flag.
+type Flag struct {
+	Enabled bool `json:"enabled"`
+	// Value is an arbitrary string for multivariate flags.
+	Value string `json:"value,omitempty"`
+	// Percent limits an enabled flag to that share (0-100) of rollout
+	// subjects. Nil means everyone.
+	Percent *float64 `json:"percent,omitempty"`
+}
+
+// Options configures New. Every field is optional.
+type Options struct {
+	// PollInterval is the maximum staleness when notifications are missed.
+	// Defaults to 30s.
+	PollInterval time.Duration
+	// Cache holds the serialized snapshot for PollInterval so instances
+	// sharing it do not all query the database.
+	Cache repo.Cache
+	// Subscriber and Publisher propagate changes made with Set and Delete.
+	Subscriber notify.Subscriber
+	Publisher  notify.Publisher
+	// Channel defaults to DefaultChannel.
+	Channel string
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Client holds the in-memory flag snapshot.
+type Client struct {
+	store *kv.Store
+	opts  Options
+
+	mu       sync.RWMutex
+	flags    map[string]Flag
+	watchers []func(name string, old, new Flag)
+}
+
+// New returns a Client with an empty snapshot; call Refresh or Run to load it.
+func New(store *kv.Store, opts Options) *Client {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 30 * time.Second
+	}
+	if opts.Channel == "" {
+		opts.Channel = DefaultChannel
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Client{store: store, opts: opts, flags: map[string]Flag{}}
+}
+
+// ── Accessors ────────────────────────────────────────────────────────────────
+
+// Get returns the flag named name and whether it exists.
+func (c *Client) Get(name string) (Flag, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	f, ok := c.flags[name]
+	return f, ok
+}
+
+// Bool reports whether name is enabled, or def when it does not exist.
+func (c *Client) Bool(name string, def bool) bool {
+	if f, ok := c.Get(name); ok {
+		return f.Enabled
+	}
+	return def
+}
+
+// String returns the value of an enabled flag, or def when it is missing or
+// disabled.
+func (c *Client) String(name, def string) string {
+	if f, ok := c.Get(name); ok && f.Enabled {
+		return f.Value
+	}
+	return def
+}
+
+// Rollout reports whether name is enabled for subject (a user id, tenant,
+// ...). The decision is a stable hash of name and subject, so a subject stays
+// in the rollout as Percent grows.
+func (c *Client) Rollout(name, subject string) bool {
+	f, ok := c.Get(name)
+	if !ok || !f.Enabled {
+		return false
+	}
+	if f.Percent == nil {
+		return true
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(subject))
+	return float64(h.Sum32()%10000)/100 < *f.Percent
+}
+
+// All returns a copy of the snapshot.
+func (c *Client) All() map[string]Flag {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]Flag, len(c.flags))
+	for k, v := range c.flags {
+		out[k] = v
+	}
+	return out
+}
+
+// OnChange registers fn to run after a refresh changes a flag. Removed flags
+// are reported with a zero new value. fn runs synchronously; keep it short.
+func (c *Client) OnChange(fn func(name string, old, new Flag)) {
+	c.mu.Lock()
+	c.watchers = append(c.watchers, fn)
+	c.mu.Unlock()
+}
+
+// ── Writes ───────────────────────────────────────────────────────────────────
+
+// Set stores f under name and propagates the change.
+func (c *Client) Set(ctx context.Context, name string, f Flag) error {
+	if err := kv.SetJSON(ctx, c.store, keyPrefix+name, f, 0); err != nil {
+		return err
+	}
+	return c.changed(ctx, name)
+}
+
+// Delete removes name and propagates the change.
+func (c *Client) Delete(ctx context.Context, name string) error {
+	if err := c.store.Delete(ctx, keyPrefix+name); err != nil {
+		return err
+	}
+	return c.changed(ctx, name)
+}
+
+func (c *Client) changed(ctx context.Context, name string) error {
+	if err := c.reload(ctx, true); err != nil {
+		return err
+	}
+	if c.opts.Publisher != nil {
+		if err := c.opts.Publisher.Notify(ctx, c.opts.Channel, name); err != nil {
+			c.opts.Logger.WarnContext(ctx, "flags: publish change", slog.String("flag", name), slog.Any("error", err))
+		}
+	}
+	return nil
+}
+
+// ── Refresh ──────────────────────────────────────────────────────────────────
+
+// Refresh reloads the snapshot, from the shared cache when it holds one.
+func (c *Client) Refresh(ctx context.Context) error { return c.reload(ctx, false) }
+
+// Run loads the snapshot and keeps it fresh until ctx is cancelled, polling
+// every PollInterval and reloading on notifications. Failed refreshes are
+// logged and the previous snapshot is kept.
+func (c *Client) Run(ctx context.Context) error {
+	var notes <-chan string
+	if c.opts.Subscriber != nil {
+		ch, err := c.opts.Subscriber.Listen(ctx, c.opts.Channel)
+		if err != nil {
+			c.opts.Logger.WarnContext(ctx, "flags: listen failed, polling only", slog.Any("error", err))
+		} else {
+			notes = ch
+		}
+	}
+	if err := c.Refresh(ctx); err != nil {
+		c.opts.Logger.WarnContext(ctx, "flags: initial load", slog.Any("error", err))
+	}
+
+	tick := time.NewTicker(c.opts.PollInterval)
+	defer tick.Stop()
+	for {
+		var err error
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+			err = c.reload(ctx, false)
+		case _, ok := <-notes:
+			if !ok {
+				notes = nil
+				continue
+			}
+			err = c.reload(ctx, true)
+		}
+		if err != nil && ctx.Err() == nil {
+			c.opts.Logger.WarnContext(ctx, "flags: refresh", slog.Any("error", err))
+		}
+	}
+}
+
+// reload replaces the snapshot. With force the shared cache is bypassed and
+// overwritten, which is what a change notification requires.
+func (c *Client) reload(ctx context.Context, force bool) error {
+	cache := c.opts.Cache
+	if cache != nil && !force {
+		if raw, ok, err := cache.Get(ctx, snapshotKey); err == nil && ok {
+			var snap map[string]Flag
+			if json.Unmarshal(raw, &snap) == nil {
+				c.apply(snap)
+				return nil
+			}
+		}
+	}
+
+	entries, err := c.store.List(ctx, keyPrefix)
+	if err != nil {
+		return err
+	}
+	snap := make(map[string]Flag, len(entries))
+	for _, e := range entries {
+		var f Flag
+		if err := json.Unmarshal(e.Value, &f); err != nil {
+			c.opts.Logger.WarnContext(ctx, "flags: skipping malformed flag", slog.String("key", e.Key), slog.Any("error", err))
+			continue
+		}
+		snap[strings.TrimPrefix(e.Key, keyPrefix)] = f
+	}
+	if cache != nil {
+		if raw, err := json.Marshal(snap); err == nil {
+			if err := cache.Set(ctx, snapshotKey, raw, c.opts.PollInterval); err != nil {
+				c.opts.Logger.WarnContext(ctx, "flags: cache snapshot", slog.Any("error", err))
+			}
+		}
+	}
+	c.apply(snap)
+	return nil
+}
+
+func (c *Client) apply(snap map[string]Flag) {
+	c.mu.Lock()
+	old := c.flags
+	c.flags = snap
+	watchers := c.watchers
+	c.mu.Unlock()
+
+	if len(watchers) == 0 {
+		return
+	}
+	for name, f := range snap {
+		if prev, ok := old[name]; !ok || !equal(prev, f) {
+			for _, fn := range watchers {
+				fn(name, prev, f)
+			}
+		}
+	}
+	for name, prev := range old {
+		if _, ok := snap[name]; !ok {
+			for _, fn := range watchers {
+				fn(name, prev, Flag{})
+			}
+		}
+	}
+}
+
+func equal(a, b Flag) bool {
+	if a.Enabled != b.Enabled || a.Value != b.Value || (a.Percent == nil) != (b.Percent == nil) {
+		return false
+	}
+	return a.Percent == nil || *a.Percent == *b.Percent
+}
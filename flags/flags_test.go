@@ -0,0 +1,86 @@
+package flags_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	"github.com/Skryldev/sql-toolkit/db/kv"
+	"github.com/Skryldev/sql-toolkit/db/notify"
+	"github.com/Skryldev/sql-toolkit/flags"
+	"github.com/Skryldev/sql-toolkit/repo"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestStore(t *testing.T) *kv.Store {
+	t.Helper()
+	return kv.New(dbtest.OpenSQLite(t, "000004_create_kv_store"))
+}
+
+func TestClient_Accessors(t *testing.T) {
+	f := flags.New(newTestStore(t), flags.Options{})
+	ctx := context.Background()
+	half := 50.0
+
+	_ = f.Set(ctx, "dark-mode", flags.Flag{Enabled: true})
+	_ = f.Set(ctx, "theme", flags.Flag{Enabled: true, Value: "blue"})
+	_ = f.Set(ctx, "checkout-v2", flags.Flag{Enabled: true, Percent: &half})
+
+	if !f.Bool("dark-mode", false) || f.Bool("missing", false) || !f.Bool("missing", true) {
+		t.Fatal("Bool accessor")
+	}
+	if f.String("theme", "red") != "blue" || f.String("missing", "red") != "red" {
+		t.Fatal("String accessor")
+	}
+	in := 0
+	for i := 0; i < 1000; i++ {
+		if f.Rollout("checkout-v2", fmt.Sprint("user-", i)) {
+			in++
+		}
+	}
+	if in < 400 || in > 600 {
+		t.Fatalf("50%% rollout enabled %d/1000 subjects", in)
+	}
+	if f.Rollout("checkout-v2", "user-1") != f.Rollout("checkout-v2", "user-1") {
+		t.Fatal("rollout must be stable per subject")
+	}
+}
+
+func TestClient_PropagatesChanges(t *testing.T) {
+	store := newTestStore(t)
+	hub := notify.NewHub()
+	cache := repo.NewMemoryCache()
+	opts := flags.Options{Subscriber: hub, Publisher: hub, Cache: cache, PollInterval: time.Hour}
+
+	writer := flags.New(store, opts)
+	reader := flags.New(store, opts)
+
+	changed := make(chan string, 4)
+	reader.OnChange(func(name string, old, new flags.Flag) {
+		if !old.Enabled && new.Enabled {
+			changed <- name
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = reader.Run(ctx) }()
+	time.Sleep(20 * time.Millisecond) // let Run subscribe
+
+	if err := writer.Set(context.Background(), "beta", flags.Flag{Enabled: true}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	select {
+	case name := <-changed:
+		if name != "beta" {
+			t.Fatalf("changed %q", name)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reader did not observe the change")
+	}
+	if !reader.Bool("beta", false) {
+		t.Fatal("reader snapshot not updated")
+	}
+}
@@ -2,9 +2,12 @@ module github.com/Skryldev/sql-toolkit
 
 go 1.25.0
 
+require (
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.34
+)
+
 require (
 	github.com/go-sql-driver/mysql v1.5.0 // indirect
 	github.com/golang-migrate/migrate/v4 v4.19.1 // indirect
-	github.com/lib/pq v1.10.9 // indirect
-	github.com/mattn/go-sqlite3 v1.14.34 // indirect
 )
@@ -0,0 +1,397 @@
+// Package matview declares materialized views and keeps them refreshed:
+// on a schedule, when a notification arrives, or on demand.
+//
+// Each View names a PostgreSQL materialized view and, optionally, the query
+// and unique key Create builds it from. Refresh runs REFRESH MATERIALIZED
+// VIEW — CONCURRENTLY when the view asks for it, so readers are not blocked
+// — and records the outcome in the matview_refreshes table (see
+// migrations/000009_create_matview_refreshes.up.sql), which Status reads.
+// MySQL and SQLite have no materialized views; there a View with a Query is
+// kept as a plain table whose contents Refresh replaces in one transaction.
+//
+//	m := matview.New(database, matview.Options{})
+//	m.MustRegister(matview.View{
+//	    Name:         "daily_sales",
+//	    Query:        `SELECT day, SUM(total) AS total FROM orders GROUP BY day`,
+//	    UniqueKey:    []string{"day"},
+//	    Concurrently: true,
+//	    Schedule:     "@every 15m", // interval: through the scheduler
+//	    Channel:      "orders",     // on-notify: e.g. from notify.InstallChangeTrigger
+//	})
+//	if err := m.Create(ctx); err != nil { ... }
+//	if err := m.Schedule(sched); err != nil { ... }
+//	go m.Listen(ctx, notify.NewPQSubscriber(dsn))
+//	err := m.Refresh(ctx, "daily_sales") // manual
+package matview
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/notify"
+	"github.com/Skryldev/sql-toolkit/scheduler"
+)
+
+// ErrUnknownView is returned for a view name that was not registered.
+var ErrUnknownView = errors.New("matview: unknown view")
+
+// View declares one materialized view and how it is refreshed. A view with
+// neither Schedule nor Channel is refreshed only by calling Refresh.
+type View struct {
+	// Name is the view's, optionally schema-qualified, name.
+	Name string
+	// Query is the SELECT defining the view. It is needed only by Create,
+	// and on dialects without materialized views.
+	Query string
+	// UniqueKey lists the columns of the unique index Create adds, which
+	// REFRESH ... CONCURRENTLY requires.
+	UniqueKey []string
+	// Concurrently refreshes without locking out readers, at the cost of a
+	// slower refresh. The view needs a unique index on plain columns; its
+	// first refresh, while it is not yet populated, is a plain one.
+	Concurrently bool
+	// Schedule, if set, is a scheduler spec ("@every 15m", "0 * * * *")
+	// on which Schedule's job refreshes the view.
+	Schedule string
+	// Channel, if set, is a notification channel on which Listen refreshes
+	// the view.
+	Channel string
+}
+
+// Options configures New. Every field is optional.
+type Options struct {
+	// Debounce is how long Listen waits after a notification before
+	// refreshing, folding every notification meanwhile into one refresh.
+	// Defaults to 1s.
+	Debounce time.Duration
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Status is a view's refresh history.
+type Status struct {
+	Name string
+	// RefreshedAt is when the last successful refresh finished, and
+	// Duration how long it took; zero if the view was never refreshed.
+	RefreshedAt time.Time
+	Duration    time.Duration
+	// AttemptedAt is when the last refresh, successful or not, finished.
+	AttemptedAt time.Time
+	// LastError is the last refresh's error; "" if it succeeded.
+	LastError string
+	// Refreshes counts successful refreshes.
+	Refreshes int64
+}
+
+// Manager holds the registered views.
+type Manager struct {
+	d      *db.DB
+	opts   Options
+	native bool // the dialect has materialized views
+
+	mu    sync.RWMutex
+	views map[string]View
+
+	sqlEnsure, sqlSucceeded, sqlFailed, sqlStatus, sqlList string
+}
+
+// New returns a Manager for views in d, recording refreshes in d's
+// matview_refreshes table.
+func New(d *db.DB, opts Options) *Manager {
+	if opts.Debounce <= 0 {
+		opts.Debounce = time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	ensure, err := db.InsertSQL(dialect, "matview_refreshes", []string{"name", "last_error"}, db.ConflictSkip, "name")
+	if err != nil {
+		panic(err) // static arguments; cannot fail
+	}
+	const cols = `name, refreshed_at, duration, attempted_at, last_error, refreshes`
+	return &Manager{
+		d:         d,
+		opts:      opts,
+		native:    dialect == db.DialectPostgres,
+		views:     make(map[string]View),
+		sqlEnsure: ensure,
+		sqlSucceeded: fmt.Sprintf(`
+			UPDATE matview_refreshes
+			SET    refreshed_at = %s, duration = %s, attempted_at = %s, last_error = '', refreshes = refreshes + 1
+			WHERE  name = %s`,
+			ph(1), ph(2), ph(3), ph(4)),
+		sqlFailed: fmt.Sprintf(`
+			UPDATE matview_refreshes SET attempted_at = %s, last_error = %s
+			WHERE  name = %s`,
+			ph(1), ph(2), ph(3)),
+		sqlStatus: fmt.Sprintf(`SELECT %s FROM matview_refreshes WHERE name = %s`, cols, ph(1)),
+		sqlList:   fmt.Sprintf(`SELECT %s FROM matview_refreshes ORDER BY name`, cols),
+	}
+}
+
+// Register adds v. Names must be unique. On dialects without materialized
+// views a View needs a Query; without one Register returns an error
+// wrapping errors.ErrUnsupported.
+func (m *Manager) Register(v View) error {
+	v.Query = strings.TrimRight(strings.TrimSpace(v.Query), ";")
+	switch {
+	case v.Name == "":
+		return errors.New("matview: view has no name")
+	case !m.native && v.Query == "":
+		return fmt.Errorf("matview: %w: %s: a view without Query on dialect %q", errors.ErrUnsupported, v.Name, m.d.Dialect())
+	case v.Concurrently && v.Query != "" && len(v.UniqueKey) == 0:
+		return fmt.Errorf("matview: %s: Concurrently needs a UniqueKey", v.Name)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, dup := m.views[v.Name]; dup {
+		return fmt.Errorf("matview: view %q already registered", v.Name)
+	}
+	m.views[v.Name] = v
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (m *Manager) MustRegister(v View) {
+	if err := m.Register(v); err != nil {
+		panic(err)
+	}
+}
+
+func (m *Manager) view(name string) (View, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.views[name]
+	if !ok {
+		return View{}, fmt.Errorf("%w: %s", ErrUnknownView, name)
+	}
+	return v, nil
+}
+
+// sorted returns the registered views by name.
+func (m *Manager) sorted() []View {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	views := make([]View, 0, len(m.views))
+	for _, v := range m.views {
+		views = append(views, v)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+// Create creates every registered view with a Query that does not exist
+// yet, populated, along with its UniqueKey index. Existing views are left
+// alone, even when their Query changed: drop them first to redefine them.
+func (m *Manager) Create(ctx context.Context) error {
+	dialect := m.d.Dialect()
+	for _, v := range m.sorted() {
+		if v.Query == "" {
+			continue
+		}
+		name := dialect.QuoteIdent(v.Name)
+		stmts := []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s AS %s`, name, v.Query)}
+		if m.native {
+			stmts = []string{fmt.Sprintf(`CREATE MATERIALIZED VIEW IF NOT EXISTS %s AS %s`, name, v.Query)}
+			if len(v.UniqueKey) > 0 {
+				cols := make([]string, len(v.UniqueKey))
+				for i, c := range v.UniqueKey {
+					cols[i] = dialect.QuoteIdent(c)
+				}
+				base := v.Name[strings.LastIndex(v.Name, ".")+1:]
+				stmts = append(stmts, fmt.Sprintf(`CREATE UNIQUE INDEX IF NOT EXISTS %s ON %s (%s)`,
+					dialect.QuoteIdent(base+"_key"), name, strings.Join(cols, ", ")))
+			}
+		}
+		for _, stmt := range stmts {
+			if _, err := m.d.Exec(db.NoTimeout(ctx), stmt); err != nil {
+				return fmt.Errorf("matview: create %s: %w", v.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ── Refreshing ───────────────────────────────────────────────────────────────
+
+// Refresh refreshes the named view now and records the outcome. It runs
+// without Config.DefaultTimeout; bound it with ctx if needed. Refreshes of
+// the same view from several callers queue up behind each other's locks.
+func (m *Manager) Refresh(ctx context.Context, name string) error {
+	v, err := m.view(name)
+	if err != nil {
+		return err
+	}
+	ctx = db.NoTimeout(ctx)
+	if _, err := m.d.Exec(ctx, m.sqlEnsure, name, ""); err != nil {
+		return fmt.Errorf("matview: refresh %s: %w", name, err)
+	}
+	started := time.Now()
+	err = m.d.ExecTx(ctx, func(tx *db.Tx) error {
+		if err := m.refresh(ctx, tx, v); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, m.sqlSucceeded,
+			tx.Now().UnixMicro(), time.Since(started).Microseconds(), tx.Now().UnixMicro(), name)
+		return err
+	})
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if len(msg) > 1024 { // the column's size; cut on a rune boundary
+		n := 1024
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n--
+		}
+		msg = msg[:n]
+	}
+	// Record the failure even when ctx was cancelled.
+	recCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if _, rerr := m.d.Exec(recCtx, m.sqlFailed, db.NowFrom(m.d).UnixMicro(), msg, name); rerr != nil {
+		m.opts.Logger.WarnContext(ctx, "matview: recording failed refresh", slog.String("view", name), slog.Any("error", rerr))
+	}
+	return fmt.Errorf("matview: refresh %s: %w", name, err)
+}
+
+func (m *Manager) refresh(ctx context.Context, tx *db.Tx, v View) error {
+	name := tx.Dialect().QuoteIdent(v.Name)
+	if !m.native {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+name); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `INSERT INTO `+name+` `+v.Query)
+		return err
+	}
+	stmt := `REFRESH MATERIALIZED VIEW ` + name
+	if v.Concurrently {
+		// CONCURRENTLY fails on a view that was never populated.
+		var populated bool
+		if err := tx.QueryRow(ctx, `SELECT relispopulated FROM pg_class WHERE oid = $1::regclass`, name).Scan(&populated); err != nil {
+			return err
+		}
+		if populated {
+			stmt = `REFRESH MATERIALIZED VIEW CONCURRENTLY ` + name
+		}
+	}
+	_, err := tx.Exec(ctx, stmt)
+	return err
+}
+
+// Schedule registers a job named "matview:<name>" with s for every view
+// with a Schedule. The scheduler runs each activation on one instance of
+// the fleet.
+func (m *Manager) Schedule(s *scheduler.Scheduler) error {
+	for _, v := range m.sorted() {
+		if v.Schedule == "" {
+			continue
+		}
+		name := v.Name
+		if err := s.Register("matview:"+name, v.Schedule, func(ctx context.Context) error {
+			return m.Refresh(ctx, name)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Listen refreshes the views with a Channel whenever a notification
+// arrives on it, Options.Debounce after the first of a burst, until ctx is
+// cancelled. A notification arriving during a refresh causes another one
+// afterwards, so the view always catches up with the last change. Refresh
+// errors are logged and recorded, not returned. Every instance running
+// Listen refreshes; run it on one, or use Schedule, to refresh only once.
+func (m *Manager) Listen(ctx context.Context, sub notify.Subscriber) error {
+	byChannel := make(map[string][]string)
+	for _, v := range m.sorted() {
+		if v.Channel != "" {
+			byChannel[v.Channel] = append(byChannel[v.Channel], v.Name)
+		}
+	}
+	if len(byChannel) == 0 {
+		return errors.New("matview: no view has a Channel")
+	}
+	var wg sync.WaitGroup
+	for channel, names := range byChannel {
+		ch, err := sub.Listen(ctx, channel)
+		if err != nil {
+			wg.Wait()
+			return err
+		}
+		wg.Go(func() { m.follow(ctx, ch, names) })
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// follow refreshes names after each burst of notifications on ch. An empty
+// payload, meaning notifications were missed, triggers a refresh too.
+func (m *Manager) follow(ctx context.Context, ch <-chan string, names []string) {
+	var due <-chan time.Time
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			if due == nil {
+				due = time.After(m.opts.Debounce)
+			}
+		case <-due:
+			due = nil
+			for _, name := range names {
+				if err := m.Refresh(ctx, name); err != nil && ctx.Err() == nil {
+					m.opts.Logger.WarnContext(ctx, "matview: refresh failed", slog.String("view", name), slog.Any("error", err))
+				}
+			}
+		}
+	}
+}
+
+// ── Status ───────────────────────────────────────────────────────────────────
+
+func scanStatus(r db.RowScanner) (Status, error) {
+	var (
+		s                      Status
+		refreshed, took, tried int64
+	)
+	err := r.Scan(&s.Name, &refreshed, &took, &tried, &s.LastError, &s.Refreshes)
+	s.RefreshedAt = fromMicro(refreshed)
+	s.Duration = time.Duration(took) * time.Microsecond
+	s.AttemptedAt = fromMicro(tried)
+	return s, err
+}
+
+func fromMicro(us int64) time.Time {
+	if us == 0 {
+		return time.Time{}
+	}
+	return time.UnixMicro(us)
+}
+
+// Status returns the named view's refresh history; a view never refreshed
+// has a zero Status with its Name.
+func (m *Manager) Status(ctx context.Context, name string) (Status, error) {
+	s, err := db.Get(ctx, m.d, scanStatus, m.sqlStatus, name)
+	if db.IsNotFound(err) {
+		return Status{Name: name}, nil
+	}
+	return s, err
+}
+
+// List returns the refresh history of every view ever refreshed, by name.
+func (m *Manager) List(ctx context.Context) ([]Status, error) {
+	return db.Select(ctx, m.d, scanStatus, m.sqlList)
+}
@@ -0,0 +1,158 @@
+package matview_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	"github.com/Skryldev/sql-toolkit/db/notify"
+	"github.com/Skryldev/sql-toolkit/matview"
+	"github.com/Skryldev/sql-toolkit/scheduler"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	d := dbtest.OpenSQLite(t, "000005_create_scheduled_jobs", "000009_create_matview_refreshes")
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, customer TEXT NOT NULL, amount INTEGER NOT NULL)`,
+		`INSERT INTO orders (customer, amount) VALUES ('ann', 10), ('ann', 5), ('bo', 7)`,
+	} {
+		if _, err := d.Exec(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+var totals = matview.View{
+	Name:      "customer_totals",
+	Query:     `SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer;`,
+	UniqueKey: []string{"customer"},
+	Schedule:  "@every 15m",
+	Channel:   "orders",
+}
+
+func total(t *testing.T, d *db.DB, customer string) (n int64) {
+	t.Helper()
+	if err := d.QueryRow(context.Background(), `SELECT total FROM customer_totals WHERE customer = ?`, customer).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRefresh(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	m := matview.New(d, matview.Options{})
+	m.MustRegister(totals)
+
+	if err := m.Register(totals); err == nil {
+		t.Fatal("registering a view twice must fail")
+	}
+	if err := m.Register(matview.View{Name: "bare"}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("view without Query on SQLite: expected ErrUnsupported, got %v", err)
+	}
+	if err := m.Refresh(ctx, "missing"); !errors.Is(err, matview.ErrUnknownView) {
+		t.Fatalf("expected ErrUnknownView, got %v", err)
+	}
+
+	if err := m.Create(ctx); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if got := total(t, d, "ann"); got != 15 {
+		t.Fatalf("created view: ann = %d, want 15", got)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO orders (customer, amount) VALUES ('ann', 1)`); err != nil {
+		t.Fatal(err)
+	}
+	if got := total(t, d, "ann"); got != 15 {
+		t.Fatalf("view changed before a refresh: ann = %d", got)
+	}
+	if err := m.Refresh(ctx, "customer_totals"); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if got := total(t, d, "ann"); got != 16 {
+		t.Fatalf("after refresh: ann = %d, want 16", got)
+	}
+	s, err := m.Status(ctx, "customer_totals")
+	if err != nil || s.Refreshes != 1 || s.RefreshedAt.IsZero() || s.LastError != "" {
+		t.Fatalf("status = %+v, %v", s, err)
+	}
+
+	if _, err := d.Exec(ctx, `ALTER TABLE orders RENAME TO old_orders`); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Refresh(ctx, "customer_totals"); err == nil {
+		t.Fatal("refresh from a missing table must fail")
+	}
+	if got := total(t, d, "ann"); got != 16 {
+		t.Fatalf("failed refresh changed the view: ann = %d", got)
+	}
+	list, err := m.List(ctx)
+	if err != nil || len(list) != 1 || list[0].Refreshes != 1 || list[0].LastError == "" ||
+		list[0].AttemptedAt.Before(list[0].RefreshedAt) {
+		t.Fatalf("list after failure = %+v, %v", list, err)
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	m := matview.New(d, matview.Options{})
+	m.MustRegister(totals)
+	m.MustRegister(matview.View{Name: "manual", Query: `SELECT 1 AS one`})
+
+	s := scheduler.New(d, scheduler.Options{})
+	if err := m.Schedule(s); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RunDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := s.Jobs(ctx)
+	if err != nil || len(jobs) != 1 || jobs[0].Name != "matview:customer_totals" {
+		t.Fatalf("jobs = %+v, %v", jobs, err)
+	}
+}
+
+func TestListen(t *testing.T) {
+	d := newTestDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := matview.New(d, matview.Options{Debounce: 10 * time.Millisecond})
+	m.MustRegister(totals)
+	if err := m.Create(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO orders (customer, amount) VALUES ('bo', 3)`); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := notify.NewHub()
+	done := make(chan error, 1)
+	go func() { done <- m.Listen(ctx, hub) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_ = hub.Notify(ctx, "orders", `{"table":"orders","op":"INSERT","key":"4"}`)
+		if s, _ := m.Status(ctx, "customer_totals"); s.Refreshes > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no refresh after notifications")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := total(t, d, "bo"); got != 10 {
+		t.Fatalf("after notified refresh: bo = %d, want 10", got)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Listen returned %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ErrDestructive is returned by CheckDown when a down migration would drop
+// or empty a table or column and destructive migrations were not allowed.
+var ErrDestructive = errors.New("migrate: destructive down migration")
+
+// Destructive is a statement that loses data: DROP TABLE, ALTER TABLE ...
+// DROP COLUMN or TRUNCATE. Column is empty except for DROP COLUMN.
+type Destructive struct {
+	Version uint
+	Kind    string // "DROP TABLE", "DROP COLUMN" or "TRUNCATE"
+	Table   string
+	Column  string
+}
+
+func (d Destructive) String() string {
+	if d.Column != "" {
+		return fmt.Sprintf("%s %s.%s", d.Kind, d.Table, d.Column)
+	}
+	return d.Kind + " " + d.Table
+}
+
+var (
+	commentRe   = regexp.MustCompile(`(?s)/\*.*?\*/|--[^\n]*`)
+	dropTableRe = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.+?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	truncateRe  = regexp.MustCompile(`(?is)^TRUNCATE\s+(?:TABLE\s+)?(?:ONLY\s+)?(.+?)(?:\s+(?:RESTART|CONTINUE)\s+IDENTITY)?(?:\s+(?:CASCADE|RESTRICT))?$`)
+	alterRe     = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(\S+)\s+(.*)$`)
+	dropColRe   = regexp.MustCompile(`(?i)\bDROP\s+(COLUMN\s+)?(?:IF\s+EXISTS\s+)?([^\s,]+)`)
+)
+
+// notColumns follow DROP inside ALTER TABLE without naming a column.
+var notColumns = map[string]bool{
+	"CONSTRAINT": true, "INDEX": true, "KEY": true, "PRIMARY": true, "FOREIGN": true,
+	"DEFAULT": true, "NOT": true, "CHECK": true, "PARTITION": true, "IDENTITY": true,
+	"EXPRESSION": true,
+}
+
+// DestructiveStatements lists the destructive statements in a migration
+// script. Comments are ignored; statements are split on semicolons, so
+// function bodies containing them may be misread.
+func DestructiveStatements(script string) []Destructive {
+	var out []Destructive
+	for _, stmt := range strings.Split(commentRe.ReplaceAllString(script, ""), ";") {
+		stmt = strings.TrimSpace(stmt)
+		if m := dropTableRe.FindStringSubmatch(stmt); m != nil {
+			for _, t := range splitNames(m[1]) {
+				out = append(out, Destructive{Kind: "DROP TABLE", Table: t})
+			}
+			continue
+		}
+		if m := truncateRe.FindStringSubmatch(stmt); m != nil {
+			for _, t := range splitNames(m[1]) {
+				out = append(out, Destructive{Kind: "TRUNCATE", Table: t})
+			}
+			continue
+		}
+		if m := alterRe.FindStringSubmatch(stmt); m != nil {
+			table := unquoteIdent(m[1])
+			for _, d := range dropColRe.FindAllStringSubmatch(m[2], -1) {
+				if d[1] == "" && notColumns[strings.ToUpper(d[2])] {
+					continue
+				}
+				out = append(out, Destructive{Kind: "DROP COLUMN", Table: table, Column: unquoteIdent(d[2])})
+			}
+		}
+	}
+	return out
+}
+
+func splitNames(list string) []string {
+	var out []string
+	for _, n := range strings.Split(list, ",") {
+		if n = unquoteIdent(strings.TrimSpace(n)); n != "" {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+func unquoteIdent(s string) string {
+	return strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "").Replace(s)
+}
+
+// Impact is a destructive statement with the rows it affects. Rows is -1
+// when they could not be counted, e.g. because the table does not exist.
+type Impact struct {
+	Destructive
+	Rows int64
+	// Estimated is true when Rows comes from planner statistics rather than
+	// a count.
+	Estimated bool
+}
+
+// DownImpact reads the down files of ms and estimates the rows each
+// destructive statement in them would affect on q: from planner statistics
+// on PostgreSQL and MySQL, by counting elsewhere. ms are inspected in the
+// order given, which should be the order they will run in.
+func DownImpact(ctx context.Context, q db.Querier, ms []Migration) ([]Impact, error) {
+	var out []Impact
+	for _, m := range ms {
+		if m.Down == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no down file", m.Version, m.Name)
+		}
+		script, err := os.ReadFile(m.Down)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		for _, d := range DestructiveStatements(string(script)) {
+			d.Version = m.Version
+			rows, estimated := tableRows(ctx, q, d.Table)
+			out = append(out, Impact{Destructive: d, Rows: rows, Estimated: estimated})
+		}
+	}
+	return out, nil
+}
+
+func tableRows(ctx context.Context, q db.Querier, table string) (int64, bool) {
+	d := db.DialectFrom(q)
+	var n int64
+	var err error
+	switch d {
+	case db.DialectPostgres:
+		err = q.QueryRow(ctx, `SELECT reltuples::bigint FROM pg_class WHERE oid = to_regclass($1)`, table).Scan(&n)
+		if err == nil && n >= 0 {
+			return n, true
+		}
+	case db.DialectMySQL:
+		err = q.QueryRow(ctx, `SELECT COALESCE(TABLE_ROWS, -1) FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`, table).Scan(&n)
+		if err == nil && n >= 0 {
+			return n, true
+		}
+	}
+	if err := q.QueryRow(ctx, "SELECT COUNT(*) FROM "+d.QuoteIdent(table)).Scan(&n); err != nil {
+		return -1, false
+	}
+	return n, false
+}
+
+// CheckDown returns an error wrapping ErrDestructive that lists impacts,
+// unless there are none or allow is set.
+func CheckDown(impacts []Impact, allow bool) error {
+	if len(impacts) == 0 || allow {
+		return nil
+	}
+	parts := make([]string, len(impacts))
+	for i, im := range impacts {
+		parts[i] = im.String()
+	}
+	return fmt.Errorf("%w: %s", ErrDestructive, strings.Join(parts, ", "))
+}
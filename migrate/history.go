@@ -0,0 +1,100 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// AppliedMigration is one row of the migration history.
+type AppliedMigration struct {
+	Version   uint
+	Name      string
+	AppliedAt time.Time
+	// Duration is how long the up migration took to run.
+	Duration time.Duration
+	// Checksum is the SHA-256 of the up file when it was applied; compare it
+	// with Migration.Checksum to spot files edited after the fact.
+	Checksum string
+}
+
+// HistoryTable is the table the history is kept in. Times are Unix
+// microseconds, as in the other tables of this module.
+const HistoryTable = "schema_migration_history"
+
+const (
+	sqlCreateHistory = `
+		CREATE TABLE IF NOT EXISTS schema_migration_history (
+		    version     BIGINT       PRIMARY KEY,
+		    name        VARCHAR(255) NOT NULL,
+		    applied_at  BIGINT       NOT NULL,
+		    duration_us BIGINT       NOT NULL,
+		    checksum    VARCHAR(64)  NOT NULL
+		)`
+
+	sqlRecordMigration = `
+		INSERT INTO schema_migration_history (version, name, applied_at, duration_us, checksum)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	sqlForgetMigration = `
+		DELETE FROM schema_migration_history WHERE version = $1`
+
+	sqlHistory = `
+		SELECT version, name, applied_at, duration_us, checksum
+		FROM   schema_migration_history
+		ORDER  BY version`
+)
+
+// EnsureHistory creates the history table if it does not exist.
+func EnsureHistory(ctx context.Context, q db.Querier) error {
+	_, err := q.Exec(ctx, sqlCreateHistory)
+	return err
+}
+
+// Record adds an applied migration to the history, replacing an earlier
+// entry for the same version (one that was rolled back without Forget), and
+// publishes db.EventMigrationApplied.
+func Record(ctx context.Context, q db.Querier, m AppliedMigration) error {
+	if err := Forget(ctx, q, m.Version); err != nil {
+		return err
+	}
+	query, args := db.DialectFrom(q).Rebind(sqlRecordMigration, []any{
+		int64(m.Version), m.Name, m.AppliedAt.UnixMicro(), m.Duration.Microseconds(), m.Checksum,
+	})
+	if _, err := q.Exec(ctx, query, args...); err != nil {
+		return err
+	}
+	db.Events().Publish(db.Event{Kind: db.EventMigrationApplied, Attrs: map[string]any{
+		"version": m.Version, "name": m.Name, "duration": m.Duration,
+	}})
+	return nil
+}
+
+// Forget removes a rolled-back migration from the history.
+func Forget(ctx context.Context, q db.Querier, version uint) error {
+	query, args := db.DialectFrom(q).Rebind(sqlForgetMigration, []any{int64(version)})
+	_, err := q.Exec(ctx, query, args...)
+	return err
+}
+
+// History returns the applied migrations ordered by version; the last one
+// is the schema revision the database runs. A database never migrated by
+// cmd/migrate has an empty history.
+func History(ctx context.Context, q db.Querier) ([]AppliedMigration, error) {
+	if err := EnsureHistory(ctx, q); err != nil {
+		return nil, fmt.Errorf("migrate: history: %w", err)
+	}
+	return db.Select(ctx, q, func(r db.RowScanner) (AppliedMigration, error) {
+		var (
+			m                   AppliedMigration
+			version, at, micros int64
+		)
+		err := r.Scan(&version, &m.Name, &at, &micros, &m.Checksum)
+		m.Version = uint(version)
+		m.AppliedAt = time.UnixMicro(at)
+		m.Duration = time.Duration(micros) * time.Microsecond
+		return m, err
+	}, sqlHistory)
+}
@@ -0,0 +1,238 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ErrUnsafe is returned by CheckLint when migrations would take locks that
+// block traffic while they run.
+var ErrUnsafe = errors.New("migrate: migration is not safe to run online")
+
+// Severity ranks lint findings. Errors block the deploy by default; warnings
+// only do so in strict mode.
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Finding is a statement that would lock a table for longer than a request
+// can wait, with the reason and a rewrite that avoids the lock.
+type Finding struct {
+	Version   uint
+	Rule      string // "add-column-not-null", "change-column-type", "set-not-null" or "create-index"
+	Severity  Severity
+	Table     string
+	Statement string
+	Message   string
+	// Rewrite is the safer form: SQL, with comments for steps that belong
+	// in application code or in a later migration.
+	Rewrite string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s: %s", f.Severity, f.Rule, f.Message)
+}
+
+var (
+	createTableRe = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:TEMP|TEMPORARY|UNLOGGED)\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([^\s(]+)`)
+	createIndexRe = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+|FULLTEXT\s+|SPATIAL\s+)?INDEX\s+(CONCURRENTLY\s+)?.*?\bON\s+(?:ONLY\s+)?([^\s(]+)`)
+	addColumnRe   = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(\S+)\s+(.+)$`)
+	addIndexRe    = regexp.MustCompile(`(?is)^ADD\s+(?:UNIQUE|INDEX|KEY|FULLTEXT|SPATIAL)\b`)
+	alterTypeRe   = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(\S+)\s+(?:SET\s+DATA\s+)?TYPE\s+(.+)$`)
+	setNotNullRe  = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(\S+)\s+SET\s+NOT\s+NULL$`)
+	modifyRe      = regexp.MustCompile(`(?is)^(?:MODIFY|CHANGE)\s+(?:COLUMN\s+)?(\S+)`)
+	notNullRe     = regexp.MustCompile(`(?i)\bNOT\s+NULL\b`)
+	defaultRe     = regexp.MustCompile(`(?i)\bDEFAULT\b`)
+	algorithmRe   = regexp.MustCompile(`(?i)\bALGORITHM\s*=`)
+	indexWordRe   = regexp.MustCompile(`(?i)\bINDEX\s+`)
+)
+
+// notAddColumn follow ADD inside ALTER TABLE without naming a column.
+var notAddColumn = map[string]bool{
+	"CONSTRAINT": true, "INDEX": true, "KEY": true, "PRIMARY": true, "UNIQUE": true,
+	"FOREIGN": true, "CHECK": true, "PARTITION": true, "FULLTEXT": true, "SPATIAL": true,
+}
+
+// LintScript checks a migration script for statements that lock busy tables
+// on PostgreSQL or MySQL: adding a NOT NULL column without a default,
+// changing a column's type, setting NOT NULL on an existing column and
+// building an index in place. Tables created by the same script are exempt,
+// as nothing uses them yet. Other dialects have no findings.
+func LintScript(d db.Dialect, script string) []Finding {
+	if d != db.DialectPostgres && d != db.DialectMySQL {
+		return nil
+	}
+	created := map[string]bool{}
+	var out []Finding
+	for _, stmt := range strings.Split(commentRe.ReplaceAllString(script, ""), ";") {
+		stmt = strings.TrimSpace(stmt)
+		if m := createTableRe.FindStringSubmatch(stmt); m != nil {
+			created[unquoteIdent(m[1])] = true
+			continue
+		}
+		if m := createIndexRe.FindStringSubmatch(stmt); m != nil {
+			if table := unquoteIdent(m[2]); !created[table] {
+				out = append(out, lintCreateIndex(d, stmt, table, m[1] != "")...)
+			}
+			continue
+		}
+		if m := alterRe.FindStringSubmatch(stmt); m != nil {
+			if table := unquoteIdent(m[1]); !created[table] {
+				for _, clause := range splitClauses(m[2]) {
+					out = append(out, lintAlter(d, stmt, table, clause)...)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func lintCreateIndex(d db.Dialect, stmt, table string, concurrently bool) []Finding {
+	switch {
+	case d == db.DialectPostgres && !concurrently:
+		at := indexWordRe.FindStringIndex(stmt)[1]
+		return []Finding{{
+			Rule: "create-index", Severity: SeverityError, Table: table, Statement: stmt,
+			Message: fmt.Sprintf("CREATE INDEX blocks writes to %s until the index is built", table),
+			Rewrite: "-- CONCURRENTLY cannot run inside a transaction: give it a migration file of its own.\n" +
+				stmt[:at] + "CONCURRENTLY " + stmt[at:] + ";",
+		}}
+	case d == db.DialectMySQL && !algorithmRe.MatchString(stmt):
+		return []Finding{{
+			Rule: "create-index", Severity: SeverityWarning, Table: table, Statement: stmt,
+			Message: fmt.Sprintf("CREATE INDEX may copy %s under a write lock when online DDL is not possible", table),
+			Rewrite: "-- Fails instead of locking when the index cannot be built online.\n" +
+				stmt + " ALGORITHM=INPLACE LOCK=NONE;",
+		}}
+	}
+	return nil
+}
+
+func lintAlter(d db.Dialect, stmt, table, clause string) []Finding {
+	quote := d.QuoteIdent
+	finding := func(rule string, sev Severity, msg, rewrite string) []Finding {
+		return []Finding{{Rule: rule, Severity: sev, Table: table, Statement: stmt, Message: msg, Rewrite: rewrite}}
+	}
+
+	if m := addColumnRe.FindStringSubmatch(clause); m != nil && !notAddColumn[strings.ToUpper(m[1])] {
+		col, def := unquoteIdent(m[1]), m[2]
+		if !notNullRe.MatchString(def) || defaultRe.MatchString(def) {
+			return nil
+		}
+		nullable := strings.TrimSpace(notNullRe.ReplaceAllString(def, ""))
+		if d == db.DialectMySQL {
+			return finding("add-column-not-null", SeverityWarning,
+				fmt.Sprintf("NOT NULL column %s.%s without a default fills existing rows with the type's implicit zero value", table, col),
+				fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT <value>;", quote(table), quote(col), def))
+		}
+		check := notNullCheck(table, col)
+		return finding("add-column-not-null", SeverityError,
+			fmt.Sprintf("NOT NULL column %s.%s without a default fails on a table with rows", table, col),
+			fmt.Sprintf("ALTER TABLE %[1]s ADD COLUMN %[2]s %[3]s;\n"+
+				"-- Backfill %[2]s in batches, then in a later migration:\n"+
+				"ALTER TABLE %[1]s ADD CONSTRAINT %[4]s CHECK (%[2]s IS NOT NULL) NOT VALID;\n"+
+				"ALTER TABLE %[1]s VALIDATE CONSTRAINT %[4]s;\n"+
+				"ALTER TABLE %[1]s ALTER COLUMN %[2]s SET NOT NULL;\n"+
+				"ALTER TABLE %[1]s DROP CONSTRAINT %[4]s;", quote(table), quote(col), nullable, check))
+	}
+
+	var col, typ string
+	if m := alterTypeRe.FindStringSubmatch(clause); m != nil && d == db.DialectPostgres {
+		col, typ = unquoteIdent(m[1]), strings.TrimSpace(m[2])
+	} else if m := modifyRe.FindStringSubmatch(clause); m != nil && d == db.DialectMySQL {
+		col, typ = unquoteIdent(m[1]), "<type>"
+	}
+	if col != "" {
+		return finding("change-column-type", SeverityError,
+			fmt.Sprintf("changing the type of %s.%s rewrites the table under an exclusive lock", table, col),
+			fmt.Sprintf("ALTER TABLE %[1]s ADD COLUMN %[2]s %[3]s;\n"+
+				"-- Write both columns from the application, backfill %[2]s in batches,\n"+
+				"-- switch reads to it, then in a later migration drop %[4]s and rename %[2]s.",
+				quote(table), quote(col+"_new"), typ, quote(col)))
+	}
+
+	if m := setNotNullRe.FindStringSubmatch(clause); m != nil && d == db.DialectPostgres {
+		col := unquoteIdent(m[1])
+		check := notNullCheck(table, col)
+		return finding("set-not-null", SeverityWarning,
+			fmt.Sprintf("SET NOT NULL scans %s under an exclusive lock", table),
+			fmt.Sprintf("ALTER TABLE %[1]s ADD CONSTRAINT %[3]s CHECK (%[2]s IS NOT NULL) NOT VALID;\n"+
+				"ALTER TABLE %[1]s VALIDATE CONSTRAINT %[3]s;\n"+
+				"-- PostgreSQL 12+ skips the scan when a valid check proves the column has no NULLs.\n"+
+				"ALTER TABLE %[1]s ALTER COLUMN %[2]s SET NOT NULL;\n"+
+				"ALTER TABLE %[1]s DROP CONSTRAINT %[3]s;", quote(table), quote(col), check))
+	}
+
+	if addIndexRe.MatchString(clause) && d == db.DialectMySQL && !algorithmRe.MatchString(stmt) {
+		return finding("create-index", SeverityWarning,
+			fmt.Sprintf("adding an index may copy %s under a write lock when online DDL is not possible", table),
+			"-- Fails instead of locking when the index cannot be built online.\n"+stmt+", ALGORITHM=INPLACE, LOCK=NONE;")
+	}
+	return nil
+}
+
+// notNullCheck names the temporary check constraint that stands in for
+// NOT NULL while it is validated.
+func notNullCheck(table, col string) string {
+	return db.DialectPostgres.QuoteIdent(table[strings.LastIndex(table, ".")+1:] + "_" + col + "_not_null")
+}
+
+// splitClauses splits the body of ALTER TABLE on the commas that separate
+// its clauses, leaving those inside parentheses alone.
+func splitClauses(body string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, r := range body {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, strings.TrimSpace(body[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(out, strings.TrimSpace(body[start:]))
+}
+
+// Lint reads the up files of ms and lints them for dialect d.
+func Lint(d db.Dialect, ms []Migration) ([]Finding, error) {
+	var out []Finding
+	for _, m := range ms {
+		script, err := os.ReadFile(m.Up)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %w", err)
+		}
+		for _, f := range LintScript(d, string(script)) {
+			f.Version = m.Version
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// CheckLint returns an error wrapping ErrUnsafe that lists the findings of
+// error severity, or of any severity when strict is set.
+func CheckLint(findings []Finding, strict bool) error {
+	var parts []string
+	for _, f := range findings {
+		if strict || f.Severity == SeverityError {
+			parts = append(parts, fmt.Sprintf("%d: %s", f.Version, f))
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnsafe, strings.Join(parts, "; "))
+}
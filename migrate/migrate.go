@@ -0,0 +1,110 @@
+// Package migrate complements cmd/migrate (golang-migrate) with what its
+// schema_migrations table does not keep: which migrations ran, when, for how
+// long, and from which file contents.
+//
+// Migrations are the NNNNNN_name.up.sql / .down.sql pairs in migrations/.
+// cmd/migrate applies them one step at a time and records each step in the
+// schema_migration_history table, which History reads back for admin pages
+// and health endpoints:
+//
+//	applied, err := migrate.History(ctx, database)
+//	current := applied[len(applied)-1]
+//	fmt.Printf("schema at %d (%s), applied %s\n", current.Version, current.Name, current.AppliedAt)
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Migration is one numbered migration on disk.
+type Migration struct {
+	Version uint
+	Name    string
+	// Up and Down are the paths of the two files; Down is empty when the
+	// migration has no down file.
+	Up, Down string
+}
+
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Load lists the migrations in dir ordered by version. Files not named
+// NNNN_name.up.sql or NNNN_name.down.sql are ignored; a down file without its
+// up file, or two names for one version, is an error.
+func Load(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	byVersion := map[uint]*Migration{}
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migrate: %s: %w", e.Name(), err)
+		}
+		mig := byVersion[uint(v)]
+		if mig == nil {
+			mig = &Migration{Version: uint(v), Name: m[2]}
+			byVersion[uint(v)] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migrate: version %d has two names: %s and %s", v, mig.Name, m[2])
+		}
+		path := filepath.Join(dir, e.Name())
+		if m[3] == "up" {
+			mig.Up = path
+		} else {
+			mig.Down = path
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Checksum returns the SHA-256 of the migration's up file, hex encoded.
+func (m Migration) Checksum() (string, error) {
+	b, err := os.ReadFile(m.Up)
+	if err != nil {
+		return "", fmt.Errorf("migrate: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// OpenURL opens a *db.DB for a golang-migrate database URL
+// (postgres://, postgresql://, mysql://, sqlite3://), so one DATABASE_URL
+// serves both the migrator and the history table.
+func OpenURL(url string) (*db.DB, error) {
+	driver, dsn := "", url
+	switch {
+	case strings.HasPrefix(url, "postgres://"), strings.HasPrefix(url, "postgresql://"):
+		driver = "postgres"
+	case strings.HasPrefix(url, "mysql://"):
+		driver, dsn = "mysql", strings.TrimPrefix(url, "mysql://")
+	case strings.HasPrefix(url, "sqlite3://"):
+		driver, dsn = "sqlite3", strings.TrimPrefix(url, "sqlite3://")
+	default:
+		return nil, fmt.Errorf("migrate: unsupported database URL scheme in %q", url)
+	}
+	return db.Open(db.Config{DriverName: driver, DSN: dsn})
+}
@@ -0,0 +1,298 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/migrate"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"000002_add_email.up.sql":      "ALTER TABLE users ADD COLUMN email TEXT;",
+		"000001_create_users.up.sql":   "CREATE TABLE users (id INTEGER);",
+		"000001_create_users.down.sql": "DROP TABLE users;",
+		"README.md":                    "not a migration",
+	})
+	ms, err := migrate.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].Version != 1 || ms[0].Name != "create_users" || ms[0].Down == "" || ms[1].Down != "" {
+		t.Fatalf("unexpected migrations: %+v", ms)
+	}
+	if sum, err := ms[0].Checksum(); err != nil || len(sum) != 64 {
+		t.Fatalf("checksum = %q, %v", sum, err)
+	}
+
+	orphan := writeFiles(t, map[string]string{"000003_x.down.sql": "SELECT 1;"})
+	if _, err := migrate.Load(orphan); err == nil {
+		t.Fatal("a down file without an up file should be rejected")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if h, err := migrate.History(ctx, d); err != nil || len(h) != 0 {
+		t.Fatalf("fresh database: %v, %v", h, err)
+	}
+	var applied []any
+	off := db.Events().On(func(ev db.Event) {
+		if ev.Kind == db.EventMigrationApplied {
+			applied = append(applied, ev.Attrs["version"])
+		}
+	})
+	defer off()
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, m := range []migrate.AppliedMigration{
+		{Version: 2, Name: "add_email", AppliedAt: at.Add(time.Minute), Duration: 40 * time.Millisecond, Checksum: "bb"},
+		{Version: 1, Name: "create_users", AppliedAt: at, Duration: 3 * time.Millisecond, Checksum: "aa"},
+	} {
+		if err := migrate.Record(ctx, d, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(applied) != 2 || applied[0] != uint(2) || applied[1] != uint(1) {
+		t.Fatalf("published versions = %v, want [2 1]", applied)
+	}
+	if err := migrate.Forget(ctx, d, 2); err != nil {
+		t.Fatal(err)
+	}
+	h, err := migrate.History(ctx, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(h) != 1 || h[0].Name != "create_users" || !h[0].AppliedAt.Equal(at) ||
+		h[0].Duration != 3*time.Millisecond || h[0].Checksum != "aa" {
+		t.Fatalf("unexpected history: %+v", h)
+	}
+}
+
+func TestDestructiveStatements(t *testing.T) {
+	got := destructiveString(migrate.DestructiveStatements(`
+		-- DROP TABLE commented_out;
+		DROP TABLE IF EXISTS outbox_consumed, "outbox" CASCADE;
+		ALTER TABLE users DROP COLUMN nickname, DROP CONSTRAINT users_email_key;
+		ALTER TABLE users ALTER COLUMN name DROP NOT NULL;
+		ALTER TABLE users ALTER COLUMN name DROP DEFAULT;
+		TRUNCATE TABLE sessions;
+		CREATE INDEX idx ON users (email);
+	`))
+	want := "DROP TABLE outbox_consumed|DROP TABLE outbox|DROP COLUMN users.nickname|TRUNCATE sessions"
+	if got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+}
+
+func destructiveString(ds []migrate.Destructive) string {
+	parts := make([]string, len(ds))
+	for i, d := range ds {
+		parts[i] = d.String()
+	}
+	return strings.Join(parts, "|")
+}
+
+func TestDownImpact(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, err := d.Exec(ctx, `CREATE TABLE users (id INTEGER, nickname TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Exec(ctx, `INSERT INTO users (id) VALUES (1), (2), (3)`); err != nil {
+		t.Fatal(err)
+	}
+	dir := writeFiles(t, map[string]string{
+		"000001_create_users.up.sql":   "CREATE TABLE users (id INTEGER);",
+		"000001_create_users.down.sql": "DROP TABLE users;",
+		"000002_nickname.up.sql":       "ALTER TABLE users ADD COLUMN nickname TEXT;",
+		"000002_nickname.down.sql":     "ALTER TABLE users DROP COLUMN nickname;",
+		"000003_index.up.sql":          "CREATE INDEX idx_users_id ON users (id);",
+		"000003_index.down.sql":        "DROP INDEX idx_users_id;",
+	})
+	ms, err := migrate.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	impacts, err := migrate.DownImpact(ctx, d, []migrate.Migration{ms[2]})
+	if err != nil || len(impacts) != 0 {
+		t.Fatalf("dropping an index loses no data: %v, %v", impacts, err)
+	}
+	if err := migrate.CheckDown(impacts, false); err != nil {
+		t.Fatalf("nothing destructive, got %v", err)
+	}
+
+	impacts, err = migrate.DownImpact(ctx, d, []migrate.Migration{ms[1], ms[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impacts) != 2 || impacts[0].Column != "nickname" || impacts[0].Rows != 3 || impacts[1].Version != 1 {
+		t.Fatalf("unexpected impacts: %+v", impacts)
+	}
+	if err := migrate.CheckDown(impacts, false); !errors.Is(err, migrate.ErrDestructive) {
+		t.Fatalf("expected ErrDestructive, got %v", err)
+	}
+	if err := migrate.CheckDown(impacts, true); err != nil {
+		t.Fatalf("allowed: %v", err)
+	}
+}
+
+func TestSquash(t *testing.T) {
+	ctx := context.Background()
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	for _, stmt := range []string{
+		`CREATE TABLE schema_migrations (version BIGINT, dirty BOOLEAN)`,
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`CREATE INDEX idx_users_email ON users (email)`,
+	} {
+		if _, err := d.Exec(ctx, stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := writeFiles(t, map[string]string{
+		"000001_create_users.up.sql":   "CREATE TABLE users (id INTEGER PRIMARY KEY);",
+		"000001_create_users.down.sql": "DROP TABLE users;",
+		"000002_email.up.sql":          "ALTER TABLE users ADD COLUMN email TEXT; CREATE INDEX idx_users_email ON users (email);",
+		"000003_later.up.sql":          "CREATE TABLE later (id INTEGER);",
+	})
+	if err := migrate.EnsureHistory(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []uint{1, 2} {
+		if err := migrate.Record(ctx, d, migrate.AppliedMigration{Version: v, Name: "x", AppliedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	schema, err := migrate.DumpSchema(ctx, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(schema) != 2 || !strings.HasPrefix(schema[0], "CREATE TABLE users") || !strings.HasPrefix(schema[1], "CREATE INDEX") {
+		t.Fatalf("unexpected schema: %q", schema)
+	}
+
+	if _, err := migrate.Squash(ctx, d, dir, 4, ""); err == nil {
+		t.Fatal("squashing through a missing version should fail")
+	}
+	baseline, err := migrate.Squash(ctx, d, dir, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(baseline.Up) != "000002_baseline.up.sql" {
+		t.Fatalf("baseline file = %s", baseline.Up)
+	}
+	ms, err := migrate.Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 2 || ms[0].Name != "baseline" || ms[1].Version != 3 {
+		t.Fatalf("unexpected migrations after squash: %+v", ms)
+	}
+	archived, err := migrate.Load(filepath.Join(dir, "archive"))
+	if err != nil || len(archived) != 2 || archived[0].Down == "" {
+		t.Fatalf("unexpected archive: %+v, %v", archived, err)
+	}
+	h, err := migrate.History(ctx, d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, _ := baseline.Checksum(); len(h) != 1 || h[0].Version != 2 || h[0].Name != "baseline" || h[0].Checksum != sum {
+		t.Fatalf("unexpected history: %+v", h)
+	}
+
+	// The baseline recreates the schema on a fresh database.
+	fresh, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	script, err := os.ReadFile(baseline.Up)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fresh.Exec(ctx, string(script)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fresh.Exec(ctx, `INSERT INTO users (id, email) VALUES (1, 'a@example.com')`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLintScript(t *testing.T) {
+	script := `
+		CREATE TABLE orders (id BIGINT PRIMARY KEY, user_id BIGINT NOT NULL);
+		CREATE INDEX idx_orders_user ON orders (user_id);
+		CREATE INDEX idx_users_email ON users (email);
+		CREATE INDEX CONCURRENTLY idx_users_name ON users (name);
+		ALTER TABLE users ADD COLUMN tier TEXT NOT NULL, ADD COLUMN plan TEXT NOT NULL DEFAULT 'free';
+		ALTER TABLE users ALTER COLUMN age TYPE BIGINT;
+		ALTER TABLE users ALTER COLUMN name SET NOT NULL;
+		ALTER TABLE users ADD CONSTRAINT users_tier_check CHECK (tier IN ('a', 'b'));
+	`
+	findings := migrate.LintScript(db.DialectPostgres, script)
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.Rule+" "+f.Table)
+	}
+	want := "create-index users|add-column-not-null users|change-column-type users|set-not-null users"
+	if got := strings.Join(rules, "|"); got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+	if !strings.Contains(findings[0].Rewrite, "CREATE INDEX CONCURRENTLY idx_users_email ON users (email);") {
+		t.Fatalf("unexpected rewrite: %s", findings[0].Rewrite)
+	}
+	if err := migrate.CheckLint(findings[3:], false); err != nil {
+		t.Fatalf("warnings pass unless strict: %v", err)
+	}
+	if err := migrate.CheckLint(findings[3:], true); !errors.Is(err, migrate.ErrUnsafe) {
+		t.Fatalf("strict: expected ErrUnsafe, got %v", err)
+	}
+	if err := migrate.CheckLint(findings, false); !errors.Is(err, migrate.ErrUnsafe) {
+		t.Fatalf("expected ErrUnsafe, got %v", err)
+	}
+
+	mysql := migrate.LintScript(db.DialectMySQL, `
+		ALTER TABLE users MODIFY COLUMN age BIGINT;
+		ALTER TABLE users ADD INDEX idx_age (age), ALGORITHM=INPLACE, LOCK=NONE;
+		CREATE INDEX idx_name ON users (name);
+	`)
+	if len(mysql) != 2 || mysql[0].Rule != "change-column-type" || mysql[1].Severity != migrate.SeverityWarning {
+		t.Fatalf("unexpected MySQL findings: %+v", mysql)
+	}
+	if migrate.LintScript(db.DialectSQLite, script) != nil {
+		t.Fatal("SQLite has no lint rules")
+	}
+}
@@ -0,0 +1,212 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// bookkeeping lists the tables of the migrators themselves, which a schema
+// dump leaves out.
+var bookkeeping = map[string]bool{"schema_migrations": true, HistoryTable: true}
+
+// DumpSchema returns DDL statements recreating the tables, indexes and
+// constraints of the database behind q, without the migration bookkeeping
+// tables and without data. SQLite and MySQL report their own DDL; for
+// PostgreSQL it is rebuilt from the catalog of the current schema — tables,
+// serial, identity and stored generated columns, enum types, constraints and
+// indexes, but no views, functions, triggers, grants or identity sequence
+// options.
+func DumpSchema(ctx context.Context, q db.Querier) ([]string, error) {
+	switch d := db.DialectFrom(q); d {
+	case db.DialectSQLite:
+		return dumpSQLite(ctx, q)
+	case db.DialectMySQL:
+		return dumpMySQL(ctx, q)
+	case db.DialectPostgres:
+		return dumpPostgres(ctx, q)
+	default:
+		return nil, fmt.Errorf("migrate: schema dump not supported for dialect %q", d)
+	}
+}
+
+func scanString(r db.RowScanner) (string, error) {
+	var s string
+	return s, r.Scan(&s)
+}
+
+func dumpSQLite(ctx context.Context, q db.Querier) ([]string, error) {
+	type object struct{ name, sql string }
+	objects, err := db.Select(ctx, q, func(r db.RowScanner) (object, error) {
+		var o object
+		return o, r.Scan(&o.name, &o.sql)
+	}, `
+		SELECT tbl_name, sql FROM sqlite_master
+		WHERE  sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER  BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'view' THEN 2 ELSE 3 END, name`)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, o := range objects {
+		if !bookkeeping[o.name] {
+			out = append(out, o.sql)
+		}
+	}
+	return out, nil
+}
+
+var autoIncrementRe = regexp.MustCompile(`\s+AUTO_INCREMENT=\d+`)
+
+func dumpMySQL(ctx context.Context, q db.Querier) ([]string, error) {
+	tables, err := db.Select(ctx, q, scanString, `
+		SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE  TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+		ORDER  BY TABLE_NAME`)
+	if err != nil {
+		return nil, err
+	}
+	// Tables come in name order; foreign keys may point forward.
+	out := []string{"SET FOREIGN_KEY_CHECKS = 0"}
+	for _, t := range tables {
+		if bookkeeping[t] {
+			continue
+		}
+		var name, ddl string
+		if err := q.QueryRow(ctx, "SHOW CREATE TABLE "+db.DialectMySQL.QuoteIdent(t)).Scan(&name, &ddl); err != nil {
+			return nil, err
+		}
+		out = append(out, autoIncrementRe.ReplaceAllString(ddl, ""))
+	}
+	return append(out, "SET FOREIGN_KEY_CHECKS = 1"), nil
+}
+
+var nextvalRe = regexp.MustCompile(`^nextval\('[^']+'::regclass\)$`)
+
+func dumpPostgres(ctx context.Context, q db.Querier) ([]string, error) {
+	var out []string
+
+	type enum struct{ name, labels string }
+	enums, err := db.Select(ctx, q, func(r db.RowScanner) (enum, error) {
+		var e enum
+		return e, r.Scan(&e.name, &e.labels)
+	}, `
+		SELECT t.typname, string_agg(quote_literal(e.enumlabel), ', ' ORDER BY e.enumsortorder)
+		FROM   pg_type t
+		JOIN   pg_enum e ON e.enumtypid = t.oid
+		WHERE  t.typnamespace = current_schema()::regnamespace
+		GROUP  BY t.typname
+		ORDER  BY t.typname`)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range enums {
+		out = append(out, fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", db.DialectPostgres.QuoteIdent(e.name), e.labels))
+	}
+
+	tables, err := db.Select(ctx, q, scanString, `
+		SELECT c.relname FROM pg_class c
+		WHERE  c.relnamespace = current_schema()::regnamespace AND c.relkind IN ('r', 'p')
+		ORDER  BY c.relname`)
+	if err != nil {
+		return nil, err
+	}
+	type column struct {
+		name, typ           string
+		notNull             bool
+		def                 *string
+		identity, generated string
+	}
+	var keys, foreignKeys, indexes []string
+	for _, t := range tables {
+		if bookkeeping[t] {
+			continue
+		}
+		cols, err := db.Select(ctx, q, func(r db.RowScanner) (column, error) {
+			var c column
+			return c, r.Scan(&c.name, &c.typ, &c.notNull, &c.def, &c.identity, &c.generated)
+		}, `
+			SELECT a.attname, format_type(a.atttypid, a.atttypmod), a.attnotnull, pg_get_expr(d.adbin, d.adrelid),
+			       a.attidentity::text, a.attgenerated::text
+			FROM   pg_attribute a
+			LEFT   JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+			WHERE  a.attrelid = to_regclass($1) AND a.attnum > 0 AND NOT a.attisdropped
+			ORDER  BY a.attnum`, quoteName(t))
+		if err != nil {
+			return nil, err
+		}
+		defs := make([]string, len(cols))
+		for i, c := range cols {
+			typ, def := c.typ, c.def
+			if def != nil && nextvalRe.MatchString(*def) {
+				switch typ {
+				case "bigint":
+					typ, def = "bigserial", nil
+				case "integer":
+					typ, def = "serial", nil
+				case "smallint":
+					typ, def = "smallserial", nil
+				}
+			}
+			col := db.DialectPostgres.QuoteIdent(c.name) + " " + typ
+			if c.notNull {
+				col += " NOT NULL"
+			}
+			// pg_attrdef holds the expression of a generated column too.
+			switch {
+			case c.generated == "s" && def != nil:
+				col += " GENERATED ALWAYS AS (" + *def + ") STORED"
+			case c.identity == "a":
+				col += " GENERATED ALWAYS AS IDENTITY"
+			case c.identity == "d":
+				col += " GENERATED BY DEFAULT AS IDENTITY"
+			case def != nil:
+				col += " DEFAULT " + *def
+			}
+			defs[i] = col
+		}
+		out = append(out, fmt.Sprintf("CREATE TABLE %s (\n    %s\n)", quoteName(t), strings.Join(defs, ",\n    ")))
+
+		type constraint struct{ kind, ddl string }
+		cons, err := db.Select(ctx, q, func(r db.RowScanner) (constraint, error) {
+			var c constraint
+			var name, def string
+			err := r.Scan(&c.kind, &name, &def)
+			c.ddl = fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", quoteName(t), quoteName(name), def)
+			return c, err
+		}, `
+			SELECT contype::text, conname, pg_get_constraintdef(oid) FROM pg_constraint
+			WHERE  conrelid = to_regclass($1) AND contype IN ('p', 'u', 'c', 'f', 'x')
+			ORDER  BY conname`, quoteName(t))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cons {
+			if c.kind == "f" {
+				foreignKeys = append(foreignKeys, c.ddl)
+			} else {
+				keys = append(keys, c.ddl)
+			}
+		}
+
+		idx, err := db.Select(ctx, q, scanString, `
+			SELECT pg_get_indexdef(i.indexrelid) FROM pg_index i
+			WHERE  i.indrelid = to_regclass($1)
+			  AND  NOT EXISTS (SELECT 1 FROM pg_constraint c WHERE c.conindid = i.indexrelid)
+			ORDER  BY i.indexrelid::regclass::text`, quoteName(t))
+		if err != nil {
+			return nil, err
+		}
+		indexes = append(indexes, idx...)
+	}
+	// Constraints after every table, and foreign keys after every key they
+	// may reference.
+	out = append(out, keys...)
+	out = append(out, foreignKeys...)
+	return append(out, indexes...), nil
+}
+
+func quoteName(name string) string { return db.DialectPostgres.QuoteIdent(name) }
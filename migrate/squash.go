@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Squash replaces the migrations in dir up to and including version through
+// with a single baseline migration, NNNN_baseline.up.sql, holding the schema
+// DumpSchema reads from q. q must be at version through: later migrations
+// would otherwise end up in the baseline as well as in their own files.
+//
+// The replaced files are moved to archiveDir (dir/archive when empty), where
+// Load and golang-migrate no longer see them, and their history entries give
+// way to one for the baseline. The baseline has no down file; rolling back
+// past it means restoring from backup. Databases at or beyond through keep
+// running as before, new ones are created from the baseline.
+func Squash(ctx context.Context, q db.Querier, dir string, through uint, archiveDir string) (Migration, error) {
+	files, err := Load(dir)
+	if err != nil {
+		return Migration{}, err
+	}
+	var old []Migration
+	for _, m := range files {
+		if m.Version <= through {
+			old = append(old, m)
+		}
+	}
+	if len(old) == 0 || old[len(old)-1].Version != through {
+		return Migration{}, fmt.Errorf("migrate: squash: no migration with version %d in %s", through, dir)
+	}
+	if len(old) == 1 && old[0].Name == "baseline" {
+		return Migration{}, fmt.Errorf("migrate: squash: version %d is already the baseline", through)
+	}
+
+	stmts, err := DumpSchema(ctx, q)
+	if err != nil {
+		return Migration{}, fmt.Errorf("migrate: squash: %w", err)
+	}
+	if archiveDir == "" {
+		archiveDir = filepath.Join(dir, "archive")
+	}
+	if err := os.MkdirAll(archiveDir, 0o755); err != nil {
+		return Migration{}, fmt.Errorf("migrate: squash: %w", err)
+	}
+	for _, m := range old {
+		for _, path := range []string{m.Up, m.Down} {
+			if path == "" {
+				continue
+			}
+			if err := os.Rename(path, filepath.Join(archiveDir, filepath.Base(path))); err != nil {
+				return Migration{}, fmt.Errorf("migrate: squash: archive: %w", err)
+			}
+		}
+	}
+
+	// Keep the zero padding of the file being replaced so the baseline sorts
+	// with its neighbours.
+	width := len(fileRe.FindStringSubmatch(filepath.Base(old[len(old)-1].Up))[1])
+	baseline := Migration{
+		Version: through,
+		Name:    "baseline",
+		Up:      filepath.Join(dir, fmt.Sprintf("%0*d_baseline.up.sql", width, through)),
+	}
+	script := fmt.Sprintf("-- Baseline squashed from migrations %d to %d on %s.\n\n%s;\n",
+		old[0].Version, through, time.Now().UTC().Format(time.DateOnly), strings.Join(stmts, ";\n\n"))
+	if err := os.WriteFile(baseline.Up, []byte(script), 0o644); err != nil {
+		return Migration{}, fmt.Errorf("migrate: squash: %w", err)
+	}
+
+	applied, err := History(ctx, q)
+	if err != nil {
+		return Migration{}, err
+	}
+	for _, a := range applied {
+		if a.Version <= through {
+			if err := Forget(ctx, q, a.Version); err != nil {
+				return Migration{}, fmt.Errorf("migrate: squash: history: %w", err)
+			}
+		}
+	}
+	sum, err := baseline.Checksum()
+	if err != nil {
+		return Migration{}, err
+	}
+	err = Record(ctx, q, AppliedMigration{Version: through, Name: baseline.Name, AppliedAt: time.Now(), Checksum: sum})
+	if err != nil {
+		return Migration{}, fmt.Errorf("migrate: squash: history: %w", err)
+	}
+	return baseline, nil
+}
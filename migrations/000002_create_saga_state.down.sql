@@ -0,0 +1,2 @@
+-- migrations/000002_create_saga_state.down.sql
+DROP TABLE IF EXISTS saga_state;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- migrations/000002_create_saga_state.up.sql
+-- Progress of sagas run by the saga package, used for crash recovery.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS saga_state (
+    id         VARCHAR(255) PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    step       INTEGER      NOT NULL,
+    status     VARCHAR(32)  NOT NULL,
+    data       TEXT         NOT NULL,
+    error      TEXT         NOT NULL DEFAULT '',
+    updated_at TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_saga_state_pending ON saga_state(name, status, updated_at);
@@ -0,0 +1,3 @@
+-- migrations/000003_create_rate_limits.down.sql
+DROP TABLE IF EXISTS rate_limit_buckets;
+DROP TABLE IF EXISTS rate_limit_windows;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- migrations/000003_create_rate_limits.up.sql
+-- Counters for db/ratelimit. Times are stored as Unix microseconds so the
+-- same schema works on every supported database.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS rate_limit_windows (
+    limit_key    VARCHAR(255) NOT NULL,
+    window_start BIGINT       NOT NULL,
+    hits         INTEGER      NOT NULL,
+    PRIMARY KEY (limit_key, window_start)
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    limit_key  VARCHAR(255)     PRIMARY KEY,
+    tokens     DOUBLE PRECISION NOT NULL,
+    updated_at BIGINT           NOT NULL
+);
@@ -0,0 +1,2 @@
+-- migrations/000004_create_kv_store.down.sql
+DROP TABLE IF EXISTS kv_store;
\ No newline at end of file
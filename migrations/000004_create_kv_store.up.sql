@@ -0,0 +1,13 @@
+-- migrations/000004_create_kv_store.up.sql
+-- Backing table for db/kv. expires_at is Unix microseconds; 0 means never.
+-- PostgreSQL and SQLite only: MySQL has no BYTEA and no CREATE INDEX IF NOT
+-- EXISTS; kv.CreateTable creates the MySQL form of the table.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS kv_store (
+    key_name   VARCHAR(255) PRIMARY KEY,
+    value      BYTEA        NOT NULL,
+    expires_at BIGINT       NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_kv_store_expires_at ON kv_store(expires_at);
@@ -0,0 +1,2 @@
+-- migrations/000005_create_scheduled_jobs.down.sql
+DROP TABLE IF EXISTS scheduled_jobs;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- migrations/000005_create_scheduled_jobs.up.sql
+-- Job state for the scheduler package. locked_by/locked_until form a lease so
+-- only one instance runs a job at a time; times are Unix microseconds.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name         VARCHAR(255)  PRIMARY KEY,
+    schedule     VARCHAR(255)  NOT NULL,
+    next_run     BIGINT        NOT NULL,
+    last_run     BIGINT        NOT NULL DEFAULT 0,
+    last_error   VARCHAR(1024) NOT NULL DEFAULT '',
+    locked_by    VARCHAR(255)  NOT NULL DEFAULT '',
+    locked_until BIGINT        NOT NULL DEFAULT 0
+);
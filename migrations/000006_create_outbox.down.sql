@@ -0,0 +1,3 @@
+-- migrations/000006_create_outbox.down.sql
+DROP TABLE IF EXISTS outbox_consumed;
+DROP TABLE IF EXISTS outbox;
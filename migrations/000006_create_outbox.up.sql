@@ -0,0 +1,27 @@
+-- migrations/000006_create_outbox.up.sql
+-- Transactional outbox for the outbox package. Messages are written in the
+-- same transaction as the change they describe and relayed to a broker
+-- afterwards; outbox_consumed records event ids already handled by consumers.
+-- Times are Unix microseconds.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS outbox (
+    id            VARCHAR(64)   PRIMARY KEY,
+    topic         VARCHAR(255)  NOT NULL,
+    partition_key VARCHAR(255)  NOT NULL DEFAULT '',
+    payload       TEXT          NOT NULL,
+    headers       TEXT          NOT NULL,
+    created_at    BIGINT        NOT NULL,
+    published_at  BIGINT        NOT NULL DEFAULT 0,
+    attempts      INTEGER       NOT NULL DEFAULT 0,
+    last_error    VARCHAR(1024) NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox(published_at, created_at);
+
+CREATE TABLE IF NOT EXISTS outbox_consumed (
+    consumer    VARCHAR(255) NOT NULL,
+    event_id    VARCHAR(64)  NOT NULL,
+    consumed_at BIGINT       NOT NULL,
+    PRIMARY KEY (consumer, event_id)
+);
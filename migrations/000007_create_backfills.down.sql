@@ -0,0 +1,2 @@
+-- migrations/000007_create_backfills.down.sql
+DROP TABLE IF EXISTS backfills;
@@ -0,0 +1,16 @@
+-- migrations/000007_create_backfills.up.sql
+-- Progress of the backfill package's chunked data migrations: one row per
+-- backfill, advanced in the same transaction as each chunk it records, so a
+-- backfill resumes exactly where it stopped. Times are Unix microseconds.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS backfills (
+    name       VARCHAR(255) PRIMARY KEY,
+    state      VARCHAR(16)  NOT NULL,
+    start_key  BIGINT       NOT NULL,
+    last_key   BIGINT       NOT NULL,
+    end_key    BIGINT       NOT NULL,
+    rows_done  BIGINT       NOT NULL DEFAULT 0,
+    started_at BIGINT       NOT NULL,
+    updated_at BIGINT       NOT NULL
+);
@@ -0,0 +1,2 @@
+-- migrations/000008_create_checkpoints.down.sql
+DROP TABLE IF EXISTS checkpoints;
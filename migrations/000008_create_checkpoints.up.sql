@@ -0,0 +1,13 @@
+-- migrations/000008_create_checkpoints.up.sql
+-- Positions of the checkpoint package's consumers: one row per consumer,
+-- holding an opaque offset or cursor that is advanced with compare-and-set
+-- in the transaction applying the work it covers. version counts advances;
+-- updated_at is Unix microseconds.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS checkpoints (
+    consumer   VARCHAR(255) PRIMARY KEY,
+    position   TEXT         NOT NULL,
+    version    BIGINT       NOT NULL,
+    updated_at BIGINT       NOT NULL
+);
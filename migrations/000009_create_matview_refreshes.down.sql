@@ -0,0 +1,2 @@
+-- migrations/000009_create_matview_refreshes.down.sql
+DROP TABLE IF EXISTS matview_refreshes;
@@ -0,0 +1,15 @@
+-- migrations/000009_create_matview_refreshes.up.sql
+-- Refresh history of the matview package's materialized views: one row per
+-- view, written after every refresh. refreshed_at and attempted_at are Unix
+-- microseconds of the last successful and the last attempted refresh (0 for
+-- never); duration is the last successful refresh's length in microseconds.
+-- Run via: go run ./cmd/migrate up
+
+CREATE TABLE IF NOT EXISTS matview_refreshes (
+    name         VARCHAR(255)  PRIMARY KEY,
+    refreshed_at BIGINT        NOT NULL DEFAULT 0,
+    duration     BIGINT        NOT NULL DEFAULT 0,
+    attempted_at BIGINT        NOT NULL DEFAULT 0,
+    last_error   VARCHAR(1024) NOT NULL DEFAULT '',
+    refreshes    BIGINT        NOT NULL DEFAULT 0
+);
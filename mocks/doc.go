@@ -0,0 +1,18 @@
+// Package mocks holds generated mocks of the db interfaces, so applications
+// share one maintained set instead of each generating (and version-skewing)
+// their own. They are plain structs with no mocking library dependency:
+// set the XxxFunc fields a test cares about, leave the rest nil to get zero
+// results, and inspect XxxCalls afterwards.
+//
+//	q := &mocks.QuerierMock{
+//	    QueryRowFunc: func(ctx context.Context, query string, args ...any) *db.Row {
+//	        return db.ErrRow(db.ErrNotFound)
+//	    },
+//	}
+//	_, err := repo.NewUserRepo(q).GetByID(ctx, 42) // db.ErrNotFound
+//	if n := len(q.QueryRowCalls()); n != 1 { ... }
+//
+// Regenerate with go generate after changing an interface.
+package mocks
+
+//go:generate moq -stub -out db_moq.go -pkg mocks ../db Querier Hook MetricsCollector Tracer
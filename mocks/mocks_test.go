@@ -0,0 +1,49 @@
+package mocks_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/mocks"
+	"github.com/Skryldev/sql-toolkit/repo"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestQuerierMock(t *testing.T) {
+	q := &mocks.QuerierMock{
+		QueryRowFunc: func(context.Context, string, ...any) *db.Row { return db.ErrRow(db.ErrNotFound) },
+	}
+	if _, err := repo.NewUserRepo(q).GetByID(context.Background(), 42); !db.IsNotFound(err) {
+		t.Fatalf("GetByID = %v", err)
+	}
+	calls := q.QueryRowCalls()
+	if len(calls) != 1 || len(calls[0].Args) != 1 || calls[0].Args[0] != int64(42) {
+		t.Fatalf("calls = %+v", calls)
+	}
+}
+
+func TestHookMocks(t *testing.T) {
+	metrics := &mocks.MetricsCollectorMock{}
+	tracer := &mocks.TracerMock{
+		StartSpanFunc: func(ctx context.Context, _ string) context.Context { return ctx },
+	}
+	hook := &mocks.HookMock{}
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3",
+		Hooks: []db.Hook{hook, db.NewMetricsHook(metrics), db.NewTracingHook(tracer)}})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	_, _ = d.Exec(context.Background(), `SELECT 1`)
+
+	if len(hook.BeforeQueryCalls()) != 1 || len(hook.AfterQueryCalls()) != 1 {
+		t.Fatalf("hook calls: %d before, %d after", len(hook.BeforeQueryCalls()), len(hook.AfterQueryCalls()))
+	}
+	if c := metrics.RecordQueryCalls(); len(c) != 1 || !c[0].Success {
+		t.Fatalf("metrics calls = %+v", c)
+	}
+	if len(tracer.EndSpanCalls()) != 1 {
+		t.Fatalf("tracer calls = %+v", tracer.EndSpanCalls())
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Page is one page of a list result, in the shape every list endpoint
+// returns. Items is never nil, so it encodes as [] rather than null.
+type Page[T any] struct {
+	Items []T `json:"items"`
+	// Total counts every matching row, or is -1 when the repository was
+	// asked not to count.
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+	// NextCursor fetches the following page when passed back as the
+	// filter's Cursor; empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// EncodeCursor returns the opaque cursor for the page starting at offset.
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+// ErrInvalidCursor is returned by DecodeCursor for a cursor not produced by
+// EncodeCursor.
+var ErrInvalidCursor = errors.New("models: invalid cursor")
+
+// DecodeCursor returns the offset encoded in cursor.
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	s, ok := strings.CutPrefix(string(raw), "o:")
+	if !ok {
+		return 0, ErrInvalidCursor
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return n, nil
+}
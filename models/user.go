@@ -27,4 +27,35 @@ type UpdateUserParams struct {
 	ID    int64
 	Name  *string
 	Email *string
-}
\ No newline at end of file
+}
+
+// UserFilter narrows and orders UserRepository.List results. Zero values
+// mean "no constraint"; the repository translates every set field into a
+// parameterised predicate.
+type UserFilter struct {
+	// NamePrefix matches users whose name starts with the given text.
+	NamePrefix string
+	// EmailDomain matches users whose email ends with "@" + EmailDomain.
+	EmailDomain string
+	// CreatedAfter / CreatedBefore bound created_at (inclusive / exclusive).
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// Filter, when set, is a JSON filter expression in the format of
+	// db.ParseFilter over id, name, email, created_at and updated_at,
+	// AND-ed with the fields above.
+	Filter string
+
+	// SortBy is one of "id" (default), "name", "email", "created_at".
+	SortBy string
+	// SortDir is "asc" (default) or "desc".
+	SortDir string
+	// Sort, when set, replaces SortBy and SortDir with several keys in the
+	// "-created_at,name" format of db.ParseSort.
+	Sort string
+
+	Limit  int
+	Offset int
+	// Cursor is the NextCursor of a previous Page; when set it overrides
+	// Offset. Only ListPage reads it.
+	Cursor string
+}
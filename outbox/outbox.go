@@ -0,0 +1,240 @@
+// Package outbox implements the transactional outbox: messages for a broker
+// (Kafka, NATS, ...) are written in the same transaction as the change they
+// describe, and a Relay publishes them afterwards. A message is never
+// published for a rolled-back change, and never lost for a committed one.
+//
+// Messages live in the outbox table (see migrations/000006_create_outbox.up.sql).
+// The relay publishes them in batches, in order per partition key, and marks
+// them published. Delivery is at-least-once: a crash between publishing and
+// marking re-publishes the batch, so consumers deduplicate on the message ID,
+// which Consumed does in the consumer's own transaction.
+//
+// Applications only write a thin Publisher adapter for their broker:
+//
+//	type kafkaPublisher struct{ w *kafka.Writer }
+//
+//	func (p kafkaPublisher) Publish(ctx context.Context, msgs []outbox.Message) error {
+//	    out := make([]kafka.Message, len(msgs))
+//	    for i, m := range msgs {
+//	        out[i] = kafka.Message{Topic: m.Topic, Key: []byte(m.Key), Value: m.Payload,
+//	            Headers: []kafka.Header{{Key: "event-id", Value: []byte(m.ID)}}}
+//	    }
+//	    return p.w.WriteMessages(ctx, out...)
+//	}
+//
+//	box := outbox.New(database)
+//	err := database.ExecTx(ctx, func(tx *db.Tx) error {
+//	    if err := orders.InsertTx(ctx, tx, o); err != nil { return err }
+//	    return box.Enqueue(ctx, tx, outbox.Message{Topic: "orders", Key: o.ID, Payload: body})
+//	})
+//	go outbox.NewRelay(box, kafkaPublisher{w}, outbox.RelayOptions{}).Run(ctx)
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Message is one outbox entry.
+type Message struct {
+	// ID identifies the message to consumers for deduplication. Enqueue
+	// generates one when empty.
+	ID    string
+	Topic string
+	// Key is the partition key: messages sharing a key are published in the
+	// order they were enqueued. Empty keys are ordered among themselves.
+	Key     string
+	Payload []byte
+	Headers map[string]string
+	// CreatedAt is set by Enqueue.
+	CreatedAt time.Time
+}
+
+// Publisher delivers messages to a broker. Publish receives the messages of
+// one partition key, in order, and must either deliver all of them or return
+// an error; the whole group is retried on error.
+type Publisher interface {
+	Publish(ctx context.Context, msgs []Message) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, msgs []Message) error
+
+// Publish implements Publisher.
+func (f PublisherFunc) Publish(ctx context.Context, msgs []Message) error { return f(ctx, msgs) }
+
+// Outbox writes and reads the outbox table.
+type Outbox struct {
+	d *db.DB
+
+	mu   sync.Mutex
+	last int64 // last created_at handed out, to keep enqueue order stable
+
+	sqlInsert, sqlPending, sqlFailed, sqlPurge, sqlConsume string
+	ph                                                     db.Placeholder
+}
+
+// New returns an Outbox using d's outbox tables. Statements use d's
+// placeholder style, so any supported dialect works.
+func New(d *db.DB) *Outbox {
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	consume, err := db.InsertSQL(dialect, "outbox_consumed", []string{"consumer", "event_id", "consumed_at"},
+		db.ConflictSkip, "consumer", "event_id")
+	if err != nil {
+		panic(err) // static arguments; cannot fail
+	}
+	return &Outbox{
+		d:  d,
+		ph: ph,
+		sqlInsert: fmt.Sprintf(`
+			INSERT INTO outbox (id, topic, partition_key, payload, headers, created_at)
+			VALUES (%s, %s, %s, %s, %s, %s)`,
+			ph(1), ph(2), ph(3), ph(4), ph(5), ph(6)),
+		sqlPending: fmt.Sprintf(`
+			SELECT id, topic, partition_key, payload, headers, created_at
+			FROM   outbox
+			WHERE  published_at = 0
+			ORDER  BY created_at, id
+			LIMIT  %s`,
+			ph(1)),
+		sqlFailed: fmt.Sprintf(`
+			UPDATE outbox SET attempts = attempts + 1, last_error = %s
+			WHERE  id = %s`,
+			ph(1), ph(2)),
+		sqlPurge: fmt.Sprintf(`
+			DELETE FROM outbox WHERE published_at > 0 AND published_at < %s`,
+			ph(1)),
+		sqlConsume: consume,
+	}
+}
+
+// Enqueue writes msgs through q, which should be the transaction making the
+// change the messages describe. It fills in missing IDs and CreatedAt.
+func (o *Outbox) Enqueue(ctx context.Context, q db.Querier, msgs ...Message) error {
+	for i := range msgs {
+		m := &msgs[i]
+		if m.ID == "" {
+			m.ID = newID()
+		}
+		m.CreatedAt = time.UnixMicro(o.nextMicro(db.NowFrom(q)))
+		headers, err := json.Marshal(m.Headers)
+		if err != nil {
+			return fmt.Errorf("outbox: encode headers: %w", err)
+		}
+		if _, err := q.Exec(ctx, o.sqlInsert,
+			m.ID, m.Topic, m.Key, string(m.Payload), string(headers), m.CreatedAt.UnixMicro()); err != nil {
+			return fmt.Errorf("outbox: enqueue %s: %w", m.Topic, err)
+		}
+	}
+	return nil
+}
+
+// nextMicro returns now in Unix microseconds, made strictly greater than the
+// previous call's result so messages enqueued by one process keep their order
+// even within a microsecond.
+func (o *Outbox) nextMicro(t time.Time) int64 {
+	now := t.UnixMicro()
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if now <= o.last {
+		now = o.last + 1
+	}
+	o.last = now
+	return now
+}
+
+// Pending returns up to limit unpublished messages, oldest first.
+func (o *Outbox) Pending(ctx context.Context, limit int) ([]Message, error) {
+	return db.Select(ctx, o.d, func(r db.RowScanner) (Message, error) {
+		var (
+			m       Message
+			payload string
+			headers string
+			created int64
+		)
+		if err := r.Scan(&m.ID, &m.Topic, &m.Key, &payload, &headers, &created); err != nil {
+			return m, err
+		}
+		m.Payload = []byte(payload)
+		m.CreatedAt = time.UnixMicro(created)
+		if err := json.Unmarshal([]byte(headers), &m.Headers); err != nil {
+			return m, fmt.Errorf("outbox: decode headers of %s: %w", m.ID, err)
+		}
+		return m, nil
+	}, o.sqlPending, limit)
+}
+
+// markPublished records ids as published at now.
+func (o *Outbox) markPublished(ctx context.Context, ids []string, now time.Time) error {
+	args := make([]any, 0, len(ids)+1)
+	args = append(args, now.UnixMicro())
+	marks := make([]string, len(ids))
+	for i, id := range ids {
+		marks[i] = o.ph(i + 2)
+		args = append(args, id)
+	}
+	_, err := o.d.Exec(ctx, `UPDATE outbox SET published_at = `+o.ph(1)+
+		` WHERE id IN (`+strings.Join(marks, ", ")+`)`, args...)
+	return err
+}
+
+func (o *Outbox) markFailed(ctx context.Context, ids []string, cause error) error {
+	msg := cause.Error()
+	if len(msg) > 1024 { // the column's size; cut on a rune boundary
+		n := 1024
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n--
+		}
+		msg = msg[:n]
+	}
+	for _, id := range ids {
+		if _, err := o.d.Exec(ctx, o.sqlFailed, msg, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Purge deletes messages published before cutoff and returns how many.
+func (o *Outbox) Purge(ctx context.Context, cutoff time.Time) (int64, error) {
+	return o.d.ExecAffected(ctx, o.sqlPurge, cutoff.UnixMicro())
+}
+
+// ── Consumer-side deduplication ──────────────────────────────────────────────
+
+// Consumed records that consumer handled the message with id, through q —
+// the transaction applying the message's effects. It reports false when the
+// message was already recorded, in which case the caller should roll back
+// (or skip its work): the message is a redelivery.
+//
+//	err := database.ExecTx(ctx, func(tx *db.Tx) error {
+//	    first, err := box.Consumed(ctx, tx, "billing", msg.ID)
+//	    if err != nil || !first {
+//	        return err
+//	    }
+//	    return applyOrderPlaced(ctx, tx, msg)
+//	})
+func (o *Outbox) Consumed(ctx context.Context, q db.Querier, consumer, id string) (bool, error) {
+	res, err := q.Exec(ctx, o.sqlConsume, consumer, id, db.NowFrom(q).UnixMicro())
+	if err != nil {
+		return false, fmt.Errorf("outbox: record consumption of %s: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
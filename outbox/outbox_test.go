@@ -0,0 +1,99 @@
+package outbox_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	"github.com/Skryldev/sql-toolkit/outbox"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	return dbtest.OpenSQLite(t, "000006_create_outbox")
+}
+
+func TestRelay(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	box := outbox.New(d)
+
+	// A rolled-back transaction leaves nothing behind.
+	_ = d.ExecTx(ctx, func(tx *db.Tx) error {
+		_ = box.Enqueue(ctx, tx, outbox.Message{Topic: "orders", Key: "o1", Payload: []byte("lost")})
+		return errors.New("rollback")
+	})
+	err := d.ExecTx(ctx, func(tx *db.Tx) error {
+		return box.Enqueue(ctx, tx,
+			outbox.Message{Topic: "orders", Key: "o1", Payload: []byte("created")},
+			outbox.Message{Topic: "orders", Key: "o2", Payload: []byte("created")},
+			outbox.Message{Topic: "orders", Key: "o1", Payload: []byte("paid"), Headers: map[string]string{"v": "2"}},
+		)
+	})
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	var got [][]outbox.Message
+	failKey := "o2"
+	relay := outbox.NewRelay(box, outbox.PublisherFunc(func(_ context.Context, msgs []outbox.Message) error {
+		if msgs[0].Key == failKey {
+			return errors.New("broker down")
+		}
+		got = append(got, msgs)
+		return nil
+	}), outbox.RelayOptions{})
+
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 2 {
+		t.Fatalf("first round published %d (err %v)", n, err)
+	}
+	if len(got) != 1 || len(got[0]) != 2 || string(got[0][0].Payload) != "created" ||
+		string(got[0][1].Payload) != "paid" || got[0][1].Headers["v"] != "2" || got[0][0].ID == "" {
+		t.Fatalf("o1 group = %+v", got)
+	}
+
+	// The failed partition is retried on the next round.
+	failKey = ""
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 1 || got[1][0].Key != "o2" {
+		t.Fatalf("second round published %d (err %v): %+v", n, err, got)
+	}
+	if pending, _ := box.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("pending after relay = %+v", pending)
+	}
+	if n, err := box.Purge(ctx, time.Now().Add(time.Second)); err != nil || n != 3 {
+		t.Fatalf("purged %d (err %v)", n, err)
+	}
+}
+
+func TestConsumed(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	box := outbox.New(d)
+
+	applied := 0
+	consume := func() error {
+		return d.ExecTx(ctx, func(tx *db.Tx) error {
+			first, err := box.Consumed(ctx, tx, "billing", "evt-1")
+			if err != nil || !first {
+				return err
+			}
+			applied++
+			return nil
+		})
+	}
+	for range 3 {
+		if err := consume(); err != nil {
+			t.Fatalf("consume: %v", err)
+		}
+	}
+	if applied != 1 {
+		t.Fatalf("applied %d times", applied)
+	}
+	if first, err := box.Consumed(ctx, d, "audit", "evt-1"); err != nil || !first {
+		t.Fatalf("other consumer: first=%v err=%v", first, err)
+	}
+}
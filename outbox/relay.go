@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RelayOptions configures NewRelay. Every field is optional.
+type RelayOptions struct {
+	// BatchSize is the most messages read per round. Defaults to 100.
+	BatchSize int
+	// PollInterval is the pause between rounds that found nothing to do, or
+	// only failures. Defaults to 1s.
+	PollInterval time.Duration
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Relay moves messages from the outbox to a Publisher.
+//
+// Run one relay per outbox table (e.g. as a leader-elected goroutine or a
+// scheduler job). Overlapping relays keep working but publish duplicates,
+// which consumers then discard through Consumed.
+type Relay struct {
+	box  *Outbox
+	pub  Publisher
+	opts RelayOptions
+}
+
+// NewRelay returns a Relay publishing box's messages to pub.
+func NewRelay(box *Outbox, pub Publisher, opts RelayOptions) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	return &Relay{box: box, pub: pub, opts: opts}
+}
+
+// Run relays until ctx is cancelled. Full batches are followed immediately by
+// the next round, so a backlog drains at publisher speed.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.opts.Logger.WarnContext(ctx, "outbox: relay round failed", slog.Any("error", err))
+		}
+		if err == nil && n == r.opts.BatchSize {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.opts.PollInterval):
+		}
+	}
+}
+
+// RelayOnce publishes one batch of pending messages and returns how many
+// were published. Messages are grouped by partition key; when a group fails,
+// it is left pending (with attempts and last_error updated) and the other
+// groups proceed, so one unavailable partition does not stall the rest.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	msgs, err := r.box.Pending(ctx, r.opts.BatchSize)
+	if err != nil || len(msgs) == 0 {
+		return 0, err
+	}
+	published := 0
+	for _, group := range groupByKey(msgs) {
+		ids := make([]string, len(group))
+		for i, m := range group {
+			ids[i] = m.ID
+		}
+		if err := r.pub.Publish(ctx, group); err != nil {
+			r.opts.Logger.WarnContext(ctx, "outbox: publish failed",
+				slog.String("key", group[0].Key), slog.Int("messages", len(group)), slog.Any("error", err))
+			if err := r.box.markFailed(ctx, ids, err); err != nil {
+				return published, err
+			}
+			continue
+		}
+		// A failure here re-publishes the group next round; consumers
+		// deduplicate on the message ID.
+		if err := r.box.markPublished(ctx, ids, r.box.d.Now()); err != nil {
+			return published, err
+		}
+		published += len(group)
+	}
+	return published, nil
+}
+
+// groupByKey splits msgs by partition key, keeping their order within each
+// key and ordering groups by their oldest message.
+func groupByKey(msgs []Message) [][]Message {
+	index := map[string]int{}
+	var groups [][]Message
+	for _, m := range msgs {
+		i, ok := index[m.Key]
+		if !ok {
+			i = len(groups)
+			index[m.Key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	return groups
+}
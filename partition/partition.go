@@ -0,0 +1,281 @@
+// Package partition keeps PostgreSQL range-partitioned tables supplied with
+// time partitions: it creates the partitions for the coming periods before
+// rows arrive for them and detaches the ones that fell out of retention,
+// replacing the cron jobs and psql scripts that usually do this.
+//
+// The parent table is created by a migration, range-partitioned on a time
+// column — date, timestamp, timestamptz, or BIGINT Unix microseconds as in
+// the other tables of this module:
+//
+//	CREATE TABLE events (id BIGSERIAL, created_at TIMESTAMPTZ NOT NULL, ...)
+//	PARTITION BY RANGE (created_at);
+//
+// and the partitions are then managed from Go, once at startup and from then
+// on by the scheduler:
+//
+//	_, err := partition.EnsureMonthly(ctx, database, "events", 12)
+//	err = partition.Register(sched, database, partition.Policy{Table: "events", Interval: partition.Monthly, Keep: 12})
+//
+// Partitions are named after the table and the start of their period
+// (events_p2026_10, events_p2026_10_15) and only partitions named that way
+// are ever detached; a DEFAULT partition and hand-made ones are left alone.
+package partition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/scheduler"
+)
+
+// Interval is the period one partition covers.
+type Interval int
+
+const (
+	Monthly Interval = iota
+	Weekly           // Monday to Monday
+	Daily
+)
+
+func (i Interval) String() string {
+	switch i {
+	case Daily:
+		return "daily"
+	case Weekly:
+		return "weekly"
+	default:
+		return "monthly"
+	}
+}
+
+// start returns the beginning of the period containing t.
+func (i Interval) start(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch i {
+	case Daily:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case Weekly:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// add moves a period start n periods forward (or back when n < 0).
+func (i Interval) add(t time.Time, n int) time.Time {
+	switch i {
+	case Daily:
+		return t.AddDate(0, 0, n)
+	case Weekly:
+		return t.AddDate(0, 0, 7*n)
+	default:
+		return t.AddDate(0, n, 0)
+	}
+}
+
+func (i Interval) suffix(t time.Time) string {
+	if i == Monthly {
+		return t.Format("p2006_01")
+	}
+	return t.Format("p2006_01_02")
+}
+
+// Policy describes how one partitioned table is maintained.
+type Policy struct {
+	Table    string
+	Interval Interval
+	// Premake is how many periods after the current one get a partition in
+	// advance. Defaults to 2, so a missed run or two never leaves inserts
+	// without a partition.
+	Premake int
+	// Keep is how many periods before the current one stay attached; older
+	// partitions are detached. Zero keeps everything.
+	Keep int
+	// Drop drops partitions once detached instead of leaving them as
+	// standalone tables for archiving.
+	Drop bool
+}
+
+// Range is one partition: the rows with From <= key < To.
+type Range struct {
+	Name     string
+	From, To time.Time
+}
+
+// Ranges returns the partitions p wants attached at now, oldest first. With
+// Keep zero the list starts at the current period.
+func (p Policy) Ranges(now time.Time) []Range {
+	premake := p.Premake
+	if premake <= 0 {
+		premake = 2
+	}
+	cur := p.Interval.start(now)
+	out := make([]Range, 0, p.Keep+premake+1)
+	for n := -p.Keep; n <= premake; n++ {
+		from := p.Interval.add(cur, n)
+		out = append(out, Range{
+			Name: p.Table + "_" + p.Interval.suffix(from),
+			From: from,
+			To:   p.Interval.add(from, 1),
+		})
+	}
+	return out
+}
+
+// Result lists what Ensure changed.
+type Result struct {
+	Created, Detached, Dropped []string
+}
+
+// EnsureMonthly creates the monthly partitions of table up to two months
+// ahead and detaches those more than keep months old; keep zero detaches
+// nothing.
+func EnsureMonthly(ctx context.Context, q db.Querier, table string, keep int) (Result, error) {
+	return Ensure(ctx, q, Policy{Table: table, Interval: Monthly, Keep: keep})
+}
+
+// EnsureDaily is EnsureMonthly with daily partitions and keep in days.
+func EnsureDaily(ctx context.Context, q db.Querier, table string, keep int) (Result, error) {
+	return Ensure(ctx, q, Policy{Table: table, Interval: Daily, Keep: keep})
+}
+
+// Ensure brings p.Table in line with p at the current time of q's clock:
+// missing partitions from the current period to Premake periods ahead are
+// created, and partitions ending before the Keep window are detached (and
+// dropped with Drop). It is idempotent and safe to run from several
+// instances, as each statement is. Only PostgreSQL is supported; other
+// dialects get an error wrapping errors.ErrUnsupported.
+func Ensure(ctx context.Context, q db.Querier, p Policy) (Result, error) {
+	var res Result
+	if d := db.DialectFrom(q); d != db.DialectPostgres {
+		return res, fmt.Errorf("partition: %s: %w on dialect %q", p.Table, errors.ErrUnsupported, d)
+	}
+	keyType, err := rangeKeyType(ctx, q, p.Table)
+	if err != nil {
+		return res, err
+	}
+	existing, err := db.Select(ctx, q, func(r db.RowScanner) (string, error) {
+		var s string
+		return s, r.Scan(&s)
+	}, `
+		SELECT c.relname FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE  i.inhparent = to_regclass($1)`, quote(p.Table))
+	if err != nil {
+		return res, fmt.Errorf("partition: %s: list partitions: %w", p.Table, err)
+	}
+	attached := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		attached[name] = true
+	}
+
+	now := db.NowFrom(q)
+	for _, r := range p.Ranges(now) {
+		if r.To.Before(now) || attached[r.Name] {
+			continue // periods already past are not backfilled
+		}
+		_, err := q.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)`,
+			quote(r.Name), quote(p.Table), bound(keyType, r.From), bound(keyType, r.To)))
+		if err != nil {
+			return res, fmt.Errorf("partition: create %s: %w", r.Name, err)
+		}
+		res.Created = append(res.Created, r.Name)
+	}
+
+	if p.Keep <= 0 {
+		return res, nil
+	}
+	cutoff := p.Interval.add(p.Interval.start(now), -p.Keep)
+	owned := regexp.MustCompile(`^` + regexp.QuoteMeta(p.Table) + `_(p\d{4}_\d{2}(?:_\d{2})?)$`)
+	for _, name := range existing {
+		m := owned.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		from, err := time.Parse(layout(m[1]), m[1])
+		if err != nil || p.Interval.add(from, 1).After(cutoff) {
+			continue
+		}
+		if _, err := q.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, quote(p.Table), quote(name))); err != nil {
+			return res, fmt.Errorf("partition: detach %s: %w", name, err)
+		}
+		res.Detached = append(res.Detached, name)
+		if p.Drop {
+			if _, err := q.Exec(ctx, `DROP TABLE `+quote(name)); err != nil {
+				return res, fmt.Errorf("partition: drop %s: %w", name, err)
+			}
+			res.Dropped = append(res.Dropped, name)
+		}
+	}
+	return res, nil
+}
+
+func layout(suffix string) string {
+	if strings.Count(suffix, "_") == 2 {
+		return "p2006_01_02"
+	}
+	return "p2006_01"
+}
+
+// rangeKeyType returns the type of the single column p.Table is range
+// partitioned on.
+func rangeKeyType(ctx context.Context, q db.Querier, table string) (string, error) {
+	var strategy, typ string
+	err := q.QueryRow(ctx, `
+		SELECT p.partstrat::text, format_type(a.atttypid, a.atttypmod)
+		FROM   pg_partitioned_table p
+		JOIN   pg_attribute a ON a.attrelid = p.partrelid AND a.attnum = p.partattrs[0]
+		WHERE  p.partrelid = to_regclass($1) AND p.partnatts = 1`, quote(table)).Scan(&strategy, &typ)
+	if db.IsNotFound(err) {
+		return "", fmt.Errorf("partition: %s is not partitioned on a single column", table)
+	}
+	if err != nil {
+		return "", fmt.Errorf("partition: %s: %w", table, err)
+	}
+	if strategy != "r" {
+		return "", fmt.Errorf("partition: %s is not range partitioned", table)
+	}
+	return typ, nil
+}
+
+// bound renders t as a partition bound for a key of type typ.
+func bound(typ string, t time.Time) string {
+	switch {
+	case typ == "bigint":
+		return fmt.Sprint(t.UnixMicro())
+	case typ == "date":
+		return "'" + t.Format(time.DateOnly) + "'"
+	default:
+		return "'" + t.Format("2006-01-02 15:04:05Z07:00") + "'"
+	}
+}
+
+func quote(name string) string { return db.DialectPostgres.QuoteIdent(name) }
+
+// ── Scheduling ───────────────────────────────────────────────────────────────
+
+// Job returns a scheduler job running Ensure for each policy. A failing
+// policy does not stop the others; their errors are joined.
+func Job(q db.Querier, policies ...Policy) scheduler.Job {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, p := range policies {
+			if _, err := Ensure(ctx, q, p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// Register adds an hourly job named "partitions" to s maintaining every
+// policy. Running it that often costs a few catalog queries and means a
+// partition is never more than an hour late.
+func Register(s *scheduler.Scheduler, q db.Querier, policies ...Policy) error {
+	return s.Register("partitions", "@hourly", Job(q, policies...))
+}
@@ -0,0 +1,74 @@
+package partition_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/partition"
+	"github.com/Skryldev/sql-toolkit/scheduler"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func rangeNames(rs []partition.Range) string {
+	names := make([]string, len(rs))
+	for i, r := range rs {
+		names[i] = r.Name
+	}
+	return strings.Join(names, " ")
+}
+
+func TestPolicy_Ranges(t *testing.T) {
+	now := time.Date(2026, 1, 15, 13, 0, 0, 0, time.UTC) // a Thursday
+	cases := []struct {
+		policy partition.Policy
+		want   string
+	}{
+		{partition.Policy{Table: "events", Interval: partition.Monthly, Keep: 2},
+			"events_p2025_11 events_p2025_12 events_p2026_01 events_p2026_02 events_p2026_03"},
+		{partition.Policy{Table: "events", Interval: partition.Daily, Premake: 1},
+			"events_p2026_01_15 events_p2026_01_16"},
+		{partition.Policy{Table: "events", Interval: partition.Weekly, Keep: 1, Premake: 1},
+			"events_p2026_01_05 events_p2026_01_12 events_p2026_01_19"},
+	}
+	for _, c := range cases {
+		if got := rangeNames(c.policy.Ranges(now)); got != c.want {
+			t.Errorf("%s: got  %s\nwant %s", c.policy.Interval, got, c.want)
+		}
+	}
+
+	rs := partition.Policy{Table: "events", Interval: partition.Monthly}.Ranges(now)
+	if !rs[0].From.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !rs[0].To.Equal(rs[1].From) {
+		t.Fatalf("ranges must be contiguous from the start of the month: %+v", rs)
+	}
+}
+
+func TestEnsure_Unsupported(t *testing.T) {
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+
+	if _, err := partition.EnsureMonthly(ctx, d, "events", 12); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	job := partition.Job(d,
+		partition.Policy{Table: "events"},
+		partition.Policy{Table: "audit_log", Interval: partition.Daily})
+	if err := job(ctx); err == nil || !strings.Contains(err.Error(), "events") || !strings.Contains(err.Error(), "audit_log") {
+		t.Fatalf("expected both policies to be tried, got %v", err)
+	}
+
+	s := scheduler.New(d, scheduler.Options{Instance: "test"})
+	if err := partition.Register(s, d, partition.Policy{Table: "events"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := partition.Register(s, d, partition.Policy{Table: "events"}); err == nil {
+		t.Fatal("registering twice should fail")
+	}
+}
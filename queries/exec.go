@@ -0,0 +1,50 @@
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Typed execution
+// ─────────────────────────────────────────────────────────────────────────────
+
+// One runs a :one query and scans its single row; see db.Get.
+//
+//	var getUser = reg.MustQuery("GetUserByID")
+//	u, err := queries.One(ctx, d, getUser, scanUser, id)
+func One[T any](ctx context.Context, q db.Querier, query Query, scan db.ScanFunc[T], args ...any) (T, error) {
+	if err := query.expect(KindOne); err != nil {
+		var zero T
+		return zero, err
+	}
+	return db.Get(ctx, q, scan, query.SQL, args...)
+}
+
+// Many runs a :many query and scans every row; see db.Select.
+func Many[T any](ctx context.Context, q db.Querier, query Query, scan db.ScanFunc[T], args ...any) ([]T, error) {
+	if err := query.expect(KindMany); err != nil {
+		return nil, err
+	}
+	return db.Select(ctx, q, scan, query.SQL, args...)
+}
+
+// Exec runs an :exec query.
+func Exec(ctx context.Context, q db.Querier, query Query, args ...any) (sql.Result, error) {
+	if err := query.expect(KindExec); err != nil {
+		return nil, err
+	}
+	return q.Exec(ctx, query.SQL, args...)
+}
+
+// expect rejects running a query in a way its annotation does not allow.
+// Undeclared queries may be run any way.
+func (q Query) expect(k Kind) error {
+	if q.Kind != KindAny && q.Kind != k {
+		return fmt.Errorf("queries: %s is declared %s, not %s", q.Label(), q.Kind, k)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package queries
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Load parses annotated SQL from rd and registers every query in it. A query
+// starts at a "-- name: X" line, optionally followed by its Kind
+// ("-- name: X :one"); the "-- key: value" lines directly below it
+// become Tags ("version" sets Version), and everything up to the next name
+// line is the statement, with a trailing semicolon removed. Text before the
+// first name line is ignored. source is used in error messages.
+//
+// A "-- dialect: postgres[, sqlite]" line inside a query starts a block kept
+// only when the Registry's dialect (see NewRegistryFor) is listed;
+// "-- dialect: *" returns to lines shared by every dialect:
+//
+//	-- name: InsertUser :one
+//	-- dialect: postgres, sqlite
+//	INSERT INTO users (name) VALUES ($1) RETURNING id;
+//	-- dialect: mysql
+//	INSERT INTO users (name) VALUES (?);
+func (r *Registry) Load(rd io.Reader, source string) error {
+	qs, err := parse(rd, source, r.dialect)
+	if err != nil {
+		return err
+	}
+	for _, q := range qs {
+		if err := r.Add(q); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// LoadFS loads every file of fsys matching the glob patterns, in lexical
+// order. It is meant for SQL embedded in the binary:
+//
+//	//go:embed sql/*.sql
+//	var sqlFiles embed.FS
+//
+//	var reg = queries.MustLoadFS(sqlFiles, "sql/*.sql")
+func (r *Registry) LoadFS(fsys fs.FS, patterns ...string) error {
+	var paths []string
+	for _, p := range patterns {
+		m, err := fs.Glob(fsys, p)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, m...)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("queries: no files match %q", patterns)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		f, err := fsys.Open(p)
+		if err != nil {
+			return err
+		}
+		err = r.Load(f, p)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MustLoadFS returns a new Registry loaded with LoadFS, panicking on error.
+func MustLoadFS(fsys fs.FS, patterns ...string) *Registry {
+	r := NewRegistry()
+	if err := r.LoadFS(fsys, patterns...); err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// LoadFile is Load for a file on disk.
+func (r *Registry) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.Load(f, path)
+}
+
+// parse splits rd into queries, keeping only the dialect blocks that apply
+// to dialect.
+func parse(rd io.Reader, source string, dialect db.Dialect) ([]Query, error) {
+	var (
+		out     []Query
+		cur     *Query
+		body    strings.Builder
+		inTags  bool
+		lineNum int
+
+		block     []db.Dialect // current dialect block; nil outside blocks
+		hasBlocks bool
+		matched   bool
+	)
+	flush := func() error {
+		if cur == nil {
+			return nil
+		}
+		cur.SQL = strings.TrimSuffix(strings.TrimSpace(body.String()), ";")
+		cur.SQL = strings.TrimSpace(cur.SQL)
+		if hasBlocks && !matched {
+			if dialect == db.DialectUnknown {
+				return fmt.Errorf("%s: query %q has dialect blocks; load it into a Registry from NewRegistryFor", source, cur.Name)
+			}
+			return fmt.Errorf("%s: query %q has no variant for %s", source, cur.Name, dialect)
+		}
+		if cur.SQL == "" {
+			return fmt.Errorf("%s: query %q has no SQL", source, cur.Name)
+		}
+		out = append(out, *cur)
+		return nil
+	}
+
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(make([]byte, 64<<10), 4<<20)
+	for sc.Scan() {
+		lineNum++
+		line := sc.Text()
+		key, value, isTag := annotation(line)
+		switch {
+		case isTag && key == "name":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			fields := strings.Fields(value)
+			if len(fields) == 0 || len(fields) > 2 {
+				return nil, fmt.Errorf("%s:%d: want \"-- name: Name [:kind]\"", source, lineNum)
+			}
+			cur = &Query{Name: fields[0]}
+			if len(fields) == 2 {
+				switch k := Kind(fields[1]); k {
+				case KindOne, KindMany, KindExec:
+					cur.Kind = k
+				default:
+					return nil, fmt.Errorf("%s:%d: unknown kind %q", source, lineNum, fields[1])
+				}
+			}
+			body.Reset()
+			inTags = true
+			block, hasBlocks, matched = nil, false, false
+		case cur == nil:
+			// preamble
+		case isTag && key == "dialect":
+			inTags = false
+			if value == "*" || value == "any" {
+				block = nil
+				continue
+			}
+			block = block[:0:0]
+			for _, name := range strings.Split(value, ",") {
+				d, err := parseDialect(strings.TrimSpace(name))
+				if err != nil {
+					return nil, fmt.Errorf("%s:%d: %w", source, lineNum, err)
+				}
+				block = append(block, d)
+			}
+			hasBlocks = true
+			if slices.Contains(block, dialect) {
+				matched = true
+			}
+		case isTag && inTags:
+			if key == "version" {
+				v, err := strconv.Atoi(value)
+				if err != nil || v < 0 {
+					return nil, fmt.Errorf("%s:%d: invalid version %q", source, lineNum, value)
+				}
+				cur.Version = v
+				continue
+			}
+			if cur.Tags == nil {
+				cur.Tags = map[string]string{}
+			}
+			cur.Tags[key] = value
+		default:
+			inTags = false
+			if block == nil || slices.Contains(block, dialect) {
+				body.WriteString(line)
+				body.WriteByte('\n')
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// annotation parses "-- key: value". Keys are lower-cased.
+func annotation(line string) (key, value string, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(line), "--")
+	if !found {
+		return "", "", false
+	}
+	key, value, found = strings.Cut(rest, ":")
+	key = strings.TrimSpace(key)
+	if !found || key == "" || strings.ContainsAny(key, " \t") {
+		return "", "", false
+	}
+	return strings.ToLower(key), strings.TrimSpace(value), true
+}
+
+// parseDialect accepts a Dialect name ("postgres", "mysql", "sqlite") or a
+// driver name ("pgx", "sqlite3", ...).
+func parseDialect(name string) (db.Dialect, error) {
+	switch d := db.Dialect(name); d {
+	case db.DialectPostgres, db.DialectMySQL, db.DialectSQLite:
+		return d, nil
+	}
+	if d := db.DialectOf(name); d != db.DialectUnknown {
+		return d, nil
+	}
+	return db.DialectUnknown, fmt.Errorf("unknown dialect %q", name)
+}
@@ -0,0 +1,248 @@
+// Package queries keeps SQL in a registry of named statements instead of
+// string constants scattered through repositories.
+//
+// Statements are added in code or loaded from .sql files annotated in the
+// goyesql style:
+//
+//	-- name: GetUserByEmail
+//	-- version: 2
+//	SELECT id, name, email FROM users WHERE email = $1;
+//
+// Repositories look statements up by name, the whole registry is prepared
+// against the database at startup with Validate, or handed to
+// db.RegisterQueries with Register so dbtest.ValidateRegistered checks it
+// with the repositories' statements, and Metrics/Tracer wrappers report
+// statements under their registered name instead of their text.
+//
+//	reg := queries.NewRegistry()
+//	if err := reg.LoadFile("sql/users.sql"); err != nil { ... }
+//	if err := reg.Validate(ctx, database); err != nil { ... }
+//	hooks := []db.Hook{db.NewMetricsHook(reg.Metrics(prom))}
+//
+//	row := database.QueryRow(ctx, reg.MustGet("GetUserByEmail"), email)
+package queries
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Kind declares how a statement is meant to be run, as written after its
+// name in a .sql file ("-- name: GetUserByID :one").
+type Kind string
+
+const (
+	KindAny  Kind = ""      // not declared
+	KindOne  Kind = ":one"  // returns a single row; use One
+	KindMany Kind = ":many" // returns rows; use Many
+	KindExec Kind = ":exec" // returns no rows; use Exec
+)
+
+// Query is one registered statement.
+type Query struct {
+	Name string
+	Kind Kind
+	// Version distinguishes revisions of the same logical query; Get returns
+	// the highest. Zero means unversioned.
+	Version int
+	SQL     string
+	// Tags holds the extra "-- key: value" annotations of a loaded query.
+	Tags map[string]string
+}
+
+// Label is the name reported to metrics and tracing: "Name" or "Name@vN".
+func (q Query) Label() string {
+	if q.Version > 0 {
+		return q.Name + "@v" + strconv.Itoa(q.Version)
+	}
+	return q.Name
+}
+
+// Registry holds named statements. It is safe for concurrent use.
+type Registry struct {
+	dialect db.Dialect
+
+	mu     sync.RWMutex
+	byName map[string][]Query // ascending Version
+}
+
+// NewRegistry returns an empty Registry. Loaded files may not contain
+// dialect blocks; use NewRegistryFor for those.
+func NewRegistry() *Registry { return NewRegistryFor(db.DialectUnknown) }
+
+// NewRegistryFor returns an empty Registry that keeps the dialect blocks of
+// loaded files matching d, e.g. database.Dialect() or
+// db.DialectOf(cfg.DriverName).
+func NewRegistryFor(d db.Dialect) *Registry {
+	return &Registry{dialect: d, byName: map[string][]Query{}}
+}
+
+// Dialect returns the dialect the Registry selects variants for.
+func (r *Registry) Dialect() db.Dialect { return r.dialect }
+
+// Add registers q. Registering the same name and version twice is an error.
+func (r *Registry) Add(q Query) error {
+	if q.Name == "" || q.SQL == "" {
+		return fmt.Errorf("queries: name and SQL are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.byName[q.Name]
+	for _, v := range versions {
+		if v.Version == q.Version {
+			return fmt.Errorf("queries: %s already registered", q.Label())
+		}
+	}
+	versions = append(versions, q)
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	r.byName[q.Name] = versions
+	return nil
+}
+
+// MustAdd is like Add but panics on error; use it from init or var blocks.
+func (r *Registry) MustAdd(name, sql string) {
+	if err := r.Add(Query{Name: name, SQL: sql}); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the latest version of name.
+func (r *Registry) Lookup(name string) (Query, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := r.byName[name]
+	if len(versions) == 0 {
+		return Query{}, false
+	}
+	return versions[len(versions)-1], true
+}
+
+// LookupVersion returns a specific version of name.
+func (r *Registry) LookupVersion(name string, version int) (Query, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, q := range r.byName[name] {
+		if q.Version == version {
+			return q, true
+		}
+	}
+	return Query{}, false
+}
+
+// MustQuery is like Lookup but panics when name is unknown. Resolve statements
+// once into package variables so a missing name fails at startup.
+func (r *Registry) MustQuery(name string) Query {
+	q, ok := r.Lookup(name)
+	if !ok {
+		panic(fmt.Sprintf("queries: %q not registered", name))
+	}
+	return q
+}
+
+// Get returns the SQL of the latest version of name.
+func (r *Registry) Get(name string) (string, error) {
+	q, ok := r.Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("queries: %q not registered", name)
+	}
+	return q.SQL, nil
+}
+
+// MustGet is like Get but panics when name is unknown. Repositories resolve
+// their statements once, at construction, so a missing name fails fast.
+func (r *Registry) MustGet(name string) string {
+	sql, err := r.Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return sql
+}
+
+// LabelOf returns the label of the statement whose text is sql. When
+// several statements share the text, it is the label of the first by name
+// and version.
+func (r *Registry) LabelOf(sql string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	label := ""
+	for _, versions := range r.byName {
+		for _, q := range versions {
+			if q.SQL == sql && (label == "" || q.Label() < label) {
+				label = q.Label()
+			}
+		}
+	}
+	return label, label != ""
+}
+
+// All returns every registered statement, every version included, keyed by
+// label.
+func (r *Registry) All() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]string, len(r.byName))
+	for _, versions := range r.byName {
+		for _, q := range versions {
+			out[q.Label()] = q.SQL
+		}
+	}
+	return out
+}
+
+// Validate prepares every registered statement on q; see db.ValidateQueries.
+func (r *Registry) Validate(ctx context.Context, q db.Querier) error {
+	return db.ValidateQueries(ctx, q, r.All())
+}
+
+// Register adds every statement to db.RegisterQueries under pkg, keyed by
+// label, so dbtest.ValidateRegistered covers them. Call it once the
+// registry is loaded; like db.RegisterQueries it panics on a key already
+// registered.
+func (r *Registry) Register(pkg string) {
+	db.RegisterQueries(pkg, r.All())
+}
+
+// ── Labelling ────────────────────────────────────────────────────────────────
+
+// Metrics wraps c so registered statements are recorded under their label
+// rather than their SQL text. Other statements pass through unchanged.
+func (r *Registry) Metrics(c db.MetricsCollector) db.MetricsCollector {
+	return labeledMetrics{r: r, c: c}
+}
+
+type labeledMetrics struct {
+	r *Registry
+	c db.MetricsCollector
+}
+
+func (m labeledMetrics) RecordQuery(query string, d time.Duration, success bool) {
+	if label, ok := m.r.LabelOf(query); ok {
+		query = label
+	}
+	m.c.RecordQuery(query, d, success)
+}
+
+// Tracer wraps t so spans of registered statements are named by label.
+func (r *Registry) Tracer(t db.Tracer) db.Tracer {
+	return labeledTracer{r: r, t: t}
+}
+
+type labeledTracer struct {
+	r *Registry
+	t db.Tracer
+}
+
+func (l labeledTracer) StartSpan(ctx context.Context, query string) context.Context {
+	if label, ok := l.r.LabelOf(query); ok {
+		query = label
+	}
+	return l.t.StartSpan(ctx, query)
+}
+
+func (l labeledTracer) EndSpan(ctx context.Context, err error) { l.t.EndSpan(ctx, err) }
@@ -0,0 +1,175 @@
+package queries_test
+
+import (
+	"context"
+	"embed"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/queries"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const usersSQL = `
+-- Queries for the users table.
+
+-- name: GetUserByEmail
+-- version: 2
+-- owner: accounts
+SELECT id, name FROM users WHERE email = $1;
+
+-- name: GetUserByEmail
+SELECT * FROM users WHERE email = $1;
+
+-- name: CountUsers
+-- Counts every user.
+SELECT COUNT(*) FROM users
+`
+
+type recordingMetrics struct{ names []string }
+
+func (m *recordingMetrics) RecordQuery(query string, _ time.Duration, _ bool) {
+	m.names = append(m.names, query)
+}
+
+func TestRegistry(t *testing.T) {
+	reg := queries.NewRegistry()
+	if err := reg.Load(strings.NewReader(usersSQL), "users.sql"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	q, ok := reg.Lookup("GetUserByEmail")
+	if !ok || q.Version != 2 || q.SQL != "SELECT id, name FROM users WHERE email = $1" || q.Tags["owner"] != "accounts" {
+		t.Fatalf("latest GetUserByEmail = %+v", q)
+	}
+	if v0, _ := reg.LookupVersion("GetUserByEmail", 0); v0.SQL != "SELECT * FROM users WHERE email = $1" {
+		t.Fatalf("version 0 = %q", v0.SQL)
+	}
+	// Comments below the tag block belong to the statement.
+	if sql := reg.MustGet("CountUsers"); sql != "-- Counts every user.\nSELECT COUNT(*) FROM users" {
+		t.Fatalf("CountUsers = %q", sql)
+	}
+	if err := reg.Load(strings.NewReader("-- name: CountUsers\nSELECT 1"), "dup.sql"); err == nil {
+		t.Fatal("expected duplicate registration error")
+	}
+
+	metrics := &recordingMetrics{}
+	d, err := db.Open(db.Config{
+		DSN: ":memory:", DriverName: "sqlite3",
+		Hooks: []db.Hook{db.NewMetricsHook(reg.Metrics(metrics))},
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if err := reg.Validate(ctx, d); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	var n int
+	_ = d.QueryRow(ctx, reg.MustGet("CountUsers")).Scan(&n)
+	if got := metrics.names[len(metrics.names)-1]; got != "CountUsers" {
+		t.Fatalf("metrics label = %q", got)
+	}
+	_ = d.QueryRow(ctx, reg.MustGet("GetUserByEmail"), "x").Scan(&n, new(string))
+	if got := metrics.names[len(metrics.names)-1]; got != "GetUserByEmail@v2" {
+		t.Fatalf("metrics label = %q", got)
+	}
+
+	reg.Register("queries_test")
+	registered := db.RegisteredQueries()
+	for label, sql := range reg.All() {
+		if registered["queries_test."+label] != sql {
+			t.Errorf("%s not in the db registry", label)
+		}
+	}
+	if len(reg.All()) != 3 {
+		t.Fatalf("All = %v, want every version of every name", reg.All())
+	}
+}
+
+//go:embed testdata/*.sql
+var sqlFiles embed.FS
+
+func TestLoadFS_TypedAccessors(t *testing.T) {
+	reg := queries.MustLoadFS(sqlFiles, "testdata/*.sql")
+	insert, get, list := reg.MustQuery("InsertUser"), reg.MustQuery("GetUserByEmail"), reg.MustQuery("ListUserNames")
+	if insert.Kind != queries.KindExec || get.Kind != queries.KindOne || list.Kind != queries.KindMany {
+		t.Fatalf("kinds: %q %q %q", insert.Kind, get.Kind, list.Kind)
+	}
+
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer d.Close()
+	ctx := context.Background()
+	if _, err := d.Exec(ctx, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT)`); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	for _, name := range []string{"bob", "alice"} {
+		if _, err := queries.Exec(ctx, d, insert, name, name+"@x"); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	scanName := func(r db.RowScanner) (string, error) {
+		var id int64
+		var name string
+		err := r.Scan(&id, &name)
+		return name, err
+	}
+	name, err := queries.One(ctx, d, get, scanName, "bob@x")
+	if err != nil || name != "bob" {
+		t.Fatalf("one = %q, %v", name, err)
+	}
+	names, err := queries.Many(ctx, d, list, func(r db.RowScanner) (string, error) {
+		var n string
+		err := r.Scan(&n)
+		return n, err
+	})
+	if err != nil || strings.Join(names, ",") != "alice,bob" {
+		t.Fatalf("many = %v, %v", names, err)
+	}
+	if _, err := queries.Many(ctx, d, get, scanName, "bob@x"); err == nil {
+		t.Fatal("expected kind mismatch error")
+	}
+}
+
+const insertSQL = `
+-- name: InsertUser :one
+-- dialect: postgres, sqlite
+INSERT INTO users (name) VALUES ($1) RETURNING id;
+-- dialect: mysql
+INSERT INTO users (name) VALUES (?);
+-- dialect: *
+`
+
+func TestLoad_DialectBlocks(t *testing.T) {
+	for driver, want := range map[string]string{
+		"sqlite3": "INSERT INTO users (name) VALUES ($1) RETURNING id",
+		"pgx":     "INSERT INTO users (name) VALUES ($1) RETURNING id",
+		"mysql":   "INSERT INTO users (name) VALUES (?)",
+	} {
+		reg := queries.NewRegistryFor(db.DialectOf(driver))
+		if err := reg.Load(strings.NewReader(insertSQL), "insert.sql"); err != nil {
+			t.Fatalf("%s: %v", driver, err)
+		}
+		if got := reg.MustGet("InsertUser"); got != want {
+			t.Errorf("%s: got %q, want %q", driver, got, want)
+		}
+	}
+	if err := queries.NewRegistry().Load(strings.NewReader(insertSQL), "insert.sql"); err == nil {
+		t.Fatal("expected error loading dialect blocks without a dialect")
+	}
+	onlyPG := "-- name: Q\n-- dialect: postgres\nSELECT 1"
+	if err := queries.NewRegistryFor(db.DialectMySQL).Load(strings.NewReader(onlyPG), "q.sql"); err == nil {
+		t.Fatal("expected error for a query without a MySQL variant")
+	}
+}
@@ -0,0 +1,8 @@
+-- name: InsertUser :exec
+INSERT INTO users (name, email) VALUES ($1, $2);
+
+-- name: GetUserByEmail :one
+SELECT id, name FROM users WHERE email = $1;
+
+-- name: ListUserNames :many
+SELECT name FROM users ORDER BY name;
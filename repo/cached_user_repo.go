@@ -0,0 +1,276 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/models"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Cache interface — pluggable backend (in-memory, Redis, memcached, ...)
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Cache is the minimal key/value contract required by the caching decorators.
+// Values are opaque bytes so any networked cache can implement it directly.
+type Cache interface {
+	// Get returns the value for key and whether it was present.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl. A zero ttl means no expiry.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes keys; missing keys are not an error.
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// cachedUserRepo — read-through decorator
+// ─────────────────────────────────────────────────────────────────────────────
+
+// cachedUserRepo wraps a UserRepository with read-through caching.
+//
+// Users are cached by id; GetByEmail goes through a small email → id index so
+// that invalidating the id entry is enough to drop every cached view of a
+// user. A stale index entry is detected by comparing the email on the loaded
+// record and treated as a miss.
+//
+// Cache failures never fail a request: reads fall back to the inner
+// repository and invalidation errors are logged.
+type cachedUserRepo struct {
+	inner UserRepository
+	cache Cache
+	ttl   time.Duration
+}
+
+// NewCachedUserRepo returns a UserRepository that caches GetByID and
+// GetByEmail results in cache for ttl and invalidates on writes.
+// It is a reference implementation of the decorator pattern; copy it for
+// other entities.
+func NewCachedUserRepo(inner UserRepository, cache Cache, ttl time.Duration) UserRepository {
+	return &cachedUserRepo{inner: inner, cache: cache, ttl: ttl}
+}
+
+func userIDKey(id int64) string        { return "user:id:" + strconv.FormatInt(id, 10) }
+func userEmailKey(email string) string { return "user:email:" + email }
+
+// UserCacheKeys returns the cache keys holding the user whose id is key (in
+// decimal), for invalidation driven by change notifications:
+//
+//	inv.Table("users", repo.UserCacheKeys)
+func UserCacheKeys(key string) []string { return []string{"user:id:" + key} }
+
+// ── Reads ────────────────────────────────────────────────────────────────────
+
+// GetByID returns the cached user or loads it from the inner repository.
+func (r *cachedUserRepo) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	if u, ok := r.lookup(ctx, userIDKey(id)); ok {
+		return u, nil
+	}
+	u, err := r.inner.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.store(ctx, u)
+	return u, nil
+}
+
+// GetByEmail resolves email through the index and falls back to the inner
+// repository on any miss.
+func (r *cachedUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if raw, ok, err := r.cache.Get(ctx, userEmailKey(email)); err == nil && ok {
+		if id, err := strconv.ParseInt(string(raw), 10, 64); err == nil {
+			if u, ok := r.lookup(ctx, userIDKey(id)); ok && u.Email == email {
+				return u, nil
+			}
+		}
+	}
+	u, err := r.inner.GetByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	r.store(ctx, u)
+	return u, nil
+}
+
+// GetAsOf is not cached; history reads are rare and keyed by time.
+func (r *cachedUserRepo) GetAsOf(ctx context.Context, id int64, at time.Time) (*models.User, error) {
+	return r.inner.GetAsOf(ctx, id, at)
+}
+
+// List is not cached; result sets are too volatile to invalidate precisely.
+func (r *cachedUserRepo) List(ctx context.Context, filter models.UserFilter) ([]*models.User, error) {
+	return r.inner.List(ctx, filter)
+}
+
+// ListPage is not cached, like List.
+func (r *cachedUserRepo) ListPage(ctx context.Context, filter models.UserFilter, count CountMode) (*models.Page[*models.User], error) {
+	return r.inner.ListPage(ctx, filter, count)
+}
+
+// Count is not cached.
+func (r *cachedUserRepo) Count(ctx context.Context) (int64, error) {
+	return r.inner.Count(ctx)
+}
+
+// ── Writes ───────────────────────────────────────────────────────────────────
+
+// Insert delegates and drops any stale email index entry for the new email.
+func (r *cachedUserRepo) Insert(ctx context.Context, params models.CreateUserParams) (*models.User, error) {
+	u, err := r.inner.Insert(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	r.invalidate(ctx, userEmailKey(u.Email))
+	return u, nil
+}
+
+// BatchInsert delegates and drops stale email index entries.
+func (r *cachedUserRepo) BatchInsert(ctx context.Context, params []models.CreateUserParams) ([]*models.User, error) {
+	users, err := r.inner.BatchInsert(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(users))
+	for _, u := range users {
+		keys = append(keys, userEmailKey(u.Email))
+	}
+	r.invalidate(ctx, keys...)
+	return users, nil
+}
+
+// Update delegates and invalidates the cached record.
+func (r *cachedUserRepo) Update(ctx context.Context, params models.UpdateUserParams) (*models.User, error) {
+	u, err := r.inner.Update(ctx, params)
+	r.invalidate(ctx, userIDKey(params.ID))
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Patch delegates and invalidates the cached record.
+func (r *cachedUserRepo) Patch(ctx context.Context, id int64, fields map[string]any) (*models.User, error) {
+	u, err := r.inner.Patch(ctx, id, fields)
+	r.invalidate(ctx, userIDKey(id))
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// Delete delegates and invalidates the cached record.
+func (r *cachedUserRepo) Delete(ctx context.Context, id int64) error {
+	err := r.inner.Delete(ctx, id)
+	r.invalidate(ctx, userIDKey(id))
+	return err
+}
+
+// ── Internal helpers ─────────────────────────────────────────────────────────
+
+func (r *cachedUserRepo) lookup(ctx context.Context, key string) (*models.User, bool) {
+	raw, ok, err := r.cache.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	u := &models.User{}
+	if err := json.Unmarshal(raw, u); err != nil {
+		return nil, false
+	}
+	return u, true
+}
+
+func (r *cachedUserRepo) store(ctx context.Context, u *models.User) {
+	raw, err := json.Marshal(u)
+	if err != nil {
+		return
+	}
+	if err := r.cache.Set(ctx, userIDKey(u.ID), raw, r.ttl); err != nil {
+		slog.WarnContext(ctx, "repo/user: cache set failed", "error", err)
+		return
+	}
+	_ = r.cache.Set(ctx, userEmailKey(u.Email), []byte(strconv.FormatInt(u.ID, 10)), r.ttl)
+}
+
+func (r *cachedUserRepo) invalidate(ctx context.Context, keys ...string) {
+	if err := r.cache.Delete(ctx, keys...); err != nil {
+		slog.WarnContext(ctx, "repo/user: cache invalidation failed", "keys", keys, "error", err)
+	}
+}
+
+var _ UserRepository = (*cachedUserRepo)(nil)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// MemoryCache — in-process Cache implementation
+// ─────────────────────────────────────────────────────────────────────────────
+
+// MemoryCache is a goroutine-safe in-process Cache. Expired entries are
+// evicted lazily on access. Suitable for single-instance services and tests.
+type MemoryCache struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time // zero = never
+}
+
+// NewMemoryCache returns an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]memoryEntry)}
+}
+
+// Get implements Cache.
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false, nil
+	}
+	if now := time.Now(); !e.expiresAt.IsZero() && now.After(e.expiresAt) {
+		c.mu.Lock()
+		// A Set may have replaced the entry since the read lock was released.
+		if cur, ok := c.entries[key]; ok && !cur.expiresAt.IsZero() && now.After(cur.expiresAt) {
+			delete(c.entries, key)
+		}
+		c.mu.Unlock()
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+// Set implements Cache.
+func (c *MemoryCache) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	e := memoryEntry{value: value}
+	if ttl > 0 {
+		e.expiresAt = time.Now().Add(ttl)
+	}
+	c.mu.Lock()
+	c.entries[key] = e
+	c.mu.Unlock()
+	return nil
+}
+
+// Delete implements Cache.
+func (c *MemoryCache) Delete(_ context.Context, keys ...string) error {
+	c.mu.Lock()
+	for _, k := range keys {
+		delete(c.entries, k)
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of stored entries, including not-yet-evicted
+// expired ones.
+func (c *MemoryCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+var _ Cache = (*MemoryCache)(nil)
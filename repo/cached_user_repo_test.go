@@ -0,0 +1,84 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/models"
+	"github.com/Skryldev/sql-toolkit/repo"
+)
+
+// countingRepo counts read calls that reach the underlying repository.
+type countingRepo struct {
+	repo.UserRepository
+	reads int
+}
+
+func (c *countingRepo) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	c.reads++
+	return c.UserRepository.GetByID(ctx, id)
+}
+
+func (c *countingRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	c.reads++
+	return c.UserRepository.GetByEmail(ctx, email)
+}
+
+func TestCachedUserRepo_ReadThroughAndInvalidate(t *testing.T) {
+	inner, _ := newTestRepo(t)
+	counting := &countingRepo{UserRepository: inner}
+	r := repo.NewCachedUserRepo(counting, repo.NewMemoryCache(), time.Minute)
+	ctx := context.Background()
+
+	u, err := r.Insert(ctx, models.CreateUserParams{Name: "Cache", Email: "cache@repo.com"})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	for range 3 {
+		if _, err := r.GetByID(ctx, u.ID); err != nil {
+			t.Fatalf("get: %v", err)
+		}
+		if _, err := r.GetByEmail(ctx, u.Email); err != nil {
+			t.Fatalf("get by email: %v", err)
+		}
+	}
+	if counting.reads != 1 {
+		t.Fatalf("expected 1 inner read, got %d", counting.reads)
+	}
+
+	newEmail := "moved@repo.com"
+	if _, err := r.Update(ctx, models.UpdateUserParams{ID: u.ID, Email: &newEmail}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	got, err := r.GetByID(ctx, u.ID)
+	if err != nil || got.Email != newEmail {
+		t.Fatalf("expected fresh record after update, got %+v err=%v", got, err)
+	}
+	if _, err := r.GetByEmail(ctx, "cache@repo.com"); !db.IsNotFound(err) {
+		t.Fatalf("stale email index should miss, got %v", err)
+	}
+
+	if err := r.Delete(ctx, u.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := r.GetByID(ctx, u.ID); !db.IsNotFound(err) {
+		t.Fatalf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestMemoryCache_Expiry(t *testing.T) {
+	c := repo.NewMemoryCache()
+	ctx := context.Background()
+
+	_ = c.Set(ctx, "k", []byte("v"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := c.Get(ctx, "k"); ok {
+		t.Fatal("expected entry to expire")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("expected expired entry to be evicted, len=%d", c.Len())
+	}
+}
@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/models"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Instrumentation — domain-level observability for repositories
+// ─────────────────────────────────────────────────────────────────────────────
+
+// Instrumentation bundles the backends used to observe repository operations.
+// It reuses the db package's MetricsCollector and Tracer interfaces so one
+// implementation serves both the SQL layer and the domain layer. All fields
+// are optional.
+type Instrumentation struct {
+	Metrics db.MetricsCollector
+	Tracer  db.Tracer
+	// Logger receives one entry per operation; nil disables logging.
+	Logger *slog.Logger
+}
+
+// Observe runs fn as the named domain operation (e.g. "UserRepository.GetByEmail").
+// The span started by the Tracer is carried in the context passed to fn, so
+// SQL-level spans emitted by hooks nest under the domain span.
+//
+// Observe is the building block for instrumented decorators: wrap each
+// interface method in a one-line call.
+//
+//	func (r *instrumentedOrderRepo) Get(ctx context.Context, id int64) (*Order, error) {
+//	    return repo.Observe(ctx, r.in, "OrderRepository.Get", func(ctx context.Context) (*Order, error) {
+//	        return r.inner.Get(ctx, id)
+//	    })
+//	}
+func Observe[T any](ctx context.Context, in Instrumentation, op string, fn func(context.Context) (T, error)) (T, error) {
+	if in.Tracer != nil {
+		ctx = in.Tracer.StartSpan(ctx, op)
+	}
+	start := time.Now()
+	v, err := fn(ctx)
+	d := time.Since(start)
+
+	if in.Tracer != nil {
+		in.Tracer.EndSpan(ctx, err)
+	}
+	if in.Metrics != nil {
+		in.Metrics.RecordQuery(op, d, err == nil)
+	}
+	if in.Logger != nil {
+		switch {
+		case err == nil, db.IsNotFound(err):
+			in.Logger.DebugContext(ctx, "repo: operation", "op", op, "duration", d, "error", err)
+		default:
+			in.Logger.ErrorContext(ctx, "repo: operation failed", "op", op, "duration", d, "error", err)
+		}
+	}
+	return v, err
+}
+
+// ObserveErr is Observe for operations that only return an error.
+func ObserveErr(ctx context.Context, in Instrumentation, op string, fn func(context.Context) error) error {
+	_, err := Observe(ctx, in, op, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// instrumentedUserRepo
+// ─────────────────────────────────────────────────────────────────────────────
+
+type instrumentedUserRepo struct {
+	inner UserRepository
+	in    Instrumentation
+}
+
+// NewInstrumentedUserRepo wraps inner so every method is reported as a
+// "UserRepository.<Method>" operation.
+func NewInstrumentedUserRepo(inner UserRepository, in Instrumentation) UserRepository {
+	return &instrumentedUserRepo{inner: inner, in: in}
+}
+
+func (r *instrumentedUserRepo) Insert(ctx context.Context, params models.CreateUserParams) (*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.Insert", func(ctx context.Context) (*models.User, error) {
+		return r.inner.Insert(ctx, params)
+	})
+}
+
+func (r *instrumentedUserRepo) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.GetByID", func(ctx context.Context) (*models.User, error) {
+		return r.inner.GetByID(ctx, id)
+	})
+}
+
+func (r *instrumentedUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.GetByEmail", func(ctx context.Context) (*models.User, error) {
+		return r.inner.GetByEmail(ctx, email)
+	})
+}
+
+func (r *instrumentedUserRepo) GetAsOf(ctx context.Context, id int64, at time.Time) (*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.GetAsOf", func(ctx context.Context) (*models.User, error) {
+		return r.inner.GetAsOf(ctx, id, at)
+	})
+}
+
+func (r *instrumentedUserRepo) List(ctx context.Context, filter models.UserFilter) ([]*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.List", func(ctx context.Context) ([]*models.User, error) {
+		return r.inner.List(ctx, filter)
+	})
+}
+
+func (r *instrumentedUserRepo) ListPage(ctx context.Context, filter models.UserFilter, count CountMode) (*models.Page[*models.User], error) {
+	return Observe(ctx, r.in, "UserRepository.ListPage", func(ctx context.Context) (*models.Page[*models.User], error) {
+		return r.inner.ListPage(ctx, filter, count)
+	})
+}
+
+func (r *instrumentedUserRepo) Update(ctx context.Context, params models.UpdateUserParams) (*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.Update", func(ctx context.Context) (*models.User, error) {
+		return r.inner.Update(ctx, params)
+	})
+}
+
+func (r *instrumentedUserRepo) Patch(ctx context.Context, id int64, fields map[string]any) (*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.Patch", func(ctx context.Context) (*models.User, error) {
+		return r.inner.Patch(ctx, id, fields)
+	})
+}
+
+func (r *instrumentedUserRepo) Delete(ctx context.Context, id int64) error {
+	return ObserveErr(ctx, r.in, "UserRepository.Delete", func(ctx context.Context) error {
+		return r.inner.Delete(ctx, id)
+	})
+}
+
+func (r *instrumentedUserRepo) BatchInsert(ctx context.Context, params []models.CreateUserParams) ([]*models.User, error) {
+	return Observe(ctx, r.in, "UserRepository.BatchInsert", func(ctx context.Context) ([]*models.User, error) {
+		return r.inner.BatchInsert(ctx, params)
+	})
+}
+
+func (r *instrumentedUserRepo) Count(ctx context.Context) (int64, error) {
+	return Observe(ctx, r.in, "UserRepository.Count", func(ctx context.Context) (int64, error) {
+		return r.inner.Count(ctx)
+	})
+}
+
+var _ UserRepository = (*instrumentedUserRepo)(nil)
@@ -0,0 +1,49 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/models"
+	"github.com/Skryldev/sql-toolkit/repo"
+)
+
+type recordedOp struct {
+	op      string
+	success bool
+}
+
+type opCollector struct{ ops []recordedOp }
+
+func (c *opCollector) RecordQuery(op string, _ time.Duration, success bool) {
+	c.ops = append(c.ops, recordedOp{op, success})
+}
+
+func TestInstrumentedUserRepo_RecordsOperations(t *testing.T) {
+	inner, _ := newTestRepo(t)
+	collector := &opCollector{}
+	r := repo.NewInstrumentedUserRepo(inner, repo.Instrumentation{Metrics: collector})
+	ctx := context.Background()
+
+	u, err := r.Insert(ctx, models.CreateUserParams{Name: "Obs", Email: "obs@repo.com"})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	_, _ = r.GetByEmail(ctx, u.Email)
+	_ = r.Delete(ctx, 99999)
+
+	want := []recordedOp{
+		{"UserRepository.Insert", true},
+		{"UserRepository.GetByEmail", true},
+		{"UserRepository.Delete", false},
+	}
+	if len(collector.ops) != len(want) {
+		t.Fatalf("expected %d ops, got %+v", len(want), collector.ops)
+	}
+	for i, w := range want {
+		if collector.ops[i] != w {
+			t.Fatalf("op %d: expected %+v, got %+v", i, w, collector.ops[i])
+		}
+	}
+}
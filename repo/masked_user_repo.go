@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db/mask"
+	"github.com/Skryldev/sql-toolkit/models"
+)
+
+// ─────────────────────────────────────────────────────────────────────────────
+// maskedUserRepo — column masking for non-production reads
+// ─────────────────────────────────────────────────────────────────────────────
+
+// maskedUserRepo applies mask.Rules to every user it returns. Rules are keyed
+// by column name ("name", "email"). Writes pass through untouched.
+type maskedUserRepo struct {
+	inner UserRepository
+	rules mask.Rules
+}
+
+// NewMaskedUserRepo wraps inner so returned users have their columns masked
+// according to rules.
+func NewMaskedUserRepo(inner UserRepository, rules mask.Rules) UserRepository {
+	return &maskedUserRepo{inner: inner, rules: rules}
+}
+
+func (r *maskedUserRepo) apply(u *models.User) *models.User {
+	if u == nil {
+		return nil
+	}
+	m := *u
+	m.Name = r.rules.String("name", m.Name)
+	m.Email = r.rules.String("email", m.Email)
+	return &m
+}
+
+func (r *maskedUserRepo) applyAll(users []*models.User) []*models.User {
+	for i, u := range users {
+		users[i] = r.apply(u)
+	}
+	return users
+}
+
+func (r *maskedUserRepo) Insert(ctx context.Context, params models.CreateUserParams) (*models.User, error) {
+	u, err := r.inner.Insert(ctx, params)
+	return r.apply(u), err
+}
+
+func (r *maskedUserRepo) GetByID(ctx context.Context, id int64) (*models.User, error) {
+	u, err := r.inner.GetByID(ctx, id)
+	return r.apply(u), err
+}
+
+func (r *maskedUserRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	u, err := r.inner.GetByEmail(ctx, email)
+	return r.apply(u), err
+}
+
+func (r *maskedUserRepo) GetAsOf(ctx context.Context, id int64, at time.Time) (*models.User, error) {
+	u, err := r.inner.GetAsOf(ctx, id, at)
+	return r.apply(u), err
+}
+
+func (r *maskedUserRepo) List(ctx context.Context, filter models.UserFilter) ([]*models.User, error) {
+	users, err := r.inner.List(ctx, filter)
+	return r.applyAll(users), err
+}
+
+func (r *maskedUserRepo) ListPage(ctx context.Context, filter models.UserFilter, count CountMode) (*models.Page[*models.User], error) {
+	page, err := r.inner.ListPage(ctx, filter, count)
+	if page != nil {
+		page.Items = r.applyAll(page.Items)
+	}
+	return page, err
+}
+
+func (r *maskedUserRepo) Update(ctx context.Context, params models.UpdateUserParams) (*models.User, error) {
+	u, err := r.inner.Update(ctx, params)
+	return r.apply(u), err
+}
+
+func (r *maskedUserRepo) Patch(ctx context.Context, id int64, fields map[string]any) (*models.User, error) {
+	u, err := r.inner.Patch(ctx, id, fields)
+	return r.apply(u), err
+}
+
+func (r *maskedUserRepo) Delete(ctx context.Context, id int64) error {
+	return r.inner.Delete(ctx, id)
+}
+
+func (r *maskedUserRepo) BatchInsert(ctx context.Context, params []models.CreateUserParams) ([]*models.User, error) {
+	users, err := r.inner.BatchInsert(ctx, params)
+	return r.applyAll(users), err
+}
+
+func (r *maskedUserRepo) Count(ctx context.Context) (int64, error) {
+	return r.inner.Count(ctx)
+}
+
+var _ UserRepository = (*maskedUserRepo)(nil)
@@ -0,0 +1,30 @@
+package repo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db/mask"
+	"github.com/Skryldev/sql-toolkit/models"
+	"github.com/Skryldev/sql-toolkit/repo"
+)
+
+func TestMaskedUserRepo(t *testing.T) {
+	inner, _ := newTestRepo(t)
+	r := repo.NewMaskedUserRepo(inner, mask.Rules{"email": mask.Email})
+	ctx := context.Background()
+
+	u, err := r.Insert(ctx, models.CreateUserParams{Name: "Mia", Email: "mia@corp.com"})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if u.Email != "m***@corp.com" || u.Name != "Mia" {
+		t.Fatalf("unexpected masking: %+v", u)
+	}
+
+	// The underlying row is untouched.
+	raw, _ := inner.GetByID(ctx, u.ID)
+	if raw.Email != "mia@corp.com" {
+		t.Fatalf("masking must not alter stored data: %q", raw.Email)
+	}
+}
@@ -0,0 +1,219 @@
+// Package repotest holds behavioural test suites that any implementation of
+// a repository interface must pass, whatever sits behind it: the SQL
+// repository, a caching or instrumenting decorator, a sharded fan-out or a
+// hand-written fake. Running the suite against a new implementation proves
+// it can be swapped in without callers noticing:
+//
+//	func TestShardedUserRepo(t *testing.T) {
+//	    repotest.UserRepository(t, func(t *testing.T) repo.UserRepository {
+//	        return newShardedRepo(t) // empty users table per call
+//	    })
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/models"
+	"github.com/Skryldev/sql-toolkit/repo"
+)
+
+// UserRepository runs the UserRepository contract as subtests. newRepo is
+// called once per subtest and must return a repository over an empty users
+// table.
+//
+// The contract covers what callers rely on: writes return the stored record
+// with its id and timestamps, reads after a write see it, and failures use
+// the db sentinels — ErrNotFound for missing users, ErrDuplicateKey for a
+// taken email, ErrInvalidFilter for unknown patch fields.
+func UserRepository(t *testing.T, newRepo func(t *testing.T) repo.UserRepository) {
+	t.Run("Insert", func(t *testing.T) {
+		r, ctx := newRepo(t), context.Background()
+		u, err := r.Insert(ctx, models.CreateUserParams{Name: "Alice", Email: "alice@example.com"})
+		if err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+		if u.ID == 0 || u.Name != "Alice" || u.Email != "alice@example.com" || u.CreatedAt.IsZero() || u.UpdatedAt.IsZero() {
+			t.Fatalf("insert returned %+v", u)
+		}
+		_, err = r.Insert(ctx, models.CreateUserParams{Name: "Other", Email: "alice@example.com"})
+		if !db.IsDuplicateKey(err) {
+			t.Fatalf("duplicate email: expected ErrDuplicateKey, got %v", err)
+		}
+	})
+
+	t.Run("Get", func(t *testing.T) {
+		r, ctx := newRepo(t), context.Background()
+		u := mustInsert(t, r, "Bob", "bob@example.com")
+		byID, err := r.GetByID(ctx, u.ID)
+		if err != nil || !sameUser(byID, u) {
+			t.Fatalf("GetByID = %+v, %v; want %+v", byID, err, u)
+		}
+		byEmail, err := r.GetByEmail(ctx, u.Email)
+		if err != nil || !sameUser(byEmail, u) {
+			t.Fatalf("GetByEmail = %+v, %v; want %+v", byEmail, err, u)
+		}
+		if _, err := r.GetByID(ctx, u.ID+1000); !db.IsNotFound(err) {
+			t.Fatalf("GetByID of a missing user: expected ErrNotFound, got %v", err)
+		}
+		if _, err := r.GetByEmail(ctx, "nobody@example.com"); !db.IsNotFound(err) {
+			t.Fatalf("GetByEmail of a missing user: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r, ctx := newRepo(t), context.Background()
+		u := mustInsert(t, r, "Carol", "carol@example.com")
+		_, _ = r.GetByID(ctx, u.ID) // warm any cache in front of the store
+		_, _ = r.GetByEmail(ctx, u.Email)
+
+		email := "carol@new.example.com"
+		got, err := r.Update(ctx, models.UpdateUserParams{ID: u.ID, Email: &email})
+		if err != nil {
+			t.Fatalf("update: %v", err)
+		}
+		if got.ID != u.ID || got.Name != "Carol" || got.Email != email || got.UpdatedAt.Before(u.UpdatedAt) {
+			t.Fatalf("update returned %+v", got)
+		}
+		if read, err := r.GetByID(ctx, u.ID); err != nil || read.Email != email {
+			t.Fatalf("GetByID after update = %+v, %v", read, err)
+		}
+		if read, err := r.GetByEmail(ctx, email); err != nil || read.ID != u.ID {
+			t.Fatalf("GetByEmail of the new email = %+v, %v", read, err)
+		}
+		if _, err := r.GetByEmail(ctx, "carol@example.com"); !db.IsNotFound(err) {
+			t.Fatalf("GetByEmail of the old email: expected ErrNotFound, got %v", err)
+		}
+
+		if same, err := r.Update(ctx, models.UpdateUserParams{ID: u.ID}); err != nil || same.Email != email {
+			t.Fatalf("empty update = %+v, %v; want the current record", same, err)
+		}
+		name := "Nobody"
+		if _, err := r.Update(ctx, models.UpdateUserParams{ID: u.ID + 1000, Name: &name}); !db.IsNotFound(err) {
+			t.Fatalf("update of a missing user: expected ErrNotFound, got %v", err)
+		}
+		other := mustInsert(t, r, "Dave", "dave@example.com")
+		if _, err := r.Update(ctx, models.UpdateUserParams{ID: other.ID, Email: &email}); !db.IsDuplicateKey(err) {
+			t.Fatalf("update to a taken email: expected ErrDuplicateKey, got %v", err)
+		}
+	})
+
+	t.Run("Patch", func(t *testing.T) {
+		r, ctx := newRepo(t), context.Background()
+		u := mustInsert(t, r, "Erin", "erin@example.com")
+		_, _ = r.GetByID(ctx, u.ID)
+
+		got, err := r.Patch(ctx, u.ID, map[string]any{"name": "Erin B"})
+		if err != nil || got.Name != "Erin B" || got.Email != u.Email {
+			t.Fatalf("patch = %+v, %v", got, err)
+		}
+		if read, err := r.GetByID(ctx, u.ID); err != nil || read.Name != "Erin B" {
+			t.Fatalf("GetByID after patch = %+v, %v", read, err)
+		}
+		if _, err := r.Patch(ctx, u.ID, map[string]any{"id": 7}); !errors.Is(err, db.ErrInvalidFilter) {
+			t.Fatalf("patch of an unknown field: expected ErrInvalidFilter, got %v", err)
+		}
+		if _, err := r.Patch(ctx, u.ID+1000, map[string]any{"name": "x"}); !db.IsNotFound(err) {
+			t.Fatalf("patch of a missing user: expected ErrNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		r, ctx := newRepo(t), context.Background()
+		u := mustInsert(t, r, "Frank", "frank@example.com")
+		_, _ = r.GetByID(ctx, u.ID)
+		_, _ = r.GetByEmail(ctx, u.Email)
+
+		if err := r.Delete(ctx, u.ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if _, err := r.GetByID(ctx, u.ID); !db.IsNotFound(err) {
+			t.Fatalf("GetByID after delete: expected ErrNotFound, got %v", err)
+		}
+		if _, err := r.GetByEmail(ctx, u.Email); !db.IsNotFound(err) {
+			t.Fatalf("GetByEmail after delete: expected ErrNotFound, got %v", err)
+		}
+		if err := r.Delete(ctx, u.ID); !db.IsNotFound(err) {
+			t.Fatalf("second delete: expected ErrNotFound, got %v", err)
+		}
+		if _, err := r.Insert(ctx, models.CreateUserParams{Name: "Frank", Email: u.Email}); err != nil {
+			t.Fatalf("reusing a deleted user's email: %v", err)
+		}
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r, ctx := newRepo(t), context.Background()
+		users, err := r.BatchInsert(ctx, []models.CreateUserParams{
+			{Name: "Gina", Email: "gina@a.example"},
+			{Name: "Hank", Email: "hank@b.example"},
+			{Name: "Gus", Email: "gus@b.example"},
+		})
+		if err != nil || len(users) != 3 {
+			t.Fatalf("batch insert = %d users, %v", len(users), err)
+		}
+		for i, u := range users {
+			if u.ID == 0 || i > 0 && u.ID <= users[i-1].ID {
+				t.Fatalf("batch insert must return users in input order with ids: %+v", users)
+			}
+		}
+		if none, err := r.BatchInsert(ctx, nil); err != nil || len(none) != 0 {
+			t.Fatalf("empty batch = %v, %v", none, err)
+		}
+		if n, err := r.Count(ctx); err != nil || n != 3 {
+			t.Fatalf("Count = %d, %v; want 3", n, err)
+		}
+
+		all, err := r.List(ctx, models.UserFilter{})
+		if err != nil || names(all) != "Gina Hank Gus" {
+			t.Fatalf("List = %q, %v; want id order", names(all), err)
+		}
+		page, err := r.List(ctx, models.UserFilter{SortBy: "name", Limit: 2, Offset: 1})
+		if err != nil || names(page) != "Gus Hank" {
+			t.Fatalf("List by name, limit 2 offset 1 = %q, %v", names(page), err)
+		}
+		g, err := r.List(ctx, models.UserFilter{NamePrefix: "G", EmailDomain: "b.example"})
+		if err != nil || names(g) != "Gus" {
+			t.Fatalf("filtered List = %q, %v", names(g), err)
+		}
+		if _, err := r.List(ctx, models.UserFilter{SortBy: "password"}); !errors.Is(err, db.ErrInvalidFilter) {
+			t.Fatalf("unknown sort: expected ErrInvalidFilter, got %v", err)
+		}
+
+		p, err := r.ListPage(ctx, models.UserFilter{Limit: 2}, repo.CountQuery)
+		if err != nil || p.Total != 3 || names(p.Items) != "Gina Hank" || p.NextCursor == "" {
+			t.Fatalf("ListPage = %+v, %v", p, err)
+		}
+		p, err = r.ListPage(ctx, models.UserFilter{Limit: 2, Cursor: p.NextCursor}, repo.CountNone)
+		if err != nil || p.Total != -1 || names(p.Items) != "Gus" || p.NextCursor != "" {
+			t.Fatalf("second ListPage = %+v, %v", p, err)
+		}
+	})
+}
+
+func mustInsert(t *testing.T, r repo.UserRepository, name, email string) *models.User {
+	t.Helper()
+	u, err := r.Insert(context.Background(), models.CreateUserParams{Name: name, Email: email})
+	if err != nil {
+		t.Fatalf("insert %s: %v", email, err)
+	}
+	return u
+}
+
+func sameUser(a, b *models.User) bool {
+	return a != nil && a.ID == b.ID && a.Name == b.Name && a.Email == b.Email &&
+		a.CreatedAt.Equal(b.CreatedAt) && a.UpdatedAt.Equal(b.UpdatedAt)
+}
+
+func names(users []*models.User) string {
+	s := ""
+	for i, u := range users {
+		if i > 0 {
+			s += " "
+		}
+		s += u.Name
+	}
+	return s
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -23,8 +24,11 @@ type UserRepository interface {
 	Insert(ctx context.Context, params models.CreateUserParams) (*models.User, error)
 	GetByID(ctx context.Context, id int64) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
-	List(ctx context.Context, limit, offset int) ([]*models.User, error)
+	GetAsOf(ctx context.Context, id int64, at time.Time) (*models.User, error)
+	List(ctx context.Context, filter models.UserFilter) ([]*models.User, error)
+	ListPage(ctx context.Context, filter models.UserFilter, count CountMode) (*models.Page[*models.User], error)
 	Update(ctx context.Context, params models.UpdateUserParams) (*models.User, error)
+	Patch(ctx context.Context, id int64, fields map[string]any) (*models.User, error)
 	Delete(ctx context.Context, id int64) error
 	BatchInsert(ctx context.Context, params []models.CreateUserParams) ([]*models.User, error)
 	Count(ctx context.Context) (int64, error)
@@ -52,8 +56,7 @@ func NewUserRepo(q db.Querier) UserRepository {
 const (
 	sqlInsertUser = `
 		INSERT INTO users (name, email, created_at, updated_at)
-		VALUES ($1, $2, $3, $3)
-		RETURNING id, name, email, created_at, updated_at`
+		VALUES ($1, $2, $3, $3)`
 
 	sqlGetUserByID = `
 		SELECT id, name, email, created_at, updated_at
@@ -69,17 +72,39 @@ const (
 
 	sqlListUsers = `
 		SELECT id, name, email, created_at, updated_at
-		FROM   users
-		ORDER  BY id
-		LIMIT  $1 OFFSET $2`
+		FROM   users`
 
 	sqlDeleteUser = `
 		DELETE FROM users WHERE id = $1`
 
 	sqlCountUsers = `
 		SELECT COUNT(*) FROM users`
+
+	sqlListUsersWithTotal = `
+		SELECT id, name, email, created_at, updated_at, COUNT(*) OVER ()
+		FROM   users`
 )
 
+// userReturning reads a written row back on every driver; see
+// db.InsertReturning.
+var userReturning = db.Returning{
+	Table:   "users",
+	Columns: []string{"id", "name", "email", "created_at", "updated_at"},
+}
+
+func init() {
+	db.RegisterQueries("repo/user", map[string]string{
+		"Insert":     sqlInsertUser,
+		"GetByID":    sqlGetUserByID,
+		"GetByEmail": sqlGetUserByEmail,
+		"List":       sqlListUsers,
+		"ListPage":   sqlListUsersWithTotal,
+		"Delete":     sqlDeleteUser,
+		"Count":      sqlCountUsers,
+	})
+	db.RegisterSchema(db.ExpectStruct[models.User]("users"))
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Insert
 // ─────────────────────────────────────────────────────────────────────────────
@@ -87,9 +112,14 @@ const (
 // Insert creates a new user and returns the persisted record including the
 // database-assigned id and timestamps.
 func (r *userRepo) Insert(ctx context.Context, params models.CreateUserParams) (*models.User, error) {
-	now := time.Now().UTC()
-	row := r.q.QueryRow(ctx, sqlInsertUser, params.Name, params.Email, now)
-	return scanUser(row)
+	now := db.NowFrom(r.q).UTC()
+	u := &models.User{}
+	err := db.InsertReturning(ctx, r.q, sqlInsertUser,
+		[]any{params.Name, params.Email, now}, userReturning, userFields(u)...)
+	if err != nil {
+		return nil, fmt.Errorf("repo/user: %w", err)
+	}
+	return u, nil
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -99,8 +129,8 @@ func (r *userRepo) Insert(ctx context.Context, params models.CreateUserParams) (
 // GetByID returns a single user by primary key.
 // Returns db.ErrNotFound when no record matches.
 func (r *userRepo) GetByID(ctx context.Context, id int64) (*models.User, error) {
-	row := r.q.QueryRow(ctx, sqlGetUserByID, id)
-	return scanUser(row)
+	query, args := r.rebind(sqlGetUserByID, id)
+	return scanUser(r.q.QueryRow(ctx, query, args...))
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -110,17 +140,71 @@ func (r *userRepo) GetByID(ctx context.Context, id int64) (*models.User, error)
 // GetByEmail looks up a user by their unique email address.
 // Returns db.ErrNotFound when no record matches.
 func (r *userRepo) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	row := r.q.QueryRow(ctx, sqlGetUserByEmail, email)
-	return scanUser(row)
+	query, args := r.rebind(sqlGetUserByEmail, email)
+	return scanUser(r.q.QueryRow(ctx, query, args...))
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// GetAsOf
+// ─────────────────────────────────────────────────────────────────────────────
+
+// usersTemporal is the users table as read by GetAsOf.
+var usersTemporal = db.TemporalTable{Name: "users"}
+
+// GetAsOf returns a user as it was at time at, from a system-versioned users
+// table (MariaDB) or its history table (PostgreSQL, see db.TemporalTable).
+// Returns db.ErrNotFound when the user did not exist then, and an error
+// wrapping errors.ErrUnsupported on other dialects.
+func (r *userRepo) GetAsOf(ctx context.Context, id int64, at time.Time) (*models.User, error) {
+	src, err := usersTemporal.AsOf(db.DialectFrom(r.q), 2)
+	if err != nil {
+		return nil, err
+	}
+	query, args := r.rebind(`
+		SELECT id, name, email, created_at, updated_at
+		FROM   `+src+`
+		WHERE  id = $1
+		LIMIT  1`, id, at)
+	return scanUser(r.q.QueryRow(ctx, query, args...))
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
 // List
 // ─────────────────────────────────────────────────────────────────────────────
 
-// List returns a paginated slice of users ordered by id.
-func (r *userRepo) List(ctx context.Context, limit, offset int) ([]*models.User, error) {
-	rows, err := r.q.Query(ctx, sqlListUsers, limit, offset)
+// userSortColumns whitelists the sort fields accepted by List.
+var userSortColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+}
+
+// userFilterColumns whitelists the fields of UserFilter.Filter.
+var userFilterColumns = map[string]string{
+	"id":         "id",
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// defaultListLimit caps List when the filter does not set a limit.
+const defaultListLimit = 100
+
+// List returns a page of users matching f. Every filter value is bound as a
+// parameter; sort input and f.Filter fields are checked against
+// userSortColumns and userFilterColumns and rejected with
+// db.ErrInvalidFilter when unknown.
+func (r *userRepo) List(ctx context.Context, f models.UserFilter) ([]*models.User, error) {
+	c, orderBy, err := r.listConditions(f)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("%s\n\t\t%s\n\t\t%s\n\t\tLIMIT %s OFFSET %s",
+		sqlListUsers, c.Where(), orderBy, c.Bind(listLimit(f)), c.Bind(f.Offset))
+
+	rows, err := r.q.Query(ctx, query, c.Args()...)
 	if err != nil {
 		return nil, err
 	}
@@ -129,7 +213,7 @@ func (r *userRepo) List(ctx context.Context, limit, offset int) ([]*models.User,
 	var users []*models.User
 	for rows.Next() {
 		u := &models.User{}
-		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		if err := rows.Scan(userFields(u)...); err != nil {
 			return nil, fmt.Errorf("repo/user: scan: %w", err)
 		}
 		users = append(users, u)
@@ -137,26 +221,201 @@ func (r *userRepo) List(ctx context.Context, limit, offset int) ([]*models.User,
 	return users, rows.Err()
 }
 
+// listConditions validates f and translates it into WHERE conditions and an
+// ORDER BY clause.
+func (r *userRepo) listConditions(f models.UserFilter) (*db.Conditions, string, error) {
+	orderBy, err := userOrderBy(f)
+	if err != nil {
+		return nil, "", err
+	}
+
+	dialect := db.DialectFrom(r.q)
+	c := db.NewDialectConditions(dialect)
+	if f.NamePrefix != "" {
+		c.Add("name LIKE ?"+dialect.LikeEscape(), db.LikePrefix(f.NamePrefix))
+	}
+	if f.EmailDomain != "" {
+		c.Add("email LIKE ?"+dialect.LikeEscape(), db.LikeSuffix("@"+f.EmailDomain))
+	}
+	if !f.CreatedAfter.IsZero() {
+		c.Add("created_at >= ?", f.CreatedAfter.UTC())
+	}
+	if !f.CreatedBefore.IsZero() {
+		c.Add("created_at < ?", f.CreatedBefore.UTC())
+	}
+	where, err := db.ParseFilter(f.Filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := c.AddFilter(where, userFilterColumns); err != nil {
+		return nil, "", err
+	}
+	return c, orderBy, nil
+}
+
+// userOrderBy builds the ORDER BY clause for f. Multi-key sorts end with id
+// so that pages stay stable when the requested keys tie.
+func userOrderBy(f models.UserFilter) (string, error) {
+	if f.Sort == "" {
+		dir, err := db.ParseSortDirection(f.SortDir)
+		if err != nil {
+			return "", err
+		}
+		return db.SortClause(userSortColumns, f.SortBy, "id", dir)
+	}
+	fields, err := db.ParseSort(f.Sort)
+	if err != nil {
+		return "", err
+	}
+	if !slices.ContainsFunc(fields, func(sf db.SortField) bool { return sf.Field == "id" }) {
+		fields = append(fields, db.SortField{Field: "id", Dir: db.Asc})
+	}
+	return db.OrderBy(userSortColumns, fields)
+}
+
+func listLimit(f models.UserFilter) int {
+	if f.Limit <= 0 {
+		return defaultListLimit
+	}
+	return f.Limit
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// ListPage
+// ─────────────────────────────────────────────────────────────────────────────
+
+// CountMode selects how ListPage computes Page.Total.
+type CountMode int
+
+const (
+	// CountNone skips counting: Total is -1, and one extra row is read to
+	// decide whether there is a next page.
+	CountNone CountMode = iota
+	// CountQuery runs SELECT COUNT(*) with the same filter, in the same
+	// read-only REPEATABLE READ transaction as the page, so Total and Items
+	// see one snapshot. On a repository bound to a caller's transaction
+	// both run at that transaction's isolation level instead.
+	CountQuery
+	// CountWindow adds COUNT(*) OVER () to the page query: one round trip,
+	// but the database still visits every matching row.
+	CountWindow
+)
+
+// ListPage is List wrapped in a models.Page. f.Cursor, when set, replaces
+// f.Offset and is rejected with db.ErrInvalidFilter when malformed.
+func (r *userRepo) ListPage(ctx context.Context, f models.UserFilter, count CountMode) (*models.Page[*models.User], error) {
+	if f.Cursor != "" {
+		offset, err := models.DecodeCursor(f.Cursor)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", db.ErrInvalidFilter, err)
+		}
+		f.Offset = offset
+	}
+	c, orderBy, err := r.listConditions(f)
+	if err != nil {
+		return nil, err
+	}
+	limit := listLimit(f)
+	page := &models.Page[*models.User]{Items: []*models.User{}, Total: -1, Limit: limit, Offset: f.Offset}
+
+	countQuery := sqlCountUsers + "\n\t\t" + c.Where()
+	countArgs := append([]any(nil), c.Args()...)
+	countRows := func(q db.Querier) error {
+		return q.QueryRow(ctx, countQuery, countArgs...).Scan(&page.Total)
+	}
+
+	base, fetch := sqlListUsers, limit
+	switch count {
+	case CountNone:
+		fetch = limit + 1
+	case CountWindow:
+		base = sqlListUsersWithTotal
+	}
+	query := fmt.Sprintf("%s\n\t\t%s\n\t\t%s\n\t\tLIMIT %s OFFSET %s",
+		base, c.Where(), orderBy, c.Bind(fetch), c.Bind(f.Offset))
+
+	err = inTx(ctx, r.q, count == CountQuery, func(q db.Querier) error {
+		if count == CountQuery {
+			if err := countRows(q); err != nil {
+				return err
+			}
+		}
+		rows, err := q.Query(ctx, query, c.Args()...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			u := &models.User{}
+			dest := userFields(u)
+			if count == CountWindow {
+				dest = append(dest, &page.Total)
+			}
+			if err := rows.Scan(dest...); err != nil {
+				return fmt.Errorf("repo/user: scan: %w", err)
+			}
+			page.Items = append(page.Items, u)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		// Past the last row the window has nothing to count over.
+		if count == CountWindow && len(page.Items) == 0 && f.Offset > 0 {
+			return countRows(q)
+		}
+		if count == CountWindow && len(page.Items) == 0 {
+			page.Total = 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	more := int64(f.Offset+len(page.Items)) < page.Total
+	if count == CountNone {
+		more = len(page.Items) > limit
+		page.Items = page.Items[:min(len(page.Items), limit)]
+	}
+	if more {
+		page.NextCursor = models.EncodeCursor(f.Offset + len(page.Items))
+	}
+	return page, nil
+}
+
+// inTx runs fn in a read-only REPEATABLE READ transaction when tx is set and
+// q is a *db.DB, and on q otherwise. Only reads may go through it.
+func inTx(ctx context.Context, q db.Querier, tx bool, fn func(db.Querier) error) error {
+	if d, ok := q.(*db.DB); ok && tx {
+		return d.ExecTx(ctx, func(tx *db.Tx) error { return fn(tx) },
+			db.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	}
+	return fn(q)
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Update — partial update with explicit SQL construction
 // ─────────────────────────────────────────────────────────────────────────────
 
+// userUpdatable lists the columns Update may set, in SET-clause order.
+var userUpdatable = []string{"name", "email"}
+
 // Update applies a partial update to a user record. Only fields with non-nil
 // pointers in params are updated. The SQL is built dynamically but remains
 // fully visible — no hidden magic.
 func (r *userRepo) Update(ctx context.Context, params models.UpdateUserParams) (*models.User, error) {
-	setClauses := make([]string, 0, 3)
-	args := make([]any, 0, 4)
+	changes := db.Diff(models.UpdateUserParams{ID: params.ID}, params)
+	setClauses := make([]string, 0, len(userUpdatable)+1)
+	args := make([]any, 0, len(userUpdatable)+2)
 	argIdx := 1
 
-	if params.Name != nil {
-		setClauses = append(setClauses, fmt.Sprintf("name = $%d", argIdx))
-		args = append(args, *params.Name)
-		argIdx++
-	}
-	if params.Email != nil {
-		setClauses = append(setClauses, fmt.Sprintf("email = $%d", argIdx))
-		args = append(args, *params.Email)
+	for _, col := range userUpdatable {
+		v, ok := changes[col]
+		if !ok {
+			continue
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", col, argIdx))
+		args = append(args, v)
 		argIdx++
 	}
 	if len(setClauses) == 0 {
@@ -164,7 +423,7 @@ func (r *userRepo) Update(ctx context.Context, params models.UpdateUserParams) (
 	}
 
 	setClauses = append(setClauses, fmt.Sprintf("updated_at = $%d", argIdx))
-	args = append(args, time.Now().UTC())
+	args = append(args, db.NowFrom(r.q).UTC())
 	argIdx++
 
 	args = append(args, params.ID)
@@ -172,12 +431,47 @@ func (r *userRepo) Update(ctx context.Context, params models.UpdateUserParams) (
 	query := fmt.Sprintf(`
 		UPDATE users
 		SET    %s
-		WHERE  id = $%d
-		RETURNING id, name, email, created_at, updated_at`,
+		WHERE  id = $%d`,
 		strings.Join(setClauses, ", "), argIdx)
 
-	row := r.q.QueryRow(ctx, query, args...)
-	return scanUser(row)
+	u := &models.User{}
+	if err := db.UpdateReturning(ctx, r.q, query, args, userReturning, params.ID, userFields(u)...); err != nil {
+		return nil, fmt.Errorf("repo/user: %w", err)
+	}
+	return u, nil
+}
+
+// UserPatchColumns whitelists the fields accepted by Patch, keyed by their
+// JSON name.
+var UserPatchColumns = map[string]string{
+	"name":  "name",
+	"email": "email",
+}
+
+// Patch applies a partial update given as field → value, e.g. the decoded
+// body of a PATCH request. Fields are checked against UserPatchColumns and
+// rejected with db.ErrInvalidFilter when unknown; an empty patch returns the
+// current record.
+func (r *userRepo) Patch(ctx context.Context, id int64, fields map[string]any) (*models.User, error) {
+	set, args, err := db.PatchSet(db.DialectFrom(r.q), UserPatchColumns, fields)
+	if err != nil {
+		return nil, err
+	}
+	if set == "" {
+		return r.GetByID(ctx, id)
+	}
+	args = append(args, db.NowFrom(r.q).UTC(), id)
+	query := fmt.Sprintf(`
+		UPDATE users
+		SET    %s, updated_at = $%d
+		WHERE  id = $%d`,
+		set, len(args)-1, len(args))
+
+	u := &models.User{}
+	if err := db.UpdateReturning(ctx, r.q, query, args, userReturning, id, userFields(u)...); err != nil {
+		return nil, fmt.Errorf("repo/user: %w", err)
+	}
+	return u, nil
 }
 
 // ─────────────────────────────────────────────────────────────────────────────
@@ -187,7 +481,8 @@ func (r *userRepo) Update(ctx context.Context, params models.UpdateUserParams) (
 // Delete removes a user by id.
 // Returns db.ErrNotFound if no row was deleted.
 func (r *userRepo) Delete(ctx context.Context, id int64) error {
-	res, err := r.q.Exec(ctx, sqlDeleteUser, id)
+	query, args := r.rebind(sqlDeleteUser, id)
+	res, err := r.q.Exec(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -212,21 +507,29 @@ func (r *userRepo) BatchInsert(ctx context.Context, params []models.CreateUserPa
 		return nil, nil
 	}
 
-	// BatchExec requires a *DB; if r.q is a *Tx, we do it manually.
-	// We detect *Tx by trying the concrete type assertion.
-	const insertSQL = `
-		INSERT INTO users (name, email, created_at, updated_at)
-		VALUES ($1, $2, $3, $3)
-		RETURNING id, name, email, created_at, updated_at`
+	now := db.NowFrom(r.q).UTC()
+	users := make([]*models.User, 0, len(params))
 
-	stmt, err := r.q.Prepare(ctx, insertSQL)
+	// MySQL has no RETURNING to prepare; read each row back instead.
+	if db.DialectFrom(r.q) == db.DialectMySQL {
+		for _, p := range params {
+			u := &models.User{}
+			err := db.InsertReturning(ctx, r.q, sqlInsertUser,
+				[]any{p.Name, p.Email, now}, userReturning, userFields(u)...)
+			if err != nil {
+				return nil, fmt.Errorf("repo/user: %w", err)
+			}
+			users = append(users, u)
+		}
+		return users, nil
+	}
+
+	stmt, err := r.q.Prepare(ctx, sqlInsertUser+"\n\t\tRETURNING id, name, email, created_at, updated_at")
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	now := time.Now().UTC()
-	users := make([]*models.User, 0, len(params))
 	for _, p := range params {
 		row := stmt.QueryRow(ctx, p.Name, p.Email, now)
 		u, err := scanUser(row)
@@ -259,13 +562,27 @@ func (r *userRepo) Count(ctx context.Context) (int64, error) {
 // adding/removing columns only requires a change in one place.
 func scanUser(row *db.Row) (*models.User, error) {
 	u := &models.User{}
-	err := row.Scan(&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt)
+	err := row.Scan(userFields(u)...)
 	if err != nil {
 		return nil, fmt.Errorf("repo/user: %w", err)
 	}
 	return u, nil
 }
 
+// userFields returns the scan destinations of u in userReturning order.
+func userFields(u *models.User) []any {
+	return []any{&u.ID, &u.Name, &u.Email, &u.CreatedAt, &u.UpdatedAt}
+}
+
+// ─────────────────────────────────────────────────────────────────────────────
+// Dialect portability — the SQL above is written with $N placeholders
+// ─────────────────────────────────────────────────────────────────────────────
+
+// rebind adapts a $N statement to the dialect of r.q; see db.Dialect.Rebind.
+func (r *userRepo) rebind(query string, args ...any) (string, []any) {
+	return db.DialectFrom(r.q).Rebind(query, args)
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Compile-time interface assertion
 // ─────────────────────────────────────────────────────────────────────────────
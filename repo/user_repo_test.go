@@ -2,12 +2,16 @@ package repo_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
 	"github.com/Skryldev/sql-toolkit/models"
 	"github.com/Skryldev/sql-toolkit/repo"
+	"github.com/Skryldev/sql-toolkit/repo/repotest"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -17,11 +21,16 @@ import (
 
 func newTestRepo(t *testing.T) (repo.UserRepository, *db.DB) {
 	t.Helper()
+	return newTestRepoWith(t, db.Config{})
+}
 
-	database, err := db.Open(db.Config{
-		DSN:        ":memory:",
-		DriverName: "sqlite3",
-	})
+// newTestRepoWith is newTestRepo with extra Config settings; DSN and
+// DriverName are filled in.
+func newTestRepoWith(t *testing.T, cfg db.Config) (repo.UserRepository, *db.DB) {
+	t.Helper()
+
+	cfg.DSN, cfg.DriverName = ":memory:", "sqlite3"
+	database, err := db.Open(cfg)
 	if err != nil {
 		t.Fatalf("open: %v", err)
 	}
@@ -43,6 +52,34 @@ func newTestRepo(t *testing.T) (repo.UserRepository, *db.DB) {
 	return repo.NewUserRepo(database), database
 }
 
+func TestUserRepo_QueriesPrepare(t *testing.T) {
+	_, database := newTestRepo(t)
+	dbtest.ValidateRegistered(t, database)
+}
+
+// TestUserRepo_Contract runs the shared UserRepository suite against the SQL
+// repository and the decorators that must be drop-in replacements for it.
+func TestUserRepo_Contract(t *testing.T) {
+	t.Run("SQL", func(t *testing.T) {
+		repotest.UserRepository(t, func(t *testing.T) repo.UserRepository {
+			r, _ := newTestRepo(t)
+			return r
+		})
+	})
+	t.Run("Cached", func(t *testing.T) {
+		repotest.UserRepository(t, func(t *testing.T) repo.UserRepository {
+			r, _ := newTestRepo(t)
+			return repo.NewCachedUserRepo(r, repo.NewMemoryCache(), time.Minute)
+		})
+	})
+	t.Run("Instrumented", func(t *testing.T) {
+		repotest.UserRepository(t, func(t *testing.T) repo.UserRepository {
+			r, _ := newTestRepo(t)
+			return repo.NewInstrumentedUserRepo(r, repo.Instrumentation{})
+		})
+	})
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Insert
 // ─────────────────────────────────────────────────────────────────────────────
@@ -110,6 +147,14 @@ func TestUserRepo_GetByID_NotFound(t *testing.T) {
 	}
 }
 
+func TestUserRepo_GetAsOf_Unsupported(t *testing.T) {
+	r, _ := newTestRepo(t)
+	_, err := r.GetAsOf(context.Background(), 1, time.Now())
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("SQLite has no system versioning; expected ErrUnsupported, got %v", err)
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Update
 // ─────────────────────────────────────────────────────────────────────────────
@@ -133,6 +178,59 @@ func TestUserRepo_Update_Name(t *testing.T) {
 	}
 }
 
+func TestUserRepo_FrozenClock(t *testing.T) {
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := db.NewFakeClock(start)
+	r, _ := newTestRepoWith(t, db.Config{Clock: clock})
+	ctx := context.Background()
+
+	u, err := r.Insert(ctx, models.CreateUserParams{Name: "Clock", Email: "clock@repo.com"})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if !u.CreatedAt.Equal(start) || !u.UpdatedAt.Equal(start) {
+		t.Fatalf("timestamps = %v / %v, want %v", u.CreatedAt, u.UpdatedAt, start)
+	}
+
+	clock.Advance(time.Hour)
+	name := "Later"
+	u, err = r.Update(ctx, models.UpdateUserParams{ID: u.ID, Name: &name})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if !u.CreatedAt.Equal(start) || !u.UpdatedAt.Equal(start.Add(time.Hour)) {
+		t.Fatalf("after update: %v / %v", u.CreatedAt, u.UpdatedAt)
+	}
+}
+
+// TestUserRepo_Update_Golden pins the SQL built by the dynamic Update for
+// each combination of fields.
+func TestUserRepo_Update_Golden(t *testing.T) {
+	rec := dbtest.NewRecorder()
+	clock := db.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	r, _ := newTestRepoWith(t, db.Config{Clock: clock, Hooks: []db.Hook{rec}})
+	ctx := context.Background()
+
+	u, err := r.Insert(ctx, models.CreateUserParams{Name: "Golden", Email: "golden@repo.com"})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	rec.Reset()
+
+	name, email := "Renamed", "renamed@repo.com"
+	for _, p := range []models.UpdateUserParams{
+		{ID: u.ID, Name: &name},
+		{ID: u.ID, Email: &email},
+		{ID: u.ID, Name: &name, Email: &email},
+		{ID: u.ID},
+	} {
+		if _, err := r.Update(ctx, p); err != nil {
+			t.Fatalf("update %+v: %v", p, err)
+		}
+	}
+	rec.AssertGolden(t, "testdata/user_update.golden")
+}
+
 func TestUserRepo_Update_NilFields_NoChange(t *testing.T) {
 	r, _ := newTestRepo(t)
 	ctx := context.Background()
@@ -149,6 +247,40 @@ func TestUserRepo_Update_NilFields_NoChange(t *testing.T) {
 	}
 }
 
+func TestUserRepo_Patch(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	u, _ := r.Insert(ctx, models.CreateUserParams{Name: "Patchy", Email: "patch@repo.com"})
+
+	patched, err := r.Patch(ctx, u.ID, map[string]any{"email": "patched@repo.com"})
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if patched.Email != "patched@repo.com" || patched.Name != "Patchy" {
+		t.Fatalf("unexpected result: %+v", patched)
+	}
+
+	for _, fields := range []map[string]any{
+		{"id": 99},
+		{"name": "ok", "created_at": "2020-01-01"},
+	} {
+		if _, err := r.Patch(ctx, u.ID, fields); !db.IsInvalidFilter(err) {
+			t.Fatalf("Patch(%v): expected ErrInvalidFilter, got %v", fields, err)
+		}
+	}
+	if got, _ := r.GetByID(ctx, u.ID); got.Name != "Patchy" {
+		t.Fatalf("rejected patch was applied: %+v", got)
+	}
+
+	if same, err := r.Patch(ctx, u.ID, nil); err != nil || same.Email != "patched@repo.com" {
+		t.Fatalf("empty patch: %+v, %v", same, err)
+	}
+	if _, err := r.Patch(ctx, 9999, map[string]any{"name": "ghost"}); !db.IsNotFound(err) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // Delete
 // ─────────────────────────────────────────────────────────────────────────────
@@ -195,7 +327,7 @@ func TestUserRepo_List(t *testing.T) {
 		}
 	}
 
-	page, err := r.List(ctx, 3, 0)
+	page, err := r.List(ctx, models.UserFilter{Limit: 3})
 	if err != nil {
 		t.Fatalf("list: %v", err)
 	}
@@ -203,12 +335,131 @@ func TestUserRepo_List(t *testing.T) {
 		t.Fatalf("expected 3, got %d", len(page))
 	}
 
-	page2, _ := r.List(ctx, 3, 3)
+	page2, _ := r.List(ctx, models.UserFilter{Limit: 3, Offset: 3})
 	if len(page2) != 2 {
 		t.Fatalf("expected 2 on page 2, got %d", len(page2))
 	}
 }
 
+func TestUserRepo_List_Filter(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, p := range []models.CreateUserParams{
+		{Name: "Anna", Email: "anna@acme.com"},
+		{Name: "Andy", Email: "andy@other.com"},
+		{Name: "An_ex", Email: "anex@acme.com"},
+		{Name: "Bert", Email: "bert@acme.com"},
+	} {
+		if _, err := r.Insert(ctx, p); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	got, err := r.List(ctx, models.UserFilter{NamePrefix: "An", EmailDomain: "acme.com", SortBy: "name", SortDir: "desc"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "Anna" || got[1].Name != "An_ex" {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+
+	// "_" must be matched literally, not as a wildcard.
+	got, _ = r.List(ctx, models.UserFilter{NamePrefix: "An_"})
+	if len(got) != 1 || got[0].Name != "An_ex" {
+		t.Fatalf("expected escaped prefix match, got %+v", got)
+	}
+
+	_, err = r.List(ctx, models.UserFilter{SortBy: "password; DROP TABLE users"})
+	if !db.IsInvalidFilter(err) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+
+	got, err = r.List(ctx, models.UserFilter{EmailDomain: "acme.com", Sort: "-email,name"})
+	if err != nil {
+		t.Fatalf("multi-key sort: %v", err)
+	}
+	if len(got) != 3 || got[0].Name != "Bert" || got[2].Name != "An_ex" {
+		t.Fatalf("unexpected order: %+v", got)
+	}
+	_, err = r.List(ctx, models.UserFilter{Sort: "name,-created_at; DROP TABLE users"})
+	if !db.IsInvalidFilter(err) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+
+	got, err = r.List(ctx, models.UserFilter{
+		EmailDomain: "acme.com",
+		Filter:      `{"or": [{"field": "name", "op": "eq", "value": "Bert"}, {"field": "name", "op": "contains", "value": "_"}]}`,
+	})
+	if err != nil || len(got) != 2 || got[0].Name != "An_ex" || got[1].Name != "Bert" {
+		t.Fatalf("filter expression = %+v, %v", got, err)
+	}
+	for _, bad := range []string{
+		`{"field": "password", "op": "eq", "value": "x"}`,
+		`{"field": "name", "op": "eq", "value": "x'; DROP TABLE users; --", "extra": 1}`,
+		`{"field": "name", "op": "matches", "value": ".*"}`,
+	} {
+		if _, err := r.List(ctx, models.UserFilter{Filter: bad}); !db.IsInvalidFilter(err) {
+			t.Fatalf("filter %s: expected ErrInvalidFilter, got %v", bad, err)
+		}
+	}
+}
+
+func TestUserRepo_ListPage(t *testing.T) {
+	r, _ := newTestRepo(t)
+	ctx := context.Background()
+
+	for i := range 5 {
+		if _, err := r.Insert(ctx, models.CreateUserParams{Name: "Paged", Email: fmt.Sprintf("page%d@repo.com", i)}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		mode      repo.CountMode
+		wantTotal int64
+	}{
+		{repo.CountNone, -1},
+		{repo.CountQuery, 5},
+		{repo.CountWindow, 5},
+	} {
+		f := models.UserFilter{Limit: 2}
+		var seen []int64
+		for pages := 0; ; pages++ {
+			page, err := r.ListPage(ctx, f, tc.mode)
+			if err != nil {
+				t.Fatalf("mode %d: list page: %v", tc.mode, err)
+			}
+			if page.Total != tc.wantTotal || page.Limit != 2 {
+				t.Fatalf("mode %d: page = %+v", tc.mode, page)
+			}
+			for _, u := range page.Items {
+				seen = append(seen, u.ID)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			if pages > 3 {
+				t.Fatalf("mode %d: cursor never ends", tc.mode)
+			}
+			f.Cursor = page.NextCursor
+		}
+		if len(seen) != 5 {
+			t.Fatalf("mode %d: walked %v", tc.mode, seen)
+		}
+	}
+
+	// Past the end the window count still reports the total.
+	page, err := r.ListPage(ctx, models.UserFilter{Offset: 10}, repo.CountWindow)
+	if err != nil || page.Total != 5 || len(page.Items) != 0 || page.Items == nil {
+		t.Fatalf("past the end: %+v, %v", page, err)
+	}
+
+	if _, err := r.ListPage(ctx, models.UserFilter{Cursor: "!!"}, repo.CountNone); !db.IsInvalidFilter(err) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+}
+
 // ─────────────────────────────────────────────────────────────────────────────
 // BatchInsert
 // ─────────────────────────────────────────────────────────────────────────────
@@ -0,0 +1,197 @@
+// Package saga sequences multi-step operations that cannot share a single
+// database transaction — e.g. a local write followed by a payment API call —
+// and undoes completed steps in reverse order when a later one fails.
+//
+// Progress is persisted in the saga_state table (see
+// migrations/000002_create_saga_state.up.sql) after every step, so a process
+// that crashes mid-saga can compensate the leftover work with Recover.
+//
+//	orders := saga.New(store, "place-order",
+//	    saga.Step[Order]{
+//	        Name:         "reserve-stock",
+//	        DoTx:         func(ctx context.Context, tx *db.Tx, o *Order) error { ... },
+//	        CompensateTx: func(ctx context.Context, tx *db.Tx, o *Order) error { ... },
+//	    },
+//	    saga.Step[Order]{
+//	        Name:       "charge-card",
+//	        Do:         func(ctx context.Context, o *Order) error { o.ChargeID, err = pay.Charge(...); return err },
+//	        Compensate: func(ctx context.Context, o *Order) error { return pay.Refund(o.ChargeID) },
+//	    },
+//	)
+//	err := orders.Run(ctx, "order-"+id, &order)
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Status is the persisted state of a saga instance.
+type Status string
+
+const (
+	StatusRunning      Status = "running"
+	StatusCompleted    Status = "completed"
+	StatusCompensating Status = "compensating"
+	StatusCompensated  Status = "compensated"
+	// StatusFailed means a compensation failed; manual intervention is needed.
+	StatusFailed Status = "failed"
+)
+
+// ErrCompensationFailed is returned when a step failed and at least one
+// compensation failed too, leaving the saga in StatusFailed.
+var ErrCompensationFailed = errors.New("saga: compensation failed")
+
+// Step is one unit of a saga. Each step sets either Do or DoTx:
+//
+//   - DoTx runs inside ExecTx together with the progress update, so database
+//     steps are recorded atomically with their effects.
+//   - Do runs outside any transaction (HTTP calls, queues, other databases).
+//     It must be idempotent: after a crash between Do and the progress update
+//     the step is considered not done, and only earlier steps are compensated.
+//
+// Compensate/CompensateTx undo a step that completed. Either may be nil for
+// steps with nothing to undo. Changes made to the payload are persisted with
+// the step, so Compensate sees what Do stored (e.g. an external id).
+type Step[T any] struct {
+	Name         string
+	Do           func(ctx context.Context, data *T) error
+	DoTx         func(ctx context.Context, tx *db.Tx, data *T) error
+	Compensate   func(ctx context.Context, data *T) error
+	CompensateTx func(ctx context.Context, tx *db.Tx, data *T) error
+}
+
+// Saga is a named, ordered list of steps operating on a payload of type T,
+// which must round-trip through encoding/json.
+type Saga[T any] struct {
+	name  string
+	steps []Step[T]
+	store *Store
+}
+
+// New returns a saga persisted in store. name identifies the saga kind in the
+// state table and must be stable across deployments.
+func New[T any](store *Store, name string, steps ...Step[T]) *Saga[T] {
+	return &Saga[T]{name: name, steps: steps, store: store}
+}
+
+// Run executes the steps in order under instance id, which must be unique in
+// the state table (use a business key to make retries safe). If a step fails,
+// the steps before it are compensated in reverse order and the step's error
+// is returned; if a compensation fails as well, the result also matches
+// ErrCompensationFailed.
+func (s *Saga[T]) Run(ctx context.Context, id string, data *T) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("saga %s: encode payload: %w", s.name, err)
+	}
+	if err := s.store.insert(ctx, id, s.name, raw); err != nil {
+		return fmt.Errorf("saga %s: %w", s.name, err)
+	}
+
+	for i, step := range s.steps {
+		if err := s.runStep(ctx, id, i, step, data); err != nil {
+			err = fmt.Errorf("saga %s: step %s: %w", s.name, step.Name, err)
+			return s.compensate(ctx, id, i, data, err)
+		}
+	}
+	return s.store.setStatus(ctx, id, StatusCompleted, "")
+}
+
+// Recover compensates instances of this saga left running or compensating by
+// a crashed process. Only instances untouched for at least minAge are
+// considered, so sagas still in progress elsewhere are left alone. Each
+// instance is claimed with a conditional UPDATE before it is compensated, so
+// processes recovering concurrently with the same non-zero minAge never
+// compensate the same instance twice; a compensation step taking longer than
+// minAge may still be taken over. It returns the number of instances
+// processed.
+func (s *Saga[T]) Recover(ctx context.Context, minAge time.Duration) (int, error) {
+	before := s.store.db.Now().UTC().Add(-minAge)
+	pending, err := s.store.pending(ctx, s.name, before)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		errs      []error
+		processed int
+	)
+	for _, p := range pending {
+		if claimed, err := s.store.claim(ctx, p, before); err != nil || !claimed {
+			if err != nil {
+				errs = append(errs, fmt.Errorf("saga %s/%s: claim: %w", s.name, p.id, err))
+			}
+			continue
+		}
+		processed++
+		var data T
+		if err := json.Unmarshal(p.data, &data); err != nil {
+			errs = append(errs, fmt.Errorf("saga %s/%s: decode payload: %w", s.name, p.id, err))
+			continue
+		}
+		cause := fmt.Errorf("saga %s: recovered after interruption at step %d", s.name, p.step)
+		if err := s.compensate(ctx, p.id, p.step, &data, cause); errors.Is(err, ErrCompensationFailed) {
+			errs = append(errs, err)
+		}
+	}
+	return processed, errors.Join(errs...)
+}
+
+func (s *Saga[T]) runStep(ctx context.Context, id string, i int, step Step[T], data *T) error {
+	if step.DoTx != nil {
+		return s.store.db.ExecTx(ctx, func(tx *db.Tx) error {
+			if err := step.DoTx(ctx, tx, data); err != nil {
+				return err
+			}
+			return s.store.advance(ctx, tx, id, i+1, StatusRunning, data)
+		})
+	}
+	if step.Do != nil {
+		if err := step.Do(ctx, data); err != nil {
+			return err
+		}
+	}
+	return s.store.advance(ctx, s.store.db, id, i+1, StatusRunning, data)
+}
+
+// compensate undoes steps [0, done) in reverse order and records the outcome.
+// It always returns a non-nil error wrapping cause.
+func (s *Saga[T]) compensate(ctx context.Context, id string, done int, data *T, cause error) error {
+	if err := s.store.setStatus(ctx, id, StatusCompensating, cause.Error()); err != nil {
+		return errors.Join(cause, err)
+	}
+	for i := done - 1; i >= 0; i-- {
+		step := s.steps[i]
+		var err error
+		switch {
+		case step.CompensateTx != nil:
+			err = s.store.db.ExecTx(ctx, func(tx *db.Tx) error {
+				if err := step.CompensateTx(ctx, tx, data); err != nil {
+					return err
+				}
+				return s.store.advance(ctx, tx, id, i, StatusCompensating, data)
+			})
+		default:
+			if step.Compensate != nil {
+				err = step.Compensate(ctx, data)
+			}
+			if err == nil {
+				err = s.store.advance(ctx, s.store.db, id, i, StatusCompensating, data)
+			}
+		}
+		if err != nil {
+			err = fmt.Errorf("%w: step %s: %w", ErrCompensationFailed, step.Name, err)
+			_ = s.store.setStatus(ctx, id, StatusFailed, err.Error())
+			return errors.Join(cause, err)
+		}
+	}
+	if err := s.store.setStatus(ctx, id, StatusCompensated, cause.Error()); err != nil {
+		return errors.Join(cause, err)
+	}
+	return cause
+}
@@ -0,0 +1,205 @@
+package saga_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/saga"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestStore(t *testing.T) (*saga.Store, *db.DB) {
+	t.Helper()
+	return openTestStore(t, db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+}
+
+func openTestStore(t *testing.T, cfg db.Config) (*saga.Store, *db.DB) {
+	t.Helper()
+	d, err := db.Open(cfg)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	_, err = d.Exec(context.Background(), `
+		CREATE TABLE saga_state (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			step       INTEGER NOT NULL,
+			status     TEXT NOT NULL,
+			data       TEXT NOT NULL,
+			error      TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME NOT NULL
+		);
+		CREATE TABLE ledger (entry TEXT NOT NULL);`)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return saga.NewStore(d), d
+}
+
+type order struct {
+	ChargeID string
+}
+
+func TestSaga_CompensatesInReverse(t *testing.T) {
+	store, d := newTestStore(t)
+	ctx := context.Background()
+	var log []string
+	boom := errors.New("shipping unavailable")
+
+	s := saga.New(store, "place-order",
+		saga.Step[order]{
+			Name: "reserve",
+			DoTx: func(ctx context.Context, tx *db.Tx, _ *order) error {
+				_, err := tx.Exec(ctx, `INSERT INTO ledger (entry) VALUES ('reserved')`)
+				return err
+			},
+			CompensateTx: func(ctx context.Context, tx *db.Tx, _ *order) error {
+				log = append(log, "unreserve")
+				_, err := tx.Exec(ctx, `DELETE FROM ledger WHERE entry = 'reserved'`)
+				return err
+			},
+		},
+		saga.Step[order]{
+			Name:       "charge",
+			Do:         func(_ context.Context, o *order) error { o.ChargeID = "ch_1"; return nil },
+			Compensate: func(_ context.Context, o *order) error { log = append(log, "refund "+o.ChargeID); return nil },
+		},
+		saga.Step[order]{
+			Name: "ship",
+			Do:   func(context.Context, *order) error { return boom },
+		},
+	)
+
+	err := s.Run(ctx, "order-1", &order{})
+	if !errors.Is(err, boom) || errors.Is(err, saga.ErrCompensationFailed) {
+		t.Fatalf("expected step error only, got %v", err)
+	}
+	if want := []string{"refund ch_1", "unreserve"}; !reflect.DeepEqual(log, want) {
+		t.Fatalf("compensation order = %v, want %v", log, want)
+	}
+	if st, _ := store.Status(ctx, "order-1"); st != saga.StatusCompensated {
+		t.Fatalf("status = %s, want compensated", st)
+	}
+	var n int
+	_ = d.QueryRow(ctx, `SELECT COUNT(*) FROM ledger`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("ledger not compensated: %d rows", n)
+	}
+
+	ok := saga.New(store, "noop", saga.Step[order]{Name: "one", Do: func(context.Context, *order) error { return nil }})
+	if err := ok.Run(ctx, "noop-1", &order{}); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if st, _ := store.Status(ctx, "noop-1"); st != saga.StatusCompleted {
+		t.Fatalf("status = %s, want completed", st)
+	}
+}
+
+func TestSaga_RecoverInterrupted(t *testing.T) {
+	store, _ := newTestStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	var refunded string
+
+	steps := []saga.Step[order]{
+		{
+			Name:       "charge",
+			Do:         func(_ context.Context, o *order) error { o.ChargeID = "ch_9"; return nil },
+			Compensate: func(_ context.Context, o *order) error { refunded = o.ChargeID; return nil },
+		},
+		{
+			// Simulates the process dying: the context is gone, so neither the
+			// step nor the compensation bookkeeping can run.
+			Name: "crash",
+			Do:   func(context.Context, *order) error { cancel(); return context.Canceled },
+		},
+	}
+	_ = saga.New(store, "checkout", steps...).Run(ctx, "co-1", &order{})
+	if st, _ := store.Status(context.Background(), "co-1"); st != saga.StatusRunning {
+		t.Fatalf("status after crash = %s, want running", st)
+	}
+
+	n, err := saga.New(store, "checkout", steps...).Recover(context.Background(), 0)
+	if err != nil || n != 1 {
+		t.Fatalf("recover: n=%d err=%v", n, err)
+	}
+	if refunded != "ch_9" {
+		t.Fatalf("expected refund of persisted charge id, got %q", refunded)
+	}
+	if st, _ := store.Status(context.Background(), "co-1"); st != saga.StatusCompensated {
+		t.Fatalf("status = %s, want compensated", st)
+	}
+	if n, _ := saga.New(store, "checkout", steps...).Recover(context.Background(), time.Hour); n != 0 {
+		t.Fatalf("second recover processed %d instances", n)
+	}
+}
+
+// barrier holds the first status updates, which the recoveries make once
+// they have read the pending instances, until all of them have read, so
+// every recovery sees the same instances.
+type barrier struct {
+	waiting *atomic.Int32
+	wg      *sync.WaitGroup
+}
+
+func (b barrier) BeforeQuery(_ context.Context, query string, _ []any) {
+	if strings.Contains(query, "UPDATE saga_state SET status") && b.waiting.Add(-1) >= 0 {
+		b.wg.Done()
+		b.wg.Wait()
+	}
+}
+
+func (barrier) AfterQuery(context.Context, string, []any, time.Duration, error) {}
+
+func TestSaga_RecoverClaimsOnce(t *testing.T) {
+	const recoverers = 3
+	var (
+		waiting atomic.Int32
+		read    sync.WaitGroup
+	)
+	store, _ := openTestStore(t, db.Config{
+		DSN: "file:" + filepath.Join(t.TempDir(), "saga.db") + "?_busy_timeout=5000", DriverName: "sqlite3",
+		Hooks: []db.Hook{barrier{&waiting, &read}},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	var refunds atomic.Int32
+	steps := []saga.Step[order]{
+		{
+			Name:       "charge",
+			Do:         func(context.Context, *order) error { return nil },
+			Compensate: func(context.Context, *order) error { refunds.Add(1); return nil },
+		},
+		{Name: "crash", Do: func(context.Context, *order) error { cancel(); return context.Canceled }},
+	}
+	s := saga.New(store, "checkout", steps...)
+	_ = s.Run(ctx, "co-1", &order{})
+	time.Sleep(5 * time.Millisecond)
+
+	var (
+		wg        sync.WaitGroup
+		processed atomic.Int32
+	)
+	read.Add(recoverers)
+	waiting.Store(recoverers)
+	for range recoverers {
+		wg.Go(func() {
+			n, err := s.Recover(context.Background(), time.Millisecond)
+			if err != nil {
+				t.Error(err)
+			}
+			processed.Add(int32(n))
+		})
+	}
+	wg.Wait()
+	if processed.Load() != 1 || refunds.Load() != 1 {
+		t.Fatalf("concurrent recovery: %d processed, %d refunds; want 1 each", processed.Load(), refunds.Load())
+	}
+}
@@ -0,0 +1,97 @@
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Store persists saga progress in the saga_state table.
+type Store struct {
+	db *db.DB
+
+	sqlInsert, sqlAdvance, sqlStatus, sqlPending, sqlClaim string
+}
+
+// NewStore returns a Store using d's saga_state table. Statements use d's
+// placeholder style, so any supported dialect works.
+func NewStore(d *db.DB) *Store {
+	ph := d.Dialect().Placeholder()
+	return &Store{
+		db: d,
+		sqlInsert: fmt.Sprintf(`
+			INSERT INTO saga_state (id, name, step, status, data, error, updated_at)
+			VALUES (%s, %s, 0, '%s', %s, '', %s)`,
+			ph(1), ph(2), StatusRunning, ph(3), ph(4)),
+		sqlAdvance: fmt.Sprintf(`
+			UPDATE saga_state SET step = %s, status = %s, data = %s, updated_at = %s
+			WHERE  id = %s`,
+			ph(1), ph(2), ph(3), ph(4), ph(5)),
+		sqlStatus: fmt.Sprintf(`
+			UPDATE saga_state SET status = %s, error = %s, updated_at = %s
+			WHERE  id = %s`,
+			ph(1), ph(2), ph(3), ph(4)),
+		sqlPending: fmt.Sprintf(`
+			SELECT id, step, data
+			FROM   saga_state
+			WHERE  name = %s AND status IN ('%s', '%s') AND updated_at <= %s
+			ORDER  BY updated_at`,
+			ph(1), StatusRunning, StatusCompensating, ph(2)),
+		sqlClaim: fmt.Sprintf(`
+			UPDATE saga_state SET status = '%s', updated_at = %s
+			WHERE  id = %s AND step = %s AND status IN ('%s', '%s') AND updated_at <= %s`,
+			StatusCompensating, ph(1), ph(2), ph(3), StatusRunning, StatusCompensating, ph(4)),
+	}
+}
+
+type pendingSaga struct {
+	id   string
+	step int
+	data []byte
+}
+
+func (s *Store) insert(ctx context.Context, id, name string, data []byte) error {
+	_, err := s.db.Exec(ctx, s.sqlInsert, id, name, string(data), s.db.Now().UTC())
+	return err
+}
+
+func (s *Store) advance(ctx context.Context, q db.Querier, id string, step int, status Status, data any) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode payload: %w", err)
+	}
+	_, err = q.Exec(ctx, s.sqlAdvance, step, string(status), string(raw), db.NowFrom(q).UTC(), id)
+	return err
+}
+
+func (s *Store) setStatus(ctx context.Context, id string, status Status, msg string) error {
+	_, err := s.db.Exec(ctx, s.sqlStatus, string(status), msg, s.db.Now().UTC(), id)
+	return err
+}
+
+func (s *Store) pending(ctx context.Context, name string, before time.Time) ([]pendingSaga, error) {
+	return db.Select(ctx, s.db, func(r db.RowScanner) (pendingSaga, error) {
+		var p pendingSaga
+		err := r.Scan(&p.id, &p.step, &p.data)
+		return p, err
+	}, s.sqlPending, name, before)
+}
+
+// claim marks a pending instance as being compensated by this process. It
+// fails when another process advanced or claimed the instance since it was
+// read, since either moves updated_at past before.
+func (s *Store) claim(ctx context.Context, p pendingSaga, before time.Time) (bool, error) {
+	n, err := s.db.ExecAffected(ctx, s.sqlClaim, s.db.Now().UTC(), p.id, p.step, before)
+	return n == 1, err
+}
+
+// Status returns the persisted status of saga instance id, or db.ErrNotFound.
+func (s *Store) Status(ctx context.Context, id string) (Status, error) {
+	var st string
+	err := s.db.QueryRow(ctx, fmt.Sprintf(`SELECT status FROM saga_state WHERE id = %s`,
+		s.db.Dialect().Placeholder()(1)), id).Scan(&st)
+	return Status(st), err
+}
@@ -0,0 +1,370 @@
+// Package scheduler runs recurring jobs across a fleet of instances, making
+// sure each activation executes on exactly one of them.
+//
+// Job state lives in the scheduled_jobs table (see
+// migrations/000005_create_scheduled_jobs.up.sql). An instance claims a due
+// job by taking a time-limited lease on its row with a conditional UPDATE,
+// renews the lease while the job runs, and releases it together with the next
+// run time. A crashed instance's lease simply expires and another instance
+// picks the job up.
+//
+//	s := scheduler.New(database, scheduler.Options{})
+//	s.MustRegister("purge-sessions", "*/10 * * * *", purgeSessions)
+//	s.MustRegister("nightly-report", "@daily", sendReport)
+//	go s.Run(ctx)
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Skryldev/sql-toolkit/db"
+)
+
+// Job is the work run at each activation. ctx is cancelled when the
+// scheduler stops or the lease is lost to another instance.
+type Job func(ctx context.Context) error
+
+// ErrLeaseLost is the cancellation cause seen by a job whose lease could not
+// be renewed.
+var ErrLeaseLost = errors.New("scheduler: lease lost")
+
+// Options configures New. Every field is optional.
+type Options struct {
+	// Instance identifies this process in locked_by. Defaults to
+	// "hostname:pid".
+	Instance string
+	// PollInterval is how often due jobs are looked for. Defaults to 10s.
+	PollInterval time.Duration
+	// Lease is how long a claim stays valid without renewal; it bounds how
+	// long a job is stuck after its instance dies. Defaults to 1m.
+	Lease time.Duration
+	// Location is the time zone cron expressions are evaluated in.
+	// Defaults to UTC.
+	Location *time.Location
+	// Logger defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Scheduler holds the registered jobs of one instance.
+type Scheduler struct {
+	d    *db.DB
+	opts Options
+
+	mu   sync.Mutex
+	jobs map[string]*entry
+
+	sqlEnsure, sqlReschedule, sqlClaim, sqlRenew, sqlRelease, sqlList string
+}
+
+type entry struct {
+	spec     string
+	schedule Schedule
+	job      Job
+}
+
+// New returns a Scheduler storing job state in d's scheduled_jobs table.
+func New(d *db.DB, opts Options) *Scheduler {
+	if opts.Instance == "" {
+		host, _ := os.Hostname()
+		opts.Instance = fmt.Sprintf("%s:%d", host, os.Getpid())
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 10 * time.Second
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = time.Minute
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	dialect := d.Dialect()
+	ph := dialect.Placeholder()
+	ensure, err := db.InsertSQL(dialect, "scheduled_jobs", []string{"name", "schedule", "next_run"}, db.ConflictSkip, "name")
+	if err != nil {
+		panic(err) // static arguments; cannot fail
+	}
+	return &Scheduler{
+		d:         d,
+		opts:      opts,
+		jobs:      make(map[string]*entry),
+		sqlEnsure: ensure,
+		sqlReschedule: fmt.Sprintf(`
+			UPDATE scheduled_jobs SET schedule = %s, next_run = %s
+			WHERE  name = %s AND schedule <> %s`,
+			ph(1), ph(2), ph(3), ph(4)),
+		sqlClaim: fmt.Sprintf(`
+			UPDATE scheduled_jobs SET locked_by = %s, locked_until = %s
+			WHERE  name = %s AND next_run <= %s AND locked_until <= %s`,
+			ph(1), ph(2), ph(3), ph(4), ph(5)),
+		sqlRenew: fmt.Sprintf(`
+			UPDATE scheduled_jobs SET locked_until = %s
+			WHERE  name = %s AND locked_by = %s`,
+			ph(1), ph(2), ph(3)),
+		sqlRelease: fmt.Sprintf(`
+			UPDATE scheduled_jobs
+			SET    last_run = %s, next_run = %s, last_error = %s, locked_by = '', locked_until = 0
+			WHERE  name = %s AND locked_by = %s`,
+			ph(1), ph(2), ph(3), ph(4), ph(5)),
+		sqlList: `
+			SELECT name, schedule, next_run, last_run, last_error, locked_by, locked_until
+			FROM   scheduled_jobs
+			ORDER  BY name`,
+	}
+}
+
+// Register adds a job running on spec (see Parse). Names must be unique and
+// stable across deployments: they key the job's row.
+func (s *Scheduler) Register(name, spec string, job Job) error {
+	sched, err := Parse(spec, s.opts.Location)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, dup := s.jobs[name]; dup {
+		return fmt.Errorf("scheduler: job %q already registered", name)
+	}
+	s.jobs[name] = &entry{spec: spec, schedule: sched, job: job}
+	return nil
+}
+
+// MustRegister is like Register but panics on error.
+func (s *Scheduler) MustRegister(name, spec string, job Job) {
+	if err := s.Register(name, spec, job); err != nil {
+		panic(err)
+	}
+}
+
+// ── Running ──────────────────────────────────────────────────────────────────
+
+// Run registers the job rows and executes due jobs every PollInterval until
+// ctx is cancelled. Jobs run concurrently; Run waits for running jobs to
+// return before it does.
+func (s *Scheduler) Run(ctx context.Context) error {
+	if err := s.sync(ctx); err != nil {
+		return err
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	tick := time.NewTicker(s.opts.PollInterval)
+	defer tick.Stop()
+	for {
+		for _, name := range s.names() {
+			claimed, err := s.claim(ctx, name)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.opts.Logger.WarnContext(ctx, "scheduler: claim", slog.String("job", name), slog.Any("error", err))
+				}
+				continue
+			}
+			if claimed {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s.execute(ctx, name)
+				}()
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-tick.C:
+		}
+	}
+}
+
+// RunDue executes every job that is due right now on the calling goroutine
+// and returns how many ran. It is meant for tests and for driving the
+// scheduler from an external loop; Run is the usual entry point.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	if err := s.sync(ctx); err != nil {
+		return 0, err
+	}
+	ran := 0
+	for _, name := range s.names() {
+		claimed, err := s.claim(ctx, name)
+		if err != nil {
+			return ran, err
+		}
+		if claimed {
+			s.execute(ctx, name)
+			ran++
+		}
+	}
+	return ran, nil
+}
+
+// sync creates rows for new jobs and moves next_run when a job's spec changed.
+func (s *Scheduler) sync(ctx context.Context) error {
+	now := s.d.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, e := range s.jobs {
+		next := nextRun(e.schedule, now)
+		if _, err := s.d.Exec(ctx, s.sqlEnsure, name, e.spec, next); err != nil {
+			return fmt.Errorf("scheduler: register %q: %w", name, err)
+		}
+		if _, err := s.d.Exec(ctx, s.sqlReschedule, e.spec, next, name, e.spec); err != nil {
+			return fmt.Errorf("scheduler: reschedule %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// claim takes the lease on name if the job is due and nobody holds it.
+func (s *Scheduler) claim(ctx context.Context, name string) (bool, error) {
+	now := s.d.Now()
+	n, err := s.d.ExecAffected(ctx, s.sqlClaim,
+		s.opts.Instance, now.Add(s.opts.Lease).UnixMicro(), name, now.UnixMicro(), now.UnixMicro())
+	return n == 1, err
+}
+
+// execute runs a claimed job, renewing the lease until it returns, then
+// records the outcome and releases the lease.
+func (s *Scheduler) execute(ctx context.Context, name string) {
+	s.mu.Lock()
+	e := s.jobs[name]
+	s.mu.Unlock()
+
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	done := make(chan struct{})
+	go s.renew(jobCtx, name, cancel, done)
+
+	started := s.d.Now()
+	err := safeRun(jobCtx, e.job)
+	close(done)
+
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+		if len(msg) > 1024 { // the column's size; cut on a rune boundary
+			n := 1024
+			for n > 0 && !utf8.RuneStart(msg[n]) {
+				n--
+			}
+			msg = msg[:n]
+		}
+		s.opts.Logger.ErrorContext(ctx, "scheduler: job failed",
+			slog.String("job", name), slog.Duration("took", s.d.Now().Sub(started)), slog.Any("error", err))
+	}
+	next := nextRun(e.schedule, s.d.Now())
+	// Release even when ctx is cancelled, so the next instance need not
+	// wait for the lease to expire.
+	relCtx, relCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer relCancel()
+	if _, err := s.d.Exec(relCtx, s.sqlRelease,
+		started.UnixMicro(), next, msg, name, s.opts.Instance); err != nil {
+		s.opts.Logger.WarnContext(ctx, "scheduler: release", slog.String("job", name), slog.Any("error", err))
+	}
+}
+
+// never is the next_run of a job whose schedule has no further activation;
+// no poll finds it due.
+const never = math.MaxInt64
+
+// nextRun returns the next_run of a job scheduled by sched after t.
+func nextRun(sched Schedule, t time.Time) int64 {
+	next := sched.Next(t)
+	if next.IsZero() {
+		return never
+	}
+	return next.UnixMicro()
+}
+
+func (s *Scheduler) renew(ctx context.Context, name string, cancel context.CancelCauseFunc, done <-chan struct{}) {
+	tick := time.NewTicker(s.opts.Lease / 3)
+	defer tick.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-tick.C:
+			until := s.d.Now().Add(s.opts.Lease).UnixMicro()
+			n, err := s.d.ExecAffected(ctx, s.sqlRenew, until, name, s.opts.Instance)
+			if err == nil && n == 0 {
+				cancel(ErrLeaseLost)
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				s.opts.Logger.WarnContext(ctx, "scheduler: renew lease", slog.String("job", name), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+func safeRun(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return job(ctx)
+}
+
+// ── Inspection ───────────────────────────────────────────────────────────────
+
+// JobState is the persisted state of one job.
+type JobState struct {
+	Name      string
+	Schedule  string
+	NextRun   time.Time // zero if the schedule never fires again
+	LastRun   time.Time // zero if never run
+	LastError string
+	// LockedBy is the instance currently running the job, if any.
+	LockedBy    string
+	LockedUntil time.Time
+}
+
+// Jobs returns the state of every job in the table, including jobs
+// registered only by other instances.
+func (s *Scheduler) Jobs(ctx context.Context) ([]JobState, error) {
+	return db.Select(ctx, s.d, func(r db.RowScanner) (JobState, error) {
+		var (
+			j                      JobState
+			next, last, lockedTill int64
+		)
+		if err := r.Scan(&j.Name, &j.Schedule, &next, &last, &j.LastError, &j.LockedBy, &lockedTill); err != nil {
+			return j, err
+		}
+		j.NextRun = fromMicro(next)
+		j.LastRun = fromMicro(last)
+		j.LockedUntil = fromMicro(lockedTill)
+		return j, nil
+	}, s.sqlList)
+}
+
+func fromMicro(us int64) time.Time {
+	if us == 0 || us == never {
+		return time.Time{}
+	}
+	return time.UnixMicro(us)
+}
@@ -0,0 +1,117 @@
+package scheduler_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/db/dbtest"
+	"github.com/Skryldev/sql-toolkit/scheduler"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	return dbtest.OpenSQLite(t, "000005_create_scheduled_jobs")
+}
+
+func TestParse(t *testing.T) {
+	from := time.Date(2024, 3, 15, 10, 7, 30, 0, time.UTC) // a Friday
+	cases := []struct{ spec, want string }{
+		{"*/15 * * * *", "2024-03-15T10:15:00Z"},
+		{"0 9-17 * * 1-5", "2024-03-15T11:00:00Z"},
+		{"30 8 * * 1", "2024-03-18T08:30:00Z"},
+		{"0 0 29 2 *", "2028-02-29T00:00:00Z"},
+		{"@daily", "2024-03-16T00:00:00Z"},
+		{"@monthly", "2024-04-01T00:00:00Z"},
+		{"@every 90s", "2024-03-15T10:09:00Z"},
+	}
+	for _, c := range cases {
+		s, err := scheduler.Parse(c.spec, nil)
+		if err != nil {
+			t.Fatalf("%q: %v", c.spec, err)
+		}
+		if got := s.Next(from).Format(time.RFC3339); got != c.want {
+			t.Errorf("%q: next = %s, want %s", c.spec, got, c.want)
+		}
+	}
+	for _, bad := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "@every -1s", "a * * * *", "0 0 30 2 *", "0 0 31 4,6 *"} {
+		if _, err := scheduler.Parse(bad, nil); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestScheduler_OneInstancePerRun(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+
+	var runs atomic.Int32
+	release := make(chan struct{})
+	job := func(context.Context) error {
+		runs.Add(1)
+		<-release
+		return errors.New("boom")
+	}
+	a := scheduler.New(d, scheduler.Options{Instance: "a"})
+	b := scheduler.New(d, scheduler.Options{Instance: "b"})
+	a.MustRegister("report", "@every 1ms", job)
+	b.MustRegister("report", "@every 1ms", job)
+	if _, err := a.RunDue(ctx); err != nil { // creates the row; not yet due
+		t.Fatalf("a: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	done := make(chan int)
+	go func() {
+		n, _ := a.RunDue(ctx)
+		done <- n
+	}()
+	for runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if n, err := b.RunDue(ctx); err != nil || n != 0 {
+		t.Fatalf("b ran %d jobs (err %v) while a held the lease", n, err)
+	}
+	close(release)
+	if n := <-done; n != 1 {
+		t.Fatalf("a ran %d jobs", n)
+	}
+
+	jobs, err := b.Jobs(ctx)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("jobs = %v, %v", jobs, err)
+	}
+	j := jobs[0]
+	if j.LastRun.IsZero() || j.LastError != "boom" || j.LockedBy != "" || !j.NextRun.After(j.LastRun) {
+		t.Fatalf("unexpected state %+v", j)
+	}
+}
+
+func TestScheduler_LastErrorTruncatedOnRune(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	s := scheduler.New(d, scheduler.Options{Instance: "a"})
+	s.MustRegister("long", "@every 1ms", func(context.Context) error {
+		return errors.New(strings.Repeat("a", 1023) + "é and more")
+	})
+	if _, err := s.RunDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if n, err := s.RunDue(ctx); err != nil || n != 1 {
+		t.Fatalf("ran %d jobs (err %v)", n, err)
+	}
+	jobs, err := s.Jobs(ctx)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("jobs = %v, %v", jobs, err)
+	}
+	if msg := jobs[0].LastError; len(msg) != 1023 || !utf8.ValidString(msg) {
+		t.Fatalf("last error is %d bytes, valid UTF-8 %v; want the 1023 before the split rune", len(msg), utf8.ValidString(msg))
+	}
+}
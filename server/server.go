@@ -0,0 +1,378 @@
+// Package server exposes named queries of a queries.Registry over HTTP: a
+// controlled "query as API" layer for internal tools that need data but
+// should not hold database credentials or send SQL of their own.
+//
+// Only the queries listed in Options.Expose are callable, and callers supply
+// arguments, never SQL. Statements run through the *db.DB, so its hooks,
+// timeouts, read-only mode and error mapping apply as to any other caller.
+// Queries name their arguments and may carry their own timeout with tags:
+//
+//	-- name: OrdersByCustomer :many
+//	-- params: customer_id, status
+//	-- timeout: 2s
+//	SELECT id, total, created_at FROM orders WHERE customer_id = $1 AND status = $2;
+//
+//	srv, err := server.New(database, reg, server.Options{
+//	    Expose:       []string{"OrdersByCustomer"},
+//	    Authenticate: verifyBearerToken,
+//	})
+//	mux.Handle("/data/", http.StripPrefix("/data", srv.Handler()))
+//
+// Call and CallNamed are the transport-neutral core of the handler; a gRPC
+// service implementation calls them the same way.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/queries"
+)
+
+var (
+	// ErrUnknownQuery is returned for a query that is not exposed.
+	ErrUnknownQuery = errors.New("server: query not exposed")
+	// ErrBadArguments is returned when a call's arguments do not match the
+	// query's parameters.
+	ErrBadArguments = errors.New("server: bad arguments")
+	// ErrForbidden wraps the error of Options.Authorize.
+	ErrForbidden = errors.New("server: forbidden")
+)
+
+// Options configures New.
+type Options struct {
+	// Expose lists the registry names callable through the server; it is
+	// the allowlist, and every name must be registered.
+	Expose []string
+	// AllowWrites permits exposing :exec queries and other statements that
+	// write. Without it New rejects them.
+	AllowWrites bool
+	// Authenticate runs before every HTTP request and returns the context
+	// the call runs with, e.g. carrying the caller's identity. An error
+	// answers 401. Nil lets every request through; mount the handler on an
+	// internal listener then.
+	Authenticate func(r *http.Request) (context.Context, error)
+	// Authorize, if set, decides whether the caller in ctx may run q. An
+	// error answers 403.
+	Authorize func(ctx context.Context, q queries.Query) error
+	// Timeout bounds every call; a "timeout" tag on the query overrides it.
+	// Zero leaves the pool's timeouts in charge.
+	Timeout time.Duration
+	// MaxRows caps the rows a call returns; a longer result is cut and
+	// marked Truncated. Defaults to 1000.
+	MaxRows int
+	// Logger records failures answered with 500. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// Server runs exposed queries.
+type Server struct {
+	d         *db.DB
+	opts      Options
+	endpoints map[string]endpoint
+}
+
+type endpoint struct {
+	query   queries.Query
+	params  []string // names of $1, $2, ...; nil when not declared
+	timeout time.Duration
+	write   bool
+}
+
+// New returns a Server for the queries of reg listed in opts.Expose, as they
+// are registered now: later versions added to reg are not picked up.
+func New(d *db.DB, reg *queries.Registry, opts Options) (*Server, error) {
+	if opts.MaxRows <= 0 {
+		opts.MaxRows = 1000
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	s := &Server{d: d, opts: opts, endpoints: make(map[string]endpoint, len(opts.Expose))}
+	for _, name := range opts.Expose {
+		q, ok := reg.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("server: %q not registered", name)
+		}
+		e := endpoint{query: q, timeout: opts.Timeout}
+		e.write = q.Kind == queries.KindExec || db.IsWriteStatement(q.SQL)
+		if e.write && !opts.AllowWrites {
+			return nil, fmt.Errorf("server: %s writes; set AllowWrites to expose it", q.Label())
+		}
+		if p := q.Tags["params"]; p != "" {
+			for _, name := range strings.Split(p, ",") {
+				e.params = append(e.params, strings.TrimSpace(name))
+			}
+		}
+		if t := q.Tags["timeout"]; t != "" {
+			timeout, err := time.ParseDuration(t)
+			if err != nil {
+				return nil, fmt.Errorf("server: %s: timeout tag: %w", q.Label(), err)
+			}
+			e.timeout = timeout
+		}
+		s.endpoints[name] = e
+	}
+	return s, nil
+}
+
+// Result is the outcome of a call: the rows of a query, or the rows
+// affected by a statement that writes.
+type Result struct {
+	Columns      []string `json:"columns,omitempty"`
+	Rows         [][]any  `json:"rows,omitempty"`
+	Truncated    bool     `json:"truncated,omitempty"`
+	RowsAffected *int64   `json:"rows_affected,omitempty"`
+}
+
+// Call runs the exposed query name with positional arguments. A :one query
+// without a row fails with db.ErrNotFound; a result longer than MaxRows is
+// cut and marked Truncated.
+func (s *Server) Call(ctx context.Context, name string, args []any) (*Result, error) {
+	e, ok := s.endpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownQuery, name)
+	}
+	if e.params != nil && len(args) != len(e.params) {
+		return nil, fmt.Errorf("%w: %s takes %d arguments (%s), got %d",
+			ErrBadArguments, name, len(e.params), strings.Join(e.params, ", "), len(args))
+	}
+	if s.opts.Authorize != nil {
+		if err := s.opts.Authorize(ctx, e.query); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrForbidden, err)
+		}
+	}
+	if e.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.timeout)
+		defer cancel()
+	}
+
+	if e.write && e.query.Kind != queries.KindMany && e.query.Kind != queries.KindOne {
+		res, err := s.d.Exec(ctx, e.query.SQL, args...)
+		if err != nil {
+			return nil, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		return &Result{RowsAffected: &n}, nil
+	}
+
+	out := &Result{Rows: [][]any{}}
+	err := db.StreamValues(db.WithMaxRows(ctx, s.opts.MaxRows), s.d, e.query.SQL, args,
+		func(cols []db.ColumnInfo) error {
+			out.Columns = make([]string, len(cols))
+			for i, c := range cols {
+				out.Columns[i] = c.Name
+			}
+			return nil
+		}, func(vals []any) error {
+			out.Rows = append(out.Rows, append([]any(nil), vals...))
+			return nil
+		})
+	if db.IsTruncated(err) {
+		out.Truncated, err = true, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.query.Kind == queries.KindOne && len(out.Rows) == 0 {
+		return nil, db.ErrNotFound
+	}
+	return out, nil
+}
+
+// CallNamed is Call with arguments by the names of the query's "params"
+// tag. Every parameter must be given, and no other.
+func (s *Server) CallNamed(ctx context.Context, name string, params map[string]any) (*Result, error) {
+	e, ok := s.endpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownQuery, name)
+	}
+	if e.params == nil && len(params) > 0 {
+		return nil, fmt.Errorf("%w: %s declares no params tag; pass positional args", ErrBadArguments, name)
+	}
+	args := make([]any, len(e.params))
+	for i, p := range e.params {
+		v, ok := params[p]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s: missing parameter %q", ErrBadArguments, name, p)
+		}
+		args[i] = v
+	}
+	if len(params) > len(e.params) {
+		for p := range params {
+			if !slices.Contains(e.params, p) {
+				return nil, fmt.Errorf("%w: %s: unknown parameter %q", ErrBadArguments, name, p)
+			}
+		}
+	}
+	return s.Call(ctx, name, args)
+}
+
+// ── HTTP ─────────────────────────────────────────────────────────────────────
+
+// Handler returns an http.Handler serving:
+//
+//	GET  /               the exposed queries with their kind and parameters
+//	GET  /queries/{name} run a read-only query; URL query values are its params
+//	POST /queries/{name} run a query; the body is {"params": {...}} or {"args": [...]}
+//
+// Results are JSON: {"columns": [...], "rows": [[...]], "truncated": bool} or
+// {"rows_affected": n}. Errors are {"error": "..."} with a status derived
+// from the db sentinels: 404 for ErrNotFound and unexposed queries, 400 for
+// bad arguments, 409 for constraint violations, 504 for timeouts, 503 in
+// read-only mode. Paths are relative; use http.StripPrefix to mount it.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		type view struct {
+			Name   string   `json:"name"`
+			Kind   string   `json:"kind,omitempty"`
+			Params []string `json:"params,omitempty"`
+			Write  bool     `json:"write,omitempty"`
+		}
+		out := []view{}
+		for _, name := range s.opts.Expose {
+			e := s.endpoints[name]
+			out = append(out, view{Name: name, Kind: strings.TrimPrefix(string(e.query.Kind), ":"), Params: e.params, Write: e.write})
+		}
+		writeJSON(w, http.StatusOK, out)
+	}))
+	mux.HandleFunc("GET /queries/{name}", s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if e, ok := s.endpoints[name]; ok && e.write {
+			writeError(w, http.StatusMethodNotAllowed, "server: "+name+" writes; use POST")
+			return
+		}
+		params := make(map[string]any, len(r.URL.Query()))
+		for k, v := range r.URL.Query() {
+			params[k] = v[len(v)-1]
+		}
+		res, err := s.CallNamed(r.Context(), name, params)
+		s.respond(w, res, err)
+	}))
+	mux.HandleFunc("POST /queries/{name}", s.authenticated(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Params map[string]any `json:"params"`
+			Args   []any          `json:"args"`
+		}
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+		dec.UseNumber()
+		if err := dec.Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, "server: invalid body: "+err.Error())
+			return
+		}
+		var (
+			res *Result
+			err error
+		)
+		if body.Args != nil {
+			if body.Params != nil {
+				writeError(w, http.StatusBadRequest, "server: give params or args, not both")
+				return
+			}
+			res, err = s.Call(r.Context(), r.PathValue("name"), jsonArgs(body.Args))
+		} else {
+			for k, v := range body.Params {
+				body.Params[k] = jsonArg(v)
+			}
+			res, err = s.CallNamed(r.Context(), r.PathValue("name"), body.Params)
+		}
+		s.respond(w, res, err)
+	}))
+	return mux
+}
+
+func (s *Server) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	if s.opts.Authenticate == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := s.opts.Authenticate(r)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		h(w, r.WithContext(ctx))
+	}
+}
+
+func (s *Server) respond(w http.ResponseWriter, res *Result, err error) {
+	if err == nil {
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	status := statusOf(err)
+	if status == http.StatusInternalServerError {
+		s.opts.Logger.Error("server: query failed", "error", err)
+		writeError(w, status, "internal error")
+		return
+	}
+	writeError(w, status, err.Error())
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, ErrUnknownQuery), db.IsNotFound(err):
+		return http.StatusNotFound
+	case errors.Is(err, ErrBadArguments), db.IsInvalidFilter(err), db.IsInvalidData(err):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case db.IsDuplicateKey(err), db.IsForeignKeyViolation(err), db.IsCheckViolation(err), db.IsNotNullViolation(err):
+		return http.StatusConflict
+	case db.IsTimeout(err):
+		return http.StatusGatewayTimeout
+	case db.IsReadOnly(err), db.IsResourceExhausted(err):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// jsonArgs converts decoded JSON values to query arguments.
+func jsonArgs(vals []any) []any {
+	for i, v := range vals {
+		vals[i] = jsonArg(v)
+	}
+	return vals
+}
+
+// jsonArg turns JSON numbers into int64 when they are integers and float64
+// otherwise; objects and arrays are passed as their JSON text.
+func jsonArg(v any) any {
+	switch x := v.(type) {
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n
+		}
+		f, _ := x.Float64()
+		return f
+	case map[string]any, []any:
+		b, _ := json.Marshal(x)
+		return string(b)
+	}
+	return v
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
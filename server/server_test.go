@@ -0,0 +1,161 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/queries"
+	"github.com/Skryldev/sql-toolkit/server"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const usersSQL = `
+-- name: UsersByDomain :many
+-- params: domain
+SELECT id, name FROM users WHERE email LIKE '%@' || $1 ORDER BY id;
+
+-- name: UserByID :one
+-- params: id
+-- timeout: 1s
+SELECT id, name FROM users WHERE id = $1;
+
+-- name: AllUsers
+SELECT id FROM users ORDER BY id;
+
+-- name: RenameUser :exec
+-- params: name, id
+UPDATE users SET name = $1 WHERE id = $2;
+`
+
+func newTestServer(t *testing.T, opts server.Options) *server.Server {
+	t.Helper()
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3", MaxOpenConns: 1})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	ctx := context.Background()
+	for _, stmt := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL UNIQUE)`,
+		`INSERT INTO users (name, email) VALUES ('Ann', 'ann@a.example'), ('Bo', 'bo@b.example'), ('Cy', 'cy@b.example')`,
+	} {
+		if _, err := d.Exec(ctx, stmt); err != nil {
+			t.Fatalf("schema: %v", err)
+		}
+	}
+	reg := queries.NewRegistry()
+	if err := reg.Load(strings.NewReader(usersSQL), "users.sql"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	srv, err := server.New(d, reg, opts)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	return srv
+}
+
+func do(t *testing.T, h http.Handler, method, target, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer good")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var out map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+type userKey struct{}
+
+func TestHandler(t *testing.T) {
+	srv := newTestServer(t, server.Options{
+		Expose:      []string{"UsersByDomain", "UserByID", "AllUsers", "RenameUser"},
+		AllowWrites: true,
+		MaxRows:     2,
+		Authenticate: func(r *http.Request) (context.Context, error) {
+			if r.Header.Get("Authorization") != "Bearer good" {
+				return nil, errors.New("bad token")
+			}
+			return context.WithValue(r.Context(), userKey{}, "tester"), nil
+		},
+		Authorize: func(ctx context.Context, q queries.Query) error {
+			if ctx.Value(userKey{}) != "tester" {
+				return errors.New("no identity")
+			}
+			if q.Name == "AllUsers" {
+				return errors.New("admins only")
+			}
+			return nil
+		},
+	})
+	h := srv.Handler()
+
+	code, out := do(t, h, "GET", "/queries/UsersByDomain?domain=b.example", "")
+	if code != http.StatusOK || len(out["rows"].([]any)) != 2 || out["columns"].([]any)[1] != "name" {
+		t.Fatalf("GET UsersByDomain = %d %v", code, out)
+	}
+	if code, out = do(t, h, "POST", "/queries/UserByID", `{"args": [2]}`); code != http.StatusOK ||
+		out["rows"].([]any)[0].([]any)[1] != "Bo" {
+		t.Fatalf("POST UserByID args = %d %v", code, out)
+	}
+	if code, _ = do(t, h, "POST", "/queries/UserByID", `{"params": {"id": 99}}`); code != http.StatusNotFound {
+		t.Fatalf(":one without a row = %d, want 404", code)
+	}
+	if code, _ = do(t, h, "POST", "/queries/UserByID", `{"params": {"id": 1, "extra": 2}}`); code != http.StatusBadRequest {
+		t.Fatalf("unknown parameter = %d, want 400", code)
+	}
+	if code, _ = do(t, h, "GET", "/queries/DropUsers", ""); code != http.StatusNotFound {
+		t.Fatalf("unexposed query = %d, want 404", code)
+	}
+	if code, _ = do(t, h, "GET", "/queries/AllUsers", ""); code != http.StatusForbidden {
+		t.Fatalf("unauthorized query = %d, want 403", code)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("missing token = %d, want 401", rec.Code)
+	}
+
+	if code, _ = do(t, h, "GET", "/queries/RenameUser?id=1&name=Al", ""); code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET of a write = %d, want 405", code)
+	}
+	if code, out = do(t, h, "POST", "/queries/RenameUser", `{"params": {"id": 1, "name": "Al"}}`); code != http.StatusOK ||
+		out["rows_affected"] != 1.0 {
+		t.Fatalf("POST RenameUser = %d %v", code, out)
+	}
+}
+
+func TestCall(t *testing.T) {
+	srv := newTestServer(t, server.Options{Expose: []string{"AllUsers", "UserByID"}, MaxRows: 2})
+	ctx := context.Background()
+
+	res, err := srv.Call(ctx, "AllUsers", nil)
+	if err != nil || len(res.Rows) != 2 || !res.Truncated || res.Rows[1][0] != int64(2) {
+		t.Fatalf("AllUsers = %+v, %v; want 2 rows, truncated", res, err)
+	}
+	if _, err := srv.Call(ctx, "UserByID", nil); !errors.Is(err, server.ErrBadArguments) {
+		t.Fatalf("missing argument: expected ErrBadArguments, got %v", err)
+	}
+	if _, err := srv.Call(ctx, "RenameUser", []any{1, "x"}); !errors.Is(err, server.ErrUnknownQuery) {
+		t.Fatalf("unexposed query: expected ErrUnknownQuery, got %v", err)
+	}
+
+	d, _ := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3"})
+	defer d.Close()
+	reg := queries.NewRegistry()
+	_ = reg.Load(strings.NewReader(usersSQL), "users.sql")
+	if _, err := server.New(d, reg, server.Options{Expose: []string{"RenameUser"}}); err == nil {
+		t.Fatal("exposing a write without AllowWrites must fail")
+	}
+	if _, err := server.New(d, reg, server.Options{Expose: []string{"Missing"}}); err == nil {
+		t.Fatal("exposing an unregistered query must fail")
+	}
+}
@@ -0,0 +1,72 @@
+// Package uow provides a unit of work: one transaction shared by every
+// repository used inside it.
+//
+//	err := uow.Run(ctx, database, func(r *uow.Repos) error {
+//	    u, err := r.Users().Insert(r.Context(), params)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    return uow.Get(r, newAuditRepo).Record(r.Context(), "user.created", u.ID)
+//	})
+//
+// Repositories are constructed on first use, so a unit of work only pays for
+// what it touches.
+package uow
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/repo"
+)
+
+// Repos bundles repositories bound to the transaction of one unit of work.
+// It must not be used after Run returns.
+type Repos struct {
+	ctx   context.Context
+	tx    *db.Tx
+	users repo.UserRepository
+	extra map[reflect.Type]any
+}
+
+// Run executes fn inside a transaction and commits when fn returns nil. If
+// ctx already carries a transaction (see db.ContextWithTx), the unit of work
+// joins it instead of starting a new one.
+func Run(ctx context.Context, d *db.DB, fn func(r *Repos) error, opts ...db.TxOptions) error {
+	return d.InTx(ctx, func(ctx context.Context) error {
+		tx, _ := db.TxFromContext(ctx)
+		return fn(&Repos{ctx: ctx, tx: tx})
+	}, opts...)
+}
+
+// Context returns the context carrying the unit of work's transaction. Pass
+// it to repository calls and to services that use db.QuerierFromContext.
+func (r *Repos) Context() context.Context { return r.ctx }
+
+// Tx returns the underlying transaction for ad-hoc statements.
+func (r *Repos) Tx() *db.Tx { return r.tx }
+
+// Users returns the user repository bound to the transaction.
+func (r *Repos) Users() repo.UserRepository {
+	if r.users == nil {
+		r.users = repo.NewUserRepo(r.tx)
+	}
+	return r.users
+}
+
+// Get returns the repository of type T bound to r's transaction, building it
+// with newFn on first use. It lets packages outside repo join a unit of work
+// without Repos knowing about them.
+func Get[T any](r *Repos, newFn func(db.Querier) T) T {
+	key := reflect.TypeFor[T]()
+	if v, ok := r.extra[key]; ok {
+		return v.(T)
+	}
+	if r.extra == nil {
+		r.extra = make(map[reflect.Type]any)
+	}
+	v := newFn(r.tx)
+	r.extra[key] = v
+	return v
+}
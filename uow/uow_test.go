@@ -0,0 +1,79 @@
+package uow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Skryldev/sql-toolkit/db"
+	"github.com/Skryldev/sql-toolkit/models"
+	"github.com/Skryldev/sql-toolkit/uow"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	d, err := db.Open(db.Config{DSN: ":memory:", DriverName: "sqlite3"})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	_, err = d.Exec(context.Background(), `
+		CREATE TABLE users (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			name       TEXT NOT NULL,
+			email      TEXT NOT NULL UNIQUE,
+			created_at DATETIME NOT NULL,
+			updated_at DATETIME NOT NULL
+		)`)
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return d
+}
+
+type counter struct{ q db.Querier }
+
+func (c counter) users(ctx context.Context) (n int, err error) {
+	err = c.q.QueryRow(ctx, `SELECT COUNT(*) FROM users`).Scan(&n)
+	return n, err
+}
+
+func TestRun_CommitAndRollback(t *testing.T) {
+	d := newTestDB(t)
+	ctx := context.Background()
+	builds := 0
+	newCounter := func(q db.Querier) counter { builds++; return counter{q} }
+
+	err := uow.Run(ctx, d, func(r *uow.Repos) error {
+		if _, err := r.Users().Insert(r.Context(), models.CreateUserParams{Name: "A", Email: "a@uow.com"}); err != nil {
+			return err
+		}
+		n, err := uow.Get(r, newCounter).users(r.Context())
+		if err != nil || n != 1 {
+			t.Fatalf("count inside uow: n=%d err=%v", n, err)
+		}
+		_, _ = uow.Get(r, newCounter).users(r.Context())
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if builds != 1 {
+		t.Fatalf("repository built %d times, want 1", builds)
+	}
+
+	boom := errors.New("boom")
+	err = uow.Run(ctx, d, func(r *uow.Repos) error {
+		if _, err := r.Users().Insert(r.Context(), models.CreateUserParams{Name: "B", Email: "b@uow.com"}); err != nil {
+			return err
+		}
+		return boom
+	})
+	if !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+	if n, _ := (counter{d}).users(ctx); n != 1 {
+		t.Fatalf("expected rollback to leave 1 user, got %d", n)
+	}
+}